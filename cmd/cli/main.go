@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"backend-service-internpro/config"
+	"backend-service-internpro/internal/container"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/seed"
+)
+
+const usage = `Usage: cli <command> [flags]
+
+Commands:
+  seed demo    Fill the database with demo schools, majorities, classes, partners and users
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "seed":
+		err = runSeed(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runSeed(args []string) error {
+	if len(args) == 0 || args[0] != "demo" {
+		return fmt.Errorf("unknown seed profile, available: demo")
+	}
+
+	opts := seed.DefaultDemoOptions()
+	fs := flag.NewFlagSet("seed demo", flag.ExitOnError)
+	fs.IntVar(&opts.Schools, "schools", opts.Schools, "number of schools")
+	fs.IntVar(&opts.Majorities, "majorities", opts.Majorities, "majorities per school")
+	fs.IntVar(&opts.Classes, "classes", opts.Classes, "classes per majority")
+	fs.IntVar(&opts.Partners, "partners", opts.Partners, "partners per school")
+	fs.IntVar(&opts.Users, "users", opts.Users, "number of users besides the demo admin")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "random seed; the same seed and counts produce the same data")
+	fs.StringVar(&opts.Password, "password", "", "password of every demo user; a random one is generated and printed when empty")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	generated := opts.Password == ""
+	if generated {
+		opts.Password = seed.GeneratePassword()
+	}
+
+	logger.InitGlobalLogger(logger.LevelInfo)
+	c, err := container.NewContainer()
+	if err != nil {
+		return fmt.Errorf("failed to initialize container: %w", err)
+	}
+
+	// Demo accounts share one password and include a super admin; the
+	// container has loaded .env by now
+	if config.LoadEnvVar("APP_ENV") == "production" {
+		return fmt.Errorf("refusing to seed demo data with APP_ENV=production")
+	}
+
+	result, err := seed.NewDemo(c, opts).Run(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Demo data seeded (admin: %s)\n", seed.DemoAdminUsername)
+	if generated {
+		fmt.Printf("  password of the new demo users: %s\n", opts.Password)
+	}
+	fmt.Printf("  %-10s %8s %8s\n", "", "created", "existing")
+	for _, row := range []struct {
+		name              string
+		created, existing int
+	}{
+		{"schools", result.Created.Schools, result.Existing.Schools},
+		{"majorities", result.Created.Majorities, result.Existing.Majorities},
+		{"classes", result.Created.Classes, result.Existing.Classes},
+		{"partners", result.Created.Partners, result.Existing.Partners},
+		{"users", result.Created.Users, result.Existing.Users},
+	} {
+		fmt.Printf("  %-10s %8d %8d\n", row.name, row.created, row.existing)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Command openapi writes the OpenAPI spec of the Huma routes to a file so
+// client generators have a stable input and spec changes show up in review.
+//
+//	go run ./cmd/openapi -out api/openapi.json
+//	go run ./cmd/openapi -check   # fail when api/openapi.json is stale
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"backend-service-internpro/internal/container"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/router"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humagin"
+	"github.com/gin-gonic/gin"
+)
+
+// specPort is the local development port written into the snapshot, fixed
+// so the output does not depend on the environment
+const specPort = "8080"
+
+func main() {
+	out := flag.String("out", "api/openapi.json", "path of the generated spec")
+	check := flag.Bool("check", false, "compare the generated spec with -out instead of writing it")
+	flag.Parse()
+
+	spec, err := generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Generating twice guards against map ordering or other
+	// nondeterminism leaking into the snapshot
+	again, err := generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !bytes.Equal(spec, again) {
+		log.Fatal("openapi spec is not deterministic across runs")
+	}
+
+	if *check {
+		current, err := os.ReadFile(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !bytes.Equal(current, spec) {
+			log.Fatalf("%s is out of date; run go generate ./cmd/server", *out)
+		}
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, spec, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate registers every route on a throwaway API and returns the
+// indented spec. Services are never called while registering routes, so an
+// empty container is enough.
+func generate() ([]byte, error) {
+	gin.SetMode(gin.ReleaseMode)
+	api := humagin.New(gin.New(), router.HumaConfig(specPort))
+	routes := router.Register(api, &container.Container{})
+
+	if missing := missingOperationIDs(api.OpenAPI()); len(missing) > 0 {
+		return nil, fmt.Errorf("operations without OperationID: %v", missing)
+	}
+	if missing := missingPermissions(api.OpenAPI(), routes); len(missing) > 0 {
+		return nil, fmt.Errorf("operations not registered through routeperm.Register: %v", missing)
+	}
+
+	raw, err := api.OpenAPI().MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal openapi spec: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to indent openapi spec: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// missingOperationIDs lists "METHOD path" for every operation without an ID
+func missingOperationIDs(spec *huma.OpenAPI) []string {
+	return collectOperations(spec, func(_, _ string, op *huma.Operation) bool {
+		return op.OperationID == ""
+	})
+}
+
+// missingPermissions lists "METHOD path" for every operation without a
+// route permission entry; such routes fall back to the unknown route policy
+func missingPermissions(spec *huma.OpenAPI, routes *routeperm.Registry) []string {
+	return collectOperations(spec, func(method, path string, _ *huma.Operation) bool {
+		_, ok := routes.Lookup(method, path)
+		return !ok
+	})
+}
+
+// collectOperations lists "METHOD path" for every operation matching match
+func collectOperations(spec *huma.OpenAPI, match func(method, path string, op *huma.Operation) bool) []string {
+	var matched []string
+	for path, item := range spec.Paths {
+		ops := map[string]*huma.Operation{
+			"GET":    item.Get,
+			"POST":   item.Post,
+			"PUT":    item.Put,
+			"PATCH":  item.Patch,
+			"DELETE": item.Delete,
+		}
+		for method, op := range ops {
+			if op != nil && match(method, path, op) {
+				matched = append(matched, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
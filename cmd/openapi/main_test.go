@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotIsUpToDate(t *testing.T) {
+	spec, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile("../../api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(current, spec) {
+		return
+	}
+
+	want, got := strings.Split(string(current), "\n"), strings.Split(string(spec), "\n")
+	for i := range min(len(want), len(got)) {
+		if want[i] != got[i] {
+			t.Fatalf("api/openapi.json is out of date; run go generate ./cmd/server\nline %d:\n  committed: %s\n  generated: %s", i+1, want[i], got[i])
+		}
+	}
+	t.Fatalf("api/openapi.json is out of date; run go generate ./cmd/server\ncommitted %d lines, generated %d", len(want), len(got))
+}
+
+func TestSuccessResponsesAreNamed(t *testing.T) {
+	spec, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every success body gets its own schema; the shared envelope is only
+	// kept for the $schema links
+	if strings.Contains(string(spec), `"$ref": "#/components/schemas/ApiResponse"`) {
+		t.Error("a response still references the shared ApiResponse schema")
+	}
+	if !strings.Contains(string(spec), `"$ref": "#/components/schemas/ListSchoolsResponse"`) {
+		t.Error("listSchools does not reference ListSchoolsResponse")
+	}
+}
+
+func TestOperationIDsAreSetAndStable(t *testing.T) {
+	spec, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(spec, again) {
+		t.Error("two runs generated different specs")
+	}
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]string{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			route := strings.ToUpper(method) + " " + path
+			if op.OperationID == "" {
+				t.Errorf("%s has no operation ID", route)
+				continue
+			}
+			if other, ok := seen[op.OperationID]; ok {
+				t.Errorf("%s and %s share the operation ID %s", other, route, op.OperationID)
+			}
+			seen[op.OperationID] = route
+		}
+	}
+}
+
+func TestExamplesArePresent(t *testing.T) {
+	spec, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type schema struct {
+		Ref        string `json:"$ref"`
+		Properties map[string]struct {
+			Examples []any `json:"examples"`
+		} `json:"properties"`
+	}
+	type media struct {
+		Schema  schema `json:"schema"`
+		Example any    `json:"example"`
+	}
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			RequestBody struct {
+				Content map[string]media `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]struct {
+				Content map[string]media `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	// hasExamples reports whether every listed property of the schema m
+	// refers to has an example
+	hasExamples := func(m media, properties ...string) bool {
+		s, ok := doc.Components.Schemas[strings.TrimPrefix(m.Schema.Ref, "#/components/schemas/")]
+		if !ok {
+			return false
+		}
+		for _, name := range properties {
+			if len(s.Properties[name].Examples) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		path, method string
+		operationID  string
+		request      []string
+		// response lists the properties whose example must be set, or is
+		// nil when the operation documents an example of the whole body
+		response []string
+		data     []string
+	}{
+		{"/v1/auth/login", "post", "login", []string{"username_or_email", "password"}, nil, []string{"access_token", "refresh_token"}},
+		{"/v1/schools", "post", "createSchool", []string{"name", "address", "domain"}, []string{"id", "name", "domain", "created_at"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operationID, func(t *testing.T) {
+			op, ok := doc.Paths[tt.path][tt.method]
+			if !ok || op.OperationID != tt.operationID {
+				t.Fatalf("%s %s is not %s", tt.method, tt.path, tt.operationID)
+			}
+			if !hasExamples(op.RequestBody.Content["application/json"], tt.request...) {
+				t.Errorf("request body lacks examples for %v", tt.request)
+			}
+
+			body := op.Responses["200"].Content["application/json"]
+			if tt.response != nil && !hasExamples(body, tt.response...) {
+				t.Errorf("response body lacks examples for %v", tt.response)
+			}
+			if tt.data != nil {
+				example, _ := body.Example.(map[string]any)
+				data, _ := example["data"].(map[string]any)
+				for _, name := range tt.data {
+					if value, _ := data[name].(string); value == "" {
+						t.Errorf("response example has no data.%s: %v", name, body.Example)
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,24 +1,29 @@
 package main
 
 import (
+	"context"
+	"expvar"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
-	authhttp "backend-service-internpro/internal/auth/delivery/http"
 	"backend-service-internpro/internal/container"
+	"backend-service-internpro/internal/pkg/authz"
 	"backend-service-internpro/internal/pkg/logger"
 	"backend-service-internpro/internal/pkg/middleware"
-	rbachttp "backend-service-internpro/internal/rbac/delivery/http"
-	schoolhttp "backend-service-internpro/internal/school/delivery/http"
-	userhttp "backend-service-internpro/internal/user/delivery/http"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/router"
 
-	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humagin"
 	"github.com/gin-gonic/gin"
 )
 
+// Keep the committed OpenAPI snapshot in sync with the registered routes
+//go:generate go run ../openapi -out ../../api/openapi.json
+
 func main() {
 	// Initialize logger
 	logger.InitGlobalLogger(logger.LevelInfo)
@@ -43,107 +48,30 @@ func main() {
 	r := gin.Default()
 
 	// Add middlewares in proper order
+	logging := middleware.LoggingConfig{UserIDKey: c.Config.Server.LogUserIDKey}
+	if c.Config.Server.QuietProbeLogs {
+		logging.SkipPaths = c.Config.Server.RateLimitExemptPaths
+	}
 	r.Use(middleware.CORSMiddleware()) // CORS first
-	r.Use(middleware.RecoveryMiddleware())
+	r.Use(middleware.RecoveryMiddlewareWithConfig(logging))
 	r.Use(middleware.SecurityHeadersMiddleware())
+	r.Use(middleware.ClientIPMiddleware())
 	r.Use(middleware.FormDataToJSONMiddleware()) // Add FormData support
-	r.Use(middleware.LoggingMiddleware())
-	r.Use(middleware.RateLimitMiddleware(time.Second, 100)) // 100 requests per second per IP
-
-	// Configure Huma with detailed OpenAPI documentation
-	config := huma.DefaultConfig("Gapura SchoolTech API", "0.0.1")
-	config.OpenAPI.Info.Description = "Dokumentasi API untuk platform SchoolTech. Ini mencakup endpoint untuk autentikasi, kepentingan internal SchoolTech Indonesia, dan kepentingan produk SchoolTech Indonesia."
-	config.OpenAPI.Info.Contact = &huma.Contact{
-		Name:  "ITDB SchoolTech",
-		Email: "itdb@schooltechindonesia.com",
-		URL:   "https://schooltechindonesia.com",
-	}
-	config.OpenAPI.Info.License = &huma.License{
-		Name: "MIT",
-		URL:  "https://opensource.org/licenses/MIT",
-	}
-	config.OpenAPI.Info.TermsOfService = "https://schooltechindonesia.com/terms"
-	config.OpenAPI.Servers = []*huma.Server{
-		{
-			URL:         "https://api.schooltechindonesia.com",
-			Description: "Production server",
-		},
-		{
-			URL:         "https://staging-api.schooltechindonesia.com",
-			Description: "Staging server",
-		},
-		{
-			URL:         "https://testing-api.schooltechindonesia.com",
-			Description: "Testing server",
-		},
-		{
-			URL:         "http://localhost:" + port,
-			Description: "Local development",
-		},
-	}
-
-	// Initialize components if not exists
-	if config.OpenAPI.Components == nil {
-		config.OpenAPI.Components = &huma.Components{}
-	}
-	if config.OpenAPI.Components.SecuritySchemes == nil {
-		config.OpenAPI.Components.SecuritySchemes = make(map[string]*huma.SecurityScheme)
-	}
-
-	// Add security schemes
-	config.OpenAPI.Components.SecuritySchemes["bearerAuth"] = &huma.SecurityScheme{
-		Type:         "http",
-		Scheme:       "bearer",
-		BearerFormat: "JWT",
-	}
-
-	// Add API tags for better organization
-	config.OpenAPI.Tags = []*huma.Tag{
-		{
-			Name:        "Authentication",
-			Description: "Endpoint untuk autentikasi pengguna, login, logout, dan manajemen token",
-		},
-		{
-			Name:        "User Management",
-			Description: "Endpoint untuk manajemen data pengguna",
-		},
-		{
-			Name:        "RBAC - Roles",
-			Description: "Endpoint untuk manajemen roles (peran) dalam sistem RBAC",
-		},
-		{
-			Name:        "RBAC - Permissions",
-			Description: "Endpoint untuk manajemen permissions (izin) dalam sistem RBAC",
-		},
-		{
-			Name:        "RBAC - Menus",
-			Description: "Endpoint untuk manajemen menus dalam sistem RBAC",
-		},
-		{
-			Name:        "RBAC - User Roles",
-			Description: "Endpoint untuk assignment dan manajemen roles pengguna",
-		},
-		{
-			Name:        "School Management",
-			Description: "Endpoint untuk manajemen data sekolah",
-		},
-		{
-			Name:        "Teacher Management",
-			Description: "Endpoint untuk manajemen data guru",
-		},
-		{
-			Name:        "Student Management",
-			Description: "Endpoint untuk manajemen data siswa",
-		},
-	}
-
-	api := humagin.New(r, config)
-
-	// Register routes
-	authhttp.New(api, c.AuthService)
-	userhttp.New(api, c.UserService, c.JWTSecrets)     // User management routes
-	rbachttp.NewHuma(api, c.RBACService, c.JWTSecrets) // RBAC management routes with Swagger
-	schoolhttp.New(api, c.SchoolService, c.JWTSecrets) // School management routes
+	r.Use(middleware.LoggingMiddlewareWithConfig(logging))
+	r.Use(middleware.UsageMiddleware(c.UsageService, c.JWTSecrets))
+	// 100 requests per second per IP; health checks, probes and docs are exempt
+	r.Use(middleware.RateLimitMiddlewareWithConfig(middleware.RateLimitConfig{
+		Rate:           time.Second,
+		Capacity:       100,
+		ExemptPaths:    c.Config.Server.RateLimitExemptPaths,
+		ExemptNetworks: c.Config.Server.RateLimitExemptNetworks,
+	}))
+
+	// Router (Huma) with detailed OpenAPI documentation
+	api := humagin.New(r, router.HumaConfig(port))
+	routes := router.Register(api, c)
+	warnPermissionDrift(c, routes)
+	warnDanglingAuditReferences(c)
 
 	// Health check endpoint
 	r.GET("/healthz", func(c *gin.Context) {
@@ -170,6 +98,19 @@ func main() {
 	r.Static("/static", "./static")
 	r.StaticFile("/test-cors", "./test-cors.html")
 
+	// Route table, only when DEBUG_ENDPOINTS=true
+	if c.Config.Server.DebugEndpoints {
+		r.GET("/debug/routes", func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, response.Success("Route table retrieved successfully", router.Table(r, routes)))
+		})
+		// Runtime, integrity and validation rollout metrics published with expvar
+		r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+	}
+
+	for _, route := range router.Table(r, routes) {
+		appLogger.Debug("route registered", "method", route.Method, "path", route.Path, "access", route.Access, "source", route.Source)
+	}
+
 	// Start server
 	appLogger.Info("starting server", "port", port)
 	if err := http.ListenAndServe(":"+port, r); err != nil {
@@ -177,3 +118,63 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// warnPermissionDrift logs the permissions declared in code, stored in the
+// database and required by routes that do not line up. Drift never stops
+// startup; GET /v1/rbac/consistency serves the full report.
+func warnPermissionDrift(c *container.Container, routes *routeperm.Registry) {
+	appLogger := logger.Global()
+	report, err := c.RBACService.CheckConsistency(context.Background(), routes.Routes())
+	if err != nil {
+		appLogger.ErrorWithErr("permission consistency check failed", err)
+		return
+	}
+	if report.Consistent() {
+		return
+	}
+
+	keys := func(perms []authz.PermissionKey) []string {
+		out := make([]string, len(perms))
+		for i, p := range perms {
+			out[i] = p.String()
+		}
+		return out
+	}
+	unknown := make([]string, len(report.UnknownRoutes))
+	for i, r := range report.UnknownRoutes {
+		required := r.Resource + ":" + r.Action
+		if r.Access == routeperm.AccessSuperAdmin {
+			required = authz.RoleSuperAdmin + " role"
+		}
+		unknown[i] = r.Method + " " + r.Path + " -> " + required
+	}
+	appLogger.Warn("permission drift between code, database and routes",
+		"missing_in_database", keys(report.MissingInDatabase),
+		"unused_by_routes", keys(report.UnusedByRoutes),
+		"unknown_routes", unknown)
+}
+
+// warnDanglingAuditReferences logs audit columns naming users that do not
+// exist. They never stop startup; GET /v1/admin/integrity serves the counts
+// and POST /v1/admin/integrity/repair clears them.
+func warnDanglingAuditReferences(c *container.Container) {
+	appLogger := logger.Global()
+	report, err := c.IntegrityService.Check(context.Background())
+	if err != nil {
+		appLogger.ErrorWithErr("audit reference check failed", err)
+		return
+	}
+	if report.Total == 0 {
+		return
+	}
+
+	var columns []string
+	for _, col := range report.Columns {
+		if col.Rows > 0 {
+			columns = append(columns, fmt.Sprintf("%s.%s=%d", col.Table, col.Column, col.Rows))
+		}
+	}
+	appLogger.Warn("audit columns reference missing users",
+		"total", report.Total,
+		"columns", columns)
+}
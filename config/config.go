@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,6 +15,7 @@ import (
 
 var DB *gorm.DB
 var JwtSecret []byte
+var JwtOldSecrets [][]byte
 var JwtExpireTime time.Duration
 var RefreshTokenExpire time.Duration
 var SmtpHost string
@@ -72,20 +74,30 @@ func InitConfig() {
 		log.Fatal("❌ DB_NAME environment variable is required")
 	}
 
-	// MySQL DSN format
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	// MySQL DSN format. clientFoundRows makes RowsAffected count the rows an
+	// UPDATE matched, so an update that changes nothing is not mistaken for
+	// one whose row is gone.
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local&clientFoundRows=true",
 		dbUser, dbPass, dbHost, dbPort, dbName)
 
 	log.Printf("🔧 DSN: %s", dsn)
 
 	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError maps driver errors such as duplicate keys to gorm.ErrDuplicatedKey
+	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("❌ Failed to connect database: ", err)
 	}
 
-	// JWT configs
-	JwtSecret = []byte(os.Getenv("JWT_SECRET"))
+	// JWT configs. JWT_SECRET lists secrets newest first: the first signs,
+	// the others still verify tokens signed before it was rotated in.
+	for i, secret := range strings.Split(os.Getenv("JWT_SECRET"), ",") {
+		if i == 0 {
+			JwtSecret = []byte(strings.TrimSpace(secret))
+		} else if secret = strings.TrimSpace(secret); secret != "" {
+			JwtOldSecrets = append(JwtOldSecrets, []byte(secret))
+		}
+	}
 
 	// Parse JWT expire time from minutes
 	jwtExpireMinutes := os.Getenv("JWT_EXPIRE_MINUTES")
@@ -0,0 +1,5 @@
+-- Remove phone number and preferred OTP delivery channel from users table
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS preferred_otp_channel,
+DROP COLUMN IF EXISTS phone;
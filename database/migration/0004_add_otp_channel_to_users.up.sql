@@ -0,0 +1,5 @@
+-- Add phone number and preferred OTP delivery channel to users table
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS phone VARCHAR(32) DEFAULT NULL AFTER partner_id,
+ADD COLUMN IF NOT EXISTS preferred_otp_channel VARCHAR(16) NOT NULL DEFAULT 'email' AFTER phone;
@@ -0,0 +1,4 @@
+-- Remove school admin delegation flag from roles
+
+ALTER TABLE roles
+DROP COLUMN IF EXISTS assignable_by_school_admin;
@@ -0,0 +1,7 @@
+-- Allow roles to be flagged as assignable by school admins
+
+ALTER TABLE roles
+ADD COLUMN IF NOT EXISTS assignable_by_school_admin TINYINT(1) NOT NULL DEFAULT 0 AFTER is_active;
+
+-- School admins may grant the teacher and student roles within their school
+UPDATE roles SET assignable_by_school_admin = 1 WHERE slug IN ('teacher', 'student');
@@ -0,0 +1,6 @@
+-- Remove notifications table and email notification preference
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS email_notifications;
+
+DROP TABLE IF EXISTS notifications;
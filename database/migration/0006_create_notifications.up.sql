@@ -0,0 +1,19 @@
+-- Create notifications table for in-app user notifications
+CREATE TABLE IF NOT EXISTS notifications (
+  id CHAR(36) PRIMARY KEY,
+  user_id CHAR(36) NOT NULL,
+  type VARCHAR(50) NOT NULL,
+  payload JSON NOT NULL,
+  read_at TIMESTAMP NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+  -- Indexes for performance
+  INDEX idx_notifications_user_id (user_id),
+  INDEX idx_notifications_read_at (read_at),
+
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Let users opt in to receiving notifications by email
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS email_notifications TINYINT(1) NOT NULL DEFAULT 0 AFTER preferred_otp_channel;
@@ -0,0 +1,3 @@
+-- Drop class_schedules table
+
+DROP TABLE IF EXISTS class_schedules;
@@ -0,0 +1,27 @@
+-- Create class_schedules table for weekly class timetables.
+-- Times are minutes from midnight so they do not depend on time zones.
+CREATE TABLE IF NOT EXISTS class_schedules (
+  id CHAR(36) PRIMARY KEY,
+  class_id CHAR(36) NOT NULL,
+  day_of_week TINYINT NOT NULL,
+  start_minute SMALLINT NOT NULL,
+  end_minute SMALLINT NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  teacher_id CHAR(36),
+  room VARCHAR(100),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_by CHAR(36),
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  updated_by CHAR(36),
+  deleted_at TIMESTAMP NULL,
+  deleted_by CHAR(36),
+
+  -- Indexes for performance
+  INDEX idx_class_schedules_class_id (class_id),
+  INDEX idx_class_schedules_day_of_week (day_of_week),
+  INDEX idx_class_schedules_teacher_id (teacher_id),
+  INDEX idx_class_schedules_deleted_at (deleted_at),
+
+  FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE,
+  FOREIGN KEY (teacher_id) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,5 @@
+-- Drop RBAC junction history tables
+
+DROP TABLE IF EXISTS role_menus_history;
+DROP TABLE IF EXISTS role_permissions_history;
+DROP TABLE IF EXISTS user_roles_history;
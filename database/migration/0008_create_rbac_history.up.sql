@@ -0,0 +1,43 @@
+-- History tables for junction rows removed by a forced role delete.
+-- Rows are moved back when the role is restored.
+CREATE TABLE IF NOT EXISTS user_roles_history (
+  id CHAR(36) PRIMARY KEY,
+  user_id CHAR(36) NOT NULL,
+  role_id CHAR(36) NOT NULL,
+  assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  assigned_by CHAR(36),
+  archived_at TIMESTAMP NOT NULL,
+
+  INDEX idx_user_roles_history_role_id (role_id),
+  INDEX idx_user_roles_history_archived_at (archived_at)
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions_history (
+  id CHAR(36) PRIMARY KEY,
+  role_id CHAR(36) NOT NULL,
+  permission_id CHAR(36) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_by CHAR(36),
+  archived_at TIMESTAMP NOT NULL,
+
+  INDEX idx_role_permissions_history_role_id (role_id),
+  INDEX idx_role_permissions_history_archived_at (archived_at)
+);
+
+CREATE TABLE IF NOT EXISTS role_menus_history (
+  id CHAR(36) PRIMARY KEY,
+  role_id CHAR(36) NOT NULL,
+  menu_id CHAR(36) NOT NULL,
+  can_view TINYINT(1) DEFAULT 1,
+  can_create TINYINT(1) DEFAULT 0,
+  can_edit TINYINT(1) DEFAULT 0,
+  can_delete TINYINT(1) DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_by CHAR(36),
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_by CHAR(36),
+  archived_at TIMESTAMP NOT NULL,
+
+  INDEX idx_role_menus_history_role_id (role_id),
+  INDEX idx_role_menus_history_archived_at (archived_at)
+);
@@ -0,0 +1 @@
+-- Nothing to revert: empty domains are not restored
@@ -0,0 +1,4 @@
+-- Schools without a domain must store NULL, not an empty string, so the
+-- unique index on domain allows any number of them
+
+UPDATE schools SET domain = NULL WHERE domain = '';
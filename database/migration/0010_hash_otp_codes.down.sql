@@ -0,0 +1,4 @@
+-- Hashed codes cannot be turned back into plaintext; drop them before shrinking the column.
+DELETE FROM otps;
+
+ALTER TABLE otps MODIFY code VARCHAR(6) NOT NULL;
@@ -0,0 +1,5 @@
+-- OTP codes are now stored as an HMAC-SHA256 hex digest. Pending plaintext
+-- codes cannot be converted and are short-lived, so they are dropped.
+DELETE FROM otps;
+
+ALTER TABLE otps MODIFY code CHAR(64) NOT NULL;
@@ -0,0 +1,4 @@
+-- Remove personal data export and audit tables
+
+DROP TABLE IF EXISTS user_audit_events;
+DROP TABLE IF EXISTS user_data_exports;
@@ -0,0 +1,34 @@
+-- Personal data exports; the generated dump is kept until expires_at
+CREATE TABLE IF NOT EXISTS user_data_exports (
+  id CHAR(36) PRIMARY KEY,
+  user_id CHAR(36) NOT NULL,
+  requested_by CHAR(36) NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  payload LONGTEXT,
+  error VARCHAR(255),
+  expires_at TIMESTAMP NULL,
+  completed_at TIMESTAMP NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+  -- Indexes for performance
+  INDEX idx_user_data_exports_user_id (user_id),
+  INDEX idx_user_data_exports_expires_at (expires_at),
+
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Audit trail of actions on a user's personal data. Rows hold no personal
+-- data themselves and are kept when the user is erased.
+CREATE TABLE IF NOT EXISTS user_audit_events (
+  id CHAR(36) PRIMARY KEY,
+  actor_id CHAR(36),
+  subject_id CHAR(36) NOT NULL,
+  action VARCHAR(50) NOT NULL,
+  details JSON NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+  -- Indexes for performance
+  INDEX idx_user_audit_events_actor_id (actor_id),
+  INDEX idx_user_audit_events_subject_id (subject_id),
+  INDEX idx_user_audit_events_created_at (created_at)
+);
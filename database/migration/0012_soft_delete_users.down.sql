@@ -0,0 +1,8 @@
+-- Drop user soft delete columns; emails stay lowercase
+
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE users
+  DROP INDEX idx_users_deleted_at,
+  DROP COLUMN deleted_by,
+  DROP COLUMN deleted_at;
@@ -0,0 +1,8 @@
+-- Soft delete users and store emails lowercase so uniqueness is case-insensitive
+
+ALTER TABLE users
+  ADD COLUMN deleted_at TIMESTAMP NULL,
+  ADD COLUMN deleted_by CHAR(36),
+  ADD INDEX idx_users_deleted_at (deleted_at);
+
+UPDATE users SET email = LOWER(TRIM(email));
@@ -0,0 +1 @@
+-- Nothing to revert: grants may have been edited since and are kept
@@ -0,0 +1,10 @@
+-- Grant every permission to super-admin so enforcing route permissions
+-- does not lock it out; other roles are granted by operators
+
+INSERT IGNORE INTO role_permissions (id, role_id, permission_id, created_at)
+SELECT UUID(), roles.id, permissions.id, NOW()
+FROM roles
+CROSS JOIN permissions
+WHERE roles.slug = 'super-admin'
+  AND roles.deleted_at IS NULL
+  AND permissions.deleted_at IS NULL;
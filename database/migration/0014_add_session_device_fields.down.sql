@@ -0,0 +1,5 @@
+-- Remove session labels and device trust
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN trusted_until,
+  DROP COLUMN device_name;
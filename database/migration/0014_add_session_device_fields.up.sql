@@ -0,0 +1,5 @@
+-- Let users label sessions and trust their devices
+
+ALTER TABLE refresh_tokens
+  ADD COLUMN device_name VARCHAR(100) NULL,
+  ADD COLUMN trusted_until TIMESTAMP NULL;
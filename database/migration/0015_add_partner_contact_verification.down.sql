@@ -0,0 +1,6 @@
+-- Drop partner contact verification columns
+
+ALTER TABLE partners
+  DROP INDEX idx_partners_contact_email_verified_at,
+  DROP COLUMN contact_email_flagged_at,
+  DROP COLUMN contact_email_verified_at;
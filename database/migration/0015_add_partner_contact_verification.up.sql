@@ -0,0 +1,6 @@
+-- Track when partner contact emails were verified and flag stale ones
+
+ALTER TABLE partners
+  ADD COLUMN contact_email_verified_at TIMESTAMP NULL AFTER contact_email,
+  ADD COLUMN contact_email_flagged_at TIMESTAMP NULL AFTER contact_email_verified_at,
+  ADD INDEX idx_partners_contact_email_verified_at (contact_email_verified_at);
@@ -0,0 +1,6 @@
+-- Drop class academic year and homeroom teacher
+
+ALTER TABLE classes
+  DROP INDEX idx_classes_school_academic_year,
+  DROP COLUMN academic_year,
+  DROP COLUMN homeroom_teacher_id;
@@ -0,0 +1,6 @@
+-- Scope classes to an academic year and record their homeroom teacher
+
+ALTER TABLE classes
+  ADD COLUMN homeroom_teacher_id CHAR(36) NULL AFTER majority_id,
+  ADD COLUMN academic_year VARCHAR(9) NULL AFTER name,
+  ADD INDEX idx_classes_school_academic_year (school_id, academic_year);
@@ -0,0 +1,3 @@
+-- Drop partner merge audit trail
+
+DROP TABLE IF EXISTS partner_merges;
@@ -0,0 +1,15 @@
+-- Audit trail of partners merged into another partner
+
+CREATE TABLE IF NOT EXISTS partner_merges (
+  id CHAR(36) PRIMARY KEY,
+  survivor_id CHAR(36) NOT NULL,
+  duplicate_id CHAR(36) NOT NULL,
+  merged_by CHAR(36),
+  fields JSON NOT NULL,
+  users_moved INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+  -- Indexes for performance
+  INDEX idx_partner_merges_survivor_id (survivor_id),
+  INDEX idx_partner_merges_duplicate_id (duplicate_id)
+);
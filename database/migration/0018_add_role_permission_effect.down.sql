@@ -0,0 +1,8 @@
+-- Denies cannot be expressed without the column, so drop them rather than
+-- turn them into allows
+
+DELETE FROM role_permissions WHERE effect = 'deny';
+DELETE FROM role_permissions_history WHERE effect = 'deny';
+
+ALTER TABLE role_permissions DROP COLUMN effect;
+ALTER TABLE role_permissions_history DROP COLUMN effect;
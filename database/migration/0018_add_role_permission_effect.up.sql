@@ -0,0 +1,8 @@
+-- Let a role explicitly deny a permission. A deny from any of a user's
+-- roles overrides allows from the others.
+
+ALTER TABLE role_permissions
+  ADD COLUMN effect VARCHAR(5) NOT NULL DEFAULT 'allow' AFTER permission_id;
+
+ALTER TABLE role_permissions_history
+  ADD COLUMN effect VARCHAR(5) NOT NULL DEFAULT 'allow' AFTER permission_id;
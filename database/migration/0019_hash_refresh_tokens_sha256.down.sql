@@ -0,0 +1,3 @@
+-- Revoked bcrypt-hashed refresh tokens cannot be restored; nothing to undo
+
+SELECT 1;
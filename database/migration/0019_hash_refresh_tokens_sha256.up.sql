@@ -0,0 +1,7 @@
+-- Refresh tokens are now looked up by a SHA-256 digest. Rows stored with a
+-- salted bcrypt hash can never be found again, so revoke them; their users
+-- have to sign in once more.
+
+UPDATE refresh_tokens
+  SET revoked = TRUE
+  WHERE token_hash LIKE '$2%';
@@ -0,0 +1,5 @@
+-- Remove login lockout tracking
+
+ALTER TABLE users
+  DROP COLUMN locked_until,
+  DROP COLUMN failed_login_attempts;
@@ -0,0 +1,5 @@
+-- Track failed logins per user so repeated guessing locks the account
+
+ALTER TABLE users
+  ADD COLUMN failed_login_attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN locked_until TIMESTAMP NULL;
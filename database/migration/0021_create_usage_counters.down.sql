@@ -0,0 +1,2 @@
+-- Drop API usage counters
+DROP TABLE IF EXISTS usage_counters;
@@ -0,0 +1,17 @@
+-- Per user API usage counters: daily rows, summed per month once older
+-- than the daily retention
+CREATE TABLE IF NOT EXISTS usage_counters (
+  user_id CHAR(36) NOT NULL,
+  granularity VARCHAR(8) NOT NULL,
+  period_start DATE NOT NULL,
+  requests BIGINT NOT NULL DEFAULT 0,
+  client_errors BIGINT NOT NULL DEFAULT 0,
+  server_errors BIGINT NOT NULL DEFAULT 0,
+  throttled BIGINT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NULL,
+
+  PRIMARY KEY (user_id, granularity, period_start),
+  INDEX idx_usage_counters_period (granularity, period_start),
+
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- Remove email verification
+
+ALTER TABLE users
+  DROP COLUMN email_verified_at;
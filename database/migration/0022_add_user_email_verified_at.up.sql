@@ -0,0 +1,7 @@
+-- Record when users verify their email. Existing users predate verification
+-- and are treated as verified so they are not locked out when it is enforced.
+
+ALTER TABLE users
+  ADD COLUMN email_verified_at TIMESTAMP NULL AFTER email_notifications;
+
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
@@ -0,0 +1,6 @@
+-- Remove role landing menus
+
+ALTER TABLE roles
+  DROP INDEX idx_roles_default_menu_id,
+  DROP COLUMN priority,
+  DROP COLUMN default_menu_id;
@@ -0,0 +1,7 @@
+-- Let roles choose the page users land on after login; priority picks
+-- between the roles of a user with more than one
+
+ALTER TABLE roles
+  ADD COLUMN default_menu_id CHAR(36) NULL AFTER assignable_by_school_admin,
+  ADD COLUMN priority INT NOT NULL DEFAULT 0 AFTER default_menu_id,
+  ADD INDEX idx_roles_default_menu_id (default_menu_id);
@@ -0,0 +1,4 @@
+-- Remove OTP attempt counts
+
+ALTER TABLE otps
+  DROP COLUMN attempts;
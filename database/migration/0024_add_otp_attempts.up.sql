@@ -0,0 +1,4 @@
+-- Count wrong codes per OTP so a code is invalidated after too many
+
+ALTER TABLE otps
+  ADD COLUMN attempts INT NOT NULL DEFAULT 0 AFTER used;
@@ -0,0 +1,3 @@
+-- Remove API keys
+
+DROP TABLE IF EXISTS api_keys;
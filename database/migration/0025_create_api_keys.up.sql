@@ -0,0 +1,25 @@
+-- API keys let machine callers, like attendance devices and partner
+-- integrations, act as their owner within a restricted set of permissions.
+-- Only the SHA-256 of a key is stored; prefix identifies it in listings.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id CHAR(36) PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash CHAR(64) NOT NULL,
+  user_id CHAR(36) NOT NULL,
+  school_id CHAR(36) NULL,
+  scopes TEXT NOT NULL,
+  expires_at TIMESTAMP NULL,
+  last_used_at TIMESTAMP NULL,
+  revoked_at TIMESTAMP NULL,
+  created_by CHAR(36) NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  UNIQUE KEY uq_api_keys_key_hash (key_hash),
+  INDEX idx_api_keys_user_id (user_id),
+  INDEX idx_api_keys_school_id (school_id),
+
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (school_id) REFERENCES schools(id) ON DELETE SET NULL
+);
@@ -0,0 +1,3 @@
+-- Remove the authentication history
+
+DROP TABLE IF EXISTS auth_events;
@@ -0,0 +1,17 @@
+-- Keep a history of logins, refreshes, logouts and password events per
+-- user, to answer who signed in to an account and from where
+CREATE TABLE IF NOT EXISTS auth_events (
+  id CHAR(36) PRIMARY KEY,
+  user_id CHAR(36) NOT NULL,
+  type VARCHAR(32) NOT NULL,
+  ip VARCHAR(64) NULL,
+  user_agent VARCHAR(255) NULL,
+  success TINYINT(1) NOT NULL,
+  reason VARCHAR(50) NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+  INDEX idx_auth_events_user_created (user_id, created_at),
+  INDEX idx_auth_events_created_at (created_at),
+
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
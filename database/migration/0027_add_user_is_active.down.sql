@@ -0,0 +1,4 @@
+-- Remove user deactivation
+
+ALTER TABLE users
+  DROP COLUMN is_active;
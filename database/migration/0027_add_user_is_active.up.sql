@@ -0,0 +1,4 @@
+-- Let admins block a user from signing in without deleting them
+
+ALTER TABLE users
+  ADD COLUMN is_active TINYINT(1) NOT NULL DEFAULT 1;
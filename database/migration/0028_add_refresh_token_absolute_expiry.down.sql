@@ -0,0 +1,4 @@
+-- Remove the sliding expiry cap of sessions
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN absolute_expires_at;
@@ -0,0 +1,5 @@
+-- Cap how far sliding expiry can extend a session. Existing sessions keep
+-- NULL and are never extended.
+
+ALTER TABLE refresh_tokens
+  ADD COLUMN absolute_expires_at TIMESTAMP NULL;
@@ -0,0 +1,3 @@
+-- Remove the links to external identity providers
+
+DROP TABLE IF EXISTS user_identities;
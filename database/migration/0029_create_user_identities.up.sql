@@ -0,0 +1,15 @@
+-- Link users to their accounts at external identity providers such as
+-- Google, so they can sign in with them
+CREATE TABLE IF NOT EXISTS user_identities (
+  id CHAR(36) PRIMARY KEY,
+  user_id CHAR(36) NOT NULL,
+  provider VARCHAR(32) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  email VARCHAR(120) NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+  UNIQUE KEY idx_user_identities_provider_subject (provider, subject),
+  INDEX idx_user_identities_user_id (user_id),
+
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
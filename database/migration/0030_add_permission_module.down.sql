@@ -0,0 +1,5 @@
+-- Remove permission modules
+
+ALTER TABLE permissions
+  DROP INDEX idx_permissions_module,
+  DROP COLUMN module;
@@ -0,0 +1,8 @@
+-- Group permissions into modules so long lists can be shown by section.
+-- Existing permissions start in the module named after their resource.
+
+ALTER TABLE permissions
+  ADD COLUMN module VARCHAR(100) NOT NULL DEFAULT '' AFTER slug,
+  ADD INDEX idx_permissions_module (module);
+
+UPDATE permissions SET module = resource;
@@ -0,0 +1,14 @@
+-- Time-limited assignments would become permanent without the columns, so
+-- drop them rather than extend them
+
+DELETE FROM user_roles WHERE valid_from IS NOT NULL OR valid_until IS NOT NULL;
+DELETE FROM user_roles_history WHERE valid_from IS NOT NULL OR valid_until IS NOT NULL;
+
+ALTER TABLE user_roles
+  DROP INDEX idx_user_roles_valid_until,
+  DROP COLUMN valid_from,
+  DROP COLUMN valid_until;
+
+ALTER TABLE user_roles_history
+  DROP COLUMN valid_from,
+  DROP COLUMN valid_until;
@@ -0,0 +1,11 @@
+-- Let role assignments start and end at set times, e.g. for substitute
+-- teachers. NULL leaves that end of the window open.
+
+ALTER TABLE user_roles
+  ADD COLUMN valid_from TIMESTAMP NULL AFTER assigned_by,
+  ADD COLUMN valid_until TIMESTAMP NULL AFTER valid_from,
+  ADD INDEX idx_user_roles_valid_until (valid_until);
+
+ALTER TABLE user_roles_history
+  ADD COLUMN valid_from TIMESTAMP NULL AFTER assigned_by,
+  ADD COLUMN valid_until TIMESTAMP NULL AFTER valid_from;
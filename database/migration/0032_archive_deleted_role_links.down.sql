@@ -0,0 +1,2 @@
+-- Nothing to undo: the archived links stay in the history tables and come
+-- back when their role is restored.
@@ -0,0 +1,22 @@
+-- Deleting a role now archives its user, permission and menu links. Do the
+-- same for roles deleted before, so none of them still grants access and a
+-- restore brings their links back.
+
+INSERT INTO user_roles_history (id, user_id, role_id, assigned_at, assigned_by, valid_from, valid_until, archived_at)
+SELECT ur.id, ur.user_id, ur.role_id, ur.assigned_at, ur.assigned_by, ur.valid_from, ur.valid_until, r.deleted_at
+FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+WHERE r.deleted_at IS NOT NULL;
+
+INSERT INTO role_permissions_history (id, role_id, permission_id, effect, created_at, created_by, archived_at)
+SELECT rp.id, rp.role_id, rp.permission_id, rp.effect, rp.created_at, rp.created_by, r.deleted_at
+FROM role_permissions rp JOIN roles r ON r.id = rp.role_id
+WHERE r.deleted_at IS NOT NULL;
+
+INSERT INTO role_menus_history (id, role_id, menu_id, can_view, can_create, can_edit, can_delete, created_at, created_by, updated_at, updated_by, archived_at)
+SELECT rm.id, rm.role_id, rm.menu_id, rm.can_view, rm.can_create, rm.can_edit, rm.can_delete, rm.created_at, rm.created_by, rm.updated_at, rm.updated_by, r.deleted_at
+FROM role_menus rm JOIN roles r ON r.id = rm.role_id
+WHERE r.deleted_at IS NOT NULL;
+
+DELETE ur FROM user_roles ur JOIN roles r ON r.id = ur.role_id WHERE r.deleted_at IS NOT NULL;
+DELETE rp FROM role_permissions rp JOIN roles r ON r.id = rp.role_id WHERE r.deleted_at IS NOT NULL;
+DELETE rm FROM role_menus rm JOIN roles r ON r.id = rm.role_id WHERE r.deleted_at IS NOT NULL;
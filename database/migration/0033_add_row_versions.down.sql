@@ -0,0 +1,7 @@
+-- Remove the row versions of schools and users
+
+ALTER TABLE users
+  DROP COLUMN version;
+
+ALTER TABLE schools
+  DROP COLUMN version;
@@ -0,0 +1,9 @@
+-- Count the writes to schools and users. Updates are guarded by the version
+-- they read, and the ETag carries it, so two edits within the same second
+-- can no longer overwrite each other.
+
+ALTER TABLE schools
+  ADD COLUMN version BIGINT NOT NULL DEFAULT 1 AFTER updated_by;
+
+ALTER TABLE users
+  ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
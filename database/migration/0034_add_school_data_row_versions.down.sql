@@ -0,0 +1,10 @@
+-- Remove the row versions of majorities, classes and partners
+
+ALTER TABLE partners
+  DROP COLUMN version;
+
+ALTER TABLE classes
+  DROP COLUMN version;
+
+ALTER TABLE majorities
+  DROP COLUMN version;
@@ -0,0 +1,11 @@
+-- Count the writes to majorities, classes and partners like 0016 does for
+-- schools and users, so their updates can be guarded by the ETag too
+
+ALTER TABLE majorities
+  ADD COLUMN version BIGINT NOT NULL DEFAULT 1 AFTER updated_by;
+
+ALTER TABLE classes
+  ADD COLUMN version BIGINT NOT NULL DEFAULT 1 AFTER updated_by;
+
+ALTER TABLE partners
+  ADD COLUMN version BIGINT NOT NULL DEFAULT 1 AFTER updated_by;
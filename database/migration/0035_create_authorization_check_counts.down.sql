@@ -0,0 +1,2 @@
+-- Drop the authorization check counts
+DROP TABLE IF EXISTS authorization_check_counts;
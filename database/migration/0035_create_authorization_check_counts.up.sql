@@ -0,0 +1,10 @@
+-- Count the permission and role checks served through the check endpoints
+-- by result, so the totals survive restarts
+CREATE TABLE IF NOT EXISTS authorization_check_counts (
+  kind VARCHAR(16) NOT NULL,
+  result VARCHAR(8) NOT NULL,
+  total BIGINT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NULL,
+
+  PRIMARY KEY (kind, result)
+);
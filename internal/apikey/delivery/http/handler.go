@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"backend-service-internpro/internal/apikey"
+	"backend-service-internpro/internal/apikey/service"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// New registers the super admin API key routes into the Huma API.
+func New(api huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// Group /v1/admin/api-keys
+	g := huma.NewGroup(api, "/v1/admin/api-keys")
+
+	// POST /admin/api-keys - Create an API key
+	routeperm.Register(g, huma.Operation{
+		OperationID: "createAPIKey",
+		Method:      http.MethodPost,
+		Path:        "",
+		Summary:     "Create an API key",
+		Description: "Super admin only. Creates a key for a machine caller, sent as the X-API-Key header instead of a bearer token. Requests made with it act as user_id but may only call routes requiring one of the key's scopes, and only while the user holds that permission. The key is returned in this response only; it is stored hashed.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		Body apikey.CreateAPIKeyRequest
+	}) (*struct {
+		Body apikey.APIKeyResponse
+	}, error) {
+		actorID, _ := requestctx.UserID(ctx)
+
+		created, err := h.svc.Create(ctx, in.Body, actorID)
+		if err != nil {
+			return nil, toHumaError(err)
+		}
+		return &struct {
+			Body apikey.APIKeyResponse
+		}{Body: *response.Success(constants.APIKeyCreateSuccess, created)}, nil
+	})
+
+	// GET /admin/api-keys - List API keys
+	routeperm.Register(g, huma.Operation{
+		OperationID: "listAPIKeys",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "List API keys",
+		Description: "Super admin only. Lists API keys, newest first, including revoked ones. Keys themselves are never shown again.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		Page  int `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit int `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}) (*struct {
+		Body apikey.APIKeyListResponse
+	}, error) {
+		data, err := h.svc.List(ctx, in.Page, in.Limit)
+		if err != nil {
+			return nil, toHumaError(err)
+		}
+		return &struct {
+			Body apikey.APIKeyListResponse
+		}{Body: *response.Success(constants.APIKeyListSuccess, data)}, nil
+	})
+
+	// GET /admin/api-keys/{id} - Get an API key
+	routeperm.Register(g, huma.Operation{
+		OperationID: "getAPIKey",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get an API key",
+		Description: "Super admin only.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"API key ID"`
+	}) (*struct {
+		Body apikey.APIKeyResponse
+	}, error) {
+		key, err := h.svc.Get(ctx, in.ID)
+		if err != nil {
+			return nil, toHumaError(err)
+		}
+		return &struct {
+			Body apikey.APIKeyResponse
+		}{Body: *response.Success(constants.APIKeyDetailSuccess, key)}, nil
+	})
+
+	// PATCH /admin/api-keys/{id} - Rename, rescope or extend an API key
+	routeperm.Register(g, huma.Operation{
+		OperationID: "updateAPIKey",
+		Method:      http.MethodPatch,
+		Path:        "/{id}",
+		Summary:     "Update an API key",
+		Description: "Super admin only. Changes the name, scopes or expiry of a key; the key itself stays the same.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" doc:"API key ID"`
+		Body apikey.UpdateAPIKeyRequest
+	}) (*struct {
+		Body apikey.APIKeyResponse
+	}, error) {
+		actorID, _ := requestctx.UserID(ctx)
+
+		key, err := h.svc.Update(ctx, in.ID, in.Body, actorID)
+		if err != nil {
+			return nil, toHumaError(err)
+		}
+		return &struct {
+			Body apikey.APIKeyResponse
+		}{Body: *response.Success(constants.APIKeyUpdateSuccess, key)}, nil
+	})
+
+	// DELETE /admin/api-keys/{id} - Revoke an API key
+	routeperm.Register(g, huma.Operation{
+		OperationID: "revokeAPIKey",
+		Method:      http.MethodDelete,
+		Path:        "/{id}",
+		Summary:     "Revoke an API key",
+		Description: "Super admin only. The key stops working at once and stays listed with revoked_at set. Revoking a revoked key changes nothing.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"API key ID"`
+	}) (*struct {
+		Body apikey.APIKeyResponse
+	}, error) {
+		actorID, _ := requestctx.UserID(ctx)
+
+		key, err := h.svc.Revoke(ctx, in.ID, actorID)
+		if err != nil {
+			return nil, toHumaError(err)
+		}
+		return &struct {
+			Body apikey.APIKeyResponse
+		}{Body: *response.Success(constants.APIKeyRevokeSuccess, key)}, nil
+	})
+}
+
+func toHumaError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return huma.Error404NotFound(constants.APIKeyNotFound)
+	case errors.Is(err, service.ErrNameRequired), errors.Is(err, service.ErrNoScopes),
+		errors.Is(err, service.ErrUnknownScope), errors.Is(err, service.ErrPastExpiry),
+		errors.Is(err, service.ErrUnknownOwner):
+		return huma.Error400BadRequest(err.Error())
+	default:
+		return huma.Error500InternalServerError(err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package apikey
+
+import (
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// APIKey describes a key without the key itself, which is only shown once
+// when it is created
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" doc:"API key ID"`
+	Name       string     `json:"name" doc:"Label of the caller using the key"`
+	Prefix     string     `json:"prefix" doc:"Start of the key, to tell keys apart"`
+	UserID     uuid.UUID  `json:"user_id" doc:"User the key acts as"`
+	SchoolID   *uuid.UUID `json:"school_id,omitempty" doc:"School the key belongs to"`
+	Scopes     []string   `json:"scopes" doc:"Permissions (resource:action) the key is limited to"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" doc:"When the key stops working; absent for keys that do not expire"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" doc:"When the key was last used, to the minute"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" doc:"When the key was revoked"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty" doc:"Admin who created the key"`
+	CreatedAt  time.Time  `json:"created_at" doc:"When the key was created"`
+}
+
+// CreateAPIKeyRequest creates a key acting as UserID. Requests made with it
+// may only call routes requiring one of Scopes, and only while UserID holds
+// that permission too.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" maxLength:"100" example:"Mesin absensi lobi" doc:"Label of the caller using the key"`
+	UserID    uuid.UUID  `json:"user_id" doc:"User the key acts as"`
+	SchoolID  *uuid.UUID `json:"school_id,omitempty" doc:"School the key belongs to"`
+	Scopes    []string   `json:"scopes" minItems:"1" example:"[\"users:view\"]" doc:"Permissions (resource:action) the key is limited to"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" doc:"When the key stops working; omit for a key that does not expire"`
+}
+
+// UpdateAPIKeyRequest changes the fields that are set
+type UpdateAPIKeyRequest struct {
+	Name      *string    `json:"name,omitempty" maxLength:"100" doc:"New label"`
+	Scopes    []string   `json:"scopes,omitempty" doc:"New permissions (resource:action), replacing the current ones"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" doc:"New expiry"`
+}
+
+// CreatedAPIKey is a new key together with the key itself
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key" doc:"The key to send as X-API-Key. It is not stored and cannot be shown again."`
+}
+
+// APIKeyListData represents a page of API keys
+type APIKeyListData struct {
+	APIKeys []APIKey `json:"api_keys"`
+	Meta    Metadata `json:"meta"`
+}
+
+// Metadata represents pagination metadata
+type Metadata struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	TotalPages int `json:"total_pages"`
+	TotalItems int `json:"total_items"`
+}
+
+type APIKeyResponse = response.ApiResponse
+type APIKeyListResponse = response.ApiResponse
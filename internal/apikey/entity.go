@@ -0,0 +1,61 @@
+package apikey
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyEntity represents the api_keys table. Only the SHA-256 of the key
+// is stored; Prefix is its start, shown to tell keys apart.
+type APIKeyEntity struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	Name       string     `gorm:"size:100;not null"`
+	Prefix     string     `gorm:"size:16;not null"`
+	KeyHash    string     `gorm:"type:char(64);not null;uniqueIndex"`
+	UserID     uuid.UUID  `gorm:"type:char(36);not null;index"`
+	SchoolID   *uuid.UUID `gorm:"type:char(36);index"`
+	Scopes     string     `gorm:"type:text;not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedBy  *uuid.UUID `gorm:"type:char(36)"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName returns the table name for the APIKeyEntity
+func (APIKeyEntity) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the "resource:action" permissions the key is limited to
+func (k *APIKeyEntity) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// Active reports whether the key may still be used at now
+func (k *APIKeyEntity) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// ToAPIKey converts APIKeyEntity to APIKey DTO
+func (k *APIKeyEntity) ToAPIKey() APIKey {
+	return APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		UserID:     k.UserID,
+		SchoolID:   k.SchoolID,
+		Scopes:     k.ScopeList(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-service-internpro/internal/apikey"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, key *apikey.APIKeyEntity) error
+	GetByID(ctx context.Context, id uuid.UUID) (*apikey.APIKeyEntity, error)
+	// GetByHash returns the key with the hash when its owner is neither
+	// deleted nor deactivated
+	GetByHash(ctx context.Context, hash string) (*apikey.APIKeyEntity, error)
+	List(ctx context.Context, offset, limit int) ([]apikey.APIKeyEntity, int64, error)
+	Update(ctx context.Context, key *apikey.APIKeyEntity) error
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, key *apikey.APIKeyEntity) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*apikey.APIKeyEntity, error) {
+	var key apikey.APIKeyEntity
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *repository) GetByHash(ctx context.Context, hash string) (*apikey.APIKeyEntity, error) {
+	var key apikey.APIKeyEntity
+	err := r.db.WithContext(ctx).
+		Joins("JOIN users ON users.id = api_keys.user_id AND users.deleted_at IS NULL AND users.is_active = ?", true).
+		Where("api_keys.key_hash = ?", hash).
+		First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *repository) List(ctx context.Context, offset, limit int) ([]apikey.APIKeyEntity, int64, error) {
+	var keys []apikey.APIKeyEntity
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&apikey.APIKeyEntity{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&keys).Error; err != nil {
+		return nil, 0, err
+	}
+	return keys, total, nil
+}
+
+func (r *repository) Update(ctx context.Context, key *apikey.APIKeyEntity) error {
+	return r.db.WithContext(ctx).Model(key).
+		Select("name", "scopes", "expires_at").
+		Updates(key).Error
+}
+
+// Revoke marks the key revoked unless it already was
+func (r *repository) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&apikey.APIKeyEntity{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *repository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&apikey.APIKeyEntity{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", at).Error
+}
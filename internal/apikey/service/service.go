@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-service-internpro/internal/apikey"
+	"backend-service-internpro/internal/apikey/repository"
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// KeyPrefix starts every API key, so leaked keys are easy to recognize
+const KeyPrefix = "ipk_"
+
+// lastUsedResolution limits how often a key's last_used_at is written
+const lastUsedResolution = time.Minute
+
+var (
+	ErrNotFound     = errors.New("api key not found")
+	ErrNameRequired = errors.New("name is required")
+	ErrNoScopes     = errors.New("at least one scope is required")
+	ErrUnknownScope = errors.New("scope is not a known permission")
+	ErrPastExpiry   = errors.New("expires_at must be in the future")
+	ErrUnknownOwner = errors.New("user or school does not exist")
+)
+
+type Service interface {
+	// Create issues a key; the result is the only place the key appears
+	Create(ctx context.Context, req apikey.CreateAPIKeyRequest, actorID uuid.UUID) (*apikey.CreatedAPIKey, error)
+	List(ctx context.Context, page, limit int) (*apikey.APIKeyListData, error)
+	Get(ctx context.Context, id uuid.UUID) (*apikey.APIKey, error)
+	Update(ctx context.Context, id uuid.UUID, req apikey.UpdateAPIKeyRequest, actorID uuid.UUID) (*apikey.APIKey, error)
+	// Revoke stops the key from working; revoked keys stay listed
+	Revoke(ctx context.Context, id uuid.UUID, actorID uuid.UUID) (*apikey.APIKey, error)
+	// Authenticate resolves a key sent as X-API-Key to the user it acts as
+	// and its scopes, noting when it was used. Unknown, revoked and expired
+	// keys and keys of deleted users alike return nil without an error.
+	Authenticate(ctx context.Context, key string) (*requestctx.APIKey, error)
+}
+
+// Config holds the optional settings of NewWithConfig
+type Config struct {
+	// Clock and IDs default to the wall clock and random UUIDs
+	Clock clock.Clock
+	IDs   idgen.Generator
+}
+
+type service struct {
+	repo  repository.Repository
+	clock clock.Clock
+	ids   idgen.Generator
+}
+
+func New(repo repository.Repository) Service {
+	return NewWithConfig(repo, Config{})
+}
+
+func NewWithConfig(repo repository.Repository, cfg Config) Service {
+	return &service{
+		repo:  repo,
+		clock: clock.OrReal(cfg.Clock),
+		ids:   idgen.OrRandom(cfg.IDs),
+	}
+}
+
+func (s *service) Create(ctx context.Context, req apikey.CreateAPIKeyRequest, actorID uuid.UUID) (*apikey.CreatedAPIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.clock.Now()) {
+		return nil, ErrPastExpiry
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	entity := &apikey.APIKeyEntity{
+		ID:        s.ids.New(),
+		Name:      name,
+		Prefix:    key[:len(KeyPrefix)+8],
+		KeyHash:   hashKey(key),
+		UserID:    req.UserID,
+		SchoolID:  req.SchoolID,
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: &actorID,
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+	if err := s.repo.Create(ctx, entity); err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return nil, ErrUnknownOwner
+		}
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	logger.Global().Auth().LogSecurityEvent("api_key_created", "", requestctx.ClientIP(ctx),
+		"api key "+entity.ID.String()+" acting as "+entity.UserID.String()+" with scopes "+entity.Scopes+" created by "+actorID.String())
+	return &apikey.CreatedAPIKey{APIKey: entity.ToAPIKey(), Key: key}, nil
+}
+
+func (s *service) List(ctx context.Context, page, limit int) (*apikey.APIKeyListData, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	entities, total, err := s.repo.List(ctx, (page-1)*limit, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	keys := make([]apikey.APIKey, len(entities))
+	for i := range entities {
+		keys[i] = entities[i].ToAPIKey()
+	}
+	return &apikey.APIKeyListData{
+		APIKeys: keys,
+		Meta: apikey.Metadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+			TotalItems: int(total),
+		},
+	}, nil
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*apikey.APIKey, error) {
+	entity, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key := entity.ToAPIKey()
+	return &key, nil
+}
+
+func (s *service) Update(ctx context.Context, id uuid.UUID, req apikey.UpdateAPIKeyRequest, actorID uuid.UUID) (*apikey.APIKey, error) {
+	entity, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrNameRequired
+		}
+		entity.Name = name
+	}
+	if req.Scopes != nil {
+		scopes, err := normalizeScopes(req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		entity.Scopes = strings.Join(scopes, ",")
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(s.clock.Now()) {
+			return nil, ErrPastExpiry
+		}
+		entity.ExpiresAt = req.ExpiresAt
+	}
+	if err := s.repo.Update(ctx, entity); err != nil {
+		return nil, fmt.Errorf("failed to update api key: %w", err)
+	}
+
+	logger.Global().Auth().LogSecurityEvent("api_key_updated", "", requestctx.ClientIP(ctx),
+		"api key "+entity.ID.String()+" now has scopes "+entity.Scopes+", updated by "+actorID.String())
+	key := entity.ToAPIKey()
+	return &key, nil
+}
+
+func (s *service) Revoke(ctx context.Context, id uuid.UUID, actorID uuid.UUID) (*apikey.APIKey, error) {
+	entity, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entity.RevokedAt == nil {
+		now := s.clock.Now()
+		if err := s.repo.Revoke(ctx, id, now); err != nil {
+			return nil, fmt.Errorf("failed to revoke api key: %w", err)
+		}
+		entity.RevokedAt = &now
+		logger.Global().Auth().LogSecurityEvent("api_key_revoked", "", requestctx.ClientIP(ctx),
+			"api key "+entity.ID.String()+" revoked by "+actorID.String())
+	}
+	key := entity.ToAPIKey()
+	return &key, nil
+}
+
+func (s *service) Authenticate(ctx context.Context, key string) (*requestctx.APIKey, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, nil
+	}
+	entity, err := s.repo.GetByHash(ctx, hashKey(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	now := s.clock.Now()
+	if !entity.Active(now) {
+		return nil, nil
+	}
+
+	if entity.LastUsedAt == nil || now.Sub(*entity.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.TouchLastUsed(ctx, entity.ID, now); err != nil {
+			logger.Global().Auth().ErrorWithErr("failed to record api key use", err, "api_key_id", entity.ID.String())
+		}
+	}
+	return &requestctx.APIKey{ID: entity.ID, UserID: entity.UserID, Scopes: entity.ScopeList()}, nil
+}
+
+func (s *service) get(ctx context.Context, id uuid.UUID) (*apikey.APIKeyEntity, error) {
+	entity, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return entity, nil
+}
+
+// normalizeScopes checks that each scope is a declared permission and
+// returns them trimmed, without duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool, len(authz.Permissions))
+	for _, p := range authz.Permissions {
+		known[p.String()] = true
+	}
+	seen := make(map[string]bool, len(scopes))
+	out := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !known[scope] {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			out = append(out, scope)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrNoScopes
+	}
+	return out, nil
+}
+
+// generateKey returns a new random key starting with KeyPrefix
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return KeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashKey is how keys are stored and looked up; they are random enough
+// that a fast hash is safe
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"backend-service-internpro/internal/audit"
+	"backend-service-internpro/internal/audit/service"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/csvexport"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/routeperm"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// EventFilter is the event selection shared by the list and the export
+type EventFilter struct {
+	Source    string    `query:"source" enum:"auth,user" doc:"Only list events of this source: auth for logins, refreshes, logouts and password events, user for actions on personal data"`
+	ActorID   string    `query:"actor_id" format:"uuid" doc:"Only list events performed by this user"`
+	SubjectID string    `query:"subject_id" format:"uuid" doc:"Only list events about this user"`
+	From      time.Time `query:"from" doc:"Only list events at or after this time (RFC 3339)"`
+	To        time.Time `query:"to" doc:"Only list events before this time (RFC 3339)"`
+	Search    string    `query:"search" doc:"Search the action and payload, e.g. an IP address or user agent"`
+}
+
+// query turns the filter into an audit.Query, or the error to answer with
+func (f *EventFilter) query() (audit.Query, error) {
+	q := audit.Query{Source: f.Source, From: f.From, To: f.To, Search: f.Search}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, huma.Error400BadRequest(constants.AuditInvalidRange)
+	}
+	if f.ActorID != "" {
+		actorID, err := uuid.Parse(f.ActorID)
+		if err != nil {
+			return q, huma.Error400BadRequest("Invalid actor ID")
+		}
+		q.ActorID = &actorID
+	}
+	if f.SubjectID != "" {
+		subjectID, err := uuid.Parse(f.SubjectID)
+		if err != nil {
+			return q, huma.Error400BadRequest("Invalid subject ID")
+		}
+		q.SubjectID = &subjectID
+	}
+	return q, nil
+}
+
+const auditDescription = "Super admin only. Lists the auth events and the actions on personal data as one log, newest first. RBAC changes and requests are only written to the application logs and are not listed. Events are deleted once older than the retention of their source."
+
+// New registers the super admin audit log routes into the Huma API.
+func New(api huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// GET /v1/audit - Search the audit log
+	routeperm.Register(api, huma.Operation{
+		OperationID: "listAuditEvents",
+		Method:      http.MethodGet,
+		Path:        "/v1/audit",
+		Summary:     "Search the audit log",
+		Description: auditDescription,
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		EventFilter
+		Page  int `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit int `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}) (*struct {
+		Body audit.EventListResponse
+	}, error) {
+		q, err := in.query()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := h.svc.List(ctx, q, in.Page, in.Limit)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body audit.EventListResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /v1/audit/export - Download the audit log as CSV
+	routeperm.Register(api, huma.Operation{
+		OperationID: "exportAuditEvents",
+		Method:      http.MethodGet,
+		Path:        "/v1/audit/export",
+		Summary:     "Export the audit log as CSV",
+		Description: auditDescription + " The export takes the same filters as the list without paging and is streamed as it is read; events recorded after it started are left out. Each export is logged as a security event.",
+		Tags:        []string{"Administration"},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "The matching events, one per row",
+				Content:     map[string]*huma.MediaType{"text/csv": {}},
+			},
+		},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *EventFilter) (*huma.StreamResponse, error) {
+		actorID, _ := requestctx.UserID(ctx)
+		q, err := in.query()
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Global().Auth().LogSecurityEvent("audit_export", "", requestctx.ClientIP(ctx),
+			"actor="+actorID.String()+" source="+q.Source)
+
+		return &huma.StreamResponse{
+			Body: func(hctx huma.Context) {
+				hctx.SetHeader("Content-Type", csvexport.ContentType)
+				hctx.SetHeader("Content-Disposition", `attachment; filename="audit-`+time.Now().UTC().Format("20060102-150405")+`.csv"`)
+				hctx.SetStatus(http.StatusOK)
+				// The status is sent, so a failure can only cut the file short
+				if err := h.svc.Export(hctx.Context(), q, hctx.BodyWriter()); err != nil {
+					logger.Global().Service().ErrorWithErr("failed to export audit events", err)
+				}
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// Sources of audit events
+const (
+	// SourceAuth is the logins, refreshes, logouts and password events of
+	// auth_events
+	SourceAuth = "auth"
+	// SourceUser is the actions on personal data of user_audit_events,
+	// including permission and role checks about a user by someone else
+	SourceUser = "user"
+)
+
+// Sources lists every source, in the order the retention is enforced
+var Sources = []string{SourceAuth, SourceUser}
+
+// Event is an audit record of any source
+type Event struct {
+	Source    string                 `json:"source" enum:"auth,user" doc:"Where the event was recorded"`
+	ID        uuid.UUID              `json:"id" doc:"Event ID"`
+	ActorID   *uuid.UUID             `json:"actor_id,omitempty" doc:"User who acted, when known"`
+	SubjectID uuid.UUID              `json:"subject_id" doc:"User the event is about"`
+	Action    string                 `json:"action" doc:"What happened, e.g. login or erase"`
+	Details   map[string]interface{} `json:"details,omitempty" doc:"Source specific payload"`
+	CreatedAt time.Time              `json:"created_at" doc:"When it happened"`
+}
+
+// Query selects audit events; zero fields do not filter
+type Query struct {
+	Source    string
+	ActorID   *uuid.UUID
+	SubjectID *uuid.UUID
+	From      time.Time
+	To        time.Time
+	// Search matches the action and the payload, case insensitively
+	Search string
+}
+
+// Metadata represents pagination metadata for audit responses
+type Metadata struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	TotalPages int `json:"total_pages"`
+	TotalItems int `json:"total_items"`
+}
+
+// EventListData is a page of audit events, newest first
+type EventListData struct {
+	Data []Event  `json:"data"`
+	Meta Metadata `json:"meta"`
+}
+
+// EventListResponse represents the audit event list response
+type EventListResponse = response.ApiResponse
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventEntity is a row of the audit tables read through one shape, so
+// sources can be listed together
+type EventEntity struct {
+	Source    string
+	ID        uuid.UUID
+	ActorID   *uuid.UUID
+	SubjectID uuid.UUID
+	Action    string
+	Details   string
+	CreatedAt time.Time
+}
+
+// ToEvent converts EventEntity to Event DTO
+func (e *EventEntity) ToEvent() Event {
+	event := Event{
+		Source:    e.Source,
+		ID:        e.ID,
+		ActorID:   e.ActorID,
+		SubjectID: e.SubjectID,
+		Action:    e.Action,
+		CreatedAt: e.CreatedAt,
+	}
+
+	if e.Details != "" {
+		_ = json.Unmarshal([]byte(e.Details), &event.Details)
+	}
+
+	return event
+}
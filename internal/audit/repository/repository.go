@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"backend-service-internpro/internal/audit"
+
+	"gorm.io/gorm"
+)
+
+// Repository reads the audit tables of every source as one log and enforces
+// their retention. Table and column names come from sources, never from
+// requests.
+type Repository interface {
+	// List returns the events matching q from offset on, newest first
+	List(ctx context.Context, q audit.Query, offset, limit int) ([]audit.EventEntity, error)
+	// Count returns how many events match q
+	Count(ctx context.Context, q audit.Query) (int64, error)
+	// DeleteBefore deletes at most batch events of source created before
+	// before, oldest first, and returns how many it deleted
+	DeleteBefore(ctx context.Context, source string, before time.Time, batch int) (int64, error)
+}
+
+// table reads a source in the shape of audit.EventEntity
+type table struct {
+	name string
+	// columns are selected as the fields of audit.EventEntity
+	columns string
+	// actor, subject and search are conditions taking one argument
+	actor   string
+	subject string
+	search  string
+}
+
+var sources = map[string]table{
+	// A failed login is attempted by someone other than the user as often
+	// as not, so only successful events have an actor
+	audit.SourceAuth: {
+		name: "auth_events",
+		columns: "'auth' AS source, id, CASE WHEN success THEN user_id END AS actor_id, user_id AS subject_id, type AS action, " +
+			"CAST(JSON_OBJECT('ip', ip, 'user_agent', user_agent, 'success', IF(success, CAST('true' AS JSON), CAST('false' AS JSON)), 'reason', reason) AS CHAR) AS details, " +
+			"created_at",
+		actor:   "success AND user_id = ?",
+		subject: "user_id = ?",
+		search:  "LOWER(CONCAT_WS(' ', type, ip, user_agent, reason)) LIKE ?",
+	},
+	audit.SourceUser: {
+		name:    "user_audit_events",
+		columns: "'user' AS source, id, actor_id, subject_id, action, CAST(details AS CHAR) AS details, created_at",
+		actor:   "actor_id = ?",
+		subject: "subject_id = ?",
+		search:  "LOWER(CONCAT_WS(' ', action, CAST(details AS CHAR))) LIKE ?",
+	},
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// events unions the sources q selects, each filtered by q
+func (r *repository) events(ctx context.Context, q audit.Query) *gorm.DB {
+	var parts []string
+	var args []interface{}
+	for _, source := range audit.Sources {
+		if q.Source != "" && q.Source != source {
+			continue
+		}
+		t := sources[source]
+		query := r.db.Table(t.name).Select(t.columns)
+		if q.ActorID != nil {
+			query = query.Where(t.actor, *q.ActorID)
+		}
+		if q.SubjectID != nil {
+			query = query.Where(t.subject, *q.SubjectID)
+		}
+		if !q.From.IsZero() {
+			query = query.Where("created_at >= ?", q.From)
+		}
+		if !q.To.IsZero() {
+			query = query.Where("created_at < ?", q.To)
+		}
+		if q.Search != "" {
+			query = query.Where(t.search, "%"+strings.ToLower(q.Search)+"%")
+		}
+		parts = append(parts, "(?)")
+		args = append(args, query)
+	}
+	return r.db.WithContext(ctx).Table("("+strings.Join(parts, " UNION ALL ")+") AS events", args...)
+}
+
+func (r *repository) List(ctx context.Context, q audit.Query, offset, limit int) ([]audit.EventEntity, error) {
+	var events []audit.EventEntity
+	err := r.events(ctx, q).
+		Order("created_at DESC, source ASC, id ASC").
+		Offset(offset).Limit(limit).
+		Scan(&events).Error
+	return events, err
+}
+
+func (r *repository) Count(ctx context.Context, q audit.Query) (int64, error) {
+	var total int64
+	err := r.events(ctx, q).Count(&total).Error
+	return total, err
+}
+
+func (r *repository) DeleteBefore(ctx context.Context, source string, before time.Time, batch int) (int64, error) {
+	res := r.db.WithContext(ctx).Exec(
+		"DELETE FROM "+sources[source].name+" WHERE created_at < ? ORDER BY created_at LIMIT ?",
+		before, batch)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/audit"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// dryRun returns a database that renders statements without a server
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/test?parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func listSQL(t *testing.T, q audit.Query) string {
+	t.Helper()
+	r := &repository{db: dryRun(t)}
+	stmt := r.events(context.Background(), q).Order("created_at DESC").Find(&[]audit.EventEntity{}).Statement
+	return r.db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...)
+}
+
+func TestListFilters(t *testing.T) {
+	actorID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	subjectID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query audit.Query
+		want  []string
+	}{
+		{
+			name:  "no filter reads every source",
+			query: audit.Query{},
+			want:  []string{"FROM `auth_events`", "UNION ALL", "FROM `user_audit_events`"},
+		},
+		{
+			name:  "source",
+			query: audit.Query{Source: audit.SourceAuth},
+			want:  []string{"FROM `auth_events`"},
+		},
+		{
+			name:  "actor",
+			query: audit.Query{ActorID: &actorID},
+			want: []string{
+				// a failed login was not necessarily attempted by its user
+				"success AND user_id = '11111111-1111-1111-1111-111111111111'",
+				"actor_id = '11111111-1111-1111-1111-111111111111'",
+			},
+		},
+		{
+			name:  "subject",
+			query: audit.Query{SubjectID: &subjectID},
+			want:  []string{"user_id = '22222222-2222-2222-2222-222222222222'", "subject_id = '22222222-2222-2222-2222-222222222222'"},
+		},
+		{
+			name:  "date range",
+			query: audit.Query{From: from, To: from.Add(24 * time.Hour)},
+			want:  []string{"created_at >= '2026-01-01 00:00:00'", "created_at < '2026-01-02 00:00:00'"},
+		},
+		{
+			name:  "search is case insensitive",
+			query: audit.Query{Search: "Erase"},
+			want:  []string{"LIKE '%erase%'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := listSQL(t, tt.query)
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("query lacks %q:\n%s", want, sql)
+				}
+			}
+		})
+	}
+}
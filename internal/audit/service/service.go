@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"backend-service-internpro/internal/audit"
+	"backend-service-internpro/internal/audit/repository"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/csvexport"
+	"backend-service-internpro/internal/pkg/response"
+)
+
+// Defaults used for zero Config values
+const (
+	DefaultRetention  = 365 * 24 * time.Hour
+	DefaultPruneBatch = 1000
+)
+
+// exportBatch is how many events an export reads at a time
+const exportBatch = 500
+
+// exportHeader names the columns of an export
+var exportHeader = []string{"source", "id", "actor_id", "subject_id", "action", "details", "created_at"}
+
+type Service interface {
+	// List returns a page of the events matching q, newest first
+	List(ctx context.Context, q audit.Query, page, limit int) (*audit.EventListResponse, error)
+	// Export writes the events matching q to w as CSV, newest first. Events
+	// are read in batches and flushed after each, so the log is never held
+	// in memory.
+	Export(ctx context.Context, q audit.Query, w io.Writer) error
+	// Prune deletes the events older than the retention of their source,
+	// in batches; it runs from the cleanup job, not a request
+	Prune(ctx context.Context) (PruneResult, error)
+}
+
+// Config holds the audit service settings
+type Config struct {
+	// Retention is how long the events of each source are kept; sources
+	// without a positive retention keep theirs for DefaultRetention
+	Retention map[string]time.Duration
+	// PruneBatch caps the events one delete removes, so pruning a large
+	// backlog never locks a table for long
+	PruneBatch int
+	// Clock defaults to the wall clock
+	Clock clock.Clock
+}
+
+// PruneResult counts the events deleted per source
+type PruneResult map[string]int64
+
+// Total returns the events deleted across sources
+func (r PruneResult) Total() int64 {
+	var total int64
+	for _, deleted := range r {
+		total += deleted
+	}
+	return total
+}
+
+type service struct {
+	repo       repository.Repository
+	retention  map[string]time.Duration
+	pruneBatch int
+	clock      clock.Clock
+}
+
+// New creates an audit service; zero Config values use the defaults
+func New(repo repository.Repository, cfg Config) Service {
+	retention := make(map[string]time.Duration, len(audit.Sources))
+	for _, source := range audit.Sources {
+		retention[source] = cfg.Retention[source]
+		if retention[source] <= 0 {
+			retention[source] = DefaultRetention
+		}
+	}
+	if cfg.PruneBatch <= 0 {
+		cfg.PruneBatch = DefaultPruneBatch
+	}
+	return &service{
+		repo:       repo,
+		retention:  retention,
+		pruneBatch: cfg.PruneBatch,
+		clock:      clock.OrReal(cfg.Clock),
+	}
+}
+
+func (s *service) List(ctx context.Context, q audit.Query, page, limit int) (*audit.EventListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	total, err := s.repo.Count(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count audit events: %w", err)
+	}
+	rows, err := s.repo.List(ctx, q, (page-1)*limit, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+
+	events := make([]audit.Event, 0, len(rows))
+	for i := range rows {
+		events = append(events, rows[i].ToEvent())
+	}
+
+	data := audit.EventListData{
+		Data: events,
+		Meta: audit.Metadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+			TotalItems: int(total),
+		},
+	}
+
+	return response.Success(constants.AuditListSuccess, data), nil
+}
+
+func (s *service) Export(ctx context.Context, q audit.Query, w io.Writer) error {
+	// Events recorded while exporting would shift the batches
+	if q.To.IsZero() {
+		q.To = s.clock.Now()
+	}
+
+	cw, err := csvexport.NewWriter(w, exportHeader...)
+	if err != nil {
+		return err
+	}
+	for offset := 0; ; offset += exportBatch {
+		rows, err := s.repo.List(ctx, q, offset, exportBatch)
+		if err != nil {
+			return fmt.Errorf("failed to get audit events: %w", err)
+		}
+		for _, row := range rows {
+			actorID := ""
+			if row.ActorID != nil {
+				actorID = row.ActorID.String()
+			}
+			if err := cw.Write(row.Source, row.ID.String(), actorID, row.SubjectID.String(),
+				row.Action, row.Details, row.CreatedAt.UTC().Format(time.RFC3339)); err != nil {
+				return err
+			}
+		}
+		if err := cw.Flush(); err != nil {
+			return err
+		}
+		if len(rows) < exportBatch {
+			return nil
+		}
+	}
+}
+
+func (s *service) Prune(ctx context.Context) (PruneResult, error) {
+	result := make(PruneResult, len(audit.Sources))
+	now := s.clock.Now()
+	for _, source := range audit.Sources {
+		before := now.Add(-s.retention[source])
+		for {
+			deleted, err := s.repo.DeleteBefore(ctx, source, before, s.pruneBatch)
+			result[source] += deleted
+			if err != nil {
+				return result, fmt.Errorf("failed to prune %s audit events: %w", source, err)
+			}
+			if deleted < int64(s.pruneBatch) {
+				break
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/audit"
+	"backend-service-internpro/internal/pkg/clock"
+
+	"github.com/google/uuid"
+)
+
+// fakeRepo keeps events in memory, newest first
+type fakeRepo struct {
+	events  []audit.EventEntity
+	deletes int
+	queries []audit.Query
+}
+
+func (f *fakeRepo) matching(q audit.Query) []audit.EventEntity {
+	var out []audit.EventEntity
+	for _, e := range f.events {
+		if q.Source != "" && e.Source != q.Source {
+			continue
+		}
+		if !q.To.IsZero() && !e.CreatedAt.Before(q.To) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func (f *fakeRepo) List(_ context.Context, q audit.Query, offset, limit int) ([]audit.EventEntity, error) {
+	f.queries = append(f.queries, q)
+	events := f.matching(q)
+	if offset >= len(events) {
+		return nil, nil
+	}
+	return events[offset:min(offset+limit, len(events))], nil
+}
+
+func (f *fakeRepo) Count(_ context.Context, q audit.Query) (int64, error) {
+	return int64(len(f.matching(q))), nil
+}
+
+func (f *fakeRepo) DeleteBefore(_ context.Context, source string, before time.Time, batch int) (int64, error) {
+	f.deletes++
+	var deleted int64
+	kept := f.events[:0]
+	for _, e := range f.events {
+		if e.Source == source && e.CreatedAt.Before(before) && deleted < int64(batch) {
+			deleted++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	f.events = kept
+	return deleted, nil
+}
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(repo *fakeRepo, cfg Config) *service {
+	cfg.Clock = clock.NewFake(now)
+	return New(repo, cfg).(*service)
+}
+
+func event(source string, createdAt time.Time) audit.EventEntity {
+	return audit.EventEntity{
+		Source:    source,
+		ID:        uuid.New(),
+		SubjectID: uuid.New(),
+		Action:    "export",
+		Details:   "{}",
+		CreatedAt: createdAt,
+	}
+}
+
+func TestPruneRetentionBoundary(t *testing.T) {
+	const day = 24 * time.Hour
+	retention := 30 * day
+
+	tests := []struct {
+		name    string
+		age     time.Duration
+		deleted bool
+	}{
+		{"older than the retention", retention + time.Second, true},
+		{"exactly at the retention", retention, false},
+		{"newer than the retention", retention - time.Second, false},
+		{"recorded now", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{events: []audit.EventEntity{event(audit.SourceUser, now.Add(-tt.age))}}
+			s := newTestService(repo, Config{Retention: map[string]time.Duration{audit.SourceUser: retention}})
+
+			result, err := s.Prune(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result[audit.SourceUser] == 1; got != tt.deleted {
+				t.Errorf("deleted = %v, want %v", got, tt.deleted)
+			}
+			if got := len(repo.events) == 0; got != tt.deleted {
+				t.Errorf("event gone = %v, want %v", got, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestPruneDeletesInBatches(t *testing.T) {
+	repo := &fakeRepo{}
+	for i := 0; i < 5; i++ {
+		repo.events = append(repo.events, event(audit.SourceUser, now.Add(-DefaultRetention-time.Hour)))
+	}
+	s := newTestService(repo, Config{PruneBatch: 2})
+
+	result, err := s.Prune(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total() != 5 {
+		t.Errorf("deleted %d events, want 5", result.Total())
+	}
+	// 2 + 2 + 1 user events, then one empty batch of auth events: a short
+	// batch ends the loop of its source
+	if repo.deletes != 4 {
+		t.Errorf("ran %d deletes, want 4", repo.deletes)
+	}
+}
+
+func TestNewDefaultsRetention(t *testing.T) {
+	s := newTestService(&fakeRepo{}, Config{})
+	for _, source := range audit.Sources {
+		if s.retention[source] != DefaultRetention {
+			t.Errorf("%s retention = %v, want %v", source, s.retention[source], DefaultRetention)
+		}
+	}
+	if s.pruneBatch != DefaultPruneBatch {
+		t.Errorf("prune batch = %d, want %d", s.pruneBatch, DefaultPruneBatch)
+	}
+}
+
+func TestListPaginates(t *testing.T) {
+	repo := &fakeRepo{}
+	for i := 0; i < 25; i++ {
+		repo.events = append(repo.events, event(audit.SourceUser, now.Add(-time.Duration(i)*time.Minute)))
+	}
+	s := newTestService(repo, Config{})
+
+	result, err := s.List(context.Background(), audit.Query{}, 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := result.Data.(audit.EventListData)
+	if len(data.Data) != 5 {
+		t.Errorf("page 3 has %d events, want 5", len(data.Data))
+	}
+	if data.Meta.TotalItems != 25 || data.Meta.TotalPages != 3 {
+		t.Errorf("meta = %+v, want 25 items on 3 pages", data.Meta)
+	}
+}
+
+func TestExportWritesEveryBatch(t *testing.T) {
+	repo := &fakeRepo{}
+	total := exportBatch*2 + 3
+	for i := 0; i < total; i++ {
+		repo.events = append(repo.events, event(audit.SourceUser, now.Add(-time.Duration(i+1)*time.Second)))
+	}
+	s := newTestService(repo, Config{})
+
+	var out strings.Builder
+	if err := s.Export(context.Background(), audit.Query{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != total+1 {
+		t.Fatalf("wrote %d records, want %d rows and the header", len(records), total)
+	}
+	if strings.Join(records[0], ",") != strings.Join(exportHeader, ",") {
+		t.Errorf("header = %v", records[0])
+	}
+	for _, q := range repo.queries {
+		if !q.To.Equal(now) {
+			t.Errorf("batch read up to %v, want the export start %v", q.To, now)
+		}
+	}
+}
+
+func TestExportEscapesFormulas(t *testing.T) {
+	e := event(audit.SourceUser, now.Add(-time.Minute))
+	e.Action = "=HYPERLINK(\"http://evil\")"
+	s := newTestService(&fakeRepo{events: []audit.EventEntity{e}}, Config{})
+
+	var out strings.Builder
+	if err := s.Export(context.Background(), audit.Query{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[1][4]; got != "'"+e.Action {
+		t.Errorf("action cell = %q, want it prefixed with a quote", got)
+	}
+}
@@ -2,15 +2,23 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"backend-service-internpro/internal/auth"
 	"backend-service-internpro/internal/auth/service"
+	"backend-service-internpro/internal/pkg/authz"
 	"backend-service-internpro/internal/pkg/constants"
 	apperrors "backend-service-internpro/internal/pkg/errors"
+	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/requestctx"
 	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
 )
 
 type Handler struct{ svc service.Service }
@@ -23,41 +31,30 @@ func New(api huma.API, svc service.Service) {
 	g := huma.NewGroup(api, "/v1/auth")
 
 	// POST /login
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "/login",
-		Summary: "Login and get access/refresh tokens",
-		Tags:    []string{"Authentication"},
-	}, func(ctx context.Context, in *struct {
-		Body          auth.LoginRequest
-		UserAgent     string `header:"User-Agent"`
-		XForwardedFor string `header:"X-Forwarded-For"`
+	routeperm.Register(g, huma.Operation{
+		OperationID: "login",
+		Method:      http.MethodPost,
+		Path:        "/login",
+		Summary:     "Login and get access/refresh tokens",
+		Description: "Answers 401 for a wrong username, email or password, and 403 when the email is not verified yet or the account was deactivated. Wrong passwords are counted per account; once the limit is reached the account is locked and the endpoint answers 423 with Retry-After. Too many attempts for one username or email within a short window answer 429 with Retry-After. When login codes are enabled, a login from a device that is not trusted answers 403 and sends a code through the user's preferred OTP channel; repeat the login with the code. Sending the refresh token of a trusted session on the device skips the code.",
+		Tags:        []string{"Authentication"},
+		Responses:   response.Example(constants.LoginSuccess, exampleLogin),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusLocked, http.StatusTooManyRequests},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body      auth.LoginRequest
+		UserAgent string `header:"User-Agent"`
 	}) (*struct {
 		Body auth.LoginResponse
 	}, error) {
 		ua := in.UserAgent
-		ip := in.XForwardedFor
+		ip := requestctx.ClientIP(ctx)
 
-		access, refresh, err := h.svc.Login(in.Body.UsernameOrEmail, in.Body.Password, ua, ip)
+		tokens, err := h.svc.Login(in.Body, ua, ip)
 		if err != nil {
-			if appErr, ok := apperrors.IsAppError(err); ok {
-				return &struct {
-					Body auth.LoginResponse
-				}{
-					Body: *response.Error(appErr.Message),
-				}, nil
-			}
-			return &struct {
-				Body auth.LoginResponse
-			}{
-				Body: *response.Error(constants.LoginFailed),
-			}, nil
+			return nil, humaError(err, constants.LoginFailed)
 		}
 
-		loginData := auth.LoginData{
-			AccessToken:  access,
-			RefreshToken: refresh,
-		}
+		loginData := auth.LoginData{Tokens: *tokens}
 
 		return &struct {
 			Body auth.LoginResponse
@@ -66,41 +63,108 @@ func New(api huma.API, svc service.Service) {
 		}, nil
 	})
 
+	// GET /oauth/google
+	routeperm.Register(g, huma.Operation{
+		OperationID: "googleAuthURL",
+		Method:      http.MethodGet,
+		Path:        "/oauth/google",
+		Summary:     "Get the Google sign-in URL",
+		Description: "Returns the Google sign-in page to redirect the user to and the state Google sends back with the code. Pass both to the callback endpoint within 10 minutes. Answers 403 when Google sign-in is not configured.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusForbidden},
+	}, routeperm.Public, func(ctx context.Context, in *struct{}) (*struct {
+		Body auth.OAuthURLResponse
+	}, error) {
+		data, err := h.svc.GoogleAuthURL()
+		if err != nil {
+			return nil, humaError(err, constants.OAuthURLFailed)
+		}
+		return &struct {
+			Body auth.OAuthURLResponse
+		}{
+			Body: *response.Success(constants.OAuthURLSuccess, data),
+		}, nil
+	})
+
+	// POST /oauth/google/callback
+	routeperm.Register(g, huma.Operation{
+		OperationID: "googleLogin",
+		Method:      http.MethodPost,
+		Path:        "/oauth/google/callback",
+		Summary:     "Login with a Google authorization code",
+		Description: "Exchanges the code Google redirected back with for access/refresh tokens as from the login endpoint. The Google account signs in the user it was linked to before or, the first time, the user with its verified email, and is linked to them. Without such a user one is created when auto-provisioning is enabled. Answers 400 for an invalid or expired state, 401 when Google rejects the code, and 403 when no user may sign in with the account, the account was deactivated, verified emails are required and the user has not verified theirs, or Google sign-in is not configured.",
+		Tags:        []string{"Authentication"},
+		Responses:   response.Example(constants.LoginSuccess, exampleLogin),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body      auth.OAuthCallbackRequest
+		UserAgent string `header:"User-Agent"`
+	}) (*struct {
+		Body auth.LoginResponse
+	}, error) {
+		tokens, err := h.svc.GoogleLogin(ctx, in.Body, in.UserAgent, requestctx.ClientIP(ctx))
+		if err != nil {
+			return nil, humaError(err, constants.OAuthLoginFailed)
+		}
+		return &struct {
+			Body auth.LoginResponse
+		}{
+			Body: *response.Success(constants.LoginSuccess, auth.LoginData{Tokens: *tokens}),
+		}, nil
+	})
+
+	// POST /register
+	routeperm.Register(g, huma.Operation{
+		OperationID: "register",
+		Method:      http.MethodPost,
+		Path:        "/register",
+		Summary:     "Register a new user",
+		Description: "Creates a user without roles. Set login to also receive access/refresh tokens as from the login endpoint, subject to the same checks and attempt limit; when verified emails are required no tokens are returned until the email is verified. Answers 400 naming the broken rule for invalid input, 409 when the username or email is taken and, with login, 429 when the login attempt limit was reached.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusConflict, http.StatusTooManyRequests},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body      auth.RegisterRequest
+		UserAgent string `header:"User-Agent"`
+	}) (*struct {
+		Body auth.RegisterResponse
+	}, error) {
+		data, err := h.svc.Register(in.Body, in.UserAgent, requestctx.ClientIP(ctx))
+		if err != nil {
+			// Validation errors name the broken rule in their details
+			return nil, humaError(err, constants.RegisterFailed)
+		}
+
+		return &struct {
+			Body auth.RegisterResponse
+		}{
+			Body: *response.Success(constants.RegisterSuccess, data),
+		}, nil
+	})
+
 	// POST /refresh
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "/refresh",
-		Summary: "Exchange refresh token for new access token",
-		Tags:    []string{"Authentication"},
-	}, func(ctx context.Context, in *struct {
-		Body          auth.RefreshRequest
-		UserAgent     string `header:"User-Agent"`
-		XForwardedFor string `header:"X-Forwarded-For"`
+	routeperm.Register(g, huma.Operation{
+		OperationID: "refreshToken",
+		Method:      http.MethodPost,
+		Path:        "/refresh",
+		Summary:     "Exchange refresh token for new access and refresh tokens",
+		Description: "The refresh token is rotated: the one sent is revoked and the returned one expires when the old one would have or, when sliding sessions are enabled, a refresh token lifetime from now but no later than the session's absolute_expires_at. Sending a revoked refresh token again is treated as theft and signs out every session of the user. Answers 401 for invalid, expired or revoked refresh tokens and 403 when the account was deactivated.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body      auth.RefreshRequest
+		UserAgent string `header:"User-Agent"`
 	}) (*struct {
 		Body auth.RefreshResponse
 	}, error) {
 		ua := in.UserAgent
-		ip := in.XForwardedFor
+		ip := requestctx.ClientIP(ctx)
 
-		access, err := h.svc.Refresh(in.Body.RefreshToken, ua, ip)
+		tokens, err := h.svc.Refresh(in.Body.RefreshToken, ua, ip)
 		if err != nil {
-			if appErr, ok := apperrors.IsAppError(err); ok {
-				return &struct {
-					Body auth.RefreshResponse
-				}{
-					Body: *response.Error(appErr.Message),
-				}, nil
-			}
-			return &struct {
-				Body auth.RefreshResponse
-			}{
-				Body: *response.Error(constants.RefreshFailed),
-			}, nil
+			return nil, humaError(err, constants.RefreshFailed)
 		}
 
-		refreshData := auth.RefreshData{
-			AccessToken: access,
-		}
+		refreshData := auth.RefreshData{Tokens: *tokens}
 
 		return &struct {
 			Body auth.RefreshResponse
@@ -110,29 +174,22 @@ func New(api huma.API, svc service.Service) {
 	})
 
 	// POST /logout
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "/logout",
-		Summary: "Revoke refresh token (logout)",
-		Tags:    []string{"Authentication"},
-	}, func(ctx context.Context, in *struct {
-		Body auth.RefreshRequest
+	routeperm.Register(g, huma.Operation{
+		OperationID: "logout",
+		Method:      http.MethodPost,
+		Path:        "/logout",
+		Summary:     "Revoke refresh token (logout)",
+		Description: "Answers 401 when the refresh token is unknown or already revoked.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusUnauthorized},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body      auth.RefreshRequest
+		UserAgent string `header:"User-Agent"`
 	}) (*struct {
 		Body auth.BasicResponse
 	}, error) {
-		if err := h.svc.Logout(in.Body.RefreshToken); err != nil {
-			if appErr, ok := apperrors.IsAppError(err); ok {
-				return &struct {
-					Body auth.BasicResponse
-				}{
-					Body: *response.Error(appErr.Message),
-				}, nil
-			}
-			return &struct {
-				Body auth.BasicResponse
-			}{
-				Body: *response.Error(constants.LogoutFailed),
-			}, nil
+		if err := h.svc.Logout(in.Body.RefreshToken, in.UserAgent, requestctx.ClientIP(ctx)); err != nil {
+			return nil, humaError(err, constants.LogoutFailed)
 		}
 		return &struct {
 			Body auth.BasicResponse
@@ -141,22 +198,88 @@ func New(api huma.API, svc service.Service) {
 		}, nil
 	})
 
+	// POST /logout-all - Revoke every session of the caller
+	routeperm.Register(g, huma.Operation{
+		OperationID: "logoutAll",
+		Method:      http.MethodPost,
+		Path:        "/logout-all",
+		Summary:     "Revoke every session of the caller (logout everywhere)",
+		Description: "Revokes all of the caller's refresh tokens and the access tokens issued so far.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusUnauthorized},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct{}) (*struct {
+		Body auth.BasicResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		revoked, err := h.svc.LogoutAll(userID)
+		if err != nil {
+			return nil, humaError(err, constants.LogoutFailed)
+		}
+		return &struct {
+			Body auth.BasicResponse
+		}{
+			Body: *response.Success(constants.LogoutAllSuccess, auth.LogoutAllData{RevokedSessions: revoked}),
+		}, nil
+	})
+
+	// GET /me - Profile of the authenticated user
+	routeperm.Register(g, huma.Operation{
+		OperationID: "getMe",
+		Method:      http.MethodGet,
+		Path:        "/me",
+		Summary:     "Get the authenticated user's profile",
+		Description: "Returns the profile and active role slugs of the access token's user. Answers 401 for missing, invalid or expired tokens and 404 when the user was deleted.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct{}) (*struct {
+		Body auth.MeResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		data, err := h.svc.Me(ctx, userID)
+		if err != nil {
+			return nil, humaError(err, constants.ProfileFailed)
+		}
+		return &struct {
+			Body auth.MeResponse
+		}{
+			Body: *response.Success(constants.ProfileSuccess, data),
+		}, nil
+	})
+
 	// POST /forgot
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "/forgot",
-		Summary: "Send OTP for password reset",
-		Tags:    []string{"Authentication"},
-	}, func(ctx context.Context, in *struct {
-		Body auth.ForgotRequest
+	routeperm.Register(g, huma.Operation{
+		OperationID: "forgotPassword",
+		Method:      http.MethodPost,
+		Path:        "/forgot",
+		Summary:     "Send OTP for password reset",
+		Description: "Sends a password reset code to the user with the email or, when it is empty, the username; codes sent earlier stop working. Codes are sent at most once per cooldown, as by /resend-otp. Answers the same whether or not the user exists.",
+		Tags:        []string{"Authentication"},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body      auth.ForgotRequest
+		UserAgent string `header:"User-Agent"`
 	}) (*struct {
 		Body auth.BasicResponse
 	}, error) {
-		err := h.svc.Forgot(in.Body.Email)
 		// Always return success message for security (prevent email enumeration)
-		if err != nil {
-			// Log the actual error for debugging but don't expose it
-			// You can add logging here later
+		if err := h.svc.Forgot(in.Body.Identifier(), in.UserAgent, requestctx.ClientIP(ctx)); err != nil {
+			appErr, ok := apperrors.IsAppError(err)
+			if !ok || appErr.Code == apperrors.CodeInternalServer {
+				logger.Global().Auth().ErrorWithErr("failed to send password reset code", err)
+			}
 		}
 		return &struct {
 			Body auth.BasicResponse
@@ -165,30 +288,52 @@ func New(api huma.API, svc service.Service) {
 		}, nil
 	})
 
-	// POST /verify-otp
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "/verify-otp",
-		Summary: "Validate OTP for password reset",
-		Tags:    []string{"Authentication"},
-	}, func(ctx context.Context, in *struct {
-		Body auth.VerifyOTPRequest
+	// POST /resend-otp
+	routeperm.Register(g, huma.Operation{
+		OperationID: "resendOTP",
+		Method:      http.MethodPost,
+		Path:        "/resend-otp",
+		Summary:     "Send a new OTP for password reset",
+		Description: "Sends a new password reset code and invalidates the earlier ones, as /forgot does. Takes the email or username like /forgot and answers the same whether or not the user exists. A new code can be requested once per cooldown per email or username given, whether or not a user has it; sooner the endpoint answers 429 with Retry-After. Codes are also sent at most once per cooldown per user, which is not reported.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body auth.ResendOTPRequest
 	}) (*struct {
 		Body auth.BasicResponse
 	}, error) {
-		if err := h.svc.VerifyOTP(in.Body.Email, in.Body.OTP); err != nil {
+		// Unknown emails get the same answer to prevent enumeration
+		if err := h.svc.ResendOTP(in.Body.Identifier()); err != nil {
 			if appErr, ok := apperrors.IsAppError(err); ok {
-				return &struct {
-					Body auth.BasicResponse
-				}{
-					Body: *response.Error(appErr.Message),
-				}, nil
+				switch appErr.Code {
+				case apperrors.CodeTooManyRequests, apperrors.CodeValidationFailed:
+					return nil, appErr.ToHumaError()
+				}
 			}
-			return &struct {
-				Body auth.BasicResponse
-			}{
-				Body: *response.Error(constants.OTPInvalid),
-			}, nil
+		}
+		return &struct {
+			Body auth.BasicResponse
+		}{
+			Body: *response.SuccessWithoutData(constants.OTPSent),
+		}, nil
+	})
+
+	// POST /verify-otp
+	routeperm.Register(g, huma.Operation{
+		OperationID: "verifyOTP",
+		Method:      http.MethodPost,
+		Path:        "/verify-otp",
+		Summary:     "Validate OTP for password reset",
+		Description: "Takes the email or username the code was requested with. Failed codes are counted per client IP and per user; once either limit is reached the endpoint answers 429 with Retry-After. After too many wrong codes the code sent is invalidated and a new one must be requested.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body auth.VerifyOTPRequest
+	}) (*struct {
+		Body auth.BasicResponse
+	}, error) {
+		if err := h.svc.VerifyOTP(in.Body.Identifier(), in.Body.OTP, requestctx.ClientIP(ctx)); err != nil {
+			return nil, humaError(err, constants.OTPInvalid)
 		}
 		return &struct {
 			Body auth.BasicResponse
@@ -198,34 +343,350 @@ func New(api huma.API, svc service.Service) {
 	})
 
 	// POST /reset-password
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "/reset-password",
-		Summary: "Reset password with valid OTP",
-		Tags:    []string{"Authentication"},
-	}, func(ctx context.Context, in *struct {
-		Body auth.ResetPasswordRequest
+	routeperm.Register(g, huma.Operation{
+		OperationID: "resetPassword",
+		Method:      http.MethodPost,
+		Path:        "/reset-password",
+		Summary:     "Reset password with valid OTP",
+		Description: "Takes the email or username the code was requested with. Failed codes are counted per client IP and per user; once either limit is reached the endpoint answers 429 with Retry-After. After too many wrong codes the code sent is invalidated and a new one must be requested.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body      auth.ResetPasswordRequest
+		UserAgent string `header:"User-Agent"`
+	}) (*struct {
+		Body auth.BasicResponse
+	}, error) {
+		if err := h.svc.ResetPassword(in.Body.Identifier(), in.Body.OTP, in.Body.NewPassword, in.UserAgent, requestctx.ClientIP(ctx)); err != nil {
+			return nil, humaError(err, constants.PasswordResetFailed)
+		}
+		return &struct {
+			Body auth.BasicResponse
+		}{
+			Body: *response.SuccessWithoutData(constants.PasswordResetSuccess),
+		}, nil
+	})
+
+	// POST /verify-email
+	routeperm.Register(g, huma.Operation{
+		OperationID: "verifyEmail",
+		Method:      http.MethodPost,
+		Path:        "/verify-email",
+		Summary:     "Verify email with the code sent on sign up",
+		Description: "New users receive a code by email when they register or are created by an administrator. Failed codes count towards the same per IP and per email limits as verify-otp; once either is reached the endpoint answers 429 with Retry-After.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body auth.VerifyEmailRequest
+	}) (*struct {
+		Body auth.BasicResponse
+	}, error) {
+		if err := h.svc.VerifyEmail(in.Body.Email, in.Body.OTP, requestctx.ClientIP(ctx)); err != nil {
+			return nil, humaError(err, constants.EmailVerifyFailed)
+		}
+		return &struct {
+			Body auth.BasicResponse
+		}{
+			Body: *response.SuccessWithoutData(constants.EmailVerified),
+		}, nil
+	})
+
+	// POST /resend-verification
+	routeperm.Register(g, huma.Operation{
+		OperationID: "resendVerification",
+		Method:      http.MethodPost,
+		Path:        "/resend-verification",
+		Summary:     "Send a new email verification code",
+		Description: "Answers the same whether or not the email is registered or already verified. A few codes can be sent per email each hour; after that the endpoint answers 429 with Retry-After.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Body auth.ResendVerificationRequest
 	}) (*struct {
 		Body auth.BasicResponse
 	}, error) {
-		if err := h.svc.ResetPassword(in.Body.Email, in.Body.OTP, in.Body.NewPassword); err != nil {
+		// Unknown and verified emails get the same answer to prevent enumeration
+		if err := h.svc.ResendVerification(in.Body.Email); err != nil {
 			if appErr, ok := apperrors.IsAppError(err); ok {
-				return &struct {
-					Body auth.BasicResponse
-				}{
-					Body: *response.Error(appErr.Message),
-				}, nil
+				switch appErr.Code {
+				case apperrors.CodeTooManyRequests, apperrors.CodeValidationFailed:
+					return nil, appErr.ToHumaError()
+				}
 			}
-			return &struct {
-				Body auth.BasicResponse
-			}{
-				Body: *response.Error(constants.PasswordResetFailed),
-			}, nil
 		}
 		return &struct {
 			Body auth.BasicResponse
 		}{
-			Body: *response.SuccessWithoutData(constants.PasswordResetSuccess),
+			Body: *response.SuccessWithoutData(constants.VerificationSent),
+		}, nil
+	})
+
+	// GET /sessions - List the caller's active sessions
+	routeperm.Register(g, huma.Operation{
+		OperationID: "listSessions",
+		Method:      http.MethodGet,
+		Path:        "/sessions",
+		Summary:     "List active sessions",
+		Description: "Lists the caller's active sessions, newest first. current marks the session of the access token used for the call.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusUnauthorized},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct{}) (*struct {
+		Body auth.SessionListResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		var currentID uuid.UUID
+		if claims, ok := requestctx.Claims(ctx); ok {
+			currentID, _ = uuid.Parse(claims.SessionID)
+		}
+
+		sessions, err := h.svc.ListSessions(userID, currentID)
+		if err != nil {
+			return nil, humaError(err, constants.SessionListFailed)
+		}
+		return &struct {
+			Body auth.SessionListResponse
+		}{
+			Body: *response.Success(constants.SessionListSuccess, sessions),
+		}, nil
+	})
+
+	// DELETE /sessions/{id} - Revoke one of the caller's sessions
+	routeperm.Register(g, huma.Operation{
+		OperationID: "revokeSession",
+		Method:      http.MethodDelete,
+		Path:        "/sessions/{id}",
+		Summary:     "Revoke a session",
+		Description: "Revokes one of the caller's active sessions and the access tokens issued with it. Sessions of other users are reported as not found.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Session ID"`
+	}) (*struct {
+		Body auth.BasicResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		if err := h.svc.RevokeSession(userID, in.ID); err != nil {
+			return nil, humaError(err, constants.SessionRevokeFailed)
+		}
+		return &struct {
+			Body auth.BasicResponse
+		}{
+			Body: *response.SuccessWithoutData(constants.SessionRevokeSuccess),
+		}, nil
+	})
+
+	// POST /revoke-token - Reject access tokens before they expire
+	routeperm.Register(g, huma.Operation{
+		OperationID: "revokeToken",
+		Method:      http.MethodPost,
+		Path:        "/revoke-token",
+		Summary:     "Revoke access tokens (super admin)",
+		Description: "Rejects an access token, given itself or by its jti, or every access token issued to a user so far, until they expire. Exactly one of token, jti and user_id must be set. Refresh tokens are not affected. Revocations are shared by every server through Redis when REDIS_ADDR is set; otherwise each server keeps its own in memory and loses them on restart.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		Body auth.RevokeTokenRequest
+	}) (*struct {
+		Body auth.RevokeTokenResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		data, err := h.svc.RevokeToken(ctx, userID, in.Body)
+		if err != nil {
+			return nil, humaError(err, constants.TokenRevokeFailed)
+		}
+		return &struct {
+			Body auth.RevokeTokenResponse
+		}{
+			Body: *response.Success(constants.TokenRevokeSuccess, data),
+		}, nil
+	})
+
+	// POST /impersonate/{user_id} - Act as another user
+	routeperm.Register(g, huma.Operation{
+		OperationID: "impersonateUser",
+		Method:      http.MethodPost,
+		Path:        "/impersonate/{user_id}",
+		Summary:     "Impersonate a user (super admin)",
+		Description: "Issues a short-lived access token acting as the user, with an impersonator_id claim naming the caller. Requests made with it are flagged in the request log. The token cannot be refreshed and ends with the caller's session. Super admins cannot be impersonated.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		UserID uuid.UUID `path:"user_id" doc:"User to impersonate"`
+	}) (*struct {
+		Body auth.ImpersonationResponse
+	}, error) {
+		claims, ok := requestctx.Claims(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		data, err := h.svc.Impersonate(ctx, claims, in.UserID)
+		if err != nil {
+			return nil, humaError(err, constants.ImpersonationFailed)
+		}
+		return &struct {
+			Body auth.ImpersonationResponse
+		}{
+			Body: *response.Success(constants.ImpersonationSuccess, data),
+		}, nil
+	})
+
+	// POST /stop-impersonation - Return to the impersonator's own account
+	routeperm.Register(g, huma.Operation{
+		OperationID: "stopImpersonation",
+		Method:      http.MethodPost,
+		Path:        "/stop-impersonation",
+		Summary:     "Stop impersonating a user",
+		Description: "Called with an impersonation token: revokes it and issues the impersonating super admin an access token of their own for the same session.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct{}) (*struct {
+		Body auth.ImpersonationResponse
+	}, error) {
+		claims, ok := requestctx.Claims(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		data, err := h.svc.StopImpersonation(ctx, claims)
+		if err != nil {
+			return nil, humaError(err, constants.ImpersonationStopFailed)
+		}
+		return &struct {
+			Body auth.ImpersonationResponse
+		}{
+			Body: *response.Success(constants.ImpersonationStopSuccess, data),
+		}, nil
+	})
+
+	// PATCH /sessions/{id} - Rename or trust one of the caller's sessions
+	routeperm.Register(g, huma.Operation{
+		OperationID: "updateSession",
+		Method:      http.MethodPatch,
+		Path:        "/sessions/{id}",
+		Summary:     "Rename or trust a session",
+		Description: "Labels one of the caller's active sessions or marks its device as trusted for the trust period. A trusted device logs in without a login code and refreshes from any user agent or IP address. When login codes are enabled, trusting answers 403 and sends a code first; repeat the request with the code. Revoking the session clears the trust.",
+		Tags:        []string{"Authentication"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" doc:"Session ID"`
+		Body auth.UpdateSessionRequest
+	}) (*struct {
+		Body auth.SessionResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		session, err := h.svc.UpdateSession(userID, in.ID, in.Body, requestctx.ClientIP(ctx))
+		if err != nil {
+			return nil, humaError(err, constants.SessionUpdateFailed)
+		}
+		return &struct {
+			Body auth.SessionResponse
+		}{
+			Body: *response.Success(constants.SessionUpdateSuccess, session),
+		}, nil
+	})
+}
+
+// humaError converts a service error to the Huma error to answer with.
+// Internal and unexpected errors answer 500 with fallback, keeping their
+// details out of the response.
+func humaError(err error, fallback string) error {
+	if errors.Is(err, authz.ErrNotSuperAdmin) {
+		return huma.Error403Forbidden(err.Error())
+	}
+	if appErr, ok := apperrors.IsAppError(err); ok && appErr.Code != apperrors.CodeInternalServer {
+		return appErr.ToHumaError()
+	}
+	return huma.Error500InternalServerError(fallback)
+}
+
+// NewUserEvents registers the authentication history of a user on the
+// shared /v1/users group
+func NewUserEvents(users huma.API, svc service.Service) {
+	// GET /users/{id}/auth-events - A user's logins and password events
+	routeperm.Register(users, huma.Operation{
+		OperationID: "listUserAuthEvents",
+		Method:      http.MethodGet,
+		Path:        "/{id}/auth-events",
+		Summary:     "List a user's authentication history",
+		Description: "Lists the user's logins, refreshes, logouts and password reset events, newest first, with the client IP and user agent and whether they succeeded. Failed logins for unknown usernames are not recorded.",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct {
+		ID    uuid.UUID `path:"id" doc:"User ID"`
+		Page  int       `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit int       `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}) (*struct {
+		Body auth.AuthEventListResponse
+	}, error) {
+		data, err := svc.ListAuthEvents(ctx, in.ID, in.Page, in.Limit)
+		if err != nil {
+			return nil, humaError(err, constants.AuthEventListFailed)
+		}
+		return &struct {
+			Body auth.AuthEventListResponse
+		}{Body: *response.Success(constants.AuthEventListSuccess, data)}, nil
+	})
+}
+
+// NewJWKS registers the endpoint publishing the public keys that verify
+// access tokens, for services that check tokens without the signing secret.
+func NewJWKS(api huma.API, secrets jwtpkg.Secrets) {
+	// GET /.well-known/jwks.json
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getJWKS",
+		Method:      http.MethodGet,
+		Path:        "/.well-known/jwks.json",
+		Summary:     "Public keys verifying access tokens",
+		Description: "JSON Web Key Set (RFC 7517) of the RSA keys verifying RS256 access tokens, matched by the kid token header. Empty while access tokens are only signed with the shared HS256 secret.",
+		Tags:        []string{"Authentication"},
+	}, routeperm.Public, func(ctx context.Context, in *struct{}) (*struct {
+		CacheControl string `header:"Cache-Control"`
+		Body         jwtpkg.JWKSet
+	}, error) {
+		return &struct {
+			CacheControl string `header:"Cache-Control"`
+			Body         jwtpkg.JWKSet
+		}{
+			CacheControl: "public, max-age=300",
+			Body:         secrets.JWKS(),
 		}, nil
 	})
 }
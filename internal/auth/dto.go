@@ -1,44 +1,242 @@
 package auth
 
-import "backend-service-internpro/internal/pkg/response"
+import (
+	"strings"
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/user"
+
+	"github.com/google/uuid"
+)
 
 // Login
 type LoginRequest struct {
-	UsernameOrEmail string `json:"username_or_email" form:"username_or_email"`
-	Password        string `json:"password" form:"password"`
+	UsernameOrEmail string `json:"username_or_email" form:"username_or_email" example:"siti.rahma@smkn1sby.sch.id"`
+	Password        string `json:"password" form:"password" example:"Rahasia#2025"`
+	DeviceName      string `json:"device_name,omitempty" form:"device_name" maxLength:"100" example:"Laptop kantor" doc:"Optional label for this session, e.g. My laptop"`
+	Code            string `json:"code,omitempty" form:"code" doc:"Login code sent after the password when login codes are enabled"`
+	RefreshToken    string `json:"refresh_token,omitempty" form:"refresh_token" doc:"Refresh token of an earlier session on this device; a login from a trusted device needs no login code"`
+}
+
+// TokenTypeBearer is the scheme access tokens are sent with
+const TokenTypeBearer = "Bearer"
+
+// Tokens are the credentials of a session as issued at login, registration
+// and refresh
+type Tokens struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type" example:"Bearer"`
+	ExpiresIn        int64  `json:"expires_in" example:"900" doc:"Seconds until the access token expires"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in" example:"86400" doc:"Seconds until the refresh token expires"`
 }
 
 type LoginData struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	User         User   `json:"user"`
+	Tokens
+	User User `json:"user"`
 }
 
 type LoginResponse = response.ApiResponse
 
+// Register
+type RegisterRequest struct {
+	Username   string `json:"username" form:"username" example:"siti_rahma" doc:"Letters, numbers and underscores, 3 to 60 characters"`
+	Email      string `json:"email" form:"email" example:"siti.rahma@smkn1sby.sch.id"`
+	Fullname   string `json:"fullname" form:"fullname" maxLength:"120" example:"Siti Rahma"`
+	Password   string `json:"password" form:"password" example:"Rahasia#2025" doc:"At least 8 characters with upper and lower case letters, a number and a special character"`
+	Login      bool   `json:"login,omitempty" form:"login" doc:"Also log the new user in and return access/refresh tokens"`
+	DeviceName string `json:"device_name,omitempty" form:"device_name" maxLength:"100" doc:"Optional label for the session when login is set"`
+}
+
+type RegisterData struct {
+	ID               uuid.UUID `json:"id"`
+	AccessToken      string    `json:"access_token,omitempty"`
+	RefreshToken     string    `json:"refresh_token,omitempty"`
+	TokenType        string    `json:"token_type,omitempty"`
+	ExpiresIn        int64     `json:"expires_in,omitempty"`
+	RefreshExpiresIn int64     `json:"refresh_expires_in,omitempty"`
+}
+
+type RegisterResponse = response.ApiResponse
+
 // Refresh
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" form:"refresh_token"`
 }
 
+// RefreshData carries the rotated tokens. RefreshToken replaces the one
+// sent, which is revoked, and keeps its expiry unless sessions slide.
 type RefreshData struct {
-	AccessToken string `json:"access_token"`
+	Tokens
 }
 
 type RefreshResponse = response.ApiResponse
 
+// OAuthURLData is where to send the user to sign in with a provider. The
+// provider redirects back with state, which must be sent with the code.
+type OAuthURLData struct {
+	URL   string `json:"url" doc:"Provider sign-in page to redirect the user to"`
+	State string `json:"state" doc:"Opaque value the provider returns with the code; valid for 10 minutes"`
+}
+
+type OAuthURLResponse = response.ApiResponse
+
+// OAuthCallbackRequest carries what the provider redirected back with
+type OAuthCallbackRequest struct {
+	Code       string `json:"code" minLength:"1" doc:"Authorization code from the provider redirect"`
+	State      string `json:"state" minLength:"1" doc:"State from the provider redirect, as issued with the sign-in URL"`
+	DeviceName string `json:"device_name,omitempty" maxLength:"100" example:"Laptop kantor" doc:"Optional label for this session, e.g. My laptop"`
+}
+
 // Forgot password
+// The password reset requests identify the user by email or, when email is
+// empty, by username
 type ForgotRequest struct {
-	Email string `json:"email" form:"email"`
+	Email    string `json:"email,omitempty" form:"email" doc:"Registered email; give this or username"`
+	Username string `json:"username,omitempty" form:"username" doc:"Username, used when email is empty"`
+}
+type ResendOTPRequest struct {
+	Email    string `json:"email,omitempty" form:"email" example:"siti.rahma@smkn1sby.sch.id" doc:"Registered email; give this or username"`
+	Username string `json:"username,omitempty" form:"username" doc:"Username, used when email is empty"`
 }
 type VerifyOTPRequest struct {
-	Email string `json:"email" form:"email"`
-	OTP   string `json:"otp" form:"otp"`
+	Email    string `json:"email,omitempty" form:"email" doc:"Email the code was requested with; give this or username"`
+	Username string `json:"username,omitempty" form:"username" doc:"Username the code was requested with, used when email is empty"`
+	OTP      string `json:"otp" form:"otp"`
+}
+type VerifyEmailRequest struct {
+	Email string `json:"email" form:"email" example:"siti.rahma@smkn1sby.sch.id"`
+	OTP   string `json:"otp" form:"otp" doc:"Verification code sent to the email"`
+}
+type ResendVerificationRequest struct {
+	Email string `json:"email" form:"email" example:"siti.rahma@smkn1sby.sch.id"`
 }
 type ResetPasswordRequest struct {
-	Email       string `json:"email" form:"email"`
+	Email       string `json:"email,omitempty" form:"email" doc:"Email the code was requested with; give this or username"`
+	Username    string `json:"username,omitempty" form:"username" doc:"Username the code was requested with, used when email is empty"`
 	OTP         string `json:"otp" form:"otp"`
 	NewPassword string `json:"new_password" form:"new_password"`
 }
 
+// Identifier returns the email or, when it is empty, the username
+func (r ForgotRequest) Identifier() string { return identifier(r.Email, r.Username) }
+
+// Identifier returns the email or, when it is empty, the username
+func (r ResendOTPRequest) Identifier() string { return identifier(r.Email, r.Username) }
+
+// Identifier returns the email or, when it is empty, the username
+func (r VerifyOTPRequest) Identifier() string { return identifier(r.Email, r.Username) }
+
+// Identifier returns the email or, when it is empty, the username
+func (r ResetPasswordRequest) Identifier() string { return identifier(r.Email, r.Username) }
+
+func identifier(email, username string) string {
+	if strings.TrimSpace(email) != "" {
+		return email
+	}
+	return username
+}
+
+// Sessions
+type Session struct {
+	ID                uuid.UUID  `json:"id" doc:"Session ID"`
+	DeviceName        *string    `json:"device_name,omitempty" doc:"Label given by the user"`
+	UserAgent         string     `json:"user_agent" doc:"User agent that logged in"`
+	IP                string     `json:"ip" doc:"IP address that logged in"`
+	Trusted           bool       `json:"trusted" doc:"Whether the device is currently trusted"`
+	TrustedUntil      *time.Time `json:"trusted_until,omitempty" doc:"When the device trust expires"`
+	ExpiresAt         time.Time  `json:"expires_at" doc:"When the session expires"`
+	AbsoluteExpiresAt *time.Time `json:"absolute_expires_at,omitempty" doc:"Latest refreshing can extend the session to, when sessions slide"`
+	CreatedAt         time.Time  `json:"created_at" doc:"When the session was created"`
+	Current           bool       `json:"current" doc:"Whether the caller's access token belongs to this session"`
+}
+
+// AuthEventData is an entry of a user's authentication history
+type AuthEventData struct {
+	ID        uuid.UUID `json:"id" doc:"Event ID"`
+	Type      string    `json:"type" enum:"login,refresh,logout,password_reset_requested,password_reset" doc:"What happened"`
+	IP        string    `json:"ip" doc:"Client IP address"`
+	UserAgent string    `json:"user_agent" doc:"Client user agent"`
+	Success   bool      `json:"success" doc:"Whether the attempt succeeded"`
+	Reason    string    `json:"reason,omitempty" doc:"Why the attempt failed"`
+	CreatedAt time.Time `json:"created_at" doc:"When it happened"`
+}
+
+// AuthEventListData represents a page of a user's authentication history
+type AuthEventListData struct {
+	Events []AuthEventData `json:"events"`
+	Meta   Metadata        `json:"meta"`
+}
+
+// Metadata represents pagination metadata
+type Metadata struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	TotalPages int `json:"total_pages"`
+	TotalItems int `json:"total_items"`
+}
+
+type AuthEventListResponse = response.ApiResponse
+
+type UpdateSessionRequest struct {
+	DeviceName *string `json:"device_name,omitempty" maxLength:"100" doc:"New label; an empty string removes it"`
+	Trusted    *bool   `json:"trusted,omitempty" doc:"Trust the device for the trust period, or stop trusting it"`
+	Code       string  `json:"code,omitempty" doc:"Login code confirming the trust when login codes are enabled"`
+}
+
+type SessionResponse = response.ApiResponse
+
+type SessionListResponse = response.ApiResponse
+
 type BasicResponse = response.ApiResponse
+
+// LogoutAllData reports the sessions revoked by logging out everywhere
+type LogoutAllData struct {
+	RevokedSessions int64 `json:"revoked_sessions" doc:"Number of sessions revoked"`
+}
+
+// Access token revocation scopes, see RevokeTokenData
+const (
+	RevokeScopeToken = "token"
+	RevokeScopeUser  = "user"
+)
+
+// RevokeTokenRequest names the access tokens to revoke; exactly one of the
+// fields must be set
+type RevokeTokenRequest struct {
+	Token  string     `json:"token,omitempty" doc:"Access token to revoke"`
+	JTI    string     `json:"jti,omitempty" maxLength:"64" doc:"ID (jti claim) of the access token to revoke"`
+	UserID *uuid.UUID `json:"user_id,omitempty" doc:"Revoke every access token issued to the user so far"`
+}
+
+// RevokeTokenData reports the access tokens that were revoked
+type RevokeTokenData struct {
+	Scope  string     `json:"scope" enum:"token,user" doc:"Whether a single token or all of a user's tokens were revoked"`
+	JTI    string     `json:"jti,omitempty" doc:"ID of the revoked token"`
+	UserID *uuid.UUID `json:"user_id,omitempty" doc:"User whose tokens were revoked"`
+	Until  time.Time  `json:"until" doc:"When the revoked tokens expire on their own"`
+}
+
+type RevokeTokenResponse = response.ApiResponse
+
+// MeData is the profile of the authenticated user
+type MeData struct {
+	User  user.User `json:"user"`
+	Roles []string  `json:"roles" doc:"Slugs of the user's active roles"`
+}
+
+type MeResponse = response.ApiResponse
+
+// ImpersonationData is an access token issued when starting or stopping
+// impersonation. It comes without a refresh token; impersonation ends when
+// the token expires.
+type ImpersonationData struct {
+	AccessToken    string     `json:"access_token"`
+	TokenType      string     `json:"token_type" example:"Bearer"`
+	ExpiresIn      int64      `json:"expires_in" doc:"Seconds until the access token expires"`
+	UserID         uuid.UUID  `json:"user_id" doc:"User the token acts as"`
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty" doc:"Super admin impersonating the user; absent once impersonation stopped"`
+}
+
+type ImpersonationResponse = response.ApiResponse
@@ -7,22 +7,39 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `gorm:"type:char(36);primaryKey"`
-	Username     string    `gorm:"uniqueIndex;size:60;not null"`
-	Email        string    `gorm:"uniqueIndex;size:120;not null"`
-	Fullname     string    `gorm:"size:120;not null"`
-	PasswordHash string    `gorm:"size:255;not null"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID                  uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	Username            string     `gorm:"uniqueIndex;size:60;not null"`
+	Email               string     `gorm:"uniqueIndex;size:120;not null"`
+	Fullname            string     `gorm:"size:120;not null"`
+	PasswordHash        string     `gorm:"size:255;not null"`
+	Phone               *string    `gorm:"size:32"`
+	PreferredOTPChannel string     `gorm:"size:16;not null;default:email"`
+	EmailNotifications  bool       `gorm:"not null;default:false"`
+	EmailVerifiedAt     *time.Time // when the user proved they own Email
+	FailedLoginAttempts int        `gorm:"not null;default:0"` // wrong passwords since the last login or lockout
+	LockedUntil         *time.Time // logins are refused until then
+	IsActive            bool       `gorm:"not null;default:true"` // false once an admin deactivated the user
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
 }
 
+// OTP purposes
+const (
+	OTPPurposeForgotPassword = "forgot_password"
+	OTPPurposeVerifyEmail    = "verify_email"
+	// OTPPurposeLogin marks the codes asked for after the password of a
+	// login from a device that is not trusted
+	OTPPurposeLogin = "login"
+)
+
 type OTP struct {
 	ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
 	UserID    uuid.UUID `gorm:"type:char(36);index;not null"`
-	Code      string    `gorm:"size:6;not null"`
+	Code      string    `gorm:"size:64;not null"` // otp.Hash of the code, never the code itself
 	Purpose   string    `gorm:"size:32;not null"`
 	ExpiresAt time.Time `gorm:"not null"`
 	Used      bool      `gorm:"default:false"`
+	Attempts  int       `gorm:"not null;default:0"` // wrong codes given while it was live
 	CreatedAt time.Time
 }
 
@@ -35,4 +52,90 @@ type RefreshToken struct {
 	Revoked   bool      `gorm:"default:false"`
 	ExpiresAt time.Time `gorm:"not null"`
 	CreatedAt time.Time
+	// DeviceName is the label the user gave the session
+	DeviceName *string `gorm:"size:100"`
+	// TrustedUntil is set while the user trusts the device; revoking clears it
+	TrustedUntil *time.Time
+	// AbsoluteExpiresAt is the latest sliding expiry can extend the session
+	// to; sessions from before it was recorded are never extended
+	AbsoluteExpiresAt *time.Time
+}
+
+// IsTrusted reports whether the device is trusted at now
+func (rt *RefreshToken) IsTrusted(now time.Time) bool {
+	return !rt.Revoked && rt.TrustedUntil != nil && now.Before(*rt.TrustedUntil)
+}
+
+// ToSession converts RefreshToken to Session DTO
+func (rt *RefreshToken) ToSession(now time.Time) Session {
+	return Session{
+		ID:                rt.ID,
+		DeviceName:        rt.DeviceName,
+		UserAgent:         rt.UserAgent,
+		IP:                rt.IP,
+		Trusted:           rt.IsTrusted(now),
+		TrustedUntil:      rt.TrustedUntil,
+		ExpiresAt:         rt.ExpiresAt,
+		AbsoluteExpiresAt: rt.AbsoluteExpiresAt,
+		CreatedAt:         rt.CreatedAt,
+	}
+}
+
+// Auth event types, see AuthEvent
+const (
+	AuthEventLogin                  = "login"
+	AuthEventGoogleLogin            = "google_login"
+	AuthEventRefresh                = "refresh"
+	AuthEventLogout                 = "logout"
+	AuthEventPasswordResetRequested = "password_reset_requested"
+	AuthEventPasswordReset          = "password_reset"
+)
+
+// AuthEvent records a login, refresh, logout or password event of a user,
+// so an account's history can be looked up after the logs are gone
+type AuthEvent struct {
+	ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;index:idx_auth_events_user_created,priority:1"`
+	Type      string    `gorm:"size:32;not null"`
+	IP        string    `gorm:"size:64"`
+	UserAgent string    `gorm:"size:255"`
+	Success   bool      `gorm:"not null"`
+	Reason    string    `gorm:"size:50"` // why it failed, e.g. wrong_password; empty on success
+	CreatedAt time.Time `gorm:"index:idx_auth_events_user_created,priority:2;index:idx_auth_events_created_at"`
+}
+
+// TableName returns the table name for the AuthEvent
+func (AuthEvent) TableName() string {
+	return "auth_events"
+}
+
+// ToAuthEventData converts AuthEvent to AuthEventData DTO
+func (e *AuthEvent) ToAuthEventData() AuthEventData {
+	return AuthEventData{
+		ID:        e.ID,
+		Type:      e.Type,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		Success:   e.Success,
+		Reason:    e.Reason,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// UserIdentity links a user to an account at an external identity
+// provider, so they can sign in with it
+type UserIdentity struct {
+	ID       uuid.UUID `gorm:"type:char(36);primaryKey"`
+	UserID   uuid.UUID `gorm:"type:char(36);not null;index"`
+	Provider string    `gorm:"size:32;not null;uniqueIndex:idx_user_identities_provider_subject,priority:1"`
+	// Subject is the provider's ID of the account
+	Subject string `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject,priority:2"`
+	// Email is the address the provider gave when the identity was linked
+	Email     string `gorm:"size:120"`
+	CreatedAt time.Time
+}
+
+// TableName returns the table name for the UserIdentity
+func (UserIdentity) TableName() string {
+	return "user_identities"
 }
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenIsTrusted(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	until := now.Add(30 * 24 * time.Hour)
+	tests := []struct {
+		name string
+		rt   RefreshToken
+		at   time.Time
+		want bool
+	}{
+		{"never trusted", RefreshToken{}, now, false},
+		{"trusted", RefreshToken{TrustedUntil: &until}, now, true},
+		{"trust expired", RefreshToken{TrustedUntil: &until}, until, false},
+		{"revoked", RefreshToken{TrustedUntil: &until, Revoked: true}, now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rt.IsTrusted(tt.at); got != tt.want {
+				t.Errorf("IsTrusted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"backend-service-internpro/internal/auth"
+	"backend-service-internpro/internal/user"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -12,15 +14,68 @@ import (
 type Repository interface {
 	FindUserByUsernameOrEmail(uore string) (*auth.User, error)
 	FindUserByEmail(email string) (*auth.User, error)
+	FindUserByID(id uuid.UUID) (*auth.User, error)
+	// EmailExists and UsernameExists include soft-deleted users, whose
+	// identifiers stay reserved until released
+	EmailExists(email string) (bool, error)
+	UsernameExists(username string) (bool, error)
+	CreateUser(u *auth.User) error
 	CreateRefreshToken(rt *auth.RefreshToken) error
 	GetRefreshToken(hash string) (*auth.RefreshToken, error)
+	// FindRefreshToken returns the token with hash whether or not it is
+	// revoked or expired, so reuse of a rotated token can be detected
+	FindRefreshToken(hash string) (*auth.RefreshToken, error)
+	// RotateRefreshToken revokes the old token and stores next in its place.
+	// It fails with ErrTokenAlreadyRevoked when the old token was revoked
+	// concurrently, e.g. by a parallel refresh with the same token.
+	RotateRefreshToken(oldID uuid.UUID, next *auth.RefreshToken) error
+	// RevokeAllRefreshTokensByUser revokes every active session of the user
+	// and returns how many were revoked
+	RevokeAllRefreshTokensByUser(userID uuid.UUID) (int64, error)
+	// RevokeRefreshToken revokes the session and clears any device trust
 	RevokeRefreshToken(id uuid.UUID) error
+	// GetUserSession returns an active session of the user
+	GetUserSession(userID, id uuid.UUID) (*auth.RefreshToken, error)
+	// ListUserSessions returns the active sessions of the user, newest first
+	ListUserSessions(userID uuid.UUID) ([]auth.RefreshToken, error)
+	UpdateSession(id uuid.UUID, deviceName *string, trustedUntil *time.Time) error
 	MarkOTPUsed(id uuid.UUID) error
-	FindValidOTP(email, code, purpose string, now time.Time) (*auth.OTP, error)
+	// FindValidOTP returns the user's live code for purpose with codeHash
+	FindValidOTP(userID uuid.UUID, codeHash, purpose string, now time.Time) (*auth.OTP, error)
 	SaveOTP(o *auth.OTP) error
+	// RecordOTPFailure counts a wrong code against the user's live codes for
+	// purpose and invalidates those given maxAttempts wrong codes; exhausted
+	// reports whether any was invalidated
+	RecordOTPFailure(userID uuid.UUID, purpose string, maxAttempts int, now time.Time) (exhausted bool, err error)
+	// InvalidateOTPs marks the user's unused codes for purpose as used
+	InvalidateOTPs(userID uuid.UUID, purpose string) error
+	// LatestOTPCreatedAt returns when the user's newest code for purpose was
+	// created, or nil when there is none
+	LatestOTPCreatedAt(userID uuid.UUID, purpose string) (*time.Time, error)
 	UpdateUserPassword(userID uuid.UUID, passwordHash string) error
+	// RecordFailedLogin counts a wrong password against the user. Once the
+	// count reaches maxFailures the account is locked until lockUntil, the
+	// count starts over and locked is true.
+	RecordFailedLogin(userID uuid.UUID, maxFailures int, lockUntil time.Time) (locked bool, err error)
+	// ResetFailedLogins clears the failure count and any lock
+	ResetFailedLogins(userID uuid.UUID) error
+	// VerifyEmail marks the user's email verified and the OTP used
+	VerifyEmail(userID, otpID uuid.UUID) error
+	// FindUserIdentity returns the link of the provider's account subject
+	FindUserIdentity(provider, subject string) (*auth.UserIdentity, error)
+	CreateUserIdentity(i *auth.UserIdentity) error
+	// CreateUserWithIdentity creates a user signing up through a provider
+	// together with the link to their account there
+	CreateUserWithIdentity(u *auth.User, i *auth.UserIdentity) error
+	CreateAuthEvent(e *auth.AuthEvent) error
+	// ListAuthEvents returns a page of the user's auth events, newest first
+	ListAuthEvents(userID uuid.UUID, offset, limit int) ([]auth.AuthEvent, int64, error)
 }
 
+// ErrTokenAlreadyRevoked is returned by RotateRefreshToken when the token
+// was revoked before it could be rotated
+var ErrTokenAlreadyRevoked = errors.New("refresh token already revoked")
+
 type repo struct{ db *gorm.DB }
 
 func New(db *gorm.DB) Repository { return &repo{db} }
@@ -28,7 +83,7 @@ func New(db *gorm.DB) Repository { return &repo{db} }
 func (r *repo) FindUserByUsernameOrEmail(uore string) (*auth.User, error) {
 	var u auth.User
 	if err := r.db.
-		Where("username = ? OR email = ?", uore, uore).
+		Where("(username = ? OR email = ?) AND deleted_at IS NULL", uore, user.NormalizeEmail(uore)).
 		First(&u).Error; err != nil {
 		return nil, err
 	}
@@ -37,12 +92,34 @@ func (r *repo) FindUserByUsernameOrEmail(uore string) (*auth.User, error) {
 
 func (r *repo) FindUserByEmail(email string) (*auth.User, error) {
 	var u auth.User
-	if err := r.db.Where("email = ?", email).First(&u).Error; err != nil {
+	if err := r.db.Where("email = ? AND deleted_at IS NULL", user.NormalizeEmail(email)).First(&u).Error; err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
+func (r *repo) FindUserByID(id uuid.UUID) (*auth.User, error) {
+	var u auth.User
+	if err := r.db.Where("id = ? AND deleted_at IS NULL", id).First(&u).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *repo) EmailExists(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&auth.User{}).Where("email = ?", user.NormalizeEmail(email)).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *repo) UsernameExists(username string) (bool, error) {
+	var count int64
+	err := r.db.Model(&auth.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *repo) CreateUser(u *auth.User) error { return r.db.Create(u).Error }
+
 func (r *repo) CreateRefreshToken(rt *auth.RefreshToken) error { return r.db.Create(rt).Error }
 
 func (r *repo) GetRefreshToken(hash string) (*auth.RefreshToken, error) {
@@ -54,22 +131,69 @@ func (r *repo) GetRefreshToken(hash string) (*auth.RefreshToken, error) {
 	return &rt, nil
 }
 
+func (r *repo) FindRefreshToken(hash string) (*auth.RefreshToken, error) {
+	var rt auth.RefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&rt).Error; err != nil {
+		return nil, err
+	}
+	return &rt, nil
+}
+
+func (r *repo) RotateRefreshToken(oldID uuid.UUID, next *auth.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&auth.RefreshToken{}).Where("id = ? AND revoked = 0", oldID).
+			Updates(map[string]interface{}{"revoked": true, "trusted_until": nil})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTokenAlreadyRevoked
+		}
+		return tx.Create(next).Error
+	})
+}
+
+func (r *repo) RevokeAllRefreshTokensByUser(userID uuid.UUID) (int64, error) {
+	res := r.db.Model(&auth.RefreshToken{}).Where("user_id = ? AND revoked = 0", userID).
+		Updates(map[string]interface{}{"revoked": true, "trusted_until": nil})
+	return res.RowsAffected, res.Error
+}
+
 func (r *repo) RevokeRefreshToken(id uuid.UUID) error {
-	return r.db.Model(&auth.RefreshToken{}).Where("id = ?", id).Update("revoked", true).Error
+	return r.db.Model(&auth.RefreshToken{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"revoked": true, "trusted_until": nil}).Error
+}
+
+func (r *repo) GetUserSession(userID, id uuid.UUID) (*auth.RefreshToken, error) {
+	var rt auth.RefreshToken
+	if err := r.db.Where("id = ? AND user_id = ? AND revoked = 0 AND expires_at > NOW()", id, userID).
+		First(&rt).Error; err != nil {
+		return nil, err
+	}
+	return &rt, nil
+}
+
+func (r *repo) ListUserSessions(userID uuid.UUID) ([]auth.RefreshToken, error) {
+	var tokens []auth.RefreshToken
+	err := r.db.Where("user_id = ? AND revoked = 0 AND expires_at > NOW()", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *repo) UpdateSession(id uuid.UUID, deviceName *string, trustedUntil *time.Time) error {
+	return r.db.Model(&auth.RefreshToken{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"device_name": deviceName, "trusted_until": trustedUntil}).Error
 }
 
 func (r *repo) MarkOTPUsed(id uuid.UUID) error {
 	return r.db.Model(&auth.OTP{}).Where("id = ?", id).Update("used", true).Error
 }
 
-func (r *repo) FindValidOTP(email, code, purpose string, now time.Time) (*auth.OTP, error) {
-	var u auth.User
-	if err := r.db.Select("id").Where("email = ?", email).First(&u).Error; err != nil {
-		return nil, err
-	}
+func (r *repo) FindValidOTP(userID uuid.UUID, codeHash, purpose string, now time.Time) (*auth.OTP, error) {
 	var o auth.OTP
 	if err := r.db.Where("user_id = ? AND code = ? AND purpose = ? AND used = 0 AND expires_at > ?",
-		u.ID, code, purpose, now).First(&o).Error; err != nil {
+		userID, codeHash, purpose, now).First(&o).Error; err != nil {
 		return nil, err
 	}
 	return &o, nil
@@ -77,8 +201,116 @@ func (r *repo) FindValidOTP(email, code, purpose string, now time.Time) (*auth.O
 
 func (r *repo) SaveOTP(o *auth.OTP) error { return r.db.Create(o).Error }
 
+func (r *repo) RecordOTPFailure(userID uuid.UUID, purpose string, maxAttempts int, now time.Time) (bool, error) {
+	exhausted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		live := tx.Model(&auth.OTP{}).Where("user_id = ? AND purpose = ? AND used = 0 AND expires_at > ?", userID, purpose, now)
+		if err := live.Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&auth.OTP{}).
+			Where("user_id = ? AND purpose = ? AND used = 0 AND attempts >= ?", userID, purpose, maxAttempts).
+			Update("used", true)
+		exhausted = res.RowsAffected > 0
+		return res.Error
+	})
+	return exhausted, err
+}
+
+func (r *repo) InvalidateOTPs(userID uuid.UUID, purpose string) error {
+	return r.db.Model(&auth.OTP{}).
+		Where("user_id = ? AND purpose = ? AND used = 0", userID, purpose).
+		Update("used", true).Error
+}
+
+func (r *repo) LatestOTPCreatedAt(userID uuid.UUID, purpose string) (*time.Time, error) {
+	var o auth.OTP
+	err := r.db.Select("created_at").
+		Where("user_id = ? AND purpose = ?", userID, purpose).
+		Order("created_at DESC").
+		First(&o).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o.CreatedAt, nil
+}
+
 func (r *repo) UpdateUserPassword(userID uuid.UUID, passwordHash string) error {
 	return r.db.Model(&auth.User{}).
 		Where("id = ?", userID).
 		Update("password_hash", passwordHash).Error
 }
+
+func (r *repo) RecordFailedLogin(userID uuid.UUID, maxFailures int, lockUntil time.Time) (bool, error) {
+	locked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&auth.User{}).Where("id = ?", userID).
+			Update("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error; err != nil {
+			return err
+		}
+		var u auth.User
+		if err := tx.Select("failed_login_attempts").Where("id = ?", userID).First(&u).Error; err != nil {
+			return err
+		}
+		if u.FailedLoginAttempts < maxFailures {
+			return nil
+		}
+		locked = true
+		return tx.Model(&auth.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"failed_login_attempts": 0, "locked_until": lockUntil}).Error
+	})
+	return locked, err
+}
+
+func (r *repo) ResetFailedLogins(userID uuid.UUID) error {
+	return r.db.Model(&auth.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"failed_login_attempts": 0, "locked_until": nil}).Error
+}
+
+func (r *repo) VerifyEmail(userID, otpID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&auth.User{}).Where("id = ? AND email_verified_at IS NULL", userID).
+			Update("email_verified_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Model(&auth.OTP{}).Where("id = ?", otpID).Update("used", true).Error
+	})
+}
+
+func (r *repo) FindUserIdentity(provider, subject string) (*auth.UserIdentity, error) {
+	var i auth.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&i).Error; err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *repo) CreateUserIdentity(i *auth.UserIdentity) error { return r.db.Create(i).Error }
+
+func (r *repo) CreateUserWithIdentity(u *auth.User, i *auth.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		return tx.Create(i).Error
+	})
+}
+
+func (r *repo) CreateAuthEvent(e *auth.AuthEvent) error { return r.db.Create(e).Error }
+
+func (r *repo) ListAuthEvents(userID uuid.UUID, offset, limit int) ([]auth.AuthEvent, int64, error) {
+	var events []auth.AuthEvent
+	var total int64
+
+	query := r.db.Model(&auth.AuthEvent{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
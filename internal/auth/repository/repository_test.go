@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/auth"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recorder returns a repository on a database that renders statements
+// without a server, and the updates it ran
+func recorder(t *testing.T) (*repo, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []string
+	err = db.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &repo{db: db}, &statements
+}
+
+func TestRevokeClearsTrust(t *testing.T) {
+	r, statements := recorder(t)
+	if err := r.RevokeRefreshToken(uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if len(*statements) != 1 {
+		t.Fatalf("ran %d updates, want 1", len(*statements))
+	}
+	update := (*statements)[0]
+	for _, want := range []string{"`revoked`=true", "`trusted_until`=NULL"} {
+		if !strings.Contains(update, want) {
+			t.Errorf("update does not contain %q:\n%s", want, update)
+		}
+	}
+}
+
+func TestRecordOTPFailure(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&auth.OTP{}); err != nil {
+		t.Fatal(err)
+	}
+	r := &repo{db}
+
+	const maxAttempts = 3
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	live := &auth.OTP{ID: uuid.New(), UserID: userID, Code: "live", Purpose: auth.OTPPurposeForgotPassword, ExpiresAt: now.Add(10 * time.Minute)}
+	// Neither is counted: one has another purpose, the other expired
+	other := &auth.OTP{ID: uuid.New(), UserID: userID, Code: "other", Purpose: auth.OTPPurposeVerifyEmail, ExpiresAt: now.Add(10 * time.Minute)}
+	expired := &auth.OTP{ID: uuid.New(), UserID: userID, Code: "expired", Purpose: auth.OTPPurposeForgotPassword, ExpiresAt: now.Add(-time.Minute)}
+	if err := db.Create([]*auth.OTP{live, other, expired}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		exhausted, err := r.RecordOTPFailure(userID, auth.OTPPurposeForgotPassword, maxAttempts, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Exactly the last allowed failure invalidates the code
+		if want := attempt == maxAttempts; exhausted != want {
+			t.Errorf("failure %d: exhausted = %v, want %v", attempt, exhausted, want)
+		}
+	}
+	// Nothing is left to invalidate
+	if exhausted, err := r.RecordOTPFailure(userID, auth.OTPPurposeForgotPassword, maxAttempts, now); err != nil || exhausted {
+		t.Errorf("failure after the code was invalidated: exhausted = %v, %v, want false", exhausted, err)
+	}
+
+	want := map[uuid.UUID]struct {
+		attempts int
+		used     bool
+	}{
+		live.ID:    {maxAttempts, true},
+		other.ID:   {0, false},
+		expired.ID: {0, false},
+	}
+	var stored []auth.OTP
+	if err := db.Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range stored {
+		if w := want[o.ID]; o.Attempts != w.attempts || o.Used != w.used {
+			t.Errorf("%s code: %d attempts, used %v, want %d, %v", o.Code, o.Attempts, o.Used, w.attempts, w.used)
+		}
+	}
+}
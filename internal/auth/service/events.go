@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"backend-service-internpro/internal/auth"
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Reasons recorded with failed auth events
+const (
+	reasonAccountLocked    = "account_locked"
+	reasonAccountDisabled  = "account_disabled"
+	reasonWrongPassword    = "wrong_password"
+	reasonEmailNotVerified = "email_not_verified"
+	reasonTokenReused      = "token_reused"
+	reasonTokenExpired     = "token_expired"
+	reasonClientDrift      = "client_drift"
+	reasonInvalidCode      = "invalid_code"
+)
+
+// recordEvent adds an entry to the user's auth history. It is best-effort:
+// failures are only logged, so a broken audit table never blocks a login.
+func (s *service) recordEvent(userID uuid.UUID, eventType string, success bool, reason, ua, ip string) {
+	e := &auth.AuthEvent{
+		ID:        s.ids.New(),
+		UserID:    userID,
+		Type:      eventType,
+		IP:        truncate(ip, 64),
+		UserAgent: truncate(ua, 255),
+		Success:   success,
+		Reason:    reason,
+		CreatedAt: s.clock.Now(),
+	}
+	if err := s.repo.CreateAuthEvent(e); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to record auth event", err, "user_id", userID.String(), "type", eventType)
+	}
+}
+
+func (s *service) ListAuthEvents(_ context.Context, userID uuid.UUID, page, limit int) (*auth.AuthEventListData, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	events, total, err := s.repo.ListAuthEvents(userID, (page-1)*limit, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auth events: %w", err)
+	}
+	data := &auth.AuthEventListData{
+		Events: make([]auth.AuthEventData, len(events)),
+		Meta: auth.Metadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+			TotalItems: int(total),
+		},
+	}
+	for i := range events {
+		data.Events[i] = events[i].ToAuthEventData()
+	}
+	return data, nil
+}
+
+// truncate shortens s to at most n bytes, as stored by its column
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
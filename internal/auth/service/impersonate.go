@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend-service-internpro/internal/auth"
+	"backend-service-internpro/internal/pkg/authz"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (s *service) Impersonate(ctx context.Context, claims *jwtpkg.Claims, targetID uuid.UUID) (*auth.ImpersonationData, error) {
+	if s.roles == nil || s.users == nil {
+		return nil, apperrors.Forbidden("impersonation is not available")
+	}
+	if claims.ImpersonatorID != "" {
+		return nil, apperrors.Forbidden("stop impersonating before impersonating another user")
+	}
+	actorID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, apperrors.Unauthorized()
+	}
+	if targetID == actorID {
+		return nil, apperrors.ValidationFailed("cannot impersonate yourself")
+	}
+
+	target, err := s.users.GetByID(ctx, targetID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.UserNotFound()
+	} else if err != nil {
+		return nil, apperrors.InternalServer("failed to get user")
+	}
+	if !target.IsActive {
+		return nil, apperrors.AccountDisabled()
+	}
+	targetIsSuperAdmin, err := s.roles.CheckUserRole(ctx, targetID, authz.RoleSuperAdmin)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to check user role")
+	}
+	if targetIsSuperAdmin {
+		return nil, apperrors.Forbidden("super admins cannot be impersonated")
+	}
+
+	// The admin's session is kept, so logging it out also ends impersonation
+	token, err := s.signAccess(targetID, jwtpkg.AccessClaims{
+		SessionID:      claims.SessionID,
+		ImpersonatorID: actorID.String(),
+	}, s.impTTL)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to generate access token")
+	}
+	logger.Global().Auth().LogSecurityEvent("impersonation_started", "", requestctx.ClientIP(ctx),
+		"user "+targetID.String()+" impersonated by "+actorID.String())
+	return &auth.ImpersonationData{
+		AccessToken:    token,
+		TokenType:      auth.TokenTypeBearer,
+		ExpiresIn:      int64(s.impTTL / time.Second),
+		UserID:         targetID,
+		ImpersonatorID: &actorID,
+	}, nil
+}
+
+func (s *service) StopImpersonation(ctx context.Context, claims *jwtpkg.Claims) (*auth.ImpersonationData, error) {
+	if claims.ImpersonatorID == "" {
+		return nil, apperrors.ValidationFailed("the token does not impersonate anyone")
+	}
+	adminID, err := uuid.Parse(claims.ImpersonatorID)
+	if err != nil {
+		return nil, apperrors.Unauthorized()
+	}
+	if s.roles == nil || s.users == nil {
+		return nil, apperrors.Forbidden("impersonation is not available")
+	}
+
+	// The impersonation token is done with either way
+	if s.revoked != nil && claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.revoked.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			logger.Global().Auth().ErrorWithErr("failed to revoke impersonation token", err, "jti", claims.ID)
+		}
+	}
+	// Deleted or demoted admins get no token back
+	if _, err := s.users.GetByID(ctx, adminID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.Unauthorized()
+	} else if err != nil {
+		return nil, apperrors.InternalServer("failed to get user")
+	}
+	if err := authz.RequireSuperAdmin(ctx, s.roles, adminID); err != nil {
+		return nil, err
+	}
+
+	token, err := s.signAccess(adminID, jwtpkg.AccessClaims{SessionID: claims.SessionID}, s.accessTTL)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to generate access token")
+	}
+	logger.Global().Auth().LogSecurityEvent("impersonation_stopped", "", requestctx.ClientIP(ctx),
+		"user "+claims.UserID+" no longer impersonated by "+adminID.String())
+	return &auth.ImpersonationData{
+		AccessToken: token,
+		TokenType:   auth.TokenTypeBearer,
+		ExpiresIn:   int64(s.accessTTL / time.Second),
+		UserID:      adminID,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"backend-service-internpro/internal/auth"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (s *service) Me(ctx context.Context, userID uuid.UUID) (*auth.MeData, error) {
+	if s.users == nil {
+		return nil, apperrors.InternalServer("user profiles are not available")
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.UserNotFound()
+	}
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to get user")
+	}
+
+	data := &auth.MeData{User: u.ToUser(), Roles: []string{}}
+	if s.roles != nil {
+		roles, err := s.roles.GetUserRoleSlugs(ctx, userID)
+		if err != nil {
+			logger.Global().Auth().ErrorWithErr("failed to get user roles", err, "user_id", userID.String())
+			return nil, apperrors.InternalServer("failed to get user roles")
+		}
+		if roles != nil {
+			data.Roles = roles
+		}
+	}
+	return data, nil
+}
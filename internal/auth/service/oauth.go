@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"backend-service-internpro/internal/auth"
+	"backend-service-internpro/internal/pkg/constants"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/pkg/oauth"
+	"backend-service-internpro/internal/user"
+
+	"gorm.io/gorm"
+)
+
+// oauthStateAudience scopes state tokens so they cannot be used as
+// anything else
+const oauthStateAudience = "oauth_state"
+
+// oauthStateTTL is how long the user has to sign in at the provider
+const oauthStateTTL = 10 * time.Minute
+
+// Provisioned usernames get a suffix when taken; after this many tries the
+// sign-in fails instead
+const maxUsernameAttempts = 5
+
+func (s *service) GoogleAuthURL() (*auth.OAuthURLData, error) {
+	if s.google == nil {
+		return nil, apperrors.Forbidden("google sign-in is not available")
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to generate nonce")
+	}
+	// The state is signed so the callback needs no server-side storage; it
+	// carries the nonce the ID token must echo
+	state, err := jwtpkg.GenerateScoped(oauthStateAudience, oauth.ProviderGoogle, nonce, s.secrets.Refresh, oauthStateTTL)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to generate state")
+	}
+	return &auth.OAuthURLData{URL: s.google.AuthURL(state, nonce), State: state}, nil
+}
+
+func (s *service) GoogleLogin(ctx context.Context, req auth.OAuthCallbackRequest, ua, ip string) (*auth.Tokens, error) {
+	if s.google == nil {
+		return nil, apperrors.Forbidden("google sign-in is not available")
+	}
+	state, err := jwtpkg.ParseScoped(req.State, oauthStateAudience, s.secrets.Refresh)
+	if err != nil || state.Subject != oauth.ProviderGoogle {
+		return nil, apperrors.ValidationFailed("state is invalid or expired")
+	}
+
+	id, err := s.google.Exchange(ctx, strings.TrimSpace(req.Code), state.Value)
+	if errors.Is(err, oauth.ErrCodeRejected) || errors.Is(err, oauth.ErrInvalidIDToken) {
+		logger.Global().Auth().LogSecurityEvent("oauth_login_failed", "", ip, err.Error())
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "Google sign-in failed, please try again")
+	}
+	if err != nil {
+		logger.Global().Auth().ErrorWithErr("google code exchange failed", err)
+		return nil, apperrors.InternalServer("failed to exchange authorization code")
+	}
+
+	u, err := s.identityUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLogin(u, auth.AuthEventGoogleLogin, ua, ip); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(u.ID, req.DeviceName, ua, ip)
+	if err != nil {
+		return nil, err
+	}
+	s.recordEvent(u.ID, auth.AuthEventGoogleLogin, true, "", ua, ip)
+	return tokens, nil
+}
+
+// identityUser returns the user linked to the provider account. An unlinked
+// account is linked to the user with its email or, with auto-provisioning,
+// to a new user; both need the provider to have verified the email.
+func (s *service) identityUser(id *oauth.Identity) (*auth.User, error) {
+	link, err := s.repo.FindUserIdentity(id.Provider, id.Subject)
+	switch {
+	case err == nil:
+		u, err := s.repo.FindUserByID(link.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.Forbidden("the account linked to this google account was deleted")
+		}
+		if err != nil {
+			return nil, apperrors.InternalServer("failed to get user")
+		}
+		return u, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, apperrors.InternalServer("failed to get identity")
+	}
+
+	if id.Email == "" || !id.EmailVerified {
+		return nil, apperrors.Forbidden("the google account has no verified email")
+	}
+	email := user.NormalizeEmail(id.Email)
+	link = &auth.UserIdentity{
+		ID:       s.ids.New(),
+		Provider: id.Provider,
+		Subject:  id.Subject,
+		Email:    email,
+	}
+
+	u, err := s.repo.FindUserByEmail(email)
+	if err == nil {
+		link.UserID = u.ID
+		if err := s.repo.CreateUserIdentity(link); err != nil {
+			return nil, apperrors.InternalServer("failed to link identity")
+		}
+		logger.Global().Auth().LogSecurityEvent("identity_linked", email, "", id.Provider+" account linked to user "+u.ID.String())
+		return u, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.InternalServer("failed to get user")
+	}
+	if !s.autoProvision {
+		return nil, apperrors.Forbidden("no account uses the email of this google account")
+	}
+	return s.provisionUser(id, link)
+}
+
+// provisionUser creates a verified user for the provider account, with a
+// username taken from the email and a random password the user can only
+// replace through a password reset
+func (s *service) provisionUser(id *oauth.Identity, link *auth.UserIdentity) (*auth.User, error) {
+	username, err := s.freeUsername(link.Email)
+	if err != nil {
+		return nil, err
+	}
+	password, err := randomToken()
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to generate password")
+	}
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to hash password")
+	}
+	fullname := strings.TrimSpace(id.Name)
+	if fullname == "" {
+		fullname = username
+	}
+	now := s.clock.Now()
+
+	u := &auth.User{
+		ID:                  s.ids.New(),
+		Username:            username,
+		Email:               link.Email,
+		Fullname:            clip(fullname, 120),
+		PasswordHash:        hash,
+		PreferredOTPChannel: string(notifier.ChannelEmail),
+		EmailVerifiedAt:     &now,
+		IsActive:            true,
+	}
+	link.UserID = u.ID
+	if err := s.repo.CreateUserWithIdentity(u, link); err != nil {
+		// Lost a race with a registration or another first sign-in
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, apperrors.Conflict(constants.EmailAlreadyExists)
+		}
+		return nil, apperrors.InternalServer("failed to create user")
+	}
+	if s.userEvents != nil {
+		// Self-registered, so the user is their own actor
+		s.userEvents.PublishUserChanged(context.Background(), user.UserChangedEvent{UserID: u.ID, Change: user.ChangeCreated, ActorID: u.ID})
+	}
+	return u, nil
+}
+
+// freeUsername derives an unused username from the local part of email
+func (s *service) freeUsername(email string) (string, error) {
+	local, _, _ := strings.Cut(email, "@")
+	base := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, local)
+	base = clip(base, 50)
+	if len(base) < 3 {
+		base = "user_" + base
+	}
+
+	candidate := base
+	for i := 0; i < maxUsernameAttempts; i++ {
+		taken, err := s.repo.UsernameExists(candidate)
+		if err != nil {
+			return "", apperrors.InternalServer("failed to check username")
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = base + "_" + s.ids.New().String()[:6]
+	}
+	return "", apperrors.Conflict(constants.UsernameExists)
+}
+
+// clip shortens s to n characters, unlike truncate never splitting one
+func clip(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// randomToken returns 32 random bytes, base64url encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"backend-service-internpro/internal/auth"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) RevokeToken(ctx context.Context, actorID uuid.UUID, req auth.RevokeTokenRequest) (*auth.RevokeTokenData, error) {
+	if s.revoked == nil {
+		return nil, apperrors.Forbidden("token revocation is not available")
+	}
+
+	token := strings.TrimSpace(req.Token)
+	jti := strings.TrimSpace(req.JTI)
+	set := 0
+	for _, given := range []bool{token != "", jti != "", req.UserID != nil} {
+		if given {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, apperrors.ValidationFailed("exactly one of token, jti and user_id is required")
+	}
+
+	var err error
+	now := s.clock.Now()
+	data := &auth.RevokeTokenData{Scope: auth.RevokeScopeToken, Until: now.Add(s.accessTTL)}
+	switch {
+	case req.UserID != nil:
+		data.Scope = auth.RevokeScopeUser
+		data.UserID = req.UserID
+		err = s.revoked.RevokeUser(ctx, req.UserID.String(), now, data.Until)
+	case token != "":
+		claims, perr := jwtpkg.ParseAccess(token, s.secrets)
+		if errors.Is(perr, jwtpkg.ErrTokenExpired) {
+			return nil, apperrors.ValidationFailed("token has already expired")
+		}
+		if perr != nil {
+			return nil, apperrors.ValidationFailed("token is invalid")
+		}
+		// Tokens issued before they had an ID can only be revoked with
+		// their user
+		if claims.ID == "" {
+			return nil, apperrors.ValidationFailed("token has no ID, revoke its user instead")
+		}
+		data.JTI = claims.ID
+		if claims.ExpiresAt != nil {
+			data.Until = claims.ExpiresAt.Time
+		}
+		err = s.revoked.RevokeToken(ctx, data.JTI, data.Until)
+	default:
+		// Without the token its expiry is unknown; none outlives accessTTL
+		data.JTI = jti
+		err = s.revoked.RevokeToken(ctx, jti, data.Until)
+	}
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to revoke token")
+	}
+
+	target := "jti " + data.JTI
+	if data.UserID != nil {
+		target = "user " + data.UserID.String()
+	}
+	logger.Global().Auth().LogSecurityEvent("access_token_revoked", "", "", "access tokens of "+target+" revoked by "+actorID.String())
+	return data, nil
+}
+
+// revokeUserTokens rejects the access tokens issued to the user so far.
+// Failures are only logged; the tokens expire within accessTTL anyway.
+func (s *service) revokeUserTokens(userID uuid.UUID) {
+	if s.revoked == nil {
+		return
+	}
+	now := s.clock.Now()
+	if err := s.revoked.RevokeUser(context.Background(), userID.String(), now, now.Add(s.accessTTL)); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to revoke access tokens", err, "user_id", userID.String())
+	}
+}
+
+// revokeSessionTokens rejects the access tokens issued with the session so
+// far, like revokeUserTokens
+func (s *service) revokeSessionTokens(sessionID uuid.UUID) {
+	if s.revoked == nil {
+		return
+	}
+	now := s.clock.Now()
+	if err := s.revoked.RevokeSession(context.Background(), sessionID.String(), now, now.Add(s.accessTTL)); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to revoke access tokens", err, "session_id", sessionID.String())
+	}
+}
@@ -1,193 +1,1229 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
 	"time"
 
 	"backend-service-internpro/internal/auth"
 	"backend-service-internpro/internal/auth/repository"
+	"backend-service-internpro/internal/pkg/attempts"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/constants"
 	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/hasher"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/jobs"
 	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/pkg/oauth"
 	"backend-service-internpro/internal/pkg/otp"
+	"backend-service-internpro/internal/pkg/revocation"
 	"backend-service-internpro/internal/pkg/validator"
+	"backend-service-internpro/internal/user"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// otpMessage is the text sent with an OTP code; body is a format taking the
+// code and email the HTML version
+type otpMessage struct {
+	subject string
+	body    string
+	email   *template.Template
+}
+
+var forgotPasswordMessage = otpMessage{
+	subject: "Kode OTP Reset Password",
+	body:    "Kode OTP reset password Anda adalah %s. Kode berlaku selama 10 menit. Jangan berikan kode ini kepada siapa pun.",
+	email: template.Must(template.New("otp").Parse(`<p>Halo {{.Name}},</p>
+<p>Kode OTP reset password Anda adalah:</p>
+<p style="font-size:24px;font-weight:bold;letter-spacing:4px">{{.Code}}</p>
+<p>Kode berlaku selama 10 menit. Jangan berikan kode ini kepada siapa pun.</p>
+<p>Jika Anda tidak meminta reset password, abaikan email ini.</p>
+`)),
+}
+
+var loginMessage = otpMessage{
+	subject: "Kode Login",
+	body:    "Kode login Anda adalah %s. Kode berlaku selama 10 menit. Jangan berikan kode ini kepada siapa pun.",
+	email: template.Must(template.New("login").Parse(`<p>Halo {{.Name}},</p>
+<p>Kode login Anda adalah:</p>
+<p style="font-size:24px;font-weight:bold;letter-spacing:4px">{{.Code}}</p>
+<p>Kode berlaku selama 10 menit. Jangan berikan kode ini kepada siapa pun.</p>
+<p>Jika Anda tidak sedang login, segera ganti password Anda.</p>
+`)),
+}
+
+var verifyEmailMessage = otpMessage{
+	subject: "Kode Verifikasi Email",
+	body:    "Kode verifikasi email Anda adalah %s. Kode berlaku selama 24 jam. Jangan berikan kode ini kepada siapa pun.",
+	email: template.Must(template.New("verify").Parse(`<p>Halo {{.Name}},</p>
+<p>Akun Anda telah dibuat. Kode verifikasi email Anda adalah:</p>
+<p style="font-size:24px;font-weight:bold;letter-spacing:4px">{{.Code}}</p>
+<p>Kode berlaku selama 24 jam. Jangan berikan kode ini kepada siapa pun.</p>
+<p>Jika Anda tidak merasa membuat akun, abaikan email ini.</p>
+`)),
+}
+
+const (
+	forgotPasswordOTPTTL = 10 * time.Minute
+	verifyEmailOTPTTL    = 24 * time.Hour
+	loginOTPTTL          = 10 * time.Minute
+)
+
+// DefaultImpersonationTTL keeps impersonation tokens short-lived, since
+// they cannot be refreshed
+const DefaultImpersonationTTL = 15 * time.Minute
+
+// DefaultRefreshMaxAge is how long after login a sliding session ends
+// however often it is refreshed
+const DefaultRefreshMaxAge = 30 * 24 * time.Hour
+
+// DefaultOTPResendCooldown is how long Forgot and ResendOTP wait after the
+// last code by default
+const DefaultOTPResendCooldown = time.Minute
+
+// Verification codes can be resent this many times per email within the
+// window, so the endpoint cannot be used to flood an inbox
+const (
+	maxVerificationResends   = 3
+	verificationResendWindow = time.Hour
 )
 
 type Service interface {
-	Login(uore, password, ua, ip string) (access, refresh string, err error)
-	Refresh(refreshToken, ua, ip string) (access string, err error)
-	Logout(refreshToken string) error
-	Forgot(email string) error
-	VerifyOTP(email, code string) error
-	ResetPassword(email, code, newPassword string) error
+	// Register creates a user and, when req.Login is set, logs them in
+	Register(req auth.RegisterRequest, ua, ip string) (*auth.RegisterData, error)
+	// Login checks the password and, with Config.LoginCodes, the login code
+	// of a device that is not trusted, then starts a session
+	Login(req auth.LoginRequest, ua, ip string) (*auth.Tokens, error)
+	// Refresh rotates the refresh token: the presented one is revoked and a
+	// new one expiring at the same time, or later with Config.SlidingRefresh,
+	// is returned with the access token.
+	// Presenting a revoked token again revokes every session of its user.
+	Refresh(refreshToken, ua, ip string) (*auth.Tokens, error)
+	// Logout revokes the session of the refresh token and the access tokens
+	// issued with it
+	Logout(refreshToken, ua, ip string) error
+	// LogoutAll revokes every active session and access token of the user
+	// and returns how many sessions were revoked
+	LogoutAll(userID uuid.UUID) (int64, error)
+	// Forgot sends a password reset code to the user with the username or
+	// email, replacing any earlier one, at most once per cooldown per user
+	// and per username or email given
+	Forgot(uore, ua, ip string) error
+	// ResendOTP sends a new password reset code like Forgot. Only the
+	// cooldown of the username or email given is reported, as
+	// TooManyRequests; the user's own is enforced silently.
+	ResendOTP(uore string) error
+	// VerifyEmail confirms the user's email with the code sent to it. Failed
+	// codes count towards the same limits as VerifyOTP.
+	VerifyEmail(email, code, ip string) error
+	// ResendVerification sends a new code to an unverified email
+	ResendVerification(email string) error
+	// PublishUserChanged sends a verification code to users created by an
+	// administrator and signs deleted and deactivated users out, see
+	// user.UserChangedEvent
+	PublishUserChanged(ctx context.Context, event user.UserChangedEvent)
+	// VerifyOTP and ResetPassword take the username or email the code was
+	// requested with. They count failed codes per client IP and per user
+	// and refuse further attempts once either limit is reached.
+	VerifyOTP(uore, code, ip string) error
+	ResetPassword(uore, code, newPassword, ua, ip string) error
+	// ListSessions returns the user's active sessions, flagging currentID
+	ListSessions(userID, currentID uuid.UUID) ([]auth.Session, error)
+	// RevokeSession revokes one of the user's active sessions and the access
+	// tokens issued with it; sessions of other users are reported as not found
+	RevokeSession(userID, sessionID uuid.UUID) error
+	// RevokeToken rejects access tokens before they expire. The route
+	// requires a super admin.
+	RevokeToken(ctx context.Context, actorID uuid.UUID, req auth.RevokeTokenRequest) (*auth.RevokeTokenData, error)
+	// UpdateSession renames or (un)trusts one of the user's active sessions.
+	// With Config.LoginCodes, trusting needs a login code like a login, and
+	// wrong codes count towards the limits of the client IP.
+	UpdateSession(userID, sessionID uuid.UUID, req auth.UpdateSessionRequest, ip string) (*auth.Session, error)
+	// Me returns the profile and active role slugs of the token's user;
+	// deleted users are reported as not found
+	Me(ctx context.Context, userID uuid.UUID) (*auth.MeData, error)
+	// Impersonate issues the caller of claims, a super admin by the route's
+	// requirement, a short-lived access token acting as the target user, who
+	// must not be a super admin
+	Impersonate(ctx context.Context, claims *jwtpkg.Claims, targetID uuid.UUID) (*auth.ImpersonationData, error)
+	// StopImpersonation revokes the impersonation token of claims and
+	// issues its super admin an access token of their own
+	StopImpersonation(ctx context.Context, claims *jwtpkg.Claims) (*auth.ImpersonationData, error)
+	// GoogleAuthURL returns the Google sign-in page to send the user to
+	GoogleAuthURL() (*auth.OAuthURLData, error)
+	// GoogleLogin signs in the Google account that the code of the sign-in
+	// page was issued for, linking or creating a user by its email the first
+	// time, see Config.OAuthAutoProvision
+	GoogleLogin(ctx context.Context, req auth.OAuthCallbackRequest, ua, ip string) (*auth.Tokens, error)
+	// ListAuthEvents returns a page of the user's logins, refreshes, logouts
+	// and password events, newest first
+	ListAuthEvents(ctx context.Context, userID uuid.UUID, page, limit int) (*auth.AuthEventListData, error)
 }
 
 type Config struct {
 	AccessTTL  time.Duration
 	RefreshTTL time.Duration
+	// Notifier delivers OTP codes; when nil the codes are only stored
+	Notifier *notifier.Dispatcher
+	// Jobs queues OTP deliveries; when nil they are sent inline
+	Jobs jobs.Queue
+	// OTPPepper keys the hash under which OTP codes are stored
+	OTPPepper []byte
+	// TrustedDeviceTTL is how long a session stays trusted once marked
+	TrustedDeviceTTL time.Duration
+	// LoginCodes asks for a code, sent through the user's preferred OTP
+	// channel, after the password of a login from a device that is not
+	// trusted
+	LoginCodes bool
+	// SlidingRefresh makes every refresh extend the session to RefreshTTL
+	// from then, up to RefreshMaxAge after login; otherwise sessions end
+	// RefreshTTL after login
+	SlidingRefresh bool
+	// RefreshMaxAge caps sliding sessions; zero uses DefaultRefreshMaxAge
+	// and it is never below RefreshTTL
+	RefreshMaxAge time.Duration
+	// OTPAttempts limits failed OTP checks; zero values use the defaults
+	OTPAttempts OTPAttemptLimits
+	// OTPResendCooldown is how long Forgot and ResendOTP wait after the last code;
+	// zero uses DefaultOTPResendCooldown
+	OTPResendCooldown time.Duration
+	// LoginLockout locks accounts after failed logins; zero values use the defaults
+	LoginLockout LoginLockout
+	// RefreshDrift decides what happens when a session is refreshed from
+	// another user agent or IP address; empty uses DefaultRefreshDrift
+	RefreshDrift DriftPolicy
+	// LoginRate limits login attempts per username or email, whether they
+	// succeed or not; zero values use the defaults
+	LoginRate LoginRate
+	// LoginAttempts counts the attempts LoginRate limits; when nil they are
+	// counted in memory per instance
+	LoginAttempts attempts.Counter
+	// RequireVerifiedEmail refuses logins until the user verified their email
+	RequireVerifiedEmail bool
+	// UserEvents is told about users created by registration; may be nil
+	UserEvents UserEventPublisher
+	// Roles lists the role slugs put in access tokens; when nil tokens carry
+	// none, every role check reads the database and nobody may revoke tokens
+	Roles RoleSource
+	// Revocations rejects access tokens before they expire; when nil they
+	// stay valid until then
+	Revocations revocation.Store
+	// Users loads profiles for Me and impersonation; when nil neither is
+	// available
+	Users UserSource
+	// ImpersonationTTL is how long impersonation tokens last; zero uses
+	// DefaultImpersonationTTL
+	ImpersonationTTL time.Duration
+	// Passwords hashes and verifies passwords; nil uses bcrypt at its
+	// default cost
+	Passwords hasher.Hasher
+	// Google signs users in with their Google accounts; when nil Google
+	// sign-in is not available
+	Google *oauth.Google
+	// OAuthAutoProvision creates users for provider accounts whose email no
+	// user has; otherwise those are refused
+	OAuthAutoProvision bool
+	// Clock and IDs default to the wall clock and random UUIDs; tests
+	// replace them to get predictable timestamps and IDs
+	Clock clock.Clock
+	IDs   idgen.Generator
+}
+
+// UserEventPublisher receives user change events, like the user service's
+// EventPublisher
+type UserEventPublisher interface {
+	PublishUserChanged(ctx context.Context, event user.UserChangedEvent)
+}
+
+// UserSource loads users that are not deleted, like the user repository
+type UserSource interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*user.UserEntity, error)
+}
+
+// RoleSource lists and checks a user's active roles, like the RBAC service
+type RoleSource interface {
+	GetUserRoleSlugs(ctx context.Context, userID uuid.UUID) ([]string, error)
+	CheckUserRole(ctx context.Context, userID uuid.UUID, roleSlug string) (bool, error)
+}
+
+// OTPAttemptLimits bounds the failed OTP checks within Window from one
+// client IP, across any emails, and for one email, from any IP. PerCode
+// wrong codes, whenever given, invalidate the code sent.
+type OTPAttemptLimits struct {
+	PerIP    int
+	PerEmail int
+	Window   time.Duration
+	PerCode  int
+}
+
+// LoginLockout locks an account for Duration once MaxFailures wrong
+// passwords were given since its last successful login
+type LoginLockout struct {
+	MaxFailures int
+	Duration    time.Duration
+}
+
+// DriftPolicy is how Refresh treats a client whose user agent or IP
+// address differs from the one the session was issued to, or is missing on
+// either side
+type DriftPolicy string
+
+const (
+	// DriftOff ignores the difference
+	DriftOff DriftPolicy = "off"
+	// DriftWarn logs it as a security event and refreshes anyway, for
+	// clients such as phones whose address changes all the time
+	DriftWarn DriftPolicy = "warn"
+	// DriftStrict logs it, refuses the refresh and revokes the session so
+	// the token cannot be retried
+	DriftStrict DriftPolicy = "strict"
+)
+
+// DefaultRefreshDrift refuses refreshes from another client
+const DefaultRefreshDrift = DriftStrict
+
+// Valid reports whether p is one of the known policies
+func (p DriftPolicy) Valid() bool {
+	return p == DriftOff || p == DriftWarn || p == DriftStrict
+}
+
+// LoginRate allows Max login attempts for one username or email within
+// Window. Unlike the IP rate limiter it holds against attempts spread over
+// many clients, and unlike LoginLockout it is checked before the password.
+type LoginRate struct {
+	Max    int
+	Window time.Duration
+}
+
+// DefaultLoginRate leaves room for retries while stopping credential stuffing
+var DefaultLoginRate = LoginRate{
+	Max:    10,
+	Window: time.Minute,
+}
+
+// DefaultLoginLockout slows password guessing against a single account
+var DefaultLoginLockout = LoginLockout{
+	MaxFailures: 5,
+	Duration:    15 * time.Minute,
+}
+
+// DefaultOTPAttemptLimits leave room for typos while stopping guessing
+var DefaultOTPAttemptLimits = OTPAttemptLimits{
+	PerIP:    20,
+	PerEmail: 5,
+	Window:   15 * time.Minute,
+	PerCode:  5,
 }
 
 type service struct {
-	repo       repository.Repository
-	secrets    jwtpkg.Secrets
-	accessTTL  time.Duration
-	refreshTTL time.Duration
-	validator  *validator.Validator
+	repo          repository.Repository
+	secrets       jwtpkg.Secrets
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+	sliding       bool          // extend sessions on refresh, see Config.SlidingRefresh
+	maxAge        time.Duration // of sessions since login
+	validator     *validator.Validator
+	notifier      *notifier.Dispatcher
+	jobs          jobs.Queue // delivers OTP codes in the background
+	otpPepper     []byte
+	trustTTL      time.Duration
+	loginCodes    bool // ask untrusted devices for a login code
+	otpByIP       *attempts.Limiter
+	otpByEmail    *attempts.Limiter
+	otpPerCode    int // wrong codes invalidating the code sent
+	lockout       LoginLockout
+	logins        attempts.Counter // attempts per username or email, see LoginRate
+	drift         DriftPolicy
+	resends       *attempts.Limiter
+	cooldown      time.Duration     // between password reset codes, see ResendOTP
+	resetRequests *attempts.Limiter // password reset codes per username or email given
+	mustVerify    bool              // refuse logins with an unverified email
+	userEvents    UserEventPublisher
+	roles         RoleSource
+	revoked       revocation.Store
+	users         UserSource
+	impTTL        time.Duration // lifetime of impersonation tokens
+	passwords     hasher.Hasher
+	google        *oauth.Google
+	autoProvision bool // create users on first provider sign-in
+	clock         clock.Clock
+	ids           idgen.Generator
 }
 
 func New(repo repository.Repository, secrets jwtpkg.Secrets) Service {
 	return &service{
-		repo:       repo,
-		secrets:    secrets,
-		accessTTL:  15 * time.Minute,
-		refreshTTL: 7 * 24 * time.Hour,
-		validator:  validator.New(),
+		repo:          repo,
+		secrets:       secrets,
+		accessTTL:     15 * time.Minute,
+		refreshTTL:    7 * 24 * time.Hour,
+		maxAge:        DefaultRefreshMaxAge,
+		validator:     validator.New(),
+		jobs:          jobs.Inline{},
+		trustTTL:      30 * 24 * time.Hour,
+		otpByIP:       attempts.New(DefaultOTPAttemptLimits.PerIP, DefaultOTPAttemptLimits.Window),
+		otpByEmail:    attempts.New(DefaultOTPAttemptLimits.PerEmail, DefaultOTPAttemptLimits.Window),
+		otpPerCode:    DefaultOTPAttemptLimits.PerCode,
+		lockout:       DefaultLoginLockout,
+		logins:        attempts.New(DefaultLoginRate.Max, DefaultLoginRate.Window),
+		drift:         DefaultRefreshDrift,
+		resends:       attempts.New(maxVerificationResends, verificationResendWindow),
+		cooldown:      DefaultOTPResendCooldown,
+		resetRequests: attempts.New(1, DefaultOTPResendCooldown),
+		impTTL:        DefaultImpersonationTTL,
+		passwords:     hasher.OrDefault(nil),
+		clock:         clock.Real{},
+		ids:           idgen.Random{},
 	}
 }
 
 func NewWithConfig(repo repository.Repository, secrets jwtpkg.Secrets, cfg Config) Service {
+	limits := cfg.OTPAttempts
+	if limits.PerIP <= 0 {
+		limits.PerIP = DefaultOTPAttemptLimits.PerIP
+	}
+	if limits.PerEmail <= 0 {
+		limits.PerEmail = DefaultOTPAttemptLimits.PerEmail
+	}
+	if limits.Window <= 0 {
+		limits.Window = DefaultOTPAttemptLimits.Window
+	}
+	if limits.PerCode <= 0 {
+		limits.PerCode = DefaultOTPAttemptLimits.PerCode
+	}
+	cooldown := cfg.OTPResendCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultOTPResendCooldown
+	}
+	lockout := cfg.LoginLockout
+	if lockout.MaxFailures <= 0 {
+		lockout.MaxFailures = DefaultLoginLockout.MaxFailures
+	}
+	if lockout.Duration <= 0 {
+		lockout.Duration = DefaultLoginLockout.Duration
+	}
+	impTTL := cfg.ImpersonationTTL
+	if impTTL <= 0 {
+		impTTL = DefaultImpersonationTTL
+	}
+	maxAge := cfg.RefreshMaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultRefreshMaxAge
+	}
+	if maxAge < cfg.RefreshTTL {
+		maxAge = cfg.RefreshTTL
+	}
+	drift := cfg.RefreshDrift
+	if !drift.Valid() {
+		drift = DefaultRefreshDrift
+	}
+	logins := cfg.LoginAttempts
+	if logins == nil {
+		rate := cfg.LoginRate
+		if rate.Max <= 0 {
+			rate.Max = DefaultLoginRate.Max
+		}
+		if rate.Window <= 0 {
+			rate.Window = DefaultLoginRate.Window
+		}
+		logins = attempts.New(rate.Max, rate.Window)
+	}
 	return &service{
-		repo:       repo,
-		secrets:    secrets,
-		accessTTL:  cfg.AccessTTL,
-		refreshTTL: cfg.RefreshTTL,
-		validator:  validator.New(),
+		repo:          repo,
+		secrets:       secrets,
+		accessTTL:     cfg.AccessTTL,
+		refreshTTL:    cfg.RefreshTTL,
+		sliding:       cfg.SlidingRefresh,
+		maxAge:        maxAge,
+		validator:     validator.New(),
+		notifier:      cfg.Notifier,
+		jobs:          jobs.OrInline(cfg.Jobs),
+		otpPepper:     cfg.OTPPepper,
+		trustTTL:      cfg.TrustedDeviceTTL,
+		loginCodes:    cfg.LoginCodes,
+		otpByIP:       attempts.New(limits.PerIP, limits.Window),
+		otpByEmail:    attempts.New(limits.PerEmail, limits.Window),
+		otpPerCode:    limits.PerCode,
+		lockout:       lockout,
+		logins:        logins,
+		drift:         drift,
+		resends:       attempts.New(maxVerificationResends, verificationResendWindow),
+		cooldown:      cooldown,
+		resetRequests: attempts.New(1, cooldown),
+		mustVerify:    cfg.RequireVerifiedEmail,
+		userEvents:    cfg.UserEvents,
+		roles:         cfg.Roles,
+		revoked:       cfg.Revocations,
+		users:         cfg.Users,
+		impTTL:        impTTL,
+		passwords:     hasher.OrDefault(cfg.Passwords),
+		google:        cfg.Google,
+		autoProvision: cfg.OAuthAutoProvision,
+		clock:         clock.OrReal(cfg.Clock),
+		ids:           idgen.OrRandom(cfg.IDs),
 	}
 }
 
-func hashPassword(p string) (string, error) {
-	b, err := bcrypt.GenerateFromPassword([]byte(p), bcrypt.DefaultCost)
-	return string(b), err
-}
-func checkPassword(p, hash string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(p)) == nil
+func (s *service) Register(req auth.RegisterRequest, ua, ip string) (*auth.RegisterData, error) {
+	fullname := strings.TrimSpace(req.Fullname)
+	if ok, msg := s.validator.IsRequired(fullname, "fullname"); !ok {
+		return nil, apperrors.ValidationFailed(msg)
+	}
+	if ok, msg := s.validator.IsValidUsername(req.Username); !ok {
+		return nil, apperrors.ValidationFailed(msg)
+	}
+	if !s.validator.IsValidEmail(req.Email) {
+		return nil, apperrors.ValidationFailed("invalid email format")
+	}
+	if ok, msg := s.validator.IsValidPassword(req.Password); !ok {
+		return nil, apperrors.ValidationFailed(msg)
+	}
+
+	email := user.NormalizeEmail(req.Email)
+	// Logging in on registration counts against the login limit like any
+	// other login, before anything is created
+	if req.Login {
+		if err := s.throttleLogin(email, ip); err != nil {
+			return nil, err
+		}
+	}
+	if taken, err := s.repo.EmailExists(email); err != nil {
+		return nil, apperrors.InternalServer("failed to check email")
+	} else if taken {
+		return nil, apperrors.Conflict(constants.EmailAlreadyExists)
+	}
+	if taken, err := s.repo.UsernameExists(req.Username); err != nil {
+		return nil, apperrors.InternalServer("failed to check username")
+	} else if taken {
+		return nil, apperrors.Conflict(constants.UsernameExists)
+	}
+
+	hash, err := s.passwords.Hash(req.Password)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to hash password")
+	}
+
+	u := &auth.User{
+		ID:                  s.ids.New(),
+		Username:            req.Username,
+		Email:               email,
+		Fullname:            fullname,
+		PasswordHash:        hash,
+		PreferredOTPChannel: string(notifier.ChannelEmail),
+		IsActive:            true,
+	}
+	if err := s.repo.CreateUser(u); err != nil {
+		// Lost a race with another registration; the identifiers are
+		// checked again to tell which one it took
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if taken, _ := s.repo.UsernameExists(req.Username); taken {
+				if taken, _ := s.repo.EmailExists(email); !taken {
+					return nil, apperrors.Conflict(constants.UsernameExists)
+				}
+			}
+			return nil, apperrors.Conflict(constants.EmailAlreadyExists)
+		}
+		return nil, apperrors.InternalServer("failed to create user")
+	}
+	if s.userEvents != nil {
+		// Self-registered, so the user is their own actor
+		s.userEvents.PublishUserChanged(context.Background(), user.UserChangedEvent{UserID: u.ID, Change: user.ChangeCreated, ActorID: u.ID})
+	}
+	if err := s.sendVerification(u); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to send email verification", err, "user_id", u.ID.String())
+	}
+
+	data := &auth.RegisterData{ID: u.ID}
+	if req.Login {
+		// Tokens are only issued when the login checks pass, as from Login.
+		// The user is created either way, so a new user who must verify
+		// their email first gets no tokens rather than an error.
+		if err := s.checkLogin(u, auth.AuthEventLogin, ua, ip); err != nil {
+			return data, nil
+		}
+		tokens, err := s.issueTokens(u.ID, req.DeviceName, ua, ip)
+		if err != nil {
+			return nil, err
+		}
+		s.recordEvent(u.ID, auth.AuthEventLogin, true, "", ua, ip)
+		data.AccessToken, data.RefreshToken = tokens.AccessToken, tokens.RefreshToken
+		data.TokenType, data.ExpiresIn, data.RefreshExpiresIn = tokens.TokenType, tokens.ExpiresIn, tokens.RefreshExpiresIn
+	}
+	return data, nil
 }
 
-func (s *service) Login(uore, password, ua, ip string) (string, string, error) {
+func (s *service) Login(req auth.LoginRequest, ua, ip string) (*auth.Tokens, error) {
 	// Validate input
-	if ok, msg := s.validator.IsRequired(uore, "username/email"); !ok {
-		return "", "", apperrors.ValidationFailed(msg)
+	if ok, msg := s.validator.IsRequired(req.UsernameOrEmail, "username/email"); !ok {
+		return nil, apperrors.ValidationFailed(msg)
+	}
+	if ok, msg := s.validator.IsRequired(req.Password, "password"); !ok {
+		return nil, apperrors.ValidationFailed(msg)
+	}
+	if err := s.throttleLogin(req.UsernameOrEmail, ip); err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.FindUserByUsernameOrEmail(req.UsernameOrEmail)
+	if err != nil {
+		return nil, apperrors.InvalidCredentials()
+	}
+	now := s.clock.Now()
+	if u.LockedUntil != nil && u.LockedUntil.After(now) {
+		s.recordEvent(u.ID, auth.AuthEventLogin, false, reasonAccountLocked, ua, ip)
+		return nil, apperrors.AccountLocked(u.LockedUntil.Sub(now))
+	}
+	ok, rehash := s.passwords.Verify(req.Password, u.PasswordHash)
+	if !ok {
+		s.recordEvent(u.ID, auth.AuthEventLogin, false, reasonWrongPassword, ua, ip)
+		return nil, s.recordLoginFailure(u, ip, now)
+	}
+	// Only told after the right password, so it reveals nothing to guessers
+	if err := s.checkLogin(u, auth.AuthEventLogin, ua, ip); err != nil {
+		return nil, err
+	}
+	if u.FailedLoginAttempts > 0 || u.LockedUntil != nil {
+		if err := s.repo.ResetFailedLogins(u.ID); err != nil {
+			logger.Global().Auth().ErrorWithErr("failed to reset failed logins", err, "user_id", u.ID.String())
+		}
+	}
+	if rehash {
+		s.upgradePassword(u.ID, req.Password)
+	}
+	if s.loginCodes && !s.trustedDevice(u.ID, req.RefreshToken) {
+		if err := s.checkLoginCode(u, req.Code, ip); err != nil {
+			if appErr, ok := apperrors.IsAppError(err); !ok || appErr.Code != apperrors.CodeLoginCodeRequired {
+				s.recordEvent(u.ID, auth.AuthEventLogin, false, reasonInvalidCode, ua, ip)
+			}
+			return nil, err
+		}
+	}
+
+	tokens, err := s.issueTokens(u.ID, req.DeviceName, ua, ip)
+	if err != nil {
+		return nil, err
 	}
-	if ok, msg := s.validator.IsRequired(password, "password"); !ok {
-		return "", "", apperrors.ValidationFailed(msg)
+	s.recordEvent(u.ID, auth.AuthEventLogin, true, "", ua, ip)
+	return tokens, nil
+}
+
+// upgradePassword rehashes the password of a user whose hash was made with
+// another algorithm or cost than the configured one. Failures are only
+// logged; the old hash keeps working.
+func (s *service) upgradePassword(userID uuid.UUID, password string) {
+	hash, err := s.passwords.Hash(password)
+	if err == nil {
+		err = s.repo.UpdateUserPassword(userID, hash)
+	}
+	if err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to upgrade password hash", err, "user_id", userID.String())
+	}
+}
+
+// throttleLogin counts a login attempt for the username or email and
+// refuses it once LoginRate is exceeded, before any password is checked
+func (s *service) throttleLogin(uore, ip string) error {
+	key := strings.ToLower(strings.TrimSpace(uore))
+	if blocked, wait := s.logins.Blocked(key); blocked {
+		return apperrors.TooManyRequests(wait)
+	}
+	if s.logins.Fail(key) {
+		logger.Global().Auth().LogSecurityEvent("login_throttled", key, ip, "login attempt limit reached for account")
+	}
+	return nil
+}
+
+// checkLogin refuses a login by the user, recording the failed event, when
+// their account was deactivated or, with RequireVerifiedEmail, their email
+// is not verified. Every way of logging in goes through it.
+func (s *service) checkLogin(u *auth.User, event, ua, ip string) error {
+	if !u.IsActive {
+		s.recordEvent(u.ID, event, false, reasonAccountDisabled, ua, ip)
+		return apperrors.AccountDisabled()
+	}
+	if s.mustVerify && u.EmailVerifiedAt == nil {
+		s.recordEvent(u.ID, event, false, reasonEmailNotVerified, ua, ip)
+		return apperrors.EmailNotVerified()
 	}
+	return nil
+}
 
-	u, err := s.repo.FindUserByUsernameOrEmail(uore)
-	if err != nil || !checkPassword(password, u.PasswordHash) {
-		return "", "", apperrors.InvalidCredentials()
+// recordLoginFailure counts a wrong password against the user and returns
+// the error to answer with, locking the account once the limit is reached
+func (s *service) recordLoginFailure(u *auth.User, ip string, now time.Time) error {
+	locked, err := s.repo.RecordFailedLogin(u.ID, s.lockout.MaxFailures, now.Add(s.lockout.Duration))
+	if err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to record failed login", err, "user_id", u.ID.String())
+		return apperrors.InvalidCredentials()
+	}
+	if !locked {
+		return apperrors.InvalidCredentials()
 	}
+	logger.Global().Auth().LogSecurityEvent("account_locked", u.Email, ip,
+		fmt.Sprintf("locked for %s after %d failed login attempts", s.lockout.Duration, s.lockout.MaxFailures))
+	return apperrors.AccountLocked(s.lockout.Duration)
+}
 
-	access, err := jwtpkg.GenerateAccess(u.ID.String(), s.secrets.Access, s.accessTTL)
+// issueTokens creates a session for the user and returns its access and
+// refresh tokens
+func (s *service) issueTokens(userID uuid.UUID, deviceName, ua, ip string) (*auth.Tokens, error) {
+	sessionID := s.ids.New()
+	access, err := s.accessToken(userID, sessionID)
 	if err != nil {
-		return "", "", apperrors.InternalServer("failed to generate access token")
+		return nil, apperrors.InternalServer("failed to generate access token")
 	}
 
-	refresh, err := jwtpkg.GenerateRefresh(u.ID.String(), s.secrets.Refresh, s.refreshTTL)
+	refresh, err := jwtpkg.GenerateRefresh(userID.String(), s.secrets.Refresh, s.refreshTTL)
 	if err != nil {
-		return "", "", apperrors.InternalServer("failed to generate refresh token")
+		return nil, apperrors.InternalServer("failed to generate refresh token")
 	}
 
 	// store refresh token hash
+	now := s.clock.Now()
+	absolute := now.Add(s.maxAge)
 	rt := &auth.RefreshToken{
-		ID:        uuid.New(),
-		UserID:    u.ID,
-		TokenHash: bcryptHash(refresh),
-		UserAgent: ua,
-		IP:        ip,
-		ExpiresAt: time.Now().Add(s.refreshTTL),
+		ID:                sessionID,
+		UserID:            userID,
+		TokenHash:         hashRefreshToken(refresh),
+		UserAgent:         ua,
+		IP:                ip,
+		ExpiresAt:         now.Add(s.refreshTTL),
+		AbsoluteExpiresAt: &absolute,
+	}
+	if name := strings.TrimSpace(deviceName); name != "" {
+		rt.DeviceName = &name
 	}
 	if err := s.repo.CreateRefreshToken(rt); err != nil {
-		return "", "", apperrors.InternalServer("failed to store refresh token")
+		return nil, apperrors.InternalServer("failed to store refresh token")
+	}
+	return s.tokens(access, refresh, s.refreshTTL), nil
+}
+
+// tokens describes a token pair with the lifetimes the service signed it
+// with, so clients need not decode the tokens to schedule a refresh
+func (s *service) tokens(access, refresh string, refreshTTL time.Duration) *auth.Tokens {
+	return &auth.Tokens{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        auth.TokenTypeBearer,
+		ExpiresIn:        int64(s.accessTTL / time.Second),
+		RefreshExpiresIn: int64(refreshTTL / time.Second),
 	}
-	return access, refresh, nil
 }
 
-func (s *service) Refresh(refreshToken, ua, ip string) (string, error) {
+// trustedDevice reports whether refreshToken, sent by a client logging in,
+// belongs to a session of the user on a device they trust. The session
+// itself may have expired; the trust outlives it.
+func (s *service) trustedDevice(userID uuid.UUID, refreshToken string) bool {
+	if refreshToken == "" {
+		return false
+	}
+	rt, err := s.repo.FindRefreshToken(hashRefreshToken(refreshToken))
+	return err == nil && rt.UserID == userID && rt.IsTrusted(s.clock.Now())
+}
+
+// checkLoginCode is the second step of a login from a device that is not
+// trusted. Without a code it sends a new one to the user and answers
+// LoginCodeRequired; a code given is checked and used up like the other
+// OTP codes.
+func (s *service) checkLoginCode(u *auth.User, code, ip string) error {
+	if code == "" {
+		if err := s.repo.InvalidateOTPs(u.ID, auth.OTPPurposeLogin); err != nil {
+			return apperrors.InternalServer("failed to invalidate OTPs")
+		}
+		code, err := s.saveOTP(u.ID, auth.OTPPurposeLogin, loginOTPTTL)
+		if err != nil {
+			return err
+		}
+		s.sendOTP(u, notifier.Channel(u.PreferredOTPChannel), code, loginMessage)
+		return apperrors.LoginCodeRequired()
+	}
+	if ok, msg := s.validator.IsValidOTP(code); !ok {
+		return apperrors.ValidationFailed(msg)
+	}
+	key := strings.ToLower(u.Email)
+	if err := s.checkOTPAttempts(key, ip); err != nil {
+		return err
+	}
+	o, err := s.repo.FindValidOTP(u.ID, otp.Hash(s.otpPepper, code), auth.OTPPurposeLogin, s.clock.Now())
+	if err != nil {
+		return s.rejectOTP(u, key, ip, auth.OTPPurposeLogin)
+	}
+	if err := s.repo.MarkOTPUsed(o.ID); err != nil {
+		return apperrors.InternalServer("failed to mark OTP as used")
+	}
+	return nil
+}
+
+// accessToken signs an access token for the session. The user's roles are
+// read first and the token is dated from then, so a role change made while
+// it is issued makes the roles claim stale; without the roles the token
+// still works and role checks read the database.
+func (s *service) accessToken(userID, sessionID uuid.UUID) (string, error) {
+	return s.signAccess(userID, jwtpkg.AccessClaims{SessionID: sessionID.String()}, s.accessTTL)
+}
+
+// signAccess signs an access token for the user like accessToken, filling
+// in the ID, issue time and roles of claims
+func (s *service) signAccess(userID uuid.UUID, claims jwtpkg.AccessClaims, ttl time.Duration) (string, error) {
+	claims.ID = s.ids.New().String()
+	claims.UserID = userID.String()
+	claims.IssuedAt = s.clock.Now()
+	if s.roles != nil {
+		roles, err := s.roles.GetUserRoleSlugs(context.Background(), userID)
+		if err != nil {
+			logger.Global().Auth().ErrorWithErr("failed to get roles for access token", err, "user_id", userID.String())
+		} else {
+			claims.Roles = roles
+		}
+	}
+	return jwtpkg.GenerateAccess(claims, s.secrets, ttl)
+}
+
+func (s *service) Refresh(refreshToken, ua, ip string) (*auth.Tokens, error) {
 	if ok, msg := s.validator.IsRequired(refreshToken, "refresh token"); !ok {
-		return "", apperrors.ValidationFailed(msg)
+		return nil, apperrors.ValidationFailed(msg)
+	}
+
+	// find by hash, including revoked tokens to detect reuse
+	rt, err := s.repo.FindRefreshToken(hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, apperrors.InvalidRefreshToken()
+	}
+	// Deactivating revoked the user's tokens; say why instead of treating
+	// their use as reuse
+	u, err := s.repo.FindUserByID(rt.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.InvalidRefreshToken()
+	}
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to get user")
+	}
+	if !u.IsActive {
+		s.recordEvent(rt.UserID, auth.AuthEventRefresh, false, reasonAccountDisabled, ua, ip)
+		return nil, apperrors.AccountDisabled()
+	}
+	if rt.Revoked {
+		s.recordEvent(rt.UserID, auth.AuthEventRefresh, false, reasonTokenReused, ua, ip)
+		s.revokeOnReuse(rt, ip)
+		return nil, apperrors.InvalidRefreshToken()
+	}
+	if s.clock.Now().After(rt.ExpiresAt) {
+		s.recordEvent(rt.UserID, auth.AuthEventRefresh, false, reasonTokenExpired, ua, ip)
+		return nil, apperrors.InvalidRefreshToken()
+	}
+
+	// A different client may be using a stolen token. A trusted device is
+	// not checked: the user vouched for it, and it may roam between networks.
+	if !rt.IsTrusted(s.clock.Now()) {
+		if err := s.checkDrift(rt, ua, ip); err != nil {
+			s.recordEvent(rt.UserID, auth.AuthEventRefresh, false, reasonClientDrift, ua, ip)
+			return nil, err
+		}
+	}
+
+	nextID := s.ids.New()
+	access, err := s.accessToken(rt.UserID, nextID)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to generate access token")
+	}
+
+	now := s.clock.Now()
+	expiresAt := s.nextExpiry(rt, now)
+	remaining := expiresAt.Sub(now)
+	refresh, err := jwtpkg.GenerateRefresh(rt.UserID.String(), s.secrets.Refresh, remaining)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to generate refresh token")
+	}
+
+	next := &auth.RefreshToken{
+		ID:                nextID,
+		UserID:            rt.UserID,
+		TokenHash:         hashRefreshToken(refresh),
+		UserAgent:         firstNonEmpty(rt.UserAgent, ua),
+		IP:                firstNonEmpty(rt.IP, ip),
+		ExpiresAt:         expiresAt,
+		DeviceName:        rt.DeviceName,
+		TrustedUntil:      rt.TrustedUntil,
+		AbsoluteExpiresAt: rt.AbsoluteExpiresAt,
+	}
+	if err := s.repo.RotateRefreshToken(rt.ID, next); err != nil {
+		if errors.Is(err, repository.ErrTokenAlreadyRevoked) {
+			// Lost the race against another use of the same token
+			s.recordEvent(rt.UserID, auth.AuthEventRefresh, false, reasonTokenReused, ua, ip)
+			s.revokeOnReuse(rt, ip)
+			return nil, apperrors.InvalidRefreshToken()
+		}
+		return nil, apperrors.InternalServer("failed to rotate refresh token")
+	}
+	s.recordEvent(rt.UserID, auth.AuthEventRefresh, true, "", ua, ip)
+
+	return s.tokens(access, refresh, remaining), nil
+}
+
+// nextExpiry is when the session of rt expires once refreshed at now. The
+// new token keeps the old expiry so rotating never extends a session,
+// unless sessions slide: then it lasts refreshTTL from now, but never past
+// the absolute expiry set at login.
+func (s *service) nextExpiry(rt *auth.RefreshToken, now time.Time) time.Time {
+	if !s.sliding || rt.AbsoluteExpiresAt == nil {
+		return rt.ExpiresAt
+	}
+	expiresAt := now.Add(s.refreshTTL)
+	if expiresAt.After(*rt.AbsoluteExpiresAt) {
+		expiresAt = *rt.AbsoluteExpiresAt
+	}
+	if expiresAt.Before(rt.ExpiresAt) {
+		return rt.ExpiresAt
+	}
+	return expiresAt
+}
+
+// checkDrift compares the client refreshing a session with the one it was
+// issued to and applies the RefreshDrift policy to a mismatch. A user agent
+// or IP address missing on either side cannot be compared, so it counts as
+// a mismatch too.
+func (s *service) checkDrift(rt *auth.RefreshToken, ua, ip string) error {
+	if s.drift == DriftOff {
+		return nil
+	}
+	var fields, changes []string
+	for _, f := range []struct{ name, issued, sent string }{
+		{"user agent", rt.UserAgent, ua},
+		{"ip address", rt.IP, ip},
+	} {
+		if change := clientDrift(f.name, f.issued, f.sent); change != "" {
+			fields = append(fields, f.name)
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	log := logger.Global().Auth()
+	details := strings.Join(changes, " and ") + " for session " + rt.ID.String() + " of user " + rt.UserID.String()
+	if s.drift == DriftWarn {
+		log.LogSecurityEvent("refresh_client_drift", "", ip, details)
+		return nil
+	}
+	log.LogSecurityEvent("refresh_client_drift", "", ip, details+"; session revoked")
+	if err := s.repo.RevokeRefreshToken(rt.ID); err != nil {
+		log.ErrorWithErr("failed to revoke session after client drift", err, "session_id", rt.ID.String())
+	}
+	s.revokeSessionTokens(rt.ID)
+	return apperrors.Unauthorized().WithDetails(strings.Join(fields, " and ") + " mismatch")
+}
+
+// clientDrift describes how the value of a client field sent on refresh differs
+// from the one the session was issued with, or is "" when they match
+func clientDrift(name, issued, sent string) string {
+	switch {
+	case issued == "":
+		return name + " not recorded"
+	case sent == "":
+		return name + " not sent"
+	case sent != issued:
+		return name + " changed"
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// revokeOnReuse treats a revoked refresh token presented again as stolen:
+// either the thief or the user already rotated it, so every session of the
+// user is revoked and both have to sign in again
+func (s *service) revokeOnReuse(rt *auth.RefreshToken, ip string) {
+	log := logger.Global().Auth()
+	log.LogSecurityEvent("refresh_token_reuse", "", ip, "revoked refresh token presented again for user "+rt.UserID.String())
+	if _, err := s.repo.RevokeAllRefreshTokensByUser(rt.UserID); err != nil {
+		log.ErrorWithErr("failed to revoke sessions after refresh token reuse", err, "user_id", rt.UserID.String())
+	}
+	s.revokeUserTokens(rt.UserID)
+}
+
+func (s *service) Logout(refreshToken, ua, ip string) error {
+	rt, err := s.repo.GetRefreshToken(hashRefreshToken(refreshToken))
+	if err != nil {
+		return apperrors.InvalidRefreshToken()
+	}
+	if err := s.repo.RevokeRefreshToken(rt.ID); err != nil {
+		return apperrors.InternalServer("failed to revoke session")
+	}
+	s.revokeSessionTokens(rt.ID)
+	s.recordEvent(rt.UserID, auth.AuthEventLogout, true, "", ua, ip)
+	return nil
+}
+
+func (s *service) LogoutAll(userID uuid.UUID) (int64, error) {
+	revoked, err := s.repo.RevokeAllRefreshTokensByUser(userID)
+	if err != nil {
+		return 0, apperrors.InternalServer("failed to revoke sessions")
 	}
+	s.revokeUserTokens(userID)
+	return revoked, nil
+}
 
-	// find by hash
-	rt, err := s.repo.GetRefreshToken(bcryptHash(refreshToken))
-	if err != nil || rt.Revoked || time.Now().After(rt.ExpiresAt) {
-		return "", apperrors.InvalidRefreshToken()
+func (s *service) ListSessions(userID, currentID uuid.UUID) ([]auth.Session, error) {
+	tokens, err := s.repo.ListUserSessions(userID)
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to list sessions")
 	}
 
-	// (opsional) cek UA/IP match → mitigasi token theft
-	if ua != "" && rt.UserAgent != "" && ua != rt.UserAgent {
-		return "", apperrors.Unauthorized().WithDetails("user agent mismatch")
+	now := s.clock.Now()
+	sessions := make([]auth.Session, 0, len(tokens))
+	for _, rt := range tokens {
+		session := rt.ToSession(now)
+		session.Current = rt.ID == currentID
+		sessions = append(sessions, session)
 	}
-	if ip != "" && rt.IP != "" && ip != rt.IP {
-		return "", apperrors.Unauthorized().WithDetails("ip address mismatch")
+	return sessions, nil
+}
+
+func (s *service) RevokeSession(userID, sessionID uuid.UUID) error {
+	rt, err := s.repo.GetUserSession(userID, sessionID)
+	if err != nil {
+		return apperrors.SessionNotFound()
 	}
+	if err := s.repo.RevokeRefreshToken(rt.ID); err != nil {
+		return apperrors.InternalServer("failed to revoke session")
+	}
+	s.revokeSessionTokens(rt.ID)
+	return nil
+}
 
-	access, err := jwtpkg.GenerateAccess(rt.UserID.String(), s.secrets.Access, s.accessTTL)
+func (s *service) UpdateSession(userID, sessionID uuid.UUID, req auth.UpdateSessionRequest, ip string) (*auth.Session, error) {
+	rt, err := s.repo.GetUserSession(userID, sessionID)
 	if err != nil {
-		return "", apperrors.InternalServer("failed to generate access token")
+		return nil, apperrors.SessionNotFound()
 	}
 
-	return access, nil
+	if req.DeviceName != nil {
+		rt.DeviceName = nil
+		if name := strings.TrimSpace(*req.DeviceName); name != "" {
+			rt.DeviceName = &name
+		}
+	}
+	if req.Trusted != nil {
+		if *req.Trusted && s.loginCodes {
+			u, err := s.repo.FindUserByID(userID)
+			if err != nil {
+				return nil, apperrors.InternalServer("failed to get user")
+			}
+			if err := s.checkLoginCode(u, req.Code, ip); err != nil {
+				return nil, err
+			}
+		}
+		rt.TrustedUntil = nil
+		if *req.Trusted {
+			until := s.clock.Now().Add(s.trustTTL)
+			rt.TrustedUntil = &until
+		}
+	}
+
+	if err := s.repo.UpdateSession(rt.ID, rt.DeviceName, rt.TrustedUntil); err != nil {
+		return nil, apperrors.InternalServer("failed to update session")
+	}
+
+	session := rt.ToSession(s.clock.Now())
+	return &session, nil
 }
 
-func (s *service) Logout(refreshToken string) error {
-	rt, err := s.repo.GetRefreshToken(bcryptHash(refreshToken))
+func (s *service) Forgot(uore, ua, ip string) error {
+	if ok, msg := s.validator.IsRequired(uore, "username/email"); !ok {
+		return apperrors.ValidationFailed(msg)
+	}
+	if err := s.throttleReset(uore); err != nil {
+		return err
+	}
+
+	u, err := s.resetUser(uore)
 	if err != nil {
 		return err
 	}
-	return s.repo.RevokeRefreshToken(rt.ID)
+	if u == nil {
+		return apperrors.UserNotFound()
+	}
+	err = s.sendResetCode(u)
+	s.recordEvent(u.ID, auth.AuthEventPasswordResetRequested, err == nil, "", ua, ip)
+	return err
 }
 
-func (s *service) Forgot(email string) error {
-	if ok, msg := s.validator.IsRequired(email, "email"); !ok {
+func (s *service) ResendOTP(uore string) error {
+	if ok, msg := s.validator.IsRequired(uore, "username/email"); !ok {
 		return apperrors.ValidationFailed(msg)
 	}
-	if !s.validator.IsValidEmail(email) {
-		return apperrors.ValidationFailed("invalid email format")
+	if err := s.throttleReset(uore); err != nil {
+		return err
 	}
 
-	u, err := s.repo.FindUserByEmail(email)
+	u, err := s.resetUser(uore)
 	if err != nil {
-		return apperrors.EmailNotFound()
+		return err
+	}
+	if u == nil {
+		return apperrors.UserNotFound()
 	}
 
+	// The user's own cooldown may have been started with their other
+	// identifier; reporting it would show the account exists
+	err = s.sendResetCode(u)
+	if appErr, ok := apperrors.IsAppError(err); ok && appErr.Code == apperrors.CodeTooManyRequests {
+		return nil
+	}
+	return err
+}
+
+// throttleReset allows one password reset code per cooldown for what was
+// given as the username or email, whether or not a user has it, so the
+// answer does not depend on the account existing
+func (s *service) throttleReset(uore string) error {
+	key := strings.ToLower(strings.TrimSpace(uore))
+	if blocked, wait := s.resetRequests.Blocked(key); blocked {
+		return apperrors.TooManyRequests(wait)
+	}
+	s.resetRequests.Fail(key)
+	return nil
+}
+
+// sendResetCode replaces the user's password reset codes with a new one,
+// so only the latest code sent works. It sends at most one code per
+// cooldown per user, however the user was looked up.
+func (s *service) sendResetCode(u *auth.User) error {
+	latest, err := s.repo.LatestOTPCreatedAt(u.ID, auth.OTPPurposeForgotPassword)
+	if err != nil {
+		return apperrors.InternalServer("failed to check OTP cooldown")
+	}
+	if latest != nil {
+		if wait := latest.Add(s.cooldown).Sub(s.clock.Now()); wait > 0 {
+			return apperrors.TooManyRequests(wait)
+		}
+	}
+
+	if err := s.repo.InvalidateOTPs(u.ID, auth.OTPPurposeForgotPassword); err != nil {
+		return apperrors.InternalServer("failed to invalidate OTPs")
+	}
+
+	code, err := s.saveOTP(u.ID, auth.OTPPurposeForgotPassword, forgotPasswordOTPTTL)
+	if err != nil {
+		return err
+	}
+
+	s.sendOTP(u, notifier.Channel(u.PreferredOTPChannel), code, forgotPasswordMessage)
+	return nil
+}
+
+// saveOTP stores a new code for purpose and returns it
+func (s *service) saveOTP(userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
 	code, err := otp.Generate6()
 	if err != nil {
-		return apperrors.InternalServer("failed to generate OTP")
+		return "", apperrors.InternalServer("failed to generate OTP")
 	}
 
+	now := s.clock.Now()
 	o := &auth.OTP{
-		ID:        uuid.New(),
-		UserID:    u.ID,
-		Code:      code,
-		Purpose:   "forgot_password",
-		ExpiresAt: time.Now().Add(10 * time.Minute),
+		ID:        s.ids.New(),
+		UserID:    userID,
+		Code:      otp.Hash(s.otpPepper, code),
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
 	}
 
 	if err := s.repo.SaveOTP(o); err != nil {
-		return apperrors.InternalServer("failed to save OTP")
+		return "", apperrors.InternalServer("failed to save OTP")
 	}
+	return code, nil
+}
 
+// sendVerification sends the user a code proving they own their email. It
+// always goes by email, whatever the preferred OTP channel.
+func (s *service) sendVerification(u *auth.User) error {
+	code, err := s.saveOTP(u.ID, auth.OTPPurposeVerifyEmail, verifyEmailOTPTTL)
+	if err != nil {
+		return err
+	}
+	s.sendOTP(u, notifier.ChannelEmail, code, verifyEmailMessage)
 	return nil
 }
 
-func (s *service) VerifyOTP(email, code string) error {
-	if ok, msg := s.validator.IsRequired(email, "email"); !ok {
+// sendOTP queues delivery of the code through channel so the response time
+// does not depend on the gateway.
+func (s *service) sendOTP(u *auth.User, channel notifier.Channel, code string, m otpMessage) {
+	if s.notifier == nil {
+		return
+	}
+
+	to := notifier.Recipient{Email: u.Email}
+	if u.Phone != nil {
+		to.Phone = *u.Phone
+	}
+	// One ID for every retry of the job so the gateway sends the code once
+	msg := notifier.Message{
+		ID:      s.ids.New().String(),
+		Subject: m.subject,
+		Body:    fmt.Sprintf(m.body, code),
+	}
+	var html strings.Builder
+	if err := m.email.Execute(&html, struct{ Name, Code string }{u.Fullname, code}); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to render OTP email", err, "user_id", u.ID.String())
+	} else {
+		msg.HTML = html.String()
+	}
+
+	err := s.jobs.Enqueue("deliver OTP", func(ctx context.Context) error {
+		return s.notifier.Dispatch(ctx, channel, to, msg)
+	}, "user_id", u.ID.String())
+	if err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to queue OTP delivery", err, "user_id", u.ID.String())
+	}
+}
+
+func (s *service) VerifyOTP(uore, code, ip string) error {
+	if ok, msg := s.validator.IsRequired(uore, "username/email"); !ok {
 		return apperrors.ValidationFailed(msg)
 	}
 	if ok, msg := s.validator.IsValidOTP(code); !ok {
 		return apperrors.ValidationFailed(msg)
 	}
+	u, err := s.resetUser(uore)
+	if err != nil {
+		return err
+	}
+	key := otpKey(uore, u)
+	if err := s.checkOTPAttempts(key, ip); err != nil {
+		return err
+	}
+	if u == nil {
+		return s.rejectOTP(nil, key, ip, auth.OTPPurposeForgotPassword)
+	}
 
-	_, err := s.repo.FindValidOTP(email, code, "forgot_password", time.Now())
+	_, err = s.repo.FindValidOTP(u.ID, otp.Hash(s.otpPepper, code), auth.OTPPurposeForgotPassword, s.clock.Now())
 	if err != nil {
-		return apperrors.InvalidOTP()
+		return s.rejectOTP(u, key, ip, auth.OTPPurposeForgotPassword)
 	}
 	return nil
 }
 
-func (s *service) ResetPassword(email, code, newPassword string) error {
-	if ok, msg := s.validator.IsRequired(email, "email"); !ok {
+func (s *service) ResetPassword(uore, code, newPassword, ua, ip string) error {
+	if ok, msg := s.validator.IsRequired(uore, "username/email"); !ok {
 		return apperrors.ValidationFailed(msg)
 	}
 	if ok, msg := s.validator.IsValidOTP(code); !ok {
@@ -196,13 +1232,25 @@ func (s *service) ResetPassword(email, code, newPassword string) error {
 	if ok, msg := s.validator.IsValidPassword(newPassword); !ok {
 		return apperrors.ValidationFailed(msg)
 	}
+	u, err := s.resetUser(uore)
+	if err != nil {
+		return err
+	}
+	key := otpKey(uore, u)
+	if err := s.checkOTPAttempts(key, ip); err != nil {
+		return err
+	}
+	if u == nil {
+		return s.rejectOTP(nil, key, ip, auth.OTPPurposeForgotPassword)
+	}
 
-	o, err := s.repo.FindValidOTP(email, code, "forgot_password", time.Now())
+	o, err := s.repo.FindValidOTP(u.ID, otp.Hash(s.otpPepper, code), auth.OTPPurposeForgotPassword, s.clock.Now())
 	if err != nil {
-		return apperrors.InvalidOTP()
+		s.recordEvent(u.ID, auth.AuthEventPasswordReset, false, reasonInvalidCode, ua, ip)
+		return s.rejectOTP(u, key, ip, auth.OTPPurposeForgotPassword)
 	}
 
-	hash, err := hashPassword(newPassword)
+	hash, err := s.passwords.Hash(newPassword)
 	if err != nil {
 		return apperrors.InternalServer("failed to hash password")
 	}
@@ -215,11 +1263,176 @@ func (s *service) ResetPassword(email, code, newPassword string) error {
 		return apperrors.InternalServer("failed to mark OTP as used")
 	}
 
+	s.recordEvent(o.UserID, auth.AuthEventPasswordReset, true, "", ua, ip)
+
+	// Whoever knew the old password may hold a session; sign them all out
+	if _, err := s.LogoutAll(o.UserID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func (s *service) VerifyEmail(email, code, ip string) error {
+	if ok, msg := s.validator.IsRequired(email, "email"); !ok {
+		return apperrors.ValidationFailed(msg)
+	}
+	if ok, msg := s.validator.IsValidOTP(code); !ok {
+		return apperrors.ValidationFailed(msg)
+	}
+	u, err := s.repo.FindUserByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.InternalServer("failed to get user")
+	}
+	key := otpKey(email, u)
+	if err := s.checkOTPAttempts(key, ip); err != nil {
+		return err
+	}
+	if u == nil {
+		return s.rejectOTP(nil, key, ip, auth.OTPPurposeVerifyEmail)
+	}
+
+	o, err := s.repo.FindValidOTP(u.ID, otp.Hash(s.otpPepper, code), auth.OTPPurposeVerifyEmail, s.clock.Now())
+	if err != nil {
+		return s.rejectOTP(u, key, ip, auth.OTPPurposeVerifyEmail)
+	}
+
+	if err := s.repo.VerifyEmail(o.UserID, o.ID); err != nil {
+		return apperrors.InternalServer("failed to verify email")
+	}
+	return nil
+}
+
+func (s *service) ResendVerification(email string) error {
+	if ok, msg := s.validator.IsRequired(email, "email"); !ok {
+		return apperrors.ValidationFailed(msg)
+	}
+	if !s.validator.IsValidEmail(email) {
+		return apperrors.ValidationFailed("invalid email format")
+	}
+
+	key := strings.ToLower(email)
+	if blocked, wait := s.resends.Blocked(key); blocked {
+		return apperrors.TooManyRequests(wait)
+	}
+
+	u, err := s.repo.FindUserByEmail(email)
+	if err != nil {
+		return apperrors.EmailNotFound()
+	}
+	if u.EmailVerifiedAt != nil {
+		return apperrors.Conflict(constants.EmailAlreadyVerified)
+	}
+
+	s.resends.Fail(key)
+	return s.sendVerification(u)
+}
+
+func (s *service) PublishUserChanged(_ context.Context, event user.UserChangedEvent) {
+	if event.Change == user.ChangeDeleted || event.Change == user.ChangeDeactivated {
+		if _, err := s.LogoutAll(event.UserID); err != nil {
+			logger.Global().Auth().ErrorWithErr("failed to sign out "+event.Change+" user", err, "user_id", event.UserID.String())
+		}
+		return
+	}
+
+	// Self-registered users get their code from Register
+	if event.Change != user.ChangeCreated || event.ActorID == event.UserID {
+		return
+	}
+
+	u, err := s.repo.FindUserByID(event.UserID)
+	if err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to load created user", err, "user_id", event.UserID.String())
+		return
+	}
+	if u.EmailVerifiedAt != nil {
+		return
+	}
+	if err := s.sendVerification(u); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to send email verification", err, "user_id", u.ID.String())
+	}
+}
+
 // helpers
-func bcryptHash(s string) string {
-	h, _ := bcrypt.GenerateFromPassword([]byte(s), bcrypt.MinCost)
-	return string(h)
+
+// resetUser looks up the user with the username or email a password reset
+// code was requested for; it returns nil when there is none
+func (s *service) resetUser(uore string) (*auth.User, error) {
+	u, err := s.repo.FindUserByUsernameOrEmail(strings.TrimSpace(uore))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.InternalServer("failed to get user")
+	}
+	return u, nil
+}
+
+// otpKey is what failed codes are counted under: the user's email, so the
+// username and email share one limit, or what was given for unknown users
+func otpKey(uore string, u *auth.User) string {
+	if u != nil {
+		return strings.ToLower(u.Email)
+	}
+	return strings.ToLower(strings.TrimSpace(uore))
+}
+
+// checkOTPAttempts refuses an OTP check while the client IP or the key is
+// blocked, telling the client when the earliest block lifts
+func (s *service) checkOTPAttempts(key, ip string) error {
+	var wait time.Duration
+	if ip != "" {
+		if blocked, d := s.otpByIP.Blocked(ip); blocked {
+			wait = d
+		}
+	}
+	if blocked, d := s.otpByEmail.Blocked(key); blocked && d > wait {
+		wait = d
+	}
+	if wait > 0 {
+		return apperrors.TooManyRequests(wait)
+	}
+	return nil
+}
+
+// rejectOTP counts a wrong code for purpose and returns the error to answer
+// with: InvalidOTP, or OTPAttemptsExceeded once the code sent to u was
+// invalidated by it. u is nil when no user has the identifier given.
+func (s *service) rejectOTP(u *auth.User, key, ip, purpose string) error {
+	s.recordOTPFailure(key, ip)
+	if u == nil {
+		return apperrors.InvalidOTP()
+	}
+
+	exhausted, err := s.repo.RecordOTPFailure(u.ID, purpose, s.otpPerCode, s.clock.Now())
+	if err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to count OTP failure", err, "purpose", purpose)
+	}
+	if exhausted {
+		logger.Global().Auth().LogSecurityEvent("otp_attempts_exceeded", u.Email, ip,
+			fmt.Sprintf("%s code invalidated after %d failed attempts", purpose, s.otpPerCode))
+		return apperrors.OTPAttemptsExceeded()
+	}
+	return apperrors.InvalidOTP()
+}
+
+// recordOTPFailure counts a wrong OTP against the client IP and the key
+// and logs a security event when either becomes blocked
+func (s *service) recordOTPFailure(key, ip string) {
+	log := logger.Global().Auth()
+	if ip != "" && s.otpByIP.Fail(ip) {
+		log.LogSecurityEvent("otp_ip_blocked", key, ip, "too many failed OTP attempts from this IP")
+	}
+	if s.otpByEmail.Fail(key) {
+		log.LogSecurityEvent("otp_email_blocked", key, ip, "too many failed OTP attempts for this email")
+	}
+}
+
+// hashRefreshToken digests a refresh token for the token_hash column. The
+// digest must be deterministic so the token can be looked up by it; the
+// token is a high-entropy signed JWT, so an unsalted SHA-256 is enough.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,1118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/auth"
+	"backend-service-internpro/internal/auth/repository"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/constants"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/hasher"
+	"backend-service-internpro/internal/pkg/idgen"
+	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/pkg/otp"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var testPepper = []byte("test-pepper")
+
+var testNow = time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+// newTestService returns a service on repo whose clock stands still at
+// testNow and whose IDs come from a sequence
+func newTestService(repo repository.Repository, cfg Config) *service {
+	cfg.AccessTTL, cfg.RefreshTTL = 15*time.Minute, 24*time.Hour
+	cfg.Clock = clock.NewFake(testNow)
+	cfg.IDs = idgen.NewSequence()
+	secrets := jwtpkg.Secrets{Access: []byte("access-secret"), Refresh: []byte("refresh-secret")}
+	return NewWithConfig(repo, secrets, cfg).(*service)
+}
+
+// fakeRepo keeps users, sessions and OTPs in memory; the methods the
+// tests do not use are left to the embedded interface
+type fakeRepo struct {
+	repository.Repository
+	users    []*auth.User
+	otps     []*auth.OTP
+	sessions []*auth.RefreshToken
+	events   []*auth.AuthEvent
+}
+
+func (r *fakeRepo) FindUserByUsernameOrEmail(uore string) (*auth.User, error) {
+	for _, u := range r.users {
+		if u.Username == uore || u.Email == uore {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) FindUserByID(id uuid.UUID) (*auth.User, error) {
+	for _, u := range r.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) FindUserByEmail(email string) (*auth.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) RecordFailedLogin(userID uuid.UUID, maxFailures int, lockUntil time.Time) (bool, error) {
+	u, err := r.FindUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	u.FailedLoginAttempts++
+	if u.FailedLoginAttempts < maxFailures {
+		return false, nil
+	}
+	u.FailedLoginAttempts, u.LockedUntil = 0, &lockUntil
+	return true, nil
+}
+
+func (r *fakeRepo) ResetFailedLogins(userID uuid.UUID) error {
+	u, err := r.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+	u.FailedLoginAttempts, u.LockedUntil = 0, nil
+	return nil
+}
+
+func (r *fakeRepo) SaveOTP(o *auth.OTP) error {
+	r.otps = append(r.otps, o)
+	return nil
+}
+
+func (r *fakeRepo) FindValidOTP(userID uuid.UUID, codeHash, purpose string, now time.Time) (*auth.OTP, error) {
+	for _, o := range r.otps {
+		if o.UserID == userID && o.Code == codeHash && o.Purpose == purpose && !o.Used && o.ExpiresAt.After(now) {
+			return o, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) MarkOTPUsed(id uuid.UUID) error {
+	for _, o := range r.otps {
+		if o.ID == id {
+			o.Used = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) InvalidateOTPs(userID uuid.UUID, purpose string) error {
+	for _, o := range r.otps {
+		if o.UserID == userID && o.Purpose == purpose {
+			o.Used = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) LatestOTPCreatedAt(userID uuid.UUID, purpose string) (*time.Time, error) {
+	var latest *time.Time
+	for _, o := range r.otps {
+		if o.UserID == userID && o.Purpose == purpose && (latest == nil || o.CreatedAt.After(*latest)) {
+			latest = &o.CreatedAt
+		}
+	}
+	return latest, nil
+}
+
+func (r *fakeRepo) RecordOTPFailure(userID uuid.UUID, purpose string, maxAttempts int, now time.Time) (bool, error) {
+	exhausted := false
+	for _, o := range r.otps {
+		if o.UserID == userID && o.Purpose == purpose && !o.Used && now.Before(o.ExpiresAt) {
+			o.Attempts++
+			if o.Attempts >= maxAttempts {
+				o.Used, exhausted = true, true
+			}
+		}
+	}
+	return exhausted, nil
+}
+
+func (r *fakeRepo) CreateRefreshToken(rt *auth.RefreshToken) error {
+	r.sessions = append(r.sessions, rt)
+	return nil
+}
+
+func (r *fakeRepo) FindRefreshToken(hash string) (*auth.RefreshToken, error) {
+	for _, rt := range r.sessions {
+		if rt.TokenHash == hash {
+			return rt, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) GetRefreshToken(hash string) (*auth.RefreshToken, error) {
+	rt, err := r.FindRefreshToken(hash)
+	if err != nil || rt.Revoked {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return rt, nil
+}
+
+func (r *fakeRepo) RotateRefreshToken(oldID uuid.UUID, next *auth.RefreshToken) error {
+	for _, rt := range r.sessions {
+		if rt.ID == oldID {
+			if rt.Revoked {
+				return repository.ErrTokenAlreadyRevoked
+			}
+			rt.Revoked, rt.TrustedUntil = true, nil
+		}
+	}
+	r.sessions = append(r.sessions, next)
+	return nil
+}
+
+func (r *fakeRepo) RevokeAllRefreshTokensByUser(userID uuid.UUID) (int64, error) {
+	var revoked int64
+	for _, rt := range r.sessions {
+		if rt.UserID == userID && !rt.Revoked {
+			rt.Revoked, rt.TrustedUntil = true, nil
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (r *fakeRepo) RevokeRefreshToken(id uuid.UUID) error {
+	for _, rt := range r.sessions {
+		if rt.ID == id {
+			rt.Revoked, rt.TrustedUntil = true, nil
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) GetUserSession(userID, id uuid.UUID) (*auth.RefreshToken, error) {
+	for _, rt := range r.sessions {
+		if rt.ID == id && rt.UserID == userID && !rt.Revoked {
+			return rt, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) UpdateSession(id uuid.UUID, deviceName *string, trustedUntil *time.Time) error {
+	for _, rt := range r.sessions {
+		if rt.ID == id {
+			rt.DeviceName, rt.TrustedUntil = deviceName, trustedUntil
+		}
+	}
+	return nil
+}
+
+// sentCodes records the messages sent to it
+type sentCodes struct{ messages []notifier.Message }
+
+func (s *sentCodes) Send(_ context.Context, _ notifier.Recipient, msg notifier.Message) error {
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// lastCode returns the code in the last message sent
+func (s *sentCodes) lastCode(t *testing.T) string {
+	t.Helper()
+	if len(s.messages) == 0 {
+		t.Fatal("no message was sent")
+	}
+	return regexp.MustCompile(`\d{6}`).FindString(s.messages[len(s.messages)-1].Body)
+}
+
+func TestOTPIsStoredHashed(t *testing.T) {
+	repo := &fakeRepo{users: []*auth.User{{ID: uuid.New(), Email: "siti@example.com", PreferredOTPChannel: "email"}}}
+	sent := &sentCodes{}
+	s := newTestService(repo, Config{
+		OTPPepper: testPepper,
+		Notifier:  notifier.NewDispatcher().Register(notifier.ChannelEmail, sent),
+	})
+
+	if err := s.Forgot("siti@example.com", "test-agent", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent.messages) != 1 {
+		t.Fatalf("%d messages sent, want 1", len(sent.messages))
+	}
+	code := regexp.MustCompile(`\d{6}`).FindString(sent.messages[0].Body)
+	stored := repo.otps[0]
+	// The gateway's idempotency key is the ID after the OTP's
+	ids := idgen.NewSequence()
+	ids.New()
+	if want := ids.New().String(); sent.messages[0].ID != want {
+		t.Errorf("message ID = %s, want %s", sent.messages[0].ID, want)
+	}
+	if stored.Code == code || strings.Contains(stored.Code, code) {
+		t.Errorf("stored code %q reveals the code %s", stored.Code, code)
+	}
+	if stored.Code != otp.Hash(testPepper, code) {
+		t.Errorf("stored code %q is not the keyed hash of the code", stored.Code)
+	}
+
+	if err := s.VerifyOTP("siti@example.com", code, "10.0.0.1"); err != nil {
+		t.Errorf("verifying the emailed code: %v", err)
+	}
+}
+
+func TestResetCodeCooldown(t *testing.T) {
+	repo := &fakeRepo{users: []*auth.User{{ID: uuid.New(), Username: "siti", Email: "siti@example.com", PreferredOTPChannel: "email"}}}
+	sent := &sentCodes{}
+	s := newTestService(repo, Config{
+		OTPPepper: testPepper,
+		Notifier:  notifier.NewDispatcher().Register(notifier.ChannelEmail, sent),
+	})
+
+	if err := s.Forgot("siti@example.com", "test-agent", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Looping on /forgot sends nothing more
+	if err := s.Forgot("siti@example.com", "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("second forgot: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+	if err := s.ResendOTP("siti@example.com"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("resend after forgot: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+	// The user's own cooldown holds under their username but is not reported
+	if err := s.ResendOTP("siti"); err != nil {
+		t.Errorf("resend with the username: %v", err)
+	}
+	if len(sent.messages) != 1 {
+		t.Errorf("%d messages sent, want 1", len(sent.messages))
+	}
+
+	// Unknown users are throttled the same way
+	if err := s.ResendOTP("nobody@example.com"); !isAppError(err, apperrors.CodeUserNotFound) {
+		t.Errorf("first resend for an unknown user: err = %v, want USER_NOT_FOUND", err)
+	}
+	if err := s.ResendOTP("Nobody@example.com "); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("second resend for an unknown user: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+}
+
+// newLoginCodeService returns a service asking for login codes and the
+// user siti, whose password is Rahasia#2025
+func newLoginCodeService(t *testing.T) (Service, *fakeRepo, *sentCodes, *auth.User) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("Rahasia#2025"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siti := &auth.User{ID: uuid.New(), Username: "siti", Email: "siti@example.com", PasswordHash: string(hash), PreferredOTPChannel: "email", IsActive: true}
+	repo := &fakeRepo{users: []*auth.User{siti}}
+	sent := &sentCodes{}
+	s := newTestService(repo, Config{
+		OTPPepper:        testPepper,
+		Notifier:         notifier.NewDispatcher().Register(notifier.ChannelEmail, sent),
+		TrustedDeviceTTL: 30 * 24 * time.Hour,
+		LoginCodes:       true,
+	})
+	return s, repo, sent, siti
+}
+
+// isAppError reports whether err is an AppError with code
+func isAppError(err error, code apperrors.ErrorCode) bool {
+	appErr, ok := apperrors.IsAppError(err)
+	return ok && appErr.Code == code
+}
+
+func TestLoginCode(t *testing.T) {
+	s, repo, sent, _ := newLoginCodeService(t)
+	req := auth.LoginRequest{UsernameOrEmail: "siti", Password: "Rahasia#2025"}
+
+	// The password alone sends a code instead of starting a session
+	if _, err := s.Login(req, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeLoginCodeRequired) {
+		t.Fatalf("login without a code: err = %v, want LOGIN_CODE_REQUIRED", err)
+	}
+	code := sent.lastCode(t)
+	if len(repo.sessions) != 0 {
+		t.Fatal("a session was started without a code")
+	}
+
+	req.Code = "000000"
+	if code == req.Code {
+		req.Code = "111111"
+	}
+	if _, err := s.Login(req, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeInvalidOTP) {
+		t.Errorf("login with a wrong code: err = %v, want INVALID_OTP", err)
+	}
+
+	req.Code = code
+	if _, err := s.Login(req, "test-agent", "10.0.0.1"); err != nil {
+		t.Fatalf("login with the code: %v", err)
+	}
+	if len(repo.sessions) != 1 {
+		t.Errorf("%d sessions, want 1", len(repo.sessions))
+	}
+	// A code is used up by the login
+	if _, err := s.Login(req, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeInvalidOTP) {
+		t.Errorf("login with a used code: err = %v, want INVALID_OTP", err)
+	}
+
+	// A wrong password never sends a code
+	before := len(sent.messages)
+	req.Password, req.Code = "wrong", ""
+	if _, err := s.Login(req, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeInvalidCredentials) {
+		t.Errorf("login with a wrong password: err = %v, want INVALID_CREDENTIALS", err)
+	}
+	if len(sent.messages) != before {
+		t.Error("a wrong password sent a login code")
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{
+		LoginLockout: LoginLockout{MaxFailures: 3, Duration: 15 * time.Minute},
+	})
+	if _, err := s.Register(registerRequest("siti@example.com", false), "test-agent", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	wrong := auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "wrong"}
+	right := auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "Rahasia#2025"}
+
+	// A correct password clears the failures counted before it
+	for range 2 {
+		if _, err := s.Login(wrong, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeInvalidCredentials) {
+			t.Fatalf("wrong password: err = %v, want INVALID_CREDENTIALS", err)
+		}
+	}
+	if _, err := s.Login(right, "test-agent", "10.0.0.1"); err != nil {
+		t.Fatalf("right password: %v", err)
+	}
+	if repo.users[0].FailedLoginAttempts != 0 {
+		t.Errorf("%d failures left after a login, want 0", repo.users[0].FailedLoginAttempts)
+	}
+
+	// The last allowed failure locks the account, even for the right password
+	for range 2 {
+		s.Login(wrong, "test-agent", "10.0.0.1")
+	}
+	_, err := s.Login(wrong, "test-agent", "10.0.0.1")
+	if !isAppError(err, apperrors.CodeAccountLocked) {
+		t.Fatalf("third wrong password: err = %v, want ACCOUNT_LOCKED", err)
+	}
+	if appErr, _ := apperrors.IsAppError(err); appErr.RetryAfter != 15*time.Minute {
+		t.Errorf("retry after %s, want 15m", appErr.RetryAfter)
+	}
+	if _, err := s.Login(right, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeAccountLocked) {
+		t.Errorf("right password while locked: err = %v, want ACCOUNT_LOCKED", err)
+	}
+}
+
+func TestTrustNeedsLoginCode(t *testing.T) {
+	s, repo, sent, siti := newLoginCodeService(t)
+	session := &auth.RefreshToken{ID: uuid.New(), UserID: siti.ID, ExpiresAt: testNow.Add(time.Hour)}
+	repo.sessions = append(repo.sessions, session)
+	trusted := true
+
+	_, err := s.UpdateSession(siti.ID, session.ID, auth.UpdateSessionRequest{Trusted: &trusted}, "10.0.0.1")
+	if !isAppError(err, apperrors.CodeLoginCodeRequired) {
+		t.Fatalf("trust without a code: err = %v, want LOGIN_CODE_REQUIRED", err)
+	}
+	if session.TrustedUntil != nil {
+		t.Fatal("the session was trusted without a code")
+	}
+
+	got, err := s.UpdateSession(siti.ID, session.ID, auth.UpdateSessionRequest{Trusted: &trusted, Code: sent.lastCode(t)}, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Trusted {
+		t.Error("session is not trusted")
+	}
+	// Trust lasts the trust period
+	if until := testNow.Add(30 * 24 * time.Hour); session.TrustedUntil == nil || session.TrustedUntil.After(until) || !session.IsTrusted(until.Add(-time.Minute)) {
+		t.Errorf("trusted until %v, want about %v", session.TrustedUntil, until)
+	}
+}
+
+func TestTrustCodeGuessesCountPerIP(t *testing.T) {
+	siti := &auth.User{ID: uuid.New(), Username: "siti", Email: "siti@example.com", IsActive: true}
+	budi := &auth.User{ID: uuid.New(), Username: "budi", Email: "budi@example.com", IsActive: true}
+	session := &auth.RefreshToken{ID: uuid.New(), UserID: siti.ID, ExpiresAt: testNow.Add(time.Hour)}
+	repo := &fakeRepo{users: []*auth.User{siti, budi}, sessions: []*auth.RefreshToken{session}}
+	s := newTestService(repo, Config{
+		OTPPepper:   testPepper,
+		LoginCodes:  true,
+		OTPAttempts: OTPAttemptLimits{PerIP: 2, PerEmail: 100, Window: 15 * time.Minute},
+	})
+	trusted := true
+	guess := auth.UpdateSessionRequest{Trusted: &trusted, Code: "000000"}
+
+	for range 2 {
+		if _, err := s.UpdateSession(siti.ID, session.ID, guess, "10.0.0.9"); !isAppError(err, apperrors.CodeInvalidOTP) {
+			t.Fatalf("wrong code: err = %v, want INVALID_OTP", err)
+		}
+	}
+	if _, err := s.UpdateSession(siti.ID, session.ID, guess, "10.0.0.9"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Fatalf("guess from a blocked IP: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+	// The guesses block the client for other codes too
+	if err := s.VerifyOTP("budi@example.com", "000000", "10.0.0.9"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("verify from the same IP: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+}
+
+func (r *fakeRepo) EmailExists(email string) (bool, error) {
+	_, err := r.FindUserByEmail(email)
+	return err == nil, nil
+}
+
+func (r *fakeRepo) UsernameExists(username string) (bool, error) {
+	for _, u := range r.users {
+		if u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) UpdateUserPassword(userID uuid.UUID, passwordHash string) error {
+	u, err := r.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = passwordHash
+	return nil
+}
+
+func (r *fakeRepo) CreateAuthEvent(e *auth.AuthEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *fakeRepo) CreateUser(u *auth.User) error {
+	r.users = append(r.users, u)
+	return nil
+}
+
+func registerRequest(email string, login bool) auth.RegisterRequest {
+	return auth.RegisterRequest{
+		Username: "siti_rahma",
+		Email:    email,
+		Fullname: "Siti Rahma",
+		Password: "Rahasia#2025",
+		Login:    login,
+	}
+}
+
+func TestRegister(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{LoginRate: LoginRate{Max: 1, Window: time.Hour}})
+
+	data, err := s.Register(registerRequest("Siti@Example.com", true), "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.AccessToken == "" || len(repo.sessions) != 1 {
+		t.Fatalf("got access token %q and %d sessions, want both", data.AccessToken, len(repo.sessions))
+	}
+	if want := uuid.MustParse("00000000-0000-4000-8000-000000000001"); data.ID != want {
+		t.Errorf("user ID = %s, want %s from the ID sequence", data.ID, want)
+	}
+	if len(repo.users) != 1 || repo.users[0].ID != data.ID || repo.users[0].Email != "siti@example.com" {
+		t.Errorf("users = %+v, want siti@example.com with the returned ID", repo.users)
+	}
+	var logins int
+	for _, e := range repo.events {
+		if e.Type == auth.AuthEventLogin && e.Success && e.UserID == data.ID && e.CreatedAt.Equal(testNow) {
+			logins++
+		}
+	}
+	if logins != 1 {
+		t.Errorf("recorded %d successful logins, want 1", logins)
+	}
+
+	// The email is taken, whatever its case
+	req := registerRequest("SITI@example.com", false)
+	req.Username = "siti_r"
+	if _, err := s.Register(req, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeConflict) {
+		t.Errorf("register a taken email: err = %v, want CONFLICT", err)
+	}
+
+	// The login attempt limit applies to the email before anything is created
+	req = registerRequest("siti@example.com", true)
+	req.Username = "siti_r"
+	if _, err := s.Register(req, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("second login registration: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+}
+
+// racingRepo stores a concurrent registration of racer just before each
+// insert, which then fails on the unique indexes
+type racingRepo struct {
+	*fakeRepo
+	racer *auth.User
+}
+
+func (r *racingRepo) CreateUser(*auth.User) error {
+	r.users = append(r.users, r.racer)
+	return gorm.ErrDuplicatedKey
+}
+
+func TestRegisterRaceNamesTheTakenIdentifier(t *testing.T) {
+	tests := []struct {
+		name  string
+		racer *auth.User
+		want  string
+	}{
+		{"same username", &auth.User{ID: uuid.New(), Username: "siti_rahma", Email: "rahma@example.com"}, constants.UsernameExists},
+		{"same email", &auth.User{ID: uuid.New(), Username: "rahma", Email: "siti@example.com"}, constants.EmailAlreadyExists},
+		{"both", &auth.User{ID: uuid.New(), Username: "siti_rahma", Email: "siti@example.com"}, constants.EmailAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(&racingRepo{fakeRepo: &fakeRepo{}, racer: tt.racer}, Config{})
+			_, err := s.Register(registerRequest("siti@example.com", false), "test-agent", "10.0.0.1")
+			if appErr, ok := apperrors.IsAppError(err); !ok || appErr.Code != apperrors.CodeConflict || appErr.Message != tt.want {
+				t.Errorf("err = %v, want CONFLICT %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterWithoutLoginIssuesNoTokens(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{})
+
+	data, err := s.Register(registerRequest("siti@example.com", false), "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.AccessToken != "" || len(repo.sessions) != 0 {
+		t.Errorf("got access token %q and %d sessions, want none", data.AccessToken, len(repo.sessions))
+	}
+}
+
+func TestLoginRefreshLogout(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{})
+	if _, err := s.Register(registerRequest("siti@example.com", false), "test-agent", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	login, err := s.Login(auth.LoginRequest{UsernameOrEmail: "siti@example.com", Password: "Rahasia#2025"}, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh := login.RefreshToken
+
+	// The session is stored under a digest that is the same on every
+	// lookup and does not reveal the token
+	stored := repo.sessions[len(repo.sessions)-1]
+	if stored.TokenHash != hashRefreshToken(refresh) || strings.Contains(stored.TokenHash, refresh) {
+		t.Fatalf("session stored under %q, want the digest of the token", stored.TokenHash)
+	}
+
+	refreshed, err := s.Refresh(refresh, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("refreshing the login token: %v", err)
+	}
+	rotated := refreshed.RefreshToken
+	if rotated == refresh {
+		t.Error("refresh returned the same refresh token, want a rotated one")
+	}
+	if err := s.Logout(rotated, "test-agent", "10.0.0.1"); err != nil {
+		t.Fatalf("logging out: %v", err)
+	}
+	if _, err := s.Refresh(rotated, "test-agent", "10.0.0.1"); err == nil {
+		t.Error("a logged out token refreshed")
+	}
+
+	// Each step is recorded in the user's auth history
+	var got []string
+	for _, e := range repo.events {
+		if e.UserID != stored.UserID || !e.CreatedAt.Equal(testNow) || e.IP != "10.0.0.1" {
+			t.Errorf("event %+v, want one of the user at testNow from 10.0.0.1", e)
+		}
+		got = append(got, fmt.Sprintf("%s:%t", e.Type, e.Success))
+	}
+	if want := "login:true refresh:true logout:true refresh:false"; strings.Join(got, " ") != want {
+		t.Errorf("events = %v, want %s", got, want)
+	}
+}
+
+func TestLoginUpgradesPasswordHash(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("Rahasia#2025"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siti := &auth.User{ID: uuid.New(), Username: "siti", Email: "siti@example.com", PasswordHash: string(hash), IsActive: true}
+	repo := &fakeRepo{users: []*auth.User{siti}}
+	argon := hasher.NewArgon2id(hasher.Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1})
+	s := newTestService(repo, Config{Passwords: argon})
+
+	login := auth.LoginRequest{UsernameOrEmail: "siti", Password: "Rahasia#2025"}
+	if _, err := s.Login(login, "test-agent", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, rehash := argon.Verify("Rahasia#2025", siti.PasswordHash); !ok || rehash {
+		t.Fatalf("hash after login = %q, want an argon2id hash of the password", siti.PasswordHash)
+	}
+	if _, err := s.Login(login, "test-agent", "10.0.0.1"); err != nil {
+		t.Errorf("login with the upgraded hash: %v", err)
+	}
+}
+
+func TestSlidingRefresh(t *testing.T) {
+	for _, sliding := range []bool{false, true} {
+		repo := &fakeRepo{}
+		// Sessions last a day from login, or from the last refresh when
+		// sliding, and never more than a day and a half
+		s := newTestService(repo, Config{SlidingRefresh: sliding, RefreshMaxAge: 36 * time.Hour})
+		now := s.clock.(*clock.Fake)
+		if _, err := s.Register(registerRequest("siti@example.com", false), "test-agent", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+		login, err := s.Login(auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "Rahasia#2025"}, "test-agent", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		refresh := login.RefreshToken
+		for _, step := range []struct {
+			after, want time.Duration
+		}{
+			{6 * time.Hour, 30 * time.Hour},
+			{10 * time.Hour, 36 * time.Hour},
+		} {
+			now.Advance(step.after)
+			refreshed, err := s.Refresh(refresh, "test-agent", "10.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			refresh = refreshed.RefreshToken
+
+			want := testNow.Add(24 * time.Hour)
+			if sliding {
+				want = testNow.Add(step.want)
+			}
+			if got := repo.sessions[len(repo.sessions)-1].ExpiresAt; !got.Equal(want) {
+				t.Errorf("sliding %t: session refreshed at %s expires %s, want %s", sliding, now.Now(), got, want)
+			}
+		}
+	}
+}
+
+func TestRefreshTokenReuseRevokesSessions(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{})
+	registered, err := s.Register(registerRequest("siti@example.com", true), "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := repo.sessions[0]
+	login, err := s.Login(auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "Rahasia#2025"}, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh := login.RefreshToken
+	if other.UserID != registered.ID {
+		t.Fatalf("registration session belongs to %s, want %s", other.UserID, registered.ID)
+	}
+
+	refreshed, err := s.Refresh(refresh, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := refreshed.RefreshToken
+	// The rotated-out token presented again looks stolen
+	if _, err := s.Refresh(refresh, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeInvalidRefreshToken) {
+		t.Fatalf("reusing a rotated token: err = %v, want INVALID_REFRESH_TOKEN", err)
+	}
+	if _, err := s.Refresh(rotated, "test-agent", "10.0.0.1"); err == nil {
+		t.Error("the rotated token still refreshes after reuse")
+	}
+	if !other.Revoked {
+		t.Error("the user's other session was not revoked")
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{})
+	registered, err := s.Register(registerRequest("siti@example.com", true), "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "Rahasia#2025"}
+	if _, err := s.Login(login, "test-agent", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	someone := &auth.RefreshToken{ID: uuid.New(), UserID: uuid.New(), ExpiresAt: testNow.Add(time.Hour)}
+	repo.sessions = append(repo.sessions, someone)
+
+	revoked, err := s.LogoutAll(registered.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 2 {
+		t.Errorf("revoked %d sessions, want 2", revoked)
+	}
+	if someone.Revoked {
+		t.Error("another user's session was revoked")
+	}
+}
+
+func TestRefreshDriftPolicy(t *testing.T) {
+	tests := []struct {
+		policy  DriftPolicy
+		refused bool
+	}{
+		{DriftOff, false},
+		{DriftWarn, false},
+		{DriftStrict, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			repo := &fakeRepo{}
+			s := newTestService(repo, Config{RefreshDrift: tt.policy})
+			registered, err := s.Register(registerRequest("siti@example.com", true), "test-agent", "10.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			session := repo.sessions[len(repo.sessions)-1]
+
+			// A phone changing networks keeps its user agent
+			_, err = s.Refresh(registered.RefreshToken, "test-agent", "10.9.9.9")
+			if !tt.refused {
+				if err != nil {
+					t.Fatalf("refresh from another IP: %v", err)
+				}
+				return
+			}
+			if !isAppError(err, apperrors.CodeUnauthorized) {
+				t.Fatalf("refresh from another IP: err = %v, want UNAUTHORIZED", err)
+			}
+			// The token cannot be retried, not even from the original client
+			if !session.Revoked {
+				t.Error("session is not revoked after the mismatch")
+			}
+			if _, err := s.Refresh(registered.RefreshToken, "test-agent", "10.0.0.1"); err == nil {
+				t.Error("retrying the refused token from the original client succeeded")
+			}
+		})
+	}
+}
+
+func TestRefreshDriftMissingClient(t *testing.T) {
+	tests := []struct {
+		name               string
+		issuedUA, issuedIP string
+		sentUA, sentIP     string
+	}{
+		{"user agent not sent", "test-agent", "10.0.0.1", "", "10.0.0.1"},
+		{"ip address not sent", "test-agent", "10.0.0.1", "test-agent", ""},
+		{"session issued without a user agent", "", "10.0.0.1", "test-agent", "10.0.0.1"},
+		{"session issued without an ip address", "test-agent", "", "test-agent", "10.0.0.1"},
+		{"neither side has a client", "", "", "", ""},
+	}
+	for _, policy := range []DriftPolicy{DriftWarn, DriftStrict} {
+		for _, tt := range tests {
+			t.Run(string(policy)+" "+tt.name, func(t *testing.T) {
+				repo := &fakeRepo{}
+				s := newTestService(repo, Config{RefreshDrift: policy})
+				registered, err := s.Register(registerRequest("siti@example.com", true), tt.issuedUA, tt.issuedIP)
+				if err != nil {
+					t.Fatal(err)
+				}
+				session := repo.sessions[len(repo.sessions)-1]
+
+				// A missing value cannot be compared, so it is a mismatch:
+				// warn logs it and refreshes, strict refuses
+				_, err = s.Refresh(registered.RefreshToken, tt.sentUA, tt.sentIP)
+				if policy == DriftWarn {
+					if err != nil {
+						t.Fatalf("refresh: %v", err)
+					}
+					return
+				}
+				if !isAppError(err, apperrors.CodeUnauthorized) {
+					t.Fatalf("refresh: err = %v, want UNAUTHORIZED", err)
+				}
+				if !session.Revoked {
+					t.Error("session is not revoked after the mismatch")
+				}
+			})
+		}
+	}
+}
+
+func TestTrustedSessionRefreshesFromAnotherClient(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{RefreshDrift: DriftStrict})
+	untrusted, err := s.Register(registerRequest("siti@example.com", true), "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(untrusted.RefreshToken, "test-agent", "10.9.9.9"); err == nil {
+		t.Fatal("refresh of an untrusted session from another IP succeeded, want it refused")
+	}
+
+	trusted, err := s.Login(auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "Rahasia#2025"}, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := testNow.Add(time.Hour)
+	repo.sessions[len(repo.sessions)-1].TrustedUntil = &until
+	tokens, err := s.Refresh(trusted.RefreshToken, "other-agent", "10.9.9.9")
+	if err != nil {
+		t.Fatalf("refresh of a trusted session from another client: %v", err)
+	}
+	// The trust moves to the rotated session
+	if rt, _ := repo.FindRefreshToken(hashRefreshToken(tokens.RefreshToken)); rt == nil || !rt.IsTrusted(testNow) {
+		t.Error("rotated session is not trusted")
+	}
+}
+
+func TestOTPInvalidatedAfterTooManyWrongCodes(t *testing.T) {
+	const perCode, right, wrong = 3, "123456", "000000"
+	tests := []struct {
+		name  string
+		guess func(s *service) error
+	}{
+		{"verify", func(s *service) error { return s.VerifyOTP("siti@example.com", wrong, "10.0.0.1") }},
+		{"reset", func(s *service) error {
+			return s.ResetPassword("siti@example.com", wrong, "Rahasia#2026", "test-agent", "10.0.0.1")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			repo := &fakeRepo{users: []*auth.User{{ID: userID, Username: "siti", Email: "siti@example.com"}}}
+			// The per-IP and per-email limits stay out of the way
+			s := newTestService(repo, Config{
+				OTPPepper:   testPepper,
+				OTPAttempts: OTPAttemptLimits{PerIP: 100, PerEmail: 100, PerCode: perCode},
+			})
+			repo.otps = append(repo.otps, &auth.OTP{
+				ID: uuid.New(), UserID: userID, Code: otp.Hash(testPepper, right),
+				Purpose: auth.OTPPurposeForgotPassword, ExpiresAt: testNow.Add(10 * time.Minute),
+			})
+
+			// One wrong code short of the limit the code still works
+			for range perCode - 1 {
+				if err := tt.guess(s); !isAppError(err, apperrors.CodeInvalidOTP) {
+					t.Fatalf("wrong code: err = %v, want INVALID_OTP", err)
+				}
+			}
+			if err := s.VerifyOTP("siti@example.com", right, "10.0.0.1"); err != nil {
+				t.Fatalf("right code after %d wrong ones: %v", perCode-1, err)
+			}
+
+			// The wrong code reaching the limit invalidates it for good
+			if err := tt.guess(s); !isAppError(err, apperrors.CodeOTPAttemptsExceeded) {
+				t.Fatalf("wrong code reaching the limit: err = %v, want OTP_ATTEMPTS_EXCEEDED", err)
+			}
+			if err := s.VerifyOTP("siti@example.com", right, "10.0.0.1"); !isAppError(err, apperrors.CodeInvalidOTP) {
+				t.Errorf("right code after the limit: err = %v, want INVALID_OTP", err)
+			}
+			if err := tt.guess(s); !isAppError(err, apperrors.CodeInvalidOTP) {
+				t.Errorf("wrong code after the limit: err = %v, want INVALID_OTP", err)
+			}
+		})
+	}
+}
+
+func TestTrustedDeviceSkipsLoginCode(t *testing.T) {
+	s, repo, sent, siti := newLoginCodeService(t)
+	req := auth.LoginRequest{UsernameOrEmail: "siti", Password: "Rahasia#2025"}
+	if _, err := s.Login(req, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeLoginCodeRequired) {
+		t.Fatalf("login without a code: err = %v, want LOGIN_CODE_REQUIRED", err)
+	}
+	req.Code = sent.lastCode(t)
+	login, err := s.Login(req, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh := login.RefreshToken
+	trusted := true
+	session := repo.sessions[0]
+	if _, err := s.UpdateSession(siti.ID, session.ID, auth.UpdateSessionRequest{Trusted: &trusted}, "10.0.0.1"); !isAppError(err, apperrors.CodeLoginCodeRequired) {
+		t.Fatalf("trust without a code: err = %v, want LOGIN_CODE_REQUIRED", err)
+	}
+	if _, err := s.UpdateSession(siti.ID, session.ID, auth.UpdateSessionRequest{Trusted: &trusted, Code: sent.lastCode(t)}, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The refresh token of the trusted session is found by its digest and
+	// stands in for the code
+	before := len(sent.messages)
+	req.Code, req.RefreshToken = "", refresh
+	if _, err := s.Login(req, "test-agent", "10.0.0.1"); err != nil {
+		t.Fatalf("login from the trusted device: %v", err)
+	}
+	if len(sent.messages) != before {
+		t.Error("a login from the trusted device sent a code")
+	}
+}
+
+func TestOTPGuessingIsBlocked(t *testing.T) {
+	repo := &fakeRepo{}
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		repo.users = append(repo.users, &auth.User{ID: uuid.New(), Username: strings.TrimSuffix(email, "@example.com"), Email: email, IsActive: true})
+	}
+	s := newTestService(repo, Config{
+		OTPPepper:   testPepper,
+		OTPAttempts: OTPAttemptLimits{PerIP: 3, PerEmail: 2, Window: 15 * time.Minute},
+	})
+	const wrong = "000000"
+
+	// One client guesses a code for each of several users; its third wrong
+	// code blocks it for everyone, including users it did not try yet
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := s.VerifyOTP(email, wrong, "10.0.0.9"); !isAppError(err, apperrors.CodeInvalidOTP) {
+			t.Fatalf("verify for %s: err = %v, want INVALID_OTP", email, err)
+		}
+	}
+	err := s.VerifyOTP("d@example.com", wrong, "10.0.0.9")
+	if !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Fatalf("verify from a blocked IP: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+	if appErr, _ := apperrors.IsAppError(err); appErr.RetryAfter <= 0 || appErr.RetryAfter > 15*time.Minute {
+		t.Errorf("retry after %s, want within the window", appErr.RetryAfter)
+	}
+	if err := s.ResetPassword("d@example.com", wrong, "Rahasia#2026", "test-agent", "10.0.0.9"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("reset from a blocked IP: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+
+	// Guesses for one user from several clients are blocked per email, and
+	// unknown users are counted the same way
+	if err := s.VerifyOTP("a@example.com", wrong, "10.0.0.8"); !isAppError(err, apperrors.CodeInvalidOTP) {
+		t.Errorf("second guess for a: err = %v, want INVALID_OTP", err)
+	}
+	if err := s.VerifyOTP("a@example.com", wrong, "10.0.0.7"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("third guess for a: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+	if err := s.ResetPassword("A@example.com", wrong, "Rahasia#2026", "test-agent", "10.0.0.7"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("reset for a: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+	for _, ip := range []string{"10.0.1.1", "10.0.1.2"} {
+		if err := s.VerifyOTP("nobody@example.com", wrong, ip); !isAppError(err, apperrors.CodeInvalidOTP) {
+			t.Errorf("guess for an unknown user: err = %v, want INVALID_OTP", err)
+		}
+	}
+	if err := s.VerifyOTP("nobody@example.com", wrong, "10.0.1.3"); !isAppError(err, apperrors.CodeTooManyRequests) {
+		t.Errorf("third guess for an unknown user: err = %v, want TOO_MANY_REQUESTS", err)
+	}
+
+	// Other users and clients are not affected
+	if err := s.VerifyOTP("b@example.com", wrong, "10.0.0.6"); !isAppError(err, apperrors.CodeInvalidOTP) {
+		t.Errorf("guess for b from another client: err = %v, want INVALID_OTP", err)
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{RequireVerifiedEmail: true})
+
+	// Registering creates the user but logs nobody in until they verify
+	data, err := s.Register(registerRequest("siti@example.com", true), "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.AccessToken != "" || len(repo.sessions) != 0 {
+		t.Fatalf("got access token %q and %d sessions, want none", data.AccessToken, len(repo.sessions))
+	}
+
+	login := auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "Rahasia#2025"}
+	if _, err := s.Login(login, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeEmailNotVerified) {
+		t.Fatalf("login: err = %v, want EMAIL_NOT_VERIFIED", err)
+	}
+
+	verified := testNow
+	repo.users[0].EmailVerifiedAt = &verified
+	if _, err := s.Login(login, "test-agent", "10.0.0.1"); err != nil {
+		t.Fatalf("login after verifying: %v", err)
+	}
+
+	var refused int
+	for _, e := range repo.events {
+		if e.Type == auth.AuthEventLogin && !e.Success && e.Reason == reasonEmailNotVerified {
+			refused++
+		}
+	}
+	if refused != 2 {
+		t.Errorf("recorded %d logins refused for the unverified email, want 2", refused)
+	}
+}
+
+func TestDeactivatedUserCannotLogIn(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo, Config{})
+	data, err := s.Register(registerRequest("siti@example.com", true), "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.users[0].IsActive = false
+
+	// Told only with the right password
+	login := auth.LoginRequest{UsernameOrEmail: "siti_rahma", Password: "Wrong#2025"}
+	if _, err := s.Login(login, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeInvalidCredentials) {
+		t.Errorf("login with a wrong password: err = %v, want INVALID_CREDENTIALS", err)
+	}
+	login.Password = "Rahasia#2025"
+	if _, err := s.Login(login, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeAccountDisabled) {
+		t.Errorf("login: err = %v, want ACCOUNT_DISABLED", err)
+	}
+	if _, err := s.Refresh(data.RefreshToken, "test-agent", "10.0.0.1"); !isAppError(err, apperrors.CodeAccountDisabled) {
+		t.Errorf("refresh: err = %v, want ACCOUNT_DISABLED", err)
+	}
+}
+
+// sentMail records the emails sent to it
+type sentMail struct{ emails [][3]string }
+
+func (m *sentMail) Send(to, subject, htmlBody string) error {
+	m.emails = append(m.emails, [3]string{to, subject, htmlBody})
+	return nil
+}
+
+func TestForgotEmailsTheCodeAsHTML(t *testing.T) {
+	repo := &fakeRepo{users: []*auth.User{{ID: uuid.New(), Email: "siti@example.com", Fullname: "Siti <Rahma>", PreferredOTPChannel: "email"}}}
+	mail := &sentMail{}
+	s := newTestService(repo, Config{
+		OTPPepper: testPepper,
+		Notifier:  notifier.NewDispatcher().Register(notifier.ChannelEmail, notifier.NewEmailSender(mail)),
+	})
+
+	if err := s.Forgot("siti@example.com", "test-agent", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mail.emails) != 1 {
+		t.Fatalf("%d emails sent, want 1", len(mail.emails))
+	}
+	to, subject, body := mail.emails[0][0], mail.emails[0][1], mail.emails[0][2]
+	code := regexp.MustCompile(`>(\d{6})<`).FindStringSubmatch(body)
+	if to != "siti@example.com" || subject != forgotPasswordMessage.subject || code == nil {
+		t.Fatalf("email to %s with subject %q does not show the code:\n%s", to, subject, body)
+	}
+	if !strings.Contains(body, "Halo Siti &lt;Rahma&gt;,") {
+		t.Errorf("email does not greet the user with their escaped name:\n%s", body)
+	}
+	if err := s.VerifyOTP("siti@example.com", code[1], "10.0.0.1"); err != nil {
+		t.Errorf("verifying the emailed code: %v", err)
+	}
+}
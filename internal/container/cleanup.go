@@ -0,0 +1,86 @@
+package container
+
+import (
+	"context"
+	"time"
+
+	"backend-service-internpro/internal/audit"
+	auditService "backend-service-internpro/internal/audit/service"
+	"backend-service-internpro/internal/pkg/logger"
+	rbacService "backend-service-internpro/internal/rbac/service"
+	schoolService "backend-service-internpro/internal/school/service"
+	usageService "backend-service-internpro/internal/usage/service"
+	userService "backend-service-internpro/internal/user/service"
+)
+
+// cleanupInterval is how often the cleanup job runs
+const cleanupInterval = time.Hour
+
+// cleanup holds the periodic maintenance tasks and their settings
+type cleanup struct {
+	users               userService.Service
+	schools             schoolService.SchoolService
+	rbac                rbacService.Service
+	usage               usageService.Service
+	audit               auditService.Service
+	identifierRetention time.Duration
+	contactStaleAfter   time.Duration
+}
+
+// start runs the cleanup in the background for the lifetime of the process,
+// starting right away
+func (c cleanup) start() {
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			c.run()
+			<-ticker.C
+		}
+	}()
+}
+
+func (c cleanup) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	released, err := c.users.ReleaseExpiredIdentifiers(ctx, c.identifierRetention)
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to release identifiers of deleted users", err)
+	} else if released > 0 {
+		logger.Global().Service().Info("released identifiers of deleted users", "count", released)
+	}
+
+	flagged, err := c.schools.FlagStaleContacts(ctx, c.contactStaleAfter)
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to flag stale partner contacts", err)
+	} else if flagged > 0 {
+		logger.Global().Service().Info("flagged stale partner contacts", "count", flagged)
+	}
+
+	pruned, err := c.rbac.PruneOrphans(ctx)
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to prune orphaned role assignments", err)
+	} else if pruned.Total() > 0 {
+		logger.Global().Service().Info("pruned orphaned role assignments",
+			"role_menus", pruned.RoleMenus, "role_permissions", pruned.RolePermissions, "user_roles", pruned.UserRoles)
+	}
+
+	rolled, err := c.usage.RollUp(ctx)
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to roll up usage counters", err)
+	} else if rolled.RolledUp > 0 || rolled.DeletedMonths > 0 {
+		logger.Global().Service().Info("rolled up usage counters",
+			"daily_rows", rolled.RolledUp, "expired_monthly_rows", rolled.DeletedMonths)
+	}
+
+	deleted, err := c.audit.Prune(ctx)
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to prune audit events", err)
+	}
+	if deleted.Total() > 0 {
+		logger.Global().Service().Info("pruned audit events past their retention",
+			"auth", deleted[audit.SourceAuth], "user", deleted[audit.SourceUser])
+	}
+}
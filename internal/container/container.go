@@ -1,54 +1,151 @@
 package container
 
 import (
+	"crypto/hkdf"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"backend-service-internpro/config"
+	apiKeyRepo "backend-service-internpro/internal/apikey/repository"
+	apiKeyService "backend-service-internpro/internal/apikey/service"
+	auditlog "backend-service-internpro/internal/audit"
+	auditRepo "backend-service-internpro/internal/audit/repository"
+	auditService "backend-service-internpro/internal/audit/service"
 	authRepo "backend-service-internpro/internal/auth/repository"
 	authService "backend-service-internpro/internal/auth/service"
+	integrityRepo "backend-service-internpro/internal/integrity/repository"
+	integrityService "backend-service-internpro/internal/integrity/service"
+	logLevelService "backend-service-internpro/internal/loglevel/service"
+	notificationRepo "backend-service-internpro/internal/notification/repository"
+	notificationService "backend-service-internpro/internal/notification/service"
+	"backend-service-internpro/internal/pkg/audit"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/hasher"
+	"backend-service-internpro/internal/pkg/httpclient"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/jobs"
 	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/mailer"
+	"backend-service-internpro/internal/pkg/middleware"
 	"backend-service-internpro/internal/pkg/migration"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/pkg/oauth"
+	"backend-service-internpro/internal/pkg/revocation"
+	"backend-service-internpro/internal/pkg/validator"
+	privacyRepo "backend-service-internpro/internal/privacy/repository"
+	privacyService "backend-service-internpro/internal/privacy/service"
+	rbacCache "backend-service-internpro/internal/rbac/cache"
 	rbacRepo "backend-service-internpro/internal/rbac/repository"
 	rbacService "backend-service-internpro/internal/rbac/service"
 	schoolRepo "backend-service-internpro/internal/school/repository"
 	schoolService "backend-service-internpro/internal/school/service"
+	searchService "backend-service-internpro/internal/search/service"
+	statsRepo "backend-service-internpro/internal/stats/repository"
+	statsService "backend-service-internpro/internal/stats/service"
+	usageRepo "backend-service-internpro/internal/usage/repository"
+	usageService "backend-service-internpro/internal/usage/service"
 	userRepo "backend-service-internpro/internal/user/repository"
 	userService "backend-service-internpro/internal/user/service"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 // Container holds all dependencies
 type Container struct {
-	DB            *gorm.DB
-	Config        *Config
-	AuthRepo      authRepo.Repository
-	AuthService   authService.Service
-	UserRepo      userRepo.Repository
-	UserService   userService.Service
-	RBACRepo      rbacRepo.Repository
-	RBACService   rbacService.Service
-	SchoolRepo    schoolRepo.SchoolRepository
-	SchoolService schoolService.SchoolService
-	JWTSecrets    jwtpkg.Secrets
+	DB                  *gorm.DB
+	Config              *Config
+	AuthRepo            authRepo.Repository
+	AuthService         authService.Service
+	UserRepo            userRepo.Repository
+	UserService         userService.Service
+	RBACRepo            rbacRepo.Repository
+	RBACService         rbacService.Service
+	SchoolRepo          schoolRepo.SchoolRepository
+	SchoolService       schoolService.SchoolService
+	SearchService       searchService.Service
+	StatsService        statsService.Service
+	IntegrityService    integrityService.Service
+	APIKeyService       apiKeyService.Service
+	LogLevelService     logLevelService.Service
+	UsageService        usageService.Service
+	NotificationService notificationService.Service
+	PrivacyService      privacyService.Service
+	AuditService        auditService.Service
+	JWTSecrets          jwtpkg.Secrets
+	Revocations         revocation.Store
+	RoutePolicy         middleware.RoutePolicy
 }
 
 // Config holds all configuration values
 type Config struct {
-	Server ServerConfig
-	JWT    JWTConfig
-	SMTP   SMTPConfig
+	Server     ServerConfig
+	JWT        JWTConfig
+	SMTP       SMTPConfig
+	OTPGateway OTPGatewayConfig
+	OTP        OTPConfig
+	Login      LoginConfig
+	Password   hasher.Config
+	OAuth      OAuthConfig
+	RBAC       RBACConfig
+	Jobs       jobs.Config
+	Privacy    PrivacyConfig
+	User       UserConfig
+	School     SchoolConfig
+	Audit      AuditConfig
+	Stats      StatsConfig
+	Usage      UsageConfig
 }
 
 type ServerConfig struct {
 	Port string
+	// PublicURL is the externally reachable base URL used in emailed links
+	PublicURL string
+	// RateLimitExemptPaths and RateLimitExemptNetworks bypass the per-IP rate limit
+	RateLimitExemptPaths    []string
+	RateLimitExemptNetworks []*net.IPNet
+	// QuietProbeLogs skips request logging for the rate limit exempt paths
+	QuietProbeLogs bool
+	// LogUserIDKey, when set, hashes the user IDs written to request logs
+	LogUserIDKey []byte
+	// WarnRouteCollisions logs routes registered twice instead of failing startup
+	WarnRouteCollisions bool
+	// DebugEndpoints exposes the /debug routes
+	DebugEndpoints bool
 }
 
 type JWTConfig struct {
-	AccessSecret    []byte
-	RefreshSecret   []byte
+	AccessSecret []byte
+	// OldAccessSecrets are retired secrets, newest first, that still verify
+	// access tokens and links signed before the last rotation
+	OldAccessSecrets [][]byte
+	RefreshSecret    []byte
+	// Alg signs access tokens, jwtpkg.AlgHS256 or jwtpkg.AlgRS256
+	Alg string
+	// AccessKey signs RS256 access tokens; nil when none is configured
+	AccessKey *rsa.PrivateKey
+	KeyID     string
+	// AcceptHS256 keeps accepting HS256 access tokens after switching to RS256
+	AcceptHS256     bool
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
+	// TrustedDeviceTTL is how long a session stays trusted once marked
+	TrustedDeviceTTL time.Duration
+	// RefreshDrift is how refreshes from another user agent or IP are treated
+	RefreshDrift authService.DriftPolicy
+	// SlidingRefresh extends sessions on every refresh, up to RefreshMaxAge
+	// after login
+	SlidingRefresh bool
+	RefreshMaxAge  time.Duration
+	// ImpersonationTTL is how long a super admin's impersonation token lasts
+	ImpersonationTTL time.Duration
 }
 
 type SMTPConfig struct {
@@ -58,14 +155,132 @@ type SMTPConfig struct {
 	Pass string
 }
 
+// OTPGatewayConfig configures the HTTP gateway used for WhatsApp/SMS OTP delivery
+type OTPGatewayConfig struct {
+	URL    string
+	APIKey string
+}
+
+// OTPConfig holds OTP storage settings
+type OTPConfig struct {
+	// Pepper keys the hash under which OTP codes are stored
+	Pepper []byte
+	// Attempts limits failed OTP checks per client IP and per email
+	Attempts authService.OTPAttemptLimits
+	// ResendCooldown is how long /forgot and /resend-otp wait after the last code
+	ResendCooldown time.Duration
+}
+
+// LoginConfig holds password login settings
+type LoginConfig struct {
+	// Codes asks devices that are not trusted for a login code
+	Codes bool
+	// Lockout locks an account after repeated wrong passwords
+	Lockout authService.LoginLockout
+	// Rate limits attempts per username or email, right or wrong
+	Rate authService.LoginRate
+	// RequireVerifiedEmail refuses logins until the email is verified
+	RequireVerifiedEmail bool
+}
+
+// OAuthConfig holds sign-in through external identity providers
+type OAuthConfig struct {
+	// Google is the OAuth client; Google sign-in is off without a client ID
+	Google oauth.GoogleConfig
+	// AutoProvision creates users for provider accounts whose email no user
+	// has; otherwise those cannot sign in
+	AutoProvision bool
+}
+
+// RBACConfig holds RBAC settings
+type RBACConfig struct {
+	// RoleRestoreWindow bounds how long a deleted role or permission can be
+	// restored
+	RoleRestoreWindow time.Duration
+	// EmbedLimit caps the related items embedded in a response
+	EmbedLimit int
+	// RoutePolicy controls how route permissions are applied
+	RoutePolicy middleware.RoutePolicy
+	// CacheTTL is how long a user's permissions are cached
+	CacheTTL time.Duration
+	// Redis shares the permission cache between servers when Addr is set;
+	// otherwise each server caches in memory
+	Redis redis.Options
+}
+
+// PrivacyConfig holds personal data export settings
+type PrivacyConfig struct {
+	// ExportTTL is how long a generated data export can be downloaded
+	ExportTTL time.Duration
+	// LinkSecret signs export download links and erase confirmations
+	LinkSecret []byte
+}
+
+// AuditConfig holds audit log settings
+type AuditConfig struct {
+	// Retention is how long the cleanup job keeps the events of each
+	// audit source
+	Retention map[string]time.Duration
+}
+
+// StatsConfig holds dashboard statistics settings
+type StatsConfig struct {
+	// CacheTTL is how long computed counts are served before recomputing
+	CacheTTL time.Duration
+}
+
+// UsageConfig holds API usage counter settings
+type UsageConfig struct {
+	// FlushInterval is how often recorded counts are written
+	FlushInterval time.Duration
+	// DailyRetention is how long daily counts are kept before the cleanup
+	// job sums them per month; MonthlyRetention bounds the monthly sums
+	DailyRetention   time.Duration
+	MonthlyRetention time.Duration
+}
+
+// UserConfig holds user account settings
+type UserConfig struct {
+	// IdentifierRetention is how long a deleted user keeps their username
+	// and email before the cleanup job releases them
+	IdentifierRetention time.Duration
+}
+
+// SchoolConfig holds school and partner settings
+type SchoolConfig struct {
+	// ContactVerifyTTL is how long a partner contact verification link is valid
+	ContactVerifyTTL time.Duration
+	// ContactStaleAfter is how long a partner contact may go unverified
+	// before the cleanup job flags it
+	ContactStaleAfter time.Duration
+}
+
+// Options overrides how NewContainerWithOptions builds the services; the
+// zero value is what the server runs with
+type Options struct {
+	// Clock and IDs are given to the auth, RBAC, user and school services,
+	// and IDs to the RBAC repository; nil uses the wall clock and random
+	// UUIDs
+	Clock clock.Clock
+	IDs   idgen.Generator
+}
+
 // NewContainer creates and initializes all dependencies
 func NewContainer() (*Container, error) {
+	return NewContainerWithOptions(Options{})
+}
+
+// NewContainerWithOptions creates and initializes all dependencies, with
+// the given overrides
+func NewContainerWithOptions(opts Options) (*Container, error) {
 	// Load configuration
 	cfg, err := loadConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	initValidation()
+
 	// Initialize database
 	db, err := initDatabase()
 	if err != nil {
@@ -74,37 +289,150 @@ func NewContainer() (*Container, error) {
 
 	// Create JWT secrets
 	jwtSecrets := jwtpkg.Secrets{
-		Access:  cfg.JWT.AccessSecret,
-		Refresh: cfg.JWT.RefreshSecret,
+		Access:      cfg.JWT.AccessSecret,
+		OldAccess:   cfg.JWT.OldAccessSecrets,
+		Refresh:     cfg.JWT.RefreshSecret,
+		Alg:         cfg.JWT.Alg,
+		AccessKey:   cfg.JWT.AccessKey,
+		KeyID:       cfg.JWT.KeyID,
+		AcceptHS256: cfg.JWT.AcceptHS256,
 	}
 
 	// Initialize repositories
 	authRepository := authRepo.New(db)
 	userRepository := userRepo.New(db)
-	rbacRepository := rbacRepo.NewRepository(db)
+	rbacRepository := rbacRepo.NewRepository(db, opts.IDs)
 	schoolRepository := schoolRepo.NewSchoolRepository(db)
+	notificationRepository := notificationRepo.New(db)
+	privacyRepository := privacyRepo.New(db)
+	statsRepository := statsRepo.New(db)
+	usageRepository := usageRepo.New(db)
 
 	// Initialize services with configuration
+	var redisClient *redis.Client
+	if cfg.RBAC.Redis.Addr != "" {
+		redisClient = redis.NewClient(&cfg.RBAC.Redis)
+	}
+	// Background work such as notification delivery runs on one bounded
+	// pool, and calls to outside services share one client. The runner
+	// retries failed deliveries, so the client sends each request once.
+	jobRunner := jobs.NewRunner(cfg.Jobs)
+	dispatcher := newNotifier(cfg, httpclient.New(httpclient.Config{MaxAttempts: 1}))
+	statsSvc := statsService.New(statsRepository, cfg.Stats.CacheTTL)
+	integritySvc := integrityService.New(integrityRepo.New(db))
+	usageSvc := usageService.New(usageRepository, usageService.Config{
+		FlushInterval:    cfg.Usage.FlushInterval,
+		DailyRetention:   cfg.Usage.DailyRetention,
+		MonthlyRetention: cfg.Usage.MonthlyRetention,
+	})
+	usageSvc.Start()
+	notificationSvc := notificationService.New(notificationRepository, dispatcher, jobRunner)
+	rbacSvc := rbacService.NewServiceWithConfig(rbacRepository, rbacService.Config{
+		Events:        rbacService.Publishers{notificationSvc, statsSvc},
+		RestoreWindow: cfg.RBAC.RoleRestoreWindow,
+		EmbedLimit:    cfg.RBAC.EmbedLimit,
+		Cache:         newPermissionCache(cfg.RBAC, redisClient),
+		Clock:         opts.Clock,
+		IDs:           opts.IDs,
+	})
+	revocations := newRevocationStore(redisClient)
+	passwords, err := hasher.New(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	var google *oauth.Google
+	if cfg.OAuth.Google.ClientID != "" {
+		google = oauth.NewGoogle(cfg.OAuth.Google)
+	}
 	authSvc := authService.NewWithConfig(authRepository, jwtSecrets, authService.Config{
-		AccessTTL:  cfg.JWT.AccessTokenTTL,
-		RefreshTTL: cfg.JWT.RefreshTokenTTL,
+		AccessTTL:            cfg.JWT.AccessTokenTTL,
+		RefreshTTL:           cfg.JWT.RefreshTokenTTL,
+		Notifier:             dispatcher,
+		Jobs:                 jobRunner,
+		OTPPepper:            cfg.OTP.Pepper,
+		TrustedDeviceTTL:     cfg.JWT.TrustedDeviceTTL,
+		RefreshDrift:         cfg.JWT.RefreshDrift,
+		LoginCodes:           cfg.Login.Codes,
+		SlidingRefresh:       cfg.JWT.SlidingRefresh,
+		RefreshMaxAge:        cfg.JWT.RefreshMaxAge,
+		ImpersonationTTL:     cfg.JWT.ImpersonationTTL,
+		Passwords:            passwords,
+		Google:               google,
+		OAuthAutoProvision:   cfg.OAuth.AutoProvision,
+		OTPAttempts:          cfg.OTP.Attempts,
+		OTPResendCooldown:    cfg.OTP.ResendCooldown,
+		LoginLockout:         cfg.Login.Lockout,
+		LoginRate:            cfg.Login.Rate,
+		RequireVerifiedEmail: cfg.Login.RequireVerifiedEmail,
+		UserEvents:           statsSvc,
+		Roles:                rbacSvc,
+		Revocations:          revocations,
+		Users:                userRepository,
+		Clock:                opts.Clock,
+		IDs:                  opts.IDs,
+	})
+	userSvc := userService.NewWithConfig(userRepository, rbacSvc, userService.Config{
+		Events:    userService.Publishers{statsSvc, authSvc},
+		Passwords: passwords,
+		Clock:     opts.Clock,
+		IDs:       opts.IDs,
 	})
-	userSvc := userService.New(userRepository)
-	rbacSvc := rbacService.NewService(rbacRepository)
-	schoolSvc := schoolService.NewSchoolService(schoolRepository)
+	schoolSvc := schoolService.NewSchoolServiceWithConfig(schoolRepository, schoolService.Config{
+		Notifier:         dispatcher,
+		SigningKey:       cfg.JWT.AccessSecret,
+		OldSigningKeys:   cfg.JWT.OldAccessSecrets,
+		PublicURL:        cfg.Server.PublicURL,
+		ContactVerifyTTL: cfg.School.ContactVerifyTTL,
+		Clock:            opts.Clock,
+		IDs:              opts.IDs,
+	})
+	searchSvc := searchService.New(userRepository, schoolRepository, rbacRepository, rbacSvc)
+	privacySvc := privacyService.New(privacyRepository, rbacSvc, privacyService.Config{
+		SigningKey:  cfg.Privacy.LinkSecret,
+		ExportTTL:   cfg.Privacy.ExportTTL,
+		Revocations: revocations,
+		AccessTTL:   cfg.JWT.AccessTokenTTL,
+		Clock:       opts.Clock,
+		IDs:         opts.IDs,
+	})
+	auditSvc := auditService.New(auditRepo.New(db), auditService.Config{
+		Retention: cfg.Audit.Retention,
+		Clock:     opts.Clock,
+	})
+
+	cleanup{
+		users:               userSvc,
+		schools:             schoolSvc,
+		rbac:                rbacSvc,
+		usage:               usageSvc,
+		audit:               auditSvc,
+		identifierRetention: cfg.User.IdentifierRetention,
+		contactStaleAfter:   cfg.School.ContactStaleAfter,
+	}.start()
 
 	return &Container{
-		DB:            db,
-		Config:        cfg,
-		AuthRepo:      authRepository,
-		AuthService:   authSvc,
-		UserRepo:      userRepository,
-		UserService:   userSvc,
-		RBACRepo:      rbacRepository,
-		RBACService:   rbacSvc,
-		SchoolRepo:    schoolRepository,
-		SchoolService: schoolSvc,
-		JWTSecrets:    jwtSecrets,
+		DB:                  db,
+		Config:              cfg,
+		AuthRepo:            authRepository,
+		AuthService:         authSvc,
+		UserRepo:            userRepository,
+		UserService:         userSvc,
+		RBACRepo:            rbacRepository,
+		RBACService:         rbacSvc,
+		SchoolRepo:          schoolRepository,
+		SchoolService:       schoolSvc,
+		SearchService:       searchSvc,
+		StatsService:        statsSvc,
+		IntegrityService:    integritySvc,
+		APIKeyService:       apiKeyService.NewWithConfig(apiKeyRepo.New(db), apiKeyService.Config{Clock: opts.Clock, IDs: opts.IDs}),
+		LogLevelService:     logLevelService.New(),
+		UsageService:        usageSvc,
+		NotificationService: notificationSvc,
+		PrivacyService:      privacySvc,
+		AuditService:        auditSvc,
+		JWTSecrets:          jwtSecrets,
+		Revocations:         revocations,
+		RoutePolicy:         cfg.RBAC.RoutePolicy,
 	}, nil
 }
 
@@ -112,15 +440,80 @@ func loadConfig() (*Config, error) {
 	// Initialize legacy config for now
 	config.InitConfig()
 
+	exemptNetworks, err := parseNetworks(config.LoadEnvVar("RATE_LIMIT_EXEMPT_CIDRS"))
+	if err != nil {
+		return nil, err
+	}
+
+	jwtAlg := getEnvWithDefault("JWT_ALG", jwtpkg.AlgHS256)
+	accessKey, err := loadAccessKey()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case jwtAlg != jwtpkg.AlgHS256 && jwtAlg != jwtpkg.AlgRS256:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q, use HS256 or RS256", jwtAlg)
+	case jwtAlg == jwtpkg.AlgRS256 && accessKey == nil:
+		return nil, errors.New("JWT_ALG=RS256 needs JWT_PRIVATE_KEY or JWT_PRIVATE_KEY_FILE")
+	}
+	otpPepper, err := dedicatedSecret("OTP_PEPPER", "otp-pepper")
+	if err != nil {
+		return nil, err
+	}
+	privacyLinkSecret, err := dedicatedSecret("PRIVACY_LINK_SECRET", "privacy-link")
+	if err != nil {
+		return nil, err
+	}
+	refreshSecret, err := dedicatedSecret("JWT_REFRESH_SECRET", "refresh-token")
+	if err != nil {
+		return nil, err
+	}
+	logKey, err := logUserIDKey()
+	if err != nil {
+		return nil, err
+	}
+	refreshDrift := authService.DriftPolicy(getEnvWithDefault("REFRESH_DRIFT_POLICY", string(authService.DefaultRefreshDrift)))
+	if !refreshDrift.Valid() {
+		return nil, fmt.Errorf("unsupported REFRESH_DRIFT_POLICY %q, use off, warn or strict", refreshDrift)
+	}
+
+	google := oauth.GoogleConfig{
+		ClientID:     config.LoadEnvVar("GOOGLE_CLIENT_ID"),
+		ClientSecret: config.LoadEnvVar("GOOGLE_CLIENT_SECRET"),
+		RedirectURL:  config.LoadEnvVar("GOOGLE_REDIRECT_URL"),
+	}
+	if google.ClientID != "" && (google.ClientSecret == "" || google.RedirectURL == "") {
+		return nil, errors.New("GOOGLE_CLIENT_ID needs GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL")
+	}
+
+	port := getEnvWithDefault("APP_PORT", "8080")
+
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnvWithDefault("APP_PORT", "8080"),
+			Port:                    port,
+			PublicURL:               getEnvWithDefault("APP_PUBLIC_URL", "http://localhost:"+port),
+			RateLimitExemptPaths:    splitList(getEnvWithDefault("RATE_LIMIT_EXEMPT_PATHS", strings.Join(middleware.DefaultExemptPaths, ","))),
+			RateLimitExemptNetworks: exemptNetworks,
+			QuietProbeLogs:          getEnvWithDefault("LOG_SKIP_EXEMPT_PATHS", "false") == "true",
+			LogUserIDKey:            logKey,
+			WarnRouteCollisions:     getEnvWithDefault("ROUTE_COLLISIONS", "fail") == "warn",
+			DebugEndpoints:          getEnvWithDefault("DEBUG_ENDPOINTS", "false") == "true",
 		},
 		JWT: JWTConfig{
-			AccessSecret:    config.JwtSecret,
-			RefreshSecret:   config.JwtSecret, // Could be different
-			AccessTokenTTL:  config.JwtExpireTime,
-			RefreshTokenTTL: config.RefreshTokenExpire,
+			AccessSecret:     config.JwtSecret,
+			OldAccessSecrets: config.JwtOldSecrets,
+			RefreshSecret:    refreshSecret,
+			Alg:              jwtAlg,
+			AccessKey:        accessKey,
+			KeyID:            config.LoadEnvVar("JWT_KEY_ID"),
+			AcceptHS256:      getEnvWithDefault("JWT_ACCEPT_HS256", "true") == "true",
+			AccessTokenTTL:   config.JwtExpireTime,
+			RefreshTokenTTL:  config.RefreshTokenExpire,
+			TrustedDeviceTTL: time.Duration(getEnvIntWithDefault("TRUSTED_DEVICE_DAYS", 30)) * 24 * time.Hour,
+			RefreshDrift:     refreshDrift,
+			SlidingRefresh:   getEnvWithDefault("REFRESH_SLIDING", "false") == "true",
+			RefreshMaxAge:    time.Duration(getEnvIntWithDefault("REFRESH_MAX_AGE_DAYS", 30)) * 24 * time.Hour,
+			ImpersonationTTL: time.Duration(getEnvIntWithDefault("IMPERSONATION_TTL_MINUTES", 15)) * time.Minute,
 		},
 		SMTP: SMTPConfig{
 			Host: config.SmtpHost,
@@ -128,12 +521,157 @@ func loadConfig() (*Config, error) {
 			User: config.SmtpUser,
 			Pass: config.SmtpPass,
 		},
+		OTPGateway: OTPGatewayConfig{
+			URL:    config.LoadEnvVar("OTP_GATEWAY_URL"),
+			APIKey: config.LoadEnvVar("OTP_GATEWAY_API_KEY"),
+		},
+		OTP: OTPConfig{
+			Pepper: otpPepper,
+			Attempts: authService.OTPAttemptLimits{
+				PerIP:    getEnvIntWithDefault("OTP_ATTEMPTS_PER_IP", authService.DefaultOTPAttemptLimits.PerIP),
+				PerEmail: getEnvIntWithDefault("OTP_ATTEMPTS_PER_EMAIL", authService.DefaultOTPAttemptLimits.PerEmail),
+				Window:   time.Duration(getEnvIntWithDefault("OTP_ATTEMPT_WINDOW_MINUTES", 15)) * time.Minute,
+				PerCode:  getEnvIntWithDefault("OTP_ATTEMPTS_PER_CODE", authService.DefaultOTPAttemptLimits.PerCode),
+			},
+			ResendCooldown: time.Duration(getEnvIntWithDefault("OTP_RESEND_COOLDOWN_SECONDS", 60)) * time.Second,
+		},
+		Login: LoginConfig{
+			Codes: getEnvWithDefault("LOGIN_OTP", "false") == "true",
+			Lockout: authService.LoginLockout{
+				MaxFailures: getEnvIntWithDefault("LOGIN_MAX_FAILURES", authService.DefaultLoginLockout.MaxFailures),
+				Duration:    time.Duration(getEnvIntWithDefault("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+			},
+			Rate: authService.LoginRate{
+				Max:    getEnvIntWithDefault("LOGIN_ATTEMPTS_PER_ACCOUNT", authService.DefaultLoginRate.Max),
+				Window: time.Duration(getEnvIntWithDefault("LOGIN_ATTEMPT_WINDOW_SECONDS", 60)) * time.Second,
+			},
+			RequireVerifiedEmail: getEnvWithDefault("REQUIRE_EMAIL_VERIFICATION", "false") == "true",
+		},
+		Password: hasher.Config{
+			Algorithm:  getEnvWithDefault("PASSWORD_HASH_ALGORITHM", hasher.AlgBcrypt),
+			BcryptCost: getEnvIntWithDefault("BCRYPT_COST", 0),
+			Argon2: hasher.Argon2Params{
+				Memory:      uint32(getEnvIntWithDefault("ARGON2_MEMORY_KB", int(hasher.DefaultArgon2Params.Memory))),
+				Iterations:  uint32(getEnvIntWithDefault("ARGON2_ITERATIONS", int(hasher.DefaultArgon2Params.Iterations))),
+				Parallelism: uint8(getEnvIntWithDefault("ARGON2_PARALLELISM", int(hasher.DefaultArgon2Params.Parallelism))),
+			},
+		},
+		OAuth: OAuthConfig{
+			Google:        google,
+			AutoProvision: getEnvWithDefault("OAUTH_AUTO_PROVISION", "false") == "true",
+		},
+		RBAC: RBACConfig{
+			RoleRestoreWindow: time.Duration(getEnvIntWithDefault("ROLE_RESTORE_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			EmbedLimit:        getEnvIntWithDefault("EMBED_LIST_LIMIT", rbacService.DefaultEmbedLimit),
+			RoutePolicy: middleware.RoutePolicy{
+				ReportOnly: getEnvWithDefault("ROUTE_PERMISSIONS_REPORT_ONLY", "false") == "true",
+				// Unknown routes are denied in production unless configured otherwise
+				DenyUnknown: getEnvWithDefault("UNKNOWN_ROUTE_POLICY", defaultUnknownRoutePolicy()) == "deny",
+			},
+			CacheTTL: time.Duration(getEnvIntWithDefault("RBAC_CACHE_TTL_SECONDS", 60)) * time.Second,
+			Redis: redis.Options{
+				Addr:     getEnvWithDefault("REDIS_ADDR", ""),
+				Password: getEnvWithDefault("REDIS_PASSWORD", ""),
+				DB:       getEnvIntWithDefault("REDIS_DB", 0),
+			},
+		},
+		Jobs: jobs.Config{
+			Workers:     getEnvIntWithDefault("JOB_WORKERS", jobs.DefaultWorkers),
+			QueueSize:   getEnvIntWithDefault("JOB_QUEUE_SIZE", jobs.DefaultQueueSize),
+			MaxAttempts: getEnvIntWithDefault("JOB_MAX_ATTEMPTS", jobs.DefaultMaxAttempts),
+		},
+		Privacy: PrivacyConfig{
+			ExportTTL:  time.Duration(getEnvIntWithDefault("DATA_EXPORT_TTL_HOURS", 24)) * time.Hour,
+			LinkSecret: privacyLinkSecret,
+		},
+		Audit: AuditConfig{
+			Retention: map[string]time.Duration{
+				auditlog.SourceAuth: time.Duration(getEnvIntWithDefault("AUDIT_AUTH_RETENTION_DAYS", 365)) * 24 * time.Hour,
+				auditlog.SourceUser: time.Duration(getEnvIntWithDefault("AUDIT_USER_RETENTION_DAYS", 730)) * 24 * time.Hour,
+			},
+		},
+		Stats: StatsConfig{
+			CacheTTL: time.Duration(getEnvIntWithDefault("STATS_CACHE_TTL_SECONDS", 30)) * time.Second,
+		},
+		Usage: UsageConfig{
+			FlushInterval:    time.Duration(getEnvIntWithDefault("USAGE_FLUSH_SECONDS", 30)) * time.Second,
+			DailyRetention:   time.Duration(getEnvIntWithDefault("USAGE_DAILY_RETENTION_DAYS", 90)) * 24 * time.Hour,
+			MonthlyRetention: time.Duration(getEnvIntWithDefault("USAGE_MONTHLY_RETENTION_DAYS", 730)) * 24 * time.Hour,
+		},
+		User: UserConfig{
+			IdentifierRetention: time.Duration(getEnvIntWithDefault("USER_IDENTIFIER_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		},
+		School: SchoolConfig{
+			ContactVerifyTTL:  time.Duration(getEnvIntWithDefault("PARTNER_CONTACT_VERIFY_LINK_DAYS", 7)) * 24 * time.Hour,
+			ContactStaleAfter: time.Duration(getEnvIntWithDefault("PARTNER_CONTACT_STALE_DAYS", 365)) * 24 * time.Hour,
+		},
 	}, nil
 }
 
+// newPermissionCache shares the permission cache through Redis when it is
+// configured and keeps it in memory otherwise
+func newPermissionCache(cfg RBACConfig, client *redis.Client) rbacCache.Cache {
+	if client == nil {
+		return rbacCache.NewMemory(cfg.CacheTTL)
+	}
+	return rbacCache.NewRedis(client, cfg.CacheTTL)
+}
+
+// newRevocationStore keeps access token revocations in Redis when it is
+// configured. Otherwise they are kept in memory, which only suits a single
+// server: other servers keep accepting the revoked tokens, and a restart
+// forgets them, until the tokens expire.
+func newRevocationStore(client *redis.Client) revocation.Store {
+	if client == nil {
+		return revocation.NewMemory()
+	}
+	return revocation.NewRedis(client)
+}
+
+// newNotifier registers the configured delivery channels used for OTPs and
+// user notifications; gateway calls go through client
+func newNotifier(cfg *Config, client *httpclient.Client) *notifier.Dispatcher {
+	d := notifier.NewDispatcher()
+
+	switch {
+	case cfg.SMTP.Host != "":
+		d.Register(notifier.ChannelEmail, notifier.NewEmailSender(mailer.SMTP{
+			Host: cfg.SMTP.Host,
+			Port: cfg.SMTP.Port,
+			User: cfg.SMTP.User,
+			Pass: cfg.SMTP.Pass,
+			From: cfg.SMTP.User,
+		}))
+	case config.LoadEnvVar("APP_ENV") != "production":
+		// Development without SMTP: flows that email keep working and the
+		// dropped emails are logged. Production leaves email unavailable so
+		// callers report it instead of pretending to send.
+		d.Register(notifier.ChannelEmail, notifier.NewEmailSender(mailer.Noop{}))
+	}
+
+	if cfg.OTPGateway.URL != "" {
+		gw := notifier.GatewayConfig{URL: cfg.OTPGateway.URL, APIKey: cfg.OTPGateway.APIKey}
+		d.Register(notifier.ChannelWhatsApp, notifier.NewGatewaySender(notifier.ChannelWhatsApp, gw, client))
+		d.Register(notifier.ChannelSMS, notifier.NewGatewaySender(notifier.ChannelSMS, gw, client))
+	}
+
+	return d
+}
+
+// initValidation enforces the rolled out validation rules listed in
+// VALIDATION_ENFORCED_RULES; all other rules run in report-only mode.
+func initValidation() {
+	validator.InitRollout(strings.Split(config.LoadEnvVar("VALIDATION_ENFORCED_RULES"), ",")...)
+}
+
 func initDatabase() (*gorm.DB, error) {
 	db := config.DB
 
+	// Stamp CreatedBy/UpdatedBy/DeletedBy from the acting user in the context
+	if err := audit.RegisterCallbacks(db); err != nil {
+		return nil, err
+	}
+
 	// Run migrations automatically in development environment
 	if err := migration.AutoMigrateIfDevelopment(db); err != nil {
 		return nil, err
@@ -142,9 +680,101 @@ func initDatabase() (*gorm.DB, error) {
 	return db, nil
 }
 
+// defaultUnknownRoutePolicy denies routes without a permission entry in
+// production and allows them elsewhere
+func defaultUnknownRoutePolicy() string {
+	if config.LoadEnvVar("APP_ENV") == "production" {
+		return "deny"
+	}
+	return "allow"
+}
+
+// dedicatedSecret returns the secret in env or, when it is not set, one
+// derived with HKDF for purpose, so no two uses share a key. It derives from
+// the oldest JWT_SECRET listed rather than the signing one: rotating in a new
+// secret then keeps pending OTPs and links valid and log hashes unchanged,
+// until that oldest secret is dropped. The variable may not repeat a
+// JWT_SECRET.
+func dedicatedSecret(env, purpose string) ([]byte, error) {
+	jwtSecrets := append([][]byte{config.JwtSecret}, config.JwtOldSecrets...)
+	if value := config.LoadEnvVar(env); value != "" {
+		for _, secret := range jwtSecrets {
+			if value == string(secret) {
+				return nil, fmt.Errorf("%s must differ from JWT_SECRET", env)
+			}
+		}
+		return []byte(value), nil
+	}
+	if len(config.JwtSecret) == 0 {
+		return nil, fmt.Errorf("%s or JWT_SECRET must be set", env)
+	}
+	return hkdf.Key(sha256.New, jwtSecrets[len(jwtSecrets)-1], nil, purpose, 32)
+}
+
+// logUserIDKey returns the key user IDs are hashed with in request logs
+// when LOG_USER_ID=hashed, nil to log them as they are. Log readers must
+// not learn a signing key, so the key is LOG_HASH_KEY or derived.
+func logUserIDKey() ([]byte, error) {
+	if getEnvWithDefault("LOG_USER_ID", "plain") != "hashed" {
+		return nil, nil
+	}
+	return dedicatedSecret("LOG_HASH_KEY", "log-user-id")
+}
+
+// loadAccessKey reads the RS256 signing key from JWT_PRIVATE_KEY, PEM with
+// newlines written as \n allowed, or from the file JWT_PRIVATE_KEY_FILE
+func loadAccessKey() (*rsa.PrivateKey, error) {
+	data := []byte(strings.ReplaceAll(config.LoadEnvVar("JWT_PRIVATE_KEY"), `\n`, "\n"))
+	if path := config.LoadEnvVar("JWT_PRIVATE_KEY_FILE"); len(data) == 0 && path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	key, err := jwtpkg.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT private key: %w", err)
+	}
+	return key, nil
+}
+
+// splitList splits a comma separated env value, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseNetworks parses a comma separated list of CIDRs
+func parseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range splitList(value) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := config.LoadEnvVar(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvIntWithDefault(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(config.LoadEnvVar(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
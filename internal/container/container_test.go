@@ -0,0 +1,47 @@
+package container
+
+import (
+	"bytes"
+	"testing"
+
+	"backend-service-internpro/config"
+)
+
+func TestUnknownRoutePolicyDefault(t *testing.T) {
+	tests := map[string]string{
+		"production":  "deny",
+		"staging":     "allow",
+		"development": "allow",
+		"":            "allow",
+	}
+	for env, want := range tests {
+		t.Setenv("APP_ENV", env)
+		if got := defaultUnknownRoutePolicy(); got != want {
+			t.Errorf("APP_ENV=%q: unknown routes %s, want %s", env, got, want)
+		}
+	}
+}
+
+func TestDedicatedSecretSurvivesRotation(t *testing.T) {
+	defer func(secret []byte, old [][]byte) { config.JwtSecret, config.JwtOldSecrets = secret, old }(config.JwtSecret, config.JwtOldSecrets)
+	t.Setenv("OTP_PEPPER", "")
+
+	config.JwtSecret, config.JwtOldSecrets = []byte("first"), nil
+	before, err := dedicatedSecret("OTP_PEPPER", "otp-pepper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.JwtSecret, config.JwtOldSecrets = []byte("second"), [][]byte{[]byte("first")}
+	after, err := dedicatedSecret("OTP_PEPPER", "otp-pepper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("rotating in a new JWT_SECRET changed the derived key")
+	}
+
+	t.Setenv("OTP_PEPPER", "first")
+	if _, err := dedicatedSecret("OTP_PEPPER", "otp-pepper"); err == nil {
+		t.Error("OTP_PEPPER repeating an old JWT_SECRET was accepted")
+	}
+}
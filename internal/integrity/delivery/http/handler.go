@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"backend-service-internpro/internal/integrity"
+	"backend-service-internpro/internal/integrity/service"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// New registers the super admin data integrity routes into the Huma API.
+func New(api huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// GET /v1/admin/integrity - Count audit references to missing users
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getIntegrityReport",
+		Method:      http.MethodGet,
+		Path:        "/v1/admin/integrity",
+		Summary:     "Count audit references to missing users",
+		Description: "Super admin only. Counts, per audit column (created_by, updated_by, deleted_by, assigned_by), the rows naming a user that does not exist, as left behind by restoring a database from another environment. Soft-deleted users still exist. The counts are also logged as a warning at startup and published under /debug/vars.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct{}) (*struct {
+		Body integrity.ReportResponse
+	}, error) {
+		report, err := h.svc.Check(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body integrity.ReportResponse
+		}{Body: *response.Success(constants.IntegrityCheckSuccess, *report)}, nil
+	})
+
+	// POST /v1/admin/integrity/repair - Clear audit references to missing users
+	routeperm.Register(api, huma.Operation{
+		OperationID: "repairIntegrity",
+		Method:      http.MethodPost,
+		Path:        "/v1/admin/integrity/repair",
+		Summary:     "Clear audit references to missing users",
+		Description: "Super admin only. Sets the audit columns counted by GET /v1/admin/integrity to NULL and reports how many rows each column lost. The repair is logged as a security event.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct{}) (*struct {
+		Body integrity.ReportResponse
+	}, error) {
+		actorID, _ := requestctx.UserID(ctx)
+
+		report, err := h.svc.Repair(ctx, actorID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body integrity.ReportResponse
+		}{Body: *response.Success(constants.IntegrityRepairSuccess, *report)}, nil
+	})
+}
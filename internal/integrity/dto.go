@@ -0,0 +1,49 @@
+package integrity
+
+import (
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+)
+
+// AuditColumn is a column holding the ID of the user who changed a row
+type AuditColumn struct {
+	Table  string `json:"table" doc:"Table name"`
+	Column string `json:"column" doc:"Column name"`
+}
+
+// AuditColumns are the audit columns checked for users that do not exist,
+// as left behind by restoring a database from another environment
+var AuditColumns = []AuditColumn{
+	{"roles", "created_by"}, {"roles", "updated_by"}, {"roles", "deleted_by"},
+	{"permissions", "created_by"}, {"permissions", "updated_by"}, {"permissions", "deleted_by"},
+	{"menus", "created_by"}, {"menus", "updated_by"}, {"menus", "deleted_by"},
+	{"role_permissions", "created_by"},
+	{"role_menus", "created_by"}, {"role_menus", "updated_by"},
+	{"user_roles", "assigned_by"},
+	{"schools", "created_by"}, {"schools", "updated_by"}, {"schools", "deleted_by"},
+	{"users", "created_by"}, {"users", "updated_by"}, {"users", "deleted_by"},
+}
+
+// DanglingCount is the number of rows whose audit column names a missing user
+type DanglingCount struct {
+	AuditColumn
+	Rows int64 `json:"rows" doc:"Rows referencing a user that does not exist"`
+}
+
+// Report lists the dangling audit references per column
+type Report struct {
+	Columns   []DanglingCount `json:"columns" doc:"Every checked column, with zero counts included"`
+	Total     int64           `json:"total" doc:"Dangling references across all columns"`
+	Repaired  bool            `json:"repaired" doc:"Whether the counted references were cleared"`
+	CheckedAt time.Time       `json:"checked_at" doc:"When the columns were checked"`
+}
+
+// ReportResponse represents the audit reference report response
+type ReportResponse = response.ApiResponse
+
+// Add records the rows found, or cleared, in col
+func (r *Report) Add(col AuditColumn, rows int64) {
+	r.Columns = append(r.Columns, DanglingCount{AuditColumn: col, Rows: rows})
+	r.Total += rows
+}
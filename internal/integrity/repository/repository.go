@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"backend-service-internpro/internal/integrity"
+
+	"gorm.io/gorm"
+)
+
+// Repository finds audit columns naming users that do not exist. Soft-deleted
+// users still exist; only IDs without any users row count. Table and column
+// names come from integrity.AuditColumns, never from requests.
+type Repository interface {
+	CountDangling(ctx context.Context, col integrity.AuditColumn) (int64, error)
+	// ClearDangling sets the dangling references in col to NULL and returns
+	// how many rows changed
+	ClearDangling(ctx context.Context, col integrity.AuditColumn) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CountDangling(ctx context.Context, col integrity.AuditColumn) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Raw("SELECT COUNT(*) FROM " + col.Table + " t WHERE " + dangling(col)).
+		Scan(&count).Error
+	return count, err
+}
+
+func (r *repository) ClearDangling(ctx context.Context, col integrity.AuditColumn) (int64, error) {
+	var cleared int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// MySQL refuses an UPDATE whose subquery reads the updated table, as
+		// it would for users, so the missing IDs are looked up first
+		var ids []string
+		if err := tx.
+			Raw("SELECT DISTINCT t." + col.Column + " FROM " + col.Table + " t WHERE " + dangling(col)).
+			Scan(&ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		res := tx.Exec("UPDATE "+col.Table+" SET "+col.Column+" = NULL WHERE "+col.Column+" IN ?", ids)
+		cleared = res.RowsAffected
+		return res.Error
+	})
+	return cleared, err
+}
+
+// dangling matches the rows of col's table, aliased t, naming a missing user
+func dangling(col integrity.AuditColumn) string {
+	return "t." + col.Column + " IS NOT NULL AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = t." + col.Column + ")"
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/integrity"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// seed creates the audit columns in an in-memory SQLite database. Every
+// column gets rows naming a live user, a soft-deleted user and nobody, and
+// its position in integrity.AuditColumns modulo 3 rows naming the same
+// missing user. It returns how many rows of each column dangle.
+func seed(t *testing.T) (*repository, map[integrity.AuditColumn]int64) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	exec := func(sql string, values ...interface{}) {
+		t.Helper()
+		if err := db.Exec(sql, values...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	columns := make(map[string][]string)
+	var tables []string
+	for _, col := range integrity.AuditColumns {
+		if _, ok := columns[col.Table]; !ok {
+			tables = append(tables, col.Table)
+		}
+		columns[col.Table] = append(columns[col.Table], col.Column)
+	}
+	columns["users"] = append(columns["users"], "deleted_at")
+	for _, table := range tables {
+		ddl := "CREATE TABLE " + table + " (id TEXT PRIMARY KEY"
+		for _, column := range columns[table] {
+			ddl += ", " + column + " TEXT"
+		}
+		exec(ddl + ")")
+	}
+
+	live, former := uuid.New(), uuid.New()
+	exec("INSERT INTO users (id) VALUES (?)", live)
+	exec("INSERT INTO users (id, deleted_at) VALUES (?, ?)", former, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	want := make(map[integrity.AuditColumn]int64)
+	for i, col := range integrity.AuditColumns {
+		insert := "INSERT INTO " + col.Table + " (id, " + col.Column + ") VALUES (?, ?)"
+		exec(insert, uuid.New(), live)
+		exec(insert, uuid.New(), former)
+		exec(insert, uuid.New(), nil)
+		missing := uuid.New()
+		for range i % 3 {
+			exec(insert, uuid.New(), missing)
+		}
+		want[col] = int64(i % 3)
+	}
+	return &repository{db: db}, want
+}
+
+func TestCountDangling(t *testing.T) {
+	r, want := seed(t)
+	for _, col := range integrity.AuditColumns {
+		got, err := r.CountDangling(context.Background(), col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want[col] {
+			t.Errorf("%s.%s: %d dangling, want %d", col.Table, col.Column, got, want[col])
+		}
+	}
+}
+
+func TestClearDangling(t *testing.T) {
+	r, want := seed(t)
+	ctx := context.Background()
+	for _, col := range integrity.AuditColumns {
+		cleared, err := r.ClearDangling(ctx, col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cleared != want[col] {
+			t.Errorf("%s.%s: cleared %d, want %d", col.Table, col.Column, cleared, want[col])
+		}
+		if left, err := r.CountDangling(ctx, col); err != nil || left != 0 {
+			t.Errorf("%s.%s: %d dangling after clearing, %v", col.Table, col.Column, left, err)
+		}
+		// References to live and soft-deleted users are kept
+		var kept int64
+		if err := r.db.Table(col.Table).Where(col.Column + " IS NOT NULL").Count(&kept).Error; err != nil {
+			t.Fatal(err)
+		}
+		if kept != 2 {
+			t.Errorf("%s.%s: %d references kept, want 2", col.Table, col.Column, kept)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"backend-service-internpro/internal/integrity"
+	"backend-service-internpro/internal/integrity/repository"
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// danglingMetric publishes the latest count per "table.column" under
+// /debug/vars
+var danglingMetric = expvar.NewMap("dangling_audit_references")
+
+type Service interface {
+	// Check counts the audit references to users that do not exist
+	Check(ctx context.Context) (*integrity.Report, error)
+	// Repair sets those references to NULL on behalf of actorID, who must
+	// be allowed to by the caller, and reports what was cleared
+	Repair(ctx context.Context, actorID uuid.UUID) (*integrity.Report, error)
+}
+
+type service struct {
+	repo repository.Repository
+}
+
+func New(repo repository.Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Check(ctx context.Context) (*integrity.Report, error) {
+	report := &integrity.Report{
+		Columns:   make([]integrity.DanglingCount, 0, len(integrity.AuditColumns)),
+		CheckedAt: time.Now(),
+	}
+	for _, col := range integrity.AuditColumns {
+		rows, err := s.repo.CountDangling(ctx, col)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s.%s: %w", col.Table, col.Column, err)
+		}
+		report.Add(col, rows)
+	}
+	publish(report)
+	return report, nil
+}
+
+func (s *service) Repair(ctx context.Context, actorID uuid.UUID) (*integrity.Report, error) {
+	report := &integrity.Report{
+		Columns:   make([]integrity.DanglingCount, 0, len(integrity.AuditColumns)),
+		Repaired:  true,
+		CheckedAt: time.Now(),
+	}
+	for _, col := range integrity.AuditColumns {
+		rows, err := s.repo.ClearDangling(ctx, col)
+		if err != nil {
+			return nil, fmt.Errorf("failed to repair %s.%s: %w", col.Table, col.Column, err)
+		}
+		report.Add(col, rows)
+	}
+
+	logger.Global().Auth().LogSecurityEvent("dangling_audit_references_cleared", "", "",
+		fmt.Sprintf("%d audit references to missing users cleared by %s", report.Total, actorID))
+
+	// Everything counted was cleared
+	for _, col := range integrity.AuditColumns {
+		danglingMetric.Set(col.Table+"."+col.Column, new(expvar.Int))
+	}
+	return report, nil
+}
+
+// publish stores the report's counts in danglingMetric
+func publish(report *integrity.Report) {
+	for _, c := range report.Columns {
+		v := new(expvar.Int)
+		v.Set(c.Rows)
+		danglingMetric.Set(c.Table+"."+c.Column, v)
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"backend-service-internpro/internal/loglevel"
+	"backend-service-internpro/internal/loglevel/service"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// New registers the super admin routes changing log levels at runtime.
+func New(api huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// GET /v1/admin/log-levels - Levels set at runtime
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getLogLevels",
+		Method:      http.MethodGet,
+		Path:        "/v1/admin/log-levels",
+		Summary:     "List log levels",
+		Description: "Super admin only. Lists the level of every scope set at runtime and the default level of the others.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct{}) (*struct {
+		Body loglevel.LevelsResponse
+	}, error) {
+		return &struct {
+			Body loglevel.LevelsResponse
+		}{Body: *response.Success(constants.LogLevelListSuccess, h.svc.Levels())}, nil
+	})
+
+	// PUT /v1/admin/log-levels - Set the level of some scopes
+	routeperm.Register(api, huma.Operation{
+		OperationID: "setLogLevels",
+		Method:      http.MethodPut,
+		Path:        "/v1/admin/log-levels",
+		Summary:     "Set log levels",
+		Description: "Super admin only. Sets the level of the given scopes, e.g. {\"rbac.repository\": \"debug\"}, without a restart. Scopes are the logger scopes such as auth, http, repository and service; a scope also covers the scopes nested in it. An empty level or \"default\" removes the level of a scope. Levels are kept in memory by each server process and reset on restart. Changes are logged as a security event.",
+		Tags:        []string{"Administration"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		Body loglevel.Levels
+	}) (*struct {
+		Body loglevel.LevelsResponse
+	}, error) {
+		actorID, _ := requestctx.UserID(ctx)
+
+		data, err := h.svc.SetLevels(ctx, actorID, in.Body)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidLevels) {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body loglevel.LevelsResponse
+		}{Body: *response.Success(constants.LogLevelUpdateSuccess, data)}, nil
+	})
+}
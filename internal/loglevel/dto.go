@@ -0,0 +1,17 @@
+package loglevel
+
+import "backend-service-internpro/internal/pkg/response"
+
+// Levels maps log scopes, e.g. "auth" or "rbac.repository", to a level:
+// debug, info, warn or error. A scope covers the scopes nested in it that
+// have no level of their own.
+type Levels map[string]string
+
+// LevelsData lists the levels set at runtime
+type LevelsData struct {
+	Default string `json:"default" doc:"Level of scopes without a level of their own"`
+	Scopes  Levels `json:"scopes" doc:"Level per scope"`
+}
+
+// LevelsResponse represents the log levels response
+type LevelsResponse = response.ApiResponse
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"backend-service-internpro/internal/loglevel"
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// maxScopes bounds the levels set in one request
+const maxScopes = 50
+
+var scopePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*(\.[a-z][a-z0-9_-]*)*$`)
+
+// ErrInvalidLevels is returned, wrapped, for levels that cannot be applied
+var ErrInvalidLevels = errors.New("invalid log levels")
+
+type Service interface {
+	// Levels returns the levels set at runtime
+	Levels() loglevel.LevelsData
+	// SetLevels applies the levels on behalf of actorID; an empty level or
+	// "default" removes the level of its scope. Nothing is applied when any
+	// entry is invalid.
+	SetLevels(ctx context.Context, actorID uuid.UUID, levels loglevel.Levels) (loglevel.LevelsData, error)
+}
+
+type service struct{}
+
+func New() Service {
+	return &service{}
+}
+
+func (s *service) Levels() loglevel.LevelsData {
+	data := loglevel.LevelsData{Default: logger.GlobalLevel().String(), Scopes: loglevel.Levels{}}
+	for scope, level := range logger.Levels() {
+		data.Scopes[scope] = level.String()
+	}
+	return data
+}
+
+func (s *service) SetLevels(_ context.Context, actorID uuid.UUID, levels loglevel.Levels) (loglevel.LevelsData, error) {
+	if len(levels) == 0 {
+		return loglevel.LevelsData{}, fmt.Errorf("%w: no scopes given", ErrInvalidLevels)
+	}
+	if len(levels) > maxScopes {
+		return loglevel.LevelsData{}, fmt.Errorf("%w: at most %d scopes at once", ErrInvalidLevels, maxScopes)
+	}
+
+	parsed := make(map[string]*logger.LogLevel, len(levels))
+	for scope, name := range levels {
+		if len(scope) > 100 || !scopePattern.MatchString(scope) {
+			return loglevel.LevelsData{}, fmt.Errorf("%w: scope %q must be lowercase names joined by dots", ErrInvalidLevels, scope)
+		}
+		if name == "" || strings.EqualFold(name, "default") {
+			parsed[scope] = nil
+			continue
+		}
+		level, err := logger.ParseLevel(name)
+		if err != nil {
+			return loglevel.LevelsData{}, fmt.Errorf("%w: %v", ErrInvalidLevels, err)
+		}
+		parsed[scope] = &level
+	}
+
+	changes := make([]string, 0, len(parsed))
+	for scope, level := range parsed {
+		if level == nil {
+			logger.ResetLevel(scope)
+			changes = append(changes, scope+"=default")
+			continue
+		}
+		logger.SetLevel(scope, *level)
+		changes = append(changes, scope+"="+level.String())
+	}
+	sort.Strings(changes)
+	logger.Global().Auth().LogSecurityEvent("log_levels_changed", "", "",
+		"log levels "+strings.Join(changes, ", ")+" set by "+actorID.String())
+
+	return s.Levels(), nil
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/pkg/response"
+)
+
+// Documented response examples
+var exampleNotificationList = notification.NotificationListData{
+	Notifications: []notification.Notification{{
+		ID:   response.ExampleID(4),
+		Type: notification.TypeRolesChanged,
+		Payload: map[string]any{
+			"added":           []string{"Guru Pembimbing"},
+			"changed_by":      response.ExampleID(5),
+			"changed_by_name": "Admin Sekolah",
+		},
+		CreatedAt: response.ExampleTime,
+	}},
+	Meta: notification.Metadata{Page: 1, Limit: 10, TotalPages: 1, TotalItems: 1},
+}
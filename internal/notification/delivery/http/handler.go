@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/notification/service"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// New registers the current user's notification routes into the Huma API.
+func New(api huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// Group /v1/me/notifications
+	g := huma.NewGroup(api, "/v1/me/notifications")
+
+	// GET /me/notifications - List the caller's notifications, newest first
+	routeperm.Register(g, huma.Operation{
+		OperationID: "listMyNotifications",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of my notifications",
+		Tags:        []string{"Notifications"},
+		Responses:   response.Example(constants.NotificationListSuccess, exampleNotificationList),
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		Page  int `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit int `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}) (*struct {
+		Body notification.NotificationListResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := h.svc.ListNotifications(ctx, userID, in.Page, in.Limit)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body notification.NotificationListResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /me/notifications/{id}/read - Mark a notification as read
+	routeperm.Register(g, huma.Operation{
+		OperationID: "markNotificationRead",
+		Method:      http.MethodPost,
+		Path:        "/{id}/read",
+		Summary:     "Mark a notification as read",
+		Tags:        []string{"Notifications"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Notification ID"`
+	}) (*struct {
+		Body notification.NotificationResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := h.svc.MarkAsRead(ctx, in.ID, userID)
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				return nil, huma.Error404NotFound(constants.NotificationNotFound)
+			}
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body notification.NotificationResponse
+		}{Body: *result}, nil
+	})
+}
@@ -0,0 +1,51 @@
+package notification
+
+import (
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	TypeRolesChanged = "roles_changed"
+)
+
+// Notification represents an in-app notification
+type Notification struct {
+	ID        uuid.UUID      `json:"id" doc:"Notification ID"`
+	Type      string         `json:"type" doc:"Notification type"`
+	Payload   map[string]any `json:"payload" doc:"Type specific notification details"`
+	ReadAt    *time.Time     `json:"read_at,omitempty" doc:"When the notification was read"`
+	CreatedAt time.Time      `json:"created_at" doc:"Notification creation date"`
+}
+
+// RolesChangedPayload is the payload of a roles_changed notification
+type RolesChangedPayload struct {
+	Added         []string  `json:"added,omitempty"`
+	Removed       []string  `json:"removed,omitempty"`
+	ChangedBy     uuid.UUID `json:"changed_by"`
+	ChangedByName string    `json:"changed_by_name,omitempty"`
+}
+
+// Metadata represents pagination metadata
+type Metadata struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	TotalPages int `json:"total_pages"`
+	TotalItems int `json:"total_items"`
+}
+
+// NotificationListData represents the data structure for notification list
+type NotificationListData struct {
+	Notifications []Notification `json:"notifications"`
+	Meta          Metadata       `json:"meta"`
+}
+
+// NotificationListResponse represents the response for notification list
+type NotificationListResponse = response.ApiResponse
+
+// NotificationResponse represents a single notification response
+type NotificationResponse = response.ApiResponse
@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationEntity represents the notification entity for database operations
+type NotificationEntity struct {
+	ID        uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID  `gorm:"type:char(36);not null;index"`
+	Type      string     `gorm:"size:50;not null"`
+	Payload   string     `gorm:"type:json;not null"`
+	ReadAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName returns the table name for the NotificationEntity
+func (NotificationEntity) TableName() string {
+	return "notifications"
+}
+
+// ToNotification converts NotificationEntity to Notification DTO
+func (n *NotificationEntity) ToNotification() Notification {
+	notification := Notification{
+		ID:        n.ID,
+		Type:      n.Type,
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+
+	if n.Payload != "" {
+		_ = json.Unmarshal([]byte(n.Payload), &notification.Payload)
+	}
+
+	return notification
+}
+
+// Recipient holds the user details needed to deliver a notification
+type Recipient struct {
+	Fullname           string
+	Email              string
+	EmailNotifications bool
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-service-internpro/internal/notification"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, n *notification.NotificationEntity) error
+	GetByID(ctx context.Context, id, userID uuid.UUID) (*notification.NotificationEntity, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]notification.NotificationEntity, int64, error)
+	MarkRead(ctx context.Context, id uuid.UUID, readAt time.Time) error
+	GetRecipient(ctx context.Context, userID uuid.UUID) (*notification.Recipient, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, n *notification.NotificationEntity) error {
+	return r.db.WithContext(ctx).Create(n).Error
+}
+
+// GetByID returns a notification only when it belongs to userID
+func (r *repository) GetByID(ctx context.Context, id, userID uuid.UUID) (*notification.NotificationEntity, error) {
+	var n notification.NotificationEntity
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&n).Error
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *repository) ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]notification.NotificationEntity, int64, error) {
+	var notifications []notification.NotificationEntity
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&notification.NotificationEntity{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+func (r *repository) MarkRead(ctx context.Context, id uuid.UUID, readAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&notification.NotificationEntity{}).
+		Where("id = ? AND read_at IS NULL", id).
+		Update("read_at", readAt).Error
+}
+
+// GetRecipient returns the contact details and email preference of a user
+func (r *repository) GetRecipient(ctx context.Context, userID uuid.UUID) (*notification.Recipient, error) {
+	var recipient notification.Recipient
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Select("fullname, email, email_notifications").
+		Where("id = ?", userID).
+		Take(&recipient).Error
+	if err != nil {
+		return nil, err
+	}
+	return &recipient, nil
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/notification/repository"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/jobs"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	rolesChangedSubject = "Perubahan Role Akun Anda"
+	rolesAddedLine      = "Role ditambahkan: %s\n"
+	rolesRemovedLine    = "Role dicabut: %s\n"
+	rolesChangedByLine  = "Diubah oleh: %s"
+)
+
+// ErrNotFound is returned when a notification does not exist or belongs to another user
+var ErrNotFound = errors.New("notification not found")
+
+type Service interface {
+	ListNotifications(ctx context.Context, userID uuid.UUID, page, limit int) (*notification.NotificationListResponse, error)
+	MarkAsRead(ctx context.Context, id, userID uuid.UUID) (*notification.NotificationResponse, error)
+
+	// PublishRolesChanged queues an in-app (and, if the user opted in,
+	// email) notification about a role change; it does not block
+	PublishRolesChanged(ctx context.Context, event rbac.RolesChangedEvent)
+}
+
+type service struct {
+	repo     repository.Repository
+	notifier *notifier.Dispatcher
+	jobs     jobs.Queue
+}
+
+// New creates a notification service; dispatcher may be nil to disable
+// email delivery. Notifications are delivered through queue, or inline when
+// it is nil.
+func New(repo repository.Repository, dispatcher *notifier.Dispatcher, queue jobs.Queue) Service {
+	return &service{
+		repo:     repo,
+		notifier: dispatcher,
+		jobs:     jobs.OrInline(queue),
+	}
+}
+
+func (s *service) ListNotifications(ctx context.Context, userID uuid.UUID, page, limit int) (*notification.NotificationListResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	entities, total, err := s.repo.ListByUser(ctx, userID, (page-1)*limit, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	notifications := make([]notification.Notification, len(entities))
+	for i, entity := range entities {
+		notifications[i] = entity.ToNotification()
+	}
+
+	data := notification.NotificationListData{
+		Notifications: notifications,
+		Meta: notification.Metadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+			TotalItems: int(total),
+		},
+	}
+
+	return response.Success(constants.NotificationListSuccess, data), nil
+}
+
+func (s *service) MarkAsRead(ctx context.Context, id, userID uuid.UUID) (*notification.NotificationResponse, error) {
+	entity, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+
+	// Marking an already read notification keeps the original read time
+	if entity.ReadAt == nil {
+		now := time.Now()
+		if err := s.repo.MarkRead(ctx, entity.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to mark notification as read: %w", err)
+		}
+		entity.ReadAt = &now
+	}
+
+	return response.Success(constants.NotificationReadSuccess, entity.ToNotification()), nil
+}
+
+func (s *service) PublishRolesChanged(_ context.Context, event rbac.RolesChangedEvent) {
+	// Queued apart from the request so a finished response does not cancel delivery
+	err := s.jobs.Enqueue("notify role change", func(ctx context.Context) error {
+		return s.notifyRolesChanged(ctx, event)
+	}, "user_id", event.UserID.String())
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to queue role change notification", err, "user_id", event.UserID.String())
+	}
+}
+
+func (s *service) notifyRolesChanged(ctx context.Context, event rbac.RolesChangedEvent) error {
+	payload := notification.RolesChangedPayload{
+		Added:     event.Added,
+		Removed:   event.Removed,
+		ChangedBy: event.ChangedBy,
+	}
+	if actor, err := s.repo.GetRecipient(ctx, event.ChangedBy); err == nil {
+		payload.ChangedByName = actor.Fullname
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	entity := &notification.NotificationEntity{
+		ID:        uuid.New(),
+		UserID:    event.UserID,
+		Type:      notification.TypeRolesChanged,
+		Payload:   string(raw),
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, entity); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	if s.notifier == nil {
+		return nil
+	}
+	// The email is a job of its own so retrying it does not store the
+	// notification twice
+	err = s.jobs.Enqueue("email role change", func(ctx context.Context) error {
+		return s.emailRolesChanged(ctx, event.UserID, payload)
+	}, "user_id", event.UserID.String())
+	if err != nil {
+		return fmt.Errorf("failed to queue role change email: %w", err)
+	}
+	return nil
+}
+
+// emailRolesChanged emails the role change to the user if they opted in
+func (s *service) emailRolesChanged(ctx context.Context, userID uuid.UUID, payload notification.RolesChangedPayload) error {
+	recipient, err := s.repo.GetRecipient(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get recipient: %w", err)
+	}
+	if !recipient.EmailNotifications {
+		return nil
+	}
+
+	msg := notifier.Message{
+		Subject: rolesChangedSubject,
+		Body:    rolesChangedBody(payload),
+	}
+	return s.notifier.Dispatch(ctx, notifier.ChannelEmail, notifier.Recipient{Email: recipient.Email}, msg)
+}
+
+func rolesChangedBody(p notification.RolesChangedPayload) string {
+	var b strings.Builder
+	if len(p.Added) > 0 {
+		fmt.Fprintf(&b, rolesAddedLine, strings.Join(p.Added, ", "))
+	}
+	if len(p.Removed) > 0 {
+		fmt.Fprintf(&b, rolesRemovedLine, strings.Join(p.Removed, ", "))
+	}
+	changedBy := p.ChangedByName
+	if changedBy == "" {
+		changedBy = p.ChangedBy.String()
+	}
+	fmt.Fprintf(&b, rolesChangedByLine, changedBy)
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/notification/repository"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeRepo stores notifications in memory and knows the recipients in it;
+// other methods are not used
+type fakeRepo struct {
+	repository.Repository
+	recipients    map[uuid.UUID]*notification.Recipient
+	notifications []*notification.NotificationEntity
+	marked        int
+}
+
+func (r *fakeRepo) Create(_ context.Context, n *notification.NotificationEntity) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id, userID uuid.UUID) (*notification.NotificationEntity, error) {
+	for _, n := range r.notifications {
+		if n.ID == id && n.UserID == userID {
+			return n, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) MarkRead(_ context.Context, id uuid.UUID, readAt time.Time) error {
+	r.marked++
+	for _, n := range r.notifications {
+		if n.ID == id {
+			n.ReadAt = &readAt
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) GetRecipient(_ context.Context, userID uuid.UUID) (*notification.Recipient, error) {
+	if recipient, ok := r.recipients[userID]; ok {
+		return recipient, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// sentEmails records the messages sent to it
+type sentEmails []notifier.Message
+
+func (s *sentEmails) Send(_ context.Context, _ notifier.Recipient, msg notifier.Message) error {
+	*s = append(*s, msg)
+	return nil
+}
+
+func TestRolesChangedCreatesNotification(t *testing.T) {
+	userID, adminID := uuid.New(), uuid.New()
+	tests := []struct {
+		name      string
+		optedIn   bool
+		wantEmail bool
+	}{
+		{"email notifications off", false, false},
+		{"email notifications on", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{recipients: map[uuid.UUID]*notification.Recipient{
+				userID:  {Fullname: "Siti", Email: "siti@example.com", EmailNotifications: tt.optedIn},
+				adminID: {Fullname: "Budi Admin"},
+			}}
+			var sent sentEmails
+			s := New(repo, notifier.NewDispatcher().Register(notifier.ChannelEmail, &sent), nil).(*service)
+
+			event := rbac.RolesChangedEvent{UserID: userID, Added: []string{"Guru"}, Removed: []string{"Siswa"}, ChangedBy: adminID}
+			if err := s.notifyRolesChanged(context.Background(), event); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(repo.notifications) != 1 {
+				t.Fatalf("created %d notifications, want 1", len(repo.notifications))
+			}
+			created := repo.notifications[0].ToNotification()
+			if repo.notifications[0].UserID != userID || created.Type != notification.TypeRolesChanged || created.ReadAt != nil {
+				t.Errorf("notification = %+v for %s, want an unread %s for %s", created, repo.notifications[0].UserID, notification.TypeRolesChanged, userID)
+			}
+			if got := created.Payload["changed_by_name"]; got != "Budi Admin" {
+				t.Errorf("changed_by_name = %v, want Budi Admin", got)
+			}
+
+			if (len(sent) == 1) != tt.wantEmail {
+				t.Fatalf("sent %d emails, want email %v", len(sent), tt.wantEmail)
+			}
+			if tt.wantEmail && !strings.Contains(sent[0].Body, "Guru") {
+				t.Errorf("email body %q does not name the added role", sent[0].Body)
+			}
+		})
+	}
+}
+
+func TestMarkAsRead(t *testing.T) {
+	userID := uuid.New()
+	unread := &notification.NotificationEntity{ID: uuid.New(), UserID: userID, Type: notification.TypeRolesChanged}
+	repo := &fakeRepo{notifications: []*notification.NotificationEntity{unread}}
+	s := New(repo, nil, nil)
+	ctx := context.Background()
+
+	resp, err := s.MarkAsRead(ctx, unread.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAt := resp.Data.(notification.Notification).ReadAt
+	if readAt == nil {
+		t.Fatal("read_at is not set")
+	}
+
+	// Reading it again keeps the first read time
+	resp, err = s.MarkAsRead(ctx, unread.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := resp.Data.(notification.Notification).ReadAt; again == nil || !again.Equal(*readAt) || repo.marked != 1 {
+		t.Errorf("second read: read_at = %v after %d writes, want %v after 1", again, repo.marked, readAt)
+	}
+
+	// Another user's notification is not found
+	if _, err := s.MarkAsRead(ctx, unread.ID, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reading another user's notification: err = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,99 @@
+// Package attempts counts failed attempts per key over a sliding window, so
+// guessing spread over many requests or many targets can be blocked
+package attempts
+
+import (
+	"sync"
+	"time"
+)
+
+// Counter is what callers need of a Limiter, so a store shared between
+// server instances can replace it
+type Counter interface {
+	// Blocked reports whether key reached the limit and how long it stays so
+	Blocked(key string) (bool, time.Duration)
+	// Fail counts an attempt for key and reports whether it reached the limit
+	Fail(key string) bool
+}
+
+// Limiter blocks a key once it failed max times within window. A nil
+// Limiter or one with max <= 0 never blocks.
+type Limiter struct {
+	mu        sync.Mutex
+	max       int
+	window    time.Duration
+	failures  map[string][]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New creates a limiter allowing max failures per key within window
+func New(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:      max,
+		window:   window,
+		failures: make(map[string][]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Blocked reports whether key reached the limit and, if so, how long until
+// its oldest counted failure leaves the window
+func (l *Limiter) Blocked(key string) (bool, time.Duration) {
+	if l == nil || l.max <= 0 {
+		return false, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	failures := l.prune(key, now)
+	if len(failures) < l.max {
+		return false, 0
+	}
+	return true, failures[len(failures)-l.max].Add(l.window).Sub(now)
+}
+
+// Fail records a failed attempt for key and reports whether it made the
+// key reach the limit
+func (l *Limiter) Fail(key string) bool {
+	if l == nil || l.max <= 0 {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	failures := append(l.prune(key, now), now)
+	l.failures[key] = failures
+	return len(failures) == l.max
+}
+
+// prune drops the failures of key that left the window
+func (l *Limiter) prune(key string, now time.Time) []time.Time {
+	failures := l.failures[key]
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(failures) && !failures[i].After(cutoff) {
+		i++
+	}
+	if i == len(failures) {
+		delete(l.failures, key)
+		return nil
+	}
+	failures = failures[i:]
+	l.failures[key] = failures
+	return failures
+}
+
+// sweep forgets keys without recent failures, at most once per window
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key := range l.failures {
+		l.prune(key, now)
+	}
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the ID of the user performing the request
+func WithActor(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFrom returns the acting user ID stored in ctx, if any
+func ActorFrom(ctx context.Context) (uuid.UUID, bool) {
+	if ctx == nil {
+		return uuid.Nil, false
+	}
+	id, ok := ctx.Value(actorKey{}).(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
+
+// RegisterCallbacks installs GORM callbacks that stamp CreatedBy, UpdatedBy
+// and DeletedBy from the acting user in the statement context. CreatedBy and
+// DeletedBy are only filled when empty so explicit values are kept;
+// UpdatedBy always reflects the user performing the update.
+func RegisterCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("audit:stamp_create", stampCreate); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("audit:stamp_update", stampUpdate)
+}
+
+func stampCreate(db *gorm.DB) {
+	actor, ok := ActorFrom(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+
+	eachRow(db, func(row reflect.Value) {
+		setActor(db, row, "CreatedBy", actor, true)
+		setActor(db, row, "UpdatedBy", actor, true)
+	})
+}
+
+func stampUpdate(db *gorm.DB) {
+	actor, ok := ActorFrom(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+
+	// Column updates, e.g. Update("deleted_at", gorm.Expr("NOW()"))
+	if values, ok := db.Statement.Dest.(map[string]interface{}); ok {
+		if db.Statement.Schema.LookUpField("UpdatedBy") != nil {
+			values["updated_by"] = actor
+		}
+		if _, deleting := values["deleted_at"]; deleting && db.Statement.Schema.LookUpField("DeletedBy") != nil {
+			if _, explicit := values["deleted_by"]; !explicit {
+				values["deleted_by"] = actor
+			}
+		}
+		return
+	}
+
+	// Struct updates (Save/Updates), including soft deletes that set DeletedAt
+	dest := reflect.Indirect(reflect.ValueOf(db.Statement.Dest))
+	if dest.Kind() != reflect.Struct || !dest.CanAddr() {
+		return
+	}
+	setActor(db, dest, "UpdatedBy", actor, false)
+	if deletedAt := db.Statement.Schema.LookUpField("DeletedAt"); deletedAt != nil {
+		if _, zero := deletedAt.ValueOf(db.Statement.Context, dest); !zero {
+			setActor(db, dest, "DeletedBy", actor, true)
+		}
+	}
+}
+
+// eachRow calls fn for every struct being written by the statement
+func eachRow(db *gorm.DB, fn func(row reflect.Value)) {
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			row := reflect.Indirect(rv.Index(i))
+			if row.Kind() == reflect.Struct && row.CanAddr() {
+				fn(row)
+			}
+		}
+	case reflect.Struct:
+		if rv.CanAddr() {
+			fn(rv)
+		}
+	}
+}
+
+// setActor stores actor in the named field when the row has it; with
+// onlyIfEmpty an explicitly set value is left untouched
+func setActor(db *gorm.DB, row reflect.Value, name string, actor uuid.UUID, onlyIfEmpty bool) {
+	field := db.Statement.Schema.LookUpField(name)
+	if field == nil || !isUUIDField(field) {
+		return
+	}
+	if onlyIfEmpty {
+		if _, zero := field.ValueOf(db.Statement.Context, row); !zero {
+			return
+		}
+	}
+	db.AddError(field.Set(db.Statement.Context, row, &actor))
+}
+
+func isUUIDField(field *schema.Field) bool {
+	t := field.FieldType
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == reflect.TypeOf(uuid.UUID{})
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// record has every audit column the callbacks fill
+type record struct {
+	ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
+	Name      string
+	CreatedBy *uuid.UUID `gorm:"type:char(36)"`
+	UpdatedBy *uuid.UUID `gorm:"type:char(36)"`
+	DeletedAt *time.Time
+	DeletedBy *uuid.UUID `gorm:"type:char(36)"`
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := RegisterCallbacks(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&record{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCallbacksStampActor(t *testing.T) {
+	actor, other := uuid.New(), uuid.New()
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	asActor := WithActor(context.Background(), actor)
+
+	tests := []struct {
+		name string
+		// write creates r, without an actor unless it is the write under
+		// test, and may then change it
+		write                                 func(db *gorm.DB, r *record) error
+		wantCreated, wantUpdated, wantDeleted *uuid.UUID
+	}{
+		{"create", func(db *gorm.DB, r *record) error {
+			return db.WithContext(asActor).Create(r).Error
+		}, &actor, &actor, nil},
+		{"create without an actor", func(db *gorm.DB, r *record) error {
+			return db.Create(r).Error
+		}, nil, nil, nil},
+		{"create keeping an explicit creator", func(db *gorm.DB, r *record) error {
+			r.CreatedBy = &other
+			return db.WithContext(asActor).Create(r).Error
+		}, &other, &actor, nil},
+		{"create in a batch", func(db *gorm.DB, r *record) error {
+			return db.WithContext(asActor).Create([]*record{r, {ID: uuid.New()}}).Error
+		}, &actor, &actor, nil},
+		{"update columns", func(db *gorm.DB, r *record) error {
+			if err := db.Create(r).Error; err != nil {
+				return err
+			}
+			return db.WithContext(asActor).Model(r).Updates(map[string]interface{}{"name": "renamed"}).Error
+		}, nil, &actor, nil},
+		{"update a struct", func(db *gorm.DB, r *record) error {
+			if err := db.Create(r).Error; err != nil {
+				return err
+			}
+			// The updater always reflects who made the change
+			r.Name, r.UpdatedBy = "renamed", &other
+			return db.WithContext(asActor).Save(r).Error
+		}, nil, &actor, nil},
+		{"soft delete by column", func(db *gorm.DB, r *record) error {
+			if err := db.Create(r).Error; err != nil {
+				return err
+			}
+			return db.WithContext(asActor).Model(r).Update("deleted_at", deletedAt).Error
+		}, nil, &actor, &actor},
+		{"soft delete keeping an explicit deleter", func(db *gorm.DB, r *record) error {
+			if err := db.Create(r).Error; err != nil {
+				return err
+			}
+			return db.WithContext(asActor).Model(r).
+				Updates(map[string]interface{}{"deleted_at": deletedAt, "deleted_by": other}).Error
+		}, nil, &actor, &other},
+		{"soft delete a struct", func(db *gorm.DB, r *record) error {
+			if err := db.Create(r).Error; err != nil {
+				return err
+			}
+			r.DeletedAt = &deletedAt
+			return db.WithContext(asActor).Save(r).Error
+		}, nil, &actor, &actor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openDB(t)
+			r := &record{ID: uuid.New(), Name: "row"}
+			if err := tt.write(db, r); err != nil {
+				t.Fatal(err)
+			}
+
+			var stored record
+			if err := db.First(&stored, "id = ?", r.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			for _, col := range []struct {
+				name      string
+				got, want *uuid.UUID
+			}{
+				{"created_by", stored.CreatedBy, tt.wantCreated},
+				{"updated_by", stored.UpdatedBy, tt.wantUpdated},
+				{"deleted_by", stored.DeletedBy, tt.wantDeleted},
+			} {
+				if (col.got == nil) != (col.want == nil) || (col.got != nil && *col.got != *col.want) {
+					t.Errorf("%s = %v, want %v", col.name, col.got, col.want)
+				}
+			}
+		})
+	}
+}
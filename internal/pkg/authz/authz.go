@@ -0,0 +1,198 @@
+package authz
+
+import (
+	"context"
+	"errors"
+
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Role slugs with special meaning for authorization decisions
+const (
+	RoleSuperAdmin  = "super-admin"
+	RoleAdmin       = "admin"
+	RoleSchoolAdmin = "school-admin"
+)
+
+// PermissionKey identifies a permission by resource and action
+type PermissionKey struct {
+	Resource string `json:"resource" doc:"Permission resource"`
+	Action   string `json:"action" doc:"Permission action"`
+}
+
+func (k PermissionKey) String() string {
+	return k.Resource + ":" + k.Action
+}
+
+// Permissions lists the permissions the code declares. Routes should only
+// require these, and each should exist in the permissions table; the
+// startup consistency check reports any drift.
+var Permissions = []PermissionKey{
+	{Resource: "users", Action: "view"},
+	{Resource: "users", Action: "create"},
+	{Resource: "users", Action: "edit"},
+	{Resource: "users", Action: "delete"},
+	{Resource: "roles", Action: "view"},
+	{Resource: "roles", Action: "edit"},
+	{Resource: "permissions", Action: "view"},
+	{Resource: "permissions", Action: "edit"},
+	{Resource: "menus", Action: "view"},
+	{Resource: "menus", Action: "edit"},
+	{Resource: "schools", Action: "view"},
+	{Resource: "schools", Action: "create"},
+	{Resource: "schools", Action: "edit"},
+	{Resource: "schools", Action: "delete"},
+}
+
+var (
+	// ErrOutOfScope is returned when the caller acts on a user outside of their school
+	ErrOutOfScope = errors.New("operation is outside of the caller's school scope")
+	// ErrNoActor is returned when an operation names no acting user and
+	// runs without a system scope
+	ErrNoActor = errors.New("operation has no acting user")
+	// ErrRoleNotDelegable is returned when a school admin assigns a role not flagged for delegation
+	ErrRoleNotDelegable = errors.New("role cannot be assigned by a school admin")
+	// ErrNotAdmin is returned when a caller without admin or super-admin
+	// changes roles, permissions or menus
+	ErrNotAdmin = errors.New("only admins may change roles, permissions and menus")
+	// ErrNotRoleManager is returned when a caller without an admin role changes a user's roles
+	ErrNotRoleManager = errors.New("only admins may change user roles")
+	// ErrOwnRoles is returned when a caller changes their own roles
+	ErrOwnRoles = errors.New("users cannot change their own roles")
+	// ErrRoleAboveCaller is returned when a caller grants or removes a role above their own
+	ErrRoleAboveCaller = errors.New("role is above the caller's own")
+	// ErrNotSuperAdmin is returned when a caller without the super admin
+	// role uses a feature limited to super admins
+	ErrNotSuperAdmin = errors.New("only super admins may do this")
+	// ErrRoleCheckFailed is returned when the caller's roles could not be
+	// read. It never carries the cause, which is logged instead.
+	ErrRoleCheckFailed = errors.New("failed to check the caller's roles")
+)
+
+// roleLevels ranks the roles with special meaning; other roles rank 0
+var roleLevels = map[string]int{
+	RoleSchoolAdmin: 1,
+	RoleAdmin:       2,
+	RoleSuperAdmin:  3,
+}
+
+// RoleLevel returns the rank of the role with slug
+func RoleLevel(slug string) int {
+	return roleLevels[slug]
+}
+
+// UserLevel returns the highest rank among the user's roles, 0 when they
+// hold no admin role
+func UserLevel(ctx context.Context, roles RoleChecker, userID uuid.UUID) (int, error) {
+	for _, slug := range []string{RoleSuperAdmin, RoleAdmin, RoleSchoolAdmin} {
+		ok, err := roles.CheckUserRole(ctx, userID, slug)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return roleLevels[slug], nil
+		}
+	}
+	return 0, nil
+}
+
+// RequireSuperAdmin returns ErrNotSuperAdmin unless the user holds the super
+// admin role. A failed lookup is logged and returned as ErrRoleCheckFailed.
+func RequireSuperAdmin(ctx context.Context, roles RoleChecker, userID uuid.UUID) error {
+	ok, err := roles.CheckUserRole(ctx, userID, RoleSuperAdmin)
+	if err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to check super admin role", err, "user_id", userID.String())
+		return ErrRoleCheckFailed
+	}
+	if !ok {
+		return ErrNotSuperAdmin
+	}
+	return nil
+}
+
+// RoleChecker reports whether a user holds a role
+type RoleChecker interface {
+	CheckUserRole(ctx context.Context, userID uuid.UUID, roleSlug string) (bool, error)
+}
+
+// Scope describes which users the caller may manage.
+// An unrestricted scope applies no school boundary; only admins and
+// system callers get one.
+type Scope struct {
+	Restricted bool
+	SchoolID   *uuid.UUID
+	// UserID limits a restricted scope to the caller themselves
+	UserID *uuid.UUID
+	// System names the in-process caller, like the demo seed, of a scope
+	// built by SystemScope
+	System string
+}
+
+// SystemScope returns the unrestricted scope of an in-process caller that
+// acts for no user, like the demo seed. Requests never get one.
+func SystemScope(caller string) Scope {
+	return Scope{System: caller}
+}
+
+// IsSystem reports whether s was built by SystemScope
+func (s Scope) IsSystem() bool {
+	return s.System != ""
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context whose operations run within scope instead of
+// the one resolved for their actor. Only in-process callers should use it.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope stored by WithScope, if any
+func ScopeFrom(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// ResolveScope determines the caller's scope. Admins are never restricted;
+// callers holding school-admin are limited to their primary school, and
+// everyone else to themselves.
+func ResolveScope(ctx context.Context, roles RoleChecker, actorID uuid.UUID, actorSchoolID *uuid.UUID) (Scope, error) {
+	for _, slug := range []string{RoleSuperAdmin, RoleAdmin} {
+		ok, err := roles.CheckUserRole(ctx, actorID, slug)
+		if err != nil {
+			return Scope{}, err
+		}
+		if ok {
+			return Scope{}, nil
+		}
+	}
+
+	isSchoolAdmin, err := roles.CheckUserRole(ctx, actorID, RoleSchoolAdmin)
+	if err != nil {
+		return Scope{}, err
+	}
+	if !isSchoolAdmin {
+		return Scope{Restricted: true, UserID: &actorID}, nil
+	}
+	return Scope{Restricted: true, SchoolID: actorSchoolID}, nil
+}
+
+// AllowsSchool reports whether every user belonging to schoolID is within
+// the scope. A scope limited to the caller allows no school.
+func (s Scope) AllowsSchool(schoolID *uuid.UUID) bool {
+	if !s.Restricted {
+		return true
+	}
+	return s.UserID == nil && s.SchoolID != nil && schoolID != nil && *s.SchoolID == *schoolID
+}
+
+// AllowsUser reports whether the user userID, belonging to schoolID, is
+// within the scope
+func (s Scope) AllowsUser(userID uuid.UUID, schoolID *uuid.UUID) bool {
+	if s.UserID != nil {
+		return *s.UserID == userID
+	}
+	return s.AllowsSchool(schoolID)
+}
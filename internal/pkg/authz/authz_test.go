@@ -0,0 +1,131 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// roles is a RoleChecker granting the same role slugs to every user
+type roles map[string]bool
+
+func (r roles) CheckUserRole(_ context.Context, _ uuid.UUID, slug string) (bool, error) {
+	return r[slug], nil
+}
+
+// failingRoles is a RoleChecker whose lookups fail
+type failingRoles struct{}
+
+func (failingRoles) CheckUserRole(context.Context, uuid.UUID, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestRequireSuperAdmin(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles RoleChecker
+		want  error
+	}{
+		{"super admin", roles{RoleSuperAdmin: true}, nil},
+		{"admin", roles{RoleAdmin: true}, ErrNotSuperAdmin},
+		{"no role", roles{}, ErrNotSuperAdmin},
+		// The cause is logged, not returned
+		{"failed lookup", failingRoles{}, ErrRoleCheckFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RequireSuperAdmin(context.Background(), tt.roles, uuid.New()); err != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveScope(t *testing.T) {
+	actorID := uuid.New()
+	school := uuid.New()
+	otherSchool := uuid.New()
+	colleague := uuid.New()
+
+	tests := []struct {
+		name            string
+		roles           roles
+		allowsColleague bool
+		allowsSelf      bool
+		allowsOther     bool
+	}{
+		{"super admin", roles{RoleSuperAdmin: true}, true, true, true},
+		{"admin", roles{RoleAdmin: true}, true, true, true},
+		{"school admin", roles{RoleSchoolAdmin: true}, true, true, false},
+		{"no admin role is limited to themselves", roles{}, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := ResolveScope(context.Background(), tt.roles, actorID, &school)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := scope.AllowsUser(colleague, &school); got != tt.allowsColleague {
+				t.Errorf("allows a user of their school = %v, want %v", got, tt.allowsColleague)
+			}
+			if got := scope.AllowsUser(actorID, &school); got != tt.allowsSelf {
+				t.Errorf("allows themselves = %v, want %v", got, tt.allowsSelf)
+			}
+			if got := scope.AllowsUser(uuid.New(), &otherSchool); got != tt.allowsOther {
+				t.Errorf("allows a user of another school = %v, want %v", got, tt.allowsOther)
+			}
+		})
+	}
+}
+
+func TestAllowsSchool(t *testing.T) {
+	school := uuid.New()
+	self := uuid.New()
+
+	tests := []struct {
+		name     string
+		scope    Scope
+		schoolID *uuid.UUID
+		want     bool
+	}{
+		{"unrestricted", Scope{}, nil, true},
+		{"own school", Scope{Restricted: true, SchoolID: &school}, &school, true},
+		{"another school", Scope{Restricted: true, SchoolID: &school}, new(uuid.UUID), false},
+		{"no school", Scope{Restricted: true, SchoolID: &school}, nil, false},
+		{"school admin without a school", Scope{Restricted: true}, &school, false},
+		{"limited to the caller", Scope{Restricted: true, UserID: &self}, &school, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.AllowsSchool(tt.schoolID); got != tt.want {
+				t.Errorf("AllowsSchool = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSystemScope(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := ScopeFrom(ctx); ok {
+		t.Fatal("scope found in a context without one")
+	}
+
+	scope, ok := ScopeFrom(WithScope(ctx, SystemScope("demo seed")))
+	if !ok || !scope.IsSystem() || scope.System != "demo seed" {
+		t.Fatalf("scope = %+v, %v, want the demo seed's system scope", scope, ok)
+	}
+	if !scope.AllowsUser(uuid.New(), nil) {
+		t.Error("system scope restricts users")
+	}
+
+	// An admin is unrestricted too, but not a system caller
+	admin, err := ResolveScope(ctx, roles{RoleAdmin: true}, uuid.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin.IsSystem() {
+		t.Errorf("admin scope %+v is a system scope", admin)
+	}
+}
@@ -2,20 +2,46 @@ package constants
 
 // Auth Messages
 const (
-	LoginSuccess         = "Login berhasil"
-	LoginFailed          = "Email/username atau password salah"
-	RefreshSuccess       = "Token berhasil diperbarui"
-	RefreshFailed        = "Token refresh tidak valid"
-	LogoutSuccess        = "Logout berhasil"
-	LogoutFailed         = "Logout gagal"
-	OTPSent              = "Jika email terdaftar, kode OTP telah dikirim"
-	OTPVerified          = "Kode OTP berhasil diverifikasi"
-	OTPInvalid           = "Kode OTP tidak valid atau telah kedaluwarsa"
-	PasswordResetSuccess = "Password berhasil direset"
-	PasswordResetFailed  = "Gagal mereset password"
-	TokenInvalid         = "Token tidak valid"
-	TokenExpired         = "Token telah kedaluwarsa"
-	UnauthorizedAccess   = "Akses tidak diizinkan"
+	LoginSuccess             = "Login berhasil"
+	LoginFailed              = "Email/username atau password salah"
+	OAuthURLSuccess          = "URL login Google berhasil dibuat"
+	OAuthURLFailed           = "Gagal membuat URL login Google"
+	OAuthLoginFailed         = "Login dengan Google gagal"
+	RegisterSuccess          = "Registrasi berhasil"
+	RegisterFailed           = "Registrasi gagal"
+	RefreshSuccess           = "Token berhasil diperbarui"
+	RefreshFailed            = "Token refresh tidak valid"
+	LogoutSuccess            = "Logout berhasil"
+	LogoutFailed             = "Logout gagal"
+	LogoutAllSuccess         = "Logout dari semua sesi berhasil"
+	SessionUpdateSuccess     = "Sesi berhasil diperbarui"
+	SessionUpdateFailed      = "Gagal memperbarui sesi"
+	SessionListSuccess       = "Daftar sesi berhasil diambil"
+	SessionListFailed        = "Gagal mengambil daftar sesi"
+	SessionRevokeSuccess     = "Sesi berhasil dicabut"
+	SessionRevokeFailed      = "Gagal mencabut sesi"
+	TokenRevokeSuccess       = "Token akses berhasil dicabut"
+	TokenRevokeFailed        = "Gagal mencabut token akses"
+	ProfileSuccess           = "Profil berhasil diambil"
+	ProfileFailed            = "Gagal mengambil profil"
+	ImpersonationSuccess     = "Berhasil masuk sebagai pengguna"
+	ImpersonationFailed      = "Gagal masuk sebagai pengguna"
+	ImpersonationStopSuccess = "Berhasil kembali ke akun sendiri"
+	ImpersonationStopFailed  = "Gagal kembali ke akun sendiri"
+	AuthEventListSuccess     = "Riwayat autentikasi berhasil diambil"
+	AuthEventListFailed      = "Gagal mengambil riwayat autentikasi"
+	OTPSent                  = "Jika email terdaftar, kode OTP telah dikirim"
+	OTPVerified              = "Kode OTP berhasil diverifikasi"
+	OTPInvalid               = "Kode OTP tidak valid atau telah kedaluwarsa"
+	PasswordResetSuccess     = "Password berhasil direset"
+	PasswordResetFailed      = "Gagal mereset password"
+	VerificationSent         = "Jika email terdaftar dan belum terverifikasi, kode verifikasi telah dikirim"
+	EmailVerified            = "Email berhasil diverifikasi"
+	EmailVerifyFailed        = "Gagal memverifikasi email"
+	EmailAlreadyVerified     = "Email sudah terverifikasi"
+	TokenInvalid             = "Token tidak valid"
+	TokenExpired             = "Token telah kedaluwarsa"
+	UnauthorizedAccess       = "Akses tidak diizinkan"
 )
 
 // User Messages
@@ -31,6 +57,16 @@ const (
 	UserDeleteFailed   = "Gagal menghapus pengguna"
 	EmailAlreadyExists = "Email sudah terdaftar"
 	UsernameExists     = "Username sudah digunakan"
+
+	UserIdentifierRetained       = "Email atau username masih tertahan oleh pengguna yang telah dihapus; lepaskan identitasnya terlebih dahulu"
+	UserIdentifiersReleased      = "Identitas pengguna berhasil dilepas"
+	UserIdentifiersReleaseFailed = "Gagal melepas identitas pengguna"
+
+	UserDeactivateSuccess = "Pengguna berhasil dinonaktifkan"
+	UserDeactivateFailed  = "Gagal menonaktifkan pengguna"
+	UserActivateSuccess   = "Pengguna berhasil diaktifkan kembali"
+	UserActivateFailed    = "Gagal mengaktifkan pengguna"
+	UserDeactivateSelf    = "Tidak dapat menonaktifkan akun sendiri"
 )
 
 // School Messages
@@ -52,6 +88,7 @@ const (
 	MajorityUpdateSuccess = "Jurusan berhasil diperbarui"
 	MajorityDeleteSuccess = "Jurusan berhasil dihapus"
 	MajorityNotFound      = "Jurusan tidak ditemukan"
+	MajorityImportSuccess = "Jurusan berhasil diimpor"
 
 	// Class Messages
 	ClassListSuccess   = "Data kelas berhasil diambil"
@@ -63,6 +100,19 @@ const (
 	ClassDeleteSuccess = "Kelas berhasil dihapus"
 	ClassNotFound      = "Kelas tidak ditemukan"
 
+	// Class rollover Messages
+	ClassRolloverSuccess     = "Kelas berhasil disalin ke tahun ajaran baru"
+	ClassRolloverDryRun      = "Pratinjau penyalinan kelas ke tahun ajaran baru"
+	ClassRolloverSameYear    = "Tahun ajaran asal dan tujuan tidak boleh sama"
+	ClassRolloverEmptySource = "Tidak ada kelas pada tahun ajaran asal"
+
+	// Class Schedule Messages
+	ClassScheduleListSuccess   = "Jadwal pelajaran berhasil diambil"
+	ClassScheduleCreateSuccess = "Jadwal pelajaran berhasil dibuat"
+	ClassScheduleUpdateSuccess = "Jadwal pelajaran berhasil diperbarui"
+	ClassScheduleDeleteSuccess = "Jadwal pelajaran berhasil dihapus"
+	ClassScheduleNotFound      = "Jadwal pelajaran tidak ditemukan"
+
 	// Partner Messages
 	PartnerListSuccess   = "Data mitra berhasil diambil"
 	PartnerDetailSuccess = "Detail mitra berhasil diambil"
@@ -72,6 +122,19 @@ const (
 	PartnerUpdateSuccess = "Mitra berhasil diperbarui"
 	PartnerDeleteSuccess = "Mitra berhasil dihapus"
 	PartnerNotFound      = "Mitra tidak ditemukan"
+
+	// Partner contact verification Messages
+	PartnerContactVerificationSent = "Email verifikasi kontak mitra berhasil dikirim"
+	PartnerContactVerified         = "Email kontak mitra berhasil diverifikasi"
+	PartnerContactEmailMissing     = "Mitra belum memiliki email kontak"
+	PartnerContactLinkInvalid      = "Tautan verifikasi tidak valid atau sudah kedaluwarsa"
+	PartnerContactMailUnavailable  = "Email verifikasi tidak dapat dikirim saat ini"
+
+	// Partner merge Messages
+	PartnerDuplicatesSuccess   = "Data mitra duplikat berhasil diambil"
+	PartnerMergeSuccess        = "Mitra berhasil digabungkan"
+	PartnerMergeSurvivorListed = "Mitra utama tidak boleh termasuk dalam daftar duplikat"
+	PartnerMergeSchoolMismatch = "Mitra yang digabungkan harus berasal dari sekolah yang sama"
 )
 
 // RBAC Messages
@@ -98,5 +161,71 @@ const (
 	ValidationError     = "Data yang dikirim tidak valid"
 	NotFound            = "Data tidak ditemukan"
 	ConflictError       = "Data sudah ada atau konflik"
+	PreconditionFailed  = "Data telah diubah oleh pengguna lain, muat ulang sebelum menyimpan"
+	ConcurrentUpdate    = "Data sedang diubah oleh pengguna lain, coba lagi"
+	UnknownFields       = "Field tidak dikenal: %s. Field yang tersedia: %s"
 	Success             = "Operasi berhasil dilakukan"
 )
+
+// Search Messages
+const (
+	SearchSuccess = "Pencarian berhasil"
+)
+
+// Stats Messages
+const (
+	StatsOverviewSuccess = "Statistik berhasil diambil"
+)
+
+// Integrity Messages
+const (
+	IntegrityCheckSuccess  = "Pemeriksaan referensi audit berhasil"
+	IntegrityRepairSuccess = "Referensi audit yang tidak valid berhasil dibersihkan"
+)
+
+// API Key Messages
+const (
+	APIKeyCreateSuccess = "API key berhasil dibuat; simpan sekarang karena tidak akan ditampilkan lagi"
+	APIKeyListSuccess   = "Daftar API key berhasil diambil"
+	APIKeyDetailSuccess = "Detail API key berhasil diambil"
+	APIKeyUpdateSuccess = "API key berhasil diperbarui"
+	APIKeyRevokeSuccess = "API key berhasil dicabut"
+	APIKeyNotFound      = "API key tidak ditemukan"
+)
+
+// Log Level Messages
+const (
+	LogLevelListSuccess   = "Level log berhasil diambil"
+	LogLevelUpdateSuccess = "Level log berhasil diperbarui"
+)
+
+// Usage Messages
+const (
+	UsageSuccess      = "Pemakaian API berhasil diambil"
+	UsageInvalidDate  = "Tanggal harus berformat YYYY-MM-DD"
+	UsageInvalidRange = "Rentang tanggal tidak valid, from harus sebelum to dan paling lama %d hari"
+)
+
+// Notification Messages
+const (
+	NotificationListSuccess = "Notifikasi berhasil diambil"
+	NotificationReadSuccess = "Notifikasi ditandai sudah dibaca"
+	NotificationNotFound    = "Notifikasi tidak ditemukan"
+)
+
+// Personal Data Messages
+const (
+	DataExportSuccess        = "Ekspor data pribadi siap diunduh"
+	DataExportPending        = "Ekspor data pribadi sedang diproses"
+	DataExportNotFound       = "Ekspor data pribadi tidak ditemukan atau sudah kedaluwarsa"
+	DataExportLinkInvalid    = "Tautan unduhan tidak valid atau sudah kedaluwarsa"
+	EraseConfirmationSuccess = "Token konfirmasi penghapusan data berhasil dibuat"
+	EraseConfirmationInvalid = "Token konfirmasi penghapusan data tidak valid atau sudah kedaluwarsa"
+	UserEraseSuccess         = "Data pribadi pengguna berhasil dianonimkan"
+)
+
+// Audit Log Messages
+const (
+	AuditListSuccess  = "Log audit berhasil diambil"
+	AuditInvalidRange = "Rentang waktu tidak valid, from harus sebelum to"
+)
@@ -0,0 +1,55 @@
+// Package csvexport writes CSV downloads row by row, so large exports are
+// streamed to the client instead of being built in memory. Cells that a
+// spreadsheet would run as a formula are escaped, as exported values may
+// come from untrusted input such as user agents.
+package csvexport
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// ContentType is the media type of the written files
+const ContentType = "text/csv; charset=utf-8"
+
+// Writer writes CSV records to an underlying writer
+type Writer struct {
+	w *csv.Writer
+}
+
+// NewWriter creates a writer and writes the header row
+func NewWriter(w io.Writer, header ...string) (*Writer, error) {
+	cw := &Writer{w: csv.NewWriter(w)}
+	if err := cw.Write(header...); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write writes a record, escaping formula cells
+func (w *Writer) Write(record ...string) error {
+	for i, cell := range record {
+		record[i] = escape(cell)
+	}
+	return w.w.Write(record)
+}
+
+// Flush sends the buffered records on, e.g. after each batch, and reports
+// any error of the writes since the last flush
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// escape prefixes a cell starting like a formula with a quote, so
+// spreadsheets show it as text
+func escape(cell string) string {
+	if cell == "" {
+		return cell
+	}
+	switch cell[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + cell
+	}
+	return cell
+}
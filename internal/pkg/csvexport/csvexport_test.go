@@ -0,0 +1,46 @@
+package csvexport
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriterEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"=1+1", "'=1+1"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cell, func(t *testing.T) {
+			if got := escape(tt.cell); got != tt.want {
+				t.Errorf("escape(%q) = %q, want %q", tt.cell, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterWritesHeaderAndRows(t *testing.T) {
+	var out strings.Builder
+	w, err := NewWriter(&out, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("1", "x,y"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a,b\n1,\"x,y\"\n"; out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,210 @@
+// Package csvimport reads uploaded CSV files for bulk imports. Files that are
+// too big, have too many rows, mix encodings or lack expected columns are
+// rejected before any row is handed over, so a bad upload fails in
+// milliseconds instead of after processing half of it.
+package csvimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errors returned by Read, wrapped with details
+var (
+	ErrTooLarge      = errors.New("file is too large")
+	ErrTooManyRows   = errors.New("file has too many rows")
+	ErrMixedEncoding = errors.New("file mixes UTF-8 with another encoding")
+	ErrEncoding      = errors.New("file is neither UTF-8 nor Windows-1252")
+	ErrHeader        = errors.New("header row does not match")
+	ErrMalformed     = errors.New("file is not valid CSV")
+)
+
+// Limits bound an upload; zero values use DefaultLimits
+type Limits struct {
+	MaxBytes int64
+	MaxRows  int // not counting the header row
+}
+
+// DefaultLimits fit a school's users or students comfortably
+var DefaultLimits = Limits{
+	MaxBytes: 5 << 20,
+	MaxRows:  10000,
+}
+
+// Options describe the expected file
+type Options struct {
+	// Columns must all be in the header row, in any order and case. Other
+	// columns are allowed and ignored.
+	Columns []string
+	Limits  Limits
+}
+
+// Row is one record of the file
+type Row struct {
+	// Line is the row's line in the file, counting the header as line 1
+	Line   int
+	fields map[string]int
+	record []string
+}
+
+// Get returns the trimmed value of column, or "" when the row is short
+func (r Row) Get(column string) string {
+	i, ok := r.fields[strings.ToLower(column)]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// Read checks the whole file against opts, then calls fn for each row in
+// order. It stops at the first error fn returns.
+func Read(r io.Reader, opts Options, fn func(Row) error) error {
+	limits := opts.Limits
+	if limits.MaxBytes <= 0 {
+		limits.MaxBytes = DefaultLimits.MaxBytes
+	}
+	if limits.MaxRows <= 0 {
+		limits.MaxRows = DefaultLimits.MaxRows
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return fmt.Errorf("%w: the limit is %d bytes", ErrTooLarge, limits.MaxBytes)
+	}
+	if data, err = toUTF8(data); err != nil {
+		return err
+	}
+
+	// First pass: the file parses, has a valid header and few enough rows
+	records := newReader(data)
+	header, err := records.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%w: the file is empty", ErrHeader)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	fields, err := checkHeader(header, opts.Columns)
+	if err != nil {
+		return err
+	}
+	rows := 0
+	for {
+		if _, err := records.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if rows++; rows > limits.MaxRows {
+			return fmt.Errorf("%w: the limit is %d rows", ErrTooManyRows, limits.MaxRows)
+		}
+	}
+
+	// Second pass: hand the rows over
+	records = newReader(data)
+	if _, err := records.Read(); err != nil {
+		return err
+	}
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := records.FieldPos(0)
+		if err := fn(Row{Line: line, fields: fields, record: record}); err != nil {
+			return err
+		}
+	}
+}
+
+func newReader(data []byte) *csv.Reader {
+	r := csv.NewReader(bytes.NewReader(data))
+	// Spreadsheets drop trailing empty cells; Row.Get handles short rows
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	return r
+}
+
+// checkHeader maps the lower cased column names to their index
+func checkHeader(header, columns []string) (map[string]int, error) {
+	fields := make(map[string]int, len(header))
+	found := make([]string, 0, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if key == "" {
+			continue
+		}
+		if _, dup := fields[key]; dup {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrHeader, name)
+		}
+		fields[key] = i
+		found = append(found, name)
+	}
+
+	var missing []string
+	for _, column := range columns {
+		if _, ok := fields[strings.ToLower(column)]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		noun := "column"
+		if len(missing) > 1 {
+			noun = "columns"
+		}
+		return nil, fmt.Errorf("%w: %s %s missing, found %s", ErrHeader, noun,
+			strings.Join(missing, ", "), strings.Join(found, ", "))
+	}
+	return fields, nil
+}
+
+// toUTF8 strips a UTF-8 byte order mark, or transcodes a Windows-1252 file
+// as saved by Excel. A file with both valid multi-byte UTF-8 sequences and
+// invalid bytes was mixed from several sources and cannot be read reliably.
+func toUTF8(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return data, nil
+	}
+
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r != utf8.RuneError && size > 1 {
+			return nil, fmt.Errorf("%w: save it as UTF-8 and upload it again", ErrMixedEncoding)
+		}
+		i += size
+	}
+
+	out := make([]byte, 0, len(data)+len(data)/8)
+	for i, b := range data {
+		if b < 0x80 || b >= 0xa0 {
+			// Latin-1 and Windows-1252 agree outside 0x80-0x9f
+			out = utf8.AppendRune(out, rune(b))
+			continue
+		}
+		r := windows1252[b-0x80]
+		if r == 0 {
+			return nil, fmt.Errorf("%w: byte 0x%x at offset %d", ErrEncoding, b, i)
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
+
+// windows1252 maps the bytes 0x80-0x9f; zero marks bytes it leaves undefined
+var windows1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
@@ -0,0 +1,123 @@
+package csvimport
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// read runs Read with the name and description columns and returns the
+// names of the rows handed over
+func read(t *testing.T, data string, limits Limits) ([]string, error) {
+	t.Helper()
+	var names []string
+	err := Read(strings.NewReader(data), Options{Columns: []string{"name"}, Limits: limits}, func(row Row) error {
+		names = append(names, row.Get("name")+"|"+row.Get("description"))
+		return nil
+	})
+	return names, err
+}
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "UTF-8 with a byte order mark",
+			data: fixture(t, "utf8_bom.csv"),
+			want: []string{"Rekayasa Perangkat Lunak|RPL", "Teknik Komputer dan Jaringan|TKJ"},
+		},
+		{
+			name: "Windows-1252 is transcoded",
+			data: fixture(t, "windows1252.csv"),
+			want: []string{"Tata Boga|Café “dapur”"},
+		},
+		{
+			name: "columns in any case and order, short rows",
+			data: "Description,NAME\n,Tata Boga\nBoga\n",
+			want: []string{"Tata Boga|", "|Boga"},
+		},
+		{name: "mixed encodings", data: fixture(t, "mixed.csv"), wantErr: ErrMixedEncoding},
+		{name: "byte undefined in Windows-1252", data: fixture(t, "undefined_byte.csv"), wantErr: ErrEncoding},
+		{name: "missing column", data: fixture(t, "header_missing.csv"), wantErr: ErrHeader},
+		{name: "duplicate column", data: "name,Name\nTata Boga,Boga\n", wantErr: ErrHeader},
+		{name: "empty file", data: "", wantErr: ErrHeader},
+		{name: "malformed", data: fixture(t, "malformed.csv"), wantErr: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := read(t, tt.data, Limits{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(got) > 0 {
+				t.Errorf("rows %q were handed over before the file was rejected", got)
+			}
+			if tt.wantErr == nil && !slices.Equal(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHeaderMessage(t *testing.T) {
+	_, err := read(t, fixture(t, "header_missing.csv"), Limits{})
+	if want := "column name missing, found nama, keterangan"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want it to say %q", err, want)
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	rows := "name\n" + strings.Repeat("Tata Boga\n", 5)
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr error
+	}{
+		{"within the limits", Limits{MaxBytes: int64(len(rows)), MaxRows: 5}, nil},
+		{"too large", Limits{MaxBytes: int64(len(rows)) - 1, MaxRows: 5}, ErrTooLarge},
+		{"too many rows", Limits{MaxBytes: int64(len(rows)), MaxRows: 4}, ErrTooManyRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := read(t, rows, tt.limits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(got) > 0 {
+				t.Errorf("%d rows were handed over before the file was rejected", len(got))
+			}
+		})
+	}
+}
+
+func TestReadStopsAtCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	var lines []int
+	err := Read(strings.NewReader("name\na\nb\nc\n"), Options{Columns: []string{"name"}}, func(row Row) error {
+		lines = append(lines, row.Line)
+		if row.Line == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || !slices.Equal(lines, []int{2, 3}) {
+		t.Errorf("err = %v after lines %v, want %v after lines [2 3]", err, lines, stop)
+	}
+}
@@ -3,6 +3,10 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 )
@@ -17,6 +21,13 @@ var (
 	ErrTokenExpired        = errors.New("token expired")
 	ErrUnauthorized        = errors.New("unauthorized")
 	ErrValidationFailed    = errors.New("validation failed")
+	ErrConflict            = errors.New("conflict")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrLoginCodeRequired   = errors.New("login code required")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrAccountLocked       = errors.New("account locked")
+	ErrEmailNotVerified    = errors.New("email not verified")
+	ErrAccountDisabled     = errors.New("account disabled")
 	ErrInternalServer      = errors.New("internal server error")
 )
 
@@ -28,10 +39,19 @@ const (
 	CodeInvalidRefreshToken ErrorCode = "INVALID_REFRESH_TOKEN"
 	CodeEmailNotFound       ErrorCode = "EMAIL_NOT_FOUND"
 	CodeInvalidOTP          ErrorCode = "INVALID_OTP"
+	CodeOTPAttemptsExceeded ErrorCode = "OTP_ATTEMPTS_EXCEEDED"
 	CodeUserNotFound        ErrorCode = "USER_NOT_FOUND"
 	CodeTokenExpired        ErrorCode = "TOKEN_EXPIRED"
 	CodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	CodeForbidden           ErrorCode = "FORBIDDEN"
 	CodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
+	CodeConflict            ErrorCode = "CONFLICT"
+	CodeSessionNotFound     ErrorCode = "SESSION_NOT_FOUND"
+	CodeLoginCodeRequired   ErrorCode = "LOGIN_CODE_REQUIRED"
+	CodeTooManyRequests     ErrorCode = "TOO_MANY_REQUESTS"
+	CodeAccountLocked       ErrorCode = "ACCOUNT_LOCKED"
+	CodeEmailNotVerified    ErrorCode = "EMAIL_NOT_VERIFIED"
+	CodeAccountDisabled     ErrorCode = "ACCOUNT_DISABLED"
 	CodeInternalServer      ErrorCode = "INTERNAL_SERVER_ERROR"
 )
 
@@ -40,6 +60,8 @@ type AppError struct {
 	Code    ErrorCode `json:"code"`
 	Message string    `json:"message"`
 	Details string    `json:"details,omitempty"`
+	// RetryAfter tells a throttled or locked out client when to try again
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -68,20 +90,44 @@ func (e *AppError) WithDetails(details string) *AppError {
 	return e
 }
 
-// ToHumaError converts AppError to Huma error
+// ToHumaError converts AppError to Huma error. Details, such as the rule a
+// validation broke, are passed on as the error's details except for
+// internal errors, whose details are meant for the logs.
 func (e *AppError) ToHumaError() error {
+	var details []error
+	if e.Details != "" {
+		details = append(details, &huma.ErrorDetail{Message: e.Details})
+	}
 	switch e.Code {
-	case CodeInvalidCredentials, CodeInvalidRefreshToken, CodeUnauthorized:
-		return huma.Error401Unauthorized(e.Message)
-	case CodeEmailNotFound, CodeUserNotFound:
-		return huma.Error404NotFound(e.Message)
-	case CodeValidationFailed, CodeInvalidOTP:
-		return huma.Error400BadRequest(e.Message)
+	case CodeInvalidCredentials, CodeInvalidRefreshToken, CodeUnauthorized, CodeTokenExpired:
+		return huma.Error401Unauthorized(e.Message, details...)
+	case CodeEmailNotFound, CodeUserNotFound, CodeSessionNotFound:
+		return huma.Error404NotFound(e.Message, details...)
+	case CodeValidationFailed, CodeInvalidOTP, CodeOTPAttemptsExceeded:
+		return huma.Error400BadRequest(e.Message, details...)
+	case CodeConflict:
+		return huma.Error409Conflict(e.Message, details...)
+	case CodeEmailNotVerified, CodeAccountDisabled, CodeLoginCodeRequired, CodeForbidden:
+		return huma.Error403Forbidden(e.Message, details...)
+	case CodeTooManyRequests:
+		return huma.ErrorWithHeaders(huma.Error429TooManyRequests(e.Message, details...), e.retryAfterHeader())
+	case CodeAccountLocked:
+		return huma.ErrorWithHeaders(huma.NewError(http.StatusLocked, e.Message, details...), e.retryAfterHeader())
 	default:
 		return huma.Error500InternalServerError(e.Message)
 	}
 }
 
+// retryAfterHeader renders RetryAfter as a Retry-After header in whole
+// seconds, never less than one
+func (e *AppError) retryAfterHeader() http.Header {
+	seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return http.Header{"Retry-After": {strconv.Itoa(seconds)}}
+}
+
 // Helper functions for common errors
 func InvalidCredentials() *AppError {
 	return New(CodeInvalidCredentials, "Invalid username/email or password")
@@ -99,10 +145,18 @@ func InvalidOTP() *AppError {
 	return New(CodeInvalidOTP, "Invalid or expired OTP code")
 }
 
+func OTPAttemptsExceeded() *AppError {
+	return New(CodeOTPAttemptsExceeded, "Too many wrong codes; the OTP code is no longer valid, please request a new one")
+}
+
 func UserNotFound() *AppError {
 	return New(CodeUserNotFound, "User not found")
 }
 
+func SessionNotFound() *AppError {
+	return New(CodeSessionNotFound, "Session not found")
+}
+
 func TokenExpired() *AppError {
 	return New(CodeTokenExpired, "Token has expired")
 }
@@ -111,10 +165,42 @@ func Unauthorized() *AppError {
 	return New(CodeUnauthorized, "Unauthorized access")
 }
 
+func Forbidden(message string) *AppError {
+	return New(CodeForbidden, message)
+}
+
 func ValidationFailed(details string) *AppError {
 	return New(CodeValidationFailed, "Validation failed").WithDetails(details)
 }
 
+func Conflict(message string) *AppError {
+	return New(CodeConflict, message)
+}
+
+func LoginCodeRequired() *AppError {
+	return New(CodeLoginCodeRequired, "A login code was sent, please send it with the request again")
+}
+
+func TooManyRequests(retryAfter time.Duration) *AppError {
+	err := New(CodeTooManyRequests, "Too many attempts, please try again later")
+	err.RetryAfter = retryAfter
+	return err
+}
+
+func AccountLocked(retryAfter time.Duration) *AppError {
+	err := New(CodeAccountLocked, "Account is temporarily locked after too many failed login attempts")
+	err.RetryAfter = retryAfter
+	return err
+}
+
+func EmailNotVerified() *AppError {
+	return New(CodeEmailNotVerified, "Email address has not been verified")
+}
+
+func AccountDisabled() *AppError {
+	return New(CodeAccountDisabled, "Account has been disabled, please contact an administrator")
+}
+
 func InternalServer(details string) *AppError {
 	return New(CodeInternalServer, "Internal server error").WithDetails(details)
 }
@@ -0,0 +1,50 @@
+package hasher
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt hashes passwords with bcrypt at a fixed cost
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt returns a bcrypt hasher; a zero cost means bcrypt.DefaultCost
+func NewBcrypt(cost int) (*Bcrypt, error) {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost %d out of range %d-%d", cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &Bcrypt{cost: cost}, nil
+}
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	return string(h), err
+}
+
+func (b *Bcrypt) Verify(password, hash string) (bool, bool) {
+	if !isBcrypt(hash) {
+		ok := verifyAny(password, hash)
+		return ok, ok
+	}
+	ok, cost := verifyBcrypt(password, hash)
+	return ok, ok && cost != b.cost
+}
+
+// verifyBcrypt checks password against a bcrypt hash and returns the cost
+// it was made with
+func verifyBcrypt(password, hash string) (bool, int) {
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false, 0
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, 0
+	}
+	return true, cost
+}
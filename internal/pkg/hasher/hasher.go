@@ -0,0 +1,72 @@
+// Package hasher hashes passwords with bcrypt or argon2id. Every hasher
+// verifies hashes of both algorithms, so the algorithm or its cost can be
+// changed and stored hashes are upgraded on the next successful login.
+package hasher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Algorithms new hashes can be made with
+const (
+	AlgBcrypt   = "bcrypt"
+	AlgArgon2id = "argon2id"
+)
+
+// Hasher hashes new passwords with one algorithm and verifies hashes made
+// with any of them
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash and, when it does,
+	// whether hash should be replaced by a new Hash of password because it
+	// was made with another algorithm or cost
+	Verify(password, hash string) (ok, rehash bool)
+}
+
+// Config selects the algorithm of new hashes and its parameters
+type Config struct {
+	// Algorithm is AlgBcrypt or AlgArgon2id; empty means bcrypt
+	Algorithm string
+	// BcryptCost defaults to bcrypt.DefaultCost
+	BcryptCost int
+	// Argon2 defaults to DefaultArgon2Params, field by field
+	Argon2 Argon2Params
+}
+
+// New returns the hasher for cfg.Algorithm
+func New(cfg Config) (Hasher, error) {
+	switch cfg.Algorithm {
+	case "", AlgBcrypt:
+		return NewBcrypt(cfg.BcryptCost)
+	case AlgArgon2id:
+		return NewArgon2id(cfg.Argon2), nil
+	}
+	return nil, fmt.Errorf("unsupported password hash algorithm %q, use bcrypt or argon2id", cfg.Algorithm)
+}
+
+// OrDefault returns h, or bcrypt at its default cost when h is nil
+func OrDefault(h Hasher) Hasher {
+	if h == nil {
+		b, _ := NewBcrypt(0)
+		return b
+	}
+	return h
+}
+
+// verifyAny checks password against a hash in any supported format
+func verifyAny(password, hash string) bool {
+	switch {
+	case isArgon2id(hash):
+		ok, _ := verifyArgon2id(password, hash)
+		return ok
+	case isBcrypt(hash):
+		ok, _ := verifyBcrypt(password, hash)
+		return ok
+	}
+	return false
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2")
+}
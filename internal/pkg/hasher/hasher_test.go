@@ -0,0 +1,71 @@
+package hasher
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// smallArgon2 keeps the tests fast
+var smallArgon2 = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestVerifyAcrossAlgorithms(t *testing.T) {
+	cheapBcrypt, err := NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	costlyBcrypt, err := NewBcrypt(bcrypt.MinCost + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon := NewArgon2id(smallArgon2)
+	strongerArgon := NewArgon2id(Argon2Params{Memory: 128, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+
+	tests := []struct {
+		name       string
+		made, used Hasher
+		wantRehash bool
+	}{
+		{"same bcrypt", cheapBcrypt, cheapBcrypt, false},
+		{"bcrypt of another cost", cheapBcrypt, costlyBcrypt, true},
+		{"bcrypt checked by argon2id", cheapBcrypt, argon, true},
+		{"same argon2id", argon, argon, false},
+		{"argon2id of other params", argon, strongerArgon, true},
+		{"argon2id checked by bcrypt", argon, cheapBcrypt, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.made.Hash("Rahasia#2025")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, rehash := tt.used.Verify("Rahasia#2025", hash); !ok || rehash != tt.wantRehash {
+				t.Errorf("right password: ok, rehash = %t, %t, want true, %t", ok, rehash, tt.wantRehash)
+			}
+			// A wrong password is never worth a new hash
+			if ok, rehash := tt.used.Verify("Rahasia#2026", hash); ok || rehash {
+				t.Errorf("wrong password: ok, rehash = %t, %t, want false, false", ok, rehash)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{Algorithm: "md5"}); err == nil {
+		t.Error("an unsupported algorithm was accepted")
+	}
+	if _, err := New(Config{BcryptCost: bcrypt.MaxCost + 1}); err == nil {
+		t.Error("a bcrypt cost out of range was accepted")
+	}
+	h, err := New(Config{Algorithm: AlgArgon2id, Argon2: smallArgon2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := h.Hash("Rahasia#2025")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isArgon2id(hash) {
+		t.Errorf("hash %q is not argon2id", hash)
+	}
+}
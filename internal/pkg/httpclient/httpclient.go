@@ -0,0 +1,90 @@
+// Package httpclient is the HTTP client shared by calls to outside
+// services. It keeps one connection pool and retries transient failures.
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Defaults of Config
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 500 * time.Millisecond
+)
+
+// Config sets how long a call may take and how it is retried
+type Config struct {
+	// Timeout bounds each attempt, reading the response included
+	Timeout time.Duration
+	// MaxAttempts is how often a request is sent, the first time included
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles after each
+	Backoff time.Duration
+}
+
+// Client sends requests and retries those failing with a network error, a
+// 429 or a 5xx status. It is safe for concurrent use.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+// New creates a client; zero values of cfg use the defaults
+func New(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	return &Client{cfg: cfg, http: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Do sends req and returns the last response, which the caller closes. A
+// request with a body is only retried when req.GetBody can rewind it, as
+// set by http.NewRequest for in-memory bodies. Waits between attempts end
+// with the request's context.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	backoff := c.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.http.Do(req)
+		if attempt == c.cfg.MaxAttempts || !retryable(resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		if resp != nil {
+			// Drained so the connection goes back to the pool
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether a failed attempt may succeed when sent again
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
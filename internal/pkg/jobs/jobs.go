@@ -0,0 +1,208 @@
+// Package jobs runs work handed off by requests, such as sending a
+// notification, on a fixed number of workers with retries, instead of a
+// goroutine per call
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"backend-service-internpro/internal/pkg/logger"
+)
+
+// Defaults of Config
+const (
+	DefaultWorkers     = 4
+	DefaultQueueSize   = 256
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = time.Second
+	DefaultTimeout     = time.Minute
+)
+
+var (
+	// ErrQueueFull is returned when every worker is busy and the queue
+	// holds as many jobs as it may
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrStopped is returned for jobs enqueued after the runner stopped
+	ErrStopped = errors.New("job runner is stopped")
+)
+
+// Job is a unit of background work. It must stop when ctx is done and be
+// safe to run again after returning an error.
+type Job func(ctx context.Context) error
+
+// Queue accepts background jobs
+type Queue interface {
+	// Enqueue hands job over without waiting for it to run. args are key
+	// value pairs logged with the job when it fails for good.
+	Enqueue(name string, job Job, args ...any) error
+}
+
+// OrInline returns q, or a queue running jobs inline when q is nil
+func OrInline(q Queue) Queue {
+	if q == nil {
+		return Inline{}
+	}
+	return q
+}
+
+// Inline runs each job once, right away on the caller's goroutine. It
+// suits tests and tools that have no runner.
+type Inline struct{}
+
+// Enqueue runs job and logs its error
+func (Inline) Enqueue(name string, job Job, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	if err := job(ctx); err != nil {
+		logFailure(name, 1, err, args)
+	}
+	return nil
+}
+
+// Config bounds the work a Runner takes on
+type Config struct {
+	// Workers is how many jobs run at once
+	Workers int
+	// QueueSize is how many jobs may wait for a worker
+	QueueSize int
+	// MaxAttempts is how often a failing job runs, the first run included
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles after each
+	Backoff time.Duration
+	// Timeout bounds each run of a job
+	Timeout time.Duration
+}
+
+// task is a queued job
+type task struct {
+	name string
+	job  Job
+	args []any
+}
+
+// Runner runs jobs on a fixed pool of workers from a bounded queue and
+// retries failed jobs with exponential backoff. Enqueue never blocks: when
+// the queue is full the job is refused.
+type Runner struct {
+	cfg   Config
+	tasks chan task
+	// done is closed by Stop to cut retry waits short
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewRunner starts a runner; zero values of cfg use the defaults
+func NewRunner(cfg Config) *Runner {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	r := &Runner{
+		cfg:   cfg,
+		tasks: make(chan task, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+	r.wg.Add(cfg.Workers)
+	for range cfg.Workers {
+		go r.work()
+	}
+	return r
+}
+
+// Enqueue queues job for a worker, or returns ErrQueueFull or ErrStopped
+func (r *Runner) Enqueue(name string, job Job, args ...any) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.stopped {
+		return ErrStopped
+	}
+	select {
+	case r.tasks <- task{name: name, job: job, args: args}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop refuses new jobs and waits until the queued ones ran, or until ctx
+// is done. Jobs still failing get no further retries.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.tasks)
+		close(r.done)
+	}
+	r.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	for t := range r.tasks {
+		r.run(t)
+	}
+}
+
+// run runs t until it succeeds or has used up its attempts
+func (r *Runner) run(t task) {
+	backoff := r.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		err := r.attempt(t.job)
+		if err == nil {
+			return
+		}
+		if attempt == r.cfg.MaxAttempts {
+			logFailure(t.name, attempt, err, t.args)
+			return
+		}
+
+		select {
+		case <-r.done:
+			logFailure(t.name, attempt, err, t.args)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (r *Runner) attempt(job Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+	defer cancel()
+	return job(ctx)
+}
+
+func logFailure(name string, attempts int, err error, args []any) {
+	logger.Global().Service().ErrorWithErr("background job failed", err,
+		append([]any{"job", name, "attempts", attempts}, args...)...)
+}
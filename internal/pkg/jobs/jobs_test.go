@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunnerRetries(t *testing.T) {
+	tests := []struct {
+		name string
+		// failures is how often the job fails before it succeeds
+		failures  int32
+		wantCalls int32
+	}{
+		{"succeeds at once", 0, 1},
+		{"succeeds on retry", 2, 3},
+		{"gives up", 10, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRunner(Config{Workers: 1, MaxAttempts: 3, Backoff: time.Millisecond})
+			var calls atomic.Int32
+			ran := make(chan struct{}, 10)
+			err := r.Enqueue("test", func(ctx context.Context) error {
+				defer func() { ran <- struct{}{} }()
+				if calls.Add(1) <= tt.failures {
+					return errors.New("gateway down")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range tt.wantCalls {
+				select {
+				case <-ran:
+				case <-time.After(time.Second):
+					t.Fatalf("job ran %d times, want %d", calls.Load(), tt.wantCalls)
+				}
+			}
+			// Give an unwanted retry the time to run
+			time.Sleep(10 * time.Millisecond)
+			if err := r.Stop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("job ran %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunnerBounds(t *testing.T) {
+	r := NewRunner(Config{Workers: 1, QueueSize: 1})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var ran atomic.Int32
+	block := func(ctx context.Context) error {
+		ran.Add(1)
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+
+	// One job runs, one waits and the next is refused
+	if err := r.Enqueue("running", block); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := r.Enqueue("queued", block); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Enqueue("refused", block); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("err = %v, want ErrQueueFull", err)
+	}
+
+	stopped := make(chan error)
+	go func() { stopped <- r.Stop(context.Background()) }()
+	// Stop waits for the queued job
+	close(release)
+	<-started
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if got := ran.Load(); got != 2 {
+		t.Errorf("%d jobs ran, want 2", got)
+	}
+	if err := r.Enqueue("late", block); !errors.Is(err, ErrStopped) {
+		t.Errorf("err = %v, want ErrStopped", err)
+	}
+}
+
+func TestStopEndsRetries(t *testing.T) {
+	r := NewRunner(Config{Workers: 1, MaxAttempts: 5, Backoff: time.Hour})
+	var calls atomic.Int32
+	failed := make(chan struct{})
+	err := r.Enqueue("test", func(ctx context.Context) error {
+		calls.Add(1)
+		close(failed)
+		return errors.New("gateway down")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-failed
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Stop(ctx); err != nil {
+		t.Fatalf("Stop waited out the backoff: %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("job ran %d times, want 1", got)
+	}
+}
@@ -1,44 +1,169 @@
 package jwt
 
 import (
+	"crypto/rsa"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Errors returned by ParseAccess. Clients refresh an expired token but must
+// sign in again when it is invalid.
+var (
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenInvalid = errors.New("token invalid")
 )
 
 type Secrets struct {
-	Access  []byte
-	Refresh []byte
+	// Access signs HS256 access tokens, which carry its SecretKeyID as kid
+	Access []byte
+	// OldAccess are retired HS256 secrets, newest first. They only verify
+	// access tokens signed before Access replaced them.
+	OldAccess [][]byte
+	Refresh   []byte
+	// Alg signs access tokens: AlgHS256, the default, with Access or
+	// AlgRS256 with AccessKey. Refresh tokens are always HS256.
+	Alg string
+	// AccessKey signs RS256 access tokens. Its public key verifies them and
+	// is published in the JWKS, also while Alg is AlgHS256.
+	AccessKey *rsa.PrivateKey
+	// KeyID is the kid of AccessKey; defaults to its RFC 7638 thumbprint
+	KeyID string
+	// AcceptHS256 keeps accepting HS256 access tokens while Alg is
+	// AlgRS256, until the ones issued before switching have expired
+	AcceptHS256 bool
 }
 
+// Token types carried in the typ claim, so a refresh token is never
+// accepted where an access token is expected
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
 type Claims struct {
 	UserID string `json:"uid"`
+	// Type is TypeAccess; ParseAccess rejects any other
+	Type string `json:"typ"`
+	// SessionID is the refresh token the access token was issued with;
+	// empty in tokens issued before sessions were tracked
+	SessionID string `json:"sid,omitempty"`
+	// Roles are the slugs of the user's active roles when the token was
+	// issued. It is nil when they were not included, as in tokens issued
+	// before roles were, and empty when the user had none.
+	Roles []string `json:"roles"`
+	// ImpersonatorID is the super admin acting as UserID; empty in the
+	// tokens users get themselves
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccess(userID string, secret []byte, ttl time.Duration) (string, error) {
+// AccessClaims is what an access token is issued for
+type AccessClaims struct {
+	// ID is the jti, by which the token can be revoked; defaults to a random UUID
+	ID        string
+	UserID    string
+	SessionID string
+	// Roles are included when not nil; an empty slice claims no roles
+	Roles []string
+	// IssuedAt defaults to now. Set it to when Roles were read, so a role
+	// change made in between makes the claim stale.
+	IssuedAt time.Time
+	// ImpersonatorID marks a token issued to a super admin acting as UserID
+	ImpersonatorID string
+}
+
+// GenerateAccess signs an access token with the algorithm of secrets
+func GenerateAccess(c AccessClaims, secrets Secrets, ttl time.Duration) (string, error) {
+	issuedAt := c.IssuedAt
+	if issuedAt.IsZero() {
+		issuedAt = time.Now()
+	}
+	id := c.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
 	claims := &Claims{
-		UserID: userID,
+		UserID:         c.UserID,
+		Type:           TypeAccess,
+		SessionID:      c.SessionID,
+		Roles:          c.Roles,
+		ImpersonatorID: c.ImpersonatorID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
 		},
 	}
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if secrets.Alg == AlgRS256 {
+		t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		t.Header["kid"] = secrets.keyID()
+		return t.SignedString(secrets.AccessKey)
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t.Header["kid"] = SecretKeyID(secrets.Access)
+	return t.SignedString(secrets.Access)
 }
 
 func GenerateRefresh(userID string, secret []byte, ttl time.Duration) (string, error) {
-	// refresh bisa pakai claims minimal
-	claims := jwt.MapClaims{"uid": userID, "exp": time.Now().Add(ttl).Unix()}
+	// refresh bisa pakai claims minimal; jti keeps tokens issued in the same
+	// second with the same expiry distinct, e.g. when rotating
+	claims := jwt.MapClaims{"uid": userID, "typ": TypeRefresh, "exp": time.Now().Add(ttl).Unix(), "jti": uuid.NewString()}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseAccess verifies an access token signed with either algorithm
+// secrets accepts, see Secrets. Tokens without the access typ, like refresh
+// tokens, are invalid whatever their signature.
+func ParseAccess(tokenStr string, secrets Secrets) (*Claims, error) {
+	t, err := jwt.ParseWithClaims(tokenStr, &Claims{}, secrets.verifyKey)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrTokenInvalid
+	}
+	claims := t.Claims.(*Claims)
+	if claims.Type != TypeAccess {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// ScopedClaims are carried by single-purpose tokens such as verification
+// links. The audience names the purpose so the token cannot be used for
+// anything else, including as an access token.
+type ScopedClaims struct {
+	// Value binds the token to data that must not change in the meantime,
+	// e.g. the email address being verified
+	Value string `json:"val,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateScoped signs a token for audience about subject
+func GenerateScoped(audience, subject, value string, secret []byte, ttl time.Duration) (string, error) {
+	claims := &ScopedClaims{
+		Value: value,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
 	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
 }
 
-func ParseAccess(tokenStr string, secret []byte) (*Claims, error) {
-	t, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
+// ParseScoped validates a token issued by GenerateScoped for audience with
+// secret or, after rotating it, with one of the old secrets
+func ParseScoped(tokenStr, audience string, secret []byte, old ...[]byte) (*ScopedClaims, error) {
+	t, err := jwt.ParseWithClaims(tokenStr, &ScopedClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return secretSet(secret, old), nil
+	}, jwt.WithAudience(audience), jwt.WithExpirationRequired(), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
-	return t.Claims.(*Claims), nil
+	return t.Claims.(*ScopedClaims), nil
 }
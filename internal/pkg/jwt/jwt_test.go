@@ -0,0 +1,52 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseAccessRejectsOtherTokens(t *testing.T) {
+	// The same secret signs everything here, so only the typ claim tells
+	// the tokens apart
+	secret := []byte("shared-secret")
+	secrets := Secrets{Access: secret, Refresh: secret}
+
+	access, err := GenerateAccess(AccessClaims{UserID: "u1"}, secrets, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := GenerateRefresh("u1", secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untyped, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid": "u1", "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"access token", access, nil},
+		{"refresh token", refresh, ErrTokenInvalid},
+		{"token without a type", untyped, ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseAccess(tt.token, secrets)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err == nil && claims.UserID != "u1" {
+				t.Errorf("user = %q, want u1", claims.UserID)
+			}
+		})
+	}
+}
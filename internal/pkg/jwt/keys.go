@@ -0,0 +1,132 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Access token signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// ParsePrivateKey reads a PEM encoded RSA private key, PKCS#1 or PKCS#8
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// JWK is an RSA public key in JSON Web Key form (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty" doc:"Key type, always RSA"`
+	Use string `json:"use" doc:"Key use, always sig"`
+	Alg string `json:"alg" doc:"Algorithm, always RS256"`
+	Kid string `json:"kid" doc:"Key ID, matching the kid header of the tokens it verifies"`
+	N   string `json:"n" doc:"Modulus, base64url encoded"`
+	E   string `json:"e" doc:"Public exponent, base64url encoded"`
+}
+
+// JWKSet is a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys" doc:"Keys verifying access tokens; empty while they are only signed with a shared secret"`
+}
+
+// JWKS lists the public keys that verify access tokens
+func (s Secrets) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if s.AccessKey != nil {
+		n, e := publicKeyParams(&s.AccessKey.PublicKey)
+		set.Keys = append(set.Keys, JWK{Kty: "RSA", Use: "sig", Alg: AlgRS256, Kid: s.keyID(), N: n, E: e})
+	}
+	return set
+}
+
+// KeyID is the RFC 7638 thumbprint of key
+func KeyID(key *rsa.PublicKey) string {
+	n, e := publicKeyParams(key)
+	// Members in lexical order, without whitespace
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// SecretKeyID is the kid of an HS256 secret. It is a truncated hash, which
+// tells no more about the secret than the tokens signed with it.
+func SecretKeyID(secret []byte) string {
+	sum := sha256.Sum256(append([]byte("kid:"), secret...))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// secretSet is secret alone, or a key set trying it before the old secrets
+func secretSet(secret []byte, old [][]byte) interface{} {
+	if len(old) == 0 {
+		return secret
+	}
+	set := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{secret}}
+	for _, o := range old {
+		set.Keys = append(set.Keys, o)
+	}
+	return set
+}
+
+func (s Secrets) keyID() string {
+	if s.KeyID != "" {
+		return s.KeyID
+	}
+	return KeyID(&s.AccessKey.PublicKey)
+}
+
+// verifyKey returns the key verifying an access token, refusing algorithms
+// that s does not accept
+func (s Secrets) verifyKey(t *jwt.Token) (interface{}, error) {
+	switch t.Method {
+	case jwt.SigningMethodHS256:
+		if s.Alg == AlgRS256 && !s.AcceptHS256 {
+			break
+		}
+		// The kid picks the secret; tokens without one, or with an unknown
+		// one, are tried against every secret
+		if kid, _ := t.Header["kid"].(string); kid != "" {
+			for _, secret := range append([][]byte{s.Access}, s.OldAccess...) {
+				if SecretKeyID(secret) == kid {
+					return secret, nil
+				}
+			}
+		}
+		return secretSet(s.Access, s.OldAccess), nil
+	case jwt.SigningMethodRS256:
+		if s.AccessKey == nil {
+			break
+		}
+		return &s.AccessKey.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
+
+func publicKeyParams(key *rsa.PublicKey) (n, e string) {
+	n = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	return n, e
+}
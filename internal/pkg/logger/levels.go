@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// ScopeKey is the field holding the scope of a record, see Logger.Scope
+const ScopeKey = "scope"
+
+// String is the name ParseLevel accepts for l
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// ParseLevel reads a level name: debug, info, warn or error
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q, use debug, info, warn or error", name)
+}
+
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// overrides holds the levels set at runtime, per scope, for every logger.
+// They live in memory and are lost on restart.
+var overrides = struct {
+	sync.RWMutex
+	levels map[string]LogLevel
+}{levels: make(map[string]LogLevel)}
+
+// SetLevel logs scope, and the scopes nested in it that have no level of
+// their own, at level instead of the logger's level
+func SetLevel(scope string, level LogLevel) {
+	overrides.Lock()
+	defer overrides.Unlock()
+	overrides.levels[scope] = level
+}
+
+// ResetLevel removes the level set for scope
+func ResetLevel(scope string) {
+	overrides.Lock()
+	defer overrides.Unlock()
+	delete(overrides.levels, scope)
+}
+
+// Levels returns the levels set per scope
+func Levels() map[string]LogLevel {
+	overrides.RLock()
+	defer overrides.RUnlock()
+	levels := make(map[string]LogLevel, len(overrides.levels))
+	for scope, level := range overrides.levels {
+		levels[scope] = level
+	}
+	return levels
+}
+
+// levelFor returns the level set for scope or the nearest scope it is
+// nested in
+func levelFor(scope string) (LogLevel, bool) {
+	overrides.RLock()
+	defer overrides.RUnlock()
+	if len(overrides.levels) == 0 {
+		return 0, false
+	}
+	for scope != "" {
+		if level, ok := overrides.levels[scope]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(scope, '.')
+		if i < 0 {
+			break
+		}
+		scope = scope[:i]
+	}
+	return 0, false
+}
+
+// scopedHandler drops records below the level of their scope, read from the
+// overrides for each record so changes apply to existing loggers
+type scopedHandler struct {
+	slog.Handler
+	scope string
+	base  slog.Level
+}
+
+func (h *scopedHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if override, ok := levelFor(h.scope); ok {
+		return level >= override.slogLevel()
+	}
+	return level >= h.base
+}
+
+func (h *scopedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scope := h.scope
+	for _, a := range attrs {
+		if a.Key == ScopeKey {
+			scope = a.Value.String()
+		}
+	}
+	return &scopedHandler{Handler: h.Handler.WithAttrs(attrs), scope: scope, base: h.base}
+}
+
+func (h *scopedHandler) WithGroup(name string) slog.Handler {
+	return &scopedHandler{Handler: h.Handler.WithGroup(name), scope: h.scope, base: h.base}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScopeLevelsChangeAtRuntime(t *testing.T) {
+	t.Cleanup(func() {
+		for scope := range Levels() {
+			ResetLevel(scope)
+		}
+	})
+	var buf bytes.Buffer
+	l := NewWithWriter(&buf, LevelInfo)
+	rbacRepo := l.Scope("rbac").Repository()
+	auth := l.Auth()
+
+	// logged returns the scopes of the records written by log
+	logged := func(log func()) []string {
+		t.Helper()
+		buf.Reset()
+		log()
+		var scopes []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("log line %q: %v", line, err)
+			}
+			scopes = append(scopes, record[ScopeKey].(string)+" "+record["level"].(string))
+		}
+		return scopes
+	}
+	debug := func() {
+		rbacRepo.Debug("query")
+		auth.Debug("token parsed")
+	}
+	info := func() {
+		rbacRepo.Info("query")
+		auth.Info("login")
+	}
+
+	steps := []struct {
+		name  string
+		apply func()
+		log   func()
+		want  []string
+	}{
+		{"logger level", func() {}, debug, nil},
+		// The nested rbac.repository scope follows rbac
+		{"debug for rbac", func() { SetLevel("rbac", LevelDebug) }, debug, []string{"rbac.repository DEBUG"}},
+		{"error for the nested scope", func() { SetLevel("rbac.repository", LevelError) }, info, []string{"auth INFO"}},
+		{"nested scope reset", func() { ResetLevel("rbac.repository") }, debug, []string{"rbac.repository DEBUG"}},
+		{"rbac reset", func() { ResetLevel("rbac") }, debug, nil},
+		{"info after the reset", func() {}, info, []string{"rbac.repository INFO", "auth INFO"}},
+	}
+	for _, step := range steps {
+		step.apply()
+		got := logged(step.log)
+		if !slices.Equal(got, step.want) {
+			t.Errorf("%s: logged %v, want %v", step.name, got, step.want)
+		}
+	}
+}
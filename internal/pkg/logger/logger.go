@@ -3,6 +3,7 @@ package logger
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"time"
@@ -11,6 +12,8 @@ import (
 // Logger wraps slog with additional functionality
 type Logger struct {
 	*slog.Logger
+	// scope selects the level overrides that apply, see SetLevel
+	scope string
 }
 
 // LogLevel represents logging levels
@@ -23,24 +26,17 @@ const (
 	LevelError
 )
 
-// New creates a new logger instance
+// New creates a new logger instance writing records at level and above,
+// unless the level of the logger's scope was overridden
 func New(level LogLevel) *Logger {
-	var slogLevel slog.Level
-	switch level {
-	case LevelDebug:
-		slogLevel = slog.LevelDebug
-	case LevelInfo:
-		slogLevel = slog.LevelInfo
-	case LevelWarn:
-		slogLevel = slog.LevelWarn
-	case LevelError:
-		slogLevel = slog.LevelError
-	default:
-		slogLevel = slog.LevelInfo
-	}
+	return NewWithWriter(os.Stdout, level)
+}
 
+// NewWithWriter creates a logger like New writing JSON records to w
+func NewWithWriter(w io.Writer, level LogLevel) *Logger {
+	// The scoped handler filters records; the JSON handler writes them all
 	opts := &slog.HandlerOptions{
-		Level: slogLevel,
+		Level: slog.LevelDebug,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			// Format time as readable string
 			if a.Key == slog.TimeKey {
@@ -50,7 +46,10 @@ func New(level LogLevel) *Logger {
 		},
 	}
 
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	handler := &scopedHandler{
+		Handler: slog.NewJSONHandler(w, opts),
+		base:    level.slogLevel(),
+	}
 	return &Logger{
 		Logger: slog.New(handler),
 	}
@@ -60,6 +59,7 @@ func New(level LogLevel) *Logger {
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	return &Logger{
 		Logger: l.Logger.With(),
+		scope:  l.scope,
 	}
 }
 
@@ -71,33 +71,48 @@ func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	}
 	return &Logger{
 		Logger: l.Logger.With(args...),
+		scope:  l.scope,
+	}
+}
+
+// Scope returns a logger for the named scope, nested in the scope of l if
+// it has one, e.g. Global().Scope("rbac").Repository() logs in the scope
+// "rbac.repository". The scope is logged in the "scope" field.
+func (l *Logger) Scope(name string) *Logger {
+	scope := name
+	if l.scope != "" {
+		scope = l.scope + "." + name
+	}
+	return &Logger{
+		Logger: l.Logger.With(ScopeKey, scope),
+		scope:  scope,
 	}
 }
 
 // Auth logger with predefined fields
 func (l *Logger) Auth() *Logger {
-	return l.WithFields(map[string]interface{}{
+	return l.Scope("auth").WithFields(map[string]interface{}{
 		"service": "auth",
 	})
 }
 
 // HTTP logger with predefined fields
 func (l *Logger) HTTP() *Logger {
-	return l.WithFields(map[string]interface{}{
+	return l.Scope("http").WithFields(map[string]interface{}{
 		"layer": "http",
 	})
 }
 
 // Repository logger with predefined fields
 func (l *Logger) Repository() *Logger {
-	return l.WithFields(map[string]interface{}{
+	return l.Scope("repository").WithFields(map[string]interface{}{
 		"layer": "repository",
 	})
 }
 
 // Service logger with predefined fields
 func (l *Logger) Service() *Logger {
-	return l.WithFields(map[string]interface{}{
+	return l.Scope("service").WithFields(map[string]interface{}{
 		"layer": "service",
 	})
 }
@@ -135,13 +150,14 @@ func (l *Logger) LogRequest(method, path, userAgent, ip string) {
 	)
 }
 
-func (l *Logger) LogResponse(method, path string, statusCode int, duration time.Duration) {
-	l.Info("request completed",
+func (l *Logger) LogResponse(method, path, userID string, statusCode int, duration time.Duration, attrs ...any) {
+	l.Info("request completed", append([]any{
 		"method", method,
 		"path", path,
+		"user_id", userID,
 		"status_code", statusCode,
 		"duration_ms", duration.Milliseconds(),
-	)
+	}, attrs...)...)
 }
 
 // Login attempts logging
@@ -189,9 +205,19 @@ func (l *Logger) LogDBOperation(operation, table string, duration time.Duration,
 // Global logger instance
 var globalLogger *Logger
 
+// globalLevel is the level the global logger was created with
+var globalLevel = LevelInfo
+
 // InitGlobalLogger initializes the global logger
 func InitGlobalLogger(level LogLevel) {
 	globalLogger = New(level)
+	globalLevel = level
+}
+
+// GlobalLevel returns the level of the global logger's scopes that have no
+// level set
+func GlobalLevel() LogLevel {
+	return globalLevel
 }
 
 // Global logger functions
@@ -3,16 +3,33 @@ package mailer
 import (
 	"fmt"
 	"net/smtp"
+
+	"backend-service-internpro/internal/pkg/logger"
 )
 
+// Mailer sends HTML emails
+type Mailer interface {
+	Send(to, subject, htmlBody string) error
+}
+
 type SMTP struct{ Host, Port, User, Pass, From string }
 
-func (s SMTP) Send(to, subject, body string) error {
+func (s SMTP) Send(to, subject, htmlBody string) error {
 	addr := fmt.Sprintf("%s:%s", s.Host, s.Port)
-	msg := []byte("To: " + to + "\r\n" +
+	msg := []byte("From: " + s.From + "\r\n" +
+		"To: " + to + "\r\n" +
 		"Subject: " + subject + "\r\n" +
 		"Mime-Version: 1.0;\r\nContent-Type: text/html; charset=\"UTF-8\";\r\n\r\n" +
-		body)
+		htmlBody)
 	auth := smtp.PlainAuth("", s.User, s.Pass, s.Host)
 	return smtp.SendMail(addr, auth, s.From, []string{to}, msg)
 }
+
+// Noop drops every email, logging only the recipient and subject. It stands
+// in for SMTP in development when SMTP_HOST is empty.
+type Noop struct{}
+
+func (Noop) Send(to, subject, htmlBody string) error {
+	logger.Global().Service().Warn("SMTP is not configured, email dropped", "to", to, "subject", subject)
+	return nil
+}
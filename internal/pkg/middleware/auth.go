@@ -2,74 +2,249 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"backend-service-internpro/internal/pkg/audit"
 	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/revocation"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-// AuthMiddleware provides JWT authentication for Gin
-func AuthMiddleware(jwtSecrets jwt.Secrets) gin.HandlerFunc {
+// AuthMiddleware provides JWT authentication for Gin. Tokens in revoked,
+// which may be nil, are rejected.
+func AuthMiddleware(jwtSecrets jwt.Secrets, revoked revocation.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header is required",
-			})
+		claims, err := ValidateToken(c.Request.Context(), c.GetHeader("Authorization"), jwtSecrets, revoked)
+		if err != nil {
+			authErr, ok := err.(*AuthError)
+			if !ok {
+				abortCheckFailed(c, "token", err)
+				return
+			}
+			c.Header("WWW-Authenticate", authErr.Challenge)
+			c.Header("Content-Type", "application/problem+json")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, authErr)
 			return
 		}
 
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header must start with 'Bearer '",
-			})
+		// Store user ID in context for use in handlers
+		c.Set("userID", claims.UserID)
+		reqCtx := requestctx.WithClaims(c.Request.Context(), claims)
+		if userID, ok := requestctx.UserID(reqCtx); ok {
+			reqCtx = audit.WithActor(reqCtx, userID)
+		}
+		c.Request = c.Request.WithContext(reqCtx)
+		c.Next()
+	}
+}
+
+// BearerAuth is the security scheme name Huma operations declare to require a JWT
+const BearerAuth = "bearerAuth"
+
+// APIKeyHeader carries the API key of machine callers
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator resolves API keys, like the API key service. A nil
+// key without an error means the key is not valid.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*requestctx.APIKey, error)
+}
+
+// HumaAuthMiddleware enforces the operation's declared security. Operations
+// requiring BearerAuth are rejected with 401 unless a valid token is sent;
+// otherwise the claims and acting user are stored in the request context.
+// Operations without a security requirement pass through untouched, so
+// requestctx.UserID is false in their handlers. Tokens in revoked, which
+// may be nil, are rejected.
+//
+// When keys is not nil, a request without an Authorization header may send
+// an API key in X-API-Key instead. It acts as the key's user, marked with
+// requestctx.WithAPIKey so the route permission check can limit it to the
+// key's scopes.
+func HumaAuthMiddleware(api huma.API, jwtSecrets jwt.Secrets, revoked revocation.Store, keys APIKeyAuthenticator) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		if !requiresBearer(ctx.Operation()) {
+			next(ctx)
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenStr == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Token is required",
-			})
+		// Machine callers send an API key instead of a token
+		if key := ctx.Header(APIKeyHeader); key != "" && keys != nil && ctx.Header("Authorization") == "" {
+			reqCtx, authErr, err := authenticateAPIKey(ctx.Context(), keys, key)
+			if err != nil {
+				logger.Global().Auth().ErrorWithErr("failed to check API key", err)
+				_ = huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to check API key")
+				return
+			}
+			if authErr != nil {
+				writeAuthError(api, ctx, authErr)
+				return
+			}
+			next(huma.WithContext(ctx, reqCtx))
 			return
 		}
 
-		claims, err := jwt.ParseAccess(tokenStr, jwtSecrets.Access)
+		claims, err := ValidateToken(ctx.Context(), ctx.Header("Authorization"), jwtSecrets, revoked)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or expired token",
-			})
+			authErr, ok := err.(*AuthError)
+			if !ok {
+				logger.Global().Auth().ErrorWithErr("failed to check token", err)
+				_ = huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to check token")
+				return
+			}
+			writeAuthError(api, ctx, authErr)
+			return
+		}
+		reqCtx := requestctx.WithClaims(ctx.Context(), claims)
+		userID, ok := requestctx.UserID(reqCtx)
+		if !ok {
+			writeAuthError(api, ctx, tokenError(jwt.ErrTokenInvalid))
 			return
 		}
+		reqCtx = audit.WithActor(reqCtx, userID)
+		next(huma.WithContext(ctx, reqCtx))
+	}
+}
 
-		// Store user ID in context for use in handlers
-		c.Set("userID", claims.UserID)
-		c.Next()
+// authenticateAPIKey returns the request context of a valid API key, the
+// 401 to answer with for an invalid one, or the error of keys
+func authenticateAPIKey(ctx context.Context, keys APIKeyAuthenticator, key string) (context.Context, *AuthError, error) {
+	apiKey, err := keys.Authenticate(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if apiKey == nil {
+		return nil, invalidAPIKey(), nil
+	}
+	reqCtx := requestctx.WithClaims(ctx, &jwt.Claims{UserID: apiKey.UserID.String()})
+	reqCtx = requestctx.WithAPIKey(reqCtx, apiKey)
+	return audit.WithActor(reqCtx, apiKey.UserID), nil, nil
+}
+
+// requiresBearer reports whether every security alternative of op needs a bearer token
+func requiresBearer(op *huma.Operation) bool {
+	if op == nil || len(op.Security) == 0 {
+		return false
+	}
+	for _, req := range op.Security {
+		if _, ok := req[BearerAuth]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// UserIDFromContext returns the authenticated user ID as requestctx.UserID
+// does, with a 401 error Huma handlers can return as is when it is missing
+func UserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := requestctx.UserID(ctx)
+	// The nil ID belongs to no user, so a token naming it counts as none
+	if !ok || userID == uuid.Nil {
+		return uuid.Nil, missingToken().humaError()
 	}
+	return userID, nil
 }
 
-// ValidateToken validates JWT token for Huma handlers
-func ValidateToken(authHeader string, jwtSecrets jwt.Secrets) (*jwt.Claims, error) {
+// AuthError is the 401 returned for a missing or rejected bearer token. The
+// body has the same shape on Gin and Huma routes, and Challenge is sent as
+// the WWW-Authenticate header (RFC 6750) so clients can tell an expired
+// token, worth refreshing, from one that needs a new sign in.
+type AuthError struct {
+	huma.ErrorModel
+	Challenge string `json:"-"`
+}
+
+// humaError returns the error for a Huma handler to return, written like
+// the other Huma errors and with the WWW-Authenticate header
+func (e *AuthError) humaError() error {
+	return huma.ErrorWithHeaders(huma.Error401Unauthorized(e.Detail), http.Header{"WWW-Authenticate": {e.Challenge}})
+}
+
+func newAuthError(detail, challenge string) *AuthError {
+	return &AuthError{
+		ErrorModel: huma.ErrorModel{
+			Title:  http.StatusText(http.StatusUnauthorized),
+			Status: http.StatusUnauthorized,
+			Detail: detail,
+		},
+		Challenge: challenge,
+	}
+}
+
+// missingToken is returned when no credentials were sent; the challenge
+// carries no error code as RFC 6750 section 3.1 asks
+func missingToken() *AuthError {
+	return newAuthError("Authorization header is required", "Bearer")
+}
+
+// tokenError translates a jwt.ParseAccess error
+func tokenError(err error) *AuthError {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return newAuthError("Token has expired",
+			`Bearer error="invalid_token", error_description="The access token expired"`)
+	}
+	return newAuthError("Invalid token",
+		`Bearer error="invalid_token", error_description="The access token is malformed or its signature is invalid"`)
+}
+
+// revokedToken is returned for a token revoked before it expired, which
+// a refresh cannot help with when its session or user was revoked too
+func revokedToken() *AuthError {
+	return newAuthError("Token has been revoked",
+		`Bearer error="invalid_token", error_description="The access token was revoked"`)
+}
+
+// invalidAPIKey is returned for an unknown, revoked or expired API key
+func invalidAPIKey() *AuthError {
+	return newAuthError("Invalid API key", `APIKey error="invalid_key", error_description="The API key is unknown, revoked or expired"`)
+}
+
+// writeAuthError writes err from a Huma middleware, where returning it is
+// not an option
+func writeAuthError(api huma.API, ctx huma.Context, err *AuthError) {
+	ctx.SetHeader("WWW-Authenticate", err.Challenge)
+	_ = huma.WriteErr(api, ctx, err.Status, err.Detail)
+}
+
+// ValidateToken validates the Authorization header of a request and, when
+// revoked is not nil, that the token was not revoked. Failures are returned
+// as *AuthError, errors of the revocation store as they are.
+func ValidateToken(ctx context.Context, authHeader string, jwtSecrets jwt.Secrets, revoked revocation.Store) (*jwt.Claims, error) {
 	if authHeader == "" {
-		return nil, huma.Error401Unauthorized("Authorization header is required")
+		return nil, missingToken()
 	}
 
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return nil, huma.Error401Unauthorized("Authorization header must start with 'Bearer '")
+		return nil, newAuthError("Authorization header must start with 'Bearer '",
+			`Bearer error="invalid_request", error_description="The Authorization header must use the Bearer scheme"`)
 	}
 
 	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 	if tokenStr == "" {
-		return nil, huma.Error401Unauthorized("Token is required")
+		return nil, newAuthError("Token is required",
+			`Bearer error="invalid_request", error_description="The bearer token is empty"`)
 	}
 
-	claims, err := jwt.ParseAccess(tokenStr, jwtSecrets.Access)
+	claims, err := jwt.ParseAccess(tokenStr, jwtSecrets)
 	if err != nil {
-		return nil, huma.Error401Unauthorized("Invalid or expired token")
+		return nil, tokenError(err)
+	}
+
+	if revoked != nil {
+		isRevoked, err := revoked.IsRevoked(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+		if isRevoked {
+			return nil, revokedToken()
+		}
 	}
 
 	return claims, nil
@@ -96,27 +271,3 @@ func GetAuthContext(c *gin.Context) (*AuthContext, bool) {
 		UserID: userIDStr,
 	}, true
 }
-
-// RequireAuth creates a middleware that requires authentication for Huma operations
-func RequireAuth(jwtSecrets jwt.Secrets) func(ctx context.Context, op *huma.Operation) *huma.ErrorDetail {
-	return func(ctx context.Context, op *huma.Operation) *huma.ErrorDetail {
-		// This would be used with Huma's middleware system if available
-		// For now, we'll use manual validation in handlers
-		return nil
-	}
-}
-
-// WithAuth is a helper function to wrap handlers with authentication
-type AuthenticatedHandler[I, O any] func(ctx context.Context, input *I, userID string) (*O, error)
-
-func WithAuth[I any, O any](
-	jwtSecrets jwt.Secrets,
-	handler AuthenticatedHandler[I, O],
-) func(ctx context.Context, input *I) (*O, error) {
-	return func(ctx context.Context, input *I) (*O, error) {
-		// Extract Authorization header from input using reflection or type assertion
-		// This is a simplified version - in practice, you'd need to handle the header extraction
-		// For now, we'll keep using the manual validation approach in handlers
-		return nil, huma.Error500InternalServerError("WithAuth wrapper not fully implemented")
-	}
-}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/requestctx"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var testSecrets = jwt.Secrets{Access: []byte("access-secret"), Refresh: []byte("refresh-secret")}
+
+// newAuthAPI returns an API behind HumaAuthMiddleware with one operation
+// requiring a bearer token and one without security. Neither handler
+// checks the caller itself.
+func newAuthAPI(t *testing.T) humatest.TestAPI {
+	t.Helper()
+	_, api := humatest.New(t)
+	api.UseMiddleware(HumaAuthMiddleware(api, testSecrets, nil, nil))
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getSecured",
+		Method:      http.MethodGet,
+		Path:        "/secured",
+		Security:    []map[string][]string{{BearerAuth: {}}},
+	}, func(ctx context.Context, _ *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "getOpen",
+		Method:      http.MethodGet,
+		Path:        "/open",
+	}, func(ctx context.Context, _ *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	return api
+}
+
+func bearer(t *testing.T, userID string, ttl time.Duration) string {
+	t.Helper()
+	token, err := jwt.GenerateAccess(jwt.AccessClaims{UserID: userID}, testSecrets, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Authorization: Bearer " + token
+}
+
+func TestDeclaredSecurityIsEnforced(t *testing.T) {
+	api := newAuthAPI(t)
+
+	tests := []struct {
+		name string
+		path string
+		args []any
+		want int
+	}{
+		{"secured without a token", "/secured", nil, http.StatusUnauthorized},
+		{"secured with a token", "/secured", []any{bearer(t, uuid.NewString(), time.Minute)}, http.StatusNoContent},
+		{"open without a token", "/open", nil, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := api.Get(tt.path, tt.args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body)
+			}
+		})
+	}
+}
+
+func TestHandlersSeeTheActingUser(t *testing.T) {
+	api := newAuthAPI(t)
+
+	// whoami records what its handler finds in the context
+	type whoami struct {
+		userID    uuid.UUID
+		hasUser   bool
+		hasClaims bool
+		err       error
+	}
+	var seen whoami
+	record := func(ctx context.Context, _ *struct{}) (*struct{}, error) {
+		seen = whoami{}
+		seen.userID, seen.hasUser = requestctx.UserID(ctx)
+		_, seen.hasClaims = requestctx.Claims(ctx)
+		_, seen.err = UserIDFromContext(ctx)
+		return nil, nil
+	}
+	huma.Register(api, huma.Operation{
+		OperationID: "getSecuredWhoami",
+		Method:      http.MethodGet,
+		Path:        "/secured/whoami",
+		Security:    []map[string][]string{{BearerAuth: {}}},
+	}, record)
+	huma.Register(api, huma.Operation{
+		OperationID: "getOpenWhoami",
+		Method:      http.MethodGet,
+		Path:        "/open/whoami",
+	}, record)
+
+	userID := uuid.New()
+	if resp := api.Get("/secured/whoami", bearer(t, userID.String(), time.Minute)); resp.Code != http.StatusNoContent {
+		t.Fatalf("status = %d: %s", resp.Code, resp.Body)
+	}
+	if !seen.hasUser || seen.userID != userID || !seen.hasClaims || seen.err != nil {
+		t.Errorf("behind the middleware: %+v, want user %s with claims", seen, userID)
+	}
+
+	// The nil ID belongs to no user, even in a valid token
+	if resp := api.Get("/secured/whoami", bearer(t, uuid.Nil.String(), time.Minute)); resp.Code != http.StatusNoContent {
+		t.Fatalf("status = %d: %s", resp.Code, resp.Body)
+	}
+	var se huma.StatusError
+	if !errors.As(seen.err, &se) || se.GetStatus() != http.StatusUnauthorized {
+		t.Errorf("UserIDFromContext for the nil ID: err = %v, want a 401", seen.err)
+	}
+
+	// A token sent to a route without security is not validated, so the
+	// handler sees no user and asking for one fails with a 401
+	if resp := api.Get("/open/whoami", bearer(t, userID.String(), time.Minute)); resp.Code != http.StatusNoContent {
+		t.Fatalf("status = %d: %s", resp.Code, resp.Body)
+	}
+	if seen.hasUser || seen.hasClaims {
+		t.Errorf("on an open route: %+v, want no user or claims", seen)
+	}
+	if !errors.As(seen.err, &se) || se.GetStatus() != http.StatusUnauthorized {
+		t.Errorf("UserIDFromContext on an open route: err = %v, want a 401", seen.err)
+	}
+}
+
+func TestUnauthorizedResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	api := newAuthAPI(t)
+	engine := gin.New()
+	engine.GET("/secured", AuthMiddleware(testSecrets, nil), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	forged, err := jwt.GenerateAccess(jwt.AccessClaims{UserID: uuid.NewString()}, jwt.Secrets{Access: []byte("other-secret")}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := `Bearer error="invalid_token", error_description="The access token expired"`
+	malformed := `Bearer error="invalid_token", error_description="The access token is malformed or its signature is invalid"`
+
+	tests := []struct {
+		name      string
+		header    string
+		detail    string
+		challenge string
+	}{
+		{"missing header", "", "Authorization header is required", "Bearer"},
+		{"another scheme", "Basic dXNlcjpwYXNz", "Authorization header must start with 'Bearer '",
+			`Bearer error="invalid_request", error_description="The Authorization header must use the Bearer scheme"`},
+		{"malformed token", "Bearer not.a.jwt", "Invalid token", malformed},
+		{"foreign signature", "Bearer " + forged, "Invalid token", malformed},
+		{"expired token", strings.TrimPrefix(bearer(t, uuid.NewString(), -time.Minute), "Authorization: "), "Token has expired", expired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if tt.header != "" {
+				args = append(args, "Authorization: "+tt.header)
+			}
+			humaResp := api.Get("/secured", args...)
+
+			req := httptest.NewRequest(http.MethodGet, "/secured", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			ginResp := httptest.NewRecorder()
+			engine.ServeHTTP(ginResp, req)
+
+			// Both routes answer the same way
+			for name, resp := range map[string]*httptest.ResponseRecorder{"huma": humaResp, "gin": ginResp} {
+				if resp.Code != http.StatusUnauthorized {
+					t.Errorf("%s: status = %d, want %d", name, resp.Code, http.StatusUnauthorized)
+				}
+				if got := resp.Header().Get("WWW-Authenticate"); got != tt.challenge {
+					t.Errorf("%s: WWW-Authenticate = %q, want %q", name, got, tt.challenge)
+				}
+				var body struct {
+					Title  string `json:"title"`
+					Status int    `json:"status"`
+					Detail string `json:"detail"`
+				}
+				if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+					t.Fatalf("%s: %v: %s", name, err, resp.Body)
+				}
+				if body.Title != "Unauthorized" || body.Status != http.StatusUnauthorized || body.Detail != tt.detail {
+					t.Errorf("%s: body = %s, want detail %q", name, resp.Body, tt.detail)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,18 @@
 package middleware
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
 	"time"
 
+	"backend-service-internpro/internal/pkg/jwt"
 	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // CORSMiddleware provides CORS support
@@ -81,12 +88,44 @@ func CORSMiddleware() gin.HandlerFunc {
 	})
 }
 
+// LoggingConfig configures request and panic logging
+type LoggingConfig struct {
+	// SkipPaths are not logged, see PathSet for the pattern syntax
+	SkipPaths []string
+	// UserIDKey, when set, logs user IDs as a hash keyed with it instead of
+	// in plain text
+	UserIDKey []byte
+	// Logger receives the records; the global logger when nil
+	Logger *logger.Logger
+}
+
+func (cfg LoggingConfig) logger() *logger.Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
+	}
+	return logger.Global()
+}
+
 // LoggingMiddleware provides request/response logging
 func LoggingMiddleware() gin.HandlerFunc {
-	appLogger := logger.Global()
+	return LoggingMiddlewareWithConfig(LoggingConfig{})
+}
+
+// LoggingMiddlewareWithConfig provides request/response logging. The
+// completion line carries the user_id of the authenticated caller, or "-"
+// when the request carried no valid token.
+func LoggingMiddlewareWithConfig(cfg LoggingConfig) gin.HandlerFunc {
+	appLogger := cfg.logger()
+	skip := NewPathSet(cfg.SkipPaths...)
 
 	return gin.HandlerFunc(func(c *gin.Context) {
+		if skip.Contains(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
 		start := time.Now()
+		trackUser(c)
 
 		// Log incoming request
 		appLogger.HTTP().LogRequest(
@@ -99,29 +138,108 @@ func LoggingMiddleware() gin.HandlerFunc {
 		// Process request
 		c.Next()
 
-		// Log response
+		// Log response, flagging requests made with an impersonation token
+		var attrs []any
+		if impersonatorID, ok := requestctx.SlotImpersonatorID(c.Request.Context()); ok {
+			attrs = append(attrs, "impersonated", true, "impersonator_id", hashUserID(impersonatorID, cfg.UserIDKey))
+		}
 		appLogger.HTTP().LogResponse(
 			c.Request.Method,
 			c.Request.URL.Path,
+			logUserID(c, cfg.UserIDKey),
 			c.Writer.Status(),
 			time.Since(start),
+			attrs...,
 		)
 	})
 }
 
+// ClientIPMiddleware stores the client IP Gin resolved from the trusted
+// proxies in the request context, see requestctx.ClientIP
+func ClientIPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(requestctx.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// UsageRecorder counts the finished requests of authenticated users
+type UsageRecorder interface {
+	Record(userID uuid.UUID, status int)
+}
+
+// UsageMiddleware reports each request of an authenticated user and its
+// status to recorder. A request throttled before authentication, like by
+// the per-IP rate limit, is attributed through its bearer token when valid.
+func UsageMiddleware(recorder UsageRecorder, jwtSecrets jwt.Secrets) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackUser(c)
+		c.Next()
+
+		status := c.Writer.Status()
+		userID, ok := requestctx.SlotUserID(c.Request.Context())
+		if !ok && status == http.StatusTooManyRequests {
+			if claims, err := ValidateToken(c.Request.Context(), c.GetHeader("Authorization"), jwtSecrets, nil); err == nil {
+				userID, err = uuid.Parse(claims.UserID)
+				ok = err == nil
+			}
+		}
+		if ok {
+			recorder.Record(userID, status)
+		}
+	}
+}
+
 // RecoveryMiddleware provides panic recovery
 func RecoveryMiddleware() gin.HandlerFunc {
-	appLogger := logger.Global()
+	return RecoveryMiddlewareWithConfig(LoggingConfig{})
+}
 
-	return gin.RecoveryWithWriter(gin.DefaultWriter, func(c *gin.Context, recovered interface{}) {
+// RecoveryMiddlewareWithConfig provides panic recovery, logging the user_id
+// like LoggingMiddlewareWithConfig; SkipPaths is ignored
+func RecoveryMiddlewareWithConfig(cfg LoggingConfig) gin.HandlerFunc {
+	appLogger := cfg.logger()
+	recovery := gin.RecoveryWithWriter(gin.DefaultWriter, func(c *gin.Context, recovered interface{}) {
 		appLogger.Error("panic recovered",
 			"error", recovered,
 			"path", c.Request.URL.Path,
 			"method", c.Request.Method,
 			"ip", c.ClientIP(),
+			"user_id", logUserID(c, cfg.UserIDKey),
 		)
 		c.JSON(500, gin.H{
 			"error": "Internal server error",
 		})
 	})
+
+	return func(c *gin.Context) {
+		trackUser(c)
+		recovery(c)
+	}
+}
+
+// trackUser lets the auth middlewares, which run later on a derived
+// context, record the authenticated user where the loggers can read it
+func trackUser(c *gin.Context) {
+	c.Request = c.Request.WithContext(requestctx.WithUserSlot(c.Request.Context()))
+}
+
+// logUserID returns the user ID to log for the request, hashed when key is
+// set, or "-" before authentication
+func logUserID(c *gin.Context, key []byte) string {
+	userID, ok := requestctx.SlotUserID(c.Request.Context())
+	if !ok {
+		return "-"
+	}
+	return hashUserID(userID, key)
+}
+
+// hashUserID returns userID as logged, hashed when key is set
+func hashUserID(userID uuid.UUID, key []byte) string {
+	if len(key) == 0 {
+		return userID.String()
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(userID[:])
+	return hex.EncodeToString(mac.Sum(nil))[:16]
 }
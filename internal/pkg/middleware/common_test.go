@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// logRecords parses the JSON log lines written to buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogsCarryTheAuthenticatedUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	hashKey := []byte("log-hash-key")
+
+	tests := []struct {
+		name   string
+		path   string
+		token  bool
+		key    []byte
+		msg    string
+		userID string
+	}{
+		{"authenticated request", "/me", true, nil, "request completed", userID.String()},
+		{"hashed user ID", "/me", true, hashKey, "request completed", hashUserID(userID, hashKey)},
+		{"request without a token", "/public", false, nil, "request completed", "-"},
+		{"token on a route without authentication", "/public", true, nil, "request completed", "-"},
+		{"panic after authentication", "/panic", true, nil, "panic recovered", userID.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := LoggingConfig{UserIDKey: tt.key, Logger: logger.NewWithWriter(&buf, logger.LevelInfo)}
+			engine := gin.New()
+			engine.Use(LoggingMiddlewareWithConfig(cfg), RecoveryMiddlewareWithConfig(cfg))
+			engine.GET("/public", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+			authed := engine.Group("", AuthMiddleware(testSecrets, nil))
+			authed.GET("/me", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+			authed.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token {
+				header, value, _ := strings.Cut(bearer(t, userID.String(), time.Minute), ": ")
+				req.Header.Set(header, value)
+			}
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			var found bool
+			for _, record := range logRecords(t, &buf) {
+				switch record["msg"] {
+				case "incoming request":
+					// Written before authentication
+					if got, ok := record["user_id"]; ok {
+						t.Errorf("incoming request logged user_id %v", got)
+					}
+				case tt.msg:
+					found = true
+					if got := record["user_id"]; got != tt.userID {
+						t.Errorf("%s logged user_id %v, want %s", tt.msg, got, tt.userID)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("no %q line in:\n%s", tt.msg, buf.String())
+			}
+		})
+	}
+}
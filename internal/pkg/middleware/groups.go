@@ -2,21 +2,22 @@ package middleware
 
 import (
 	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/revocation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AuthenticatedRoutes creates a router group with authentication middleware
-func AuthenticatedRoutes(router *gin.Engine, jwtSecrets jwt.Secrets) *gin.RouterGroup {
+func AuthenticatedRoutes(router *gin.Engine, jwtSecrets jwt.Secrets, revoked revocation.Store) *gin.RouterGroup {
 	authGroup := router.Group("/")
-	authGroup.Use(AuthMiddleware(jwtSecrets))
+	authGroup.Use(AuthMiddleware(jwtSecrets, revoked))
 	return authGroup
 }
 
 // AdminRoutes creates a router group with admin authentication
-func AdminRoutes(router *gin.Engine, jwtSecrets jwt.Secrets) *gin.RouterGroup {
+func AdminRoutes(router *gin.Engine, jwtSecrets jwt.Secrets, revoked revocation.Store) *gin.RouterGroup {
 	adminGroup := router.Group("/admin")
-	adminGroup.Use(AuthMiddleware(jwtSecrets))
+	adminGroup.Use(AuthMiddleware(jwtSecrets, revoked))
 	// Add admin role checking here if needed
 	return adminGroup
 }
@@ -1,11 +1,17 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strings"
 
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/routeperm"
 	"backend-service-internpro/internal/rbac/service"
 
+	"github.com/danielgtaylor/huma/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -22,12 +28,21 @@ func NewRBACMiddleware(rbacService service.Service) *RBACMiddleware {
 	}
 }
 
+// abortCheckFailed answers 500 when what could not be checked. The cause
+// may come from the database, so it is logged instead of returned.
+func abortCheckFailed(c *gin.Context, what string, err error) {
+	logger.Global().Auth().ErrorWithErr("failed to check "+what, err, "route", c.Request.Method+" "+c.FullPath())
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+		"error":   http.StatusText(http.StatusInternalServerError),
+		"message": "Failed to check " + what,
+	})
+}
+
 // RequirePermission creates middleware that requires specific permission
 func (m *RBACMiddleware) RequirePermission(resource, action string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user ID from context (should be set by auth middleware)
-		userID, exists := c.Get("user_id")
-		if !exists {
+		uid, ok := requestctx.UserID(c.Request.Context())
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Unauthorized",
 				"message": "User not authenticated",
@@ -36,25 +51,10 @@ func (m *RBACMiddleware) RequirePermission(resource, action string) gin.HandlerF
 			return
 		}
 
-		// Parse user ID
-		uid, err := uuid.Parse(userID.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Invalid user ID",
-				"message": err.Error(),
-			})
-			c.Abort()
-			return
-		}
-
 		// Check permission
 		hasPermission, err := m.rbacService.CheckUserPermission(c.Request.Context(), uid, resource, action)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to check permission",
-				"message": err.Error(),
-			})
-			c.Abort()
+			abortCheckFailed(c, "permission", err)
 			return
 		}
 
@@ -74,9 +74,8 @@ func (m *RBACMiddleware) RequirePermission(resource, action string) gin.HandlerF
 // RequireRole creates middleware that requires specific role
 func (m *RBACMiddleware) RequireRole(roleSlug string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user ID from context (should be set by auth middleware)
-		userID, exists := c.Get("user_id")
-		if !exists {
+		uid, ok := requestctx.UserID(c.Request.Context())
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Unauthorized",
 				"message": "User not authenticated",
@@ -85,25 +84,10 @@ func (m *RBACMiddleware) RequireRole(roleSlug string) gin.HandlerFunc {
 			return
 		}
 
-		// Parse user ID
-		uid, err := uuid.Parse(userID.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Invalid user ID",
-				"message": err.Error(),
-			})
-			c.Abort()
-			return
-		}
-
 		// Check role
 		hasRole, err := m.rbacService.CheckUserRole(c.Request.Context(), uid, roleSlug)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to check role",
-				"message": err.Error(),
-			})
-			c.Abort()
+			abortCheckFailed(c, "role", err)
 			return
 		}
 
@@ -123,9 +107,8 @@ func (m *RBACMiddleware) RequireRole(roleSlug string) gin.HandlerFunc {
 // RequireAnyRole creates middleware that requires any of the specified roles
 func (m *RBACMiddleware) RequireAnyRole(roleSlugs ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user ID from context (should be set by auth middleware)
-		userID, exists := c.Get("user_id")
-		if !exists {
+		uid, ok := requestctx.UserID(c.Request.Context())
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Unauthorized",
 				"message": "User not authenticated",
@@ -134,27 +117,12 @@ func (m *RBACMiddleware) RequireAnyRole(roleSlugs ...string) gin.HandlerFunc {
 			return
 		}
 
-		// Parse user ID
-		uid, err := uuid.Parse(userID.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Invalid user ID",
-				"message": err.Error(),
-			})
-			c.Abort()
-			return
-		}
-
 		// Check if user has any of the required roles
 		hasAnyRole := false
 		for _, roleSlug := range roleSlugs {
 			hasRole, err := m.rbacService.CheckUserRole(c.Request.Context(), uid, roleSlug)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "Failed to check role",
-					"message": err.Error(),
-				})
-				c.Abort()
+				abortCheckFailed(c, "role", err)
 				return
 			}
 			if hasRole {
@@ -179,9 +147,8 @@ func (m *RBACMiddleware) RequireAnyRole(roleSlugs ...string) gin.HandlerFunc {
 // RequireAnyPermission creates middleware that requires any of the specified permissions
 func (m *RBACMiddleware) RequireAnyPermission(permissions [][]string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user ID from context (should be set by auth middleware)
-		userID, exists := c.Get("user_id")
-		if !exists {
+		uid, ok := requestctx.UserID(c.Request.Context())
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Unauthorized",
 				"message": "User not authenticated",
@@ -190,17 +157,6 @@ func (m *RBACMiddleware) RequireAnyPermission(permissions [][]string) gin.Handle
 			return
 		}
 
-		// Parse user ID
-		uid, err := uuid.Parse(userID.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Invalid user ID",
-				"message": err.Error(),
-			})
-			c.Abort()
-			return
-		}
-
 		// Check if user has any of the required permissions
 		hasAnyPermission := false
 		for _, permission := range permissions {
@@ -212,11 +168,7 @@ func (m *RBACMiddleware) RequireAnyPermission(permissions [][]string) gin.Handle
 
 			hasPermission, err := m.rbacService.CheckUserPermission(c.Request.Context(), uid, resource, action)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "Failed to check permission",
-					"message": err.Error(),
-				})
-				c.Abort()
+				abortCheckFailed(c, "permission", err)
 				return
 			}
 			if hasPermission {
@@ -252,9 +204,8 @@ func (m *RBACMiddleware) RequireAdmin() gin.HandlerFunc {
 // This can be used for endpoints like /users/:id where user should only access their own data
 func (m *RBACMiddleware) RequireResourceOwnership(resourceIDParam string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user ID from context (should be set by auth middleware)
-		userID, exists := c.Get("user_id")
-		if !exists {
+		uid, ok := requestctx.UserID(c.Request.Context())
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Unauthorized",
 				"message": "User not authenticated",
@@ -275,36 +226,18 @@ func (m *RBACMiddleware) RequireResourceOwnership(resourceIDParam string) gin.Ha
 		}
 
 		// Check if user owns the resource (user ID matches resource ID)
-		if userID.(string) != resourceID {
+		if uid.String() != resourceID {
 			// Check if user has admin privileges as fallback
-			uid, err := uuid.Parse(userID.(string))
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":   "Invalid user ID",
-					"message": err.Error(),
-				})
-				c.Abort()
-				return
-			}
-
 			hasAdminRole, err := m.rbacService.CheckUserRole(c.Request.Context(), uid, "super-admin")
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "Failed to check admin role",
-					"message": err.Error(),
-				})
-				c.Abort()
+				abortCheckFailed(c, "admin role", err)
 				return
 			}
 
 			if !hasAdminRole {
 				hasAdminRole, err = m.rbacService.CheckUserRole(c.Request.Context(), uid, "admin")
 				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error":   "Failed to check admin role",
-						"message": err.Error(),
-					})
-					c.Abort()
+					abortCheckFailed(c, "admin role", err)
 					return
 				}
 			}
@@ -323,96 +256,129 @@ func (m *RBACMiddleware) RequireResourceOwnership(resourceIDParam string) gin.Ha
 	}
 }
 
-// DynamicPermissionCheck creates middleware for dynamic permission checking based on HTTP method and path
-func (m *RBACMiddleware) DynamicPermissionCheck() gin.HandlerFunc {
+// RoutePolicy controls how the route permission registry is applied
+type RoutePolicy struct {
+	// ReportOnly only logs callers missing a route's permission instead of
+	// rejecting them, so role grants can be completed first. It must be
+	// turned on explicitly; the zero policy enforces.
+	ReportOnly bool
+	// DenyUnknown rejects requests to routes missing from the registry
+	DenyUnknown bool
+}
+
+// RoutePermissionCheck creates middleware checking the permission registered
+// for the matched Gin route template
+func (m *RBACMiddleware) RoutePermissionCheck(routes *routeperm.Registry, policy RoutePolicy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user ID from context (should be set by auth middleware)
-		userID, exists := c.Get("user_id")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "User not authenticated",
-			})
-			c.Abort()
-			return
-		}
+		userID, _ := requestctx.UserID(c.Request.Context())
 
-		// Parse user ID
-		uid, err := uuid.Parse(userID.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Invalid user ID",
-				"message": err.Error(),
+		status, message := m.checkRoute(c.Request.Context(), routes, policy, c.Request.Method, c.FullPath(), userID)
+		if status != 0 {
+			c.JSON(status, gin.H{
+				"error":   http.StatusText(status),
+				"message": message,
 			})
 			c.Abort()
 			return
 		}
 
-		// Determine resource and action based on path and method
-		resource, action := m.extractResourceAndAction(c.Request.URL.Path, c.Request.Method)
-		if resource == "" || action == "" {
-			// If we can't determine resource/action, allow access
-			// You might want to change this behavior based on your security requirements
-			c.Next()
-			return
-		}
+		c.Next()
+	}
+}
 
-		// Check permission
-		hasPermission, err := m.rbacService.CheckUserPermission(c.Request.Context(), uid, resource, action)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to check permission",
-				"message": err.Error(),
-			})
-			c.Abort()
-			return
-		}
+// HumaRoutePermission creates Huma middleware checking the permission
+// registered for the matched operation. It must run after HumaAuthMiddleware.
+func (m *RBACMiddleware) HumaRoutePermission(api huma.API, routes *routeperm.Registry, policy RoutePolicy) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		op := ctx.Operation()
+		userID, _ := UserIDFromContext(ctx.Context())
 
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Forbidden",
-				"message": "Insufficient permissions",
-			})
-			c.Abort()
+		status, message := m.checkRoute(ctx.Context(), routes, policy, op.Method, op.Path, userID)
+		if status != 0 {
+			_ = huma.WriteErr(api, ctx, status, message)
 			return
 		}
 
-		c.Next()
+		next(ctx)
 	}
 }
 
-// extractResourceAndAction extracts resource and action from URL path and HTTP method
-func (m *RBACMiddleware) extractResourceAndAction(path, method string) (string, string) {
-	// Remove leading slash and split path
-	path = strings.TrimPrefix(path, "/")
-	segments := strings.Split(path, "/")
+// checkRoute looks up the route template and returns a non-zero status
+// when the request must be rejected
+func (m *RBACMiddleware) checkRoute(ctx context.Context, routes *routeperm.Registry, policy RoutePolicy, method, path string, userID uuid.UUID) (int, string) {
+	route, ok := routes.Lookup(method, path)
+	if !ok {
+		if policy.DenyUnknown {
+			logger.Global().Auth().LogSecurityEvent("unknown_route", "", "", method+" "+path)
+			return http.StatusForbidden, "Route has no permission entry"
+		}
+		return 0, ""
+	}
+	apiKey, viaAPIKey := requestctx.CallerAPIKey(ctx)
+	if route.Access == routeperm.AccessSuperAdmin {
+		return m.checkSuperAdmin(ctx, viaAPIKey, userID, method+" "+path)
+	}
+	if route.Access != routeperm.AccessPermission {
+		// Routes that check the caller themselves would see the key's user
+		// with all of their rights
+		if viaAPIKey && route.Access == routeperm.AccessAuthenticated {
+			return http.StatusForbidden, "API keys may only call routes requiring a permission"
+		}
+		return 0, ""
+	}
+
+	if userID == uuid.Nil {
+		return http.StatusUnauthorized, "User not authenticated"
+	}
+	if viaAPIKey && !apiKey.HasScope(route.Resource, route.Action) {
+		return http.StatusForbidden, "API key scopes do not include " + route.Resource + ":" + route.Action
+	}
 
-	if len(segments) < 3 {
-		return "", ""
+	hasPermission, err := m.rbacService.CheckUserPermission(ctx, userID, route.Resource, route.Action)
+	if err != nil {
+		logCheckFailed(ctx, "permission", err, method+" "+path, userID)
+		return http.StatusInternalServerError, "Failed to check permission"
 	}
 
-	// Expected format: /api/v1/resource or /api/v1/rbac/resource
-	var resource string
-	if segments[2] == "rbac" && len(segments) > 3 {
-		resource = segments[3]
-	} else {
-		resource = segments[2]
+	if !hasPermission {
+		if !policy.ReportOnly {
+			return http.StatusForbidden, "Insufficient permissions"
+		}
+		logger.Global().Auth().Warn("route permission missing (report-only)",
+			"user_id", userID.String(),
+			"route", method+" "+path,
+			"permission", route.Resource+":"+route.Action,
+		)
 	}
+	return 0, ""
+}
 
-	// Map HTTP methods to actions
-	var action string
-	switch method {
-	case "GET":
-		action = "view"
-	case "POST":
-		action = "create"
-	case "PUT", "PATCH":
-		action = "edit"
-	case "DELETE":
-		action = "delete"
-	default:
-		return "", ""
+// checkSuperAdmin returns a non-zero status unless the caller is a super
+// admin. Report-only mode does not apply: these routes never depended on
+// role grants being completed.
+func (m *RBACMiddleware) checkSuperAdmin(ctx context.Context, viaAPIKey bool, userID uuid.UUID, route string) (int, string) {
+	if viaAPIKey {
+		return http.StatusForbidden, "API keys may only call routes requiring a permission"
+	}
+	if userID == uuid.Nil {
+		return http.StatusUnauthorized, "User not authenticated"
 	}
+	if err := authz.RequireSuperAdmin(ctx, m.rbacService, userID); err != nil {
+		if errors.Is(err, authz.ErrNotSuperAdmin) {
+			return http.StatusForbidden, "Insufficient permissions"
+		}
+		logCheckFailed(ctx, "super admin role", err, route, userID)
+		return http.StatusInternalServerError, "Failed to check permission"
+	}
+	return 0, ""
+}
 
-	return resource, action
+// logCheckFailed logs why a route check answered 500; the response itself
+// stays generic
+func logCheckFailed(ctx context.Context, what string, err error, route string, userID uuid.UUID) {
+	logger.Global().Auth().ErrorWithErr("failed to check "+what, err,
+		"route", route,
+		"user_id", userID.String(),
+		"ip", requestctx.ClientIP(ctx),
+	)
 }
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/rbac"
+	"backend-service-internpro/internal/rbac/cache"
+	"backend-service-internpro/internal/rbac/repository"
+	"backend-service-internpro/internal/rbac/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// fakeRBAC grants the permissions in granted and the roles of users in
+// roles, failing role checks with roleErr; other methods are not used
+type fakeRBAC struct {
+	service.Service
+	granted map[string]bool
+	roles   map[uuid.UUID]string
+	roleErr error
+}
+
+func (f *fakeRBAC) CheckUserPermission(_ context.Context, _ uuid.UUID, resource, action string) (bool, error) {
+	return f.granted[resource+":"+action], nil
+}
+
+func (f *fakeRBAC) CheckUserRole(_ context.Context, userID uuid.UUID, roleSlug string) (bool, error) {
+	if f.roleErr != nil {
+		return false, f.roleErr
+	}
+	return f.roles[userID] == roleSlug, nil
+}
+
+func TestCheckRoute(t *testing.T) {
+	routes := routeperm.NewRegistry()
+	routes.Add(http.MethodGet, "/v1/schools", routeperm.Require("schools", "view"))
+	routes.Add(http.MethodDelete, "/v1/schools/:id", routeperm.Require("schools", "delete"))
+	routes.Add(http.MethodGet, "/v1/me", routeperm.Authenticated)
+	routes.Add(http.MethodPost, "/v1/auth/login", routeperm.Public)
+	routes.Add(http.MethodGet, "/v1/audit", routeperm.SuperAdmin)
+
+	user, superAdmin := uuid.New(), uuid.New()
+	m := NewRBACMiddleware(&fakeRBAC{
+		granted: map[string]bool{"schools:view": true},
+		roles:   map[uuid.UUID]string{superAdmin: authz.RoleSuperAdmin},
+	})
+
+	tests := []struct {
+		name   string
+		policy RoutePolicy
+		method string
+		path   string
+		userID uuid.UUID
+		want   int
+	}{
+		{"granted permission", RoutePolicy{}, http.MethodGet, "/v1/schools", user, 0},
+		{"missing permission is denied by default", RoutePolicy{}, http.MethodDelete, "/v1/schools/:id", user, http.StatusForbidden},
+		{"missing permission is only logged in report-only mode", RoutePolicy{ReportOnly: true}, http.MethodDelete, "/v1/schools/:id", user, 0},
+		{"permission route without a user", RoutePolicy{}, http.MethodGet, "/v1/schools", uuid.Nil, http.StatusUnauthorized},
+		{"authenticated route", RoutePolicy{}, http.MethodGet, "/v1/me", user, 0},
+		{"public route", RoutePolicy{}, http.MethodPost, "/v1/auth/login", uuid.Nil, 0},
+		{"unknown route is denied when configured", RoutePolicy{DenyUnknown: true}, http.MethodGet, "/v1/unknown", user, http.StatusForbidden},
+		{"unknown route is allowed otherwise", RoutePolicy{}, http.MethodGet, "/v1/unknown", user, 0},
+		{"super admin route", RoutePolicy{}, http.MethodGet, "/v1/audit", superAdmin, 0},
+		{"super admin route for another user", RoutePolicy{}, http.MethodGet, "/v1/audit", user, http.StatusForbidden},
+		{"super admin route is enforced in report-only mode", RoutePolicy{ReportOnly: true}, http.MethodGet, "/v1/audit", user, http.StatusForbidden},
+		{"super admin route without a user", RoutePolicy{}, http.MethodGet, "/v1/audit", uuid.Nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := m.checkRoute(context.Background(), routes, tt.policy, tt.method, tt.path, tt.userID)
+			if got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// A failed role lookup answers a generic 500
+	failing := NewRBACMiddleware(&fakeRBAC{roleErr: errors.New("connection refused")})
+	status, message := failing.checkRoute(context.Background(), routes, RoutePolicy{}, http.MethodGet, "/v1/audit", superAdmin)
+	if status != http.StatusInternalServerError || strings.Contains(message, "connection refused") {
+		t.Errorf("failed role lookup: status %d, message %q", status, message)
+	}
+}
+
+func TestGinChecksHideFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	failing := NewRBACMiddleware(&fakeRBAC{roleErr: errors.New("connection refused")})
+	userID := uuid.New()
+
+	for name, check := range map[string]gin.HandlerFunc{
+		"role":      failing.RequireRole(authz.RoleSuperAdmin),
+		"any role":  failing.RequireAnyRole(authz.RoleSuperAdmin),
+		"ownership": failing.RequireResourceOwnership("id"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/users/:id", func(c *gin.Context) {
+				c.Request = c.Request.WithContext(requestctx.WithClaims(c.Request.Context(), &jwt.Claims{UserID: userID.String()}))
+			}, check, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil))
+			if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "connection refused") {
+				t.Errorf("status %d, body %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+// fakeEffects holds the permission effects of the user's roles; other
+// methods are not used
+type fakeEffects struct {
+	repository.Repository
+	effects []rbac.PermissionEffect
+}
+
+func (f *fakeEffects) GetUserPermissionEffects(context.Context, uuid.UUID) ([]rbac.PermissionEffect, error) {
+	return f.effects, nil
+}
+
+func allow(permission string) rbac.PermissionEffect {
+	effect, _ := rbac.ParsePermissionEffect(permission)
+	return effect
+}
+
+func deny(permission string) rbac.PermissionEffect {
+	effect, _ := rbac.ParsePermissionEffect("-" + permission)
+	return effect
+}
+
+func TestCheckRouteWithDenies(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles [][]rbac.PermissionEffect // effects of each role of the user
+		check string                    // resource:action the route requires
+		want  int
+	}{
+		{"no permission", nil, "classes:view", http.StatusForbidden},
+		{"allow", [][]rbac.PermissionEffect{{allow("classes:view")}}, "classes:view", 0},
+		{"allow of another action", [][]rbac.PermissionEffect{{allow("classes:view")}}, "classes:edit", http.StatusForbidden},
+		{"manage allows every action", [][]rbac.PermissionEffect{{allow("classes:manage")}}, "classes:delete", 0},
+		{"wildcard action", [][]rbac.PermissionEffect{{allow("classes:*")}}, "classes:delete", 0},
+		{"wildcard resource and action", [][]rbac.PermissionEffect{{allow("*:*")}}, "schools:delete", 0},
+		{"manage of another resource", [][]rbac.PermissionEffect{{allow("schools:manage")}}, "classes:view", http.StatusForbidden},
+		{"deny alone", [][]rbac.PermissionEffect{{deny("classes:view")}}, "classes:view", http.StatusForbidden},
+		{"deny beats manage in the same role", [][]rbac.PermissionEffect{{allow("classes:manage"), deny("classes:delete")}}, "classes:delete", http.StatusForbidden},
+		{"deny leaves other actions of manage", [][]rbac.PermissionEffect{{allow("classes:manage"), deny("classes:delete")}}, "classes:edit", 0},
+		{"deny of one role beats allow of another", [][]rbac.PermissionEffect{{allow("classes:delete")}, {deny("classes:delete")}}, "classes:delete", http.StatusForbidden},
+		{"deny of one role beats manage of another", [][]rbac.PermissionEffect{{allow("classes:manage")}, {deny("classes:delete")}}, "classes:delete", http.StatusForbidden},
+		{"denied manage blocks every action", [][]rbac.PermissionEffect{{allow("classes:view")}, {deny("classes:manage")}}, "classes:view", http.StatusForbidden},
+		{"denied wildcard action blocks every action", [][]rbac.PermissionEffect{{allow("classes:view")}, {deny("classes:*")}}, "classes:view", http.StatusForbidden},
+		{"denied action on every resource", [][]rbac.PermissionEffect{{allow("*:*")}, {deny("*:delete")}}, "schools:delete", http.StatusForbidden},
+		{"denied action on every resource leaves others", [][]rbac.PermissionEffect{{allow("*:*")}, {deny("*:delete")}}, "schools:view", 0},
+		{"deny of another resource", [][]rbac.PermissionEffect{{allow("classes:manage")}, {deny("schools:manage")}}, "classes:edit", 0},
+		{"allows of several roles", [][]rbac.PermissionEffect{{allow("classes:view")}, {allow("classes:view")}}, "classes:view", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeEffects{}
+			for _, role := range tt.roles {
+				repo.effects = append(repo.effects, role...)
+			}
+			svc := service.NewServiceWithConfig(repo, service.Config{Cache: cache.NewMemory(time.Minute)})
+			required := allow(tt.check)
+			routes := routeperm.NewRegistry()
+			routes.Add(http.MethodGet, "/v1/classes", routeperm.Require(required.Resource, required.Action))
+
+			got, _ := NewRBACMiddleware(svc).checkRoute(context.Background(), routes, RoutePolicy{}, http.MethodGet, "/v1/classes", uuid.New())
+			if got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
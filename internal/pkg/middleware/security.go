@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"backend-service-internpro/internal/pkg/requestctx"
+
+	"github.com/danielgtaylor/huma/v2"
 	"github.com/gin-gonic/gin"
 )
 
@@ -87,13 +92,88 @@ func (rl *RateLimiter) cleanup() {
 	}
 }
 
-// RateLimitMiddleware creates a rate limiting middleware
+// DefaultExemptPaths are the health, readiness, metrics and docs routes polled
+// by load balancers, scrapers and browsers. A trailing "*" matches a prefix.
+var DefaultExemptPaths = []string{
+	"/healthz",
+	"/readyz",
+	"/metrics",
+	"/docs",
+	"/openapi.json",
+	"/openapi.yaml",
+	"/openapi-3.0.json",
+	"/openapi-3.0.yaml",
+	"/schemas/*",
+}
+
+// PathSet matches request paths against exact paths and "prefix*" patterns
+type PathSet struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+// NewPathSet creates a PathSet from patterns; blank patterns are ignored
+func NewPathSet(patterns ...string) *PathSet {
+	s := &PathSet{exact: make(map[string]bool)}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		switch {
+		case p == "":
+		case strings.HasSuffix(p, "*"):
+			s.prefixes = append(s.prefixes, strings.TrimSuffix(p, "*"))
+		default:
+			s.exact[p] = true
+		}
+	}
+	return s
+}
+
+// Contains reports whether path matches one of the patterns
+func (s *PathSet) Contains(path string) bool {
+	if s.exact[path] {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitConfig configures RateLimitMiddlewareWithConfig
+type RateLimitConfig struct {
+	Rate     time.Duration
+	Capacity int
+	// ExemptPaths never consume tokens, see PathSet for the pattern syntax
+	ExemptPaths []string
+	// ExemptNetworks never consume tokens, e.g. the load balancer's subnet
+	ExemptNetworks []*net.IPNet
+}
+
+// RateLimitMiddleware creates a rate limiting middleware exempting DefaultExemptPaths
 func RateLimitMiddleware(rate time.Duration, capacity int) gin.HandlerFunc {
-	limiter := NewRateLimiter(rate, capacity)
+	return RateLimitMiddlewareWithConfig(RateLimitConfig{
+		Rate:        rate,
+		Capacity:    capacity,
+		ExemptPaths: DefaultExemptPaths,
+	})
+}
+
+// RateLimitMiddlewareWithConfig creates a per-IP rate limiting middleware.
+// Exempt requests are passed through before the limiter is consulted.
+func RateLimitMiddlewareWithConfig(cfg RateLimitConfig) gin.HandlerFunc {
+	limiter := NewRateLimiter(cfg.Rate, cfg.Capacity)
+	exemptPaths := NewPathSet(cfg.ExemptPaths...)
 
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
 
+		if exemptPaths.Contains(c.Request.URL.Path) || inNetworks(ip, cfg.ExemptNetworks) {
+			c.Next()
+			return
+		}
+
 		if !limiter.Allow(ip) {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":   "Rate limit exceeded",
@@ -107,6 +187,45 @@ func RateLimitMiddleware(rate time.Duration, capacity int) gin.HandlerFunc {
 	}
 }
 
+// inNetworks reports whether ip belongs to one of networks
+func inNetworks(ip string, networks []*net.IPNet) bool {
+	if len(networks) == 0 {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range networks {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// HumaUserRateLimit rate limits an operation per authenticated user,
+// falling back to the client IP for anonymous requests. Add it to the
+// Middlewares of operations that need a stricter limit than the global
+// per-IP one.
+func HumaUserRateLimit(api huma.API, rate time.Duration, capacity int) func(ctx huma.Context, next func(huma.Context)) {
+	limiter := NewRateLimiter(rate, capacity)
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		key := "ip:" + requestctx.ClientIP(ctx.Context())
+		if userID, ok := requestctx.UserID(ctx.Context()); ok {
+			key = "user:" + userID.String()
+		}
+
+		if !limiter.Allow(key) {
+			_ = huma.WriteErr(api, ctx, http.StatusTooManyRequests, "Too many requests, please try again later")
+			return
+		}
+
+		next(ctx)
+	}
+}
+
 // SecurityHeadersMiddleware adds security headers
 func SecurityHeadersMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
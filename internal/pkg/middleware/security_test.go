@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitExemptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, lb, _ := net.ParseCIDR("10.0.0.0/8")
+	engine := gin.New()
+	engine.Use(RateLimitMiddlewareWithConfig(RateLimitConfig{
+		Rate:           time.Hour,
+		Capacity:       2,
+		ExemptPaths:    DefaultExemptPaths,
+		ExemptNetworks: []*net.IPNet{lb},
+	}))
+	for _, path := range []string{"/healthz", "/schemas/School.json", "/v1/schools"} {
+		engine.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+	get := func(path, ip string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":40000"
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Exempt paths and networks never consume tokens, however often they are called
+	for i := 0; i < 10; i++ {
+		if code := get("/healthz", "192.0.2.1"); code != http.StatusOK {
+			t.Fatalf("health check %d: status = %d, want %d", i+1, code, http.StatusOK)
+		}
+		if code := get("/schemas/School.json", "192.0.2.1"); code != http.StatusOK {
+			t.Fatalf("schema %d: status = %d, want %d", i+1, code, http.StatusOK)
+		}
+		if code := get("/v1/schools", "10.1.2.3"); code != http.StatusOK {
+			t.Fatalf("request %d from the load balancer: status = %d, want %d", i+1, code, http.StatusOK)
+		}
+	}
+
+	// The client's tokens are all still there for other paths
+	for i := 0; i < 2; i++ {
+		if code := get("/v1/schools", "192.0.2.1"); code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, code, http.StatusOK)
+		}
+	}
+	if code := get("/v1/schools", "192.0.2.1"); code != http.StatusTooManyRequests {
+		t.Errorf("request over capacity: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := get("/healthz", "192.0.2.1"); code != http.StatusOK {
+		t.Errorf("health check once limited: status = %d, want %d", code, http.StatusOK)
+	}
+}
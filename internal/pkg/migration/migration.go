@@ -3,10 +3,15 @@ package migration
 import (
 	"log"
 	"os"
+	"time"
 
+	"backend-service-internpro/internal/apikey"
 	"backend-service-internpro/internal/auth"
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/privacy"
 	"backend-service-internpro/internal/rbac"
 	"backend-service-internpro/internal/school"
+	"backend-service-internpro/internal/usage"
 	"backend-service-internpro/internal/user"
 
 	"github.com/google/uuid"
@@ -37,7 +42,11 @@ func AutoMigrate(db *gorm.DB) error {
 		return err
 	}
 
-	if err := db.AutoMigrate(&school.PartnerEntity{}); err != nil {
+	if err := db.AutoMigrate(&school.PartnerEntity{}, &school.PartnerMergeEntity{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&school.ClassScheduleEntity{}); err != nil {
 		return err
 	}
 
@@ -62,6 +71,14 @@ func AutoMigrate(db *gorm.DB) error {
 		return err
 	}
 
+	if err := db.AutoMigrate(&auth.AuthEvent{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&auth.UserIdentity{}); err != nil {
+		return err
+	}
+
 	// Migrate RBAC tables
 	if err := db.AutoMigrate(&rbac.RoleEntity{}); err != nil {
 		return err
@@ -79,6 +96,30 @@ func AutoMigrate(db *gorm.DB) error {
 		return err
 	}
 
+	if err := db.AutoMigrate(&rbac.UserRoleHistoryEntity{}, &rbac.RolePermissionHistoryEntity{}, &rbac.RoleMenuHistoryEntity{}); err != nil {
+		return err
+	}
+
+	// Migrate notification tables
+	if err := db.AutoMigrate(&notification.NotificationEntity{}); err != nil {
+		return err
+	}
+
+	// Migrate personal data tables
+	if err := db.AutoMigrate(&privacy.DataExportEntity{}, &privacy.AuditEventEntity{}); err != nil {
+		return err
+	}
+
+	// Migrate API usage tables
+	if err := db.AutoMigrate(&usage.CounterEntity{}); err != nil {
+		return err
+	}
+
+	// Migrate API key tables
+	if err := db.AutoMigrate(&apikey.APIKeyEntity{}); err != nil {
+		return err
+	}
+
 	log.Println("✅ Database migrations completed successfully")
 	return nil
 }
@@ -129,7 +170,10 @@ func CreateDummyData(db *gorm.DB) error {
 			Fullname:     "Test User",
 			PasswordHash: string(hashedPassword),
 			IsAdmin:      true, // Set as admin
+			IsActive:     true,
 		}
+		now := time.Now()
+		dummyUser.EmailVerifiedAt = &now
 
 		if err := db.Create(&dummyUser).Error; err != nil {
 			return err
@@ -174,12 +218,27 @@ func CreateInitialRBACData(db *gorm.DB) error {
 			IsActive:    true,
 		}
 
-		if err := db.Create(&adminRole).Error; err != nil {
-			return err
+		schoolAdminRole := rbac.RoleEntity{
+			ID:          uuid.New(),
+			Name:        "School Admin",
+			Slug:        "school-admin",
+			Description: "Manages users of their own school",
+			IsActive:    true,
 		}
 
-		if err := db.Create(&userRole).Error; err != nil {
-			return err
+		teacherRole := rbac.RoleEntity{
+			ID:                      uuid.New(),
+			Name:                    "Teacher",
+			Slug:                    "teacher",
+			Description:             "Teacher access within a school",
+			IsActive:                true,
+			AssignableBySchoolAdmin: true,
+		}
+
+		for _, role := range []*rbac.RoleEntity{&adminRole, &userRole, &schoolAdminRole, &teacherRole} {
+			if err := db.Create(role).Error; err != nil {
+				return err
+			}
 		}
 
 		// Create default permissions
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+
+	"backend-service-internpro/internal/pkg/mailer"
+)
+
+// EmailSender delivers notifications through a mailer
+type EmailSender struct {
+	mailer mailer.Mailer
+}
+
+// NewEmailSender wraps a mailer as a notification channel
+func NewEmailSender(m mailer.Mailer) *EmailSender {
+	return &EmailSender{mailer: m}
+}
+
+func (s *EmailSender) Send(ctx context.Context, to Recipient, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body := msg.HTML
+	if body == "" {
+		body = msg.Body
+	}
+	return s.mailer.Send(to.Email, msg.Subject, body)
+}
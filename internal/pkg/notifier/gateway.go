@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"backend-service-internpro/internal/pkg/httpclient"
+
+	"github.com/google/uuid"
+)
+
+// GatewayConfig configures an HTTP messaging gateway (WhatsApp/SMS)
+type GatewayConfig struct {
+	URL    string
+	APIKey string
+}
+
+// GatewayPayload is the JSON body posted to the gateway
+type GatewayPayload struct {
+	Channel Channel `json:"channel"`
+	To      string  `json:"to"`
+	Message string  `json:"message"`
+}
+
+// GatewaySender delivers notifications to a phone number through an HTTP gateway
+type GatewaySender struct {
+	channel Channel
+	cfg     GatewayConfig
+	client  *httpclient.Client
+}
+
+// NewGatewaySender creates a sender for the given phone channel. Calls go
+// through client; nil uses a client sending each request once, leaving
+// retries to the job that delivers the message.
+func NewGatewaySender(ch Channel, cfg GatewayConfig, client *httpclient.Client) *GatewaySender {
+	if client == nil {
+		client = httpclient.New(httpclient.Config{MaxAttempts: 1})
+	}
+	return &GatewaySender{
+		channel: ch,
+		cfg:     cfg,
+		client:  client,
+	}
+}
+
+func (s *GatewaySender) Send(ctx context.Context, to Recipient, msg Message) error {
+	body, err := json.Marshal(GatewayPayload{
+		Channel: s.channel,
+		To:      to.Phone,
+		Message: msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	// The gateway drops a message it already accepted under the same key
+	key := msg.ID
+	if key == "" {
+		key = uuid.NewString()
+	}
+	req.Header.Set("Idempotency-Key", key)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("gateway responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
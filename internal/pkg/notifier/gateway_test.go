@@ -0,0 +1,157 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeGateway answers with statuses in turn, repeating the last, and
+// records the requests it got
+type fakeGateway struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	payloads []GatewayPayload
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var p GatewayPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	g.requests = append(g.requests, r)
+	g.payloads = append(g.payloads, p)
+	status := g.statuses[min(len(g.requests), len(g.statuses))-1]
+	w.WriteHeader(status)
+}
+
+// fakeEmail records the recipients it was asked to mail
+type fakeEmail struct{ sent []string }
+
+func (s *fakeEmail) Send(_ context.Context, to Recipient, _ Message) error {
+	s.sent = append(s.sent, to.Email)
+	return nil
+}
+
+func TestGatewaySenderPayload(t *testing.T) {
+	gw := &fakeGateway{statuses: []int{http.StatusAccepted}}
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+
+	sender := NewGatewaySender(ChannelWhatsApp, GatewayConfig{URL: srv.URL, APIKey: "secret"}, nil)
+	err := sender.Send(context.Background(), Recipient{Email: "budi@example.com", Phone: "+6281234567890"},
+		Message{Subject: "OTP", Body: "Your code is 123456", HTML: "<p>Your code is 123456</p>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(gw.requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(gw.requests))
+	}
+	req := gw.requests[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.Method)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want \"Bearer secret\"", got)
+	}
+	// Phone channels get the plain body, never the HTML
+	want := GatewayPayload{Channel: ChannelWhatsApp, To: "+6281234567890", Message: "Your code is 123456"}
+	if gw.payloads[0] != want {
+		t.Errorf("payload %+v, want %+v", gw.payloads[0], want)
+	}
+}
+
+func TestGatewayIdempotencyKey(t *testing.T) {
+	gw := &fakeGateway{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+
+	sender := NewGatewaySender(ChannelSMS, GatewayConfig{URL: srv.URL}, nil)
+	to := Recipient{Phone: "+6281234567890"}
+	// A retried job sends the same message again
+	msg := Message{ID: "otp-1", Body: "hello"}
+	for range 2 {
+		if err := sender.Send(context.Background(), to, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 2 {
+		if err := sender.Send(context.Background(), to, Message{Body: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := make([]string, len(gw.requests))
+	for i, req := range gw.requests {
+		keys[i] = req.Header.Get("Idempotency-Key")
+	}
+	if keys[0] != "otp-1" || keys[1] != "otp-1" {
+		t.Errorf("resends keyed %q and %q, want the message ID", keys[0], keys[1])
+	}
+	if keys[2] == "" || keys[3] == "" || keys[2] == keys[3] {
+		t.Errorf("messages without an ID keyed %q and %q, want distinct keys", keys[2], keys[3])
+	}
+}
+
+func TestGatewayFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		// wantCalls is how often the gateway is called
+		wantCalls int
+		wantEmail bool
+	}{
+		{"delivered", []int{http.StatusOK}, 1, false},
+		{"rejected", []int{http.StatusBadRequest}, 1, true},
+		// Retries are left to the job, so a failed call falls back at once
+		{"down", []int{http.StatusBadGateway}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := &fakeGateway{statuses: tt.statuses}
+			srv := httptest.NewServer(gw)
+			defer srv.Close()
+
+			email := &fakeEmail{}
+			d := NewDispatcher().
+				Register(ChannelSMS, NewGatewaySender(ChannelSMS, GatewayConfig{URL: srv.URL}, nil)).
+				Register(ChannelEmail, email)
+
+			err := d.Dispatch(context.Background(), ChannelSMS,
+				Recipient{Email: "budi@example.com", Phone: "+6281234567890"}, Message{Body: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(gw.requests) != tt.wantCalls {
+				t.Errorf("gateway called %d times, want %d", len(gw.requests), tt.wantCalls)
+			}
+			if sent := len(email.sent) == 1; sent != tt.wantEmail {
+				t.Errorf("email sent to %v, want fallback %v", email.sent, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestGatewayFallbackWithoutEmail(t *testing.T) {
+	gw := &fakeGateway{statuses: []int{http.StatusUnauthorized}}
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+
+	d := NewDispatcher().Register(ChannelSMS, NewGatewaySender(ChannelSMS, GatewayConfig{URL: srv.URL}, nil))
+	err := d.Dispatch(context.Background(), ChannelSMS, Recipient{Phone: "+6281234567890"}, Message{Body: "hello"})
+	if err == nil || errors.Is(err, ErrNoChannel) {
+		t.Errorf("err = %v, want the gateway failure", err)
+	}
+}
@@ -0,0 +1,109 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Channel identifies how a notification is delivered to a user
+type Channel string
+
+const (
+	ChannelEmail    Channel = "email"
+	ChannelWhatsApp Channel = "whatsapp"
+	ChannelSMS      Channel = "sms"
+)
+
+// IsValid reports whether c is a supported channel
+func (c Channel) IsValid() bool {
+	switch c {
+	case ChannelEmail, ChannelWhatsApp, ChannelSMS:
+		return true
+	}
+	return false
+}
+
+// Recipient holds the addresses a notification can be delivered to
+type Recipient struct {
+	Email string
+	Phone string
+}
+
+// Message is a channel-agnostic notification
+type Message struct {
+	// ID identifies the message across resends, so a channel that dedupes
+	// them such as the gateway delivers it once. Set it before handing the
+	// message to a job that may be retried; empty gets a new ID per send.
+	ID      string
+	Subject string
+	Body    string
+	// HTML replaces Body for email when set; other channels use Body
+	HTML string
+}
+
+// Sender delivers a message over a single channel
+type Sender interface {
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+// ErrNoChannel is returned when neither the preferred channel nor the fallback can be used
+var ErrNoChannel = errors.New("no notification channel available")
+
+// Dispatcher routes messages to the preferred channel and falls back to email
+type Dispatcher struct {
+	senders map[Channel]Sender
+}
+
+// NewDispatcher creates an empty dispatcher; register channels with Register
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{senders: make(map[Channel]Sender)}
+}
+
+// Register adds or replaces the sender used for a channel
+func (d *Dispatcher) Register(ch Channel, s Sender) *Dispatcher {
+	if s != nil {
+		d.senders[ch] = s
+	}
+	return d
+}
+
+// Dispatch sends msg through the preferred channel. When the preferred
+// channel is not configured, the recipient has no address for it, or the
+// delivery fails, the message is sent by email instead.
+func (d *Dispatcher) Dispatch(ctx context.Context, preferred Channel, to Recipient, msg Message) error {
+	var firstErr error
+
+	if preferred != ChannelEmail && d.canDeliver(preferred, to) {
+		err := d.senders[preferred].Send(ctx, to, msg)
+		if err == nil {
+			return nil
+		}
+		firstErr = fmt.Errorf("%s delivery failed: %w", preferred, err)
+	}
+
+	if !d.canDeliver(ChannelEmail, to) {
+		if firstErr != nil {
+			return firstErr
+		}
+		return ErrNoChannel
+	}
+
+	if err := d.senders[ChannelEmail].Send(ctx, to, msg); err != nil {
+		if firstErr != nil {
+			return errors.Join(firstErr, fmt.Errorf("email fallback failed: %w", err))
+		}
+		return fmt.Errorf("email delivery failed: %w", err)
+	}
+	return nil
+}
+
+func (d *Dispatcher) canDeliver(ch Channel, to Recipient) bool {
+	if _, ok := d.senders[ch]; !ok {
+		return false
+	}
+	if ch == ChannelEmail {
+		return to.Email != ""
+	}
+	return to.Phone != ""
+}
@@ -0,0 +1,250 @@
+// Package oauth signs users in with external identity providers through
+// the OAuth 2.0 authorization code flow and OpenID Connect ID tokens.
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ProviderGoogle names Google identities, see Identity
+const ProviderGoogle = "google"
+
+// Google endpoints, see https://accounts.google.com/.well-known/openid-configuration
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+// googleIssuers are the iss values Google signs ID tokens with
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+var (
+	// ErrCodeRejected is returned when the provider does not accept the
+	// authorization code, e.g. because it expired or was already used
+	ErrCodeRejected = errors.New("authorization code rejected")
+	// ErrInvalidIDToken is returned when the ID token fails verification
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// Identity is the account a provider vouched for
+type Identity struct {
+	Provider string
+	// Subject identifies the account at the provider; unlike the email it
+	// never changes
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// GoogleConfig holds the OAuth client registered in the Google Cloud console
+type GoogleConfig struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where Google sends the user back with the code; it
+	// must be registered with the client
+	RedirectURL string
+	Timeout     time.Duration
+}
+
+// Google signs users in with their Google accounts
+type Google struct {
+	cfg    GoogleConfig
+	client *http.Client
+	certs  keyCache
+}
+
+// NewGoogle creates the Google provider
+func NewGoogle(cfg GoogleConfig) *Google {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	return &Google{
+		cfg:    cfg,
+		client: client,
+		certs:  keyCache{url: googleCertsURL, client: client},
+	}
+}
+
+// AuthURL is where to send the user to sign in. Google returns state
+// unchanged with the code and puts nonce in the ID token.
+func (g *Google) AuthURL(state, nonce string) string {
+	q := url.Values{
+		"client_id":     {g.cfg.ClientID},
+		"redirect_uri":  {g.cfg.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {"openid email profile"},
+		"state":         {state},
+		"nonce":         {nonce},
+		"prompt":        {"select_account"},
+	}
+	return googleAuthURL + "?" + q.Encode()
+}
+
+// googleClaims are the ID token claims used, see
+// https://developers.google.com/identity/openid-connect/openid-connect#an-id-tokens-payload
+type googleClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// Exchange redeems the code for the ID token of the user who signed in and
+// verifies it was issued to this client with nonce
+func (g *Google) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	form := url.Values{
+		"code":          {code},
+		"client_id":     {g.cfg.ClientID},
+		"client_secret": {g.cfg.ClientSecret},
+		"redirect_uri":  {g.cfg.RedirectURL},
+		"grant_type":    {"authorization_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Google answers 400 invalid_grant for expired, used or foreign codes
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrCodeRejected
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint responded with status %d", resp.StatusCode)
+	}
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	claims := &googleClaims{}
+	_, err = jwt.ParseWithClaims(body.IDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return g.certs.get(ctx, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithAudience(g.cfg.ClientID), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if !validIssuer(claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Nonce != nonce || claims.Subject == "" {
+		return nil, fmt.Errorf("%w: nonce or subject mismatch", ErrInvalidIDToken)
+	}
+
+	return &Identity{
+		Provider:      ProviderGoogle,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+func validIssuer(iss string) bool {
+	for _, i := range googleIssuers {
+		if iss == i {
+			return true
+		}
+	}
+	return false
+}
+
+// keyCache holds the provider's signing keys, fetching them again once they
+// are an hour old or a token names a key it does not know
+type keyCache struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+const keyCacheTTL = time.Hour
+
+func (c *keyCache) get(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[kid]; ok && time.Since(c.fetched) < keyCacheTTL {
+		return key, nil
+	}
+	keys, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.keys, c.fetched = keys, time.Now()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (c *keyCache) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("key endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode key set: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
@@ -1,7 +1,10 @@
 package otp
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 )
@@ -15,6 +18,15 @@ func Generate6() (string, error) {
 	return fmt.Sprintf("%06d", n%1000000), nil
 }
 
+// Hash returns the hex HMAC-SHA256 of code keyed with pepper. Only the hash
+// is stored so a database read does not reveal usable codes; the pepper
+// keeps the small code space from being brute forced offline.
+func Hash(pepper []byte, code string) string {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // helper for random
 func binaryRead(r io.Reader, out interface{}) error {
 	b := make([]byte, 4)
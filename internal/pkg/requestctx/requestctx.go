@@ -0,0 +1,148 @@
+package requestctx
+
+import (
+	"context"
+
+	"backend-service-internpro/internal/pkg/jwt"
+
+	"github.com/google/uuid"
+)
+
+type claimsKey struct{}
+
+type userIDKey struct{}
+
+type slotKey struct{}
+
+type clientIPKey struct{}
+
+type apiKeyKey struct{}
+
+// slot is shared by every context derived from the one it was added to, so
+// middlewares that run before authentication can see who was authenticated
+type slot struct {
+	userID       uuid.UUID
+	set          bool
+	impersonator uuid.UUID // uuid.Nil unless the token impersonates userID
+}
+
+// WithUserSlot returns a context in which WithClaims also records the user
+// for code holding ctx, like the request logger, which only sees the context
+// from before authentication
+func WithUserSlot(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(slotKey{}).(*slot); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, slotKey{}, &slot{})
+}
+
+// SlotUserID returns the user recorded by WithClaims in any context derived
+// from ctx after WithUserSlot. It is false until a token was validated.
+func SlotUserID(ctx context.Context) (uuid.UUID, bool) {
+	s, ok := ctx.Value(slotKey{}).(*slot)
+	if !ok || !s.set {
+		return uuid.Nil, false
+	}
+	return s.userID, true
+}
+
+// SlotImpersonatorID returns the super admin recorded by WithClaims as
+// impersonating the SlotUserID. It is false for users' own tokens.
+func SlotImpersonatorID(ctx context.Context) (uuid.UUID, bool) {
+	s, ok := ctx.Value(slotKey{}).(*slot)
+	if !ok || !s.set || s.impersonator == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return s.impersonator, true
+}
+
+// WithClaims returns a context carrying validated token claims and the user
+// they belong to. Only the auth middleware should call it.
+func WithClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	ctx = context.WithValue(ctx, claimsKey{}, claims)
+	if userID, err := uuid.Parse(claims.UserID); err == nil {
+		ctx = context.WithValue(ctx, userIDKey{}, userID)
+		if s, ok := ctx.Value(slotKey{}).(*slot); ok {
+			s.userID, s.set = userID, true
+			s.impersonator, _ = impersonatorID(claims)
+		}
+	}
+	return ctx
+}
+
+// Claims returns the token claims of the authenticated request
+func Claims(ctx context.Context) (*jwt.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*jwt.Claims)
+	return claims, ok
+}
+
+// UserID returns the acting user of the authenticated request. It is false
+// on routes that do not require a token.
+func UserID(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(uuid.UUID)
+	return userID, ok
+}
+
+// ImpersonatorID returns the super admin acting as UserID when the request
+// was made with an impersonation token
+func ImpersonatorID(ctx context.Context) (uuid.UUID, bool) {
+	claims, ok := Claims(ctx)
+	if !ok {
+		return uuid.Nil, false
+	}
+	return impersonatorID(claims)
+}
+
+func impersonatorID(claims *jwt.Claims) (uuid.UUID, bool) {
+	if claims.ImpersonatorID == "" {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(claims.ImpersonatorID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// APIKey is the key a machine caller authenticated with instead of a token
+type APIKey struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// Scopes are the "resource:action" permissions the key is limited to
+	Scopes []string
+}
+
+// HasScope reports whether the key may use the resource:action permission
+func (k APIKey) HasScope(resource, action string) bool {
+	for _, scope := range k.Scopes {
+		if scope == resource+":"+action {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAPIKey returns a context marking the request as made with key. Only
+// the auth middleware should call it, after WithClaims.
+func WithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, key)
+}
+
+// CallerAPIKey returns the API key the request was made with; it is false
+// for requests made with a token
+func CallerAPIKey(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyKey{}).(*APIKey)
+	return key, ok
+}
+
+// WithClientIP returns a context carrying the client IP as resolved by the
+// router, which honors the trusted proxies unlike a raw X-Forwarded-For
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the client IP stored by WithClientIP, or ""
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,37 @@
+package response
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag returns the entity tag of a resource at version. Every write bumps
+// the version, so each one gets a new tag.
+func ETag(version int64) string {
+	return `W/"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// MatchVersions parses the If-Match header of a write into the versions the
+// client accepts. It returns nil for an empty header, meaning the client did
+// not ask for a check, and for "*", which matches any version. Tags are
+// compared weakly, ignoring the W/ prefix; tags this server did not issue
+// match nothing.
+func MatchVersions(header string) []int64 {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			continue
+		}
+		if version, err := strconv.ParseInt(unquoted, 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
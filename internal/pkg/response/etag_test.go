@@ -0,0 +1,29 @@
+package response
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchVersions(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int64
+	}{
+		{"", nil},
+		{"*", nil},
+		{ETag(7), []int64{7}},
+		{`"7"`, []int64{7}},
+		{`W/"3", W/"7"`, []int64{3, 7}},
+		// A tag from the old timestamp scheme, or from anywhere else,
+		// matches no version
+		{`W/"sxq1c0"`, []int64{}},
+		{`W/7`, []int64{}},
+	}
+	for _, tt := range tests {
+		got := MatchVersions(tt.header)
+		if (got == nil) != (tt.want == nil) || !slices.Equal(got, tt.want) {
+			t.Errorf("MatchVersions(%q) = %#v, want %#v", tt.header, got, tt.want)
+		}
+	}
+}
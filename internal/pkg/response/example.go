@@ -0,0 +1,34 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// ExampleTime is the timestamp used in documented examples so the spec
+// stays the same between generations
+var ExampleTime = time.Date(2025, time.July, 14, 8, 30, 0, 0, time.UTC)
+
+// ExampleID returns a fixed UUID for documented examples; n tells apart
+// the IDs of one example
+func ExampleID(n byte) uuid.UUID {
+	return uuid.UUID{0x3f, 0x1c, 0x9a, 0x52, 0x8d, 0x4e, 0x4b, 0x7a, 0x9c, 0x2e, 0x1a, 0x5b, 0x6d, 0x7e, 0x8f, n}
+}
+
+// Example documents the body an operation returns with status 200 as the
+// ApiResponse envelope around data. Data is untyped in the envelope, so
+// without an example the spec shows an empty object. Use it as the
+// Responses of a huma.Operation; Huma still fills in the schema.
+func Example(message string, data interface{}) map[string]*huma.Response {
+	return map[string]*huma.Response{
+		strconv.Itoa(http.StatusOK): {
+			Content: map[string]*huma.MediaType{
+				"application/json": {Example: Success(message, data)},
+			},
+		},
+	}
+}
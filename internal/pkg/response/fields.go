@@ -0,0 +1,155 @@
+package response
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"backend-service-internpro/internal/pkg/constants"
+)
+
+// UnknownFieldsError is returned by ParseFields when the fields parameter
+// names fields the item does not have
+type UnknownFieldsError struct {
+	Unknown []string
+	Valid   []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf(constants.UnknownFields, strings.Join(e.Unknown, ", "), strings.Join(e.Valid, ", "))
+}
+
+// ParseFields parses a comma separated fields query parameter against the
+// JSON field names of T, the item type of a list. An empty parameter returns
+// nil, meaning every field.
+func ParseFields[T any](raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	valid := jsonFields(reflect.TypeOf((*T)(nil)).Elem())
+	known := make(map[string]bool, len(valid))
+	for _, f := range valid {
+		known[f.name] = true
+	}
+
+	var fields, unknown []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if !known[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		fields = append(fields, name)
+	}
+
+	if len(unknown) > 0 {
+		names := make([]string, len(valid))
+		for i, f := range valid {
+			names[i] = f.name
+		}
+		return nil, &UnknownFieldsError{Unknown: unknown, Valid: names}
+	}
+	return fields, nil
+}
+
+// SelectFields prunes the items of every list in a list payload such as
+// SchoolListData down to fields. Other members, like pagination metadata,
+// are kept as they are. With no fields, data is returned unchanged.
+func SelectFields(data interface{}, fields []string) interface{} {
+	if len(fields) == 0 || data == nil {
+		return data
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return data
+	}
+
+	out := make(map[string]interface{}, v.NumField())
+	for _, f := range jsonFields(v.Type()) {
+		member := v.FieldByIndex(f.index)
+		if member.Kind() == reflect.Slice && structType(member.Type().Elem()) {
+			out[f.name] = selectItems(member, fields)
+			continue
+		}
+		out[f.name] = member.Interface()
+	}
+	return out
+}
+
+func selectItems(list reflect.Value, fields []string) []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, list.Len())
+	if list.Len() == 0 {
+		return items
+	}
+
+	index := make(map[string][]int)
+	for _, f := range jsonFields(list.Type().Elem()) {
+		index[f.name] = f.index
+	}
+
+	for i := 0; i < list.Len(); i++ {
+		item := reflect.Indirect(list.Index(i))
+		if !item.IsValid() {
+			continue
+		}
+		selected := make(map[string]interface{}, len(fields))
+		for _, name := range fields {
+			if idx, ok := index[name]; ok {
+				selected[name] = item.FieldByIndex(idx).Interface()
+			}
+		}
+		items = append(items, selected)
+	}
+	return items
+}
+
+type jsonField struct {
+	name  string
+	index []int
+}
+
+// jsonFields lists the exported fields of a struct type under their JSON
+// names, in declaration order. Embedded structs are not flattened; none of
+// the list DTOs use them.
+func jsonFields(t reflect.Type) []jsonField {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make([]jsonField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := sf.Name
+		if tag := sf.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n, _, _ := strings.Cut(tag, ","); n != "" {
+				name = n
+			}
+		}
+		fields = append(fields, jsonField{name: name, index: sf.Index})
+	}
+	return fields
+}
+
+func structType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
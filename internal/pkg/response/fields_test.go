@@ -0,0 +1,102 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+type testSchool struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Domain *string `json:"domain,omitempty"`
+	Secret string  `json:"-"`
+}
+
+type testPagination struct {
+	Page  int `json:"page"`
+	Total int `json:"total"`
+}
+
+type testSchoolList struct {
+	Schools    []testSchool   `json:"schools"`
+	Pagination testPagination `json:"pagination"`
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		raw         string
+		want        []string
+		wantUnknown []string
+	}{
+		{"", nil, nil},
+		{"  ", nil, nil},
+		{"id,name", []string{"id", "name"}, nil},
+		{" name , id ,name,", []string{"name", "id"}, nil},
+		{"id,address,Secret", nil, []string{"address", "Secret"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFields[testSchool](tt.raw)
+		var unknown *UnknownFieldsError
+		if tt.wantUnknown != nil {
+			if !errors.As(err, &unknown) {
+				t.Fatalf("ParseFields(%q): err = %v, want unknown fields", tt.raw, err)
+			}
+			if !slices.Equal(unknown.Unknown, tt.wantUnknown) || !slices.Equal(unknown.Valid, []string{"id", "name", "domain"}) {
+				t.Errorf("ParseFields(%q) = unknown %v of valid %v, want unknown %v of id, name and domain", tt.raw, unknown.Unknown, unknown.Valid, tt.wantUnknown)
+			}
+			continue
+		}
+		if err != nil || (got == nil) != (tt.want == nil) || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseFields(%q) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestSelectFieldsInEnvelope(t *testing.T) {
+	domain := "smkn1sby.sch.id"
+	data := testSchoolList{
+		Schools: []testSchool{
+			{ID: "1", Name: "SMK Negeri 1 Surabaya", Domain: &domain, Secret: "s"},
+			{ID: "2", Name: "SMK Negeri 2 Malang"},
+		},
+		Pagination: testPagination{Page: 1, Total: 2},
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{"every field", nil,
+			`{"status":true,"message":"ok","data":{"schools":[{"id":"1","name":"SMK Negeri 1 Surabaya","domain":"smkn1sby.sch.id"},{"id":"2","name":"SMK Negeri 2 Malang"}],"pagination":{"page":1,"total":2}}}`},
+		{"a subset", []string{"name"},
+			`{"status":true,"message":"ok","data":{"pagination":{"page":1,"total":2},"schools":[{"name":"SMK Negeri 1 Surabaya"},{"name":"SMK Negeri 2 Malang"}]}}`},
+		// A selected field is sent even when empty, so clients see every
+		// field they asked for
+		{"an optional field", []string{"id", "domain"},
+			`{"status":true,"message":"ok","data":{"pagination":{"page":1,"total":2},"schools":[{"domain":"smkn1sby.sch.id","id":"1"},{"domain":null,"id":"2"}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(Success("ok", SelectFields(data, tt.fields)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("body = %s\nwant   %s", body, tt.want)
+			}
+		})
+	}
+
+	// An empty page keeps its list and pagination
+	empty := testSchoolList{Pagination: testPagination{Page: 3, Total: 2}}
+	body, err := json.Marshal(SelectFields(empty, []string{"id"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"pagination":{"page":3,"total":2},"schools":[]}`; string(body) != want {
+		t.Errorf("empty page = %s, want %s", body, want)
+	}
+}
@@ -0,0 +1,88 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend-service-internpro/internal/pkg/jwt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix namespaces the revocation keys in a shared Redis database
+const redisPrefix = "revoked:"
+
+// Redis is a Store shared by all servers through Redis, which also keeps
+// revocations across restarts. Each entry expires with the tokens it
+// matches.
+type Redis struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+var _ Store = (*Redis)(nil)
+
+// NewRedis creates a store keeping revocations in Redis
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client, now: time.Now}
+}
+
+func (r *Redis) RevokeToken(ctx context.Context, jti string, until time.Time) error {
+	return r.add(ctx, "jti:"+jti, until, until)
+}
+
+func (r *Redis) RevokeUser(ctx context.Context, userID string, before, until time.Time) error {
+	return r.add(ctx, "user:"+userID, issuedBefore(before), until)
+}
+
+func (r *Redis) RevokeSession(ctx context.Context, sessionID string, before, until time.Time) error {
+	return r.add(ctx, "sid:"+sessionID, issuedBefore(before), until)
+}
+
+func (r *Redis) IsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	var keys []string
+	for _, key := range []string{"jti:" + claims.ID, "user:" + claims.UserID, "sid:" + claims.SessionID} {
+		if key[len(key)-1] != ':' {
+			keys = append(keys, redisPrefix+key)
+		}
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		before, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("revocation: malformed entry %q", s)
+		}
+		if issuedAt.Before(time.Unix(0, before)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// add stores the cutoff under key until until. A later revocation of the
+// same key replaces the entry; its cutoff is never earlier, being now or,
+// for a jti, the same expiry.
+func (r *Redis) add(ctx context.Context, key string, before, until time.Time) error {
+	ttl := until.Sub(r.now())
+	if ttl <= 0 {
+		return nil
+	}
+	return r.client.Set(ctx, redisPrefix+key, strconv.FormatInt(before.UnixNano(), 10), ttl).Err()
+}
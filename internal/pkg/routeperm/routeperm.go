@@ -0,0 +1,289 @@
+package routeperm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// Access levels of a route
+const (
+	// AccessPublic routes need no token
+	AccessPublic = "public"
+	// AccessAuthenticated routes accept any signed-in user; the service
+	// applies its own checks
+	AccessAuthenticated = "authenticated"
+	// AccessPermission routes require Resource and Action
+	AccessPermission = "permission"
+	// AccessSuperAdmin routes require the super admin role
+	AccessSuperAdmin = "super-admin"
+)
+
+// Keys of the values stored in huma.Operation.Metadata
+const (
+	// metadataKey stores the Permission
+	metadataKey = "routeperm"
+	// sourceKey stores the module that registered the operation
+	sourceKey = "routeperm.source"
+)
+
+// Permission is what a route requires from the caller
+type Permission struct {
+	Access   string
+	Resource string
+	Action   string
+}
+
+var (
+	// Public marks routes that are reachable without a token
+	Public = Permission{Access: AccessPublic}
+	// Authenticated marks routes any signed-in user may call
+	Authenticated = Permission{Access: AccessAuthenticated}
+	// SuperAdmin marks routes only super admins may call
+	SuperAdmin = Permission{Access: AccessSuperAdmin}
+)
+
+// Require marks routes that need the resource/action permission
+func Require(resource, action string) Permission {
+	return Permission{Access: AccessPermission, Resource: resource, Action: action}
+}
+
+// Route is an entry of the route map
+type Route struct {
+	Method      string `json:"method" doc:"HTTP method"`
+	Path        string `json:"path" doc:"Route template"`
+	OperationID string `json:"operation_id,omitempty" doc:"OpenAPI operation ID"`
+	Access      string `json:"access" enum:"public,authenticated,permission,super-admin" doc:"Who may call the route"`
+	Resource    string `json:"resource,omitempty" doc:"Required permission resource"`
+	Action      string `json:"action,omitempty" doc:"Required permission action"`
+	Source      string `json:"source,omitempty" doc:"Module that registered the route"`
+}
+
+// Permission returns what the route requires
+func (r Route) Permission() Permission {
+	return Permission{Access: r.Access, Resource: r.Resource, Action: r.Action}
+}
+
+// Collision is a route registered twice for the same method and path.
+// Paths collide when they only differ in parameter names, e.g.
+// /v1/users/{id}/roles and /v1/users/:user_id/roles.
+type Collision struct {
+	First  Route
+	Second Route
+}
+
+func (c Collision) Error() string {
+	return fmt.Sprintf("route %s %s registered by %s collides with %s %s registered by %s",
+		c.Second.Method, c.Second.Path, sourceName(c.Second.Source),
+		c.First.Method, c.First.Path, sourceName(c.First.Source))
+}
+
+func sourceName(source string) string {
+	if source == "" {
+		return "an unknown module"
+	}
+	return source
+}
+
+// Registry maps route templates to the permission they require. It is
+// filled while routes are registered so requests only do a map lookup.
+type Registry struct {
+	mu          sync.RWMutex
+	routes      map[string]Route
+	templates   map[string]Route
+	collisions  []Collision
+	onCollision func(Collision)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		routes:    make(map[string]Route),
+		templates: make(map[string]Route),
+	}
+}
+
+// OnCollision calls fn whenever a route collides with one registered
+// before. fn runs before the route reaches the router, so it can stop
+// startup with a clearer message than the router's own panic.
+func (r *Registry) OnCollision(fn func(Collision)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onCollision = fn
+}
+
+// Collisions lists the collisions seen so far in registration order
+func (r *Registry) Collisions() []Collision {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Collision(nil), r.collisions...)
+}
+
+// Attach records every operation later added to api that was registered
+// through Register. Call it before registering routes.
+func (r *Registry) Attach(api huma.API) {
+	oapi := api.OpenAPI()
+	oapi.OnAddOperation = append(oapi.OnAddOperation, func(_ *huma.OpenAPI, op *huma.Operation) {
+		perm, ok := op.Metadata[metadataKey].(Permission)
+		if !ok {
+			return
+		}
+		source, _ := op.Metadata[sourceKey].(string)
+		r.add(Route{
+			Method:      op.Method,
+			Path:        op.Path,
+			OperationID: op.OperationID,
+			Access:      perm.Access,
+			Resource:    perm.Resource,
+			Action:      perm.Action,
+			Source:      source,
+		})
+	})
+}
+
+// Add records a route registered outside of Huma, e.g. on a Gin group.
+// path is the Gin route template as returned by gin.Context.FullPath.
+func (r *Registry) Add(method, path string, perm Permission) {
+	r.add(Route{
+		Method:   method,
+		Path:     path,
+		Access:   perm.Access,
+		Resource: perm.Resource,
+		Action:   perm.Action,
+		Source:   callerModule(2),
+	})
+}
+
+func (r *Registry) add(route Route) {
+	r.mu.Lock()
+	key := routeKey(route.Method, Template(route.Path))
+	first, collides := r.templates[key]
+	if collides {
+		r.collisions = append(r.collisions, Collision{First: first, Second: route})
+	} else {
+		r.templates[key] = route
+	}
+	r.routes[routeKey(route.Method, route.Path)] = route
+	onCollision := r.onCollision
+	r.mu.Unlock()
+
+	if collides && onCollision != nil {
+		onCollision(Collision{First: first, Second: route})
+	}
+}
+
+// Known reports whether a route matching method and path, ignoring
+// parameter names, was registered
+func (r *Registry) Known(method, path string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.templates[routeKey(method, Template(path))]
+	return ok
+}
+
+// Lookup returns the entry of the route template registered for method
+func (r *Registry) Lookup(method, path string) (Route, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	route, ok := r.routes[routeKey(method, path)]
+	return route, ok
+}
+
+// Routes lists every entry ordered by path, then method
+func (r *Registry) Routes() []Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make([]Route, 0, len(r.routes))
+	for _, route := range r.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// Template replaces the parameters of a Huma ({id}) or Gin (:id, *path)
+// route template with {} so templates can be compared across routers
+func Template(route string) string {
+	segments := strings.Split(route, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") ||
+			(strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")) {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// callerModule names the module of the function skip frames above the
+// caller, e.g. "school" for internal/school/delivery/http and "search" for
+// internal/pkg/search
+func callerModule(skip int) string {
+	_, file, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	if i := strings.LastIndex(file, "/internal/"); i >= 0 {
+		rest := strings.TrimPrefix(file[i+len("/internal/"):], "pkg/")
+		if j := strings.IndexByte(rest, '/'); j >= 0 {
+			return rest[:j]
+		}
+	}
+	return path.Base(path.Dir(file))
+}
+
+// Register registers handler like huma.Register and records the permission
+// the operation requires in the registry attached to api
+func Register[I, O any](api huma.API, op huma.Operation, perm Permission, handler func(context.Context, *I) (*O, error)) {
+	if op.Metadata == nil {
+		op.Metadata = make(map[string]any)
+	}
+	op.Metadata[metadataKey] = perm
+	op.Metadata[sourceKey] = callerModule(1)
+	huma.Register(api, op, handler)
+}
+
+// Handle registers handlers on a Gin group and records the permission the
+// route requires
+func Handle(reg *Registry, group *gin.RouterGroup, method, path string, perm Permission, handlers ...gin.HandlerFunc) {
+	// Recorded first so a collision is reported before Gin panics on it
+	reg.add(Route{
+		Method:   method,
+		Path:     joinPath(group.BasePath(), path),
+		Access:   perm.Access,
+		Resource: perm.Resource,
+		Action:   perm.Action,
+		Source:   callerModule(1),
+	})
+	group.Handle(method, path, handlers...)
+}
+
+// joinPath mirrors how Gin joins a group base path with a relative path
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	if base == "/" {
+		base = ""
+	}
+	if path[0] != '/' {
+		path = "/" + path
+	}
+	return base + path
+}
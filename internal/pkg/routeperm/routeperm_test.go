@@ -0,0 +1,70 @@
+package routeperm
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry()
+	r.Add(http.MethodGet, "/v1/users/:user_id/roles", Require("roles", "view"))
+	r.Add(http.MethodPost, "/v1/users/:user_id/roles", Require("roles", "edit"))
+	r.Add(http.MethodGet, "/v1/auth/me", Authenticated)
+
+	tests := []struct {
+		method string
+		path   string
+		want   Permission
+		found  bool
+	}{
+		{http.MethodGet, "/v1/users/:user_id/roles", Require("roles", "view"), true},
+		{http.MethodPost, "/v1/users/:user_id/roles", Require("roles", "edit"), true},
+		{http.MethodGet, "/v1/auth/me", Authenticated, true},
+		{http.MethodDelete, "/v1/users/:user_id/roles", Permission{}, false},
+		// Lookup matches the template the router reports, not a raw path
+		{http.MethodGet, "/v1/users/42/roles", Permission{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			route, ok := r.Lookup(tt.method, tt.path)
+			if ok != tt.found {
+				t.Fatalf("found = %v, want %v", ok, tt.found)
+			}
+			if route.Permission() != tt.want {
+				t.Errorf("permission = %+v, want %+v", route.Permission(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryCollisions(t *testing.T) {
+	r := NewRegistry()
+	var seen []Collision
+	r.OnCollision(func(c Collision) { seen = append(seen, c) })
+
+	r.Add(http.MethodGet, "/v1/users/{id}/roles", Require("roles", "view"))
+	r.Add(http.MethodGet, "/v1/users/:user_id/roles", Require("roles", "view"))
+	r.Add(http.MethodPost, "/v1/users/:user_id/roles", Require("roles", "edit"))
+
+	if len(seen) != 1 || len(r.Collisions()) != 1 {
+		t.Fatalf("got %d collisions, want 1", len(seen))
+	}
+	if !r.Known(http.MethodGet, "/v1/users/:id/roles") {
+		t.Error("template with another parameter name is not known")
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := map[string]string{
+		"/v1/users/{id}":          "/v1/users/{}",
+		"/v1/users/:user_id":      "/v1/users/{}",
+		"/static/*filepath":       "/static/{}",
+		"/v1/rbac/route-map":      "/v1/rbac/route-map",
+		"/v1/schools/{id}/photos": "/v1/schools/{}/photos",
+	}
+	for in, want := range tests {
+		if got := Template(in); got != want {
+			t.Errorf("Template(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
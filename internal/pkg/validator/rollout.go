@@ -0,0 +1,193 @@
+package validator
+
+import (
+	"expvar"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/logger"
+)
+
+// Rules that are rolled out gradually. Until a rule is enforced its
+// violations are only reported so breakage can be measured first.
+const (
+	RuleSchoolDomain = "school_domain"
+	RuleSlugPattern  = "slug_pattern"
+	RulePhoneFormat  = "phone_format"
+	RuleMenuURL      = "menu_url"
+)
+
+var (
+	slugRegex   = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	phoneRegex  = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	domainRegex = regexp.MustCompile(`^(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,}$`)
+)
+
+// Rollout decides per rule whether a violation rejects the request or is
+// only logged and counted.
+type Rollout struct {
+	mu         sync.RWMutex
+	enforced   map[string]bool
+	violations map[string]*atomic.Int64
+}
+
+// NewRollout creates a rollout enforcing only the given rules
+func NewRollout(enforced ...string) *Rollout {
+	r := &Rollout{
+		enforced:   make(map[string]bool),
+		violations: make(map[string]*atomic.Int64),
+	}
+	for _, rule := range enforced {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			r.enforced[rule] = true
+		}
+	}
+	return r
+}
+
+// SetEnforced switches a rule between enforce and report-only mode
+func (r *Rollout) SetEnforced(rule string, enforced bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enforced[rule] = enforced
+}
+
+// IsEnforced reports whether violations of rule reject the request
+func (r *Rollout) IsEnforced(rule string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.enforced[rule]
+}
+
+// Check records a violation of rule when ok is false. In enforce mode it
+// returns a validation error; in report-only mode it logs and returns nil.
+func (r *Rollout) Check(rule string, ok bool, msg string) error {
+	if ok {
+		return nil
+	}
+
+	count := r.counter(rule).Add(1)
+	if r.IsEnforced(rule) {
+		return apperrors.ValidationFailed(msg)
+	}
+
+	logger.Global().Service().Warn("validation violation (report-only)",
+		"rule", rule,
+		"message", msg,
+		"violations_total", count,
+	)
+	return nil
+}
+
+// Violations returns the number of violations recorded per rule
+func (r *Rollout) Violations() map[string]int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]int64, len(r.violations))
+	for rule, c := range r.violations {
+		out[rule] = c.Load()
+	}
+	return out
+}
+
+func (r *Rollout) counter(rule string) *atomic.Int64 {
+	r.mu.RLock()
+	c, ok := r.violations[rule]
+	r.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok = r.violations[rule]; !ok {
+		c = &atomic.Int64{}
+		r.violations[rule] = c
+	}
+	return c
+}
+
+// Global rollout instance, report-only for every rule until configured
+var globalRollout = NewRollout()
+
+// The violations of the global rollout are published per rule under
+// /debug/vars, to measure breakage before a rule is enforced
+func init() {
+	expvar.Publish("validation_violations", expvar.Func(func() any {
+		return GlobalRollout().Violations()
+	}))
+}
+
+// InitRollout replaces the global rollout with one enforcing the given rules
+func InitRollout(enforced ...string) {
+	globalRollout = NewRollout(enforced...)
+}
+
+// GlobalRollout returns the rollout consulted by services
+func GlobalRollout() *Rollout {
+	return globalRollout
+}
+
+// Check consults the global rollout
+func Check(rule string, ok bool, msg string) error {
+	return globalRollout.Check(rule, ok, msg)
+}
+
+// IsValidSlug reports whether s is lowercase words separated by single hyphens
+func (v *Validator) IsValidSlug(s string) (bool, string) {
+	if !slugRegex.MatchString(s) {
+		return false, "slug may only contain lowercase letters, numbers and single hyphens"
+	}
+	return true, ""
+}
+
+// IsValidPhone reports whether phone is in E.164 format (e.g. +6281234567890)
+func (v *Validator) IsValidPhone(phone string) (bool, string) {
+	if !phoneRegex.MatchString(phone) {
+		return false, "phone number must be in international format, e.g. +6281234567890"
+	}
+	return true, ""
+}
+
+// IsValidMenuURL reports whether u is an absolute path (e.g. /users) or an
+// absolute http(s) URL
+func (v *Validator) IsValidMenuURL(u string) (bool, string) {
+	const msg = "menu URL must be an absolute path such as /users or an http(s) URL"
+	if strings.ContainsAny(u, " \t\n") {
+		return false, msg
+	}
+	if strings.HasPrefix(u, "/") {
+		if strings.HasPrefix(u, "//") {
+			return false, msg
+		}
+		return true, ""
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return false, msg
+	}
+	return true, ""
+}
+
+// NormalizeDomain lowercases a domain and strips scheme, path and trailing dots
+func (v *Validator) NormalizeDomain(domain string) string {
+	d := strings.ToLower(strings.TrimSpace(domain))
+	d = strings.TrimPrefix(d, "https://")
+	d = strings.TrimPrefix(d, "http://")
+	if i := strings.IndexAny(d, "/?#"); i >= 0 {
+		d = d[:i]
+	}
+	return strings.TrimSuffix(d, ".")
+}
+
+// IsValidDomain reports whether domain is already in normalized form and well formed
+func (v *Validator) IsValidDomain(domain string) (bool, string) {
+	if domain != v.NormalizeDomain(domain) || !domainRegex.MatchString(domain) {
+		return false, "domain must be a lowercase host name without scheme or path, e.g. school.sch.id"
+	}
+	return true, ""
+}
@@ -0,0 +1,83 @@
+package validator
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	apperrors "backend-service-internpro/internal/pkg/errors"
+)
+
+func TestRolloutCheck(t *testing.T) {
+	r := NewRollout(RulePhoneFormat)
+
+	tests := []struct {
+		rule    string
+		ok      bool
+		wantErr bool
+	}{
+		{RulePhoneFormat, true, false},
+		{RulePhoneFormat, false, true},
+		{RuleSchoolDomain, false, false},
+		{RuleSchoolDomain, false, false},
+	}
+	for _, tt := range tests {
+		err := r.Check(tt.rule, tt.ok, "bad value")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Check(%s, %v) = %v, want error %v", tt.rule, tt.ok, err, tt.wantErr)
+		}
+		if appErr, ok := apperrors.IsAppError(err); err != nil && (!ok || appErr.Code != apperrors.CodeValidationFailed) {
+			t.Errorf("Check(%s, %v) = %v, want %s", tt.rule, tt.ok, err, apperrors.CodeValidationFailed)
+		}
+	}
+
+	// Violations are counted in both modes
+	got := r.Violations()
+	if got[RulePhoneFormat] != 1 || got[RuleSchoolDomain] != 2 {
+		t.Errorf("violations = %v, want %s: 1 and %s: 2", got, RulePhoneFormat, RuleSchoolDomain)
+	}
+
+	r.SetEnforced(RuleSchoolDomain, true)
+	if err := r.Check(RuleSchoolDomain, false, "bad value"); err == nil {
+		t.Error("enforced rule accepted a violation")
+	}
+}
+
+func TestViolationsPublished(t *testing.T) {
+	InitRollout()
+	if err := Check(RuleSlugPattern, false, "bad slug"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]int64
+	if err := json.Unmarshal([]byte(expvar.Get("validation_violations").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got[RuleSlugPattern] != 1 {
+		t.Errorf("published violations = %v, want %s: 1", got, RuleSlugPattern)
+	}
+}
+
+func TestIsValidMenuURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"/users", true},
+		{"/schools/classes?tab=schedule", true},
+		{"https://docs.example.com/guide", true},
+		{"http://localhost:3000/dashboard", true},
+		{"users", false},
+		{"//evil.example.com", false},
+		{"/users list", false},
+		{"ftp://files.example.com", false},
+		{"https://", false},
+		{"javascript:alert(1)", false},
+	}
+	v := New()
+	for _, tt := range tests {
+		if got, _ := v.IsValidMenuURL(tt.url); got != tt.want {
+			t.Errorf("IsValidMenuURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package http
+
+import (
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/privacy"
+)
+
+// Documented response examples
+var exampleDataExport = privacy.DataExport{
+	ID:        response.ExampleID(6),
+	UserID:    response.ExampleID(1),
+	Status:    privacy.ExportPending,
+	CreatedAt: response.ExampleTime,
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/privacy"
+	"backend-service-internpro/internal/privacy/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// New registers the personal data export and erasure routes. The user
+// sub-resources go on users, the /v1/users group owned by the router.
+func New(api, users huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+	g := users
+
+	// GET /users/{id}/data-export - Start or poll a personal data export
+	routeperm.Register(g, huma.Operation{
+		OperationID: "requestUserDataExport",
+		Method:      http.MethodGet,
+		Path:        "/{id}/data-export",
+		Summary:     "Export a user's personal data",
+		Description: "Starts generating an export of the user's profile, roles, login history, audit events and notifications. Poll until the status is ready, then use the signed download link before it expires.",
+		Tags:        []string{"User Management"},
+		Responses:   response.Example(constants.DataExportPending, exampleDataExport),
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"User ID"`
+	}) (*struct {
+		Status int
+		Body   privacy.DataExportResponse
+	}, error) {
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := h.svc.RequestExport(ctx, in.ID, actorID)
+		if err != nil {
+			return nil, privacyError(err)
+		}
+
+		status := http.StatusOK
+		if export, ok := result.Data.(privacy.DataExport); ok && export.Status == privacy.ExportPending {
+			status = http.StatusAccepted
+		}
+
+		return &struct {
+			Status int
+			Body   privacy.DataExportResponse
+		}{Status: status, Body: *result}, nil
+	})
+
+	// GET /data-exports/{id}/download - Download a ready export through its signed link
+	routeperm.Register(api, huma.Operation{
+		OperationID: "downloadUserDataExport",
+		Method:      http.MethodGet,
+		Path:        "/v1/data-exports/{id}/download",
+		Summary:     "Download a personal data export",
+		Description: "The signed link returned by the export endpoint is the credential; no bearer token is needed.",
+		Tags:        []string{"User Management"},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		ID        uuid.UUID `path:"id" doc:"Export ID"`
+		Expires   int64     `query:"expires" required:"true" doc:"Link expiry as a Unix timestamp"`
+		Signature string    `query:"signature" required:"true" doc:"Link signature"`
+	}) (*struct {
+		ContentDisposition string `header:"Content-Disposition"`
+		Body               json.RawMessage
+	}, error) {
+		payload, err := h.svc.DownloadExport(ctx, in.ID, in.Expires, in.Signature)
+		if err != nil {
+			return nil, privacyError(err)
+		}
+
+		return &struct {
+			ContentDisposition string `header:"Content-Disposition"`
+			Body               json.RawMessage
+		}{
+			ContentDisposition: `attachment; filename="data-export-` + in.ID.String() + `.json"`,
+			Body:               payload,
+		}, nil
+	})
+
+	// POST /users/{id}/erase/confirmation - Issue the token required to erase a user
+	routeperm.Register(g, huma.Operation{
+		OperationID: "issueUserEraseConfirmation",
+		Method:      http.MethodPost,
+		Path:        "/{id}/erase/confirmation",
+		Summary:     "Issue a confirmation token for erasing a user",
+		Description: "Super admin only. The token is bound to the user and the caller and expires after a few minutes.",
+		Tags:        []string{"User Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"User ID"`
+	}) (*struct {
+		Body privacy.EraseConfirmationResponse
+	}, error) {
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := h.svc.IssueEraseConfirmation(ctx, in.ID, actorID)
+		if err != nil {
+			return nil, privacyError(err)
+		}
+
+		return &struct {
+			Body privacy.EraseConfirmationResponse
+		}{Body: *result}, nil
+	})
+
+	// DELETE /users/{id}/erase - Anonymize a user's personal data
+	routeperm.Register(g, huma.Operation{
+		OperationID: "eraseUser",
+		Method:      http.MethodDelete,
+		Path:        "/{id}/erase",
+		Summary:     "Erase a user's personal data",
+		Description: "Super admin only. Scrambles the user's personal fields, deactivates the user, revokes their access tokens and API keys and removes sessions, auth events, OTPs, Google sign-in links, notifications and exports. Unlike deleting the user, the row, role assignments and user audit trail are kept.",
+		Tags:        []string{"User Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		ID                uuid.UUID `path:"id" doc:"User ID"`
+		ConfirmationToken string    `query:"confirmation_token" required:"true" doc:"Token from the erase confirmation endpoint"`
+	}) (*struct {
+		Body privacy.BasicResponse
+	}, error) {
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := h.svc.EraseUser(ctx, in.ID, actorID, in.ConfirmationToken)
+		if err != nil {
+			return nil, privacyError(err)
+		}
+
+		return &struct {
+			Body privacy.BasicResponse
+		}{Body: *result}, nil
+	})
+}
+
+// privacyError maps service errors to HTTP errors
+func privacyError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return huma.Error404NotFound(constants.UserNotFound)
+	case errors.Is(err, service.ErrExportNotFound):
+		return huma.Error404NotFound(constants.DataExportNotFound)
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, authz.ErrNotSuperAdmin):
+		return huma.Error403Forbidden(constants.InsufficientPermission)
+	case errors.Is(err, service.ErrInvalidLink):
+		return huma.Error403Forbidden(constants.DataExportLinkInvalid)
+	case errors.Is(err, service.ErrInvalidConfirmation):
+		return huma.Error403Forbidden(constants.EraseConfirmationInvalid)
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
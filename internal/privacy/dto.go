@@ -0,0 +1,92 @@
+package privacy
+
+import (
+	"time"
+
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/user"
+
+	"github.com/google/uuid"
+)
+
+// Data export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// Audit actions recorded for personal data requests
+const (
+	ActionExportRequested  = "data_export.requested"
+	ActionExportDownloaded = "data_export.downloaded"
+	ActionEraseConfirmed   = "erase.confirmation_issued"
+	ActionErased           = "user.erased"
+	// ActionAuthorizationChecked is a permission or role check about the
+	// user made by someone else, which reveals what the user may do
+	ActionAuthorizationChecked = "authorization.checked"
+)
+
+// DataExport represents the state of a personal data export
+type DataExport struct {
+	ID          uuid.UUID  `json:"id" doc:"Export ID"`
+	UserID      uuid.UUID  `json:"user_id" doc:"User whose data is exported"`
+	Status      string     `json:"status" enum:"pending,ready,failed" doc:"Export status"`
+	DownloadURL string     `json:"download_url,omitempty" doc:"Signed download link, present once the export is ready"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" doc:"When the export and its download link expire"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" doc:"When the export finished"`
+	CreatedAt   time.Time  `json:"created_at" doc:"When the export was requested"`
+}
+
+// AuditEvent represents an action performed on a user's personal data
+type AuditEvent struct {
+	ID        uuid.UUID         `json:"id"`
+	ActorID   *uuid.UUID        `json:"actor_id,omitempty"`
+	Action    string            `json:"action"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// LoginRecord is a session issued to the user, read from refresh_tokens
+type LoginRecord struct {
+	DeviceName *string   `json:"device_name,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Revoked    bool      `json:"revoked"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RoleRecord is a role assigned to the user
+type RoleRecord struct {
+	Name       string     `json:"name"`
+	Slug       string     `json:"slug"`
+	AssignedAt time.Time  `json:"assigned_at"`
+	AssignedBy *uuid.UUID `json:"assigned_by,omitempty"`
+}
+
+// UserDataDump is the content of a personal data export
+type UserDataDump struct {
+	GeneratedAt   time.Time                   `json:"generated_at"`
+	Profile       user.User                   `json:"profile"`
+	Roles         []RoleRecord                `json:"roles"`
+	LoginHistory  []LoginRecord               `json:"login_history"`
+	AuditEvents   []AuditEvent                `json:"audit_events"`
+	Notifications []notification.Notification `json:"notifications"`
+}
+
+// EraseConfirmation is the short-lived token required to erase a user
+type EraseConfirmation struct {
+	Token     string    `json:"token" doc:"Pass as confirmation_token to the erase endpoint"`
+	ExpiresAt time.Time `json:"expires_at" doc:"Token expiry"`
+}
+
+// DataExportResponse represents the response for a data export request
+type DataExportResponse = response.ApiResponse
+
+// EraseConfirmationResponse represents the response carrying an erase confirmation token
+type EraseConfirmationResponse = response.ApiResponse
+
+// BasicResponse represents basic response with message
+type BasicResponse = response.ApiResponse
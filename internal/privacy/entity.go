@@ -0,0 +1,71 @@
+package privacy
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataExportEntity represents a personal data export for database operations.
+// The generated dump is kept in Payload until the export expires.
+type DataExportEntity struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	UserID      uuid.UUID  `gorm:"type:char(36);not null;index"`
+	RequestedBy uuid.UUID  `gorm:"type:char(36);not null"`
+	Status      string     `gorm:"size:16;not null"`
+	Payload     string     `gorm:"type:longtext"`
+	Error       string     `gorm:"size:255"`
+	ExpiresAt   *time.Time `gorm:"index"`
+	CompletedAt *time.Time
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName returns the table name for the DataExportEntity
+func (DataExportEntity) TableName() string {
+	return "user_data_exports"
+}
+
+// ToDataExport converts DataExportEntity to DataExport DTO without the download link
+func (e *DataExportEntity) ToDataExport() DataExport {
+	return DataExport{
+		ID:          e.ID,
+		UserID:      e.UserID,
+		Status:      e.Status,
+		ExpiresAt:   e.ExpiresAt,
+		CompletedAt: e.CompletedAt,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+// AuditEventEntity records an action performed on a user's personal data.
+// Details never contain the personal data itself so the row survives erasure.
+type AuditEventEntity struct {
+	ID        uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	ActorID   *uuid.UUID `gorm:"type:char(36);index"`
+	SubjectID uuid.UUID  `gorm:"type:char(36);not null;index"`
+	Action    string     `gorm:"size:50;not null"`
+	Details   string     `gorm:"type:json;not null"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+// TableName returns the table name for the AuditEventEntity
+func (AuditEventEntity) TableName() string {
+	return "user_audit_events"
+}
+
+// ToAuditEvent converts AuditEventEntity to AuditEvent DTO
+func (e *AuditEventEntity) ToAuditEvent() AuditEvent {
+	event := AuditEvent{
+		ID:        e.ID,
+		ActorID:   e.ActorID,
+		Action:    e.Action,
+		CreatedAt: e.CreatedAt,
+	}
+
+	if e.Details != "" {
+		_ = json.Unmarshal([]byte(e.Details), &event.Details)
+	}
+
+	return event
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-service-internpro/internal/apikey"
+	"backend-service-internpro/internal/auth"
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/privacy"
+	"backend-service-internpro/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	// CreateExport stores a pending export together with the audit event requesting it
+	CreateExport(ctx context.Context, export *privacy.DataExportEntity, event *privacy.AuditEventEntity) error
+	GetExport(ctx context.Context, id uuid.UUID) (*privacy.DataExportEntity, error)
+	// GetLatestExport returns the newest export of a user that did not fail
+	GetLatestExport(ctx context.Context, userID uuid.UUID) (*privacy.DataExportEntity, error)
+	CompleteExport(ctx context.Context, id uuid.UUID, payload string, completedAt, expiresAt time.Time) error
+	FailExport(ctx context.Context, id uuid.UUID, reason string) error
+
+	CreateAuditEvent(ctx context.Context, event *privacy.AuditEventEntity) error
+	GetAuditEvents(ctx context.Context, subjectID uuid.UUID) ([]privacy.AuditEventEntity, error)
+
+	GetUser(ctx context.Context, id uuid.UUID) (*user.UserEntity, error)
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]privacy.RoleRecord, error)
+	GetLoginHistory(ctx context.Context, userID uuid.UUID) ([]privacy.LoginRecord, error)
+	GetNotifications(ctx context.Context, userID uuid.UUID) ([]notification.NotificationEntity, error)
+
+	// EraseUser overwrites the user's personal fields with values, removes
+	// rows that only hold personal data, revokes the user's API keys and
+	// records event, atomically
+	EraseUser(ctx context.Context, userID uuid.UUID, values map[string]interface{}, event *privacy.AuditEventEntity) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateExport(ctx context.Context, export *privacy.DataExportEntity, event *privacy.AuditEventEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(export).Error; err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+func (r *repository) GetExport(ctx context.Context, id uuid.UUID) (*privacy.DataExportEntity, error) {
+	var export privacy.DataExportEntity
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *repository) GetLatestExport(ctx context.Context, userID uuid.UUID) (*privacy.DataExportEntity, error) {
+	var export privacy.DataExportEntity
+	err := r.db.WithContext(ctx).
+		Omit("payload").
+		Where("user_id = ? AND status <> ?", userID, privacy.ExportFailed).
+		Order("created_at DESC").
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *repository) CompleteExport(ctx context.Context, id uuid.UUID, payload string, completedAt, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&privacy.DataExportEntity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       privacy.ExportReady,
+			"payload":      payload,
+			"completed_at": completedAt,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+func (r *repository) FailExport(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.db.WithContext(ctx).Model(&privacy.DataExportEntity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status": privacy.ExportFailed,
+			"error":  reason,
+		}).Error
+}
+
+func (r *repository) CreateAuditEvent(ctx context.Context, event *privacy.AuditEventEntity) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *repository) GetAuditEvents(ctx context.Context, subjectID uuid.UUID) ([]privacy.AuditEventEntity, error) {
+	var events []privacy.AuditEventEntity
+	err := r.db.WithContext(ctx).
+		Where("subject_id = ?", subjectID).
+		Order("created_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+func (r *repository) GetUser(ctx context.Context, id uuid.UUID) (*user.UserEntity, error) {
+	var u user.UserEntity
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&u).Error
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *repository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]privacy.RoleRecord, error) {
+	var roles []privacy.RoleRecord
+	err := r.db.WithContext(ctx).
+		Table("user_roles").
+		Select("roles.name, roles.slug, user_roles.assigned_at, user_roles.assigned_by").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("user_roles.assigned_at ASC").
+		Scan(&roles).Error
+	return roles, err
+}
+
+// GetLoginHistory lists the sessions issued to the user; each login creates a refresh token
+func (r *repository) GetLoginHistory(ctx context.Context, userID uuid.UUID) ([]privacy.LoginRecord, error) {
+	var logins []privacy.LoginRecord
+	err := r.db.WithContext(ctx).
+		Model(&auth.RefreshToken{}).
+		Select("device_name, user_agent, ip, revoked, expires_at, created_at").
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Scan(&logins).Error
+	return logins, err
+}
+
+func (r *repository) GetNotifications(ctx context.Context, userID uuid.UUID) ([]notification.NotificationEntity, error) {
+	var notifications []notification.NotificationEntity
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *repository) EraseUser(ctx context.Context, userID uuid.UUID, values map[string]interface{}, event *privacy.AuditEventEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user.UserEntity{}).Where("id = ?", userID).Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Sessions and auth events carry IP and user agent, provider links
+		// the email and account of the provider, notifications and exports
+		// carry names; none of them is needed once the user is erased
+		for _, model := range []interface{}{
+			&auth.RefreshToken{},
+			&auth.AuthEvent{},
+			&auth.OTP{},
+			&auth.UserIdentity{},
+			&notification.NotificationEntity{},
+			&privacy.DataExportEntity{},
+		} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// API keys act as the user, so they stop working; the rows stay
+		// for the audit trail of what the keys did
+		if err := tx.Model(&apikey.APIKeyEntity{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", event.CreatedAt).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(event).Error
+	})
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"backend-service-internpro/internal/privacy"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// noConn lets dry runs begin and commit transactions without a server;
+// nothing else reaches it
+type noConn struct {
+	gorm.ConnPool
+}
+
+func (c noConn) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) { return c, nil }
+func (noConn) Commit() error                                                    { return nil }
+func (noConn) Rollback() error                                                  { return nil }
+
+// recorder returns a repository on a database that renders statements
+// without a server, and the statements it ran
+func recorder(t *testing.T) (*repository, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      noConn{},
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []string
+	record := func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+		// Dry runs affect no rows; pretend the user exists
+		tx.RowsAffected = 1
+	}
+	for name, processor := range map[string]interface {
+		Register(string, func(*gorm.DB)) error
+	}{
+		"update": db.Callback().Update().After("gorm:update"),
+		"delete": db.Callback().Delete().After("gorm:delete"),
+		"create": db.Callback().Create().After("gorm:create"),
+	} {
+		if err := processor.Register("test:record_"+name, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &repository{db: db}, &statements
+}
+
+func TestEraseUserDeletesPersonalRecords(t *testing.T) {
+	r, statements := recorder(t)
+	userID := uuid.New()
+
+	err := r.EraseUser(context.Background(), userID, map[string]interface{}{"fullname": "Erased User"},
+		&privacy.AuditEventEntity{ID: uuid.New(), SubjectID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := strings.Join(*statements, "\n")
+	for _, table := range []string{"refresh_tokens", "auth_events", "otps", "user_identities", "notifications", "user_data_exports"} {
+		if !strings.Contains(all, "DELETE FROM `"+table+"` WHERE user_id = '"+userID.String()+"'") {
+			t.Errorf("%s rows of the user are not deleted:\n%s", table, all)
+		}
+	}
+	if !strings.Contains(all, "UPDATE `api_keys` SET `revoked_at`=") ||
+		!strings.Contains(all, "WHERE user_id = '"+userID.String()+"' AND revoked_at IS NULL") {
+		t.Errorf("API keys of the user are not revoked:\n%s", all)
+	}
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-service-internpro/internal/notification"
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/revocation"
+	"backend-service-internpro/internal/privacy"
+	"backend-service-internpro/internal/privacy/repository"
+	"backend-service-internpro/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultExportTTL is how long a generated export can be downloaded
+	DefaultExportTTL = 24 * time.Hour
+	// DefaultConfirmationTTL is how long an erase confirmation token is valid
+	DefaultConfirmationTTL = 5 * time.Minute
+
+	// exportTimeout bounds generation; a pending export older than this is
+	// assumed lost (e.g. the server restarted) and is generated again
+	exportTimeout = time.Minute
+
+	erasedFullname = "Erased User"
+	erasedDomain   = "erased.invalid"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrExportNotFound      = errors.New("data export not found")
+	ErrForbidden           = errors.New("not allowed to manage this user's personal data")
+	ErrInvalidLink         = errors.New("download link is invalid or has expired")
+	ErrInvalidConfirmation = errors.New("confirmation token is invalid or has expired")
+)
+
+type Service interface {
+	// RequestExport returns the user's current export, starting a new one in
+	// the background when none is pending or downloadable
+	RequestExport(ctx context.Context, userID, actorID uuid.UUID) (*privacy.DataExportResponse, error)
+	// DownloadExport returns the export content for a signed download link
+	DownloadExport(ctx context.Context, id uuid.UUID, expires int64, signature string) ([]byte, error)
+
+	IssueEraseConfirmation(ctx context.Context, userID, actorID uuid.UUID) (*privacy.EraseConfirmationResponse, error)
+	// EraseUser anonymizes the user's personal data, deactivates them and
+	// revokes their tokens; unlike a delete the row, role assignments and
+	// audit trail are kept
+	EraseUser(ctx context.Context, userID, actorID uuid.UUID, token string) (*privacy.BasicResponse, error)
+	// RecordAuthorizationCheck writes a check of kind, permission or role,
+	// that actorID made about the user's subject, e.g. schools:view, to the
+	// audit trail
+	RecordAuthorizationCheck(ctx context.Context, userID uuid.UUID, actorID *uuid.UUID, kind, subject string, allowed bool) error
+}
+
+// Config configures personal data exports and erasure
+type Config struct {
+	// SigningKey signs download links and erase confirmation tokens; it
+	// must not sign anything else
+	SigningKey      []byte
+	ExportTTL       time.Duration
+	ConfirmationTTL time.Duration
+	// Revocations rejects the access tokens of erased users; when nil they
+	// stay valid until they expire
+	Revocations revocation.Store
+	// AccessTTL is the lifetime of access tokens, how long a revocation
+	// must last
+	AccessTTL time.Duration
+	// Clock and IDs default to the wall clock and random UUIDs; tests
+	// replace them to get predictable timestamps and IDs
+	Clock clock.Clock
+	IDs   idgen.Generator
+}
+
+type service struct {
+	repo            repository.Repository
+	roles           authz.RoleChecker
+	signingKey      []byte
+	exportTTL       time.Duration
+	confirmationTTL time.Duration
+	revoked         revocation.Store
+	accessTTL       time.Duration
+	clock           clock.Clock
+	ids             idgen.Generator
+}
+
+func New(repo repository.Repository, roles authz.RoleChecker, cfg Config) Service {
+	if cfg.ExportTTL <= 0 {
+		cfg.ExportTTL = DefaultExportTTL
+	}
+	if cfg.ConfirmationTTL <= 0 {
+		cfg.ConfirmationTTL = DefaultConfirmationTTL
+	}
+	return &service{
+		repo:            repo,
+		roles:           roles,
+		signingKey:      cfg.SigningKey,
+		exportTTL:       cfg.ExportTTL,
+		confirmationTTL: cfg.ConfirmationTTL,
+		revoked:         cfg.Revocations,
+		accessTTL:       cfg.AccessTTL,
+		clock:           clock.OrReal(cfg.Clock),
+		ids:             idgen.OrRandom(cfg.IDs),
+	}
+}
+
+func (s *service) RequestExport(ctx context.Context, userID, actorID uuid.UUID) (*privacy.DataExportResponse, error) {
+	target, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkExportAccess(ctx, actorID, target); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	latest, err := s.repo.GetLatestExport(ctx, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if latest != nil && s.isCurrent(latest, now) {
+		message := constants.DataExportSuccess
+		if latest.Status == privacy.ExportPending {
+			message = constants.DataExportPending
+		}
+		return response.Success(message, s.toDataExport(latest)), nil
+	}
+
+	export := &privacy.DataExportEntity{
+		ID:          s.ids.New(),
+		UserID:      userID,
+		RequestedBy: actorID,
+		Status:      privacy.ExportPending,
+		CreatedAt:   now,
+	}
+	event := s.newEvent(&actorID, userID, privacy.ActionExportRequested, map[string]string{"export_id": export.ID.String()})
+	if err := s.repo.CreateExport(ctx, export, event); err != nil {
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+	logEvent(event)
+
+	// Detached from the request so a finished response does not cancel generation
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+		s.generateExport(ctx, export.ID, userID)
+	}()
+
+	return response.Success(constants.DataExportPending, s.toDataExport(export)), nil
+}
+
+func (s *service) DownloadExport(ctx context.Context, id uuid.UUID, expires int64, signature string) ([]byte, error) {
+	now := s.clock.Now()
+	if now.Unix() > expires || !hmac.Equal([]byte(signature), []byte(s.sign("export", id.String(), strconv.FormatInt(expires, 10)))) {
+		return nil, ErrInvalidLink
+	}
+
+	export, err := s.repo.GetExport(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export.Status != privacy.ExportReady || export.ExpiresAt == nil || now.After(*export.ExpiresAt) {
+		return nil, ErrExportNotFound
+	}
+
+	// The link is the credential, so the downloader is unknown
+	event := s.newEvent(nil, export.UserID, privacy.ActionExportDownloaded, map[string]string{"export_id": export.ID.String()})
+	if err := s.repo.CreateAuditEvent(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+	logEvent(event)
+
+	return []byte(export.Payload), nil
+}
+
+func (s *service) IssueEraseConfirmation(ctx context.Context, userID, actorID uuid.UUID) (*privacy.EraseConfirmationResponse, error) {
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.clock.Now().Add(s.confirmationTTL)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	confirmation := privacy.EraseConfirmation{
+		Token:     expires + "." + s.sign("erase", userID.String(), actorID.String(), expires),
+		ExpiresAt: expiresAt,
+	}
+
+	event := s.newEvent(&actorID, userID, privacy.ActionEraseConfirmed, nil)
+	if err := s.repo.CreateAuditEvent(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+	logEvent(event)
+
+	return response.Success(constants.EraseConfirmationSuccess, confirmation), nil
+}
+
+func (s *service) EraseUser(ctx context.Context, userID, actorID uuid.UUID, token string) (*privacy.BasicResponse, error) {
+	if !s.validConfirmation(token, userID, actorID) {
+		return nil, ErrInvalidConfirmation
+	}
+
+	id := userID.String()
+	values := map[string]interface{}{
+		"username":              "erased-" + id,
+		"email":                 "erased-" + id + "@" + erasedDomain,
+		"fullname":              erasedFullname,
+		"password_hash":         "!", // never a valid bcrypt hash, so login is impossible
+		"phone":                 nil,
+		"preferred_otp_channel": string(notifier.ChannelEmail),
+		"email_notifications":   false,
+		"is_active":             false,
+		"updated_at":            s.clock.Now(),
+	}
+	event := s.newEvent(&actorID, userID, privacy.ActionErased, nil)
+	if err := s.repo.EraseUser(ctx, userID, values, event); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to erase user: %w", err)
+	}
+	logEvent(event)
+	s.revokeTokens(userID)
+
+	return response.SuccessWithoutData(constants.UserEraseSuccess), nil
+}
+
+// revokeTokens rejects the access tokens issued to the erased user so far.
+// Failures are only logged: the user is deactivated, which refuses new
+// tokens, and the ones out expire within accessTTL.
+func (s *service) revokeTokens(userID uuid.UUID) {
+	if s.revoked == nil {
+		return
+	}
+	now := s.clock.Now()
+	if err := s.revoked.RevokeUser(context.Background(), userID.String(), now, now.Add(s.accessTTL)); err != nil {
+		logger.Global().Service().ErrorWithErr("failed to revoke access tokens of erased user", err, "user_id", userID.String())
+	}
+}
+
+func (s *service) generateExport(ctx context.Context, exportID, userID uuid.UUID) {
+	dump, err := s.collect(ctx, userID)
+	var payload []byte
+	if err == nil {
+		payload, err = json.Marshal(dump)
+	}
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to generate data export", err, "export_id", exportID.String())
+		if err := s.repo.FailExport(ctx, exportID, "failed to collect user data"); err != nil {
+			logger.Global().Service().ErrorWithErr("failed to mark data export as failed", err, "export_id", exportID.String())
+		}
+		return
+	}
+
+	now := s.clock.Now()
+	if err := s.repo.CompleteExport(ctx, exportID, string(payload), now, now.Add(s.exportTTL)); err != nil {
+		logger.Global().Service().ErrorWithErr("failed to store data export", err, "export_id", exportID.String())
+	}
+}
+
+// collect assembles every piece of personal data stored about the user
+func (s *service) collect(ctx context.Context, userID uuid.UUID) (*privacy.UserDataDump, error) {
+	u, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	roles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	logins, err := s.repo.GetLoginHistory(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login history: %w", err)
+	}
+	events, err := s.repo.GetAuditEvents(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+	notifications, err := s.repo.GetNotifications(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	dump := &privacy.UserDataDump{
+		GeneratedAt:   s.clock.Now(),
+		Profile:       u.ToUser(),
+		Roles:         roles,
+		LoginHistory:  logins,
+		AuditEvents:   make([]privacy.AuditEvent, len(events)),
+		Notifications: make([]notification.Notification, len(notifications)),
+	}
+	for i, e := range events {
+		dump.AuditEvents[i] = e.ToAuditEvent()
+	}
+	for i, n := range notifications {
+		dump.Notifications[i] = n.ToNotification()
+	}
+	return dump, nil
+}
+
+// isCurrent reports whether an export can be handed out instead of starting a new one
+func (s *service) isCurrent(e *privacy.DataExportEntity, now time.Time) bool {
+	switch e.Status {
+	case privacy.ExportPending:
+		return now.Sub(e.CreatedAt) < exportTimeout
+	case privacy.ExportReady:
+		return e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+	}
+	return false
+}
+
+func (s *service) toDataExport(e *privacy.DataExportEntity) privacy.DataExport {
+	export := e.ToDataExport()
+	if e.Status == privacy.ExportReady && e.ExpiresAt != nil {
+		expires := strconv.FormatInt(e.ExpiresAt.Unix(), 10)
+		export.DownloadURL = fmt.Sprintf("/v1/data-exports/%s/download?expires=%s&signature=%s",
+			e.ID, expires, s.sign("export", e.ID.String(), expires))
+	}
+	return export
+}
+
+// checkExportAccess allows users to export their own data; admins may export
+// anyone's and school admins only users of their own school
+func (s *service) checkExportAccess(ctx context.Context, actorID uuid.UUID, target *user.UserEntity) error {
+	if actorID == target.ID {
+		return nil
+	}
+
+	for _, slug := range []string{authz.RoleSuperAdmin, authz.RoleAdmin} {
+		ok, err := s.roles.CheckUserRole(ctx, actorID, slug)
+		if err != nil {
+			return fmt.Errorf("failed to check user role: %w", err)
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	isSchoolAdmin, err := s.roles.CheckUserRole(ctx, actorID, authz.RoleSchoolAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to check user role: %w", err)
+	}
+	if isSchoolAdmin {
+		actor, err := s.repo.GetUser(ctx, actorID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get actor: %w", err)
+		}
+		if actor != nil && (authz.Scope{Restricted: true, SchoolID: actor.SchoolID}).AllowsSchool(target.SchoolID) {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+func (s *service) getUser(ctx context.Context, id uuid.UUID) (*user.UserEntity, error) {
+	u, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return u, nil
+}
+
+// validConfirmation checks a token issued by IssueEraseConfirmation for the
+// same user and actor that has not expired
+func (s *service) validConfirmation(token string, userID, actorID uuid.UUID) bool {
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.clock.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign("erase", userID.String(), actorID.String(), expires)))
+}
+
+// sign returns the hex HMAC-SHA256 of parts; the first part names the
+// purpose so a signature for one use cannot be replayed for another
+func (s *service) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) RecordAuthorizationCheck(ctx context.Context, userID uuid.UUID, actorID *uuid.UUID, kind, subject string, allowed bool) error {
+	result := "denied"
+	if allowed {
+		result = "allowed"
+	}
+	event := s.newEvent(actorID, userID, privacy.ActionAuthorizationChecked, map[string]string{
+		"check":   kind,
+		"subject": subject,
+		"result":  result,
+	})
+	if err := s.repo.CreateAuditEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	logEvent(event)
+	return nil
+}
+
+func (s *service) newEvent(actorID *uuid.UUID, subjectID uuid.UUID, action string, details map[string]string) *privacy.AuditEventEntity {
+	event := &privacy.AuditEventEntity{
+		ID:        s.ids.New(),
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		Action:    action,
+		Details:   "{}",
+		CreatedAt: s.clock.Now(),
+	}
+	if len(details) > 0 {
+		raw, _ := json.Marshal(details)
+		event.Details = string(raw)
+	}
+	return event
+}
+
+func logEvent(e *privacy.AuditEventEntity) {
+	actor := ""
+	if e.ActorID != nil {
+		actor = e.ActorID.String()
+	}
+	logger.Global().Auth().Info("personal data action",
+		"action", e.Action,
+		"actor_id", actor,
+		"subject_id", e.SubjectID.String(),
+	)
+}
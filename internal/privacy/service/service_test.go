@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/idgen"
+	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/revocation"
+	"backend-service-internpro/internal/privacy"
+	"backend-service-internpro/internal/privacy/repository"
+	"backend-service-internpro/internal/user"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// fakeRepo knows every user and records audit events and the values an
+// erasure writes; other methods are not used
+type fakeRepo struct {
+	repository.Repository
+	erased map[string]interface{}
+	events []*privacy.AuditEventEntity
+}
+
+func (r *fakeRepo) GetUser(_ context.Context, id uuid.UUID) (*user.UserEntity, error) {
+	return &user.UserEntity{ID: id}, nil
+}
+
+func (r *fakeRepo) CreateAuditEvent(_ context.Context, event *privacy.AuditEventEntity) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeRepo) EraseUser(_ context.Context, _ uuid.UUID, values map[string]interface{}, event *privacy.AuditEventEntity) error {
+	r.erased = values
+	r.events = append(r.events, event)
+	return nil
+}
+
+// superAdmins is a RoleChecker granting super-admin to the users in it
+type superAdmins map[uuid.UUID]bool
+
+func (s superAdmins) CheckUserRole(_ context.Context, userID uuid.UUID, slug string) (bool, error) {
+	return slug == authz.RoleSuperAdmin && s[userID], nil
+}
+
+func TestEraseUser(t *testing.T) {
+	actorID, userID := uuid.New(), uuid.New()
+	repo := &fakeRepo{}
+	revoked := revocation.NewMemory()
+	s := New(repo, superAdmins{actorID: true}, Config{
+		SigningKey:  []byte("privacy-link-secret"),
+		Revocations: revoked,
+		AccessTTL:   15 * time.Minute,
+	})
+	ctx := context.Background()
+
+	// A confirmation signed with another key is refused
+	other := New(repo, superAdmins{actorID: true}, Config{SigningKey: []byte("access-secret")})
+	forged, err := other.IssueEraseConfirmation(ctx, userID, actorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := forged.Data.(privacy.EraseConfirmation).Token
+	if _, err := s.EraseUser(ctx, userID, actorID, token); !errors.Is(err, ErrInvalidConfirmation) {
+		t.Fatalf("erase with a foreign confirmation: err = %v, want %v", err, ErrInvalidConfirmation)
+	}
+	if repo.erased != nil {
+		t.Fatal("a foreign confirmation erased the user")
+	}
+
+	confirmation, err := s.IssueEraseConfirmation(ctx, userID, actorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token = confirmation.Data.(privacy.EraseConfirmation).Token
+	if _, err := s.EraseUser(ctx, userID, actorID, token); err != nil {
+		t.Fatal(err)
+	}
+	if active, ok := repo.erased["is_active"]; !ok || active != false {
+		t.Errorf("is_active = %v, want false", active)
+	}
+	if repo.erased["password_hash"] != "!" {
+		t.Errorf("password_hash = %v, want an unusable hash", repo.erased["password_hash"])
+	}
+	issued := jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	claims := &jwtpkg.Claims{UserID: userID.String(), RegisteredClaims: jwt.RegisteredClaims{ID: "jti", IssuedAt: issued}}
+	if ok, err := revoked.IsRevoked(ctx, claims); err != nil || !ok {
+		t.Errorf("access token issued before the erasure revoked = %v (%v), want true", ok, err)
+	}
+}
+
+func TestEraseConfirmationExpires(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	actorID, userID := uuid.New(), uuid.New()
+	repo := &fakeRepo{}
+	s := New(repo, superAdmins{actorID: true}, Config{
+		SigningKey: []byte("privacy-link-secret"),
+		Clock:      clk,
+		IDs:        idgen.NewSequence(),
+	})
+	ctx := context.Background()
+
+	confirmation, err := s.IssueEraseConfirmation(ctx, userID, actorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := confirmation.Data.(privacy.EraseConfirmation)
+	if want := now.Add(DefaultConfirmationTTL); !issued.ExpiresAt.Equal(want) {
+		t.Errorf("confirmation expires at %s, want %s", issued.ExpiresAt, want)
+	}
+
+	clk.Advance(DefaultConfirmationTTL + time.Second)
+	if _, err := s.EraseUser(ctx, userID, actorID, issued.Token); !errors.Is(err, ErrInvalidConfirmation) {
+		t.Fatalf("erase with an expired confirmation: err = %v, want %v", err, ErrInvalidConfirmation)
+	}
+
+	confirmation, err = s.IssueEraseConfirmation(ctx, userID, actorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EraseUser(ctx, userID, actorID, confirmation.Data.(privacy.EraseConfirmation).Token); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.erased["updated_at"]; got != clk.Now() {
+		t.Errorf("updated_at = %v, want %v", got, clk.Now())
+	}
+
+	// Two confirmations and the erasure, in order, with IDs from the sequence
+	want := []struct {
+		id     string
+		action string
+	}{
+		{"00000000-0000-4000-8000-000000000001", privacy.ActionEraseConfirmed},
+		{"00000000-0000-4000-8000-000000000002", privacy.ActionEraseConfirmed},
+		{"00000000-0000-4000-8000-000000000003", privacy.ActionErased},
+	}
+	if len(repo.events) != len(want) {
+		t.Fatalf("recorded %d audit events, want %d", len(repo.events), len(want))
+	}
+	for i, event := range repo.events {
+		if event.ID.String() != want[i].id || event.Action != want[i].action {
+			t.Errorf("event %d = %s %s, want %s %s", i, event.ID, event.Action, want[i].id, want[i].action)
+		}
+	}
+	if last := repo.events[2]; !last.CreatedAt.Equal(clk.Now()) {
+		t.Errorf("erasure recorded at %s, want %s", last.CreatedAt, clk.Now())
+	}
+}
+
+func TestRecordAuthorizationCheck(t *testing.T) {
+	actorID, userID := uuid.New(), uuid.New()
+	repo := &fakeRepo{}
+	s := New(repo, superAdmins{}, Config{IDs: idgen.NewSequence()})
+
+	if err := s.RecordAuthorizationCheck(context.Background(), userID, &actorID, "permission", "schools:delete", false); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.events) != 1 {
+		t.Fatalf("recorded %d audit events, want 1", len(repo.events))
+	}
+	event := repo.events[0]
+	if event.ActorID == nil || *event.ActorID != actorID || event.SubjectID != userID || event.Action != privacy.ActionAuthorizationChecked {
+		t.Errorf("event = actor %v subject %s action %s, want actor %s subject %s action %s",
+			event.ActorID, event.SubjectID, event.Action, actorID, userID, privacy.ActionAuthorizationChecked)
+	}
+	if want := `{"check":"permission","result":"denied","subject":"schools:delete"}`; event.Details != want {
+		t.Errorf("details = %s, want %s", event.Details, want)
+	}
+}
@@ -0,0 +1,114 @@
+// Package cache keeps the effective permissions of users between requests,
+// so permission checks need not query the database every time
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cache stores the permissions a user's roles allow or deny, as
+// resource:action keys with a "-" prefix for denies. It must be
+// invalidated whenever something that decides them changes.
+type Cache interface {
+	// Get returns the user's permissions and whether they were cached
+	Get(ctx context.Context, userID uuid.UUID) ([]string, bool, error)
+	// Set caches the user's permissions
+	Set(ctx context.Context, userID uuid.UUID, permissions []string) error
+	// Delete drops the permissions of the users, e.g. after their roles
+	// changed
+	Delete(ctx context.Context, userIDs ...uuid.UUID) error
+	// Clear drops every user's permissions, e.g. after a role's
+	// permissions changed
+	Clear(ctx context.Context) error
+}
+
+// DefaultTTL is how long permissions stay cached. It bounds how long a
+// change made outside the service, or on another server with a Memory
+// cache, goes unnoticed.
+const DefaultTTL = time.Minute
+
+type entry struct {
+	permissions []string
+	expires     time.Time
+}
+
+// Memory is a Cache kept in memory, for a single server process. With
+// several servers, changes made on one are only seen by the others once
+// their entries expire.
+type Memory struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[uuid.UUID]entry
+	lastSweep time.Time
+}
+
+var _ Cache = (*Memory)(nil)
+
+// sweepInterval is how often expired entries are dropped
+const sweepInterval = time.Minute
+
+// NewMemory creates an empty in-memory cache keeping entries for ttl
+func NewMemory(ttl time.Duration) *Memory {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Memory{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[uuid.UUID]entry),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, userID uuid.UUID) ([]string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[userID]
+	if !ok || !m.now().Before(e.expires) {
+		return nil, false, nil
+	}
+	return e.permissions, true, nil
+}
+
+func (m *Memory) Set(_ context.Context, userID uuid.UUID, permissions []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+	m.entries[userID] = entry{permissions: permissions, expires: m.now().Add(m.ttl)}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, userIDs ...uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, userID := range userIDs {
+		delete(m.entries, userID)
+	}
+	return nil
+}
+
+func (m *Memory) Clear(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.entries)
+	return nil
+}
+
+// sweep drops expired entries, at most once per sweep interval
+func (m *Memory) sweep() {
+	now := m.now()
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for userID, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, userID)
+		}
+	}
+}
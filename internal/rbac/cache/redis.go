@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces the cache keys in a shared Redis database
+const keyPrefix = "rbac:perms:"
+
+// scanBatch is how many keys one SCAN asks for while clearing
+const scanBatch = 500
+
+// Redis is a Cache shared by all servers through Redis, so an invalidation
+// on one server is seen by every other at once
+type Redis struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+var _ Cache = (*Redis)(nil)
+
+// NewRedis creates a cache storing entries in Redis for ttl
+func NewRedis(client *redis.Client, ttl time.Duration) *Redis {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Redis{client: client, ttl: ttl}
+}
+
+func (r *Redis) Get(ctx context.Context, userID uuid.UUID) ([]string, bool, error) {
+	value, err := r.client.Get(ctx, keyPrefix+userID.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var permissions []string
+	if err := json.Unmarshal([]byte(value), &permissions); err != nil {
+		return nil, false, err
+	}
+	return permissions, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, userID uuid.UUID, permissions []string) error {
+	value, err := json.Marshal(permissions)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, keyPrefix+userID.String(), value, r.ttl).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, userIDs ...uuid.UUID) error {
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, keyPrefix+userID.String())
+	}
+	return r.del(ctx, keys)
+}
+
+func (r *Redis) Clear(ctx context.Context) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, keyPrefix+"*", scanBatch).Result()
+		if err != nil {
+			return err
+		}
+		if err := r.del(ctx, keys); err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// del deletes keys; DEL without keys is an error in Redis
+func (r *Redis) del(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/rbac"
+)
+
+// Documented response examples
+var exampleRoleList = rbac.RoleListData{
+	Data: []rbac.Role{{
+		ID:                      response.ExampleID(3),
+		Name:                    "Guru Pembimbing",
+		Slug:                    "guru-pembimbing",
+		Description:             "Membimbing siswa selama magang",
+		IsActive:                true,
+		AssignableBySchoolAdmin: true,
+		CreatedAt:               response.ExampleTime,
+		UpdatedAt:               response.ExampleTime,
+	}},
+	Meta: rbac.RBACMetadata{Page: 1, Limit: 10, TotalPages: 1, TotalItems: 1},
+}
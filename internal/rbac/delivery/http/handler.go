@@ -153,7 +153,7 @@ func (h *Handler) GetRoles(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	search := c.Query("search")
 
-	response, err := h.rbacService.GetRoles(c.Request.Context(), page, limit, search)
+	response, err := h.rbacService.GetRoles(c.Request.Context(), page, limit, rbac.RoleFilter{Search: search})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to get roles",
@@ -318,7 +318,7 @@ func (h *Handler) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.rbacService.DeleteRole(c.Request.Context(), id, deletedBy); err != nil {
+	if err := h.rbacService.DeleteRole(c.Request.Context(), id, deletedBy, false); err != nil {
 		if err.Error() == "role not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "Role not found",
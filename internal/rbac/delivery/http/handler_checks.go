@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/rbac"
+	"backend-service-internpro/internal/rbac/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// The check endpoints can be used to enumerate the permission model, so
+// they get a stricter per-user limit: a burst of 10, then one every 6s.
+const (
+	checkRateInterval = 6 * time.Second
+	checkRateBurst    = 10
+)
+
+// CheckAuditor writes the checks made about another user to the audit
+// trail; the privacy service is one
+type CheckAuditor interface {
+	RecordAuthorizationCheck(ctx context.Context, userID uuid.UUID, actorID *uuid.UUID, kind, subject string, allowed bool) error
+}
+
+// checkHandler serves the authorization checks, counting them and auditing
+// the ones made about another user
+type checkHandler struct {
+	rbacService service.Service
+	audit       CheckAuditor
+}
+
+// NewChecks registers the endpoints checking a user's permissions and roles.
+func NewChecks(api huma.API, rbacService service.Service, audit CheckAuditor) {
+	h := &checkHandler{
+		rbacService: rbacService,
+		audit:       audit,
+	}
+	h.register(api)
+}
+
+func (h *checkHandler) register(api huma.API) {
+	limit := huma.Middlewares{middleware.HumaUserRateLimit(api, checkRateInterval, checkRateBurst)}
+
+	// POST /rbac/auth/check-permission - Whether a user holds a permission
+	routeperm.Register(api, huma.Operation{
+		OperationID: "checkUserPermission",
+		Method:      http.MethodPost,
+		Path:        "/v1/rbac/auth/check-permission",
+		Summary:     "Check whether a user holds a permission",
+		Description: "Checks about another user are written to the audit trail. Each caller may make 10 checks at once, then one every 6 seconds, across both check endpoints.",
+		Tags:        []string{"RBAC - Authorization"},
+		Middlewares: limit,
+		Errors:      []int{http.StatusUnauthorized, http.StatusTooManyRequests},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		Body rbac.CheckPermissionRequest
+	}) (*struct {
+		Body rbac.CheckPermissionResponse
+	}, error) {
+		hasPermission, err := h.rbacService.CheckUserPermission(ctx, in.Body.UserID, in.Body.Resource, in.Body.Action)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to check user permission")
+		}
+		if err := h.record(ctx, "permission", in.Body.UserID, in.Body.Resource+":"+in.Body.Action, hasPermission); err != nil {
+			return nil, err
+		}
+
+		return &struct {
+			Body rbac.CheckPermissionResponse
+		}{Body: *response.Success("Permission checked successfully", rbac.CheckPermissionData{HasPermission: hasPermission})}, nil
+	})
+
+	// POST /rbac/auth/check-role - Whether a user holds a role
+	routeperm.Register(api, huma.Operation{
+		OperationID: "checkUserRole",
+		Method:      http.MethodPost,
+		Path:        "/v1/rbac/auth/check-role",
+		Summary:     "Check whether a user holds a role",
+		Description: "Checks about another user are written to the audit trail. Each caller may make 10 checks at once, then one every 6 seconds, across both check endpoints.",
+		Tags:        []string{"RBAC - Authorization"},
+		Middlewares: limit,
+		Errors:      []int{http.StatusUnauthorized, http.StatusTooManyRequests},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		Body rbac.CheckRoleRequest
+	}) (*struct {
+		Body rbac.CheckRoleResponse
+	}, error) {
+		hasRole, err := h.rbacService.CheckUserRole(ctx, in.Body.UserID, in.Body.RoleSlug)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to check user role")
+		}
+		if err := h.record(ctx, "role", in.Body.UserID, in.Body.RoleSlug, hasRole); err != nil {
+			return nil, err
+		}
+
+		return &struct {
+			Body rbac.CheckRoleResponse
+		}{Body: *response.Success("Role checked successfully", rbac.CheckRoleData{HasRole: hasRole})}, nil
+	})
+
+	// GET /rbac/auth/check-counts - Checks served by result
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getCheckCounts",
+		Method:      http.MethodGet,
+		Path:        "/v1/rbac/auth/check-counts",
+		Summary:     "Count the authorization checks served",
+		Description: "Super admin only. Counts the permission and role checks served, by result. A rise in denied checks can point to someone mapping out the permission model.",
+		Tags:        []string{"RBAC - Authorization"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+	}) (*struct {
+		Body rbac.CheckCountsResponse
+	}, error) {
+		counts, err := h.rbacService.GetCheckCounts(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to get check counts")
+		}
+		return &struct {
+			Body rbac.CheckCountsResponse
+		}{Body: *response.Success("Check counts retrieved successfully", rbac.CheckCountsData{Counts: counts})}, nil
+	})
+}
+
+// record counts the check and, when it was made about another user, writes
+// it to the audit trail. A check that cannot be audited is not answered; a
+// failed count is only logged.
+func (h *checkHandler) record(ctx context.Context, kind string, targetID uuid.UUID, subject string, allowed bool) error {
+	if err := h.rbacService.CountCheck(ctx, kind, allowed); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to count authorization check", err, "check", kind)
+	}
+
+	var callerID *uuid.UUID
+	if userID, ok := requestctx.UserID(ctx); ok {
+		if userID == targetID {
+			return nil
+		}
+		callerID = &userID
+	}
+
+	if err := h.audit.RecordAuthorizationCheck(ctx, targetID, callerID, kind, subject, allowed); err != nil {
+		logger.Global().Auth().ErrorWithErr("failed to audit authorization check", err, "check", kind)
+		return huma.Error500InternalServerError("Failed to record the check")
+	}
+	return nil
+}
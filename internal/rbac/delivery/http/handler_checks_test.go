@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/rbac/service"
+
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/google/uuid"
+)
+
+// fakeChecks grants every user the permissions and roles listed and counts
+// the checks; other methods are not used
+type fakeChecks struct {
+	service.Service
+	granted map[string]bool
+	counts  map[string]int64
+}
+
+func (f *fakeChecks) CheckUserPermission(_ context.Context, _ uuid.UUID, resource, action string) (bool, error) {
+	return f.granted[resource+":"+action], nil
+}
+
+func (f *fakeChecks) CheckUserRole(_ context.Context, _ uuid.UUID, slug string) (bool, error) {
+	return f.granted[slug], nil
+}
+
+func (f *fakeChecks) CountCheck(_ context.Context, kind string, allowed bool) error {
+	if allowed {
+		f.counts[kind+":allowed"]++
+	} else {
+		f.counts[kind+":denied"]++
+	}
+	return nil
+}
+
+func (f *fakeChecks) GetCheckCounts(context.Context) (map[string]int64, error) {
+	return f.counts, nil
+}
+
+type auditRow struct {
+	callerID, targetID uuid.UUID
+	check, subject     string
+	allowed            bool
+}
+
+// fakeAuditor keeps the audited checks, or fails with err
+type fakeAuditor struct {
+	rows []auditRow
+	err  error
+}
+
+func (a *fakeAuditor) RecordAuthorizationCheck(_ context.Context, userID uuid.UUID, actorID *uuid.UUID, kind, subject string, allowed bool) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.rows = append(a.rows, auditRow{*actorID, userID, kind, subject, allowed})
+	return nil
+}
+
+func newCheckAPI(t *testing.T, granted map[string]bool) (humatest.TestAPI, *fakeChecks, *fakeAuditor) {
+	t.Helper()
+	_, api := humatest.New(t)
+	checks := &fakeChecks{granted: granted, counts: map[string]int64{}}
+	audit := &fakeAuditor{}
+	NewChecks(api, checks, audit)
+	return api, checks, audit
+}
+
+func asUser(userID uuid.UUID) context.Context {
+	return requestctx.WithClaims(context.Background(), &jwt.Claims{UserID: userID.String()})
+}
+
+func TestCheckAuditsOtherUsers(t *testing.T) {
+	api, checks, audit := newCheckAPI(t, map[string]bool{"schools:view": true, "admin": true})
+	caller, target := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name  string
+		path  string
+		body  map[string]any
+		audit *auditRow
+	}{
+		{
+			name:  "allowed permission of another user",
+			path:  "/v1/rbac/auth/check-permission",
+			body:  map[string]any{"user_id": target, "resource": "schools", "action": "view"},
+			audit: &auditRow{caller, target, "permission", "schools:view", true},
+		},
+		{
+			name:  "denied permission of another user",
+			path:  "/v1/rbac/auth/check-permission",
+			body:  map[string]any{"user_id": target, "resource": "schools", "action": "delete"},
+			audit: &auditRow{caller, target, "permission", "schools:delete", false},
+		},
+		{
+			name:  "role of another user",
+			path:  "/v1/rbac/auth/check-role",
+			body:  map[string]any{"user_id": target, "role_slug": "admin"},
+			audit: &auditRow{caller, target, "role", "admin", true},
+		},
+		{
+			name: "own permission is not audited",
+			path: "/v1/rbac/auth/check-permission",
+			body: map[string]any{"user_id": caller, "resource": "schools", "action": "view"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit.rows = nil
+			resp := api.PostCtx(asUser(caller), tt.path, tt.body)
+			if resp.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+			}
+			switch {
+			case tt.audit == nil && len(audit.rows) != 0:
+				t.Errorf("audited %+v, want nothing", audit.rows)
+			case tt.audit != nil && (len(audit.rows) != 1 || audit.rows[0] != *tt.audit):
+				t.Errorf("audited %+v, want %+v", audit.rows, *tt.audit)
+			}
+		})
+	}
+
+	if counts := checks.counts; counts["permission:allowed"] != 2 || counts["permission:denied"] != 1 || counts["role:allowed"] != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+}
+
+func TestCheckFailsWithoutAudit(t *testing.T) {
+	api, _, audit := newCheckAPI(t, map[string]bool{"admin": true})
+	audit.err = errors.New("audit store unavailable")
+	caller := uuid.New()
+
+	resp := api.PostCtx(asUser(caller), "/v1/rbac/auth/check-role", map[string]any{"user_id": uuid.New(), "role_slug": "admin"})
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("check about another user: status = %d, want %d", resp.Code, http.StatusInternalServerError)
+	}
+	// Own checks are not audited, so they are still answered
+	resp = api.PostCtx(asUser(caller), "/v1/rbac/auth/check-role", map[string]any{"user_id": caller, "role_slug": "admin"})
+	if resp.Code != http.StatusOK {
+		t.Errorf("own check: status = %d, want %d", resp.Code, http.StatusOK)
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	api, _, _ := newCheckAPI(t, nil)
+	caller, other := uuid.New(), uuid.New()
+	body := map[string]any{"user_id": uuid.New(), "role_slug": "admin"}
+
+	for i := 0; i < checkRateBurst; i++ {
+		if resp := api.PostCtx(asUser(caller), "/v1/rbac/auth/check-role", body); resp.Code != http.StatusOK {
+			t.Fatalf("check %d: status = %d", i+1, resp.Code)
+		}
+	}
+	// The limit is shared by both check endpoints
+	resp := api.PostCtx(asUser(caller), "/v1/rbac/auth/check-permission",
+		map[string]any{"user_id": uuid.New(), "resource": "schools", "action": "view"})
+	if resp.Code != http.StatusTooManyRequests {
+		t.Errorf("check past the burst: status = %d, want %d", resp.Code, http.StatusTooManyRequests)
+	}
+	if resp := api.PostCtx(asUser(other), "/v1/rbac/auth/check-role", body); resp.Code != http.StatusOK {
+		t.Errorf("another caller: status = %d, want %d", resp.Code, http.StatusOK)
+	}
+}
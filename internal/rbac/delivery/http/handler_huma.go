@@ -2,10 +2,18 @@ package http
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
-	"backend-service-internpro/internal/pkg/jwt"
-	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/constants"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
 	"backend-service-internpro/internal/rbac"
 	"backend-service-internpro/internal/rbac/service"
 
@@ -15,43 +23,55 @@ import (
 
 type HumaHandler struct {
 	rbacService service.Service
-	jwtSecrets  jwt.Secrets
 }
 
 // NewHuma registers RBAC routes into the Huma API for Swagger documentation.
-func NewHuma(api huma.API, rbacService service.Service, jwtSecrets jwt.Secrets) {
+func NewHuma(api huma.API, rbacService service.Service) {
 	h := &HumaHandler{
 		rbacService: rbacService,
-		jwtSecrets:  jwtSecrets,
 	}
 
 	// Role Management Routes
 	roleGroup := huma.NewGroup(api, "/v1/roles")
 
 	// GET /roles - List all roles
-	huma.Register(roleGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "",
-		Summary: "Get list of roles with pagination",
-		Tags:    []string{"RBAC - Roles"},
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "listRoles",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of roles with pagination",
+		Description: "With include_deleted, which only super admins may set, deleted roles are listed too with their deleted_at, so they can be found and restored.",
+		Tags:        []string{"RBAC - Roles"},
+		Responses:   response.Example("Roles retrieved successfully", exampleRoleList),
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		Page          int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
-		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
-		Search        string `query:"search" doc:"Search by name or slug"`
+	}, routeperm.Require("roles", "view"), func(ctx context.Context, in *struct {
+		Page           int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit          int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Search         string `query:"search" doc:"Search by name or slug"`
+		IsActive       string `query:"is_active" enum:"true,false" doc:"Only list active or inactive roles"`
+		SortBy         string `query:"sort_by" doc:"Sort by name, slug, is_active, created_at or updated_at; defaults to created_at"`
+		Order          string `query:"order" doc:"Sort direction, asc or desc; defaults to desc for created_at and asc otherwise"`
+		IncludeDeleted bool   `query:"include_deleted" doc:"Also list deleted roles; super admins only"`
 	}) (*struct {
 		Body rbac.RoleListResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		if in.IncludeDeleted {
+			actorID, _ := requestctx.UserID(ctx)
+			if err := authz.RequireSuperAdmin(ctx, h.rbacService, actorID); err != nil {
+				return nil, writeError(err)
+			}
 		}
 
-		result, err := h.rbacService.GetRoles(ctx, in.Page, in.Limit, in.Search)
+		result, err := h.rbacService.GetRoles(ctx, in.Page, in.Limit, rbac.RoleFilter{
+			Search:         in.Search,
+			IsActive:       activeFilter(in.IsActive),
+			IncludeDeleted: in.IncludeDeleted,
+			ListSort:       rbac.ListSort{SortBy: in.SortBy, Order: in.Order},
+		})
 		if err != nil {
-			return nil, huma.Error500InternalServerError(err.Error())
+			return nil, writeError(err)
 		}
 
 		return &struct {
@@ -60,29 +80,399 @@ func NewHuma(api huma.API, rbacService service.Service, jwtSecrets jwt.Secrets)
 	})
 
 	// GET /roles/{id} - Get role by ID
-	huma.Register(roleGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/{id}",
-		Summary: "Get role by ID",
-		Tags:    []string{"RBAC - Roles"},
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "getRole",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get role by ID",
+		Tags:        []string{"RBAC - Roles"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string    `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+	}, routeperm.Require("roles", "view"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" required:"true" doc:"Role ID"`
 	}) (*struct {
 		Body rbac.RoleResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		result, err := h.rbacService.GetRoleByID(ctx, in.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound(err.Error())
+		}
+
+		return &struct {
+			Body rbac.RoleResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /roles - Create role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID:   "createRole",
+		Method:        http.MethodPost,
+		Path:          "",
+		Summary:       "Create a role",
+		Description:   "Answers 400 for an invalid slug or default menu and 409 when the slug is taken.",
+		Tags:          []string{"RBAC - Roles"},
+		DefaultStatus: http.StatusCreated,
+		Errors:        []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		Body rbac.CreateRoleRequest
+	}) (*struct {
+		Body rbac.CreateRoleResponse
+	}, error) {
+		createdBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		result, err := h.rbacService.CreateRole(ctx, &in.Body, createdBy)
+		if err != nil {
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.CreateRoleResponse
+		}{Body: *result}, nil
+	})
+
+	// PUT /roles/{id} - Update role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "updateRole",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update a role",
+		Description: "Changes only the fields sent and returns the updated role. Answers 400 for an invalid slug or default menu, 404 when the role does not exist and 409 when the slug is taken.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Body rbac.UpdateRoleRequest
+	}) (*struct {
+		Body rbac.RoleResponse
+	}, error) {
+		updatedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
 		}
 
+		if err := h.rbacService.UpdateRole(ctx, in.ID, &in.Body, updatedBy); err != nil {
+			return nil, writeError(err)
+		}
 		result, err := h.rbacService.GetRoleByID(ctx, in.ID)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		result.Message = "Role updated successfully"
+
+		return &struct {
+			Body rbac.RoleResponse
+		}{Body: *result}, nil
+	})
+
+	// DELETE /roles/{id} - Delete role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "deleteRole",
+		Method:      http.MethodDelete,
+		Path:        "/{id}",
+		Summary:     "Delete a role",
+		Description: "Soft deletes the role and, in the same transaction, detaches it from its users, permissions and menus, so nobody keeps its access. The role and its assignments can be restored within the restore window. Answers 409 with the number of users when the role is still assigned to any, unless force is set. Answers 404 when the role does not exist.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		ID    uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Force bool      `query:"force" doc:"Delete the role even while it is assigned to users"`
+	}) (*struct {
+		Body rbac.RoleResponse
+	}, error) {
+		deletedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		if err := h.rbacService.DeleteRole(ctx, in.ID, deletedBy, in.Force); err != nil {
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.RoleResponse
+		}{Body: *response.SuccessWithoutData("Role deleted successfully")}, nil
+	})
+
+	// POST /roles/{id}/restore - Restore a deleted role and its assignments
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "restoreRole",
+		Method:      http.MethodPost,
+		Path:        "/{id}/restore",
+		Summary:     "Restore a deleted role",
+		Description: "Reinstates the role and the user, permission and menu assignments it lost when deleted. Assignments whose target no longer exists or was deleted are skipped and counted. The restore is logged with the acting user. Answers 404 when no deleted role has the ID, 409 when another role took its slug meanwhile and 410 once the restore window has passed.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusGone},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+	}) (*struct {
+		Body rbac.RoleRestoreResponse
+	}, error) {
+		restoredBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		result, err := h.rbacService.RestoreRole(ctx, in.ID, restoredBy)
+		if err != nil {
+			return nil, restoreError(err)
+		}
+
+		return &struct {
+			Body rbac.RoleRestoreResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /roles/{id}/permissions - List the role's permissions
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "listRolePermissions",
+		Method:      http.MethodGet,
+		Path:        "/{id}/permissions",
+		Summary:     "Get the permissions of a role with pagination",
+		Description: "Responses embedding a role's permissions carry at most the embed limit; this lists all of them.",
+		Tags:        []string{"RBAC - Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "view"), func(ctx context.Context, in *struct {
+		ID    uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Page  int       `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit int       `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}) (*struct {
+		Body rbac.PermissionListResponse
+	}, error) {
+		result, err := h.rbacService.GetRolePermissions(ctx, in.ID, in.Page, in.Limit)
+		if err != nil {
+			return nil, huma.Error404NotFound(err.Error())
+		}
+
+		return &struct {
+			Body rbac.PermissionListResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /roles/{id}/permissions - Assign permissions to the role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "assignRolePermissions",
+		Method:      http.MethodPost,
+		Path:        "/{id}/permissions",
+		Summary:     "Assign permissions to a role",
+		Description: "Adds the listed permissions to those the role allows and denies, switching the effect of ones it already has, and returns the role with its permissions. With mode=replace the role's permissions become exactly the listed ones. Answers 400 listing the permission IDs that do not exist and 404 when the role does not exist.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Mode string    `query:"mode" enum:"add,replace" default:"add" doc:"Add to the role's permissions or replace them"`
+		Body rbac.AssignRolePermissionsRequest
+	}) (*struct {
+		Body rbac.RoleResponse
+	}, error) {
+		assignedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		assign := h.rbacService.AddPermissionsToRole
+		if in.Mode == "replace" {
+			assign = h.rbacService.AssignPermissionsToRole
+		}
+		if err := assign(ctx, in.ID, &in.Body, assignedBy); err != nil {
+			return nil, writeError(err)
+		}
+		result, err := h.rbacService.GetRoleWithPermissions(ctx, in.ID)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		result.Message = "Permissions assigned to role successfully"
+
+		return &struct {
+			Body rbac.RoleResponse
+		}{Body: *result}, nil
+	})
+
+	// DELETE /roles/{id}/permissions - Detach permissions from the role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "removeRolePermissions",
+		Method:      http.MethodDelete,
+		Path:        "/{id}/permissions",
+		Summary:     "Remove permissions from a role",
+		Description: "Detaches permission_ids from the role, whether it allows or denies them, and returns the role with the permissions left. Permissions the role does not have are ignored. Answers 404 when the role does not exist.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Body rbac.RemoveRolePermissionsRequest
+	}) (*struct {
+		Body rbac.RoleResponse
+	}, error) {
+		removedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+		if err := h.rbacService.RemovePermissionsFromRole(ctx, in.ID, in.Body.PermissionIDs, removedBy); err != nil {
+			return nil, writeError(err)
+		}
+		result, err := h.rbacService.GetRoleWithPermissions(ctx, in.ID)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		result.Message = "Permissions removed from role successfully"
+
+		return &struct {
+			Body rbac.RoleResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /roles/{id}/menus - List the role's menus
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "listRoleMenus",
+		Method:      http.MethodGet,
+		Path:        "/{id}/menus",
+		Summary:     "Get the menus of a role with pagination",
+		Description: "Lists the role's menus with the role's access to each. Responses embedding a role's menus carry at most the embed limit; this lists all of them.",
+		Tags:        []string{"RBAC - Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "view"), func(ctx context.Context, in *struct {
+		ID    uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Page  int       `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit int       `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}) (*struct {
+		Body rbac.MenuListResponse
+	}, error) {
+		result, err := h.rbacService.GetRoleMenus(ctx, in.ID, in.Page, in.Limit)
 		if err != nil {
 			return nil, huma.Error404NotFound(err.Error())
 		}
 
+		return &struct {
+			Body rbac.MenuListResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /roles/{id}/users - List the users holding the role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "listRoleUsers",
+		Method:      http.MethodGet,
+		Path:        "/{id}/users",
+		Summary:     "Get the users of a role with pagination",
+		Description: "Lists the users holding the role, most recently assigned first. Needs users:view as it shows the users' details. Answers 404 when the role does not exist.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct {
+		ID     uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Page   int       `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit  int       `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Search string    `query:"search" doc:"Search by username, email or full name"`
+	}) (*struct {
+		Body rbac.RoleUserListResponse
+	}, error) {
+		result, err := h.rbacService.GetRoleUsers(ctx, in.ID, in.Page, in.Limit, in.Search)
+		if err != nil {
+			if err.Error() == "role not found" {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.RoleUserListResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /roles/{id}/menus - Assign menus to the role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "assignRoleMenus",
+		Method:      http.MethodPost,
+		Path:        "/{id}/menus",
+		Summary:     "Assign menus to a role",
+		Description: "Replaces the role's menus and its view, create, edit and delete access to each, and returns the role with its menus. Answers 400 listing the menu IDs that do not exist or are listed twice and 404 when the role does not exist.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Body rbac.AssignRoleMenusRequest
+	}) (*struct {
+		Body rbac.RoleResponse
+	}, error) {
+		assignedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		if err := h.rbacService.AssignMenusToRole(ctx, in.ID, &in.Body, assignedBy); err != nil {
+			return nil, writeError(err)
+		}
+		result, err := h.rbacService.GetRoleWithMenus(ctx, in.ID)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		result.Message = "Menus assigned to role successfully"
+
+		return &struct {
+			Body rbac.RoleResponse
+		}{Body: *result}, nil
+	})
+
+	// DELETE /roles/{id}/menus - Remove menus from the role
+	routeperm.Register(roleGroup, huma.Operation{
+		OperationID: "removeRoleMenus",
+		Method:      http.MethodDelete,
+		Path:        "/{id}/menus",
+		Summary:     "Remove menus from a role",
+		Description: "Removes menu_ids from the role's menus and returns the role with the menus left. Menus the role does not have are ignored. Answers 404 when the role does not exist.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("roles", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"Role ID"`
+		Body rbac.RemoveRoleMenusRequest
+	}) (*struct {
+		Body rbac.RoleResponse
+	}, error) {
+		removedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+		if err := h.rbacService.RemoveMenusFromRole(ctx, in.ID, in.Body.MenuIDs, removedBy); err != nil {
+			return nil, writeError(err)
+		}
+		result, err := h.rbacService.GetRoleWithMenus(ctx, in.ID)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		result.Message = "Menus removed from role successfully"
+
 		return &struct {
 			Body rbac.RoleResponse
 		}{Body: *result}, nil
@@ -92,29 +482,46 @@ func NewHuma(api huma.API, rbacService service.Service, jwtSecrets jwt.Secrets)
 	permissionGroup := huma.NewGroup(api, "/v1/permissions")
 
 	// GET /permissions - List all permissions
-	huma.Register(permissionGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "",
-		Summary: "Get list of permissions with pagination",
-		Tags:    []string{"RBAC - Permissions"},
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "listPermissions",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of permissions with pagination",
+		Description: "With include_deleted, which only super admins may set, deleted permissions are listed too with their deleted_at, so they can be found and restored.",
+		Tags:        []string{"RBAC - Permissions"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		Page          int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
-		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
-		Search        string `query:"search" doc:"Search by name, resource, or action"`
+	}, routeperm.Require("permissions", "view"), func(ctx context.Context, in *struct {
+		Page           int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit          int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Search         string `query:"search" doc:"Search by name, module, resource, or action"`
+		IsActive       string `query:"is_active" enum:"true,false" doc:"Only list active or inactive permissions"`
+		Resource       string `query:"resource" doc:"Only list permissions on this resource"`
+		Action         string `query:"action" doc:"Only list permissions for this action"`
+		SortBy         string `query:"sort_by" doc:"Sort by name, slug, module, resource, action, is_active, created_at or updated_at; defaults to resource"`
+		Order          string `query:"order" doc:"Sort direction, asc or desc; defaults to asc"`
+		IncludeDeleted bool   `query:"include_deleted" doc:"Also list deleted permissions; super admins only"`
 	}) (*struct {
 		Body rbac.PermissionListResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		if in.IncludeDeleted {
+			actorID, _ := requestctx.UserID(ctx)
+			if err := authz.RequireSuperAdmin(ctx, h.rbacService, actorID); err != nil {
+				return nil, writeError(err)
+			}
 		}
 
-		result, err := h.rbacService.GetPermissions(ctx, in.Page, in.Limit, in.Search)
+		result, err := h.rbacService.GetPermissions(ctx, in.Page, in.Limit, rbac.PermissionFilter{
+			Search:         in.Search,
+			IsActive:       activeFilter(in.IsActive),
+			Resource:       in.Resource,
+			Action:         in.Action,
+			IncludeDeleted: in.IncludeDeleted,
+			ListSort:       rbac.ListSort{SortBy: in.SortBy, Order: in.Order},
+		})
 		if err != nil {
-			return nil, huma.Error500InternalServerError(err.Error())
+			return nil, writeError(err)
 		}
 
 		return &struct {
@@ -122,25 +529,47 @@ func NewHuma(api huma.API, rbacService service.Service, jwtSecrets jwt.Secrets)
 		}{Body: *result}, nil
 	})
 
-	// GET /permissions/{id} - Get permission by ID
-	huma.Register(permissionGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/{id}",
-		Summary: "Get permission by ID",
-		Tags:    []string{"RBAC - Permissions"},
+	// GET /permissions/grouped - List permissions by module
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "listGroupedPermissions",
+		Method:      http.MethodGet,
+		Path:        "/grouped",
+		Summary:     "Get permissions grouped by module",
+		Description: "Returns every permission, unpaginated, keyed by module with modules in name order and each module's permissions ordered by resource then action. search narrows the permissions like it does for the paginated list.",
+		Tags:        []string{"RBAC - Permissions"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string    `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            uuid.UUID `path:"id" required:"true" doc:"Permission ID"`
+	}, routeperm.Require("permissions", "view"), func(ctx context.Context, in *struct {
+		Search string `query:"search" doc:"Search by name, module, resource, or action"`
 	}) (*struct {
-		Body rbac.PermissionResponse
+		Body rbac.GroupedPermissionsResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		result, err := h.rbacService.GetGroupedPermissions(ctx, in.Search)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
 		}
 
+		return &struct {
+			Body rbac.GroupedPermissionsResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /permissions/{id} - Get permission by ID
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "getPermission",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get permission by ID",
+		Tags:        []string{"RBAC - Permissions"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("permissions", "view"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" required:"true" doc:"Permission ID"`
+	}) (*struct {
+		Body rbac.PermissionResponse
+	}, error) {
 		result, err := h.rbacService.GetPermissionByID(ctx, in.ID)
 		if err != nil {
 			return nil, huma.Error404NotFound(err.Error())
@@ -151,161 +580,1051 @@ func NewHuma(api huma.API, rbacService service.Service, jwtSecrets jwt.Secrets)
 		}{Body: *result}, nil
 	})
 
-	// Menu Management Routes
-	menuGroup := huma.NewGroup(api, "/v1/menus")
+	// GET /permissions/resource/{resource} - List a resource's permissions
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "listResourcePermissions",
+		Method:      http.MethodGet,
+		Path:        "/resource/{resource}",
+		Summary:     "Get the permissions of a resource",
+		Tags:        []string{"RBAC - Permissions"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("permissions", "view"), func(ctx context.Context, in *struct {
+		Resource string `path:"resource" example:"users" doc:"Permission resource"`
+	}) (*struct {
+		Body rbac.PermissionListResponse
+	}, error) {
+		result, err := h.rbacService.GetPermissionsByResource(ctx, in.Resource)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
 
-	// GET /menus - List all menus
-	huma.Register(menuGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "",
-		Summary: "Get list of menus with pagination",
-		Tags:    []string{"RBAC - Menus"},
+		return &struct {
+			Body rbac.PermissionListResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /permissions - Create permission
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID:   "createPermission",
+		Method:        http.MethodPost,
+		Path:          "",
+		Summary:       "Create a permission",
+		Description:   "Answers 400 for an invalid slug and 409 when the slug is taken.",
+		Tags:          []string{"RBAC - Permissions"},
+		DefaultStatus: http.StatusCreated,
+		Errors:        []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		Page          int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
-		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
-		Search        string `query:"search" doc:"Search by name or slug"`
+	}, routeperm.Require("permissions", "edit"), func(ctx context.Context, in *struct {
+		Body rbac.CreatePermissionRequest
 	}) (*struct {
-		Body rbac.MenuListResponse
+		Body rbac.CreatePermissionResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		createdBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
 		}
 
-		result, err := h.rbacService.GetMenus(ctx, in.Page, in.Limit, in.Search)
+		result, err := h.rbacService.CreatePermission(ctx, &in.Body, createdBy)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(err.Error())
+			return nil, writeError(err)
 		}
 
 		return &struct {
-			Body rbac.MenuListResponse
+			Body rbac.CreatePermissionResponse
 		}{Body: *result}, nil
 	})
 
-	// GET /menus/tree - Get menu tree
-	huma.Register(menuGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/tree",
-		Summary: "Get hierarchical menu tree",
-		Tags:    []string{"RBAC - Menus"},
+	// POST /permissions/bulk - Create several permissions
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID:   "createPermissionsBulk",
+		Method:        http.MethodPost,
+		Path:          "/bulk",
+		Summary:       "Create several permissions",
+		Description:   "Creates all permissions in one transaction and returns their IDs. When any slug is invalid, taken or repeated, or any name is repeated, nothing is created and the 400 lists each offending item by its index.",
+		Tags:          []string{"RBAC - Permissions"},
+		DefaultStatus: http.StatusCreated,
+		Errors:        []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
+	}, routeperm.Require("permissions", "edit"), func(ctx context.Context, in *struct {
+		Body rbac.BulkCreatePermissionsRequest
 	}) (*struct {
-		Body rbac.MenuTreeResponse
+		Body rbac.BulkCreatePermissionsResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		createdBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
 		}
 
-		result, err := h.rbacService.GetMenuTree(ctx)
+		result, err := h.rbacService.CreatePermissions(ctx, &in.Body, createdBy)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(err.Error())
+			var bulkErr *rbac.BulkError
+			if errors.As(err, &bulkErr) {
+				return nil, bulkError(bulkErr, "body.permissions", "no permissions were created")
+			}
+			return nil, writeError(err)
 		}
 
 		return &struct {
-			Body rbac.MenuTreeResponse
+			Body rbac.BulkCreatePermissionsResponse
 		}{Body: *result}, nil
 	})
 
-	// User-Role Management Routes
-	userRoleGroup := huma.NewGroup(api, "/v1/users")
-
-	// GET /users/{id}/roles - Get user roles
-	huma.Register(userRoleGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/{id}/roles",
-		Summary: "Get roles assigned to user",
-		Tags:    []string{"RBAC - User Roles"},
+	// POST /permissions/generate - Create the permissions of a resource
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "generatePermissions",
+		Method:      http.MethodPost,
+		Path:        "/generate",
+		Summary:     "Generate the permissions of a resource",
+		Description: "Creates a permission for each action of the resource, named and slugged like the built-in ones, e.g. \"View Students\" and view-students. Actions default to view, create, edit and delete. Permissions that already exist are skipped and reported. With role_id the created permissions are granted to the role in the same transaction. Answers 400 for an invalid resource or action, 404 when the role does not exist and 409 when a derived slug belongs to another permission.",
+		Tags:        []string{"RBAC - Permissions"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string    `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            uuid.UUID `path:"id" required:"true" doc:"User ID"`
+	}, routeperm.Require("permissions", "edit"), func(ctx context.Context, in *struct {
+		Body rbac.GeneratePermissionsRequest
 	}) (*struct {
-		Body rbac.UserRoleListResponse
+		Body rbac.GeneratePermissionsResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		createdBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
 		}
 
-		result, err := h.rbacService.GetUserRoles(ctx, in.ID)
+		result, err := h.rbacService.GeneratePermissions(ctx, &in.Body, createdBy)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(err.Error())
+			return nil, writeError(err)
 		}
 
 		return &struct {
-			Body rbac.UserRoleListResponse
+			Body rbac.GeneratePermissionsResponse
 		}{Body: *result}, nil
 	})
 
-	// GET /users/{id}/permissions - Get user permissions
-	huma.Register(userRoleGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/{id}/permissions",
-		Summary: "Get permissions available to user through roles",
-		Tags:    []string{"RBAC - User Roles"},
+	// PUT /permissions/{id} - Update permission
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "updatePermission",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update a permission",
+		Description: "Changes only the fields sent and returns the updated permission. Answers 400 for an invalid slug, 404 when the permission does not exist and 409 when the slug is taken.",
+		Tags:        []string{"RBAC - Permissions"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string    `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            uuid.UUID `path:"id" required:"true" doc:"User ID"`
+	}, routeperm.Require("permissions", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"Permission ID"`
+		Body rbac.UpdatePermissionRequest
 	}) (*struct {
-		Body rbac.PermissionListResponse
+		Body rbac.PermissionResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		updatedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
 		}
 
-		result, err := h.rbacService.GetUserPermissions(ctx, in.ID)
+		if err := h.rbacService.UpdatePermission(ctx, in.ID, &in.Body, updatedBy); err != nil {
+			return nil, writeError(err)
+		}
+		result, err := h.rbacService.GetPermissionByID(ctx, in.ID)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(err.Error())
+			return nil, writeError(err)
 		}
+		result.Message = "Permission updated successfully"
 
 		return &struct {
-			Body rbac.PermissionListResponse
+			Body rbac.PermissionResponse
 		}{Body: *result}, nil
 	})
 
-	// GET /users/{id}/menus - Get user accessible menus
-	huma.Register(userRoleGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/{id}/menus",
-		Summary: "Get menus accessible to user through roles",
-		Tags:    []string{"RBAC - User Roles"},
+	// DELETE /permissions/{id} - Delete permission
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "deletePermission",
+		Method:      http.MethodDelete,
+		Path:        "/{id}",
+		Summary:     "Delete a permission",
+		Description: "Soft deletes the permission. Answers 409 naming the roles it is still attached to, unless force is set; with force it is detached from them. Answers 404 when the permission does not exist.",
+		Tags:        []string{"RBAC - Permissions"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string    `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            uuid.UUID `path:"id" required:"true" doc:"User ID"`
+	}, routeperm.Require("permissions", "edit"), func(ctx context.Context, in *struct {
+		ID    uuid.UUID `path:"id" required:"true" doc:"Permission ID"`
+		Force bool      `query:"force" doc:"Also detach the permission from the roles it is attached to"`
 	}) (*struct {
-		Body rbac.UserMenuResponse
+		Body rbac.PermissionResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
+		deletedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
 		}
 
-		result, err := h.rbacService.GetUserAccessibleMenus(ctx, in.ID)
+		if err := h.rbacService.DeletePermission(ctx, in.ID, deletedBy, in.Force); err != nil {
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.PermissionResponse
+		}{Body: *response.SuccessWithoutData("Permission deleted successfully")}, nil
+	})
+
+	// POST /permissions/{id}/restore - Restore a deleted permission
+	routeperm.Register(permissionGroup, huma.Operation{
+		OperationID: "restorePermission",
+		Method:      http.MethodPost,
+		Path:        "/{id}/restore",
+		Summary:     "Restore a deleted permission",
+		Description: "Reinstates the permission and returns it. Roles it was detached from by a forced delete do not get it back. The restore is logged with the acting user. Answers 404 when no deleted permission has the ID, 409 when another permission took its slug meanwhile and 410 once the restore window has passed.",
+		Tags:        []string{"RBAC - Permissions"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusGone},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("permissions", "edit"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" required:"true" doc:"Permission ID"`
+	}) (*struct {
+		Body rbac.PermissionResponse
+	}, error) {
+		restoredBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		result, err := h.rbacService.RestorePermission(ctx, in.ID, restoredBy)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(err.Error())
+			return nil, restoreError(err)
 		}
 
 		return &struct {
-			Body rbac.UserMenuResponse
+			Body rbac.PermissionResponse
 		}{Body: *result}, nil
 	})
+
+	// Menu Management Routes
+	menuGroup := huma.NewGroup(api, "/v1/menus")
+
+	// GET /menus - List all menus
+	routeperm.Register(menuGroup, huma.Operation{
+		OperationID: "listMenus",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of menus with pagination",
+		Tags:        []string{"RBAC - Menus"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("menus", "view"), func(ctx context.Context, in *struct {
+		Page     int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit    int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Search   string `query:"search" doc:"Search by name or slug"`
+		IsActive string `query:"is_active" enum:"true,false" doc:"Only list active or inactive menus"`
+		ParentID string `query:"parent_id" format:"uuid" doc:"Only list the direct children of this menu"`
+		SortBy   string `query:"sort_by" doc:"Sort by name, slug, sort_order, is_active, created_at or updated_at; defaults to sort_order"`
+		Order    string `query:"order" doc:"Sort direction, asc or desc; defaults to asc"`
+	}) (*struct {
+		Body rbac.MenuListResponse
+	}, error) {
+		filter := rbac.MenuFilter{
+			Search:   in.Search,
+			IsActive: activeFilter(in.IsActive),
+			ListSort: rbac.ListSort{SortBy: in.SortBy, Order: in.Order},
+		}
+		if in.ParentID != "" {
+			parentID, err := uuid.Parse(in.ParentID)
+			if err != nil {
+				return nil, huma.Error400BadRequest("Invalid parent ID")
+			}
+			filter.ParentID = &parentID
+		}
+
+		result, err := h.rbacService.GetMenus(ctx, in.Page, in.Limit, filter)
+		if err != nil {
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.MenuListResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /menus/tree - Get menu tree
+	routeperm.Register(menuGroup, huma.Operation{
+		OperationID: "getMenuTree",
+		Method:      http.MethodGet,
+		Path:        "/tree",
+		Summary:     "Get hierarchical menu tree",
+		Description: "Returns the active top-level menus with their active descendants nested at any depth, each level by sort order. Menus under an inactive menu are hidden with it.",
+		Tags:        []string{"RBAC - Menus"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("menus", "view"), func(ctx context.Context, in *struct {
+	}) (*struct {
+		Body rbac.MenuTreeResponse
+	}, error) {
+		result, err := h.rbacService.GetMenuTree(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.MenuTreeResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /menus/report - Navigation cleanup report
+	routeperm.Register(menuGroup, huma.Operation{
+		OperationID: "getMenuReport",
+		Method:      http.MethodGet,
+		Path:        "/report",
+		Summary:     "Get navigation cleanup report",
+		Description: "Lists active menus with an empty URL, a URL shared with other menus, an inactive or deleted parent, or no role assignment.",
+		Tags:        []string{"RBAC - Menus"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("menus", "view"), func(ctx context.Context, in *struct {
+	}) (*struct {
+		Body rbac.MenuReportResponse
+	}, error) {
+		result, err := h.rbacService.GetMenuReport(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.MenuReportResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /menus - Create menu
+	routeperm.Register(menuGroup, huma.Operation{
+		OperationID:   "createMenu",
+		Method:        http.MethodPost,
+		Path:          "",
+		Summary:       "Create a menu",
+		Description:   "Returns warnings for issues that do not block the change, such as a URL other active menus already use. Answers 400 for an invalid slug or URL, or a parent that is missing or whose ancestors form a loop, and 409 when the slug is taken.",
+		Tags:          []string{"RBAC - Menus"},
+		DefaultStatus: http.StatusCreated,
+		Errors:        []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("menus", "edit"), func(ctx context.Context, in *struct {
+		Body rbac.CreateMenuRequest
+	}) (*struct {
+		Body rbac.CreateMenuResponse
+	}, error) {
+		createdBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		result, err := h.rbacService.CreateMenu(ctx, &in.Body, createdBy)
+		if err != nil {
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.CreateMenuResponse
+		}{Body: *result}, nil
+	})
+
+	// PUT /menus/{id} - Update menu
+	routeperm.Register(menuGroup, huma.Operation{
+		OperationID: "updateMenu",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update a menu",
+		Description: "Changes only the fields sent and returns the updated menu with any warnings. A menu cannot be moved under itself or one of its descendants at any depth; send the nil UUID as parent_id to move it to the top level. Answers 400 for an invalid slug, URL or parent, 404 when the menu does not exist and 409 when the slug is taken.",
+		Tags:        []string{"RBAC - Menus"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("menus", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"Menu ID"`
+		Body rbac.UpdateMenuRequest
+	}) (*struct {
+		Body rbac.MenuResponse
+	}, error) {
+		updatedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		warnings, err := h.rbacService.UpdateMenu(ctx, in.ID, &in.Body, updatedBy)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		result, err := h.rbacService.GetMenuByID(ctx, in.ID)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		menu, ok := result.Data.(rbac.Menu)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+
+		return &struct {
+			Body rbac.MenuResponse
+		}{Body: *response.Success("Menu updated successfully", rbac.UpdateMenuData{Menu: menu, Warnings: warnings})}, nil
+	})
+
+	// PATCH /menus/reorder - Move and reorder several menus
+	routeperm.Register(menuGroup, huma.Operation{
+		OperationID: "reorderMenus",
+		Method:      http.MethodPatch,
+		Path:        "/reorder",
+		Summary:     "Reorder menus",
+		Description: "Sets the parent and sort order of each listed menu in one transaction and returns the refreshed menu tree. Placements are checked against the tree after the whole batch, so menus may swap places. When any menu is missing or listed twice, a parent is missing, or a move would put a menu under itself or one of its descendants, nothing is changed and the 400 lists each offending item by its index.",
+		Tags:        []string{"RBAC - Menus"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("menus", "edit"), func(ctx context.Context, in *struct {
+		Body rbac.ReorderMenusRequest
+	}) (*struct {
+		Body rbac.MenuTreeResponse
+	}, error) {
+		updatedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		result, err := h.rbacService.ReorderMenus(ctx, &in.Body, updatedBy)
+		if err != nil {
+			var bulkErr *rbac.BulkError
+			if errors.As(err, &bulkErr) {
+				return nil, bulkError(bulkErr, "body.menus", "no menus were reordered")
+			}
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.MenuTreeResponse
+		}{Body: *result}, nil
+	})
+
+	// DELETE /menus/{id} - Delete menu
+	routeperm.Register(menuGroup, huma.Operation{
+		OperationID: "deleteMenu",
+		Method:      http.MethodDelete,
+		Path:        "/{id}",
+		Summary:     "Delete a menu",
+		Description: "Soft deletes the menu. A menu with child menus is refused with 409 unless force is set; then its children move up to its parent. Answers 404 when the menu does not exist.",
+		Tags:        []string{"RBAC - Menus"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("menus", "edit"), func(ctx context.Context, in *struct {
+		ID    uuid.UUID `path:"id" required:"true" doc:"Menu ID"`
+		Force bool      `query:"force" doc:"Delete a menu with children, moving them up to its parent"`
+	}) (*struct {
+		Body rbac.MenuResponse
+	}, error) {
+		deletedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		if err := h.rbacService.DeleteMenu(ctx, in.ID, deletedBy, in.Force); err != nil {
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.MenuResponse
+		}{Body: *response.SuccessWithoutData("Menu deleted successfully")}, nil
+	})
+}
+
+// NewUserRoles registers the role, permission and menu sub-resources of a
+// user on users, the /v1/users group owned by the router.
+func NewUserRoles(users huma.API, rbacService service.Service) {
+	h := &HumaHandler{
+		rbacService: rbacService,
+	}
+
+	// User-Role Management Routes
+	userRoleGroup := users
+
+	// GET /users/{id}/roles - Get user roles
+	routeperm.Register(userRoleGroup, huma.Operation{
+		OperationID: "listUserRoles",
+		Method:      http.MethodGet,
+		Path:        "/{id}/roles",
+		Summary:     "Get roles assigned to user",
+		Tags:        []string{"RBAC - User Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" required:"true" doc:"User ID"`
+	}) (*struct {
+		Body rbac.UserRoleListResponse
+	}, error) {
+		result, err := h.rbacService.GetUserRoles(ctx, in.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.UserRoleListResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /users/roles/expiring - List role assignments about to end
+	routeperm.Register(userRoleGroup, huma.Operation{
+		OperationID: "listExpiringUserRoles",
+		Method:      http.MethodGet,
+		Path:        "/roles/expiring",
+		Summary:     "Get role assignments about to expire",
+		Description: "Lists the role assignments whose valid_until falls within the next within_days days, soonest first, so admins can extend or replace them in time. Deleted users and roles are left out.",
+		Tags:        []string{"RBAC - User Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct {
+		WithinDays int `query:"within_days" minimum:"1" maximum:"365" default:"7" doc:"How many days ahead to look"`
+		Page       int `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit      int `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}) (*struct {
+		Body rbac.ExpiringUserRoleListResponse
+	}, error) {
+		result, err := h.rbacService.GetExpiringUserRoles(ctx, time.Duration(in.WithinDays)*24*time.Hour, in.Page, in.Limit)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.ExpiringUserRoleListResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /users/{id}/permissions - Get user permissions
+	routeperm.Register(userRoleGroup, huma.Operation{
+		OperationID: "listUserPermissions",
+		Method:      http.MethodGet,
+		Path:        "/{id}/permissions",
+		Summary:     "Get permissions available to user through roles",
+		Tags:        []string{"RBAC - User Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" required:"true" doc:"User ID"`
+	}) (*struct {
+		Body rbac.PermissionListResponse
+	}, error) {
+		result, err := h.rbacService.GetUserPermissions(ctx, in.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.PermissionListResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /users/{id}/permissions/{resource}/{action}/explain - Trace a permission
+	routeperm.Register(userRoleGroup, huma.Operation{
+		OperationID: "explainUserPermission",
+		Method:      http.MethodGet,
+		Path:        "/{id}/permissions/{resource}/{action}/explain",
+		Summary:     "Explain why a user holds a permission",
+		Description: "Tells whether the user holds resource:action and lists every path to it: the user_roles row with who assigned it and when, the role, and the role_permissions row with its effect. Only assignments in effect and roles that are active and not deleted count; a deny on any path wins. The answer comes from the database, so permission checks may lag it by up to the permission cache TTL. School admins may only explain users of their school.",
+		Tags:        []string{"RBAC - User Roles"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct {
+		ID       uuid.UUID `path:"id" required:"true" doc:"User ID"`
+		Resource string    `path:"resource" maxLength:"100" doc:"Permission resource, e.g. students"`
+		Action   string    `path:"action" maxLength:"50" doc:"Permission action, e.g. edit"`
+	}) (*struct {
+		Body rbac.PermissionExplanationResponse
+	}, error) {
+		actorID, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		result, err := h.rbacService.ExplainUserPermission(ctx, actorID, in.ID, in.Resource, in.Action)
+		if err != nil {
+			return nil, userRolesError(err)
+		}
+
+		return &struct {
+			Body rbac.PermissionExplanationResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /users/{id}/menus - Get user accessible menus
+	routeperm.Register(userRoleGroup, huma.Operation{
+		OperationID: "listUserMenus",
+		Method:      http.MethodGet,
+		Path:        "/{id}/menus",
+		Summary:     "Get menus accessible to user through roles",
+		Description: "Lists the menus the user may view through any of their roles. Each menu appears once, with the access of all their roles combined: a flag is set when any role sets it, and role_ids lists the roles granting the menu. Each menu carries its active descendants nested at any depth.",
+		Tags:        []string{"RBAC - User Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" required:"true" doc:"User ID"`
+	}) (*struct {
+		Body rbac.UserMenuResponse
+	}, error) {
+		result, err := h.rbacService.GetUserAccessibleMenus(ctx, in.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.UserMenuResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /users/{id}/roles - Replace user roles
+	routeperm.Register(userRoleGroup, huma.Operation{
+		OperationID: "assignUserRoles",
+		Method:      http.MethodPost,
+		Path:        "/{id}/roles",
+		Summary:     "Assign roles to a user",
+		Description: "Assigns the roles in role_ids the user does not hold yet and returns what changed and the resulting roles. With mode=replace the user's roles become exactly role_ids. Either way roles the user keeps retain their assignment date and assigner. valid_from and valid_until limit when the assignments are in effect and apply to every role in role_ids, including kept ones; leaving both out makes new assignments permanent and leaves kept ones as they are. Expired assignments grant nothing, though access tokens issued before keep claiming the role until they expire. Only admins may change roles, never their own nor a role above their highest; school admins may only manage users of their school and roles flagged as assignable by them, otherwise 403. Answers 400 when a role does not exist or valid_until is not after valid_from and now.",
+		Tags:        []string{"RBAC - User Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"User ID"`
+		Mode string    `query:"mode" enum:"add,replace" default:"add" doc:"Add to the user's roles or replace them"`
+		Body rbac.AssignUserRolesRequest
+	}) (*struct {
+		Body rbac.UserRoleResponse
+	}, error) {
+		assignedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		assign := h.rbacService.AddRolesToUser
+		if in.Mode == "replace" {
+			assign = h.rbacService.AssignRolesToUser
+		}
+		result, err := assign(ctx, in.ID, &in.Body, assignedBy)
+		if err != nil {
+			return nil, userRolesError(err)
+		}
+		changes, ok := result.Data.(rbac.UserRoleChanges)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+		roles, err := h.userRoles(ctx, in.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &struct {
+			Body rbac.UserRoleResponse
+		}{Body: *response.Success(result.Message, rbac.UserRolesData{Changes: &changes, Roles: roles})}, nil
+	})
+
+	// DELETE /users/{id}/roles - Remove user roles
+	routeperm.Register(userRoleGroup, huma.Operation{
+		OperationID: "removeUserRoles",
+		Method:      http.MethodDelete,
+		Path:        "/{id}/roles",
+		Summary:     "Remove roles from a user",
+		Description: "Removes role_ids from the user's roles and returns the roles left. Roles the user does not have are ignored. Only admins may change roles, never their own nor a role above their highest; school admins may only manage users of their school and roles flagged as assignable by them, otherwise 403.",
+		Tags:        []string{"RBAC - User Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" required:"true" doc:"User ID"`
+		Body rbac.RemoveUserRolesRequest
+	}) (*struct {
+		Body rbac.UserRoleResponse
+	}, error) {
+		removedBy, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		if err := h.rbacService.RemoveRolesFromUser(ctx, in.ID, in.Body.RoleIDs, removedBy); err != nil {
+			return nil, userRolesError(err)
+		}
+		roles, err := h.userRoles(ctx, in.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &struct {
+			Body rbac.UserRoleResponse
+		}{Body: *response.Success("Roles removed from user successfully", rbac.UserRolesData{Roles: roles})}, nil
+	})
+}
+
+// userRoles lists the user's roles after a change
+func (h *HumaHandler) userRoles(ctx context.Context, userID uuid.UUID) ([]rbac.UserRole, error) {
+	result, err := h.rbacService.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	data, ok := result.Data.(rbac.UserRoleListData)
+	if !ok {
+		return nil, huma.Error500InternalServerError("Invalid response data type")
+	}
+	if data.Data == nil {
+		return []rbac.UserRole{}, nil
+	}
+	return data.Data, nil
+}
+
+// userRolesError converts an error of changing a user's roles to the Huma
+// error to answer with
+func userRolesError(err error) error {
+	if appErr, ok := apperrors.IsAppError(err); ok {
+		return appErr.ToHumaError()
+	}
+	if isForbidden(err) {
+		return huma.Error403Forbidden(err.Error())
+	}
+	if strings.HasPrefix(err.Error(), "role with ID ") {
+		return huma.Error400BadRequest(err.Error())
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// isForbidden reports whether err is a school scope or role management
+// policy violation
+func isForbidden(err error) bool {
+	for _, target := range []error{
+		authz.ErrOutOfScope, authz.ErrRoleNotDelegable,
+		authz.ErrNotAdmin, authz.ErrNotRoleManager, authz.ErrOwnRoles, authz.ErrRoleAboveCaller,
+		authz.ErrNotSuperAdmin,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
 }
 
-// validateToken validates JWT token from Authorization header
-func (h *HumaHandler) validateToken(authHeader string) error {
-	_, err := middleware.ValidateToken(authHeader, h.jwtSecrets)
-	return err
+// NewLanding registers the endpoint telling the frontend which page to open
+// after login.
+func NewLanding(api huma.API, rbacService service.Service) {
+	// GET /me/landing - The caller's landing menu
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getMyLanding",
+		Method:      http.MethodGet,
+		Path:        "/v1/me/landing",
+		Summary:     "Get the menu to open after login",
+		Description: "Returns the default menu of the caller's highest priority role that has one the caller can view, ties going to the role with the smaller slug. Without one, the first accessible menu with a URL by sort order is returned. Responds 404 when the caller can view no menu.",
+		Tags:        []string{"RBAC - User Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+	}) (*struct {
+		Body rbac.LandingResponse
+	}, error) {
+		userID, ok := requestctx.UserID(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized(constants.UnauthorizedAccess)
+		}
+
+		result, err := rbacService.GetUserLanding(ctx, userID)
+		if err != nil {
+			if err.Error() == "no accessible menu" {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.LandingResponse
+		}{Body: *result}, nil
+	})
+}
+
+// writeError converts an error of creating, updating or deleting a role,
+// permission or menu, or of assigning permissions or menus, to the Huma
+// error to answer with
+func writeError(err error) error {
+	if appErr, ok := apperrors.IsAppError(err); ok {
+		return appErr.ToHumaError()
+	}
+	if isForbidden(err) {
+		return huma.Error403Forbidden(err.Error())
+	}
+	switch err.Error() {
+	case "role not found", "permission not found", "menu not found":
+		return huma.Error404NotFound(err.Error())
+	case "role slug already exists", "permission slug already exists", "menu slug already exists",
+		"menu has child menus":
+		return huma.Error409Conflict(err.Error())
+	case "default menu must be an active menu with a URL",
+		"default menu must be an active menu with a URL assigned to the role",
+		"parent menu not found",
+		"menu cannot be parent of itself",
+		"menu cannot be moved under its own descendant",
+		"parent menu is nested too deep",
+		"permission cannot be both allowed and denied":
+		return huma.Error400BadRequest(err.Error())
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// activeFilter turns the is_active query value into a filter, nil when it
+// was not given
+func activeFilter(value string) *bool {
+	if value == "" {
+		return nil
+	}
+	active := value == "true"
+	return &active
+}
+
+// restoreError maps the errors of restoring a role or permission, which add
+// an expired restore window to those of writeError
+func restoreError(err error) error {
+	switch err.Error() {
+	case "role restore window has expired", "permission restore window has expired":
+		return huma.Error410Gone(err.Error())
+	}
+	return writeError(err)
+}
+
+// bulkError answers a rejected bulk request with a 400 locating each
+// invalid item under location
+func bulkError(err *rbac.BulkError, location, msg string) error {
+	details := make([]error, 0, len(err.Items))
+	for _, item := range err.Items {
+		details = append(details, &huma.ErrorDetail{
+			Location: fmt.Sprintf("%s[%d].%s", location, item.Index, item.Field),
+			Message:  item.Message,
+			Value:    item.Value,
+		})
+	}
+	return huma.Error400BadRequest(msg, details...)
+}
+
+// NewMaintenance registers the RBAC maintenance endpoints.
+func NewMaintenance(api huma.API, rbacService service.Service) {
+	// POST /rbac/maintenance/prune-orphans - Remove assignments whose target is gone
+	routeperm.Register(api, huma.Operation{
+		OperationID: "pruneRBACOrphans",
+		Method:      http.MethodPost,
+		Path:        "/v1/rbac/maintenance/prune-orphans",
+		Summary:     "Remove orphaned role assignments",
+		Description: "Super admin only. Deletes role_menus, role_permissions and user_roles rows whose role, menu, permission or user no longer exists or was deleted longer ago than the role restore window. The cleanup job runs the same pruning every hour.",
+		Tags:        []string{"RBAC - Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+	}) (*struct {
+		Body rbac.PruneOrphansResponse
+	}, error) {
+		result, err := rbacService.PruneOrphans(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.PruneOrphansResponse
+		}{Body: *response.Success("Orphaned assignments pruned successfully", *result)}, nil
+	})
+
+	// POST /rbac/cache/invalidate - Stop trusting roles claimed by access tokens
+	routeperm.Register(api, huma.Operation{
+		OperationID: "invalidateRBACCache",
+		Method:      http.MethodPost,
+		Path:        "/v1/rbac/cache/invalidate",
+		Summary:     "Invalidate the roles cached in access tokens",
+		Description: "Super admin only. Access tokens carry the user's roles, which role checks trust until a role change made through the API. After a change made elsewhere, like a manual database fix, this makes role checks read the database for every token issued until now, or only for one user_id or one role_id. It also drops the cached permissions that permission checks use.",
+		Tags:        []string{"RBAC - Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		Body rbac.InvalidateRoleClaimsRequest
+	}) (*struct {
+		Body rbac.InvalidateRoleClaimsResponse
+	}, error) {
+		result, err := rbacService.InvalidateRoleClaims(ctx, &in.Body)
+		if err != nil {
+			switch err.Error() {
+			case "role not found":
+				return nil, huma.Error404NotFound(err.Error())
+			case "give either user_id or role_id, not both":
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.InvalidateRoleClaimsResponse
+		}{Body: *result}, nil
+	})
+}
+
+// importBodyLimit caps the size of an imported document
+const importBodyLimit = 8 << 20
+
+// NewTransfer registers the endpoints exporting the RBAC configuration and
+// importing it into another environment.
+func NewTransfer(api huma.API, rbacService service.Service) {
+	// GET /rbac/export - Download the RBAC configuration
+	routeperm.Register(api, huma.Operation{
+		OperationID: "exportRBAC",
+		Method:      http.MethodGet,
+		Path:        "/v1/rbac/export",
+		Summary:     "Export the RBAC configuration",
+		Description: "Super admin only. Returns the roles, permissions and menus with the permissions and menus of each role, referring to each other by slug rather than ID so the document can be imported into another environment. Deleted items are left out.",
+		Tags:        []string{"RBAC - Roles"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+	}) (*struct {
+		ContentDisposition string `header:"Content-Disposition"`
+		Body               rbac.RBACDocument
+	}, error) {
+		doc, err := rbacService.ExportRBAC(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			ContentDisposition string `header:"Content-Disposition"`
+			Body               rbac.RBACDocument
+		}{
+			ContentDisposition: `attachment; filename="rbac-export-` + doc.ExportedAt.Format("20060102-150405") + `.json"`,
+			Body:               *doc,
+		}, nil
+	})
+
+	// POST /rbac/import - Apply an exported RBAC configuration
+	routeperm.Register(api, huma.Operation{
+		OperationID: "importRBAC",
+		Method:      http.MethodPost,
+		Path:        "/v1/rbac/import",
+		Summary:     "Import an RBAC configuration",
+		Description: "Super admin only. Creates or updates the roles, permissions and menus of an exported document by slug, and makes the permissions and menus of each listed role match the document. Items the document leaves out are not touched. Everything is written in one transaction, and with dry_run nothing is written; either way the response reports what changes. Answers 409, listing every clash, when a slug belongs to a permission with another resource or action or to a deleted item, or a name belongs to an item the document leaves out, and 400 for an invalid document.",
+		Tags:        []string{"RBAC - Roles"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+		// Documents of large setups outgrow the default limit
+		MaxBodyBytes: importBodyLimit,
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+		DryRun bool `query:"dry_run" doc:"Only report what would change"`
+		Body   rbac.RBACDocument
+	}) (*struct {
+		Body rbac.RBACImportResponse
+	}, error) {
+		actorID, _ := requestctx.UserID(ctx)
+
+		result, err := rbacService.ImportRBAC(ctx, &in.Body, in.DryRun, actorID)
+		if err != nil {
+			var importErr *rbac.ImportError
+			if errors.As(err, &importErr) {
+				return nil, importError(importErr)
+			}
+			return nil, writeError(err)
+		}
+
+		return &struct {
+			Body rbac.RBACImportResponse
+		}{Body: *result}, nil
+	})
+}
+
+// importError answers a rejected import with one detail per issue: 409
+// when the document clashes with the current configuration, 400 otherwise
+func importError(err *rbac.ImportError) error {
+	details := make([]error, 0, len(err.Issues))
+	for _, issue := range err.Issues {
+		details = append(details, &huma.ErrorDetail{
+			Location: "body." + issue.Location,
+			Message:  issue.Message,
+			Value:    issue.Value,
+		})
+	}
+	if err.Conflict() {
+		return huma.Error409Conflict("the document conflicts with the current configuration", details...)
+	}
+	return huma.Error400BadRequest("the document is invalid", details...)
+}
+
+// NewRouteMap registers the endpoint listing the permission each registered
+// route requires, used by the admin UI to explain access.
+func NewRouteMap(api huma.API, routes *routeperm.Registry) {
+	// GET /rbac/route-map - Permission required by each endpoint
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getRouteMap",
+		Method:      http.MethodGet,
+		Path:        "/v1/rbac/route-map",
+		Summary:     "Get the permission required by each endpoint",
+		Description: "Public routes need no token, authenticated routes accept any signed-in user, permission routes need the listed resource and action and super-admin routes need the super admin role.",
+		Tags:        []string{"RBAC - Permissions"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("permissions", "view"), func(ctx context.Context, in *struct {
+	}) (*struct {
+		Body rbac.RouteMapResponse
+	}, error) {
+		result := response.Success("Route map retrieved successfully", rbac.RouteMap{Routes: routes.Routes()})
+
+		return &struct {
+			Body rbac.RouteMapResponse
+		}{Body: *result}, nil
+	})
+}
+
+// NewConsistency registers the endpoint reporting drift between the
+// permissions declared in code, the database and the route map.
+func NewConsistency(api huma.API, rbacService service.Service, routes *routeperm.Registry) {
+	// GET /rbac/consistency - Compare code, database and route permissions
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getRBACConsistency",
+		Method:      http.MethodGet,
+		Path:        "/v1/rbac/consistency",
+		Summary:     "Check permission consistency",
+		Description: "Super admin only. Lists permissions declared in code but missing from the database, active database permissions no route requires and routes requiring a permission the database lacks, or the super admin role when it is missing or inactive. The same report is logged as a warning at startup.",
+		Tags:        []string{"RBAC - Permissions"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.SuperAdmin, func(ctx context.Context, in *struct {
+	}) (*struct {
+		Body rbac.ConsistencyResponse
+	}, error) {
+		report, err := rbacService.CheckConsistency(ctx, routes.Routes())
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body rbac.ConsistencyResponse
+		}{Body: *response.Success("Permission consistency checked successfully", *report)}, nil
+	})
 }
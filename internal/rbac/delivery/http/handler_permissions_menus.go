@@ -81,7 +81,7 @@ func (h *Handler) GetPermissions(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	search := c.Query("search")
 
-	response, err := h.rbacService.GetPermissions(c.Request.Context(), page, limit, search)
+	response, err := h.rbacService.GetPermissions(c.Request.Context(), page, limit, rbac.PermissionFilter{Search: search})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to get permissions",
@@ -246,7 +246,7 @@ func (h *Handler) DeletePermission(c *gin.Context) {
 		return
 	}
 
-	if err := h.rbacService.DeletePermission(c.Request.Context(), id, deletedBy); err != nil {
+	if err := h.rbacService.DeletePermission(c.Request.Context(), id, deletedBy, false); err != nil {
 		if err.Error() == "permission not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "Permission not found",
@@ -362,7 +362,7 @@ func (h *Handler) GetMenus(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	search := c.Query("search")
 
-	response, err := h.rbacService.GetMenus(c.Request.Context(), page, limit, search)
+	response, err := h.rbacService.GetMenus(c.Request.Context(), page, limit, rbac.MenuFilter{Search: search})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to get menus",
@@ -488,7 +488,7 @@ func (h *Handler) UpdateMenu(c *gin.Context) {
 		return
 	}
 
-	if err := h.rbacService.UpdateMenu(c.Request.Context(), id, &req, updatedBy); err != nil {
+	if _, err := h.rbacService.UpdateMenu(c.Request.Context(), id, &req, updatedBy); err != nil {
 		if err.Error() == "menu not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "Menu not found",
@@ -549,7 +549,7 @@ func (h *Handler) DeleteMenu(c *gin.Context) {
 		return
 	}
 
-	if err := h.rbacService.DeleteMenu(c.Request.Context(), id, deletedBy); err != nil {
+	if err := h.rbacService.DeleteMenu(c.Request.Context(), id, deletedBy, false); err != nil {
 		if err.Error() == "menu not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "Menu not found",
@@ -96,6 +96,13 @@ func (h *Handler) AssignRolesToUser(c *gin.Context) {
 
 	response, err := h.rbacService.AssignRolesToUser(c.Request.Context(), userID, &req, assignedBy)
 	if err != nil {
+		if isForbidden(err) {
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Error:   "Forbidden",
+				Message: err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to assign roles to user",
 			Message: err.Error(),
@@ -137,7 +144,33 @@ func (h *Handler) RemoveRolesFromUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.rbacService.RemoveRolesFromUser(c.Request.Context(), userID, req.RoleIDs); err != nil {
+	// Get remover user ID from context
+	removerID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	removedBy, err := uuid.Parse(removerID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid remover user ID",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.rbacService.RemoveRolesFromUser(c.Request.Context(), userID, req.RoleIDs, removedBy); err != nil {
+		if isForbidden(err) {
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Error:   "Forbidden",
+				Message: err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to remove roles from user",
 			Message: err.Error(),
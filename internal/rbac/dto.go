@@ -1,7 +1,9 @@
 package rbac
 
 import (
+	"backend-service-internpro/internal/pkg/authz"
 	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,57 +11,79 @@ import (
 
 // Role represents a role in the system
 type Role struct {
-	ID          uuid.UUID    `json:"id" doc:"Role ID"`
-	Name        string       `json:"name" doc:"Role name"`
-	Slug        string       `json:"slug" doc:"Role slug"`
-	Description string       `json:"description" doc:"Role description"`
-	IsActive    bool         `json:"is_active" doc:"Role active status"`
-	CreatedAt   time.Time    `json:"created_at" doc:"Role creation date"`
-	UpdatedAt   time.Time    `json:"updated_at" doc:"Role last update date"`
-	Permissions []Permission `json:"permissions,omitempty" doc:"Role permissions"`
-	Menus       []Menu       `json:"menus,omitempty" doc:"Role menus"`
+	ID                      uuid.UUID    `json:"id" doc:"Role ID"`
+	Name                    string       `json:"name" doc:"Role name"`
+	Slug                    string       `json:"slug" doc:"Role slug"`
+	Description             string       `json:"description" doc:"Role description"`
+	IsActive                bool         `json:"is_active" doc:"Role active status"`
+	AssignableBySchoolAdmin bool         `json:"assignable_by_school_admin" doc:"Whether school admins may assign this role"`
+	DefaultMenuID           *uuid.UUID   `json:"default_menu_id,omitempty" doc:"Menu users of this role land on after login"`
+	Priority                int          `json:"priority" doc:"Decides whose landing menu is used when a user has several roles; the highest wins"`
+	CreatedAt               time.Time    `json:"created_at" doc:"Role creation date"`
+	UpdatedAt               time.Time    `json:"updated_at" doc:"Role last update date"`
+	DeletedAt               *time.Time   `json:"deleted_at,omitempty" doc:"When the role was deleted, set only when deleted roles are listed"`
+	Permissions             []Permission `json:"permissions,omitempty" doc:"Role permissions, at most the embed limit"`
+	PermissionsTotal        *int         `json:"permissions_total,omitempty" doc:"Number of role permissions, set when permissions are embedded"`
+	PermissionsLink         string       `json:"permissions_link,omitempty" doc:"Paginated list of the role permissions, set when permissions are embedded"`
+	Menus                   []Menu       `json:"menus,omitempty" doc:"Role menus, at most the embed limit"`
+	MenusTotal              *int         `json:"menus_total,omitempty" doc:"Number of role menus, set when menus are embedded"`
+	MenusLink               string       `json:"menus_link,omitempty" doc:"Paginated list of the role menus, set when menus are embedded"`
+	Truncated               bool         `json:"truncated,omitempty" doc:"Whether an embedded list holds fewer items than its total; page through its link for the rest"`
 }
 
 // Permission represents a permission in the system
 type Permission struct {
-	ID          uuid.UUID `json:"id" doc:"Permission ID"`
-	Name        string    `json:"name" doc:"Permission name"`
-	Slug        string    `json:"slug" doc:"Permission slug"`
-	Resource    string    `json:"resource" doc:"Permission resource"`
-	Action      string    `json:"action" doc:"Permission action"`
-	Description string    `json:"description" doc:"Permission description"`
-	IsActive    bool      `json:"is_active" doc:"Permission active status"`
-	CreatedAt   time.Time `json:"created_at" doc:"Permission creation date"`
-	UpdatedAt   time.Time `json:"updated_at" doc:"Permission last update date"`
+	ID          uuid.UUID  `json:"id" doc:"Permission ID"`
+	Name        string     `json:"name" doc:"Permission name"`
+	Slug        string     `json:"slug" doc:"Permission slug"`
+	Module      string     `json:"module" doc:"Module the permission is grouped under"`
+	Resource    string     `json:"resource" doc:"Permission resource"`
+	Action      string     `json:"action" doc:"Permission action"`
+	Description string     `json:"description" doc:"Permission description"`
+	IsActive    bool       `json:"is_active" doc:"Permission active status"`
+	Effect      string     `json:"effect,omitempty" enum:"allow,deny" doc:"Whether the role allows or denies the permission, only set when listed for a role"`
+	CreatedAt   time.Time  `json:"created_at" doc:"Permission creation date"`
+	UpdatedAt   time.Time  `json:"updated_at" doc:"Permission last update date"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" doc:"When the permission was deleted, set only when deleted permissions are listed"`
 }
 
 // Menu represents a menu in the system
 type Menu struct {
-	ID        uuid.UUID  `json:"id" doc:"Menu ID"`
-	Name      string     `json:"name" doc:"Menu name"`
-	Slug      string     `json:"slug" doc:"Menu slug"`
-	URL       string     `json:"url" doc:"Menu URL"`
-	Icon      string     `json:"icon" doc:"Menu icon"`
-	ParentID  *uuid.UUID `json:"parent_id" doc:"Parent menu ID"`
-	SortOrder int        `json:"sort_order" doc:"Menu sort order"`
-	IsActive  bool       `json:"is_active" doc:"Menu active status"`
-	CreatedAt time.Time  `json:"created_at" doc:"Menu creation date"`
-	UpdatedAt time.Time  `json:"updated_at" doc:"Menu last update date"`
-	Children  []Menu     `json:"children,omitempty" doc:"Child menus"`
+	ID        uuid.UUID   `json:"id" doc:"Menu ID"`
+	Name      string      `json:"name" doc:"Menu name"`
+	Slug      string      `json:"slug" doc:"Menu slug"`
+	URL       string      `json:"url" doc:"Menu URL"`
+	Icon      string      `json:"icon" doc:"Menu icon"`
+	ParentID  *uuid.UUID  `json:"parent_id" doc:"Parent menu ID"`
+	SortOrder int         `json:"sort_order" doc:"Menu sort order"`
+	IsActive  bool        `json:"is_active" doc:"Menu active status"`
+	CreatedAt time.Time   `json:"created_at" doc:"Menu creation date"`
+	UpdatedAt time.Time   `json:"updated_at" doc:"Menu last update date"`
+	Children  []Menu      `json:"children,omitempty" doc:"Child menus"`
+	Access    *MenuAccess `json:"access,omitempty" doc:"What the role may do on the page, set when listing the menus of a role"`
+}
+
+// MenuAccess holds a role's CRUD flags for a menu
+type MenuAccess struct {
+	CanView   bool `json:"can_view" doc:"Can view permission"`
+	CanCreate bool `json:"can_create" doc:"Can create permission"`
+	CanEdit   bool `json:"can_edit" doc:"Can edit permission"`
+	CanDelete bool `json:"can_delete" doc:"Can delete permission"`
 }
 
 // RoleMenu represents role-menu relationship with permissions
 type RoleMenu struct {
-	ID        uuid.UUID `json:"id" doc:"Role-Menu ID"`
-	RoleID    uuid.UUID `json:"role_id" doc:"Role ID"`
-	MenuID    uuid.UUID `json:"menu_id" doc:"Menu ID"`
-	CanView   bool      `json:"can_view" doc:"Can view permission"`
-	CanCreate bool      `json:"can_create" doc:"Can create permission"`
-	CanEdit   bool      `json:"can_edit" doc:"Can edit permission"`
-	CanDelete bool      `json:"can_delete" doc:"Can delete permission"`
-	Menu      Menu      `json:"menu" doc:"Menu details"`
-	CreatedAt time.Time `json:"created_at" doc:"Assignment creation date"`
-	UpdatedAt time.Time `json:"updated_at" doc:"Assignment last update date"`
+	ID        uuid.UUID   `json:"id" doc:"Role-Menu ID"`
+	RoleID    uuid.UUID   `json:"role_id" doc:"Role ID; for a user's menus, the first role granting it"`
+	RoleIDs   []uuid.UUID `json:"role_ids,omitempty" doc:"Every role granting the menu, set on a user's menus"`
+	MenuID    uuid.UUID   `json:"menu_id" doc:"Menu ID"`
+	CanView   bool        `json:"can_view" doc:"Can view permission"`
+	CanCreate bool        `json:"can_create" doc:"Can create permission"`
+	CanEdit   bool        `json:"can_edit" doc:"Can edit permission"`
+	CanDelete bool        `json:"can_delete" doc:"Can delete permission"`
+	Menu      Menu        `json:"menu" doc:"Menu details"`
+	CreatedAt time.Time   `json:"created_at" doc:"Assignment creation date"`
+	UpdatedAt time.Time   `json:"updated_at" doc:"Assignment last update date"`
 }
 
 // UserRole represents user-role relationship
@@ -69,14 +93,146 @@ type UserRole struct {
 	RoleID     uuid.UUID `json:"role_id" doc:"Role ID"`
 	Role       Role      `json:"role" doc:"Role details"`
 	AssignedAt time.Time `json:"assigned_at" doc:"Role assignment date"`
+	RoleValidity
+}
+
+// RoleValidity is when a role assignment is in effect. A missing bound
+// leaves that end open, so an assignment without either is permanent.
+type RoleValidity struct {
+	ValidFrom  *time.Time `json:"valid_from,omitempty" doc:"When the assignment takes effect; immediately when absent"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" doc:"When the assignment ends; never when absent"`
+}
+
+// IsZero reports whether neither bound is given
+func (v RoleValidity) IsZero() bool {
+	return v.ValidFrom == nil && v.ValidUntil == nil
+}
+
+// ExpiringUserRole is a role assignment that is about to end
+type ExpiringUserRole struct {
+	UserID     uuid.UUID `json:"user_id" doc:"User ID"`
+	Username   string    `json:"username" doc:"Username"`
+	Email      string    `json:"email" doc:"Email address"`
+	Fullname   string    `json:"fullname" doc:"Full name"`
+	RoleID     uuid.UUID `json:"role_id" doc:"Role ID"`
+	RoleName   string    `json:"role_name" doc:"Role name"`
+	RoleSlug   string    `json:"role_slug" doc:"Role slug"`
+	ValidUntil time.Time `json:"valid_until" doc:"When the assignment ends"`
+}
+
+type ExpiringUserRoleListData struct {
+	Data []ExpiringUserRole `json:"data"`
+	Meta RBACMetadata       `json:"meta"`
+}
+
+type ExpiringUserRoleListResponse = response.ApiResponse
+
+// PermissionGrant is one path from a user to a permission: the user's role
+// assignment, the role and the role's link to the permission
+type PermissionGrant struct {
+	UserRole       PermissionGrantUserRole       `json:"user_role" doc:"The user_roles row giving the user the role"`
+	Role           PermissionGrantRole           `json:"role" doc:"The role"`
+	RolePermission PermissionGrantRolePermission `json:"role_permission" doc:"The role_permissions row linking the role to the permission"`
 }
 
+type PermissionGrantUserRole struct {
+	ID         uuid.UUID  `json:"id" doc:"User role ID"`
+	AssignedAt time.Time  `json:"assigned_at" doc:"When the role was assigned"`
+	AssignedBy *uuid.UUID `json:"assigned_by,omitempty" doc:"User who assigned the role"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty" doc:"When the assignment starts"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" doc:"When the assignment ends"`
+}
+
+type PermissionGrantRole struct {
+	ID   uuid.UUID `json:"id" doc:"Role ID"`
+	Name string    `json:"name" doc:"Role name"`
+	Slug string    `json:"slug" doc:"Role slug"`
+}
+
+type PermissionGrantRolePermission struct {
+	ID             uuid.UUID  `json:"id" doc:"Role permission ID"`
+	PermissionID   uuid.UUID  `json:"permission_id" doc:"Permission ID"`
+	PermissionSlug string     `json:"permission_slug" doc:"Permission slug"`
+	Effect         string     `json:"effect" enum:"allow,deny" doc:"Whether the role allows or denies the permission"`
+	CreatedAt      time.Time  `json:"created_at" doc:"When the role got the permission"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty" doc:"User who gave the role the permission"`
+}
+
+// PermissionExplanation tells whether a user holds a permission and through
+// which roles
+type PermissionExplanation struct {
+	UserID   uuid.UUID         `json:"user_id" doc:"User ID"`
+	Resource string            `json:"resource" doc:"Permission resource"`
+	Action   string            `json:"action" doc:"Permission action"`
+	Granted  bool              `json:"granted" doc:"Whether the user holds the permission; a deny on any path wins over every allow"`
+	Grants   []PermissionGrant `json:"grants" doc:"Every path in effect from the user to the permission, allows and denies, oldest assignment first"`
+}
+
+type PermissionExplanationResponse = response.ApiResponse
+
+// RoleUser is a user holding a role
+type RoleUser struct {
+	UserID     uuid.UUID  `json:"user_id" doc:"User ID"`
+	Username   string     `json:"username" doc:"Username"`
+	Email      string     `json:"email" doc:"Email address"`
+	Fullname   string     `json:"fullname" doc:"Full name"`
+	AssignedAt time.Time  `json:"assigned_at" doc:"Role assignment date"`
+	AssignedBy *uuid.UUID `json:"assigned_by,omitempty" doc:"User who assigned the role"`
+}
+
+type RoleUserListData struct {
+	Data []RoleUser   `json:"data"`
+	Meta RBACMetadata `json:"meta"`
+}
+
+type RoleUserListResponse = response.ApiResponse
+
 // RBACMetadata represents pagination metadata for RBAC responses
 type RBACMetadata struct {
-	Page       int `json:"page"`
-	Limit      int `json:"limit"`
-	TotalPages int `json:"total_pages"`
-	TotalItems int `json:"total_items"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	TotalPages int    `json:"total_pages"`
+	TotalItems int    `json:"total_items"`
+	SortBy     string `json:"sort_by,omitempty"`
+	Order      string `json:"order,omitempty"`
+}
+
+// Sort directions of a listing
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// ListSort is the field and direction a listing is sorted by
+type ListSort struct {
+	SortBy string
+	Order  string
+}
+
+// RoleFilter narrows a role listing; empty fields do not filter
+type RoleFilter struct {
+	Search         string
+	IsActive       *bool
+	IncludeDeleted bool
+	ListSort
+}
+
+// PermissionFilter narrows a permission listing; empty fields do not filter
+type PermissionFilter struct {
+	Search         string
+	IsActive       *bool
+	Resource       string
+	Action         string
+	IncludeDeleted bool
+	ListSort
+}
+
+// MenuFilter narrows a menu listing; empty fields do not filter
+type MenuFilter struct {
+	Search   string
+	IsActive *bool
+	ParentID *uuid.UUID
+	ListSort
 }
 
 // Role Request/Response DTOs
@@ -100,17 +256,23 @@ type RoleQueryParams struct {
 type RoleResponse = response.ApiResponse
 
 type CreateRoleRequest struct {
-	Name        string `json:"name" form:"name" minLength:"1" maxLength:"100" doc:"Role name"`
-	Slug        string `json:"slug" form:"slug" minLength:"1" maxLength:"100" doc:"Role slug"`
-	Description string `json:"description" form:"description" maxLength:"1000" doc:"Role description"`
-	IsActive    *bool  `json:"is_active" form:"is_active" doc:"Role active status"`
+	Name                    string     `json:"name" form:"name" minLength:"1" maxLength:"100" example:"Guru Pembimbing" doc:"Role name"`
+	Slug                    string     `json:"slug" form:"slug" minLength:"1" maxLength:"100" example:"guru-pembimbing" doc:"Role slug"`
+	Description             string     `json:"description" form:"description" maxLength:"1000" example:"Membimbing siswa selama magang" doc:"Role description"`
+	IsActive                *bool      `json:"is_active" form:"is_active" example:"true" doc:"Role active status"`
+	AssignableBySchoolAdmin bool       `json:"assignable_by_school_admin,omitempty" form:"assignable_by_school_admin" example:"true" doc:"Whether school admins may assign this role"`
+	DefaultMenuID           *uuid.UUID `json:"default_menu_id,omitempty" form:"default_menu_id" doc:"Active menu users of this role land on after login; it is assigned to the new role with view access"`
+	Priority                int        `json:"priority,omitempty" form:"priority" example:"10" doc:"Decides whose landing menu is used when a user has several roles; the highest wins"`
 }
 
 type UpdateRoleRequest struct {
-	Name        *string `json:"name" form:"name" minLength:"1" maxLength:"100" doc:"Role name"`
-	Slug        *string `json:"slug" form:"slug" minLength:"1" maxLength:"100" doc:"Role slug"`
-	Description *string `json:"description" form:"description" maxLength:"1000" doc:"Role description"`
-	IsActive    *bool   `json:"is_active" form:"is_active" doc:"Role active status"`
+	Name                    *string    `json:"name" form:"name" minLength:"1" maxLength:"100" doc:"Role name"`
+	Slug                    *string    `json:"slug" form:"slug" minLength:"1" maxLength:"100" doc:"Role slug"`
+	Description             *string    `json:"description" form:"description" maxLength:"1000" doc:"Role description"`
+	IsActive                *bool      `json:"is_active" form:"is_active" doc:"Role active status"`
+	AssignableBySchoolAdmin *bool      `json:"assignable_by_school_admin,omitempty" form:"assignable_by_school_admin" doc:"Whether school admins may assign this role"`
+	DefaultMenuID           *uuid.UUID `json:"default_menu_id,omitempty" form:"default_menu_id" doc:"Active menu assigned to the role that its users land on after login; the nil UUID clears it"`
+	Priority                *int       `json:"priority,omitempty" form:"priority" doc:"Decides whose landing menu is used when a user has several roles; the highest wins"`
 }
 
 type CreateRoleData struct {
@@ -120,7 +282,8 @@ type CreateRoleData struct {
 type CreateRoleResponse = response.ApiResponse
 
 type AssignRolePermissionsRequest struct {
-	PermissionIDs []uuid.UUID `json:"permission_ids" doc:"List of permission IDs to assign"`
+	PermissionIDs       []uuid.UUID `json:"permission_ids" doc:"List of permission IDs to assign"`
+	DeniedPermissionIDs []uuid.UUID `json:"denied_permission_ids,omitempty" doc:"Permission IDs the role explicitly denies; a deny overrides allows from the user's other roles"`
 }
 
 // AssignPermissionsToRoleRequest represents request to assign permissions to role
@@ -148,14 +311,21 @@ type PaginatedPermissionsResponse = response.ApiResponse
 type PermissionQueryParams struct {
 	Page   int    `json:"page" minimum:"1" default:"1" doc:"Page number"`
 	Limit  int    `json:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
-	Search string `json:"search" doc:"Search by name, resource, or action"`
+	Search string `json:"search" doc:"Search by name, module, resource, or action"`
 }
 
 type PermissionResponse = response.ApiResponse
 
+// GroupedPermissionsData maps each module to its permissions, ordered by
+// resource then action
+type GroupedPermissionsData map[string][]Permission
+
+type GroupedPermissionsResponse = response.ApiResponse
+
 type CreatePermissionRequest struct {
 	Name        string `json:"name" form:"name" minLength:"1" maxLength:"100" doc:"Permission name"`
 	Slug        string `json:"slug" form:"slug" minLength:"1" maxLength:"100" doc:"Permission slug"`
+	Module      string `json:"module,omitempty" form:"module" maxLength:"100" doc:"Module to group the permission under; defaults to the resource"`
 	Resource    string `json:"resource" form:"resource" minLength:"1" maxLength:"100" doc:"Permission resource"`
 	Action      string `json:"action" form:"action" minLength:"1" maxLength:"50" doc:"Permission action"`
 	Description string `json:"description" form:"description" maxLength:"1000" doc:"Permission description"`
@@ -165,6 +335,7 @@ type CreatePermissionRequest struct {
 type UpdatePermissionRequest struct {
 	Name        *string `json:"name" form:"name" minLength:"1" maxLength:"100" doc:"Permission name"`
 	Slug        *string `json:"slug" form:"slug" minLength:"1" maxLength:"100" doc:"Permission slug"`
+	Module      *string `json:"module" form:"module" minLength:"1" maxLength:"100" doc:"Module to group the permission under"`
 	Resource    *string `json:"resource" form:"resource" minLength:"1" maxLength:"100" doc:"Permission resource"`
 	Action      *string `json:"action" form:"action" minLength:"1" maxLength:"50" doc:"Permission action"`
 	Description *string `json:"description" form:"description" maxLength:"1000" doc:"Permission description"`
@@ -177,6 +348,58 @@ type CreatePermissionData struct {
 
 type CreatePermissionResponse = response.ApiResponse
 
+// BulkCreatePermissionsRequest creates several permissions at once, e.g.
+// those of a new module
+type BulkCreatePermissionsRequest struct {
+	Permissions []CreatePermissionRequest `json:"permissions" minItems:"1" maxItems:"100" doc:"Permissions to create"`
+}
+
+// BulkPermissionResult is a permission created by a bulk request
+type BulkPermissionResult struct {
+	Index int       `json:"index" doc:"Position of the permission in the request"`
+	ID    uuid.UUID `json:"id" doc:"Created permission ID"`
+	Slug  string    `json:"slug" doc:"Permission slug"`
+}
+
+type BulkCreatePermissionsData struct {
+	Results []BulkPermissionResult `json:"results" doc:"Created permissions, in request order"`
+}
+
+type BulkCreatePermissionsResponse = response.ApiResponse
+
+// GeneratePermissionsRequest creates the permissions of a resource with
+// derived names and slugs, e.g. "View Students" and view-students
+type GeneratePermissionsRequest struct {
+	Resource string     `json:"resource" minLength:"1" maxLength:"100" doc:"Resource to create permissions for, e.g. students"`
+	Actions  []string   `json:"actions,omitempty" maxItems:"20" doc:"Actions to create permissions for; defaults to view, create, edit and delete"`
+	RoleID   *uuid.UUID `json:"role_id,omitempty" doc:"Role to grant the created permissions to"`
+}
+
+type GeneratePermissionsData struct {
+	Created []Permission `json:"created" doc:"Permissions created"`
+	Skipped []Permission `json:"skipped" doc:"Permissions that already existed and were left as they are"`
+}
+
+type GeneratePermissionsResponse = response.ApiResponse
+
+// BulkItemError is why one item of a bulk request was rejected
+type BulkItemError struct {
+	Index   int
+	Field   string
+	Value   string
+	Message string
+}
+
+// BulkError rejects a bulk request for the listed items; none of the
+// request was written
+type BulkError struct {
+	Items []BulkItemError
+}
+
+func (e *BulkError) Error() string {
+	return "some items are invalid"
+}
+
 // Menu Request/Response DTOs
 type MenuListData struct {
 	Data []Menu       `json:"data"`
@@ -212,21 +435,100 @@ type UpdateMenuRequest struct {
 	Slug      *string    `json:"slug" form:"slug" minLength:"1" maxLength:"100" doc:"Menu slug"`
 	URL       *string    `json:"url" form:"url" maxLength:"255" doc:"Menu URL"`
 	Icon      *string    `json:"icon" form:"icon" maxLength:"100" doc:"Menu icon"`
-	ParentID  *uuid.UUID `json:"parent_id" form:"parent_id" doc:"Parent menu ID"`
+	ParentID  *uuid.UUID `json:"parent_id" form:"parent_id" doc:"Parent menu ID; the nil UUID moves the menu to the top level"`
 	SortOrder *int       `json:"sort_order" form:"sort_order" doc:"Menu sort order"`
 	IsActive  *bool      `json:"is_active" form:"is_active" doc:"Menu active status"`
 }
 
 type CreateMenuData struct {
-	ID uuid.UUID `json:"id" doc:"Created menu ID"`
+	ID       uuid.UUID `json:"id" doc:"Created menu ID"`
+	Warnings []string  `json:"warnings,omitempty" doc:"Non-blocking issues, e.g. a URL shared with other menus"`
 }
 
 type CreateMenuResponse = response.ApiResponse
 
+// UpdateMenuData is the updated menu with any non-blocking warnings
+type UpdateMenuData struct {
+	Menu
+	Warnings []string `json:"warnings,omitempty" doc:"Non-blocking issues, e.g. a URL shared with other menus"`
+}
+
+// ReorderMenuItem places one menu in a reorder request
+type ReorderMenuItem struct {
+	MenuID    uuid.UUID  `json:"menu_id" doc:"Menu ID"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" doc:"New parent menu ID; omit it or send the nil UUID for the top level"`
+	SortOrder int        `json:"sort_order" doc:"New sort order among the menu's siblings"`
+}
+
+// ReorderMenusRequest moves and reorders several menus at once
+type ReorderMenusRequest struct {
+	Menus []ReorderMenuItem `json:"menus" minItems:"1" maxItems:"500" doc:"Menus to place, each listed once"`
+}
+
+// MenuReportItem identifies a menu listed in the navigation report
+type MenuReportItem struct {
+	ID       uuid.UUID  `json:"id" doc:"Menu ID"`
+	Name     string     `json:"name" doc:"Menu name"`
+	Slug     string     `json:"slug" doc:"Menu slug"`
+	URL      string     `json:"url" doc:"Menu URL"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty" doc:"Parent menu ID"`
+}
+
+// MenuURLGroup lists the active menus sharing one URL
+type MenuURLGroup struct {
+	URL   string           `json:"url" doc:"Shared URL"`
+	Menus []MenuReportItem `json:"menus" doc:"Menus using the URL"`
+}
+
+// MenuReport lists active menus that need cleaning up
+type MenuReport struct {
+	EmptyURL       []MenuReportItem `json:"empty_url" doc:"Menus without a URL and without active children"`
+	DuplicateURLs  []MenuURLGroup   `json:"duplicate_urls" doc:"URLs used by more than one menu"`
+	InactiveParent []MenuReportItem `json:"inactive_parent" doc:"Menus whose parent is inactive or deleted"`
+	Unassigned     []MenuReportItem `json:"unassigned" doc:"Menus not assigned to any role"`
+}
+
+// MenuReportResponse represents the navigation report response
+type MenuReportResponse = response.ApiResponse
+
+// RouteMap lists the permission each endpoint requires
+type RouteMap struct {
+	Routes []routeperm.Route `json:"routes" doc:"Endpoints ordered by path, then method"`
+}
+
+// RouteMapResponse represents the route map response
+type RouteMapResponse = response.ApiResponse
+
+// ConsistencyReport lists the drift between the permissions declared in
+// code, the permissions table and the permissions routes require
+type ConsistencyReport struct {
+	MissingInDatabase []authz.PermissionKey `json:"missing_in_database" doc:"Permissions declared in code but absent or inactive in the database"`
+	UnusedByRoutes    []authz.PermissionKey `json:"unused_by_routes" doc:"Active database permissions no route requires"`
+	UnknownRoutes     []routeperm.Route     `json:"unknown_routes" doc:"Routes requiring a permission, or the super admin role, absent or inactive in the database"`
+}
+
+// Consistent reports whether no drift was found
+func (r ConsistencyReport) Consistent() bool {
+	return len(r.MissingInDatabase) == 0 && len(r.UnusedByRoutes) == 0 && len(r.UnknownRoutes) == 0
+}
+
+// ConsistencyResponse represents the permission consistency response
+type ConsistencyResponse = response.ApiResponse
+
 type AssignRoleMenusRequest struct {
 	MenuPermissions []MenuPermissionRequest `json:"menu_permissions" doc:"List of menu permissions to assign"`
 }
 
+// RemoveRolePermissionsRequest lists the permissions to detach from a role
+type RemoveRolePermissionsRequest struct {
+	PermissionIDs []uuid.UUID `json:"permission_ids" minItems:"1" doc:"Permissions to detach from the role, whether allowed or denied"`
+}
+
+// RemoveRoleMenusRequest lists the menus to take away from a role
+type RemoveRoleMenusRequest struct {
+	MenuIDs []uuid.UUID `json:"menu_ids" minItems:"1" doc:"Menus to take away from the role"`
+}
+
 type MenuPermissionRequest struct {
 	MenuID    uuid.UUID `json:"menu_id" doc:"Menu ID"`
 	CanView   bool      `json:"can_view" doc:"Can view permission"`
@@ -244,15 +546,209 @@ type UserRoleListData struct {
 type UserRoleListResponse = response.ApiResponse
 
 type AssignUserRolesRequest struct {
-	RoleIDs []uuid.UUID `json:"role_ids" doc:"List of role IDs to assign"`
+	RoleIDs []uuid.UUID `json:"role_ids" doc:"Roles to assign; when replacing, the complete list of roles the user should have"`
+	// The validity applies to every role in RoleIDs. Roles the user already
+	// holds keep theirs when neither bound is given.
+	RoleValidity
 }
 
-type UserRoleData struct {
-	ID uuid.UUID `json:"id" doc:"Assignment ID"`
+// UserRoleChanges reports how an assignment changed a user's roles. Kept
+// assignments keep their assigned_at and assigned_by and take the validity
+// of the request when it gives one.
+type UserRoleChanges struct {
+	Added   []uuid.UUID `json:"added" doc:"Roles newly assigned"`
+	Removed []uuid.UUID `json:"removed" doc:"Roles no longer assigned"`
+	Kept    []uuid.UUID `json:"kept" doc:"Roles that were already assigned"`
 }
 
 type UserRoleResponse = response.ApiResponse
 
+type RemoveUserRolesRequest struct {
+	RoleIDs []uuid.UUID `json:"role_ids" minItems:"1" doc:"Roles to take away from the user"`
+}
+
+// UserRolesData is a user's roles after changing them, so clients need not
+// fetch them again
+type UserRolesData struct {
+	Changes *UserRoleChanges `json:"changes,omitempty" doc:"How assigning changed the roles; absent when removing"`
+	Roles   []UserRole       `json:"roles" doc:"Roles the user has now"`
+}
+
+// RoleRestoreData reports what was reinstated when restoring a role;
+// assignments whose user, permission or menu no longer exists are skipped
+type RoleRestoreData struct {
+	ID                  uuid.UUID `json:"id" doc:"Restored role ID"`
+	RestoredUsers       int       `json:"restored_users" doc:"User assignments reinstated"`
+	SkippedUsers        int       `json:"skipped_users" doc:"User assignments skipped because the user no longer exists"`
+	RestoredPermissions int       `json:"restored_permissions" doc:"Permissions reinstated"`
+	SkippedPermissions  int       `json:"skipped_permissions" doc:"Permissions skipped because they were deleted"`
+	RestoredMenus       int       `json:"restored_menus" doc:"Menus reinstated"`
+	SkippedMenus        int       `json:"skipped_menus" doc:"Menus skipped because they were deleted"`
+}
+
+type RoleRestoreResponse = response.ApiResponse
+
+// PruneOrphansData counts the junction rows removed because the role, menu,
+// permission or user they reference is gone
+type PruneOrphansData struct {
+	RoleMenus       int64 `json:"role_menus" doc:"Role menu rows removed"`
+	RolePermissions int64 `json:"role_permissions" doc:"Role permission rows removed"`
+	UserRoles       int64 `json:"user_roles" doc:"User role rows removed"`
+}
+
+// Total is the number of rows removed across all tables
+func (d PruneOrphansData) Total() int64 {
+	return d.RoleMenus + d.RolePermissions + d.UserRoles
+}
+
+type PruneOrphansResponse = response.ApiResponse
+
+// RBACDocumentVersion is the version of the export format this server
+// writes and reads
+const RBACDocumentVersion = 1
+
+// RBACDocument is the RBAC configuration as exported, referring to roles,
+// permissions and menus by slug so it can be imported into another
+// environment
+type RBACDocument struct {
+	Version         int                          `json:"version" minimum:"1" maximum:"1" doc:"Format version"`
+	ExportedAt      *time.Time                   `json:"exported_at,omitempty" doc:"When the document was exported"`
+	Permissions     []RBACDocumentPermission     `json:"permissions" maxItems:"2000" doc:"Permissions"`
+	Menus           []RBACDocumentMenu           `json:"menus" maxItems:"2000" doc:"Menus"`
+	Roles           []RBACDocumentRole           `json:"roles" maxItems:"500" doc:"Roles"`
+	RolePermissions []RBACDocumentRolePermission `json:"role_permissions" maxItems:"20000" doc:"Permissions of the listed roles"`
+	RoleMenus       []RBACDocumentRoleMenu       `json:"role_menus" maxItems:"20000" doc:"Menus of the listed roles"`
+}
+
+type RBACDocumentPermission struct {
+	Slug        string `json:"slug" minLength:"1" maxLength:"100" doc:"Permission slug"`
+	Name        string `json:"name" minLength:"1" maxLength:"100" doc:"Permission name"`
+	Module      string `json:"module" maxLength:"100" doc:"Module the permission is grouped under; defaults to the resource"`
+	Resource    string `json:"resource" minLength:"1" maxLength:"100" doc:"Permission resource"`
+	Action      string `json:"action" minLength:"1" maxLength:"50" doc:"Permission action"`
+	Description string `json:"description" maxLength:"1000" doc:"Permission description"`
+	IsActive    bool   `json:"is_active" doc:"Permission active status"`
+}
+
+type RBACDocumentMenu struct {
+	Slug       string `json:"slug" minLength:"1" maxLength:"100" doc:"Menu slug"`
+	Name       string `json:"name" minLength:"1" maxLength:"100" doc:"Menu name"`
+	URL        string `json:"url" maxLength:"255" doc:"Menu URL"`
+	Icon       string `json:"icon" maxLength:"100" doc:"Menu icon"`
+	ParentSlug string `json:"parent_slug,omitempty" maxLength:"100" doc:"Slug of the parent menu"`
+	SortOrder  int    `json:"sort_order" doc:"Menu sort order"`
+	IsActive   bool   `json:"is_active" doc:"Menu active status"`
+}
+
+type RBACDocumentRole struct {
+	Slug                    string `json:"slug" minLength:"1" maxLength:"100" doc:"Role slug"`
+	Name                    string `json:"name" minLength:"1" maxLength:"100" doc:"Role name"`
+	Description             string `json:"description" maxLength:"1000" doc:"Role description"`
+	IsActive                bool   `json:"is_active" doc:"Role active status"`
+	AssignableBySchoolAdmin bool   `json:"assignable_by_school_admin" doc:"Whether school admins may assign this role"`
+	DefaultMenuSlug         string `json:"default_menu_slug,omitempty" maxLength:"100" doc:"Slug of the menu users of this role land on after login"`
+	Priority                int    `json:"priority" doc:"Decides whose landing menu is used when a user has several roles; the highest wins"`
+}
+
+type RBACDocumentRolePermission struct {
+	RoleSlug       string `json:"role_slug" minLength:"1" maxLength:"100" doc:"Role slug"`
+	PermissionSlug string `json:"permission_slug" minLength:"1" maxLength:"100" doc:"Permission slug"`
+	Effect         string `json:"effect" enum:"allow,deny" doc:"Whether the role allows or denies the permission"`
+}
+
+type RBACDocumentRoleMenu struct {
+	RoleSlug  string `json:"role_slug" minLength:"1" maxLength:"100" doc:"Role slug"`
+	MenuSlug  string `json:"menu_slug" minLength:"1" maxLength:"100" doc:"Menu slug"`
+	CanView   bool   `json:"can_view" doc:"Can view the menu"`
+	CanCreate bool   `json:"can_create" doc:"Can create in the menu"`
+	CanEdit   bool   `json:"can_edit" doc:"Can edit in the menu"`
+	CanDelete bool   `json:"can_delete" doc:"Can delete in the menu"`
+}
+
+// RBACImportChanges is what an import does to one kind of item. Roles,
+// permissions and menus are named by slug, links as role_slug/slug.
+type RBACImportChanges struct {
+	Created   []string `json:"created" doc:"Items created"`
+	Updated   []string `json:"updated" doc:"Items whose fields changed"`
+	Removed   []string `json:"removed,omitempty" doc:"Links of listed roles the document leaves out, so they are removed"`
+	Unchanged int      `json:"unchanged" doc:"Number of items already as in the document"`
+}
+
+// RBACImportReport is what an import did, or with dry_run would do
+type RBACImportReport struct {
+	DryRun          bool              `json:"dry_run" doc:"Whether nothing was written"`
+	Permissions     RBACImportChanges `json:"permissions"`
+	Menus           RBACImportChanges `json:"menus"`
+	Roles           RBACImportChanges `json:"roles"`
+	RolePermissions RBACImportChanges `json:"role_permissions"`
+	RoleMenus       RBACImportChanges `json:"role_menus"`
+}
+
+type RBACImportResponse = response.ApiResponse
+
+// ImportIssue is why one item of an imported document was rejected
+type ImportIssue struct {
+	// Location points at the item, like permissions[3].slug
+	Location string
+	Value    string
+	Message  string
+	// Conflict marks an item clashing with the current configuration
+	// rather than an invalid document
+	Conflict bool
+}
+
+// ImportError rejects an import for the listed issues; nothing was written
+type ImportError struct {
+	Issues []ImportIssue
+}
+
+func (e *ImportError) Error() string {
+	return "the document cannot be imported"
+}
+
+// Conflict reports whether any issue clashes with the current configuration
+func (e *ImportError) Conflict() bool {
+	for _, issue := range e.Issues {
+		if issue.Conflict {
+			return true
+		}
+	}
+	return false
+}
+
+// Role claim invalidation scopes
+const (
+	InvalidateScopeAll  = "all"
+	InvalidateScopeUser = "user"
+	InvalidateScopeRole = "role"
+)
+
+// InvalidateRoleClaimsRequest narrows an invalidation to one user or one
+// role; without either it applies to everyone
+type InvalidateRoleClaimsRequest struct {
+	UserID *uuid.UUID `json:"user_id,omitempty" doc:"Only distrust the roles claimed by this user's tokens"`
+	RoleID *uuid.UUID `json:"role_id,omitempty" doc:"Only distrust claims about holding this role"`
+}
+
+// InvalidateRoleClaimsData describes an invalidation
+type InvalidateRoleClaimsData struct {
+	Scope         string     `json:"scope" enum:"all,user,role" doc:"What the invalidation applied to"`
+	UserID        *uuid.UUID `json:"user_id,omitempty" doc:"User whose claims were invalidated"`
+	RoleID        *uuid.UUID `json:"role_id,omitempty" doc:"Role whose claims were invalidated"`
+	RoleSlug      string     `json:"role_slug,omitempty" doc:"Slug of that role"`
+	InvalidatedAt time.Time  `json:"invalidated_at" doc:"Claims in access tokens issued until then are checked against the database"`
+}
+
+type InvalidateRoleClaimsResponse = response.ApiResponse
+
+// RolesChangedEvent describes roles added to or removed from a user
+type RolesChangedEvent struct {
+	UserID    uuid.UUID
+	Added     []string
+	Removed   []string
+	ChangedBy uuid.UUID
+}
+
 // Menu Tree Response for hierarchical menu structure
 type MenuTreeData struct {
 	Data []Menu `json:"data"`
@@ -267,5 +763,59 @@ type UserMenuData struct {
 
 type UserMenuResponse = response.ApiResponse
 
+// Landing sources
+const (
+	LandingRoleDefault     = "role_default"
+	LandingFirstAccessible = "first_accessible"
+)
+
+// Landing is the page a user opens first after login
+type Landing struct {
+	MenuID uuid.UUID  `json:"menu_id" doc:"Menu ID"`
+	Name   string     `json:"name" doc:"Menu name"`
+	Slug   string     `json:"slug" doc:"Menu slug"`
+	URL    string     `json:"url" doc:"Menu URL"`
+	RoleID *uuid.UUID `json:"role_id,omitempty" doc:"Role whose default menu this is, unset for the fallback"`
+	Source string     `json:"source" enum:"role_default,first_accessible" doc:"Whether the menu is a role's default or the first accessible menu"`
+}
+
+// LandingResponse represents the landing menu response
+type LandingResponse = response.ApiResponse
+
 // Basic Response for operations that don't return data
 type BasicResponse = response.ApiResponse
+
+// CheckPermissionRequest asks whether a user holds a permission
+type CheckPermissionRequest struct {
+	UserID   uuid.UUID `json:"user_id" doc:"User to check"`
+	Resource string    `json:"resource" minLength:"1" doc:"Permission resource"`
+	Action   string    `json:"action" minLength:"1" doc:"Permission action"`
+}
+
+// CheckPermissionData answers a CheckPermissionRequest
+type CheckPermissionData struct {
+	HasPermission bool `json:"has_permission" doc:"Whether the user holds the permission"`
+}
+
+type CheckPermissionResponse = response.ApiResponse
+
+// CheckRoleRequest asks whether a user holds a role
+type CheckRoleRequest struct {
+	UserID   uuid.UUID `json:"user_id" doc:"User to check"`
+	RoleSlug string    `json:"role_slug" minLength:"1" doc:"Slug of the role"`
+}
+
+// CheckRoleData answers a CheckRoleRequest
+type CheckRoleData struct {
+	HasRole bool `json:"has_role" doc:"Whether the user holds the role"`
+}
+
+type CheckRoleResponse = response.ApiResponse
+
+// CheckCountsData counts the authorization checks served, keyed by
+// "<permission|role>:<allowed|denied>"
+type CheckCountsData struct {
+	Counts map[string]int64 `json:"counts" doc:"Checks served by kind and result, e.g. permission:denied"`
+}
+
+type CheckCountsResponse = response.ApiResponse
@@ -1,6 +1,7 @@
 package rbac
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,17 +9,20 @@ import (
 
 // RoleEntity represents the role entity for database operations
 type RoleEntity struct {
-	ID          uuid.UUID `gorm:"type:char(36);primaryKey"`
-	Name        string    `gorm:"size:100;not null;uniqueIndex"`
-	Slug        string    `gorm:"size:100;not null;uniqueIndex"`
-	Description string    `gorm:"type:text"`
-	IsActive    bool      `gorm:"default:true"`
-	CreatedAt   time.Time
-	CreatedBy   *uuid.UUID `gorm:"type:char(36)"`
-	UpdatedAt   time.Time
-	UpdatedBy   *uuid.UUID `gorm:"type:char(36)"`
-	DeletedAt   *time.Time `gorm:"index"`
-	DeletedBy   *uuid.UUID `gorm:"type:char(36)"`
+	ID                      uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	Name                    string     `gorm:"size:100;not null;uniqueIndex"`
+	Slug                    string     `gorm:"size:100;not null;uniqueIndex"`
+	Description             string     `gorm:"type:text"`
+	IsActive                bool       `gorm:"default:true"`
+	AssignableBySchoolAdmin bool       `gorm:"default:false"`
+	DefaultMenuID           *uuid.UUID `gorm:"type:char(36);index"` // page users of the role land on after login
+	Priority                int        `gorm:"not null;default:0"`  // the highest wins among a user's roles
+	CreatedAt               time.Time
+	CreatedBy               *uuid.UUID `gorm:"type:char(36)"`
+	UpdatedAt               time.Time
+	UpdatedBy               *uuid.UUID `gorm:"type:char(36)"`
+	DeletedAt               *time.Time `gorm:"index"`
+	DeletedBy               *uuid.UUID `gorm:"type:char(36)"`
 
 	// Relationships
 	Permissions []PermissionEntity `gorm:"many2many:role_permissions;"`
@@ -43,15 +47,19 @@ func (r *RoleEntity) ToRole() Role {
 	}
 
 	return Role{
-		ID:          r.ID,
-		Name:        r.Name,
-		Slug:        r.Slug,
-		Description: r.Description,
-		IsActive:    r.IsActive,
-		CreatedAt:   r.CreatedAt,
-		UpdatedAt:   r.UpdatedAt,
-		Permissions: permissions,
-		Menus:       menus,
+		ID:                      r.ID,
+		Name:                    r.Name,
+		Slug:                    r.Slug,
+		Description:             r.Description,
+		IsActive:                r.IsActive,
+		CreatedAt:               r.CreatedAt,
+		UpdatedAt:               r.UpdatedAt,
+		DeletedAt:               r.DeletedAt,
+		Permissions:             permissions,
+		Menus:                   menus,
+		AssignableBySchoolAdmin: r.AssignableBySchoolAdmin,
+		DefaultMenuID:           r.DefaultMenuID,
+		Priority:                r.Priority,
 	}
 }
 
@@ -60,6 +68,7 @@ type PermissionEntity struct {
 	ID          uuid.UUID `gorm:"type:char(36);primaryKey"`
 	Name        string    `gorm:"size:100;not null;uniqueIndex"`
 	Slug        string    `gorm:"size:100;not null;uniqueIndex"`
+	Module      string    `gorm:"size:100;not null;index"`
 	Resource    string    `gorm:"size:100;not null"`
 	Action      string    `gorm:"size:50;not null"`
 	Description string    `gorm:"type:text"`
@@ -71,6 +80,10 @@ type PermissionEntity struct {
 	DeletedAt   *time.Time `gorm:"index"`
 	DeletedBy   *uuid.UUID `gorm:"type:char(36)"`
 
+	// Effect is read from role_permissions when permissions are listed for
+	// a role; it is not a column of permissions
+	Effect string `gorm:"->;-:migration"`
+
 	// Relationships
 	Roles []RoleEntity `gorm:"many2many:role_permissions;"`
 }
@@ -86,12 +99,15 @@ func (p *PermissionEntity) ToPermission() Permission {
 		ID:          p.ID,
 		Name:        p.Name,
 		Slug:        p.Slug,
+		Module:      p.Module,
 		Resource:    p.Resource,
 		Action:      p.Action,
 		Description: p.Description,
 		IsActive:    p.IsActive,
+		Effect:      p.Effect,
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
+		DeletedAt:   p.DeletedAt,
 	}
 }
 
@@ -147,11 +163,95 @@ func (m *MenuEntity) ToMenu() Menu {
 	}
 }
 
+// Effects of a role_permissions row
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Permissions applying to more than one resource or action
+const (
+	// ActionManage grants every action on its resource
+	ActionManage = "manage"
+	// Wildcard as the resource or action matches every resource or action
+	Wildcard = "*"
+)
+
+// Covers reports whether a permission on resource:action applies to a check
+// of checkResource:checkAction. A permission on resource:manage or
+// resource:* covers every action on the resource and *:* covers everything.
+func Covers(resource, action, checkResource, checkAction string) bool {
+	return (resource == checkResource || resource == Wildcard) &&
+		(action == checkAction || action == ActionManage || action == Wildcard)
+}
+
+// Allowed decides a permission check from the effects of the rows covering
+// the permission for the user's roles, see Covers. Precedence:
+//
+//  1. a deny from any role wins, whatever the other roles allow
+//  2. otherwise a single allow is enough
+//  3. no rows at all means the permission is not granted
+//
+// The rows are expanded before the denies are weighed, so a deny on
+// classes:delete blocks it despite an allow on classes:manage, and a deny
+// on classes:manage blocks every action on classes. A deny on
+// classes:delete does not touch classes:edit.
+func Allowed(effects []string) bool {
+	allowed := false
+	for _, effect := range effects {
+		switch effect {
+		case EffectDeny:
+			return false
+		case EffectAllow:
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// PermissionEffect is the effect one of a user's roles has on a permission
+type PermissionEffect struct {
+	Resource string
+	Action   string
+	Effect   string
+}
+
+// Key encodes the effect as resource:action, prefixed with "-" for a deny
+func (p PermissionEffect) Key() string {
+	if p.Effect == EffectDeny {
+		return "-" + p.Resource + ":" + p.Action
+	}
+	return p.Resource + ":" + p.Action
+}
+
+// ParsePermissionEffect decodes a Key
+func ParsePermissionEffect(key string) (PermissionEffect, bool) {
+	effect := EffectAllow
+	if rest, ok := strings.CutPrefix(key, "-"); ok {
+		effect, key = EffectDeny, rest
+	}
+	resource, action, ok := strings.Cut(key, ":")
+	return PermissionEffect{Resource: resource, Action: action, Effect: effect}, ok
+}
+
+// Decide checks resource:action against every effect the user's roles
+// have, keeping those covering it and letting Allowed decide
+func Decide(effects []PermissionEffect, resource, action string) bool {
+	var covering []string
+	for _, e := range effects {
+		if Covers(e.Resource, e.Action, resource, action) {
+			covering = append(covering, e.Effect)
+		}
+	}
+	return Allowed(covering)
+}
+
 // RolePermissionEntity represents the role_permissions junction table
 type RolePermissionEntity struct {
 	ID           uuid.UUID `gorm:"type:char(36);primaryKey"`
 	RoleID       uuid.UUID `gorm:"type:char(36);not null;index;uniqueIndex:unique_role_permission"`
 	PermissionID uuid.UUID `gorm:"type:char(36);not null;index;uniqueIndex:unique_role_permission"`
+	Effect       string    `gorm:"size:5;not null;default:allow"`
 	CreatedAt    time.Time
 	CreatedBy    *uuid.UUID `gorm:"type:char(36)"`
 
@@ -172,6 +272,8 @@ type UserRoleEntity struct {
 	RoleID     uuid.UUID  `gorm:"type:char(36);not null;index;uniqueIndex:unique_user_role"`
 	AssignedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
 	AssignedBy *uuid.UUID `gorm:"type:char(36)"`
+	ValidFrom  *time.Time
+	ValidUntil *time.Time `gorm:"index"`
 
 	// Relationships
 	Role RoleEntity `gorm:"foreignKey:RoleID"`
@@ -182,6 +284,100 @@ func (UserRoleEntity) TableName() string {
 	return "user_roles"
 }
 
+// RoleUserEntity is a row of user_roles joined with the user it assigns
+type RoleUserEntity struct {
+	UserID     uuid.UUID
+	Username   string
+	Email      string
+	Fullname   string
+	AssignedAt time.Time
+	AssignedBy *uuid.UUID
+}
+
+// ToRoleUser converts RoleUserEntity to RoleUser DTO
+func (u *RoleUserEntity) ToRoleUser() RoleUser {
+	return RoleUser{
+		UserID:     u.UserID,
+		Username:   u.Username,
+		Email:      u.Email,
+		Fullname:   u.Fullname,
+		AssignedAt: u.AssignedAt,
+		AssignedBy: u.AssignedBy,
+	}
+}
+
+// ExpiringUserRoleEntity is a row of user_roles joined with its user and
+// role, for assignments about to end
+type ExpiringUserRoleEntity struct {
+	UserID     uuid.UUID
+	Username   string
+	Email      string
+	Fullname   string
+	RoleID     uuid.UUID
+	RoleName   string
+	RoleSlug   string
+	ValidUntil time.Time
+}
+
+// ToExpiringUserRole converts ExpiringUserRoleEntity to ExpiringUserRole DTO
+func (e *ExpiringUserRoleEntity) ToExpiringUserRole() ExpiringUserRole {
+	return ExpiringUserRole{
+		UserID:     e.UserID,
+		Username:   e.Username,
+		Email:      e.Email,
+		Fullname:   e.Fullname,
+		RoleID:     e.RoleID,
+		RoleName:   e.RoleName,
+		RoleSlug:   e.RoleSlug,
+		ValidUntil: e.ValidUntil,
+	}
+}
+
+// PermissionGrantEntity is a user_roles row joined with its role and one of
+// the role's role_permissions rows
+type PermissionGrantEntity struct {
+	UserRoleID       uuid.UUID
+	AssignedAt       time.Time
+	AssignedBy       *uuid.UUID
+	ValidFrom        *time.Time
+	ValidUntil       *time.Time
+	RoleID           uuid.UUID
+	RoleName         string
+	RoleSlug         string
+	RolePermissionID uuid.UUID
+	PermissionID     uuid.UUID
+	PermissionSlug   string
+	Effect           string
+	GrantedAt        time.Time
+	GrantedBy        *uuid.UUID
+}
+
+// ToPermissionGrant converts PermissionGrantEntity to PermissionGrant DTO
+func (g *PermissionGrantEntity) ToPermissionGrant() PermissionGrant {
+	return PermissionGrant{
+		UserRole: PermissionGrantUserRole{
+			ID:         g.UserRoleID,
+			AssignedAt: g.AssignedAt,
+			AssignedBy: g.AssignedBy,
+			ValidFrom:  g.ValidFrom,
+			ValidUntil: g.ValidUntil,
+		},
+		Role: PermissionGrantRole{
+			ID:   g.RoleID,
+			Name: g.RoleName,
+			Slug: g.RoleSlug,
+		},
+		RolePermission: PermissionGrantRolePermission{
+			ID:             g.RolePermissionID,
+			PermissionID:   g.PermissionID,
+			PermissionSlug: g.PermissionSlug,
+			Effect:         g.Effect,
+			CreatedAt:      g.GrantedAt,
+			CreatedBy:      g.GrantedBy,
+		},
+	}
+}
+
 // RoleMenuEntity represents the role_menus junction table
 type RoleMenuEntity struct {
 	ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
@@ -206,6 +402,74 @@ func (RoleMenuEntity) TableName() string {
 	return "role_menus"
 }
 
+// UserRoleHistoryEntity keeps user_roles rows removed by a role delete
+// so they can be reinstated when the role is restored
+type UserRoleHistoryEntity struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	UserID     uuid.UUID  `gorm:"type:char(36);not null"`
+	RoleID     uuid.UUID  `gorm:"type:char(36);not null;index"`
+	AssignedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	AssignedBy *uuid.UUID `gorm:"type:char(36)"`
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+	ArchivedAt time.Time `gorm:"not null;index"`
+}
+
+// TableName returns the table name for the UserRoleHistoryEntity
+func (UserRoleHistoryEntity) TableName() string {
+	return "user_roles_history"
+}
+
+// RolePermissionHistoryEntity keeps role_permissions rows removed by a role delete
+type RolePermissionHistoryEntity struct {
+	ID           uuid.UUID `gorm:"type:char(36);primaryKey"`
+	RoleID       uuid.UUID `gorm:"type:char(36);not null;index"`
+	PermissionID uuid.UUID `gorm:"type:char(36);not null"`
+	Effect       string    `gorm:"size:5;not null;default:allow"`
+	CreatedAt    time.Time
+	CreatedBy    *uuid.UUID `gorm:"type:char(36)"`
+	ArchivedAt   time.Time  `gorm:"not null;index"`
+}
+
+// TableName returns the table name for the RolePermissionHistoryEntity
+func (RolePermissionHistoryEntity) TableName() string {
+	return "role_permissions_history"
+}
+
+// RoleMenuHistoryEntity keeps role_menus rows removed by a role delete
+type RoleMenuHistoryEntity struct {
+	ID         uuid.UUID `gorm:"type:char(36);primaryKey"`
+	RoleID     uuid.UUID `gorm:"type:char(36);not null;index"`
+	MenuID     uuid.UUID `gorm:"type:char(36);not null"`
+	CanView    bool      `gorm:"default:true"`
+	CanCreate  bool      `gorm:"default:false"`
+	CanEdit    bool      `gorm:"default:false"`
+	CanDelete  bool      `gorm:"default:false"`
+	CreatedAt  time.Time
+	CreatedBy  *uuid.UUID `gorm:"type:char(36)"`
+	UpdatedAt  time.Time
+	UpdatedBy  *uuid.UUID `gorm:"type:char(36)"`
+	ArchivedAt time.Time  `gorm:"not null;index"`
+}
+
+// TableName returns the table name for the RoleMenuHistoryEntity
+func (RoleMenuHistoryEntity) TableName() string {
+	return "role_menus_history"
+}
+
+// ToRoleMenuAccess converts RoleMenuEntity to the Menu DTO of its menu,
+// carrying the role's flags as its access
+func (rm *RoleMenuEntity) ToRoleMenuAccess() Menu {
+	menu := rm.Menu.ToMenu()
+	menu.Access = &MenuAccess{
+		CanView:   rm.CanView,
+		CanCreate: rm.CanCreate,
+		CanEdit:   rm.CanEdit,
+		CanDelete: rm.CanDelete,
+	}
+	return menu
+}
+
 // ToRoleMenu converts RoleMenuEntity to RoleMenu DTO
 func (rm *RoleMenuEntity) ToRoleMenu() RoleMenu {
 	return RoleMenu{
@@ -221,3 +485,44 @@ func (rm *RoleMenuEntity) ToRoleMenu() RoleMenu {
 		UpdatedAt: rm.UpdatedAt,
 	}
 }
+
+// RBACSnapshot is every role, permission and menu, deleted ones included,
+// with the links between them
+type RBACSnapshot struct {
+	Roles           []RoleEntity
+	Permissions     []PermissionEntity
+	Menus           []MenuEntity
+	RolePermissions []RolePermissionEntity
+	RoleMenus       []RoleMenuEntity
+}
+
+// RBACImportPlan is the writes an import makes. New menus are ordered
+// parents first.
+type RBACImportPlan struct {
+	CreatePermissions     []PermissionEntity
+	UpdatePermissions     []PermissionEntity
+	CreateMenus           []MenuEntity
+	UpdateMenus           []MenuEntity
+	CreateRoles           []RoleEntity
+	UpdateRoles           []RoleEntity
+	CreateRolePermissions []RolePermissionEntity
+	UpdateRolePermissions []RolePermissionEntity
+	DeleteRolePermissions []uuid.UUID
+	CreateRoleMenus       []RoleMenuEntity
+	UpdateRoleMenus       []RoleMenuEntity
+	DeleteRoleMenus       []uuid.UUID
+}
+
+// CheckCountEntity counts the permission or role checks answered with one
+// result through the check endpoints
+type CheckCountEntity struct {
+	Kind      string `gorm:"size:16;primaryKey"` // permission or role
+	Result    string `gorm:"size:8;primaryKey"`  // allowed or denied
+	Total     int64  `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+// TableName returns the table name for the CheckCountEntity
+func (CheckCountEntity) TableName() string {
+	return "authorization_check_counts"
+}
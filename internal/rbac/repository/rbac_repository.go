@@ -3,28 +3,51 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	"backend-service-internpro/internal/pkg/idgen"
 	"backend-service-internpro/internal/rbac"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type repository struct {
-	db *gorm.DB
+	db  *gorm.DB
+	ids idgen.Generator // IDs of the link rows the repository creates
 }
 
-// NewRepository creates a new RBAC repository
-func NewRepository(db *gorm.DB) Repository {
+// NewRepository creates a new RBAC repository; a nil ids uses random UUIDs
+func NewRepository(db *gorm.DB, ids idgen.Generator) Repository {
 	return &repository{
-		db: db,
+		db:  db,
+		ids: idgen.OrRandom(ids),
 	}
 }
 
 // Role methods
+// CreateRole creates the role. A default menu is assigned to the role with
+// view access in the same transaction, so it is reachable by the role's users.
 func (r *repository) CreateRole(ctx context.Context, role *rbac.RoleEntity) error {
-	return r.db.WithContext(ctx).Create(role).Error
+	if role.DefaultMenuID == nil {
+		return r.db.WithContext(ctx).Create(role).Error
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rbac.RoleMenuEntity{
+			ID:        r.ids.New(),
+			RoleID:    role.ID,
+			MenuID:    *role.DefaultMenuID,
+			CanView:   true,
+			CreatedBy: role.CreatedBy,
+			UpdatedBy: role.CreatedBy,
+		}).Error
+	})
 }
 
 func (r *repository) GetRoleByID(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error) {
@@ -51,14 +74,20 @@ func (r *repository) GetRoleBySlug(ctx context.Context, slug string) (*rbac.Role
 	return &role, nil
 }
 
-func (r *repository) GetRoles(ctx context.Context, page, limit int, search string) ([]rbac.RoleEntity, int64, error) {
+func (r *repository) GetRoles(ctx context.Context, page, limit int, filter rbac.RoleFilter) ([]rbac.RoleEntity, int64, error) {
 	var roles []rbac.RoleEntity
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&rbac.RoleEntity{}).Where("deleted_at IS NULL")
+	query := r.db.WithContext(ctx).Model(&rbac.RoleEntity{})
+	if !filter.IncludeDeleted {
+		query = query.Where("deleted_at IS NULL")
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
 
-	if search != "" {
-		searchPattern := "%" + strings.ToLower(search) + "%"
+	if filter.Search != "" {
+		searchPattern := "%" + strings.ToLower(filter.Search) + "%"
 		query = query.Where("LOWER(name) LIKE ? OR LOWER(slug) LIKE ? OR LOWER(description) LIKE ?",
 			searchPattern, searchPattern, searchPattern)
 	}
@@ -70,28 +99,74 @@ func (r *repository) GetRoles(ctx context.Context, page, limit int, search strin
 
 	// Get paginated results
 	offset := (page - 1) * limit
-	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&roles).Error
+	err := sortBy(query, filter.ListSort).Order("created_at DESC").Order("id ASC").
+		Offset(offset).Limit(limit).Find(&roles).Error
 
 	return roles, total, err
 }
 
 func (r *repository) UpdateRole(ctx context.Context, role *rbac.RoleEntity) error {
-	return r.db.WithContext(ctx).Save(role).Error
+	return updated(r.db.WithContext(ctx).Model(role).
+		Where("deleted_at IS NULL").
+		Select("name", "slug", "description", "is_active", "assignable_by_school_admin", "default_menu_id", "priority", "updated_at", "updated_by").
+		Updates(role))
 }
 
-func (r *repository) DeleteRole(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Model(&rbac.RoleEntity{}).
-		Where("id = ?", id).
-		Update("deleted_at", gorm.Expr("NOW()")).Error
+// DeleteRole soft deletes a role and detaches it from users, permissions
+// and menus in the same transaction, so a deleted role grants nothing. The
+// detached rows are moved to history tables so RestoreRole can reinstate
+// them.
+func (r *repository) DeleteRole(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		archives := []string{
+			`INSERT INTO user_roles_history (id, user_id, role_id, assigned_at, assigned_by, valid_from, valid_until, archived_at)
+			SELECT id, user_id, role_id, assigned_at, assigned_by, valid_from, valid_until, ? FROM user_roles WHERE role_id = ?`,
+			`INSERT INTO role_permissions_history (id, role_id, permission_id, effect, created_at, created_by, archived_at)
+			SELECT id, role_id, permission_id, effect, created_at, created_by, ? FROM role_permissions WHERE role_id = ?`,
+			`INSERT INTO role_menus_history (id, role_id, menu_id, can_view, can_create, can_edit, can_delete, created_at, created_by, updated_at, updated_by, archived_at)
+			SELECT id, role_id, menu_id, can_view, can_create, can_edit, can_delete, created_at, created_by, updated_at, updated_by, ? FROM role_menus WHERE role_id = ?`,
+		}
+		for _, query := range archives {
+			if err := tx.Exec(query, now, id).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("role_id = ?", id).Delete(&rbac.UserRoleEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&rbac.RolePermissionEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&rbac.RoleMenuEntity{}).Error; err != nil {
+			return err
+		}
+
+		return updated(tx.Model(&rbac.RoleEntity{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{"deleted_at": now, "deleted_by": deletedBy}))
+	})
 }
 
-func (r *repository) GetRoleWithPermissions(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error) {
-	var role rbac.RoleEntity
-	err := r.db.WithContext(ctx).
-		Preload("Permissions", "deleted_at IS NULL AND is_active = ?", true).
-		Where("id = ? AND deleted_at IS NULL", id).
-		First(&role).Error
+// updated returns the error of an update, or gorm.ErrRecordNotFound when it
+// matched no row
+func updated(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
 
+// GetDeletedRoleByID returns a soft deleted role, nil when the role does not
+// exist or is not deleted
+func (r *repository) GetDeletedRoleByID(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error) {
+	var role rbac.RoleEntity
+	err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NOT NULL", id).First(&role).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -101,20 +176,63 @@ func (r *repository) GetRoleWithPermissions(ctx context.Context, id uuid.UUID) (
 	return &role, nil
 }
 
-func (r *repository) GetRoleWithMenus(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error) {
-	var role rbac.RoleEntity
-	err := r.db.WithContext(ctx).
-		Preload("Menus", "deleted_at IS NULL AND is_active = ?", true).
-		Where("id = ? AND deleted_at IS NULL", id).
-		First(&role).Error
+// RestoreRole undeletes a role and reinstates the assignments archived by
+// DeleteRole, skipping those whose user, permission or menu is gone or
+// soft-deleted
+func (r *repository) RestoreRole(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) (*rbac.RoleRestoreData, error) {
+	result := &rbac.RoleRestoreData{ID: id}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updated(tx.Model(&rbac.RoleEntity{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": nil, "updated_by": restoredBy})); err != nil {
+			return err
+		}
 
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil
+		restores := []struct {
+			history  interface{}
+			query    string
+			restored *int
+			skipped  *int
+		}{
+			{&rbac.UserRoleHistoryEntity{}, `INSERT INTO user_roles (id, user_id, role_id, assigned_at, assigned_by, valid_from, valid_until)
+				SELECT h.id, h.user_id, h.role_id, h.assigned_at, h.assigned_by, h.valid_from, h.valid_until FROM user_roles_history h
+				WHERE h.role_id = ? AND EXISTS (SELECT 1 FROM users u WHERE u.id = h.user_id AND u.deleted_at IS NULL)`,
+				&result.RestoredUsers, &result.SkippedUsers},
+			{&rbac.RolePermissionHistoryEntity{}, `INSERT INTO role_permissions (id, role_id, permission_id, effect, created_at, created_by)
+				SELECT h.id, h.role_id, h.permission_id, h.effect, h.created_at, h.created_by FROM role_permissions_history h
+				WHERE h.role_id = ? AND EXISTS (SELECT 1 FROM permissions p WHERE p.id = h.permission_id AND p.deleted_at IS NULL)`,
+				&result.RestoredPermissions, &result.SkippedPermissions},
+			{&rbac.RoleMenuHistoryEntity{}, `INSERT INTO role_menus (id, role_id, menu_id, can_view, can_create, can_edit, can_delete, created_at, created_by, updated_at, updated_by)
+				SELECT h.id, h.role_id, h.menu_id, h.can_view, h.can_create, h.can_edit, h.can_delete, h.created_at, h.created_by, h.updated_at, h.updated_by FROM role_menus_history h
+				WHERE h.role_id = ? AND EXISTS (SELECT 1 FROM menus m WHERE m.id = h.menu_id AND m.deleted_at IS NULL)`,
+				&result.RestoredMenus, &result.SkippedMenus},
 		}
+
+		for _, restore := range restores {
+			var archived int64
+			if err := tx.Model(restore.history).Where("role_id = ?", id).Count(&archived).Error; err != nil {
+				return err
+			}
+
+			res := tx.Exec(restore.query, id)
+			if res.Error != nil {
+				return res.Error
+			}
+			*restore.restored = int(res.RowsAffected)
+			*restore.skipped = int(archived - res.RowsAffected)
+
+			if err := tx.Where("role_id = ?", id).Delete(restore.history).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	return &role, nil
+
+	return result, nil
 }
 
 // Permission methods
@@ -122,6 +240,34 @@ func (r *repository) CreatePermission(ctx context.Context, permission *rbac.Perm
 	return r.db.WithContext(ctx).Create(permission).Error
 }
 
+// CreatePermissions inserts all of the permissions or, on any failure, none
+func (r *repository) CreatePermissions(ctx context.Context, permissions []rbac.PermissionEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&permissions).Error
+	})
+}
+
+// CreatePermissionsForRole inserts the permissions and grants them to the
+// role in one transaction
+func (r *repository) CreatePermissionsForRole(ctx context.Context, permissions []rbac.PermissionEntity, roleID uuid.UUID, assignedBy uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&permissions).Error; err != nil {
+			return err
+		}
+		rolePermissions := make([]rbac.RolePermissionEntity, 0, len(permissions))
+		for _, permission := range permissions {
+			rolePermissions = append(rolePermissions, rbac.RolePermissionEntity{
+				ID:           r.ids.New(),
+				RoleID:       roleID,
+				PermissionID: permission.ID,
+				Effect:       rbac.EffectAllow,
+				CreatedBy:    &assignedBy,
+			})
+		}
+		return tx.Create(&rolePermissions).Error
+	})
+}
+
 func (r *repository) GetPermissionByID(ctx context.Context, id uuid.UUID) (*rbac.PermissionEntity, error) {
 	var permission rbac.PermissionEntity
 	err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", id).First(&permission).Error
@@ -146,17 +292,24 @@ func (r *repository) GetPermissionBySlug(ctx context.Context, slug string) (*rba
 	return &permission, nil
 }
 
-func (r *repository) GetPermissions(ctx context.Context, page, limit int, search string) ([]rbac.PermissionEntity, int64, error) {
+func (r *repository) GetPermissions(ctx context.Context, page, limit int, filter rbac.PermissionFilter) ([]rbac.PermissionEntity, int64, error) {
 	var permissions []rbac.PermissionEntity
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&rbac.PermissionEntity{}).Where("deleted_at IS NULL")
-
-	if search != "" {
-		searchPattern := "%" + strings.ToLower(search) + "%"
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(slug) LIKE ? OR LOWER(resource) LIKE ? OR LOWER(action) LIKE ?",
-			searchPattern, searchPattern, searchPattern, searchPattern)
+	query := r.db.WithContext(ctx).Model(&rbac.PermissionEntity{})
+	if !filter.IncludeDeleted {
+		query = query.Where("deleted_at IS NULL")
 	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.Resource != "" {
+		query = query.Where("resource = ?", filter.Resource)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	query = searchPermissions(query, filter.Search)
 
 	// Count total
 	if err := query.Count(&total).Error; err != nil {
@@ -165,19 +318,99 @@ func (r *repository) GetPermissions(ctx context.Context, page, limit int, search
 
 	// Get paginated results
 	offset := (page - 1) * limit
-	err := query.Order("resource ASC, action ASC").Offset(offset).Limit(limit).Find(&permissions).Error
+	err := sortBy(query, filter.ListSort).Order("resource ASC, action ASC").Order("id ASC").
+		Offset(offset).Limit(limit).Find(&permissions).Error
 
 	return permissions, total, err
 }
 
+// GetPermissionsByModule returns every permission matching search, ordered
+// by module, resource and action
+func (r *repository) GetPermissionsByModule(ctx context.Context, search string) ([]rbac.PermissionEntity, error) {
+	var permissions []rbac.PermissionEntity
+	err := searchPermissions(r.db.WithContext(ctx).Where("deleted_at IS NULL"), search).
+		Order("module ASC, resource ASC, action ASC").
+		Find(&permissions).Error
+	return permissions, err
+}
+
+// searchPermissions narrows query to the permissions whose name, slug,
+// module, resource or action contains search
+func searchPermissions(query *gorm.DB, search string) *gorm.DB {
+	if search == "" {
+		return query
+	}
+	searchPattern := "%" + strings.ToLower(search) + "%"
+	return query.Where("LOWER(name) LIKE ? OR LOWER(slug) LIKE ? OR LOWER(module) LIKE ? OR LOWER(resource) LIKE ? OR LOWER(action) LIKE ?",
+		searchPattern, searchPattern, searchPattern, searchPattern, searchPattern)
+}
+
+func (r *repository) GetActivePermissions(ctx context.Context) ([]rbac.PermissionEntity, error) {
+	var permissions []rbac.PermissionEntity
+	err := r.db.WithContext(ctx).
+		Where("deleted_at IS NULL AND is_active = ?", true).
+		Order("resource ASC, action ASC").
+		Find(&permissions).Error
+	return permissions, err
+}
+
 func (r *repository) UpdatePermission(ctx context.Context, permission *rbac.PermissionEntity) error {
-	return r.db.WithContext(ctx).Save(permission).Error
+	return updated(r.db.WithContext(ctx).Model(permission).
+		Where("deleted_at IS NULL").
+		Select("name", "slug", "module", "resource", "action", "description", "is_active", "updated_at", "updated_by").
+		Updates(permission))
+}
+
+func (r *repository) DeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
+	return updated(r.db.WithContext(ctx).Model(&rbac.PermissionEntity{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{"deleted_at": gorm.Expr("NOW()"), "deleted_by": deletedBy}))
+}
+
+// ForceDeletePermission soft deletes a permission and detaches it from all
+// roles in one transaction
+func (r *repository) ForceDeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("permission_id = ?", id).Delete(&rbac.RolePermissionEntity{}).Error; err != nil {
+			return err
+		}
+		return updated(tx.Model(&rbac.PermissionEntity{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{"deleted_at": gorm.Expr("NOW()"), "deleted_by": deletedBy}))
+	})
+}
+
+// GetDeletedPermissionByID returns a soft deleted permission, nil when the
+// permission does not exist or is not deleted
+func (r *repository) GetDeletedPermissionByID(ctx context.Context, id uuid.UUID) (*rbac.PermissionEntity, error) {
+	var permission rbac.PermissionEntity
+	err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NOT NULL", id).First(&permission).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &permission, nil
 }
 
-func (r *repository) DeletePermission(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Model(&rbac.PermissionEntity{}).
-		Where("id = ?", id).
-		Update("deleted_at", gorm.Expr("NOW()")).Error
+func (r *repository) RestorePermission(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) error {
+	return updated(r.db.WithContext(ctx).Model(&rbac.PermissionEntity{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": nil, "updated_by": restoredBy}))
+}
+
+// GetPermissionRoleNames returns the names of the active roles the
+// permission is attached to, allowed or denied
+func (r *repository) GetPermissionRoleNames(ctx context.Context, id uuid.UUID) ([]string, error) {
+	var names []string
+	err := r.db.WithContext(ctx).
+		Table("role_permissions").
+		Joins("INNER JOIN roles ON roles.id = role_permissions.role_id").
+		Where("role_permissions.permission_id = ? AND roles.deleted_at IS NULL", id).
+		Order("roles.name ASC").
+		Pluck("roles.name", &names).Error
+	return names, err
 }
 
 func (r *repository) GetPermissionsByResource(ctx context.Context, resource string) ([]rbac.PermissionEntity, error) {
@@ -226,14 +459,20 @@ func (r *repository) GetMenuBySlug(ctx context.Context, slug string) (*rbac.Menu
 	return &menu, nil
 }
 
-func (r *repository) GetMenus(ctx context.Context, page, limit int, search string) ([]rbac.MenuEntity, int64, error) {
+func (r *repository) GetMenus(ctx context.Context, page, limit int, filter rbac.MenuFilter) ([]rbac.MenuEntity, int64, error) {
 	var menus []rbac.MenuEntity
 	var total int64
 
 	query := r.db.WithContext(ctx).Model(&rbac.MenuEntity{}).Where("deleted_at IS NULL")
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.ParentID != nil {
+		query = query.Where("parent_id = ?", *filter.ParentID)
+	}
 
-	if search != "" {
-		searchPattern := "%" + strings.ToLower(search) + "%"
+	if filter.Search != "" {
+		searchPattern := "%" + strings.ToLower(filter.Search) + "%"
 		query = query.Where("LOWER(name) LIKE ? OR LOWER(slug) LIKE ? OR LOWER(url) LIKE ?",
 			searchPattern, searchPattern, searchPattern)
 	}
@@ -245,31 +484,145 @@ func (r *repository) GetMenus(ctx context.Context, page, limit int, search strin
 
 	// Get paginated results
 	offset := (page - 1) * limit
-	err := query.Order("sort_order ASC, name ASC").Offset(offset).Limit(limit).Find(&menus).Error
+	err := sortBy(query, filter.ListSort).Order("sort_order ASC, name ASC").Order("id ASC").
+		Offset(offset).Limit(limit).Find(&menus).Error
 
 	return menus, total, err
 }
 
+// GetMenuTree returns the active top-level menus with their active
+// descendants nested at any depth
 func (r *repository) GetMenuTree(ctx context.Context) ([]rbac.MenuEntity, error) {
+	tree, err := r.activeMenuTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tree.children(uuid.Nil), nil
+}
+
+// menuTree holds menus by parent ID, uuid.Nil for the top level, so a
+// branch of any depth is built from a single query
+type menuTree map[uuid.UUID][]rbac.MenuEntity
+
+// activeMenuTree loads every active menu that is not deleted, in display
+// order
+func (r *repository) activeMenuTree(ctx context.Context) (menuTree, error) {
 	var menus []rbac.MenuEntity
 	err := r.db.WithContext(ctx).
-		Preload("Children", func(db *gorm.DB) *gorm.DB {
-			return db.Where("deleted_at IS NULL AND is_active = ?", true).Order("sort_order ASC")
-		}).
-		Where("parent_id IS NULL AND deleted_at IS NULL AND is_active = ?", true).
+		Where("deleted_at IS NULL AND is_active = ?", true).
 		Order("sort_order ASC").
 		Find(&menus).Error
+	if err != nil {
+		return nil, err
+	}
+	tree := make(menuTree)
+	for _, menu := range menus {
+		parentID := uuid.Nil
+		if menu.ParentID != nil {
+			parentID = *menu.ParentID
+		}
+		tree[parentID] = append(tree[parentID], menu)
+	}
+	return tree, nil
+}
+
+// children returns the menus under parentID, each with its own children
+// filled in. Menus under an inactive or deleted menu are hidden with it.
+func (t menuTree) children(parentID uuid.UUID) []rbac.MenuEntity {
+	return t.branch(parentID, make(map[uuid.UUID]bool))
+}
+
+// branch builds the menus under parentID. path holds the menus above it, so
+// a cycle already in the table ends the walk instead of looping.
+func (t menuTree) branch(parentID uuid.UUID, path map[uuid.UUID]bool) []rbac.MenuEntity {
+	menus := t[parentID]
+	if len(menus) == 0 || path[parentID] {
+		return nil
+	}
+	path[parentID] = true
+	defer delete(path, parentID)
+
+	nested := make([]rbac.MenuEntity, len(menus))
+	for i, menu := range menus {
+		menu.Children = t.branch(menu.ID, path)
+		nested[i] = menu
+	}
+	return nested
+}
+
+func (r *repository) GetActiveMenusByURL(ctx context.Context, url string) ([]rbac.MenuEntity, error) {
+	var menus []rbac.MenuEntity
+	err := r.db.WithContext(ctx).
+		Where("url = ? AND deleted_at IS NULL AND is_active = ?", url, true).
+		Order("slug ASC").
+		Find(&menus).Error
 	return menus, err
 }
 
+// GetAllMenus returns every menu that is not deleted, active or not
+func (r *repository) GetAllMenus(ctx context.Context) ([]rbac.MenuEntity, error) {
+	var menus []rbac.MenuEntity
+	err := r.db.WithContext(ctx).
+		Where("deleted_at IS NULL").
+		Order("slug ASC").
+		Find(&menus).Error
+	return menus, err
+}
+
+// GetAssignedMenuIDs returns the menus assigned to at least one role that is not deleted
+func (r *repository) GetAssignedMenuIDs(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Model(&rbac.RoleMenuEntity{}).
+		Distinct("role_menus.menu_id").
+		Joins("JOIN roles ON roles.id = role_menus.role_id AND roles.deleted_at IS NULL").
+		Pluck("role_menus.menu_id", &ids).Error
+	return ids, err
+}
+
 func (r *repository) UpdateMenu(ctx context.Context, menu *rbac.MenuEntity) error {
-	return r.db.WithContext(ctx).Save(menu).Error
+	return updated(r.db.WithContext(ctx).Model(menu).
+		Where("deleted_at IS NULL").
+		Select("name", "slug", "url", "icon", "parent_id", "sort_order", "is_active", "updated_at", "updated_by").
+		Updates(menu))
+}
+
+func (r *repository) ReorderMenus(ctx context.Context, menus []rbac.MenuEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range menus {
+			if err := updated(tx.Model(&menus[i]).
+				Where("deleted_at IS NULL").
+				Select("parent_id", "sort_order", "updated_at", "updated_by").
+				Updates(&menus[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func (r *repository) DeleteMenu(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Model(&rbac.MenuEntity{}).
-		Where("id = ?", id).
-		Update("deleted_at", gorm.Expr("NOW()")).Error
+func (r *repository) DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var menu rbac.MenuEntity
+		if err := tx.Where("id = ? AND deleted_at IS NULL", id).First(&menu).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&rbac.MenuEntity{}).Where("parent_id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{"parent_id": menu.ParentID, "updated_by": deletedBy}).Error; err != nil {
+			return err
+		}
+		return updated(tx.Model(&rbac.MenuEntity{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{"deleted_at": gorm.Expr("NOW()"), "deleted_by": deletedBy}))
+	})
+}
+
+func (r *repository) CountChildMenus(ctx context.Context, id uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&rbac.MenuEntity{}).
+		Where("parent_id = ? AND deleted_at IS NULL", id).
+		Count(&count).Error
+	return count, err
 }
 
 func (r *repository) GetMenusByParentID(ctx context.Context, parentID *uuid.UUID) ([]rbac.MenuEntity, error) {
@@ -296,28 +649,74 @@ func (r *repository) GetMenusByIDs(ctx context.Context, ids []uuid.UUID) ([]rbac
 }
 
 // Role-Permission methods
-func (r *repository) AssignPermissionsToRole(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID, assignedBy uuid.UUID) error {
-	// First, remove existing permissions
-	if err := r.db.WithContext(ctx).Where("role_id = ?", roleID).Delete(&rbac.RolePermissionEntity{}).Error; err != nil {
-		return err
-	}
-
-	// Then add new permissions
+// AssignPermissionsToRole replaces the role's permissions in one
+// transaction, so a failed insert leaves the old ones in place
+func (r *repository) AssignPermissionsToRole(ctx context.Context, roleID uuid.UUID, permissionIDs, deniedIDs []uuid.UUID, assignedBy uuid.UUID) error {
 	var rolePermissions []rbac.RolePermissionEntity
-	for _, permissionID := range permissionIDs {
-		rolePermissions = append(rolePermissions, rbac.RolePermissionEntity{
-			ID:           uuid.New(),
-			RoleID:       roleID,
-			PermissionID: permissionID,
-			CreatedBy:    &assignedBy,
-		})
+	for _, grant := range []struct {
+		ids    []uuid.UUID
+		effect string
+	}{{permissionIDs, rbac.EffectAllow}, {deniedIDs, rbac.EffectDeny}} {
+		for _, permissionID := range grant.ids {
+			rolePermissions = append(rolePermissions, rbac.RolePermissionEntity{
+				ID:           r.ids.New(),
+				RoleID:       roleID,
+				PermissionID: permissionID,
+				Effect:       grant.effect,
+				CreatedBy:    &assignedBy,
+			})
+		}
 	}
 
-	if len(rolePermissions) > 0 {
-		return r.db.WithContext(ctx).Create(&rolePermissions).Error
-	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// First, remove existing permissions
+		if err := tx.Where("role_id = ?", roleID).Delete(&rbac.RolePermissionEntity{}).Error; err != nil {
+			return err
+		}
 
-	return nil
+		// Then add new permissions
+		if len(rolePermissions) > 0 {
+			return tx.Create(&rolePermissions).Error
+		}
+		return nil
+	})
+}
+
+// AddPermissionsToRole attaches the permissions to the role and leaves its
+// other permissions alone. A permission the role already has takes the
+// effect it is listed with.
+func (r *repository) AddPermissionsToRole(ctx context.Context, roleID uuid.UUID, permissionIDs, deniedIDs []uuid.UUID, assignedBy uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, grant := range []struct {
+			ids    []uuid.UUID
+			effect string
+		}{{permissionIDs, rbac.EffectAllow}, {deniedIDs, rbac.EffectDeny}} {
+			if len(grant.ids) == 0 {
+				continue
+			}
+			if err := tx.Model(&rbac.RolePermissionEntity{}).
+				Where("role_id = ? AND permission_id IN ? AND effect <> ?", roleID, grant.ids, grant.effect).
+				Update("effect", grant.effect).Error; err != nil {
+				return err
+			}
+
+			rolePermissions := make([]rbac.RolePermissionEntity, 0, len(grant.ids))
+			for _, permissionID := range grant.ids {
+				rolePermissions = append(rolePermissions, rbac.RolePermissionEntity{
+					ID:           r.ids.New(),
+					RoleID:       roleID,
+					PermissionID: permissionID,
+					Effect:       grant.effect,
+					CreatedBy:    &assignedBy,
+				})
+			}
+			// Rows the role already has, possibly added concurrently, are kept
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rolePermissions).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (r *repository) RemovePermissionsFromRole(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID) error {
@@ -330,13 +729,34 @@ func (r *repository) GetRolePermissions(ctx context.Context, roleID uuid.UUID) (
 	var permissions []rbac.PermissionEntity
 	err := r.db.WithContext(ctx).
 		Table("permissions").
-		Select("permissions.*").
+		Select("permissions.*, role_permissions.effect").
 		Joins("INNER JOIN role_permissions ON permissions.id = role_permissions.permission_id").
 		Where("role_permissions.role_id = ? AND permissions.deleted_at IS NULL AND permissions.is_active = ?", roleID, true).
 		Find(&permissions).Error
 	return permissions, err
 }
 
+func (r *repository) GetRolePermissionsPage(ctx context.Context, roleID uuid.UUID, page, limit int) ([]rbac.PermissionEntity, int64, error) {
+	var permissions []rbac.PermissionEntity
+	var total int64
+
+	query := r.db.WithContext(ctx).
+		Table("permissions").
+		Joins("INNER JOIN role_permissions ON permissions.id = role_permissions.permission_id").
+		Where("role_permissions.role_id = ? AND permissions.deleted_at IS NULL AND permissions.is_active = ?", roleID, true)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Select("permissions.*, role_permissions.effect").
+		Order("permissions.resource ASC, permissions.action ASC").
+		Offset(offset).Limit(limit).
+		Find(&permissions).Error
+	return permissions, total, err
+}
+
 func (r *repository) CheckRoleHasPermission(ctx context.Context, roleID uuid.UUID, permissionSlug string) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).
@@ -349,28 +769,152 @@ func (r *repository) CheckRoleHasPermission(ctx context.Context, roleID uuid.UUI
 }
 
 // User-Role methods
-func (r *repository) AssignRolesToUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, assignedBy uuid.UUID) error {
-	// First, remove existing roles
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&rbac.UserRoleEntity{}).Error; err != nil {
-		return err
+func (r *repository) AssignRolesToUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, validity rbac.RoleValidity, assignedBy uuid.UUID) (*rbac.UserRoleChanges, error) {
+	changes := &rbac.UserRoleChanges{
+		Added:   []uuid.UUID{},
+		Removed: []uuid.UUID{},
+		Kept:    []uuid.UUID{},
 	}
 
-	// Then add new roles
-	var userRoles []rbac.UserRoleEntity
-	for _, roleID := range roleIDs {
-		userRoles = append(userRoles, rbac.UserRoleEntity{
-			ID:         uuid.New(),
-			UserID:     userID,
-			RoleID:     roleID,
-			AssignedBy: &assignedBy,
-		})
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []uuid.UUID
+		if err := tx.Model(&rbac.UserRoleEntity{}).
+			Where("user_id = ?", userID).
+			Pluck("role_id", &current).Error; err != nil {
+			return err
+		}
+
+		// Leave rows of roles that stay alone so their metadata survives
+		wanted := make(map[uuid.UUID]bool, len(roleIDs))
+		for _, roleID := range roleIDs {
+			wanted[roleID] = true
+		}
+		existing := make(map[uuid.UUID]bool, len(current))
+		for _, roleID := range current {
+			existing[roleID] = true
+			if wanted[roleID] {
+				changes.Kept = append(changes.Kept, roleID)
+			} else {
+				changes.Removed = append(changes.Removed, roleID)
+			}
+		}
+
+		var userRoles []rbac.UserRoleEntity
+		for _, roleID := range roleIDs {
+			if existing[roleID] {
+				continue
+			}
+			existing[roleID] = true
+			changes.Added = append(changes.Added, roleID)
+			userRoles = append(userRoles, rbac.UserRoleEntity{
+				ID:         r.ids.New(),
+				UserID:     userID,
+				RoleID:     roleID,
+				AssignedBy: &assignedBy,
+				ValidFrom:  validity.ValidFrom,
+				ValidUntil: validity.ValidUntil,
+			})
+		}
+
+		if len(changes.Removed) > 0 {
+			if err := tx.Where("user_id = ? AND role_id IN ?", userID, changes.Removed).
+				Delete(&rbac.UserRoleEntity{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := setRoleValidity(tx, userID, changes.Kept, validity); err != nil {
+			return err
+		}
+		if len(userRoles) > 0 {
+			return tx.Create(&userRoles).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return changes, nil
+}
 
-	if len(userRoles) > 0 {
-		return r.db.WithContext(ctx).Create(&userRoles).Error
+// AddRolesToUser assigns the roles the user does not hold yet and leaves
+// the other assignments untouched
+func (r *repository) AddRolesToUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, validity rbac.RoleValidity, assignedBy uuid.UUID) (*rbac.UserRoleChanges, error) {
+	changes := &rbac.UserRoleChanges{
+		Added:   []uuid.UUID{},
+		Removed: []uuid.UUID{},
+		Kept:    []uuid.UUID{},
 	}
 
-	return nil
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []uuid.UUID
+		if err := tx.Model(&rbac.UserRoleEntity{}).
+			Where("user_id = ? AND role_id IN ?", userID, roleIDs).
+			Pluck("role_id", &current).Error; err != nil {
+			return err
+		}
+		existing := make(map[uuid.UUID]bool, len(current))
+		for _, roleID := range current {
+			existing[roleID] = true
+		}
+
+		var userRoles []rbac.UserRoleEntity
+		for _, roleID := range roleIDs {
+			if existing[roleID] {
+				changes.Kept = append(changes.Kept, roleID)
+				continue
+			}
+			existing[roleID] = true
+			changes.Added = append(changes.Added, roleID)
+			userRoles = append(userRoles, rbac.UserRoleEntity{
+				ID:         r.ids.New(),
+				UserID:     userID,
+				RoleID:     roleID,
+				AssignedBy: &assignedBy,
+				ValidFrom:  validity.ValidFrom,
+				ValidUntil: validity.ValidUntil,
+			})
+		}
+		if err := setRoleValidity(tx, userID, changes.Kept, validity); err != nil {
+			return err
+		}
+		// A concurrent assignment of the same role wins; the unique index
+		// turns this one into a no-op
+		if len(userRoles) > 0 {
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRoles).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// setRoleValidity gives the user's assignments of roleIDs the validity. A
+// request without one leaves them as they are, so re-saving a role list
+// does not make a time-limited assignment permanent.
+func setRoleValidity(tx *gorm.DB, userID uuid.UUID, roleIDs []uuid.UUID, validity rbac.RoleValidity) error {
+	if len(roleIDs) == 0 || validity.IsZero() {
+		return nil
+	}
+	return tx.Model(&rbac.UserRoleEntity{}).
+		Where("user_id = ? AND role_id IN ?", userID, roleIDs).
+		Updates(map[string]interface{}{"valid_from": validity.ValidFrom, "valid_until": validity.ValidUntil}).Error
+}
+
+// inEffect limits the rows of the user_roles table, or of its alias, to the
+// assignments in effect now
+func inEffect(table string) string {
+	return "(" + table + ".valid_from IS NULL OR " + table + ".valid_from <= NOW()) AND (" +
+		table + ".valid_until IS NULL OR " + table + ".valid_until > NOW())"
+}
+
+// activeRole joins the roles table, as alias, to the user_roles table or its
+// alias, keeping the assignments of roles that are active and not deleted. A
+// deleted or deactivated role neither grants nor denies anything.
+func activeRole(userRoles, alias string) string {
+	return "INNER JOIN roles AS " + alias + " ON " + alias + ".id = " + userRoles + ".role_id AND " +
+		alias + ".deleted_at IS NULL AND " + alias + ".is_active = TRUE"
 }
 
 func (r *repository) RemoveRolesFromUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID) error {
@@ -384,15 +928,55 @@ func (r *repository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]rbac
 	err := r.db.WithContext(ctx).
 		Preload("Role", "deleted_at IS NULL AND is_active = ?", true).
 		Where("user_id = ?", userID).
+		Where(inEffect("user_roles")).
 		Find(&userRoles).Error
 	return userRoles, err
 }
 
-func (r *repository) GetUsersByRole(ctx context.Context, roleID uuid.UUID, page, limit int) ([]rbac.UserRoleEntity, int64, error) {
-	var userRoles []rbac.UserRoleEntity
+// GetExpiringUserRoles pages through the assignments ending after from and
+// no later than until, soonest first, skipping deleted users and roles
+func (r *repository) GetExpiringUserRoles(ctx context.Context, from, until time.Time, page, limit int) ([]rbac.ExpiringUserRoleEntity, int64, error) {
+	var assignments []rbac.ExpiringUserRoleEntity
+	var total int64
+
+	query := r.db.WithContext(ctx).
+		Table("user_roles").
+		Joins("INNER JOIN users ON users.id = user_roles.user_id").
+		Joins("INNER JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.valid_until > ? AND user_roles.valid_until <= ? AND users.deleted_at IS NULL AND roles.deleted_at IS NULL", from, until)
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * limit
+	err := query.
+		Select("user_roles.user_id, users.username, users.email, users.fullname, user_roles.role_id, roles.name AS role_name, roles.slug AS role_slug, user_roles.valid_until").
+		Order("user_roles.valid_until ASC, users.username ASC").
+		Offset(offset).Limit(limit).
+		Scan(&assignments).Error
+
+	return assignments, total, err
+}
+
+// GetUsersByRole pages through the users holding the role, skipping
+// deleted users
+func (r *repository) GetUsersByRole(ctx context.Context, roleID uuid.UUID, page, limit int, search string) ([]rbac.RoleUserEntity, int64, error) {
+	var users []rbac.RoleUserEntity
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&rbac.UserRoleEntity{}).Where("role_id = ?", roleID)
+	query := r.db.WithContext(ctx).
+		Table("user_roles").
+		Joins("INNER JOIN users ON users.id = user_roles.user_id").
+		Where("user_roles.role_id = ? AND users.deleted_at IS NULL", roleID)
+
+	if search != "" {
+		searchPattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(users.username) LIKE ? OR LOWER(users.email) LIKE ? OR LOWER(users.fullname) LIKE ?",
+			searchPattern, searchPattern, searchPattern)
+	}
 
 	// Count total
 	if err := query.Count(&total).Error; err != nil {
@@ -401,9 +985,13 @@ func (r *repository) GetUsersByRole(ctx context.Context, roleID uuid.UUID, page,
 
 	// Get paginated results
 	offset := (page - 1) * limit
-	err := query.Order("assigned_at DESC").Offset(offset).Limit(limit).Find(&userRoles).Error
+	err := query.
+		Select("user_roles.user_id, users.username, users.email, users.fullname, user_roles.assigned_at, user_roles.assigned_by").
+		Order("user_roles.assigned_at DESC, users.username ASC").
+		Offset(offset).Limit(limit).
+		Scan(&users).Error
 
-	return userRoles, total, err
+	return users, total, err
 }
 
 func (r *repository) CheckUserHasRole(ctx context.Context, userID uuid.UUID, roleSlug string) (bool, error) {
@@ -413,30 +1001,53 @@ func (r *repository) CheckUserHasRole(ctx context.Context, userID uuid.UUID, rol
 		Joins("INNER JOIN roles ON user_roles.role_id = roles.id").
 		Where("user_roles.user_id = ? AND roles.slug = ? AND roles.deleted_at IS NULL AND roles.is_active = ?",
 			userID, roleSlug, true).
+		Where(inEffect("user_roles")).
 		Count(&count).Error
 	return count > 0, err
 }
 
-// Role-Menu methods
-func (r *repository) AssignMenusToRole(ctx context.Context, roleID uuid.UUID, menuPermissions []rbac.RoleMenuEntity, assignedBy uuid.UUID) error {
-	// First, remove existing menus
-	if err := r.db.WithContext(ctx).Where("role_id = ?", roleID).Delete(&rbac.RoleMenuEntity{}).Error; err != nil {
-		return err
+// GetUserSchoolID returns the primary school of a user, nil when the user has none
+func (r *repository) GetUserSchoolID(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	var row struct{ SchoolID *uuid.UUID }
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Select("school_id").
+		Where("id = ?", userID).
+		Take(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
 	}
+	return row.SchoolID, nil
+}
+
+// Role-Menu methods
 
-	// Then add new menus with permissions
+// AssignMenusToRole replaces the role's menus in one transaction, like
+// AssignPermissionsToRole
+func (r *repository) AssignMenusToRole(ctx context.Context, roleID uuid.UUID, menuPermissions []rbac.RoleMenuEntity, assignedBy uuid.UUID) error {
 	for i := range menuPermissions {
-		menuPermissions[i].ID = uuid.New()
+		menuPermissions[i].ID = r.ids.New()
 		menuPermissions[i].RoleID = roleID
 		menuPermissions[i].CreatedBy = &assignedBy
 		menuPermissions[i].UpdatedBy = &assignedBy
 	}
 
-	if len(menuPermissions) > 0 {
-		return r.db.WithContext(ctx).Create(&menuPermissions).Error
-	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// First, remove existing menus
+		if err := tx.Where("role_id = ?", roleID).Delete(&rbac.RoleMenuEntity{}).Error; err != nil {
+			return err
+		}
 
-	return nil
+		// Then add new menus with permissions. Select all columns so false
+		// flags are written instead of the column defaults.
+		if len(menuPermissions) > 0 {
+			return tx.Select("*").Create(&menuPermissions).Error
+		}
+		return nil
+	})
 }
 
 func (r *repository) RemoveMenusFromRole(ctx context.Context, roleID uuid.UUID, menuIDs []uuid.UUID) error {
@@ -455,15 +1066,39 @@ func (r *repository) GetRoleMenus(ctx context.Context, roleID uuid.UUID) ([]rbac
 	return roleMenus, err
 }
 
+func (r *repository) GetRoleMenusPage(ctx context.Context, roleID uuid.UUID, page, limit int) ([]rbac.RoleMenuEntity, int64, error) {
+	var roleMenus []rbac.RoleMenuEntity
+	var total int64
+
+	query := r.db.WithContext(ctx).
+		Model(&rbac.RoleMenuEntity{}).
+		Joins("INNER JOIN menus ON menus.id = role_menus.menu_id").
+		Where("role_menus.role_id = ? AND menus.deleted_at IS NULL AND menus.is_active = ?", roleID, true)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Select("role_menus.*").
+		Preload("Menu").
+		Order("menus.sort_order ASC, menus.name ASC").
+		Offset(offset).Limit(limit).
+		Find(&roleMenus).Error
+	return roleMenus, total, err
+}
+
 func (r *repository) GetUserMenus(ctx context.Context, userID uuid.UUID) ([]rbac.RoleMenuEntity, error) {
 	var roleMenus []rbac.RoleMenuEntity
 	err := r.db.WithContext(ctx).
 		Select("DISTINCT role_menus.*").
 		Table("role_menus").
 		Joins("INNER JOIN user_roles ON role_menus.role_id = user_roles.role_id").
+		Joins(activeRole("user_roles", "roles")).
 		Joins("INNER JOIN menus ON role_menus.menu_id = menus.id").
 		Preload("Menu", "deleted_at IS NULL AND is_active = ?", true).
 		Where("user_roles.user_id = ? AND menus.deleted_at IS NULL AND menus.is_active = ?", userID, true).
+		Where(inEffect("user_roles")).
 		Order("menus.sort_order ASC").
 		Find(&roleMenus).Error
 	return roleMenus, err
@@ -482,44 +1117,297 @@ func (r *repository) UpdateRoleMenuPermissions(ctx context.Context, roleMenuID u
 }
 
 // Complex queries
+
+// GetUserPermissions returns the permissions the user effectively has: those
+// allowed by one of their roles and not covered by a deny of any, see
+// rbac.Allowed
 func (r *repository) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]rbac.PermissionEntity, error) {
+	denied := r.db.
+		Table("role_permissions AS denied").
+		Select("1").
+		Joins("INNER JOIN permissions AS denied_permissions ON denied.permission_id = denied_permissions.id").
+		Joins("INNER JOIN user_roles AS denied_roles ON denied.role_id = denied_roles.role_id").
+		Joins(activeRole("denied_roles", "denied_role")).
+		Where("denied_roles.user_id = ? AND denied.effect = ?", userID, rbac.EffectDeny).
+		Where("denied_permissions.resource IN (permissions.resource, ?) AND denied_permissions.action IN (permissions.action, ?, ?)",
+			rbac.Wildcard, rbac.ActionManage, rbac.Wildcard).
+		Where("denied_permissions.deleted_at IS NULL AND denied_permissions.is_active = ?", true).
+		Where(inEffect("denied_roles"))
+
 	var permissions []rbac.PermissionEntity
 	err := r.db.WithContext(ctx).
 		Select("DISTINCT permissions.*").
 		Table("permissions").
 		Joins("INNER JOIN role_permissions ON permissions.id = role_permissions.permission_id").
 		Joins("INNER JOIN user_roles ON role_permissions.role_id = user_roles.role_id").
-		Where("user_roles.user_id = ? AND permissions.deleted_at IS NULL AND permissions.is_active = ?", userID, true).
+		Joins(activeRole("user_roles", "roles")).
+		Where("user_roles.user_id = ? AND role_permissions.effect = ? AND permissions.deleted_at IS NULL AND permissions.is_active = ?", userID, rbac.EffectAllow, true).
+		Where(inEffect("user_roles")).
+		Where("NOT EXISTS (?)", denied).
 		Find(&permissions).Error
 	return permissions, err
 }
 
+// CheckUserHasPermission collects the effects every role of the user has on
+// the permissions covering resource:action and lets rbac.Decide weigh them,
+// so a deny wins over allows once manage and wildcards are expanded
 func (r *repository) CheckUserHasPermission(ctx context.Context, userID uuid.UUID, resource, action string) (bool, error) {
-	var count int64
+	var effects []rbac.PermissionEffect
+	err := r.userPermissionEffects(ctx, userID).
+		Where("permissions.resource IN (?, ?) AND permissions.action IN (?, ?, ?)",
+			resource, rbac.Wildcard, action, rbac.ActionManage, rbac.Wildcard).
+		Scan(&effects).Error
+	return rbac.Decide(effects, resource, action), err
+}
+
+func (r *repository) GetUserPermissionEffects(ctx context.Context, userID uuid.UUID) ([]rbac.PermissionEffect, error) {
+	var effects []rbac.PermissionEffect
+	err := r.userPermissionEffects(ctx, userID).Scan(&effects).Error
+	return effects, err
+}
+
+// userPermissionEffects selects the effect of each active permission of the
+// user's roles in effect
+func (r *repository) userPermissionEffects(ctx context.Context, userID uuid.UUID) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Table("permissions").
+		Select("permissions.resource, permissions.action, role_permissions.effect").
+		Joins("INNER JOIN role_permissions ON permissions.id = role_permissions.permission_id").
+		Joins("INNER JOIN user_roles ON role_permissions.role_id = user_roles.role_id").
+		Joins(activeRole("user_roles", "roles")).
+		Where("user_roles.user_id = ? AND permissions.deleted_at IS NULL AND permissions.is_active = ?", userID, true).
+		Where(inEffect("user_roles"))
+}
+
+func (r *repository) GetPermissionGrants(ctx context.Context, userID uuid.UUID, resource, action string) ([]rbac.PermissionGrantEntity, error) {
+	var grants []rbac.PermissionGrantEntity
 	err := r.db.WithContext(ctx).
 		Table("permissions").
 		Joins("INNER JOIN role_permissions ON permissions.id = role_permissions.permission_id").
 		Joins("INNER JOIN user_roles ON role_permissions.role_id = user_roles.role_id").
-		Where("user_roles.user_id = ? AND permissions.resource = ? AND permissions.action = ? AND permissions.deleted_at IS NULL AND permissions.is_active = ?",
-			userID, resource, action, true).
-		Count(&count).Error
-	return count > 0, err
+		Joins(activeRole("user_roles", "roles")).
+		Where("user_roles.user_id = ? AND permissions.resource IN (?, ?) AND permissions.action IN (?, ?, ?) AND permissions.deleted_at IS NULL AND permissions.is_active = ?",
+			userID, resource, rbac.Wildcard, action, rbac.ActionManage, rbac.Wildcard, true).
+		Where(inEffect("user_roles")).
+		Select("user_roles.id AS user_role_id, user_roles.assigned_at, user_roles.assigned_by, user_roles.valid_from, user_roles.valid_until, " +
+			"roles.id AS role_id, roles.name AS role_name, roles.slug AS role_slug, " +
+			"role_permissions.id AS role_permission_id, permissions.id AS permission_id, permissions.slug AS permission_slug, " +
+			"role_permissions.effect, role_permissions.created_at AS granted_at, role_permissions.created_by AS granted_by").
+		Order("user_roles.assigned_at ASC, roles.slug ASC").
+		Scan(&grants).Error
+	return grants, err
 }
 
+// GetUserAccessibleMenus returns the role menus of the user's roles, each
+// menu with its active descendants nested at any depth. Rows without
+// can_view are kept, as another role may grant the view.
 func (r *repository) GetUserAccessibleMenus(ctx context.Context, userID uuid.UUID) ([]rbac.RoleMenuEntity, error) {
 	var roleMenus []rbac.RoleMenuEntity
 	err := r.db.WithContext(ctx).
 		Select("role_menus.*").
 		Table("role_menus").
 		Joins("INNER JOIN user_roles ON role_menus.role_id = user_roles.role_id").
+		Joins(activeRole("user_roles", "roles")).
 		Joins("INNER JOIN menus ON role_menus.menu_id = menus.id").
-		Preload("Menu.Children", func(db *gorm.DB) *gorm.DB {
-			return db.Where("deleted_at IS NULL AND is_active = ?", true).Order("sort_order ASC")
-		}).
 		Preload("Menu", "deleted_at IS NULL AND is_active = ?", true).
-		Where("user_roles.user_id = ? AND menus.deleted_at IS NULL AND menus.is_active = ? AND role_menus.can_view = ?",
-			userID, true, true).
+		Where("user_roles.user_id = ? AND menus.deleted_at IS NULL AND menus.is_active = ?", userID, true).
+		Where(inEffect("user_roles")).
 		Order("menus.sort_order ASC").
 		Find(&roleMenus).Error
-	return roleMenus, err
+	if err != nil || len(roleMenus) == 0 {
+		return roleMenus, err
+	}
+
+	tree, err := r.activeMenuTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range roleMenus {
+		roleMenus[i].Menu.Children = tree.children(roleMenus[i].MenuID)
+	}
+	return roleMenus, nil
+}
+
+// orphanBatchSize bounds the rows removed by one DELETE while pruning orphans
+const orphanBatchSize = 1000
+
+// orphanTables lists the junction tables PruneOrphans cleans with the
+// foreign key columns of each and the table they reference
+var orphanTables = []struct {
+	table string
+	refs  [][2]string
+}{
+	{"role_menus", [][2]string{{"role_id", "roles"}, {"menu_id", "menus"}}},
+	{"role_permissions", [][2]string{{"role_id", "roles"}, {"permission_id", "permissions"}}},
+	{"user_roles", [][2]string{{"role_id", "roles"}, {"user_id", "users"}}},
+}
+
+// PruneOrphans deletes junction rows whose role, menu, permission or user no
+// longer exists or was soft deleted before deletedBefore. Rows are deleted in
+// batches so a large cleanup does not hold locks on the table for long.
+func (r *repository) PruneOrphans(ctx context.Context, deletedBefore time.Time) (*rbac.PruneOrphansData, error) {
+	counts := make(map[string]int64, len(orphanTables))
+
+	for _, t := range orphanTables {
+		conditions := make([]string, 0, len(t.refs))
+		for _, ref := range t.refs {
+			conditions = append(conditions, fmt.Sprintf(
+				"NOT EXISTS (SELECT 1 FROM %s ref WHERE ref.id = %s.%s AND (ref.deleted_at IS NULL OR ref.deleted_at >= @cutoff))",
+				ref[1], t.table, ref[0]))
+		}
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s LIMIT @limit", t.table, strings.Join(conditions, " OR "))
+
+		for {
+			res := r.db.WithContext(ctx).Exec(query, map[string]interface{}{"cutoff": deletedBefore, "limit": orphanBatchSize})
+			if res.Error != nil {
+				return nil, fmt.Errorf("failed to prune %s: %w", t.table, res.Error)
+			}
+			counts[t.table] += res.RowsAffected
+			if res.RowsAffected < orphanBatchSize {
+				break
+			}
+		}
+	}
+
+	return &rbac.PruneOrphansData{
+		RoleMenus:       counts["role_menus"],
+		RolePermissions: counts["role_permissions"],
+		UserRoles:       counts["user_roles"],
+	}, nil
+}
+
+// GetRBACSnapshot loads every role, permission and menu, deleted ones
+// included so their slugs are known, and the links between them
+func (r *repository) GetRBACSnapshot(ctx context.Context) (*rbac.RBACSnapshot, error) {
+	var snapshot rbac.RBACSnapshot
+	db := r.db.WithContext(ctx)
+	if err := db.Order("slug ASC").Find(&snapshot.Roles).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("slug ASC").Find(&snapshot.Permissions).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("slug ASC").Find(&snapshot.Menus).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&snapshot.RolePermissions).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&snapshot.RoleMenus).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// ImportRBAC writes the plan in one transaction. Creates select every
+// column so false flags are written instead of the column defaults.
+func (r *repository) ImportRBAC(ctx context.Context, plan *rbac.RBACImportPlan) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(plan.CreatePermissions) > 0 {
+			if err := tx.Select("*").Create(&plan.CreatePermissions).Error; err != nil {
+				return err
+			}
+		}
+		for i := range plan.UpdatePermissions {
+			if err := tx.Model(&plan.UpdatePermissions[i]).
+				Select("name", "module", "description", "is_active", "updated_at", "updated_by").
+				Updates(&plan.UpdatePermissions[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		// One at a time, so each parent exists before its children
+		for i := range plan.CreateMenus {
+			if err := tx.Select("*").Create(&plan.CreateMenus[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range plan.UpdateMenus {
+			if err := tx.Model(&plan.UpdateMenus[i]).
+				Select("name", "url", "icon", "parent_id", "sort_order", "is_active", "updated_at", "updated_by").
+				Updates(&plan.UpdateMenus[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(plan.CreateRoles) > 0 {
+			if err := tx.Select("*").Create(&plan.CreateRoles).Error; err != nil {
+				return err
+			}
+		}
+		for i := range plan.UpdateRoles {
+			if err := tx.Model(&plan.UpdateRoles[i]).
+				Select("name", "description", "is_active", "assignable_by_school_admin", "default_menu_id", "priority", "updated_at", "updated_by").
+				Updates(&plan.UpdateRoles[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(plan.DeleteRolePermissions) > 0 {
+			if err := tx.Where("id IN ?", plan.DeleteRolePermissions).Delete(&rbac.RolePermissionEntity{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, rp := range plan.UpdateRolePermissions {
+			if err := tx.Model(&rbac.RolePermissionEntity{}).
+				Where("id = ?", rp.ID).
+				Update("effect", rp.Effect).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.CreateRolePermissions) > 0 {
+			if err := tx.Create(&plan.CreateRolePermissions).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(plan.DeleteRoleMenus) > 0 {
+			if err := tx.Where("id IN ?", plan.DeleteRoleMenus).Delete(&rbac.RoleMenuEntity{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range plan.UpdateRoleMenus {
+			if err := tx.Model(&plan.UpdateRoleMenus[i]).
+				Select("can_view", "can_create", "can_edit", "can_delete", "updated_at", "updated_by").
+				Updates(&plan.UpdateRoleMenus[i]).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.CreateRoleMenus) > 0 {
+			return tx.Select("*").Create(&plan.CreateRoleMenus).Error
+		}
+		return nil
+	})
+}
+
+// sortBy orders a listing by the sort the service validated. The orders
+// added after it break ties, with the id last so pages never overlap.
+func sortBy(query *gorm.DB, sort rbac.ListSort) *gorm.DB {
+	if sort.SortBy == "" {
+		return query
+	}
+	return query.Order(clause.OrderByColumn{
+		Column: clause.Column{Name: sort.SortBy},
+		Desc:   sort.Order == rbac.SortDesc,
+	})
+}
+
+// countCheck makes the insert of a first check add one to an existing row
+var countCheck = clause.OnConflict{
+	Columns: []clause.Column{{Name: "kind"}, {Name: "result"}},
+	DoUpdates: clause.Assignments(map[string]interface{}{
+		"total":      gorm.Expr("total + 1"),
+		"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+	}),
+}
+
+func (r *repository) CountCheck(ctx context.Context, kind, result string) error {
+	row := rbac.CheckCountEntity{Kind: kind, Result: result, Total: 1}
+	return r.db.WithContext(ctx).Clauses(countCheck).Create(&row).Error
+}
+
+func (r *repository) GetCheckCounts(ctx context.Context) ([]rbac.CheckCountEntity, error) {
+	var rows []rbac.CheckCountEntity
+	err := r.db.WithContext(ctx).Order("kind ASC, result ASC").Find(&rows).Error
+	return rows, err
 }
@@ -0,0 +1,816 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// noConn lets dry runs begin and commit transactions without a server;
+// nothing else reaches it
+type noConn struct {
+	gorm.ConnPool
+}
+
+func (c noConn) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) { return c, nil }
+func (noConn) Commit() error                                                    { return nil }
+func (noConn) Rollback() error                                                  { return nil }
+
+// recorder returns a repository on a database that renders statements
+// without a server, and the raw statements it ran
+func recorder(t *testing.T) (*repository, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      noConn{},
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []string
+	err = db.Callback().Raw().After("gorm:raw").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Dry runs affect no rows; pretend the role is deleted
+	err = db.Callback().Update().After("gorm:update").Register("test:affect", func(tx *gorm.DB) {
+		tx.RowsAffected = 1
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &repository{db: db, ids: idgen.NewSequence()}, &statements
+}
+
+// sqliteDriver is SQLite with the MySQL functions the repository calls
+const sqliteDriver = "sqlite3_mysql"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		return conn.RegisterFunc("NOW", func() string {
+			return time.Now().UTC().Format(sqlite3.SQLiteTimestampFormats[0])
+		}, false)
+	}})
+}
+
+var limitedDelete = regexp.MustCompile(`(?s)^DELETE FROM (\w+) WHERE (.*) LIMIT \?$`)
+
+// sqliteRepo returns a repository on an in-memory SQLite database with
+// tables for models
+func sqliteRepo(t *testing.T, models ...interface{}) *repository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: sqliteDriver, DSN: "file::memory:"}), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	// SQLite cannot limit a DELETE; select the rows to delete instead
+	err = db.Callback().Raw().Before("gorm:raw").Register("test:delete_limit", func(tx *gorm.DB) {
+		m := limitedDelete.FindStringSubmatch(tx.Statement.SQL.String())
+		if m == nil {
+			return
+		}
+		tx.Statement.SQL.Reset()
+		tx.Statement.SQL.WriteString("DELETE FROM " + m[1] + " WHERE rowid IN (SELECT rowid FROM " + m[1] + " WHERE " + m[2] + " LIMIT ?)")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return &repository{db: db, ids: idgen.NewSequence()}
+}
+
+func TestRestoreRoleSkipsDeletedTargets(t *testing.T) {
+	r, statements := recorder(t)
+	if _, err := r.RestoreRole(context.Background(), uuid.New(), uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"INSERT INTO user_roles":       "FROM users u WHERE u.id = h.user_id AND u.deleted_at IS NULL",
+		"INSERT INTO role_permissions": "FROM permissions p WHERE p.id = h.permission_id AND p.deleted_at IS NULL",
+		"INSERT INTO role_menus":       "FROM menus m WHERE m.id = h.menu_id AND m.deleted_at IS NULL",
+	}
+	for insert, check := range want {
+		var found bool
+		for _, stmt := range *statements {
+			if strings.Contains(stmt, insert) {
+				found = true
+				if !strings.Contains(stmt, check) {
+					t.Errorf("%s restores rows without %q:\n%s", insert, check, stmt)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s did not run", insert)
+		}
+	}
+}
+
+func TestRestoreRoleCountsSkippedAssignments(t *testing.T) {
+	tests := []struct {
+		name                        string
+		archived, restored, skipped int64
+	}{
+		{"full restore", 3, 3, 0},
+		{"partial restore with a deleted permission", 3, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := recorder(t)
+			// Archived permissions are counted, then only those whose
+			// permission still exists are inserted back
+			err := r.db.Callback().Query().After("gorm:query").Register("test:archived", func(tx *gorm.DB) {
+				if count, ok := tx.Statement.Dest.(*int64); ok && tx.Statement.Table == "role_permissions_history" {
+					*count, tx.RowsAffected = tt.archived, 1
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.db.Callback().Raw().After("gorm:raw").Register("test:restored", func(tx *gorm.DB) {
+				if strings.Contains(tx.Statement.SQL.String(), "INSERT INTO role_permissions ") {
+					tx.RowsAffected = tt.restored
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := r.RestoreRole(context.Background(), uuid.New(), uuid.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.RestoredPermissions != int(tt.restored) || result.SkippedPermissions != int(tt.skipped) {
+				t.Errorf("restored %d and skipped %d permissions, want %d and %d",
+					result.RestoredPermissions, result.SkippedPermissions, tt.restored, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestAssignRolesKeepsUnchangedAssignments(t *testing.T) {
+	r := sqliteRepo(t, &rbac.UserRoleEntity{})
+	ctx := context.Background()
+	userID, firstAdmin, secondAdmin := uuid.New(), uuid.New(), uuid.New()
+	teacher, mentor, student := uuid.New(), uuid.New(), uuid.New()
+	assignedAt := time.Date(2024, 7, 15, 8, 0, 0, 0, time.UTC)
+	for _, roleID := range []uuid.UUID{teacher, mentor} {
+		err := r.db.Create(&rbac.UserRoleEntity{ID: uuid.New(), UserID: userID, RoleID: roleID, AssignedAt: assignedAt, AssignedBy: &firstAdmin}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Keep teacher, drop mentor and add student
+	changes, err := r.AssignRolesToUser(ctx, userID, []uuid.UUID{teacher, student}, rbac.RoleValidity{}, secondAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changes.Kept, []uuid.UUID{teacher}) || !slices.Equal(changes.Removed, []uuid.UUID{mentor}) || !slices.Equal(changes.Added, []uuid.UUID{student}) {
+		t.Errorf("changes = %+v, want teacher kept, mentor removed and student added", *changes)
+	}
+
+	var rows []rbac.UserRoleEntity
+	if err := r.db.Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	held := map[uuid.UUID]rbac.UserRoleEntity{}
+	for _, row := range rows {
+		held[row.RoleID] = row
+	}
+	if len(held) != 2 {
+		t.Fatalf("user holds %d roles, want 2", len(held))
+	}
+	if kept := held[teacher]; !kept.AssignedAt.Equal(assignedAt) || kept.AssignedBy == nil || *kept.AssignedBy != firstAdmin {
+		t.Errorf("kept role assigned at %s by %v, want %s by %s", kept.AssignedAt, kept.AssignedBy, assignedAt, firstAdmin)
+	}
+	if added, ok := held[student]; !ok || added.AssignedBy == nil || *added.AssignedBy != secondAdmin {
+		t.Errorf("added role = %+v, want it assigned by %s", added, secondAdmin)
+	}
+	if want := idgen.NewSequence().New(); held[student].ID != want {
+		t.Errorf("added role ID = %s, want %s from the ID generator", held[student].ID, want)
+	}
+}
+
+func TestAssignRolesKeepsValidityWithoutOne(t *testing.T) {
+	r := sqliteRepo(t, &rbac.UserRoleEntity{})
+	ctx := context.Background()
+	userID, admin, substitute := uuid.New(), uuid.New(), uuid.New()
+	validUntil := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	err := r.db.Create(&rbac.UserRoleEntity{ID: uuid.New(), UserID: userID, RoleID: substitute, AssignedBy: &admin, ValidUntil: &validUntil}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	held := func() rbac.UserRoleEntity {
+		t.Helper()
+		var row rbac.UserRoleEntity
+		if err := r.db.Where("user_id = ? AND role_id = ?", userID, substitute).First(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+		return row
+	}
+
+	// Re-saving the role list without a validity keeps the kept role's
+	if _, err := r.AssignRolesToUser(ctx, userID, []uuid.UUID{substitute}, rbac.RoleValidity{}, admin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddRolesToUser(ctx, userID, []uuid.UUID{substitute}, rbac.RoleValidity{}, admin); err != nil {
+		t.Fatal(err)
+	}
+	if row := held(); row.ValidUntil == nil || !row.ValidUntil.Equal(validUntil) {
+		t.Errorf("valid_until = %v after re-saving without a validity, want %s", row.ValidUntil, validUntil)
+	}
+
+	// A validity given replaces it
+	extended := validUntil.AddDate(0, 1, 0)
+	if _, err := r.AssignRolesToUser(ctx, userID, []uuid.UUID{substitute}, rbac.RoleValidity{ValidUntil: &extended}, admin); err != nil {
+		t.Fatal(err)
+	}
+	if row := held(); row.ValidUntil == nil || !row.ValidUntil.Equal(extended) {
+		t.Errorf("valid_until = %v after extending, want %s", row.ValidUntil, extended)
+	}
+}
+
+// orphanUser is the part of the users table PruneOrphans reads
+type orphanUser struct {
+	ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
+	DeletedAt *time.Time
+}
+
+func (orphanUser) TableName() string {
+	return "users"
+}
+
+func TestPruneOrphans(t *testing.T) {
+	r := sqliteRepo(t, &rbac.RoleEntity{}, &rbac.MenuEntity{}, &rbac.PermissionEntity{}, &orphanUser{},
+		&rbac.RoleMenuEntity{}, &rbac.RolePermissionEntity{}, &rbac.UserRoleEntity{})
+	cutoff := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	longAgo, recently := cutoff.Add(-48*time.Hour), cutoff.Add(48*time.Hour)
+
+	// Live, long deleted and recently deleted roles; a missing ID stands
+	// for a row deleted for good
+	role, deletedRole, restorableRole, missing := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	menu, deletedMenu := uuid.New(), uuid.New()
+	permission := uuid.New()
+	user, deletedUser := uuid.New(), uuid.New()
+	fixtures := []interface{}{
+		&rbac.RoleEntity{ID: role, Name: "Guru", Slug: "teacher"},
+		&rbac.RoleEntity{ID: deletedRole, Name: "Mentor", Slug: "mentor", DeletedAt: &longAgo},
+		&rbac.RoleEntity{ID: restorableRole, Name: "Siswa", Slug: "student", DeletedAt: &recently},
+		&rbac.MenuEntity{ID: menu, Name: "Dashboard", Slug: "dashboard"},
+		&rbac.MenuEntity{ID: deletedMenu, Name: "Laporan", Slug: "reports", DeletedAt: &longAgo},
+		&rbac.PermissionEntity{ID: permission, Name: "View users", Slug: "users.view", Module: "users", Resource: "users", Action: "view"},
+		&orphanUser{ID: user},
+		&orphanUser{ID: deletedUser, DeletedAt: &longAgo},
+
+		&rbac.RoleMenuEntity{ID: uuid.New(), RoleID: role, MenuID: menu},
+		&rbac.RoleMenuEntity{ID: uuid.New(), RoleID: restorableRole, MenuID: menu},
+		&rbac.RoleMenuEntity{ID: uuid.New(), RoleID: role, MenuID: missing},
+		&rbac.RoleMenuEntity{ID: uuid.New(), RoleID: role, MenuID: deletedMenu},
+		&rbac.RoleMenuEntity{ID: uuid.New(), RoleID: deletedRole, MenuID: menu},
+
+		&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: role, PermissionID: permission},
+		&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: role, PermissionID: missing},
+		&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: missing, PermissionID: permission},
+
+		&rbac.UserRoleEntity{ID: uuid.New(), UserID: user, RoleID: role},
+		&rbac.UserRoleEntity{ID: uuid.New(), UserID: user, RoleID: restorableRole},
+		&rbac.UserRoleEntity{ID: uuid.New(), UserID: deletedUser, RoleID: role},
+		&rbac.UserRoleEntity{ID: uuid.New(), UserID: user, RoleID: deletedRole},
+	}
+	for _, fixture := range fixtures {
+		if err := r.db.Create(fixture).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := r.PruneOrphans(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (rbac.PruneOrphansData{RoleMenus: 3, RolePermissions: 2, UserRoles: 2}); *got != want {
+		t.Errorf("pruned %+v, want %+v", *got, want)
+	}
+
+	// Only assignments between live or restorable rows are left
+	var roleMenus []rbac.RoleMenuEntity
+	var rolePermissions []rbac.RolePermissionEntity
+	var userRoles []rbac.UserRoleEntity
+	for _, rows := range []interface{}{&roleMenus, &rolePermissions, &userRoles} {
+		if err := r.db.Find(rows).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	var left []string
+	for _, rm := range roleMenus {
+		left = append(left, "menu "+rm.RoleID.String()+" "+rm.MenuID.String())
+	}
+	for _, rp := range rolePermissions {
+		left = append(left, "permission "+rp.RoleID.String()+" "+rp.PermissionID.String())
+	}
+	for _, ur := range userRoles {
+		left = append(left, "user "+ur.RoleID.String()+" "+ur.UserID.String())
+	}
+	want := []string{
+		"menu " + role.String() + " " + menu.String(),
+		"menu " + restorableRole.String() + " " + menu.String(),
+		"permission " + role.String() + " " + permission.String(),
+		"user " + role.String() + " " + user.String(),
+		"user " + restorableRole.String() + " " + user.String(),
+	}
+	slices.Sort(left)
+	slices.Sort(want)
+	if !slices.Equal(left, want) {
+		t.Errorf("rows left:\n%s\nwant:\n%s", strings.Join(left, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Nothing is left to prune
+	if again, err := r.PruneOrphans(context.Background(), cutoff); err != nil || *again != (rbac.PruneOrphansData{}) {
+		t.Errorf("second prune = %+v, %v, want nothing", again, err)
+	}
+}
+
+func TestPruneOrphansInBatches(t *testing.T) {
+	r := sqliteRepo(t, &rbac.RoleEntity{}, &rbac.MenuEntity{}, &rbac.PermissionEntity{}, &orphanUser{},
+		&rbac.RoleMenuEntity{}, &rbac.RolePermissionEntity{}, &rbac.UserRoleEntity{})
+	orphans := make([]rbac.RoleMenuEntity, orphanBatchSize+1)
+	for i := range orphans {
+		orphans[i] = rbac.RoleMenuEntity{ID: uuid.New(), RoleID: uuid.New(), MenuID: uuid.New()}
+	}
+	if err := r.db.CreateInBatches(orphans, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.PruneOrphans(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RoleMenus != orphanBatchSize+1 {
+		t.Errorf("pruned %d role menus, want %d", got.RoleMenus, orphanBatchSize+1)
+	}
+}
+
+func TestEditRacingDeleteKeepsRowDeleted(t *testing.T) {
+	r := sqliteRepo(t, &rbac.RoleEntity{}, &rbac.PermissionEntity{}, &rbac.MenuEntity{})
+	ctx := context.Background()
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	deletedBy := uuid.New()
+
+	// Each edit was loaded before another admin deleted the row
+	role := &rbac.RoleEntity{ID: uuid.New(), Name: "Guru", Slug: "teacher", IsActive: true}
+	permission := &rbac.PermissionEntity{ID: uuid.New(), Name: "View users", Slug: "users.view", Module: "users", Resource: "users", Action: "view", IsActive: true}
+	menu := &rbac.MenuEntity{ID: uuid.New(), Name: "Dashboard", Slug: "dashboard", IsActive: true}
+	tests := []struct {
+		name     string
+		model    interface{}
+		id       uuid.UUID
+		original string
+		update   func() error
+	}{
+		{"role", role, role.ID, role.Name, func() error {
+			role.Name = "Guru Pamong"
+			return r.UpdateRole(ctx, role)
+		}},
+		{"permission", permission, permission.ID, permission.Name, func() error {
+			permission.Name = "List users"
+			return r.UpdatePermission(ctx, permission)
+		}},
+		{"menu", menu, menu.ID, menu.Name, func() error {
+			menu.Name = "Beranda"
+			return r.UpdateMenu(ctx, menu)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.db.Create(tt.model).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := r.db.Model(tt.model).Where("id = ?", tt.id).
+				Updates(map[string]interface{}{"deleted_at": deletedAt, "deleted_by": deletedBy}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.update(); !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Errorf("update err = %v, want %v", err, gorm.ErrRecordNotFound)
+			}
+
+			var row struct {
+				Name      string
+				DeletedAt *time.Time
+				DeletedBy *uuid.UUID
+			}
+			if err := r.db.Model(tt.model).Where("id = ?", tt.id).Take(&row).Error; err != nil {
+				t.Fatal(err)
+			}
+			if row.DeletedAt == nil || !row.DeletedAt.Equal(deletedAt) || row.DeletedBy == nil || *row.DeletedBy != deletedBy {
+				t.Errorf("row deleted at %v by %v, want %s by %s", row.DeletedAt, row.DeletedBy, deletedAt, deletedBy)
+			}
+			if row.Name != tt.original {
+				t.Errorf("deleted row was renamed to %q", row.Name)
+			}
+		})
+	}
+}
+
+func TestForceDeletePermission(t *testing.T) {
+	r := sqliteRepo(t, &rbac.RoleEntity{}, &rbac.PermissionEntity{}, &rbac.RolePermissionEntity{})
+	ctx := context.Background()
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	teacher, principal, mentor := uuid.New(), uuid.New(), uuid.New()
+	target, other := uuid.New(), uuid.New()
+	fixtures := []interface{}{
+		&rbac.RoleEntity{ID: teacher, Name: "Guru", Slug: "teacher"},
+		&rbac.RoleEntity{ID: principal, Name: "Kepala Sekolah", Slug: "principal"},
+		&rbac.RoleEntity{ID: mentor, Name: "Mentor", Slug: "mentor", DeletedAt: &deletedAt},
+		&rbac.PermissionEntity{ID: target, Name: "Delete users", Slug: "users.delete", Module: "users", Resource: "users", Action: "delete"},
+		&rbac.PermissionEntity{ID: other, Name: "View users", Slug: "users.view", Module: "users", Resource: "users", Action: "view"},
+		// Attached as an allow and as a deny; a deleted role does not count
+		&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: teacher, PermissionID: target},
+		&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: principal, PermissionID: target, Effect: rbac.EffectDeny},
+		&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: mentor, PermissionID: target},
+		&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: teacher, PermissionID: other},
+	}
+	for _, f := range fixtures {
+		if err := r.db.Create(f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := r.GetPermissionRoleNames(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Guru", "Kepala Sekolah"}; !slices.Equal(names, want) {
+		t.Errorf("attached to %v, want %v", names, want)
+	}
+
+	deletedBy := uuid.New()
+	if err := r.ForceDeletePermission(ctx, target, deletedBy); err != nil {
+		t.Fatal(err)
+	}
+	var attached []uuid.UUID
+	if err := r.db.Model(&rbac.RolePermissionEntity{}).Pluck("permission_id", &attached).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(attached, []uuid.UUID{other}) {
+		t.Errorf("role permissions left for %v, want only %s", attached, other)
+	}
+	var deleted rbac.PermissionEntity
+	if err := r.db.First(&deleted, "id = ?", target).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deleted.DeletedAt == nil || deleted.DeletedBy == nil || *deleted.DeletedBy != deletedBy {
+		t.Errorf("permission deleted at %v by %v, want deleted by %s", deleted.DeletedAt, deleted.DeletedBy, deletedBy)
+	}
+
+	// A permission that is already deleted is reported missing
+	if err := r.ForceDeletePermission(ctx, target, deletedBy); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("deleting again: err = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestFailedInsertKeepsAssignments(t *testing.T) {
+	r := sqliteRepo(t, &rbac.RoleEntity{}, &rbac.MenuEntity{}, &rbac.PermissionEntity{},
+		&rbac.RoleMenuEntity{}, &rbac.RolePermissionEntity{}, &rbac.UserRoleEntity{})
+	ctx := context.Background()
+	// Inserts fail while failing is set, as on a dropped connection
+	failing := false
+	err := r.db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		if failing {
+			tx.AddError(errors.New("connection lost"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roleID, userID, adminID := uuid.New(), uuid.New(), uuid.New()
+	permissionID, menuID, heldRoleID := uuid.New(), uuid.New(), uuid.New()
+	if err := r.AssignPermissionsToRole(ctx, roleID, []uuid.UUID{permissionID}, nil, adminID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AssignMenusToRole(ctx, roleID, []rbac.RoleMenuEntity{{MenuID: menuID, CanView: true}}, adminID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AssignRolesToUser(ctx, userID, []uuid.UUID{heldRoleID}, rbac.RoleValidity{}, adminID); err != nil {
+		t.Fatal(err)
+	}
+
+	failing = true
+	tests := []struct {
+		name    string
+		replace func() error
+		model   interface{}
+		column  string
+		want    uuid.UUID
+	}{
+		{"role permissions", func() error {
+			return r.AssignPermissionsToRole(ctx, roleID, []uuid.UUID{uuid.New()}, nil, adminID)
+		}, &rbac.RolePermissionEntity{}, "permission_id", permissionID},
+		{"role menus", func() error {
+			return r.AssignMenusToRole(ctx, roleID, []rbac.RoleMenuEntity{{MenuID: uuid.New()}}, adminID)
+		}, &rbac.RoleMenuEntity{}, "menu_id", menuID},
+		{"user roles", func() error {
+			_, err := r.AssignRolesToUser(ctx, userID, []uuid.UUID{uuid.New()}, rbac.RoleValidity{}, adminID)
+			return err
+		}, &rbac.UserRoleEntity{}, "role_id", heldRoleID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.replace(); err == nil {
+				t.Fatal("replacing succeeded while inserts fail")
+			}
+			var left []uuid.UUID
+			if err := r.db.Model(tt.model).Pluck(tt.column, &left).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(left, []uuid.UUID{tt.want}) {
+				t.Errorf("%s left %v, want the original %s", tt.name, left, tt.want)
+			}
+		})
+	}
+}
+
+// menuPaths lists every menu in a tree by its slug path, depth first in the
+// order the tree holds them
+func menuPaths(prefix string, menus []rbac.MenuEntity) []string {
+	var paths []string
+	for _, menu := range menus {
+		path := prefix + menu.Slug
+		paths = append(paths, path)
+		paths = append(paths, menuPaths(path+"/", menu.Children)...)
+	}
+	return paths
+}
+
+func TestMenuTreeNestsEveryLevel(t *testing.T) {
+	r := sqliteRepo(t, &rbac.RoleEntity{}, &rbac.MenuEntity{}, &rbac.PermissionEntity{},
+		&rbac.RoleMenuEntity{}, &rbac.RolePermissionEntity{}, &rbac.UserRoleEntity{})
+	ctx := context.Background()
+	ids := make(map[string]uuid.UUID)
+	menu := func(slug, parent string, sortOrder int) *rbac.MenuEntity {
+		ids[slug] = uuid.New()
+		m := &rbac.MenuEntity{ID: ids[slug], Name: slug, Slug: slug, SortOrder: sortOrder, IsActive: true}
+		if parent != "" {
+			parentID := ids[parent]
+			m.ParentID = &parentID
+		}
+		return m
+	}
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	deleted := menu("reports", "classes", 3)
+	deleted.DeletedAt = &deletedAt
+	fixtures := []*rbac.MenuEntity{
+		menu("academics", "", 2),
+		menu("dashboard", "", 1),
+		menu("classes", "academics", 1),
+		// Siblings sort by sort_order, not by insertion
+		menu("class-detail", "classes", 2),
+		menu("schedules", "classes", 1),
+		menu("grades", "class-detail", 1),
+		menu("archive", "classes", 4),
+		menu("archive-detail", "archive", 1),
+		deleted,
+		menu("report-detail", "reports", 1),
+		menu("loop-a", "", 9),
+		menu("loop-b", "loop-a", 1),
+	}
+	for _, m := range fixtures {
+		if err := r.db.Create(m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// is_active defaults to true, so a false value has to be written apart;
+	// loop-a is made a child of loop-b, a cycle no top-level menu reaches
+	if err := r.db.Model(&rbac.MenuEntity{}).Where("id = ?", ids["archive"]).Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := r.db.Model(&rbac.MenuEntity{}).Where("id = ?", ids["loop-a"]).Update("parent_id", ids["loop-b"]).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := r.GetMenuTree(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Menus under an inactive or deleted menu are hidden with it
+	want := []string{
+		"dashboard",
+		"academics",
+		"academics/classes",
+		"academics/classes/schedules",
+		"academics/classes/class-detail",
+		"academics/classes/class-detail/grades",
+	}
+	if got := menuPaths("", tree); !slices.Equal(got, want) {
+		t.Errorf("menu tree = %v, want %v", got, want)
+	}
+
+	// A user's role menus carry the same subtrees; one inside the cycle
+	// ends where the walk comes back around
+	roleID, userID := uuid.New(), uuid.New()
+	if err := r.db.Create(&rbac.RoleEntity{ID: roleID, Name: "Guru", Slug: "teacher", IsActive: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := r.db.Create(&rbac.UserRoleEntity{ID: uuid.New(), UserID: userID, RoleID: roleID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, slug := range []string{"classes", "loop-a"} {
+		if err := r.db.Create(&rbac.RoleMenuEntity{ID: uuid.New(), RoleID: roleID, MenuID: ids[slug], CanView: true}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	roleMenus, err := r.GetUserAccessibleMenus(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	menus := make([]rbac.MenuEntity, len(roleMenus))
+	for i, rm := range roleMenus {
+		menus[i] = rm.Menu
+	}
+	want = []string{
+		"classes",
+		"classes/schedules",
+		"classes/class-detail",
+		"classes/class-detail/grades",
+		"loop-a",
+		"loop-a/loop-b",
+		"loop-a/loop-b/loop-a",
+	}
+	if got := menuPaths("", menus); !slices.Equal(got, want) {
+		t.Errorf("accessible menus = %v, want %v", got, want)
+	}
+}
+
+func TestDeletedRoleRevokesAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(r *repository, roleID uuid.UUID) error
+		// whether the role's junction rows are archived and removed
+		detached bool
+	}{
+		{"deleted", func(r *repository, roleID uuid.UUID) error {
+			return r.DeleteRole(context.Background(), roleID, uuid.New())
+		}, true},
+		// A role deleted before deletes detached roles still has its rows
+		{"deleted without detaching", func(r *repository, roleID uuid.UUID) error {
+			return r.db.Model(&rbac.RoleEntity{}).Where("id = ?", roleID).Update("deleted_at", time.Now()).Error
+		}, false},
+		{"deactivated", func(r *repository, roleID uuid.UUID) error {
+			return r.db.Model(&rbac.RoleEntity{}).Where("id = ?", roleID).Update("is_active", false).Error
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := sqliteRepo(t, &rbac.RoleEntity{}, &rbac.MenuEntity{}, &rbac.PermissionEntity{},
+				&rbac.RoleMenuEntity{}, &rbac.RolePermissionEntity{}, &rbac.UserRoleEntity{},
+				&rbac.UserRoleHistoryEntity{}, &rbac.RolePermissionHistoryEntity{}, &rbac.RoleMenuHistoryEntity{})
+			ctx := context.Background()
+			teacher, principal := uuid.New(), uuid.New()
+			siti, budi := uuid.New(), uuid.New()
+			menuID, permissionID := uuid.New(), uuid.New()
+			// Siti holds only the teacher role; Budi holds the principal
+			// role, which grants the same menu and permission
+			fixtures := []interface{}{
+				&rbac.RoleEntity{ID: teacher, Name: "Guru", Slug: "teacher", IsActive: true},
+				&rbac.RoleEntity{ID: principal, Name: "Kepala Sekolah", Slug: "principal", IsActive: true},
+				&rbac.MenuEntity{ID: menuID, Name: "Kelas", Slug: "classes", IsActive: true},
+				&rbac.PermissionEntity{ID: permissionID, Name: "View classes", Slug: "classes.view", Module: "classes", Resource: "classes", Action: "view", IsActive: true},
+			}
+			for _, roleID := range []uuid.UUID{teacher, principal} {
+				fixtures = append(fixtures,
+					&rbac.RoleMenuEntity{ID: uuid.New(), RoleID: roleID, MenuID: menuID, CanView: true},
+					&rbac.RolePermissionEntity{ID: uuid.New(), RoleID: roleID, PermissionID: permissionID},
+				)
+			}
+			fixtures = append(fixtures,
+				&rbac.UserRoleEntity{ID: uuid.New(), UserID: siti, RoleID: teacher},
+				&rbac.UserRoleEntity{ID: uuid.New(), UserID: budi, RoleID: principal},
+			)
+			for _, f := range fixtures {
+				if err := r.db.Create(f).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// access reports how many menus, accessible menus and
+			// permissions the user gets, and whether the permission check
+			// passes
+			access := func(userID uuid.UUID) [4]int {
+				t.Helper()
+				menus, err := r.GetUserMenus(ctx, userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				accessible, err := r.GetUserAccessibleMenus(ctx, userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				permissions, err := r.GetUserPermissions(ctx, userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				allowed, err := r.CheckUserHasPermission(ctx, userID, "classes", "view")
+				if err != nil {
+					t.Fatal(err)
+				}
+				checked := 0
+				if allowed {
+					checked = 1
+				}
+				return [4]int{len(menus), len(accessible), len(permissions), checked}
+			}
+			full, none := [4]int{1, 1, 1, 1}, [4]int{}
+			if got := access(siti); got != full {
+				t.Fatalf("access before the change = %v, want %v", got, full)
+			}
+
+			if err := tt.revoke(r, teacher); err != nil {
+				t.Fatal(err)
+			}
+			if got := access(siti); got != none {
+				t.Errorf("access of the role's only holder = %v, want %v", got, none)
+			}
+			if got := access(budi); got != full {
+				t.Errorf("access of another role's holder = %v, want %v", got, full)
+			}
+
+			junctions := []struct {
+				live, archived interface{}
+			}{
+				{&rbac.UserRoleEntity{}, &rbac.UserRoleHistoryEntity{}},
+				{&rbac.RolePermissionEntity{}, &rbac.RolePermissionHistoryEntity{}},
+				{&rbac.RoleMenuEntity{}, &rbac.RoleMenuHistoryEntity{}},
+			}
+			wantLive, wantArchived := int64(1), int64(0)
+			if tt.detached {
+				wantLive, wantArchived = 0, 1
+			}
+			for _, j := range junctions {
+				var live, archived int64
+				if err := r.db.Model(j.live).Where("role_id = ?", teacher).Count(&live).Error; err != nil {
+					t.Fatal(err)
+				}
+				if err := r.db.Model(j.archived).Where("role_id = ?", teacher).Count(&archived).Error; err != nil {
+					t.Fatal(err)
+				}
+				if live != wantLive || archived != wantArchived {
+					t.Errorf("%T: %d live and %d archived rows of the role, want %d and %d",
+						j.live, live, archived, wantLive, wantArchived)
+				}
+			}
+		})
+	}
+}
+
+func TestCountCheckAccumulates(t *testing.T) {
+	r := sqliteRepo(t, &rbac.CheckCountEntity{})
+	ctx := context.Background()
+	for _, c := range [][2]string{{"permission", "denied"}, {"role", "allowed"}, {"permission", "denied"}} {
+		if err := r.CountCheck(ctx, c[0], c[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := r.GetCheckCounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	for _, row := range rows {
+		got[row.Kind+":"+row.Result] = row.Total
+	}
+	if len(got) != 2 || got["permission:denied"] != 2 || got["role:allowed"] != 1 {
+		t.Errorf("counts = %v, want permission:denied 2 and role:allowed 1", got)
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"backend-service-internpro/internal/rbac"
 
@@ -13,19 +14,37 @@ type Repository interface {
 	CreateRole(ctx context.Context, role *rbac.RoleEntity) error
 	GetRoleByID(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error)
 	GetRoleBySlug(ctx context.Context, slug string) (*rbac.RoleEntity, error)
-	GetRoles(ctx context.Context, page, limit int, search string) ([]rbac.RoleEntity, int64, error)
+	// GetRoles, GetPermissions and GetMenus filter and sort by the filter;
+	// GetRoles and GetPermissions list deleted rows too with IncludeDeleted
+	GetRoles(ctx context.Context, page, limit int, filter rbac.RoleFilter) ([]rbac.RoleEntity, int64, error)
+	// UpdateRole, UpdatePermission and UpdateMenu write the editable columns
+	// of an active row and never its deleted_at or deleted_by; they return
+	// gorm.ErrRecordNotFound when the row was deleted meanwhile
 	UpdateRole(ctx context.Context, role *rbac.RoleEntity) error
-	DeleteRole(ctx context.Context, id uuid.UUID) error
-	GetRoleWithPermissions(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error)
-	GetRoleWithMenus(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error)
+	// DeleteRole, DeletePermission and DeleteMenu soft delete an active row
+	// and return gorm.ErrRecordNotFound when there is none. DeleteRole also
+	// archives the role's user, permission and menu links.
+	DeleteRole(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error
+	GetDeletedRoleByID(ctx context.Context, id uuid.UUID) (*rbac.RoleEntity, error)
+	RestoreRole(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) (*rbac.RoleRestoreData, error)
 
 	// Permission methods
 	CreatePermission(ctx context.Context, permission *rbac.PermissionEntity) error
+	CreatePermissions(ctx context.Context, permissions []rbac.PermissionEntity) error
+	CreatePermissionsForRole(ctx context.Context, permissions []rbac.PermissionEntity, roleID uuid.UUID, assignedBy uuid.UUID) error
 	GetPermissionByID(ctx context.Context, id uuid.UUID) (*rbac.PermissionEntity, error)
 	GetPermissionBySlug(ctx context.Context, slug string) (*rbac.PermissionEntity, error)
-	GetPermissions(ctx context.Context, page, limit int, search string) ([]rbac.PermissionEntity, int64, error)
+	GetPermissions(ctx context.Context, page, limit int, filter rbac.PermissionFilter) ([]rbac.PermissionEntity, int64, error)
+	GetPermissionsByModule(ctx context.Context, search string) ([]rbac.PermissionEntity, error)
+	GetActivePermissions(ctx context.Context) ([]rbac.PermissionEntity, error)
 	UpdatePermission(ctx context.Context, permission *rbac.PermissionEntity) error
-	DeletePermission(ctx context.Context, id uuid.UUID) error
+	DeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error
+	ForceDeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error
+	GetDeletedPermissionByID(ctx context.Context, id uuid.UUID) (*rbac.PermissionEntity, error)
+	// RestorePermission undeletes a permission; links dropped by a forced
+	// delete are not reinstated
+	RestorePermission(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) error
+	GetPermissionRoleNames(ctx context.Context, id uuid.UUID) ([]string, error)
 	GetPermissionsByResource(ctx context.Context, resource string) ([]rbac.PermissionEntity, error)
 	GetPermissionsByIDs(ctx context.Context, ids []uuid.UUID) ([]rbac.PermissionEntity, error)
 
@@ -33,35 +52,75 @@ type Repository interface {
 	CreateMenu(ctx context.Context, menu *rbac.MenuEntity) error
 	GetMenuByID(ctx context.Context, id uuid.UUID) (*rbac.MenuEntity, error)
 	GetMenuBySlug(ctx context.Context, slug string) (*rbac.MenuEntity, error)
-	GetMenus(ctx context.Context, page, limit int, search string) ([]rbac.MenuEntity, int64, error)
+	GetMenus(ctx context.Context, page, limit int, filter rbac.MenuFilter) ([]rbac.MenuEntity, int64, error)
 	GetMenuTree(ctx context.Context) ([]rbac.MenuEntity, error)
 	UpdateMenu(ctx context.Context, menu *rbac.MenuEntity) error
-	DeleteMenu(ctx context.Context, id uuid.UUID) error
+	// DeleteMenu also moves the menu's children up to its parent, so none is
+	// left under a deleted menu
+	DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error
+	// ReorderMenus writes the parent and sort order of each menu in one
+	// transaction
+	ReorderMenus(ctx context.Context, menus []rbac.MenuEntity) error
+	// CountChildMenus counts the menus under the menu, active or not
+	CountChildMenus(ctx context.Context, id uuid.UUID) (int64, error)
 	GetMenusByParentID(ctx context.Context, parentID *uuid.UUID) ([]rbac.MenuEntity, error)
 	GetMenusByIDs(ctx context.Context, ids []uuid.UUID) ([]rbac.MenuEntity, error)
+	GetActiveMenusByURL(ctx context.Context, url string) ([]rbac.MenuEntity, error)
+	GetAllMenus(ctx context.Context) ([]rbac.MenuEntity, error)
+	GetAssignedMenuIDs(ctx context.Context) ([]uuid.UUID, error)
 
 	// Role-Permission methods
-	AssignPermissionsToRole(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID, assignedBy uuid.UUID) error
+	AssignPermissionsToRole(ctx context.Context, roleID uuid.UUID, permissionIDs, deniedIDs []uuid.UUID, assignedBy uuid.UUID) error
+	AddPermissionsToRole(ctx context.Context, roleID uuid.UUID, permissionIDs, deniedIDs []uuid.UUID, assignedBy uuid.UUID) error
 	RemovePermissionsFromRole(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID) error
 	GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]rbac.PermissionEntity, error)
+	GetRolePermissionsPage(ctx context.Context, roleID uuid.UUID, page, limit int) ([]rbac.PermissionEntity, int64, error)
 	CheckRoleHasPermission(ctx context.Context, roleID uuid.UUID, permissionSlug string) (bool, error)
 
 	// User-Role methods
-	AssignRolesToUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, assignedBy uuid.UUID) error
+	// AssignRolesToUser makes roleIDs the user's roles, writing only the difference
+	AssignRolesToUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, validity rbac.RoleValidity, assignedBy uuid.UUID) (*rbac.UserRoleChanges, error)
+	AddRolesToUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, validity rbac.RoleValidity, assignedBy uuid.UUID) (*rbac.UserRoleChanges, error)
 	RemoveRolesFromUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID) error
+	// GetUserRoles returns the user's assignments in effect now
 	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]rbac.UserRoleEntity, error)
-	GetUsersByRole(ctx context.Context, roleID uuid.UUID, page, limit int) ([]rbac.UserRoleEntity, int64, error)
+	GetExpiringUserRoles(ctx context.Context, from, until time.Time, page, limit int) ([]rbac.ExpiringUserRoleEntity, int64, error)
+	GetUsersByRole(ctx context.Context, roleID uuid.UUID, page, limit int, search string) ([]rbac.RoleUserEntity, int64, error)
 	CheckUserHasRole(ctx context.Context, userID uuid.UUID, roleSlug string) (bool, error)
+	GetUserSchoolID(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error)
 
 	// Role-Menu methods
 	AssignMenusToRole(ctx context.Context, roleID uuid.UUID, menuPermissions []rbac.RoleMenuEntity, assignedBy uuid.UUID) error
 	RemoveMenusFromRole(ctx context.Context, roleID uuid.UUID, menuIDs []uuid.UUID) error
 	GetRoleMenus(ctx context.Context, roleID uuid.UUID) ([]rbac.RoleMenuEntity, error)
+	GetRoleMenusPage(ctx context.Context, roleID uuid.UUID, page, limit int) ([]rbac.RoleMenuEntity, int64, error)
 	GetUserMenus(ctx context.Context, userID uuid.UUID) ([]rbac.RoleMenuEntity, error)
 	UpdateRoleMenuPermissions(ctx context.Context, roleMenuID uuid.UUID, canView, canCreate, canEdit, canDelete bool, updatedBy uuid.UUID) error
 
 	// Complex queries
 	GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]rbac.PermissionEntity, error)
 	CheckUserHasPermission(ctx context.Context, userID uuid.UUID, resource, action string) (bool, error)
+	// GetUserPermissionEffects returns the effect each role of the user has
+	// on each of its permissions, for rbac.Decide
+	GetUserPermissionEffects(ctx context.Context, userID uuid.UUID) ([]rbac.PermissionEffect, error)
+	// GetPermissionGrants returns the paths CheckUserHasPermission weighs:
+	// each assignment in effect linking the user to a permission covering
+	// resource:action, see rbac.Covers
+	GetPermissionGrants(ctx context.Context, userID uuid.UUID, resource, action string) ([]rbac.PermissionGrantEntity, error)
+	// GetUserAccessibleMenus returns the user's role menus, one per role
+	// granting a menu, with the menus' descendants nested
 	GetUserAccessibleMenus(ctx context.Context, userID uuid.UUID) ([]rbac.RoleMenuEntity, error)
+
+	// Maintenance
+	PruneOrphans(ctx context.Context, deletedBefore time.Time) (*rbac.PruneOrphansData, error)
+
+	// Check counts
+	// CountCheck adds one to the checks of kind answered with result
+	CountCheck(ctx context.Context, kind, result string) error
+	GetCheckCounts(ctx context.Context) ([]rbac.CheckCountEntity, error)
+
+	// Transfer methods
+	GetRBACSnapshot(ctx context.Context) (*rbac.RBACSnapshot, error)
+	// ImportRBAC applies the plan in one transaction
+	ImportRBAC(ctx context.Context, plan *rbac.RBACImportPlan) error
 }
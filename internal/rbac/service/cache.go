@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"backend-service-internpro/internal/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// cachedPermissions returns the effects the user's roles have as
+// rbac.PermissionEffect keys, from the cache when it has them and from the
+// database otherwise. A failing
+// cache is logged and bypassed rather than failing the check.
+func (s *service) cachedPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	permissions, ok, err := s.cache.Get(ctx, userID)
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to read permission cache", err)
+	}
+	if ok {
+		return permissions, nil
+	}
+
+	effects, err := s.repo.GetUserPermissionEffects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	permissions = make([]string, 0, len(effects))
+	for _, effect := range effects {
+		permissions = append(permissions, effect.Key())
+	}
+	if err := s.cache.Set(ctx, userID, permissions); err != nil {
+		logger.Global().Service().ErrorWithErr("failed to write permission cache", err)
+	}
+	return permissions, nil
+}
+
+// forgetPermissions drops the cached permissions of the users, or of every
+// user when none are given. The change is already saved, so a failing cache
+// is only logged; its entries expire on their own.
+func (s *service) forgetPermissions(ctx context.Context, userIDs ...uuid.UUID) {
+	if s.cache == nil {
+		return
+	}
+	var err error
+	if len(userIDs) == 0 {
+		err = s.cache.Clear(ctx)
+	} else {
+		err = s.cache.Delete(ctx, userIDs...)
+	}
+	if err != nil {
+		logger.Global().Service().ErrorWithErr("failed to invalidate permission cache", err)
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+)
+
+// roleChanges remembers when role assignments last changed, so roles claimed
+// by access tokens issued before are no longer trusted. It only sees changes
+// made through this process: it starts out at the process start, which
+// distrusts every token issued before, and a change made by another process
+// is picked up once the tokens issued before it expire.
+type roleChanges struct {
+	mu    sync.RWMutex
+	all   time.Time               // a change that may affect any user
+	users map[uuid.UUID]time.Time // changes to one user's roles after all
+	roles map[string]time.Time    // changes to who holds a role, by slug, after all
+}
+
+func newRoleChanges(now time.Time) *roleChanges {
+	return &roleChanges{
+		all:   now,
+		users: make(map[uuid.UUID]time.Time),
+		roles: make(map[string]time.Time),
+	}
+}
+
+// user records a change to the roles of one user
+func (c *roleChanges) user(userID uuid.UUID, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users[userID] = at
+}
+
+// role records a change to who holds the role
+func (c *roleChanges) role(slug string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roles[slug] = at
+}
+
+// everyone records a change that may affect the roles of any user, like a
+// role being renamed, deactivated or deleted
+func (c *roleChanges) everyone(at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.all = at
+	// Every earlier change to a single user or role is covered now
+	clear(c.users)
+	clear(c.roles)
+}
+
+// since returns when the user's roles or the holders of the role last
+// changed
+func (c *roleChanges) since(userID uuid.UUID, slug string) time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	last := c.all
+	if at, ok := c.users[userID]; ok && at.After(last) {
+		last = at
+	}
+	if at, ok := c.roles[slug]; ok && at.After(last) {
+		last = at
+	}
+	return last
+}
+
+// claimedRoles returns the roles the access token of the request claims for
+// userID, when it has them and whether the user holds roleSlug cannot have
+// changed since it was issued
+func (s *service) claimedRoles(ctx context.Context, userID uuid.UUID, roleSlug string) ([]string, bool) {
+	claims, ok := requestctx.Claims(ctx)
+	if !ok || claims.Roles == nil || claims.IssuedAt == nil || claims.UserID != userID.String() {
+		return nil, false
+	}
+	// IssuedAt is rounded down to the second, which at worst treats a token
+	// issued just after a change as stale
+	if !claims.IssuedAt.After(s.roleChanges.since(userID, roleSlug)) {
+		return nil, false
+	}
+	return claims.Roles, true
+}
+
+func (s *service) CheckUserRole(ctx context.Context, userID uuid.UUID, roleSlug string) (bool, error) {
+	if roles, ok := s.claimedRoles(ctx, userID, roleSlug); ok {
+		return slices.Contains(roles, roleSlug), nil
+	}
+	return s.repo.CheckUserHasRole(ctx, userID, roleSlug)
+}
+
+// InvalidateRoleClaims stops trusting the roles claimed by access tokens
+// issued until now, for every user or only for one user or one role, so
+// role checks read the database again. It is meant for role assignments
+// changed behind the service's back, like a manual database fix.
+func (s *service) InvalidateRoleClaims(ctx context.Context, req *rbac.InvalidateRoleClaimsRequest) (*rbac.InvalidateRoleClaimsResponse, error) {
+	if req.UserID != nil && req.RoleID != nil {
+		return nil, errors.New("give either user_id or role_id, not both")
+	}
+
+	now := s.clock.Now()
+	data := rbac.InvalidateRoleClaimsData{
+		Scope:         rbac.InvalidateScopeAll,
+		UserID:        req.UserID,
+		RoleID:        req.RoleID,
+		InvalidatedAt: now,
+	}
+	switch {
+	case req.UserID != nil:
+		data.Scope = rbac.InvalidateScopeUser
+		s.roleChanges.user(*req.UserID, now)
+		s.forgetPermissions(ctx, *req.UserID)
+	case req.RoleID != nil:
+		// A deleted role may still be claimed by tokens issued before
+		role, err := s.repo.GetRoleByID(ctx, *req.RoleID)
+		if err == nil && role == nil {
+			role, err = s.repo.GetDeletedRoleByID(ctx, *req.RoleID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role: %w", err)
+		}
+		if role == nil {
+			return nil, errors.New("role not found")
+		}
+		data.Scope = rbac.InvalidateScopeRole
+		data.RoleSlug = role.Slug
+		s.roleChanges.role(role.Slug, now)
+		s.forgetPermissions(ctx)
+	default:
+		s.roleChanges.everyone(now)
+		s.forgetPermissions(ctx)
+	}
+
+	details := "scope " + data.Scope
+	if actorID, ok := requestctx.UserID(ctx); ok {
+		details += " by user " + actorID.String()
+	}
+	if data.UserID != nil {
+		details += " for user " + data.UserID.String()
+	}
+	if data.RoleID != nil {
+		details += " for role " + data.RoleSlug
+	}
+	logger.Global().Auth().LogSecurityEvent("role_claims_invalidated", "", requestctx.ClientIP(ctx), details)
+
+	return response.Success("Role claims invalidated successfully", data), nil
+}
+
+func (s *service) GetUserRoleSlugs(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	userRoles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	slugs := make([]string, 0, len(userRoles))
+	for _, ur := range userRoles {
+		// Inactive and deleted roles are not preloaded
+		if ur.Role.ID != uuid.Nil {
+			slugs = append(slugs, ur.Role.Slug)
+		}
+	}
+	return slugs, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/clock"
+	jwtpkg "backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/rbac"
+	"backend-service-internpro/internal/rbac/cache"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestInvalidateRoleClaims(t *testing.T) {
+	siti, budi := uuid.New(), uuid.New()
+	tests := []struct {
+		name string
+		req  func(repo *fakeRepo) rbac.InvalidateRoleClaimsRequest
+		// whether each check still trusts the token, by user and role
+		trusted map[uuid.UUID]map[string]bool
+		// whether the user's permissions are still cached
+		cached map[uuid.UUID]bool
+	}{
+		{
+			name: "one user",
+			req: func(*fakeRepo) rbac.InvalidateRoleClaimsRequest {
+				return rbac.InvalidateRoleClaimsRequest{UserID: &siti}
+			},
+			trusted: map[uuid.UUID]map[string]bool{
+				siti: {"teacher": false, "student": false},
+				budi: {"teacher": true, "student": true},
+			},
+			cached: map[uuid.UUID]bool{siti: false, budi: true},
+		},
+		{
+			name: "one role",
+			req: func(repo *fakeRepo) rbac.InvalidateRoleClaimsRequest {
+				roleID := repo.roleID("teacher")
+				return rbac.InvalidateRoleClaimsRequest{RoleID: &roleID}
+			},
+			trusted: map[uuid.UUID]map[string]bool{
+				siti: {"teacher": false, "student": true},
+				budi: {"teacher": false, "student": true},
+			},
+			cached: map[uuid.UUID]bool{siti: false, budi: false},
+		},
+		{
+			name: "everyone",
+			req: func(*fakeRepo) rbac.InvalidateRoleClaimsRequest {
+				return rbac.InvalidateRoleClaimsRequest{}
+			},
+			trusted: map[uuid.UUID]map[string]bool{
+				siti: {"teacher": false, "student": false},
+				budi: {"teacher": false, "student": false},
+			},
+			cached: map[uuid.UUID]bool{siti: false, budi: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+			repo := newFakeRepo("teacher", "student")
+			permissions := cache.NewMemory(time.Hour)
+			svc := NewServiceWithConfig(repo, Config{Clock: clk, Cache: permissions})
+
+			// Both tokens claim roles a manual database fix took away, so
+			// a trusted claim says yes and the database says no
+			clk.Advance(time.Minute)
+			tokens := make(map[uuid.UUID]context.Context)
+			for _, userID := range []uuid.UUID{siti, budi} {
+				tokens[userID] = requestctx.WithClaims(context.Background(), &jwtpkg.Claims{
+					UserID:           userID.String(),
+					Roles:            []string{"teacher", "student"},
+					RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(clk.Now())},
+				})
+				if err := permissions.Set(context.Background(), userID, []string{"classes:view"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			clk.Advance(time.Minute)
+
+			req := tt.req(repo)
+			if _, err := svc.InvalidateRoleClaims(context.Background(), &req); err != nil {
+				t.Fatal(err)
+			}
+
+			for userID, roles := range tt.trusted {
+				for slug, want := range roles {
+					got, err := svc.CheckUserRole(tokens[userID], userID, slug)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got != want {
+						t.Errorf("%s check of user %s trusted the token = %v, want %v", slug, userID, got, want)
+					}
+				}
+			}
+			for userID, want := range tt.cached {
+				if _, ok, _ := permissions.Get(context.Background(), userID); ok != want {
+					t.Errorf("permissions of user %s cached = %v, want %v", userID, ok, want)
+				}
+			}
+
+			// Tokens issued afterwards are trusted again
+			clk.Advance(2 * time.Second)
+			fresh := requestctx.WithClaims(context.Background(), &jwtpkg.Claims{
+				UserID:           siti.String(),
+				Roles:            []string{"teacher"},
+				RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(clk.Now())},
+			})
+			if ok, err := svc.CheckUserRole(fresh, siti, "teacher"); err != nil || !ok {
+				t.Errorf("check with a token issued after the invalidation = %v, %v, want the claim trusted", ok, err)
+			}
+		})
+	}
+}
+
+func TestInvalidateRoleClaimsRejects(t *testing.T) {
+	repo := newFakeRepo("teacher")
+	svc := NewService(repo)
+	userID, roleID, unknown := uuid.New(), repo.roleID("teacher"), uuid.New()
+
+	for name, req := range map[string]rbac.InvalidateRoleClaimsRequest{
+		"both a user and a role": {UserID: &userID, RoleID: &roleID},
+		"an unknown role":        {RoleID: &unknown},
+	} {
+		if _, err := svc.InvalidateRoleClaims(context.Background(), &req); err == nil {
+			t.Errorf("invalidating %s succeeded", name)
+		}
+	}
+}
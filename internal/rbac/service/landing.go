@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) GetUserLanding(ctx context.Context, userID uuid.UUID) (*rbac.LandingResponse, error) {
+	userRoles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	roleMenus, err := s.repo.GetUserAccessibleMenus(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user accessible menus: %w", err)
+	}
+
+	landing, ok := resolveLanding(userRoles, roleMenus)
+	if !ok {
+		return nil, errors.New("no accessible menu")
+	}
+
+	return response.Success("Landing menu retrieved successfully", landing), nil
+}
+
+// resolveLanding picks the default menu of the user's highest priority role
+// that has a usable one, ties going to the role with the smaller slug. A
+// default counts only while the user can still view it, so menus that were
+// deactivated or unassigned since are skipped. Without one, the first
+// accessible menu with a URL by sort order is used.
+func resolveLanding(userRoles []rbac.UserRoleEntity, roleMenus []rbac.RoleMenuEntity) (rbac.Landing, bool) {
+	viewable := make(map[uuid.UUID]*rbac.MenuEntity, len(roleMenus))
+	for i := range roleMenus {
+		if m := &roleMenus[i].Menu; roleMenus[i].CanView && m.ID != uuid.Nil && m.URL != "" {
+			viewable[m.ID] = m
+		}
+	}
+
+	roles := make([]*rbac.RoleEntity, 0, len(userRoles))
+	for i := range userRoles {
+		// Inactive and deleted roles are not preloaded
+		if r := &userRoles[i].Role; r.ID != uuid.Nil {
+			roles = append(roles, r)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].Priority != roles[j].Priority {
+			return roles[i].Priority > roles[j].Priority
+		}
+		return roles[i].Slug < roles[j].Slug
+	})
+
+	for _, r := range roles {
+		if r.DefaultMenuID == nil {
+			continue
+		}
+		if m, ok := viewable[*r.DefaultMenuID]; ok {
+			roleID := r.ID
+			return landingOf(m, &roleID, rbac.LandingRoleDefault), true
+		}
+	}
+
+	// roleMenus are ordered by the menus' sort order
+	for i := range roleMenus {
+		if m, ok := viewable[roleMenus[i].Menu.ID]; ok {
+			return landingOf(m, nil, rbac.LandingFirstAccessible), true
+		}
+	}
+	return rbac.Landing{}, false
+}
+
+func landingOf(m *rbac.MenuEntity, roleID *uuid.UUID, source string) rbac.Landing {
+	return rbac.Landing{
+		MenuID: m.ID,
+		Name:   m.Name,
+		Slug:   m.Slug,
+		URL:    m.URL,
+		RoleID: roleID,
+		Source: source,
+	}
+}
+
+// checkDefaultMenu reports an error unless menuID is an active menu with a
+// URL assigned to the role
+func (s *service) checkDefaultMenu(ctx context.Context, roleID, menuID uuid.UUID) error {
+	roleMenus, err := s.repo.GetRoleMenus(ctx, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to get role menus: %w", err)
+	}
+	for _, rm := range roleMenus {
+		// Only active menus are preloaded
+		if rm.MenuID == menuID && rm.Menu.ID != uuid.Nil && rm.Menu.URL != "" {
+			return nil
+		}
+	}
+	return errors.New("default menu must be an active menu with a URL assigned to the role")
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+)
+
+func TestResolveLanding(t *testing.T) {
+	id := func(n byte) uuid.UUID { return uuid.UUID{15: n} }
+	ptr := func(u uuid.UUID) *uuid.UUID { return &u }
+
+	// Menus in sort order; settings is a group header without a URL
+	settings := rbac.MenuEntity{ID: id(1), Slug: "settings"}
+	dashboard := rbac.MenuEntity{ID: id(2), Slug: "dashboard", URL: "/dashboard"}
+	classes := rbac.MenuEntity{ID: id(3), Slug: "classes", URL: "/classes"}
+	assignments := rbac.MenuEntity{ID: id(4), Slug: "assignments", URL: "/assignments"}
+	viewable := func(menus ...rbac.MenuEntity) []rbac.RoleMenuEntity {
+		roleMenus := make([]rbac.RoleMenuEntity, len(menus))
+		for i, m := range menus {
+			roleMenus[i] = rbac.RoleMenuEntity{MenuID: m.ID, CanView: true, Menu: m}
+		}
+		return roleMenus
+	}
+	all := viewable(settings, dashboard, classes, assignments)
+
+	teacher := rbac.RoleEntity{ID: id(10), Slug: "teacher", Priority: 10, DefaultMenuID: ptr(classes.ID)}
+	student := rbac.RoleEntity{ID: id(11), Slug: "student", DefaultMenuID: ptr(assignments.ID)}
+	mentor := rbac.RoleEntity{ID: id(12), Slug: "mentor", Priority: 10, DefaultMenuID: ptr(dashboard.ID)}
+	partner := rbac.RoleEntity{ID: id(13), Slug: "partner", Priority: 20}
+	holding := func(roles ...rbac.RoleEntity) []rbac.UserRoleEntity {
+		userRoles := make([]rbac.UserRoleEntity, len(roles))
+		for i, r := range roles {
+			userRoles[i] = rbac.UserRoleEntity{RoleID: r.ID, Role: r}
+		}
+		return userRoles
+	}
+	hidden := viewable(settings, dashboard, classes, assignments)
+	hidden[2].CanView = false
+
+	tests := []struct {
+		name      string
+		userRoles []rbac.UserRoleEntity
+		roleMenus []rbac.RoleMenuEntity
+		want      string
+		wantRole  *uuid.UUID
+	}{
+		{"the highest priority default wins", holding(student, teacher), all, "classes", &teacher.ID},
+		{"a tie goes to the smaller slug", holding(teacher, mentor), all, "dashboard", &mentor.ID},
+		{"a role without a default is skipped", holding(partner, student), all, "assignments", &student.ID},
+		{"a default the user cannot view is skipped", holding(teacher, student), hidden, "assignments", &student.ID},
+		{"an inactive role is skipped", holding(rbac.RoleEntity{}, student), all, "assignments", &student.ID},
+		{"without defaults the first menu with a URL", holding(partner), all, "dashboard", nil},
+		{"a default no longer assigned falls back", holding(teacher), viewable(settings, assignments), "assignments", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolveLanding(tt.userRoles, tt.roleMenus)
+			if !ok {
+				t.Fatal("no landing menu")
+			}
+			wantSource := rbac.LandingRoleDefault
+			if tt.wantRole == nil {
+				wantSource = rbac.LandingFirstAccessible
+			}
+			if got.Slug != tt.want || got.Source != wantSource {
+				t.Errorf("landing = %s (%s), want %s (%s)", got.Slug, got.Source, tt.want, wantSource)
+			}
+			if (got.RoleID == nil) != (tt.wantRole == nil) || (got.RoleID != nil && *got.RoleID != *tt.wantRole) {
+				t.Errorf("landing role = %v, want %v", got.RoleID, tt.wantRole)
+			}
+		})
+	}
+
+	// Nothing to land on
+	if got, ok := resolveLanding(holding(teacher), viewable(settings)); ok {
+		t.Errorf("landing = %+v, want none", got)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/validator"
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) GetMenuReport(ctx context.Context) (*rbac.MenuReportResponse, error) {
+	menus, err := s.repo.GetAllMenus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menus: %w", err)
+	}
+
+	assignedIDs, err := s.repo.GetAssignedMenuIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assigned menus: %w", err)
+	}
+	assigned := make(map[uuid.UUID]bool, len(assignedIDs))
+	for _, id := range assignedIDs {
+		assigned[id] = true
+	}
+
+	return response.Success("Menu report generated successfully", buildMenuReport(menus, assigned)), nil
+}
+
+// buildMenuReport inspects the active menus among menus. Inactive menus are
+// hidden from navigation already and only matter as parents. Every list is
+// ordered by slug so the report is stable between runs.
+func buildMenuReport(menus []rbac.MenuEntity, assigned map[uuid.UUID]bool) rbac.MenuReport {
+	byID := make(map[uuid.UUID]*rbac.MenuEntity, len(menus))
+	hasActiveChild := make(map[uuid.UUID]bool)
+	for i := range menus {
+		m := &menus[i]
+		byID[m.ID] = m
+		if m.IsActive && m.ParentID != nil {
+			hasActiveChild[*m.ParentID] = true
+		}
+	}
+
+	sorted := make([]*rbac.MenuEntity, 0, len(menus))
+	for i := range menus {
+		if menus[i].IsActive {
+			sorted = append(sorted, &menus[i])
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Slug < sorted[j].Slug })
+
+	report := rbac.MenuReport{
+		EmptyURL:       []rbac.MenuReportItem{},
+		DuplicateURLs:  []rbac.MenuURLGroup{},
+		InactiveParent: []rbac.MenuReportItem{},
+		Unassigned:     []rbac.MenuReportItem{},
+	}
+	byURL := make(map[string][]rbac.MenuReportItem)
+
+	for _, m := range sorted {
+		item := toMenuReportItem(m)
+
+		// Group headers legitimately have no URL; only leaves lead nowhere
+		if strings.TrimSpace(m.URL) == "" {
+			if !hasActiveChild[m.ID] {
+				report.EmptyURL = append(report.EmptyURL, item)
+			}
+		} else {
+			byURL[m.URL] = append(byURL[m.URL], item)
+		}
+
+		if m.ParentID != nil {
+			if parent, ok := byID[*m.ParentID]; !ok || !parent.IsActive {
+				report.InactiveParent = append(report.InactiveParent, item)
+			}
+		}
+
+		if !assigned[m.ID] {
+			report.Unassigned = append(report.Unassigned, item)
+		}
+	}
+
+	urls := make([]string, 0, len(byURL))
+	for url, items := range byURL {
+		if len(items) > 1 {
+			urls = append(urls, url)
+		}
+	}
+	sort.Strings(urls)
+	for _, url := range urls {
+		report.DuplicateURLs = append(report.DuplicateURLs, rbac.MenuURLGroup{URL: url, Menus: byURL[url]})
+	}
+
+	return report
+}
+
+func toMenuReportItem(m *rbac.MenuEntity) rbac.MenuReportItem {
+	return rbac.MenuReportItem{
+		ID:       m.ID,
+		Name:     m.Name,
+		Slug:     m.Slug,
+		URL:      m.URL,
+		ParentID: m.ParentID,
+	}
+}
+
+// checkMenuURL applies the menu URL rule to non-empty URLs, report-only until enforced
+func (s *service) checkMenuURL(url string) error {
+	if url == "" {
+		return nil
+	}
+	ok, msg := s.validator.IsValidMenuURL(url)
+	return validator.Check(validator.RuleMenuURL, ok, msg)
+}
+
+// duplicateURLWarnings describes other active menus already using url.
+// Sharing a URL is allowed, so this never rejects the request.
+func (s *service) duplicateURLWarnings(ctx context.Context, url string, menuID uuid.UUID) ([]string, error) {
+	if url == "" {
+		return nil, nil
+	}
+
+	menus, err := s.repo.GetActiveMenusByURL(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check menu URL: %w", err)
+	}
+
+	var slugs []string
+	for _, m := range menus {
+		if m.ID != menuID {
+			slugs = append(slugs, m.Slug)
+		}
+	}
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("URL %s is also used by menu: %s", url, strings.Join(slugs, ", "))}, nil
+}
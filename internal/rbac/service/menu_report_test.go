@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"backend-service-internpro/internal/rbac"
+	"backend-service-internpro/internal/rbac/repository"
+
+	"github.com/google/uuid"
+)
+
+// menuFixture is a small navigation with one menu of every kind the report
+// lists, by slug:
+//
+//	settings       group header without a URL, its child users is active
+//	users          /users, shared with people
+//	people         /users
+//	placeholder    no URL and no children
+//	archive        inactive parent of reports
+//	reports        /reports under an inactive parent
+//	orphan         /orphan whose parent was deleted
+//	audit          /audit, not assigned to any role
+//	old-audit      inactive, /audit; inactive menus are not reported
+func menuFixture() ([]rbac.MenuEntity, map[uuid.UUID]bool) {
+	id := func(n byte) uuid.UUID { return uuid.UUID{15: n} }
+	ptr := func(u uuid.UUID) *uuid.UUID { return &u }
+	menus := []rbac.MenuEntity{
+		{ID: id(1), Slug: "settings", IsActive: true},
+		{ID: id(2), Slug: "users", URL: "/users", ParentID: ptr(id(1)), IsActive: true},
+		{ID: id(3), Slug: "people", URL: "/users", IsActive: true},
+		{ID: id(4), Slug: "placeholder", IsActive: true},
+		{ID: id(5), Slug: "archive", IsActive: false},
+		{ID: id(6), Slug: "reports", URL: "/reports", ParentID: ptr(id(5)), IsActive: true},
+		{ID: id(7), Slug: "orphan", URL: "/orphan", ParentID: ptr(id(99)), IsActive: true},
+		{ID: id(8), Slug: "audit", URL: "/audit", IsActive: true},
+		{ID: id(9), Slug: "old-audit", URL: "/audit", IsActive: false},
+	}
+	assigned := map[uuid.UUID]bool{}
+	for _, m := range menus {
+		if m.Slug != "audit" {
+			assigned[m.ID] = true
+		}
+	}
+	return menus, assigned
+}
+
+func slugs(items []rbac.MenuReportItem) []string {
+	out := []string{}
+	for _, item := range items {
+		out = append(out, item.Slug)
+	}
+	return out
+}
+
+func TestBuildMenuReport(t *testing.T) {
+	menus, assigned := menuFixture()
+	report := buildMenuReport(menus, assigned)
+
+	if got, want := slugs(report.EmptyURL), []string{"placeholder"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("empty URL = %v, want %v", got, want)
+	}
+	if len(report.DuplicateURLs) != 1 || report.DuplicateURLs[0].URL != "/users" {
+		t.Fatalf("duplicate URLs = %+v, want only /users", report.DuplicateURLs)
+	}
+	if got, want := slugs(report.DuplicateURLs[0].Menus), []string{"people", "users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("menus sharing /users = %v, want %v", got, want)
+	}
+	if got, want := slugs(report.InactiveParent), []string{"orphan", "reports"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inactive parent = %v, want %v", got, want)
+	}
+	if got, want := slugs(report.Unassigned), []string{"audit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unassigned = %v, want %v", got, want)
+	}
+
+	// The order of the menus read does not change the report
+	reversed := make([]rbac.MenuEntity, len(menus))
+	for i, m := range menus {
+		reversed[len(menus)-1-i] = m
+	}
+	if again := buildMenuReport(reversed, assigned); !reflect.DeepEqual(again, report) {
+		t.Errorf("report of the reversed menus differs:\n%+v\nwant\n%+v", again, report)
+	}
+}
+
+// menuRepo serves the fixture's active menus by URL; other methods are not used
+type menuRepo struct {
+	repository.Repository
+	menus []rbac.MenuEntity
+}
+
+func (r *menuRepo) GetActiveMenusByURL(_ context.Context, url string) ([]rbac.MenuEntity, error) {
+	var out []rbac.MenuEntity
+	for _, m := range r.menus {
+		if m.IsActive && m.URL == url {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func TestDuplicateURLWarnings(t *testing.T) {
+	menus, _ := menuFixture()
+	s := NewService(&menuRepo{menus: menus}).(*service)
+	users := menus[1]
+
+	tests := []struct {
+		name   string
+		url    string
+		menuID uuid.UUID
+		want   []string
+	}{
+		{"new menu on a shared URL", "/users", uuid.Nil, []string{"URL /users is also used by menu: users, people"}},
+		{"existing menu on a shared URL", "/users", users.ID, []string{"URL /users is also used by menu: people"}},
+		{"URL only an inactive menu used", "/audit", menus[7].ID, nil},
+		{"unused URL", "/new", uuid.Nil, nil},
+		{"no URL", "", uuid.Nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.duplicateURLWarnings(context.Background(), tt.url, tt.menuID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
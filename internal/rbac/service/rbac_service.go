@@ -5,42 +5,140 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"slices"
+	"strings"
 	"time"
-
+	"unicode"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/clock"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/requestctx"
 	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/pkg/validator"
 	"backend-service-internpro/internal/rbac"
+	"backend-service-internpro/internal/rbac/cache"
 	"backend-service-internpro/internal/rbac/repository"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// EventPublisher receives RBAC change events, e.g. to notify affected users
+type EventPublisher interface {
+	PublishRolesChanged(ctx context.Context, event rbac.RolesChangedEvent)
+}
+
+// Publishers forwards events to each of its publishers in order
+type Publishers []EventPublisher
+
+func (p Publishers) PublishRolesChanged(ctx context.Context, event rbac.RolesChangedEvent) {
+	for _, publisher := range p {
+		publisher.PublishRolesChanged(ctx, event)
+	}
+}
+
+// DefaultRestoreWindow is how long a deleted role or permission can be
+// restored by default
+const DefaultRestoreWindow = 30 * 24 * time.Hour
+
+// DefaultEmbedLimit is how many related items a response embeds by default
+const DefaultEmbedLimit = 200
+
+// Config holds optional RBAC service settings
+type Config struct {
+	// Events receives role change events; may be nil
+	Events EventPublisher
+	// RestoreWindow bounds how long after deletion a role or permission can
+	// be restored
+	RestoreWindow time.Duration
+	// EmbedLimit caps the permissions and menus embedded in a role; the
+	// rest are reachable through the paginated role sub-resources
+	EmbedLimit int
+	// Cache keeps users' permissions between checks; nil checks the
+	// database every time
+	Cache cache.Cache
+	// Clock and IDs default to the wall clock and random UUIDs; tests
+	// replace them to get predictable timestamps and IDs
+	Clock clock.Clock
+	IDs   idgen.Generator
+}
+
 type service struct {
-	repo repository.Repository
+	repo          repository.Repository
+	validator     *validator.Validator
+	events        EventPublisher
+	restoreWindow time.Duration
+	embedLimit    int
+	clock         clock.Clock
+	ids           idgen.Generator
+	roleChanges   *roleChanges
+	cache         cache.Cache
 }
 
-// NewService creates a new RBAC service
+// NewService creates a new RBAC service with default settings
 func NewService(repo repository.Repository) Service {
-	return &service{
-		repo: repo,
-	}
+	return NewServiceWithConfig(repo, Config{})
+}
+
+// NewServiceWithConfig creates a new RBAC service with custom settings
+func NewServiceWithConfig(repo repository.Repository, cfg Config) Service {
+	if cfg.RestoreWindow <= 0 {
+		cfg.RestoreWindow = DefaultRestoreWindow
+	}
+	if cfg.EmbedLimit <= 0 {
+		cfg.EmbedLimit = DefaultEmbedLimit
+	}
+	s := &service{
+		repo:          repo,
+		validator:     validator.New(),
+		events:        cfg.Events,
+		restoreWindow: cfg.RestoreWindow,
+		embedLimit:    cfg.EmbedLimit,
+		cache:         cfg.Cache,
+		clock:         clock.OrReal(cfg.Clock),
+		ids:           idgen.OrRandom(cfg.IDs),
+	}
+	s.roleChanges = newRoleChanges(s.clock.Now())
+	return s
 }
 
 // Role services
 func (s *service) CreateRole(ctx context.Context, req *rbac.CreateRoleRequest, createdBy uuid.UUID) (*rbac.CreateRoleResponse, error) {
+	if err := s.requireAdmin(ctx, createdBy); err != nil {
+		return nil, err
+	}
 	// Validate slug uniqueness
 	if err := s.ValidateRoleSlug(ctx, req.Slug, nil); err != nil {
 		return nil, err
 	}
 
 	role := &rbac.RoleEntity{
-		ID:          uuid.New(),
+		ID:          s.ids.New(),
 		Name:        req.Name,
 		Slug:        req.Slug,
 		Description: req.Description,
 		IsActive:    req.IsActive != nil && *req.IsActive,
 		CreatedBy:   &createdBy,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   s.clock.Now(),
+		UpdatedAt:   s.clock.Now(),
+	}
+	role.AssignableBySchoolAdmin = req.AssignableBySchoolAdmin
+	role.Priority = req.Priority
+
+	if req.DefaultMenuID != nil && *req.DefaultMenuID != uuid.Nil {
+		// The new role has no menus yet; the repository assigns this one
+		menu, err := s.repo.GetMenuByID(ctx, *req.DefaultMenuID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get menu: %w", err)
+		}
+		if menu == nil || !menu.IsActive || menu.URL == "" {
+			return nil, errors.New("default menu must be an active menu with a URL")
+		}
+		role.DefaultMenuID = req.DefaultMenuID
 	}
 
 	if err := s.repo.CreateRole(ctx, role); err != nil {
@@ -64,15 +162,23 @@ func (s *service) GetRoleByID(ctx context.Context, id uuid.UUID) (*rbac.RoleResp
 	return response.Success("Role retrieved successfully", role.ToRole()), nil
 }
 
-func (s *service) GetRoles(ctx context.Context, page, limit int, search string) (*rbac.RoleListResponse, error) {
+// roleSortFields are the fields a role listing may be sorted by
+var roleSortFields = []string{"name", "slug", "is_active", "created_at", "updated_at"}
+
+func (s *service) GetRoles(ctx context.Context, page, limit int, filter rbac.RoleFilter) (*rbac.RoleListResponse, error) {
 	if page < 1 {
 		page = 1
 	}
 	if limit < 1 || limit > 100 {
 		limit = 10
 	}
+	sort, err := listSort(filter.ListSort, roleSortFields, rbac.ListSort{SortBy: "created_at", Order: rbac.SortDesc})
+	if err != nil {
+		return nil, err
+	}
+	filter.ListSort = sort
 
-	roles, total, err := s.repo.GetRoles(ctx, page, limit, search)
+	roles, total, err := s.repo.GetRoles(ctx, page, limit, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get roles: %w", err)
 	}
@@ -91,6 +197,8 @@ func (s *service) GetRoles(ctx context.Context, page, limit int, search string)
 			Limit:      limit,
 			TotalPages: totalPages,
 			TotalItems: int(total),
+			SortBy:     sort.SortBy,
+			Order:      sort.Order,
 		},
 	}
 
@@ -98,6 +206,9 @@ func (s *service) GetRoles(ctx context.Context, page, limit int, search string)
 }
 
 func (s *service) UpdateRole(ctx context.Context, id uuid.UUID, req *rbac.UpdateRoleRequest, updatedBy uuid.UUID) error {
+	if err := s.requireAdmin(ctx, updatedBy); err != nil {
+		return err
+	}
 	role, err := s.repo.GetRoleByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get role: %w", err)
@@ -105,6 +216,8 @@ func (s *service) UpdateRole(ctx context.Context, id uuid.UUID, req *rbac.Update
 	if role == nil {
 		return errors.New("role not found")
 	}
+	// Access tokens claim the slugs of active roles
+	slug, active := role.Slug, role.IsActive
 
 	// Update fields if provided
 	if req.Name != nil {
@@ -123,18 +236,47 @@ func (s *service) UpdateRole(ctx context.Context, id uuid.UUID, req *rbac.Update
 	if req.IsActive != nil {
 		role.IsActive = *req.IsActive
 	}
+	if req.AssignableBySchoolAdmin != nil {
+		role.AssignableBySchoolAdmin = *req.AssignableBySchoolAdmin
+	}
+	if req.Priority != nil {
+		role.Priority = *req.Priority
+	}
+	if req.DefaultMenuID != nil {
+		if *req.DefaultMenuID == uuid.Nil {
+			role.DefaultMenuID = nil
+		} else {
+			if err := s.checkDefaultMenu(ctx, id, *req.DefaultMenuID); err != nil {
+				return err
+			}
+			role.DefaultMenuID = req.DefaultMenuID
+		}
+	}
 
 	role.UpdatedBy = &updatedBy
-	role.UpdatedAt = time.Now()
+	role.UpdatedAt = s.clock.Now()
 
 	if err := s.repo.UpdateRole(ctx, role); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("role not found")
+		}
 		return fmt.Errorf("failed to update role: %w", err)
 	}
+	if role.Slug != slug || role.IsActive != active {
+		s.roleChanges.everyone(s.clock.Now())
+		s.forgetPermissions(ctx)
+	}
 
 	return nil
 }
 
-func (s *service) DeleteRole(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
+// DeleteRole soft deletes a role. With force the role is also detached from
+// its users, permissions and menus; those assignments are archived and come
+// back when the role is restored.
+func (s *service) DeleteRole(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID, force bool) error {
+	if err := s.requireAdmin(ctx, deletedBy); err != nil {
+		return err
+	}
 	role, err := s.repo.GetRoleByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get role: %w", err)
@@ -143,19 +285,146 @@ func (s *service) DeleteRole(ctx context.Context, id uuid.UUID, deletedBy uuid.U
 		return errors.New("role not found")
 	}
 
-	role.DeletedBy = &deletedBy
-	now := time.Now()
-	role.DeletedAt = &now
+	// Users would silently lose the role's access, so only a forced delete
+	// may take it from them. Either way their assignments are archived.
+	if !force {
+		_, assigned, err := s.repo.GetUsersByRole(ctx, id, 1, 1, "")
+		if err != nil {
+			return fmt.Errorf("failed to count role users: %w", err)
+		}
+		if assigned > 0 {
+			noun := "users"
+			if assigned == 1 {
+				noun = "user"
+			}
+			return apperrors.Conflict(fmt.Sprintf("role is still assigned to %d %s, delete it with force to detach them", assigned, noun))
+		}
+	}
 
-	if err := s.repo.UpdateRole(ctx, role); err != nil {
+	if err := s.repo.DeleteRole(ctx, id, deletedBy); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("role not found")
+		}
 		return fmt.Errorf("failed to delete role: %w", err)
 	}
+	s.roleChanges.everyone(s.clock.Now())
+	s.forgetPermissions(ctx)
 
 	return nil
 }
 
+// RestoreRole undeletes a role within the restore window and reinstates the
+// assignments archived when it was deleted
+func (s *service) RestoreRole(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) (*rbac.RoleRestoreResponse, error) {
+	if err := s.requireAdmin(ctx, restoredBy); err != nil {
+		return nil, err
+	}
+	role, err := s.repo.GetDeletedRoleByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+	if role.DeletedAt != nil && s.clock.Now().Sub(*role.DeletedAt) > s.restoreWindow {
+		return nil, errors.New("role restore window has expired")
+	}
+	taken, err := s.repo.GetRoleBySlug(ctx, role.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check role slug: %w", err)
+	}
+	if taken != nil {
+		return nil, apperrors.Conflict("role slug " + role.Slug + " is now used by another role")
+	}
+
+	result, err := s.repo.RestoreRole(ctx, id, restoredBy)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role not found")
+		}
+		return nil, fmt.Errorf("failed to restore role: %w", err)
+	}
+	s.roleChanges.everyone(s.clock.Now())
+	s.forgetPermissions(ctx)
+	logger.Global().Auth().LogSecurityEvent("role_restored", "", requestctx.ClientIP(ctx),
+		"role "+role.Slug+" restored by "+restoredBy.String())
+
+	return response.Success("Role restored successfully", *result), nil
+}
+
+// PruneOrphans keeps rows pointing at something deleted within the restore
+// window, so a role restored in that window gets its assignments back
+func (s *service) PruneOrphans(ctx context.Context) (*rbac.PruneOrphansData, error) {
+	result, err := s.repo.PruneOrphans(ctx, s.clock.Now().Add(-s.restoreWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune orphaned assignments: %w", err)
+	}
+	s.forgetPermissions(ctx)
+	return result, nil
+}
+
+func (s *service) CheckConsistency(ctx context.Context, routes []routeperm.Route) (*rbac.ConsistencyReport, error) {
+	permissions, err := s.repo.GetActivePermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+
+	inDatabase := make(map[authz.PermissionKey]bool, len(permissions))
+	for _, p := range permissions {
+		inDatabase[authz.PermissionKey{Resource: p.Resource, Action: p.Action}] = true
+	}
+
+	report := &rbac.ConsistencyReport{
+		MissingInDatabase: []authz.PermissionKey{},
+		UnusedByRoutes:    []authz.PermissionKey{},
+		UnknownRoutes:     []routeperm.Route{},
+	}
+	for _, key := range authz.Permissions {
+		if !inDatabase[key] {
+			report.MissingInDatabase = append(report.MissingInDatabase, key)
+		}
+	}
+
+	// Super admin routes need the role instead of a permission
+	superAdminExists := true
+	if slices.ContainsFunc(routes, func(r routeperm.Route) bool { return r.Access == routeperm.AccessSuperAdmin }) {
+		role, err := s.repo.GetRoleBySlug(ctx, authz.RoleSuperAdmin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get super admin role: %w", err)
+		}
+		superAdminExists = role != nil && role.IsActive
+	}
+
+	required := make(map[authz.PermissionKey]bool)
+	for _, route := range routes {
+		if route.Access == routeperm.AccessSuperAdmin && !superAdminExists {
+			report.UnknownRoutes = append(report.UnknownRoutes, route)
+		}
+		if route.Access != routeperm.AccessPermission {
+			continue
+		}
+		key := authz.PermissionKey{Resource: route.Resource, Action: route.Action}
+		required[key] = true
+		if !inDatabase[key] {
+			report.UnknownRoutes = append(report.UnknownRoutes, route)
+		}
+	}
+
+	// permissions is ordered by resource and action, so the report is too
+	for _, p := range permissions {
+		key := authz.PermissionKey{Resource: p.Resource, Action: p.Action}
+		if !required[key] {
+			report.UnusedByRoutes = append(report.UnusedByRoutes, key)
+		}
+	}
+
+	return report, nil
+}
+
+// GetRoleWithPermissions embeds at most embedLimit permissions; the total
+// and link let clients page through the rest
 func (s *service) GetRoleWithPermissions(ctx context.Context, id uuid.UUID) (*rbac.RoleResponse, error) {
-	role, err := s.repo.GetRoleWithPermissions(ctx, id)
+	role, err := s.repo.GetRoleByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get role with permissions: %w", err)
 	}
@@ -163,11 +432,28 @@ func (s *service) GetRoleWithPermissions(ctx context.Context, id uuid.UUID) (*rb
 		return nil, errors.New("role not found")
 	}
 
-	return response.Success("Role with permissions retrieved successfully", role.ToRole()), nil
+	permissions, total, err := s.repo.GetRolePermissionsPage(ctx, id, 1, s.embedLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role with permissions: %w", err)
+	}
+
+	data := role.ToRole()
+	data.Permissions = make([]rbac.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		data.Permissions = append(data.Permissions, permission.ToPermission())
+	}
+	count := int(total)
+	data.PermissionsTotal = &count
+	data.PermissionsLink = "/v1/roles/" + id.String() + "/permissions"
+	data.Truncated = len(data.Permissions) < count
+
+	return response.Success("Role with permissions retrieved successfully", data), nil
 }
 
+// GetRoleWithMenus embeds at most embedLimit menus; the total and link let
+// clients page through the rest
 func (s *service) GetRoleWithMenus(ctx context.Context, id uuid.UUID) (*rbac.RoleResponse, error) {
-	role, err := s.repo.GetRoleWithMenus(ctx, id)
+	role, err := s.repo.GetRoleByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get role with menus: %w", err)
 	}
@@ -175,10 +461,202 @@ func (s *service) GetRoleWithMenus(ctx context.Context, id uuid.UUID) (*rbac.Rol
 		return nil, errors.New("role not found")
 	}
 
-	return response.Success("Role with menus retrieved successfully", role.ToRole()), nil
+	menus, total, err := s.repo.GetRoleMenusPage(ctx, id, 1, s.embedLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role with menus: %w", err)
+	}
+
+	data := role.ToRole()
+	data.Menus = make([]rbac.Menu, 0, len(menus))
+	for _, roleMenu := range menus {
+		data.Menus = append(data.Menus, roleMenu.ToRoleMenuAccess())
+	}
+	count := int(total)
+	data.MenusTotal = &count
+	data.MenusLink = "/v1/roles/" + id.String() + "/menus"
+	data.Truncated = len(data.Menus) < count
+
+	return response.Success("Role with menus retrieved successfully", data), nil
+}
+
+func (s *service) GetRolePermissions(ctx context.Context, roleID uuid.UUID, page, limit int) (*rbac.PermissionListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+
+	permissions, total, err := s.repo.GetRolePermissionsPage(ctx, roleID, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+
+	var permissionList []rbac.Permission
+	for _, permission := range permissions {
+		permissionList = append(permissionList, permission.ToPermission())
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	data := rbac.PermissionListData{
+		Data: permissionList,
+		Meta: rbac.RBACMetadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: totalPages,
+			TotalItems: int(total),
+		},
+	}
+
+	return response.Success("Role permissions retrieved successfully", data), nil
+}
+
+func (s *service) GetRoleMenus(ctx context.Context, roleID uuid.UUID, page, limit int) (*rbac.MenuListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+
+	menus, total, err := s.repo.GetRoleMenusPage(ctx, roleID, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role menus: %w", err)
+	}
+
+	var menuList []rbac.Menu
+	for _, roleMenu := range menus {
+		menuList = append(menuList, roleMenu.ToRoleMenuAccess())
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	data := rbac.MenuListData{
+		Data: menuList,
+		Meta: rbac.RBACMetadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: totalPages,
+			TotalItems: int(total),
+		},
+	}
+
+	return response.Success("Role menus retrieved successfully", data), nil
+}
+
+func (s *service) GetRoleUsers(ctx context.Context, roleID uuid.UUID, page, limit int, search string) (*rbac.RoleUserListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+
+	users, total, err := s.repo.GetUsersByRole(ctx, roleID, page, limit, search)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role users: %w", err)
+	}
+
+	userList := make([]rbac.RoleUser, 0, len(users))
+	for _, u := range users {
+		userList = append(userList, u.ToRoleUser())
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	data := rbac.RoleUserListData{
+		Data: userList,
+		Meta: rbac.RBACMetadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: totalPages,
+			TotalItems: int(total),
+		},
+	}
+
+	return response.Success("Role users retrieved successfully", data), nil
 }
 
 func (s *service) AssignPermissionsToRole(ctx context.Context, roleID uuid.UUID, req *rbac.AssignRolePermissionsRequest, assignedBy uuid.UUID) error {
+	if err := s.requireAdmin(ctx, assignedBy); err != nil {
+		return err
+	}
+	if err := s.checkRolePermissions(ctx, roleID, req); err != nil {
+		return err
+	}
+
+	if err := s.repo.AssignPermissionsToRole(ctx, roleID, req.PermissionIDs, req.DeniedPermissionIDs, assignedBy); err != nil {
+		return fmt.Errorf("failed to assign permissions to role: %w", err)
+	}
+	s.forgetPermissions(ctx)
+
+	return nil
+}
+
+func (s *service) AddPermissionsToRole(ctx context.Context, roleID uuid.UUID, req *rbac.AssignRolePermissionsRequest, assignedBy uuid.UUID) error {
+	if err := s.requireAdmin(ctx, assignedBy); err != nil {
+		return err
+	}
+	if err := s.checkRolePermissions(ctx, roleID, req); err != nil {
+		return err
+	}
+
+	if err := s.repo.AddPermissionsToRole(ctx, roleID, req.PermissionIDs, req.DeniedPermissionIDs, assignedBy); err != nil {
+		return fmt.Errorf("failed to add permissions to role: %w", err)
+	}
+	s.forgetPermissions(ctx)
+
+	return nil
+}
+
+func (s *service) RemovePermissionsFromRole(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID, removedBy uuid.UUID) error {
+	if err := s.requireAdmin(ctx, removedBy); err != nil {
+		return err
+	}
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+	if role == nil {
+		return errors.New("role not found")
+	}
+
+	if err := s.repo.RemovePermissionsFromRole(ctx, roleID, permissionIDs); err != nil {
+		return fmt.Errorf("failed to remove permissions from role: %w", err)
+	}
+	s.forgetPermissions(ctx)
+
+	return nil
+}
+
+// checkRolePermissions validates a request to assign or add permissions to
+// the role
+func (s *service) checkRolePermissions(ctx context.Context, roleID uuid.UUID, req *rbac.AssignRolePermissionsRequest) error {
 	// Check if role exists
 	role, err := s.repo.GetRoleByID(ctx, roleID)
 	if err != nil {
@@ -188,23 +666,45 @@ func (s *service) AssignPermissionsToRole(ctx context.Context, roleID uuid.UUID,
 		return errors.New("role not found")
 	}
 
+	// A permission is either allowed or denied by a role, never both
+	allowed := make(map[uuid.UUID]bool, len(req.PermissionIDs))
+	for _, id := range req.PermissionIDs {
+		allowed[id] = true
+	}
+	for _, id := range req.DeniedPermissionIDs {
+		if allowed[id] {
+			return errors.New("permission cannot be both allowed and denied")
+		}
+	}
+
 	// Validate permissions exist
-	permissions, err := s.repo.GetPermissionsByIDs(ctx, req.PermissionIDs)
+	ids := append(append([]uuid.UUID{}, req.PermissionIDs...), req.DeniedPermissionIDs...)
+	permissions, err := s.repo.GetPermissionsByIDs(ctx, ids)
 	if err != nil {
 		return fmt.Errorf("failed to get permissions: %w", err)
 	}
-	if len(permissions) != len(req.PermissionIDs) {
-		return errors.New("some permissions not found")
+	found := make(map[uuid.UUID]bool, len(permissions))
+	for _, p := range permissions {
+		found[p.ID] = true
 	}
-
-	if err := s.repo.AssignPermissionsToRole(ctx, roleID, req.PermissionIDs, assignedBy); err != nil {
-		return fmt.Errorf("failed to assign permissions to role: %w", err)
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			found[id] = true // list each once
+			missing = append(missing, id.String())
+		}
+	}
+	if len(missing) > 0 {
+		return apperrors.ValidationFailed("permissions not found: " + strings.Join(missing, ", "))
 	}
 
 	return nil
 }
 
 func (s *service) AssignMenusToRole(ctx context.Context, roleID uuid.UUID, req *rbac.AssignRoleMenusRequest, assignedBy uuid.UUID) error {
+	if err := s.requireAdmin(ctx, assignedBy); err != nil {
+		return err
+	}
 	// Check if role exists
 	role, err := s.repo.GetRoleByID(ctx, roleID)
 	if err != nil {
@@ -214,9 +714,14 @@ func (s *service) AssignMenusToRole(ctx context.Context, roleID uuid.UUID, req *
 		return errors.New("role not found")
 	}
 
-	// Validate menus exist
+	// Validate menus exist, each listed once
 	var menuIDs []uuid.UUID
+	listed := make(map[uuid.UUID]bool, len(req.MenuPermissions))
 	for _, mp := range req.MenuPermissions {
+		if listed[mp.MenuID] {
+			return apperrors.ValidationFailed("menu listed more than once: " + mp.MenuID.String())
+		}
+		listed[mp.MenuID] = true
 		menuIDs = append(menuIDs, mp.MenuID)
 	}
 
@@ -224,8 +729,18 @@ func (s *service) AssignMenusToRole(ctx context.Context, roleID uuid.UUID, req *
 	if err != nil {
 		return fmt.Errorf("failed to get menus: %w", err)
 	}
-	if len(menus) != len(menuIDs) {
-		return errors.New("some menus not found")
+	found := make(map[uuid.UUID]bool, len(menus))
+	for _, m := range menus {
+		found[m.ID] = true
+	}
+	var missing []string
+	for _, id := range menuIDs {
+		if !found[id] {
+			missing = append(missing, id.String())
+		}
+	}
+	if len(missing) > 0 {
+		return apperrors.ValidationFailed("menus not found: " + strings.Join(missing, ", "))
 	}
 
 	// Convert to entities
@@ -247,24 +762,47 @@ func (s *service) AssignMenusToRole(ctx context.Context, roleID uuid.UUID, req *
 	return nil
 }
 
+func (s *service) RemoveMenusFromRole(ctx context.Context, roleID uuid.UUID, menuIDs []uuid.UUID, removedBy uuid.UUID) error {
+	if err := s.requireAdmin(ctx, removedBy); err != nil {
+		return err
+	}
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+	if role == nil {
+		return errors.New("role not found")
+	}
+
+	if err := s.repo.RemoveMenusFromRole(ctx, roleID, menuIDs); err != nil {
+		return fmt.Errorf("failed to remove menus from role: %w", err)
+	}
+
+	return nil
+}
+
 // Permission services
 func (s *service) CreatePermission(ctx context.Context, req *rbac.CreatePermissionRequest, createdBy uuid.UUID) (*rbac.CreatePermissionResponse, error) {
+	if err := s.requireAdmin(ctx, createdBy); err != nil {
+		return nil, err
+	}
 	// Validate slug uniqueness
 	if err := s.ValidatePermissionSlug(ctx, req.Slug, nil); err != nil {
 		return nil, err
 	}
 
 	permission := &rbac.PermissionEntity{
-		ID:          uuid.New(),
+		ID:          s.ids.New(),
 		Name:        req.Name,
 		Slug:        req.Slug,
+		Module:      permissionModule(req.Module, req.Resource),
 		Resource:    req.Resource,
 		Action:      req.Action,
 		Description: req.Description,
 		IsActive:    req.IsActive != nil && *req.IsActive,
 		CreatedBy:   &createdBy,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   s.clock.Now(),
+		UpdatedAt:   s.clock.Now(),
 	}
 
 	if err := s.repo.CreatePermission(ctx, permission); err != nil {
@@ -276,6 +814,189 @@ func (s *service) CreatePermission(ctx context.Context, req *rbac.CreatePermissi
 	}), nil
 }
 
+func (s *service) CreatePermissions(ctx context.Context, req *rbac.BulkCreatePermissionsRequest, createdBy uuid.UUID) (*rbac.BulkCreatePermissionsResponse, error) {
+	if err := s.requireAdmin(ctx, createdBy); err != nil {
+		return nil, err
+	}
+	// Validate every item before writing any, so the whole batch is
+	// reported at once
+	invalid := &rbac.BulkError{}
+	slugs := make(map[string]int, len(req.Permissions))
+	names := make(map[string]int, len(req.Permissions))
+	for i, item := range req.Permissions {
+		if first, dup := slugs[item.Slug]; dup {
+			invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "slug", Value: item.Slug, Message: fmt.Sprintf("slug repeats item %d", first)})
+		} else {
+			slugs[item.Slug] = i
+			if err := s.ValidatePermissionSlug(ctx, item.Slug, nil); err != nil {
+				if _, ok := apperrors.IsAppError(err); !ok && err.Error() != "permission slug already exists" {
+					return nil, err
+				}
+				invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "slug", Value: item.Slug, Message: bulkMessage(err)})
+			}
+		}
+		if first, dup := names[item.Name]; dup {
+			invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "name", Value: item.Name, Message: fmt.Sprintf("name repeats item %d", first)})
+		} else {
+			names[item.Name] = i
+		}
+	}
+	if len(invalid.Items) > 0 {
+		return nil, invalid
+	}
+
+	now := s.clock.Now()
+	permissions := make([]rbac.PermissionEntity, 0, len(req.Permissions))
+	results := make([]rbac.BulkPermissionResult, 0, len(req.Permissions))
+	for i, item := range req.Permissions {
+		permission := rbac.PermissionEntity{
+			ID:          s.ids.New(),
+			Name:        item.Name,
+			Slug:        item.Slug,
+			Module:      permissionModule(item.Module, item.Resource),
+			Resource:    item.Resource,
+			Action:      item.Action,
+			Description: item.Description,
+			IsActive:    item.IsActive != nil && *item.IsActive,
+			CreatedBy:   &createdBy,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		permissions = append(permissions, permission)
+		results = append(results, rbac.BulkPermissionResult{Index: i, ID: permission.ID, Slug: permission.Slug})
+	}
+
+	if err := s.repo.CreatePermissions(ctx, permissions); err != nil {
+		return nil, fmt.Errorf("failed to create permissions: %w", err)
+	}
+
+	return response.Success("Permissions created successfully", rbac.BulkCreatePermissionsData{Results: results}), nil
+}
+
+// defaultPermissionActions are generated when a request names no actions
+var defaultPermissionActions = []string{"view", "create", "edit", "delete"}
+
+// GeneratePermissions creates the missing permissions of a resource. Names
+// and slugs follow the seeded ones: "View Users" and view-users.
+func (s *service) GeneratePermissions(ctx context.Context, req *rbac.GeneratePermissionsRequest, createdBy uuid.UUID) (*rbac.GeneratePermissionsResponse, error) {
+	resource := strings.ToLower(strings.TrimSpace(req.Resource))
+	if resource == "" {
+		return nil, apperrors.ValidationFailed("resource is required")
+	}
+	actions := req.Actions
+	if len(actions) == 0 {
+		actions = defaultPermissionActions
+	}
+
+	if req.RoleID != nil {
+		role, err := s.repo.GetRoleByID(ctx, *req.RoleID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role: %w", err)
+		}
+		if role == nil {
+			return nil, errors.New("role not found")
+		}
+	}
+
+	existing, err := s.repo.GetPermissionsByResource(ctx, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions by resource: %w", err)
+	}
+	byAction := make(map[string]rbac.PermissionEntity, len(existing))
+	for _, permission := range existing {
+		byAction[permission.Action] = permission
+	}
+
+	now := s.clock.Now()
+	data := rbac.GeneratePermissionsData{Created: []rbac.Permission{}, Skipped: []rbac.Permission{}}
+	var permissions []rbac.PermissionEntity
+	seen := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		action = strings.ToLower(strings.TrimSpace(action))
+		if seen[action] {
+			continue
+		}
+		seen[action] = true
+		if action == "" || len(action) > 50 {
+			return nil, apperrors.ValidationFailed("action must be 1 to 50 characters")
+		}
+		if permission, ok := byAction[action]; ok {
+			data.Skipped = append(data.Skipped, permission.ToPermission())
+			continue
+		}
+
+		slug := action + "-" + resource
+		if err := s.checkSlugPattern(slug); err != nil {
+			return nil, err
+		}
+		// Inactive permissions are not listed by resource but keep their slug
+		found, err := s.repo.GetPermissionBySlug(ctx, slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check permission slug: %w", err)
+		}
+		if found != nil {
+			if found.Resource != resource || found.Action != action {
+				return nil, errors.New("permission slug already exists")
+			}
+			data.Skipped = append(data.Skipped, found.ToPermission())
+			continue
+		}
+
+		permissions = append(permissions, rbac.PermissionEntity{
+			ID:          s.ids.New(),
+			Name:        titleWords(action) + " " + titleWords(resource),
+			Slug:        slug,
+			Module:      resource,
+			Resource:    resource,
+			Action:      action,
+			Description: "Permission to " + action + " " + strings.NewReplacer("-", " ", "_", " ").Replace(resource),
+			IsActive:    true,
+			CreatedBy:   &createdBy,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	if len(permissions) > 0 {
+		if req.RoleID != nil {
+			err = s.repo.CreatePermissionsForRole(ctx, permissions, *req.RoleID, createdBy)
+		} else {
+			err = s.repo.CreatePermissions(ctx, permissions)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create permissions: %w", err)
+		}
+		if req.RoleID != nil {
+			s.forgetPermissions(ctx)
+		}
+	}
+	for _, permission := range permissions {
+		data.Created = append(data.Created, permission.ToPermission())
+	}
+
+	return response.Success("Permissions generated successfully", data), nil
+}
+
+// titleWords capitalizes the words of a resource or action, splitting it on
+// hyphens and underscores
+func titleWords(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '_' })
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
+
+// bulkMessage is the reason to report for an item rejected with err
+func bulkMessage(err error) string {
+	if appErr, ok := apperrors.IsAppError(err); ok && appErr.Details != "" {
+		return appErr.Details
+	}
+	return err.Error()
+}
+
 func (s *service) GetPermissionByID(ctx context.Context, id uuid.UUID) (*rbac.PermissionResponse, error) {
 	permission, err := s.repo.GetPermissionByID(ctx, id)
 	if err != nil {
@@ -288,15 +1009,23 @@ func (s *service) GetPermissionByID(ctx context.Context, id uuid.UUID) (*rbac.Pe
 	return response.Success("Permission retrieved successfully", permission.ToPermission()), nil
 }
 
-func (s *service) GetPermissions(ctx context.Context, page, limit int, search string) (*rbac.PermissionListResponse, error) {
+// permissionSortFields are the fields a permission listing may be sorted by
+var permissionSortFields = []string{"name", "slug", "module", "resource", "action", "is_active", "created_at", "updated_at"}
+
+func (s *service) GetPermissions(ctx context.Context, page, limit int, filter rbac.PermissionFilter) (*rbac.PermissionListResponse, error) {
 	if page < 1 {
 		page = 1
 	}
 	if limit < 1 || limit > 100 {
 		limit = 10
 	}
+	sort, err := listSort(filter.ListSort, permissionSortFields, rbac.ListSort{SortBy: "resource", Order: rbac.SortAsc})
+	if err != nil {
+		return nil, err
+	}
+	filter.ListSort = sort
 
-	permissions, total, err := s.repo.GetPermissions(ctx, page, limit, search)
+	permissions, total, err := s.repo.GetPermissions(ctx, page, limit, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get permissions: %w", err)
 	}
@@ -315,13 +1044,41 @@ func (s *service) GetPermissions(ctx context.Context, page, limit int, search st
 			Limit:      limit,
 			TotalPages: totalPages,
 			TotalItems: int(total),
+			SortBy:     sort.SortBy,
+			Order:      sort.Order,
 		},
 	}
 
 	return response.Success("Permissions retrieved successfully", data), nil
 }
 
+func (s *service) GetGroupedPermissions(ctx context.Context, search string) (*rbac.GroupedPermissionsResponse, error) {
+	permissions, err := s.repo.GetPermissionsByModule(ctx, search)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+
+	groups := rbac.GroupedPermissionsData{}
+	for _, permission := range permissions {
+		groups[permission.Module] = append(groups[permission.Module], permission.ToPermission())
+	}
+
+	return response.Success("Permissions retrieved successfully", groups), nil
+}
+
+// permissionModule is the module a new permission is grouped under, its
+// resource unless one is given
+func permissionModule(module, resource string) string {
+	if module = strings.TrimSpace(module); module != "" {
+		return module
+	}
+	return resource
+}
+
 func (s *service) UpdatePermission(ctx context.Context, id uuid.UUID, req *rbac.UpdatePermissionRequest, updatedBy uuid.UUID) error {
+	if err := s.requireAdmin(ctx, updatedBy); err != nil {
+		return err
+	}
 	permission, err := s.repo.GetPermissionByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get permission: %w", err)
@@ -341,6 +1098,9 @@ func (s *service) UpdatePermission(ctx context.Context, id uuid.UUID, req *rbac.
 		}
 		permission.Slug = *req.Slug
 	}
+	if req.Module != nil {
+		permission.Module = strings.TrimSpace(*req.Module)
+	}
 	if req.Resource != nil {
 		permission.Resource = *req.Resource
 	}
@@ -355,16 +1115,63 @@ func (s *service) UpdatePermission(ctx context.Context, id uuid.UUID, req *rbac.
 	}
 
 	permission.UpdatedBy = &updatedBy
-	permission.UpdatedAt = time.Now()
+	permission.UpdatedAt = s.clock.Now()
 
 	if err := s.repo.UpdatePermission(ctx, permission); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("permission not found")
+		}
 		return fmt.Errorf("failed to update permission: %w", err)
 	}
+	s.forgetPermissions(ctx)
 
 	return nil
 }
 
-func (s *service) DeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
+// RestorePermission undeletes a permission within the restore window. Roles
+// it was detached from by a forced delete do not get it back.
+func (s *service) RestorePermission(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) (*rbac.PermissionResponse, error) {
+	if err := s.requireAdmin(ctx, restoredBy); err != nil {
+		return nil, err
+	}
+	permission, err := s.repo.GetDeletedPermissionByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permission: %w", err)
+	}
+	if permission == nil {
+		return nil, errors.New("permission not found")
+	}
+	if permission.DeletedAt != nil && s.clock.Now().Sub(*permission.DeletedAt) > s.restoreWindow {
+		return nil, errors.New("permission restore window has expired")
+	}
+	taken, err := s.repo.GetPermissionBySlug(ctx, permission.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permission slug: %w", err)
+	}
+	if taken != nil {
+		return nil, apperrors.Conflict("permission slug " + permission.Slug + " is now used by another permission")
+	}
+
+	if err := s.repo.RestorePermission(ctx, id, restoredBy); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("permission not found")
+		}
+		return nil, fmt.Errorf("failed to restore permission: %w", err)
+	}
+	s.forgetPermissions(ctx)
+	logger.Global().Auth().LogSecurityEvent("permission_restored", "", requestctx.ClientIP(ctx),
+		"permission "+permission.Slug+" restored by "+restoredBy.String())
+
+	permission.DeletedAt = nil
+	permission.DeletedBy = nil
+	permission.UpdatedBy = &restoredBy
+	return response.Success("Permission restored successfully", permission.ToPermission()), nil
+}
+
+func (s *service) DeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID, force bool) error {
+	if err := s.requireAdmin(ctx, deletedBy); err != nil {
+		return err
+	}
 	permission, err := s.repo.GetPermissionByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get permission: %w", err)
@@ -373,13 +1180,34 @@ func (s *service) DeletePermission(ctx context.Context, id uuid.UUID, deletedBy
 		return errors.New("permission not found")
 	}
 
-	permission.DeletedBy = &deletedBy
-	now := time.Now()
-	permission.DeletedAt = &now
+	if force {
+		if err := s.repo.ForceDeletePermission(ctx, id, deletedBy); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("permission not found")
+			}
+			return fmt.Errorf("failed to delete permission: %w", err)
+		}
+		s.forgetPermissions(ctx)
+		return nil
+	}
+
+	// Deleting would silently change what the roles grant, so the admin
+	// has to confirm with force
+	roles, err := s.repo.GetPermissionRoleNames(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get permission roles: %w", err)
+	}
+	if len(roles) > 0 {
+		return apperrors.Conflict("permission is still attached to roles " + strings.Join(roles, ", ") + ", delete it with force to detach it")
+	}
 
-	if err := s.repo.UpdatePermission(ctx, permission); err != nil {
+	if err := s.repo.DeletePermission(ctx, id, deletedBy); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("permission not found")
+		}
 		return fmt.Errorf("failed to delete permission: %w", err)
 	}
+	s.forgetPermissions(ctx)
 
 	return nil
 }
@@ -404,25 +1232,62 @@ func (s *service) GetPermissionsByResource(ctx context.Context, resource string)
 			TotalItems: len(permissionList),
 		},
 	}
-
-	return response.Success("Permissions by resource retrieved successfully", data), nil
+
+	return response.Success("Permissions by resource retrieved successfully", data), nil
+}
+
+// maxMenuDepth bounds the walk up a menu's ancestors, in case the table
+// already holds a cycle
+const maxMenuDepth = 100
+
+// checkMenuParent rejects placing the menu under parentID when that menu
+// does not exist or is the menu itself or one of its descendants, at any
+// depth. A chain of ancestors longer than maxMenuDepth is rejected too, as
+// it can only come from a cycle already in the table.
+func (s *service) checkMenuParent(ctx context.Context, id, parentID uuid.UUID) error {
+	if parentID == id {
+		return errors.New("menu cannot be parent of itself")
+	}
+	parent, err := s.repo.GetMenuByID(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to get parent menu: %w", err)
+	}
+	if parent == nil {
+		return errors.New("parent menu not found")
+	}
+	for depth := 0; parent != nil && parent.ParentID != nil; depth++ {
+		if *parent.ParentID == id {
+			return errors.New("menu cannot be moved under its own descendant")
+		}
+		if depth == maxMenuDepth {
+			return errors.New("parent menu is nested too deep")
+		}
+		if parent, err = s.repo.GetMenuByID(ctx, *parent.ParentID); err != nil {
+			return fmt.Errorf("failed to get parent menu: %w", err)
+		}
+	}
+	return nil
 }
 
 // Menu services
 func (s *service) CreateMenu(ctx context.Context, req *rbac.CreateMenuRequest, createdBy uuid.UUID) (*rbac.CreateMenuResponse, error) {
+	if err := s.requireAdmin(ctx, createdBy); err != nil {
+		return nil, err
+	}
 	// Validate slug uniqueness
 	if err := s.ValidateMenuSlug(ctx, req.Slug, nil); err != nil {
 		return nil, err
 	}
+	if err := s.checkMenuURL(req.URL); err != nil {
+		return nil, err
+	}
 
-	// Validate parent menu exists if provided
+	// Validate the parent as a move is validated, which also refuses a
+	// parent whose ancestors loop
+	id := s.ids.New()
 	if req.ParentID != nil {
-		parent, err := s.repo.GetMenuByID(ctx, *req.ParentID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get parent menu: %w", err)
-		}
-		if parent == nil {
-			return nil, errors.New("parent menu not found")
+		if err := s.checkMenuParent(ctx, id, *req.ParentID); err != nil {
+			return nil, err
 		}
 	}
 
@@ -432,7 +1297,7 @@ func (s *service) CreateMenu(ctx context.Context, req *rbac.CreateMenuRequest, c
 	}
 
 	menu := &rbac.MenuEntity{
-		ID:        uuid.New(),
+		ID:        id,
 		Name:      req.Name,
 		Slug:      req.Slug,
 		URL:       req.URL,
@@ -441,8 +1306,13 @@ func (s *service) CreateMenu(ctx context.Context, req *rbac.CreateMenuRequest, c
 		SortOrder: sortOrder,
 		IsActive:  req.IsActive != nil && *req.IsActive,
 		CreatedBy: &createdBy,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+
+	warnings, err := s.duplicateURLWarnings(ctx, menu.URL, menu.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.repo.CreateMenu(ctx, menu); err != nil {
@@ -450,7 +1320,8 @@ func (s *service) CreateMenu(ctx context.Context, req *rbac.CreateMenuRequest, c
 	}
 
 	return response.Success("Menu created successfully", rbac.CreateMenuData{
-		ID: menu.ID,
+		ID:       menu.ID,
+		Warnings: warnings,
 	}), nil
 }
 
@@ -466,15 +1337,23 @@ func (s *service) GetMenuByID(ctx context.Context, id uuid.UUID) (*rbac.MenuResp
 	return response.Success("Menu retrieved successfully", menu.ToMenu()), nil
 }
 
-func (s *service) GetMenus(ctx context.Context, page, limit int, search string) (*rbac.MenuListResponse, error) {
+// menuSortFields are the fields a menu listing may be sorted by
+var menuSortFields = []string{"name", "slug", "sort_order", "is_active", "created_at", "updated_at"}
+
+func (s *service) GetMenus(ctx context.Context, page, limit int, filter rbac.MenuFilter) (*rbac.MenuListResponse, error) {
 	if page < 1 {
 		page = 1
 	}
 	if limit < 1 || limit > 100 {
 		limit = 10
 	}
+	sort, err := listSort(filter.ListSort, menuSortFields, rbac.ListSort{SortBy: "sort_order", Order: rbac.SortAsc})
+	if err != nil {
+		return nil, err
+	}
+	filter.ListSort = sort
 
-	menus, total, err := s.repo.GetMenus(ctx, page, limit, search)
+	menus, total, err := s.repo.GetMenus(ctx, page, limit, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get menus: %w", err)
 	}
@@ -493,12 +1372,36 @@ func (s *service) GetMenus(ctx context.Context, page, limit int, search string)
 			Limit:      limit,
 			TotalPages: totalPages,
 			TotalItems: int(total),
+			SortBy:     sort.SortBy,
+			Order:      sort.Order,
 		},
 	}
 
 	return response.Success("Menus retrieved successfully", data), nil
 }
 
+// listSort checks a requested sort against the fields a listing allows,
+// falling back to the listing's default field and to ascending order
+func listSort(sort rbac.ListSort, fields []string, fallback rbac.ListSort) (rbac.ListSort, error) {
+	if sort.SortBy == "" {
+		sort.SortBy = fallback.SortBy
+		if sort.Order == "" {
+			sort.Order = fallback.Order
+		}
+	}
+	if !slices.Contains(fields, sort.SortBy) {
+		return rbac.ListSort{}, apperrors.ValidationFailed("sort_by must be one of: " + strings.Join(fields, ", "))
+	}
+	switch sort.Order {
+	case "":
+		sort.Order = rbac.SortAsc
+	case rbac.SortAsc, rbac.SortDesc:
+	default:
+		return rbac.ListSort{}, apperrors.ValidationFailed("order must be asc or desc")
+	}
+	return sort, nil
+}
+
 func (s *service) GetMenuTree(ctx context.Context) (*rbac.MenuTreeResponse, error) {
 	menus, err := s.repo.GetMenuTree(ctx)
 	if err != nil {
@@ -515,13 +1418,16 @@ func (s *service) GetMenuTree(ctx context.Context) (*rbac.MenuTreeResponse, erro
 	}, nil
 }
 
-func (s *service) UpdateMenu(ctx context.Context, id uuid.UUID, req *rbac.UpdateMenuRequest, updatedBy uuid.UUID) error {
+func (s *service) UpdateMenu(ctx context.Context, id uuid.UUID, req *rbac.UpdateMenuRequest, updatedBy uuid.UUID) ([]string, error) {
+	if err := s.requireAdmin(ctx, updatedBy); err != nil {
+		return nil, err
+	}
 	menu, err := s.repo.GetMenuByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to get menu: %w", err)
+		return nil, fmt.Errorf("failed to get menu: %w", err)
 	}
 	if menu == nil {
-		return errors.New("menu not found")
+		return nil, errors.New("menu not found")
 	}
 
 	// Update fields if provided
@@ -531,31 +1437,28 @@ func (s *service) UpdateMenu(ctx context.Context, id uuid.UUID, req *rbac.Update
 	if req.Slug != nil {
 		// Validate slug uniqueness
 		if err := s.ValidateMenuSlug(ctx, *req.Slug, &id); err != nil {
-			return err
+			return nil, err
 		}
 		menu.Slug = *req.Slug
 	}
 	if req.URL != nil {
+		if err := s.checkMenuURL(*req.URL); err != nil {
+			return nil, err
+		}
 		menu.URL = *req.URL
 	}
 	if req.Icon != nil {
 		menu.Icon = *req.Icon
 	}
 	if req.ParentID != nil {
-		// Validate parent menu exists and prevent circular reference
-		if *req.ParentID == id {
-			return errors.New("menu cannot be parent of itself")
-		}
-		if req.ParentID != nil {
-			parent, err := s.repo.GetMenuByID(ctx, *req.ParentID)
-			if err != nil {
-				return fmt.Errorf("failed to get parent menu: %w", err)
-			}
-			if parent == nil {
-				return errors.New("parent menu not found")
+		if *req.ParentID == uuid.Nil {
+			menu.ParentID = nil
+		} else {
+			if err := s.checkMenuParent(ctx, id, *req.ParentID); err != nil {
+				return nil, err
 			}
+			menu.ParentID = req.ParentID
 		}
-		menu.ParentID = req.ParentID
 	}
 	if req.SortOrder != nil {
 		menu.SortOrder = *req.SortOrder
@@ -565,16 +1468,125 @@ func (s *service) UpdateMenu(ctx context.Context, id uuid.UUID, req *rbac.Update
 	}
 
 	menu.UpdatedBy = &updatedBy
-	menu.UpdatedAt = time.Now()
+	menu.UpdatedAt = s.clock.Now()
+
+	warnings, err := s.duplicateURLWarnings(ctx, menu.URL, menu.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.UpdateMenu(ctx, menu); err != nil {
-		return fmt.Errorf("failed to update menu: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("menu not found")
+		}
+		return nil, fmt.Errorf("failed to update menu: %w", err)
 	}
 
-	return nil
+	return warnings, nil
+}
+
+// ReorderMenus checks every placement against the tree as it will be after
+// the whole batch, so menus may swap places in one request
+func (s *service) ReorderMenus(ctx context.Context, req *rbac.ReorderMenusRequest, updatedBy uuid.UUID) (*rbac.MenuTreeResponse, error) {
+	if err := s.requireAdmin(ctx, updatedBy); err != nil {
+		return nil, err
+	}
+	menus, err := s.repo.GetAllMenus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menus: %w", err)
+	}
+	parents := make(map[uuid.UUID]*uuid.UUID, len(menus))
+	for _, menu := range menus {
+		parents[menu.ID] = menu.ParentID
+	}
+
+	invalid := &rbac.BulkError{}
+	listed := make(map[uuid.UUID]int, len(req.Menus))
+	placed := make(map[int]bool, len(req.Menus))
+	for i, item := range req.Menus {
+		if first, dup := listed[item.MenuID]; dup {
+			invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "menu_id", Value: item.MenuID.String(), Message: fmt.Sprintf("menu repeats item %d", first)})
+			continue
+		}
+		listed[item.MenuID] = i
+		if _, ok := parents[item.MenuID]; !ok {
+			invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "menu_id", Value: item.MenuID.String(), Message: "menu not found"})
+			continue
+		}
+		parentID := reorderParent(item.ParentID)
+		if parentID != nil {
+			if *parentID == item.MenuID {
+				invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "parent_id", Value: parentID.String(), Message: "menu cannot be parent of itself"})
+				continue
+			}
+			if _, ok := parents[*parentID]; !ok {
+				invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "parent_id", Value: parentID.String(), Message: "parent menu not found"})
+				continue
+			}
+		}
+		parents[item.MenuID] = parentID
+		placed[i] = true
+	}
+	for i, item := range req.Menus {
+		if placed[i] && isOwnAncestor(parents, item.MenuID) {
+			invalid.Items = append(invalid.Items, rbac.BulkItemError{Index: i, Field: "parent_id", Value: parents[item.MenuID].String(), Message: "menu would be its own ancestor"})
+		}
+	}
+	if len(invalid.Items) > 0 {
+		return nil, invalid
+	}
+
+	now := s.clock.Now()
+	reordered := make([]rbac.MenuEntity, 0, len(req.Menus))
+	for _, item := range req.Menus {
+		reordered = append(reordered, rbac.MenuEntity{
+			ID:        item.MenuID,
+			ParentID:  reorderParent(item.ParentID),
+			SortOrder: item.SortOrder,
+			UpdatedBy: &updatedBy,
+			UpdatedAt: now,
+		})
+	}
+	if err := s.repo.ReorderMenus(ctx, reordered); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("menu not found")
+		}
+		return nil, fmt.Errorf("failed to reorder menus: %w", err)
+	}
+
+	tree, err := s.GetMenuTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return response.Success("Menus reordered successfully", tree.Data), nil
+}
+
+// reorderParent reads the nil UUID, like a missing parent, as the top level
+func reorderParent(parentID *uuid.UUID) *uuid.UUID {
+	if parentID == nil || *parentID == uuid.Nil {
+		return nil
+	}
+	return parentID
+}
+
+// isOwnAncestor reports whether walking up from the menu through parents
+// leads back to it. The walk is bounded in case the table already holds a
+// cycle elsewhere.
+func isOwnAncestor(parents map[uuid.UUID]*uuid.UUID, id uuid.UUID) bool {
+	parentID := parents[id]
+	for depth := 0; parentID != nil && depth <= len(parents); depth++ {
+		if *parentID == id {
+			return true
+		}
+		parentID = parents[*parentID]
+	}
+	return false
 }
 
-func (s *service) DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
+func (s *service) DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID, force bool) error {
+	if err := s.requireAdmin(ctx, deletedBy); err != nil {
+		return err
+	}
 	menu, err := s.repo.GetMenuByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get menu: %w", err)
@@ -582,12 +1594,20 @@ func (s *service) DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.U
 	if menu == nil {
 		return errors.New("menu not found")
 	}
+	if !force {
+		children, err := s.repo.CountChildMenus(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to count child menus: %w", err)
+		}
+		if children > 0 {
+			return errors.New("menu has child menus")
+		}
+	}
 
-	menu.DeletedBy = &deletedBy
-	now := time.Now()
-	menu.DeletedAt = &now
-
-	if err := s.repo.UpdateMenu(ctx, menu); err != nil {
+	if err := s.repo.DeleteMenu(ctx, id, deletedBy); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("menu not found")
+		}
 		return fmt.Errorf("failed to delete menu: %w", err)
 	}
 
@@ -596,24 +1616,151 @@ func (s *service) DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.U
 
 // User-Role services
 func (s *service) AssignRolesToUser(ctx context.Context, userID uuid.UUID, req *rbac.AssignUserRolesRequest, assignedBy uuid.UUID) (*rbac.UserRoleResponse, error) {
-	// Validate roles exist
+	scope, level, err := s.checkRoleManager(ctx, assignedBy, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRoleValidity(req.RoleValidity); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	held := make(map[uuid.UUID]rbac.UserRoleEntity, len(current))
+	for _, ur := range current {
+		held[ur.RoleID] = ur
+	}
+
+	// Validate roles exist; school admins may only change delegable roles
+	names := make(map[uuid.UUID]string, len(req.RoleIDs)+len(current))
+	if err := s.checkAssignedRoles(ctx, scope, level, held, req.RoleIDs, names); err != nil {
+		return nil, err
+	}
+	wanted := make(map[uuid.UUID]bool, len(req.RoleIDs))
 	for _, roleID := range req.RoleIDs {
+		wanted[roleID] = true
+	}
+	for roleID, ur := range held {
+		if wanted[roleID] {
+			continue
+		}
+		if scope.Restricted && !ur.Role.AssignableBySchoolAdmin {
+			return nil, fmt.Errorf("%w: %s", authz.ErrRoleNotDelegable, ur.Role.Slug)
+		}
+		if authz.RoleLevel(ur.Role.Slug) > level {
+			return nil, fmt.Errorf("%w: %s", authz.ErrRoleAboveCaller, ur.Role.Slug)
+		}
+		names[roleID] = ur.Role.Name
+	}
+
+	changes, err := s.repo.AssignRolesToUser(ctx, userID, req.RoleIDs, req.RoleValidity, assignedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign roles to user: %w", err)
+	}
+	s.roleChanges.user(userID, s.clock.Now())
+	s.forgetPermissions(ctx, userID)
+
+	s.publishRolesChanged(ctx, rbac.RolesChangedEvent{
+		UserID:    userID,
+		Added:     roleNames(changes.Added, names),
+		Removed:   roleNames(changes.Removed, names),
+		ChangedBy: assignedBy,
+	})
+
+	return response.Success("Roles assigned to user successfully", *changes), nil
+}
+
+func (s *service) AddRolesToUser(ctx context.Context, userID uuid.UUID, req *rbac.AssignUserRolesRequest, assignedBy uuid.UUID) (*rbac.UserRoleResponse, error) {
+	scope, level, err := s.checkRoleManager(ctx, assignedBy, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRoleValidity(req.RoleValidity); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	held := make(map[uuid.UUID]rbac.UserRoleEntity, len(current))
+	for _, ur := range current {
+		held[ur.RoleID] = ur
+	}
+
+	names := make(map[uuid.UUID]string, len(req.RoleIDs))
+	if err := s.checkAssignedRoles(ctx, scope, level, held, req.RoleIDs, names); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.AddRolesToUser(ctx, userID, req.RoleIDs, req.RoleValidity, assignedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add roles to user: %w", err)
+	}
+	s.roleChanges.user(userID, s.clock.Now())
+	s.forgetPermissions(ctx, userID)
+
+	s.publishRolesChanged(ctx, rbac.RolesChangedEvent{
+		UserID:    userID,
+		Added:     roleNames(changes.Added, names),
+		ChangedBy: assignedBy,
+	})
+
+	return response.Success("Roles added to user successfully", *changes), nil
+}
+
+// checkRoleValidity rejects a validity window that is empty or already over
+func (s *service) checkRoleValidity(v rbac.RoleValidity) error {
+	if v.ValidUntil == nil {
+		return nil
+	}
+	if v.ValidFrom != nil && !v.ValidUntil.After(*v.ValidFrom) {
+		return apperrors.ValidationFailed("valid_until must be after valid_from")
+	}
+	if !v.ValidUntil.After(s.clock.Now()) {
+		return apperrors.ValidationFailed("valid_until must be in the future")
+	}
+	return nil
+}
+
+// checkAssignedRoles checks that the roles exist and, for school admins,
+// that the ones the user does not hold yet are delegable. It records the
+// role names in names.
+func (s *service) checkAssignedRoles(ctx context.Context, scope authz.Scope, level int, held map[uuid.UUID]rbac.UserRoleEntity, roleIDs []uuid.UUID, names map[uuid.UUID]string) error {
+	for _, roleID := range roleIDs {
 		role, err := s.repo.GetRoleByID(ctx, roleID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get role: %w", err)
+			return fmt.Errorf("failed to get role: %w", err)
 		}
 		if role == nil {
-			return nil, fmt.Errorf("role with ID %s not found", roleID)
+			return fmt.Errorf("role with ID %s not found", roleID)
 		}
+		_, kept := held[roleID]
+		if scope.Restricted && !kept && !role.AssignableBySchoolAdmin {
+			return fmt.Errorf("%w: %s", authz.ErrRoleNotDelegable, role.Slug)
+		}
+		if !kept && authz.RoleLevel(role.Slug) > level {
+			return fmt.Errorf("%w: %s", authz.ErrRoleAboveCaller, role.Slug)
+		}
+		names[roleID] = role.Name
 	}
+	return nil
+}
 
-	if err := s.repo.AssignRolesToUser(ctx, userID, req.RoleIDs, assignedBy); err != nil {
-		return nil, fmt.Errorf("failed to assign roles to user: %w", err)
+// roleNames resolves role IDs to names, falling back to the ID for roles
+// that are no longer active
+func roleNames(ids []uuid.UUID, names map[uuid.UUID]string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name := names[id]; name != "" {
+			out = append(out, name)
+		} else {
+			out = append(out, id.String())
+		}
 	}
-
-	return response.Success("Roles assigned to user successfully", rbac.UserRoleData{
-		ID: uuid.New(),
-	}), nil
+	return out
 }
 
 func (s *service) GetUserRoles(ctx context.Context, userID uuid.UUID) (*rbac.UserRoleListResponse, error) {
@@ -630,6 +1777,10 @@ func (s *service) GetUserRoles(ctx context.Context, userID uuid.UUID) (*rbac.Use
 			RoleID:     userRole.RoleID,
 			Role:       userRole.Role.ToRole(),
 			AssignedAt: userRole.AssignedAt,
+			RoleValidity: rbac.RoleValidity{
+				ValidFrom:  userRole.ValidFrom,
+				ValidUntil: userRole.ValidUntil,
+			},
 		})
 	}
 
@@ -646,21 +1797,149 @@ func (s *service) GetUserRoles(ctx context.Context, userID uuid.UUID) (*rbac.Use
 	return response.Success("User roles retrieved successfully", data), nil
 }
 
-func (s *service) RemoveRolesFromUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID) error {
+func (s *service) GetExpiringUserRoles(ctx context.Context, within time.Duration, page, limit int) (*rbac.ExpiringUserRoleListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	now := s.clock.Now()
+	assignments, total, err := s.repo.GetExpiringUserRoles(ctx, now, now.Add(within), page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring user roles: %w", err)
+	}
+
+	list := make([]rbac.ExpiringUserRole, 0, len(assignments))
+	for _, a := range assignments {
+		list = append(list, a.ToExpiringUserRole())
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	data := rbac.ExpiringUserRoleListData{
+		Data: list,
+		Meta: rbac.RBACMetadata{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: totalPages,
+			TotalItems: int(total),
+		},
+	}
+
+	return response.Success("Expiring user roles retrieved successfully", data), nil
+}
+
+func (s *service) RemoveRolesFromUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, removedBy uuid.UUID) error {
+	scope, level, err := s.checkRoleManager(ctx, removedBy, userID)
+	if err != nil {
+		return err
+	}
+
+	removed := make([]string, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		role, err := s.repo.GetRoleByID(ctx, roleID)
+		if err != nil {
+			return fmt.Errorf("failed to get role: %w", err)
+		}
+		if role == nil {
+			continue
+		}
+		if scope.Restricted && !role.AssignableBySchoolAdmin {
+			return fmt.Errorf("%w: %s", authz.ErrRoleNotDelegable, role.Slug)
+		}
+		if authz.RoleLevel(role.Slug) > level {
+			return fmt.Errorf("%w: %s", authz.ErrRoleAboveCaller, role.Slug)
+		}
+		removed = append(removed, role.Name)
+	}
+
 	if err := s.repo.RemoveRolesFromUser(ctx, userID, roleIDs); err != nil {
 		return fmt.Errorf("failed to remove roles from user: %w", err)
 	}
+	s.roleChanges.user(userID, s.clock.Now())
+	s.forgetPermissions(ctx, userID)
 
+	s.publishRolesChanged(ctx, rbac.RolesChangedEvent{UserID: userID, Removed: removed, ChangedBy: removedBy})
 	return nil
 }
 
+// publishRolesChanged hands a role change to the event publisher, if any
+func (s *service) publishRolesChanged(ctx context.Context, event rbac.RolesChangedEvent) {
+	if s.events == nil || len(event.Added)+len(event.Removed) == 0 {
+		return
+	}
+	s.events.PublishRolesChanged(ctx, event)
+}
+
 // Authorization services
 func (s *service) CheckUserPermission(ctx context.Context, userID uuid.UUID, resource, action string) (bool, error) {
-	return s.repo.CheckUserHasPermission(ctx, userID, resource, action)
+	if s.cache == nil {
+		return s.repo.CheckUserHasPermission(ctx, userID, resource, action)
+	}
+	permissions, err := s.cachedPermissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	effects := make([]rbac.PermissionEffect, 0, len(permissions))
+	for _, key := range permissions {
+		if effect, ok := rbac.ParsePermissionEffect(key); ok {
+			effects = append(effects, effect)
+		}
+	}
+	return rbac.Decide(effects, resource, action), nil
+}
+
+func (s *service) CountCheck(ctx context.Context, kind string, allowed bool) error {
+	result := "denied"
+	if allowed {
+		result = "allowed"
+	}
+	if err := s.repo.CountCheck(ctx, kind, result); err != nil {
+		return fmt.Errorf("failed to count check: %w", err)
+	}
+	return nil
+}
+
+func (s *service) GetCheckCounts(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.repo.GetCheckCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get check counts: %w", err)
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Kind+":"+row.Result] = row.Total
+	}
+	return counts, nil
 }
 
-func (s *service) CheckUserRole(ctx context.Context, userID uuid.UUID, roleSlug string) (bool, error) {
-	return s.repo.CheckUserHasRole(ctx, userID, roleSlug)
+// ExplainUserPermission reads the database, not the permission cache, so it
+// shows the paths CheckUserPermission will weigh once its cache expires
+func (s *service) ExplainUserPermission(ctx context.Context, actorID, userID uuid.UUID, resource, action string) (*rbac.PermissionExplanationResponse, error) {
+	if _, err := s.checkUserScope(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	entities, err := s.repo.GetPermissionGrants(ctx, userID, resource, action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permission grants: %w", err)
+	}
+
+	grants := make([]rbac.PermissionGrant, 0, len(entities))
+	effects := make([]string, 0, len(entities))
+	for _, grant := range entities {
+		grants = append(grants, grant.ToPermissionGrant())
+		effects = append(effects, grant.Effect)
+	}
+
+	return response.Success("Permission explained successfully", rbac.PermissionExplanation{
+		UserID:   userID,
+		Resource: resource,
+		Action:   action,
+		Granted:  rbac.Allowed(effects),
+		Grants:   grants,
+	}), nil
 }
 
 func (s *service) GetUserPermissions(ctx context.Context, userID uuid.UUID) (*rbac.PermissionListResponse, error) {
@@ -693,13 +1972,8 @@ func (s *service) GetUserMenus(ctx context.Context, userID uuid.UUID) (*rbac.Use
 		return nil, fmt.Errorf("failed to get user menus: %w", err)
 	}
 
-	var menuList []rbac.RoleMenu
-	for _, roleMenu := range roleMenus {
-		menuList = append(menuList, roleMenu.ToRoleMenu())
-	}
-
 	return &rbac.UserMenuResponse{
-		Data: menuList,
+		Data: mergeUserMenus(roleMenus),
 	}, nil
 }
 
@@ -709,18 +1983,53 @@ func (s *service) GetUserAccessibleMenus(ctx context.Context, userID uuid.UUID)
 		return nil, fmt.Errorf("failed to get user accessible menus: %w", err)
 	}
 
-	var menuList []rbac.RoleMenu
-	for _, roleMenu := range roleMenus {
-		menuList = append(menuList, roleMenu.ToRoleMenu())
+	menus := mergeUserMenus(roleMenus)
+	viewable := menus[:0]
+	for _, menu := range menus {
+		if menu.CanView {
+			viewable = append(viewable, menu)
+		}
 	}
 
 	return &rbac.UserMenuResponse{
-		Data: menuList,
+		Data: viewable,
 	}, nil
 }
 
+// mergeUserMenus returns each menu once, in the order first seen, with the
+// access of all the user's roles combined: a flag is set when any role sets
+// it. The rest is taken from the first role menu of the menu.
+func mergeUserMenus(roleMenus []rbac.RoleMenuEntity) []rbac.RoleMenu {
+	menus := make([]rbac.RoleMenu, 0, len(roleMenus))
+	seen := make(map[uuid.UUID]int, len(roleMenus))
+	for _, roleMenu := range roleMenus {
+		i, ok := seen[roleMenu.MenuID]
+		if !ok {
+			seen[roleMenu.MenuID] = len(menus)
+			menu := roleMenu.ToRoleMenu()
+			menu.RoleIDs = []uuid.UUID{roleMenu.RoleID}
+			menus = append(menus, menu)
+			continue
+		}
+
+		menu := &menus[i]
+		menu.CanView = menu.CanView || roleMenu.CanView
+		menu.CanCreate = menu.CanCreate || roleMenu.CanCreate
+		menu.CanEdit = menu.CanEdit || roleMenu.CanEdit
+		menu.CanDelete = menu.CanDelete || roleMenu.CanDelete
+		if !slices.Contains(menu.RoleIDs, roleMenu.RoleID) {
+			menu.RoleIDs = append(menu.RoleIDs, roleMenu.RoleID)
+		}
+	}
+	return menus
+}
+
 // Validation services
 func (s *service) ValidateRoleSlug(ctx context.Context, slug string, excludeID *uuid.UUID) error {
+	if err := s.checkSlugPattern(slug); err != nil {
+		return err
+	}
+
 	existingRole, err := s.repo.GetRoleBySlug(ctx, slug)
 	if err != nil {
 		return fmt.Errorf("failed to check role slug: %w", err)
@@ -732,6 +2041,10 @@ func (s *service) ValidateRoleSlug(ctx context.Context, slug string, excludeID *
 }
 
 func (s *service) ValidatePermissionSlug(ctx context.Context, slug string, excludeID *uuid.UUID) error {
+	if err := s.checkSlugPattern(slug); err != nil {
+		return err
+	}
+
 	existingPermission, err := s.repo.GetPermissionBySlug(ctx, slug)
 	if err != nil {
 		return fmt.Errorf("failed to check permission slug: %w", err)
@@ -743,6 +2056,10 @@ func (s *service) ValidatePermissionSlug(ctx context.Context, slug string, exclu
 }
 
 func (s *service) ValidateMenuSlug(ctx context.Context, slug string, excludeID *uuid.UUID) error {
+	if err := s.checkSlugPattern(slug); err != nil {
+		return err
+	}
+
 	existingMenu, err := s.repo.GetMenuBySlug(ctx, slug)
 	if err != nil {
 		return fmt.Errorf("failed to check menu slug: %w", err)
@@ -752,3 +2069,70 @@ func (s *service) ValidateMenuSlug(ctx context.Context, slug string, excludeID *
 	}
 	return nil
 }
+
+// checkSlugPattern applies the slug pattern rule, report-only until enforced
+func (s *service) checkSlugPattern(slug string) error {
+	ok, msg := s.validator.IsValidSlug(slug)
+	return validator.Check(validator.RuleSlugPattern, ok, msg)
+}
+
+// requireAdmin checks that actorID holds admin or super-admin. Roles,
+// permissions and menus are shared by every school, so school admins may
+// not change them even when granted the route permission.
+func (s *service) requireAdmin(ctx context.Context, actorID uuid.UUID) error {
+	level, err := authz.UserLevel(ctx, s, actorID)
+	if err != nil {
+		return fmt.Errorf("failed to get actor roles: %w", err)
+	}
+	if level < authz.RoleLevel(authz.RoleAdmin) {
+		return authz.ErrNotAdmin
+	}
+	return nil
+}
+
+// checkRoleManager returns the scope and role level of an actor changing
+// the roles of targetUserID. Route permissions alone do not suffice: only
+// admins may change roles, never their own, and never grant or remove a
+// role above their own level, which the callers check per role.
+func (s *service) checkRoleManager(ctx context.Context, actorID, targetUserID uuid.UUID) (authz.Scope, int, error) {
+	if actorID == targetUserID {
+		return authz.Scope{}, 0, authz.ErrOwnRoles
+	}
+	level, err := authz.UserLevel(ctx, s, actorID)
+	if err != nil {
+		return authz.Scope{}, 0, fmt.Errorf("failed to get actor roles: %w", err)
+	}
+	if level == 0 {
+		return authz.Scope{}, 0, authz.ErrNotRoleManager
+	}
+	scope, err := s.checkUserScope(ctx, actorID, targetUserID)
+	if err != nil {
+		return authz.Scope{}, 0, err
+	}
+	return scope, level, nil
+}
+
+// checkUserScope resolves the actor's scope and rejects targets outside of it
+func (s *service) checkUserScope(ctx context.Context, actorID, targetUserID uuid.UUID) (authz.Scope, error) {
+	actorSchoolID, err := s.repo.GetUserSchoolID(ctx, actorID)
+	if err != nil {
+		return authz.Scope{}, fmt.Errorf("failed to get actor school: %w", err)
+	}
+
+	scope, err := authz.ResolveScope(ctx, s, actorID, actorSchoolID)
+	if err != nil {
+		return authz.Scope{}, fmt.Errorf("failed to resolve actor scope: %w", err)
+	}
+	if !scope.Restricted {
+		return scope, nil
+	}
+
+	targetSchoolID, err := s.repo.GetUserSchoolID(ctx, targetUserID)
+	if err != nil {
+		return authz.Scope{}, fmt.Errorf("failed to get user school: %w", err)
+	}
+	if !scope.AllowsUser(targetUserID, targetSchoolID) {
+		return authz.Scope{}, authz.ErrOutOfScope
+	}
+	return scope, nil
+}
@@ -0,0 +1,638 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/clock"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/rbac"
+	"backend-service-internpro/internal/rbac/repository"
+
+	"github.com/google/uuid"
+)
+
+// activePermissions lists the active permissions in the database; other
+// methods are not used
+type activePermissions struct {
+	repository.Repository
+	permissions  []rbac.PermissionEntity
+	noSuperAdmin bool
+}
+
+func (r *activePermissions) GetActivePermissions(context.Context) ([]rbac.PermissionEntity, error) {
+	return r.permissions, nil
+}
+
+// GetRoleBySlug knows an active super admin role unless noSuperAdmin is set
+func (r *activePermissions) GetRoleBySlug(_ context.Context, slug string) (*rbac.RoleEntity, error) {
+	if r.noSuperAdmin || slug != authz.RoleSuperAdmin {
+		return nil, nil
+	}
+	return &rbac.RoleEntity{Slug: slug, IsActive: true}, nil
+}
+
+func TestCheckConsistency(t *testing.T) {
+	// Every declared permission is in the database and required by a route,
+	// except for the drift the cases add
+	var permissions []rbac.PermissionEntity
+	var routes []routeperm.Route
+	for _, key := range authz.Permissions {
+		permissions = append(permissions, rbac.PermissionEntity{Resource: key.Resource, Action: key.Action})
+		routes = append(routes, routeperm.Route{Method: "GET", Path: "/v1/" + key.String(), Access: routeperm.AccessPermission, Resource: key.Resource, Action: key.Action})
+	}
+	public := routeperm.Route{Method: "GET", Path: "/v1/health", Access: routeperm.AccessPublic}
+	auditRoute := routeperm.Route{Method: "GET", Path: "/v1/audit", Access: routeperm.AccessSuperAdmin}
+	gradesRoute := routeperm.Route{Method: "PUT", Path: "/v1/grades/{id}", Access: routeperm.AccessPermission, Resource: "grades", Action: "edit"}
+	usersDelete := authz.PermissionKey{Resource: "users", Action: "delete"}
+	reportsExport := authz.PermissionKey{Resource: "reports", Action: "export"}
+
+	tests := []struct {
+		name         string
+		permissions  []rbac.PermissionEntity
+		noSuperAdmin bool
+		routes       []routeperm.Route
+		want         rbac.ConsistencyReport
+	}{
+		{"consistent", permissions, false, append(slices.Clone(routes), public, auditRoute), rbac.ConsistencyReport{}},
+		{
+			"declared permission missing from the database",
+			slices.DeleteFunc(slices.Clone(permissions), func(p rbac.PermissionEntity) bool {
+				return p.Resource == usersDelete.Resource && p.Action == usersDelete.Action
+			}),
+			false,
+			routes,
+			rbac.ConsistencyReport{
+				MissingInDatabase: []authz.PermissionKey{usersDelete},
+				UnknownRoutes:     []routeperm.Route{routes[slices.Index(authz.Permissions, usersDelete)]},
+			},
+		},
+		{
+			"database permission no route requires",
+			append(slices.Clone(permissions), rbac.PermissionEntity{Resource: reportsExport.Resource, Action: reportsExport.Action}),
+			false,
+			routes,
+			rbac.ConsistencyReport{UnusedByRoutes: []authz.PermissionKey{reportsExport}},
+		},
+		{
+			"route requiring an unknown permission",
+			permissions,
+			false,
+			append(slices.Clone(routes), gradesRoute),
+			rbac.ConsistencyReport{UnknownRoutes: []routeperm.Route{gradesRoute}},
+		},
+		{
+			"super admin route without the role",
+			permissions,
+			true,
+			append(slices.Clone(routes), auditRoute),
+			rbac.ConsistencyReport{UnknownRoutes: []routeperm.Route{auditRoute}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewService(&activePermissions{permissions: tt.permissions, noSuperAdmin: tt.noSuperAdmin})
+			got, err := s.CheckConsistency(context.Background(), tt.routes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got.MissingInDatabase, tt.want.MissingInDatabase) ||
+				!slices.Equal(got.UnusedByRoutes, tt.want.UnusedByRoutes) ||
+				!slices.Equal(got.UnknownRoutes, tt.want.UnknownRoutes) {
+				t.Errorf("report = %+v, want %+v", *got, tt.want)
+			}
+			if got.Consistent() != (tt.name == "consistent") {
+				t.Errorf("Consistent() = %v", got.Consistent())
+			}
+		})
+	}
+}
+
+// roleItems knows one role with n permissions and n menus and pages
+// through them; other methods are not used
+type roleItems struct {
+	repository.Repository
+	role *rbac.RoleEntity
+	n    int
+}
+
+func (r *roleItems) GetRoleByID(_ context.Context, id uuid.UUID) (*rbac.RoleEntity, error) {
+	if id != r.role.ID {
+		return nil, nil
+	}
+	return r.role, nil
+}
+
+// page returns the number of items on page of size limit
+func (r *roleItems) page(page, limit int) int {
+	return max(0, min(limit, r.n-(page-1)*limit))
+}
+
+func (r *roleItems) GetRolePermissionsPage(_ context.Context, _ uuid.UUID, page, limit int) ([]rbac.PermissionEntity, int64, error) {
+	return make([]rbac.PermissionEntity, r.page(page, limit)), int64(r.n), nil
+}
+
+func (r *roleItems) GetRoleMenusPage(_ context.Context, _ uuid.UUID, page, limit int) ([]rbac.RoleMenuEntity, int64, error) {
+	return make([]rbac.RoleMenuEntity, r.page(page, limit)), int64(r.n), nil
+}
+
+func TestEmbeddedListsAreCapped(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit, n      int
+		wantEmbedded  int
+		wantTruncated bool
+	}{
+		{"under the limit", 3, 2, 2, false},
+		{"at the limit", 3, 3, 3, false},
+		{"over the limit", 3, 5, 3, true},
+		{"default limit", 0, DefaultEmbedLimit + 1, DefaultEmbedLimit, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &roleItems{role: &rbac.RoleEntity{ID: uuid.New(), Slug: "teacher"}, n: tt.n}
+			s := NewServiceWithConfig(repo, Config{EmbedLimit: tt.limit})
+			ctx := context.Background()
+
+			withPermissions, err := s.GetRoleWithPermissions(ctx, repo.role.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			role := withPermissions.Data.(rbac.Role)
+			if len(role.Permissions) != tt.wantEmbedded || role.PermissionsTotal == nil || *role.PermissionsTotal != tt.n || role.Truncated != tt.wantTruncated {
+				t.Errorf("embedded %d of %v permissions, truncated %v; want %d of %d, truncated %v",
+					len(role.Permissions), role.PermissionsTotal, role.Truncated, tt.wantEmbedded, tt.n, tt.wantTruncated)
+			}
+			if want := "/v1/roles/" + repo.role.ID.String() + "/permissions"; role.PermissionsLink != want {
+				t.Errorf("permissions link = %q, want %q", role.PermissionsLink, want)
+			}
+
+			withMenus, err := s.GetRoleWithMenus(ctx, repo.role.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			role = withMenus.Data.(rbac.Role)
+			if len(role.Menus) != tt.wantEmbedded || role.MenusTotal == nil || *role.MenusTotal != tt.n || role.Truncated != tt.wantTruncated {
+				t.Errorf("embedded %d of %v menus, truncated %v; want %d of %d, truncated %v",
+					len(role.Menus), role.MenusTotal, role.Truncated, tt.wantEmbedded, tt.n, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestRestoreRoleWithinWindow(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	ids := idgen.NewSequence()
+	repo := newFakeRepo("super-admin")
+	svc := NewServiceWithConfig(repo, Config{RestoreWindow: 24 * time.Hour, Clock: clk, IDs: ids})
+	callerID := ids.New()
+	repo.grant(callerID, "super-admin")
+
+	deleted := now
+	expired, kept := ids.New(), ids.New()
+	repo.deleted[expired] = &rbac.RoleEntity{ID: expired, Slug: "expired", DeletedAt: &deleted}
+	repo.deleted[kept] = &rbac.RoleEntity{ID: kept, Slug: "kept", DeletedAt: &deleted}
+
+	// A day after the deletion both roles are still in the window
+	clk.Advance(24 * time.Hour)
+	if _, err := svc.RestoreRole(context.Background(), kept, callerID); err != nil {
+		t.Fatalf("restore at the end of the window: %v", err)
+	}
+
+	clk.Advance(time.Second)
+	if _, err := svc.RestoreRole(context.Background(), expired, callerID); err == nil {
+		t.Fatal("restore after the window succeeded, want an error")
+	}
+	if repo.writes != 1 {
+		t.Errorf("restored %d roles, want 1", repo.writes)
+	}
+	if _, ok := repo.roles[kept]; !ok {
+		t.Errorf("role %s is not restored", kept)
+	}
+}
+
+// fakeRepo holds the roles of each user in memory and puts every user in
+// the same school; other methods are not used
+type fakeRepo struct {
+	repository.Repository
+	schoolID  uuid.UUID
+	roles     map[uuid.UUID]*rbac.RoleEntity
+	userRoles map[uuid.UUID][]uuid.UUID
+	deleted   map[uuid.UUID]*rbac.RoleEntity
+	writes    int
+}
+
+func newFakeRepo(slugs ...string) *fakeRepo {
+	r := &fakeRepo{schoolID: uuid.New(), roles: map[uuid.UUID]*rbac.RoleEntity{}, userRoles: map[uuid.UUID][]uuid.UUID{}, deleted: map[uuid.UUID]*rbac.RoleEntity{}}
+	for _, slug := range slugs {
+		id := uuid.New()
+		r.roles[id] = &rbac.RoleEntity{ID: id, Slug: slug, Name: slug, AssignableBySchoolAdmin: slug == "teacher"}
+	}
+	return r
+}
+
+func (r *fakeRepo) roleID(slug string) uuid.UUID {
+	for id, role := range r.roles {
+		if role.Slug == slug {
+			return id
+		}
+	}
+	panic("unknown role " + slug)
+}
+
+func (r *fakeRepo) grant(userID uuid.UUID, slug string) {
+	r.userRoles[userID] = append(r.userRoles[userID], r.roleID(slug))
+}
+
+func (r *fakeRepo) GetUserSchoolID(context.Context, uuid.UUID) (*uuid.UUID, error) {
+	return &r.schoolID, nil
+}
+
+func (r *fakeRepo) CheckUserHasRole(_ context.Context, userID uuid.UUID, slug string) (bool, error) {
+	for _, id := range r.userRoles[userID] {
+		if r.roles[id].Slug == slug {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) GetUserRoles(_ context.Context, userID uuid.UUID) ([]rbac.UserRoleEntity, error) {
+	var out []rbac.UserRoleEntity
+	for _, id := range r.userRoles[userID] {
+		out = append(out, rbac.UserRoleEntity{UserID: userID, RoleID: id, Role: *r.roles[id]})
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) GetRoleByID(_ context.Context, id uuid.UUID) (*rbac.RoleEntity, error) {
+	return r.roles[id], nil
+}
+
+func (r *fakeRepo) GetRoleBySlug(_ context.Context, slug string) (*rbac.RoleEntity, error) {
+	for _, role := range r.roles {
+		if role.Slug == slug {
+			return role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) GetDeletedRoleByID(_ context.Context, id uuid.UUID) (*rbac.RoleEntity, error) {
+	return r.deleted[id], nil
+}
+
+func (r *fakeRepo) RestoreRole(_ context.Context, id uuid.UUID, _ uuid.UUID) (*rbac.RoleRestoreData, error) {
+	r.writes++
+	r.roles[id] = r.deleted[id]
+	delete(r.deleted, id)
+	return &rbac.RoleRestoreData{ID: id}, nil
+}
+
+func (r *fakeRepo) AddRolesToUser(_ context.Context, userID uuid.UUID, roleIDs []uuid.UUID, _ rbac.RoleValidity, _ uuid.UUID) (*rbac.UserRoleChanges, error) {
+	r.writes++
+	r.userRoles[userID] = append(r.userRoles[userID], roleIDs...)
+	return &rbac.UserRoleChanges{Added: roleIDs}, nil
+}
+
+func (r *fakeRepo) RemoveRolesFromUser(context.Context, uuid.UUID, []uuid.UUID) error {
+	r.writes++
+	return nil
+}
+
+func TestUserRoleChangesRequireAManager(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller string // role held by the caller
+		self   bool
+		add    string
+		remove string
+		want   error
+	}{
+		{name: "super admin grants admin", caller: "super-admin", add: "admin"},
+		{name: "admin grants teacher", caller: "admin", add: "teacher"},
+		{name: "admin grants admin", caller: "admin", add: "admin"},
+		{name: "admin grants super admin", caller: "admin", add: "super-admin", want: authz.ErrRoleAboveCaller},
+		{name: "admin removes super admin", caller: "admin", remove: "super-admin", want: authz.ErrRoleAboveCaller},
+		{name: "school admin grants teacher", caller: "school-admin", add: "teacher"},
+		{name: "school admin grants admin", caller: "school-admin", add: "admin", want: authz.ErrRoleNotDelegable},
+		{name: "caller without an admin role", caller: "teacher", add: "teacher", want: authz.ErrNotRoleManager},
+		{name: "super admin changes their own roles", caller: "super-admin", self: true, add: "teacher", want: authz.ErrOwnRoles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo("super-admin", "admin", "school-admin", "teacher")
+			svc := NewService(repo)
+			callerID, targetID := uuid.New(), uuid.New()
+			if tt.self {
+				targetID = callerID
+			}
+			repo.grant(callerID, tt.caller)
+			if tt.remove != "" {
+				repo.grant(targetID, tt.remove)
+			}
+
+			var err error
+			if tt.add != "" {
+				_, err = svc.AddRolesToUser(context.Background(), targetID, &rbac.AssignUserRolesRequest{
+					RoleIDs: []uuid.UUID{repo.roleID(tt.add)},
+				}, callerID)
+			} else {
+				err = svc.RemoveRolesFromUser(context.Background(), targetID, []uuid.UUID{repo.roleID(tt.remove)}, callerID)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if wrote := repo.writes > 0; wrote != (tt.want == nil) {
+				t.Errorf("wrote = %v, want %v", wrote, tt.want == nil)
+			}
+		})
+	}
+}
+
+func TestRoleWritesRequireAnAdmin(t *testing.T) {
+	tests := []struct {
+		caller string
+		want   error
+	}{
+		{"super-admin", nil},
+		{"admin", nil},
+		{"school-admin", authz.ErrNotAdmin},
+		{"teacher", authz.ErrNotAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caller, func(t *testing.T) {
+			repo := newFakeRepo("super-admin", "admin", "school-admin", "teacher")
+			svc := NewService(repo)
+			callerID := uuid.New()
+			repo.grant(callerID, tt.caller)
+
+			err := svc.RemovePermissionsFromRole(context.Background(), uuid.New(), nil, callerID)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("err = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			// Past the admin check the unknown role is reported instead
+			if errors.Is(err, authz.ErrNotAdmin) {
+				t.Fatalf("err = %v, want the admin check to pass", err)
+			}
+		})
+	}
+}
+
+// attachedPermission knows one permission attached to the named roles and
+// records how it was deleted; the roles of callers come from fakeRepo
+type attachedPermission struct {
+	*fakeRepo
+	permission *rbac.PermissionEntity
+	roleNames  []string
+	deleted    string
+}
+
+func (r *attachedPermission) GetPermissionByID(_ context.Context, id uuid.UUID) (*rbac.PermissionEntity, error) {
+	if id != r.permission.ID {
+		return nil, nil
+	}
+	return r.permission, nil
+}
+
+func (r *attachedPermission) GetPermissionRoleNames(context.Context, uuid.UUID) ([]string, error) {
+	return r.roleNames, nil
+}
+
+func (r *attachedPermission) DeletePermission(context.Context, uuid.UUID, uuid.UUID) error {
+	r.deleted = "deleted"
+	return nil
+}
+
+func (r *attachedPermission) ForceDeletePermission(context.Context, uuid.UUID, uuid.UUID) error {
+	r.deleted, r.roleNames = "detached and deleted", nil
+	return nil
+}
+
+func TestDeletePermissionAttachedToRoles(t *testing.T) {
+	tests := []struct {
+		name        string
+		roleNames   []string
+		force       bool
+		wantDeleted string
+	}{
+		{"not attached", nil, false, "deleted"},
+		{"attached", []string{"Guru", "Kepala Sekolah"}, false, ""},
+		{"attached, forced", []string{"Guru", "Kepala Sekolah"}, true, "detached and deleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &attachedPermission{
+				fakeRepo:   newFakeRepo("super-admin"),
+				permission: &rbac.PermissionEntity{ID: uuid.New(), Slug: "users.delete"},
+				roleNames:  tt.roleNames,
+			}
+			adminID := uuid.New()
+			repo.grant(adminID, "super-admin")
+
+			err := NewService(repo).DeletePermission(context.Background(), repo.permission.ID, adminID, tt.force)
+			if tt.wantDeleted == "" {
+				appErr, ok := apperrors.IsAppError(err)
+				if !ok || appErr.Code != apperrors.CodeConflict {
+					t.Fatalf("err = %v, want a conflict", err)
+				}
+				for _, name := range tt.roleNames {
+					if !strings.Contains(appErr.Message, name) {
+						t.Errorf("conflict %q does not name role %s", appErr.Message, name)
+					}
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if repo.deleted != tt.wantDeleted {
+				t.Errorf("permission %q, want %q", repo.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+// menuTable holds menus in memory by ID; the roles of callers come from
+// fakeRepo
+type menuTable struct {
+	*fakeRepo
+	menus map[uuid.UUID]rbac.MenuEntity
+}
+
+func (r *menuTable) GetMenuByID(_ context.Context, id uuid.UUID) (*rbac.MenuEntity, error) {
+	menu, ok := r.menus[id]
+	if !ok {
+		return nil, nil
+	}
+	return &menu, nil
+}
+
+func (r *menuTable) GetMenuBySlug(context.Context, string) (*rbac.MenuEntity, error) {
+	return nil, nil
+}
+
+func (r *menuTable) CreateMenu(_ context.Context, menu *rbac.MenuEntity) error {
+	r.menus[menu.ID] = *menu
+	return nil
+}
+
+func (r *menuTable) UpdateMenu(_ context.Context, menu *rbac.MenuEntity) error {
+	r.menus[menu.ID] = *menu
+	return nil
+}
+
+func TestMenuParentCycles(t *testing.T) {
+	repo := &menuTable{fakeRepo: newFakeRepo("admin"), menus: map[uuid.UUID]rbac.MenuEntity{}}
+	adminID := uuid.New()
+	repo.grant(adminID, "admin")
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	// a <- b <- c, and x <- y <- z <- x, a loop already in the table
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	x, y, z := uuid.New(), uuid.New(), uuid.New()
+	for id, parent := range map[uuid.UUID]*uuid.UUID{a: nil, b: &a, c: &b, x: &z, y: &x, z: &y} {
+		repo.menus[id] = rbac.MenuEntity{ID: id, ParentID: parent}
+	}
+
+	moves := []struct {
+		name     string
+		id       uuid.UUID
+		parentID uuid.UUID
+		wantErr  string
+	}{
+		{"under itself", a, a, "menu cannot be parent of itself"},
+		{"under its child", b, c, "menu cannot be moved under its own descendant"},
+		{"under its grandchild", a, c, "menu cannot be moved under its own descendant"},
+		{"under a menu inside a loop", a, y, "parent menu is nested too deep"},
+		{"under a missing menu", a, uuid.New(), "parent menu not found"},
+		{"under its grandparent", c, a, ""},
+	}
+	for _, tt := range moves {
+		t.Run("move "+tt.name, func(t *testing.T) {
+			before := repo.menus[tt.id].ParentID
+			parentID := tt.parentID
+			_, err := svc.UpdateMenu(ctx, tt.id, &rbac.UpdateMenuRequest{ParentID: &parentID}, adminID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := repo.menus[tt.id].ParentID; got == nil || *got != tt.parentID {
+					t.Errorf("parent = %v, want %s", got, tt.parentID)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if got := repo.menus[tt.id].ParentID; got != before {
+				t.Errorf("parent changed to %v on a refused move", got)
+			}
+		})
+	}
+
+	creates := []struct {
+		name     string
+		parentID uuid.UUID
+		wantErr  string
+	}{
+		{"under a menu inside a loop", z, "parent menu is nested too deep"},
+		{"under a missing menu", uuid.New(), "parent menu not found"},
+		{"under a menu outside the loop", b, ""},
+	}
+	for _, tt := range creates {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			count := len(repo.menus)
+			parentID := tt.parentID
+			_, err := svc.CreateMenu(ctx, &rbac.CreateMenuRequest{Name: "Nilai", Slug: "grades", ParentID: &parentID}, adminID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(repo.menus) != count+1 {
+					t.Errorf("%d menus after creating, want %d", len(repo.menus), count+1)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if len(repo.menus) != count {
+				t.Errorf("a menu was created under a refused parent")
+			}
+		})
+	}
+}
+
+// userMenus returns the same role menus from both user menu queries
+type userMenus struct {
+	repository.Repository
+	roleMenus []rbac.RoleMenuEntity
+}
+
+func (r *userMenus) GetUserMenus(context.Context, uuid.UUID) ([]rbac.RoleMenuEntity, error) {
+	return r.roleMenus, nil
+}
+
+func (r *userMenus) GetUserAccessibleMenus(context.Context, uuid.UUID) ([]rbac.RoleMenuEntity, error) {
+	return r.roleMenus, nil
+}
+
+func TestUserMenusMergeRoles(t *testing.T) {
+	teacher, principal := uuid.New(), uuid.New()
+	users, reports := uuid.New(), uuid.New()
+	// Both roles grant the users menu with conflicting flags; only the
+	// teacher role grants reports, without the view
+	repo := &userMenus{roleMenus: []rbac.RoleMenuEntity{
+		{ID: uuid.New(), RoleID: teacher, MenuID: users, CanView: true, CanCreate: true},
+		{ID: uuid.New(), RoleID: teacher, MenuID: reports, CanEdit: true},
+		{ID: uuid.New(), RoleID: principal, MenuID: users, CanEdit: true, CanDelete: true},
+	}}
+	merged := rbac.RoleMenu{MenuID: users, RoleID: teacher, RoleIDs: []uuid.UUID{teacher, principal},
+		CanView: true, CanCreate: true, CanEdit: true, CanDelete: true}
+	hidden := rbac.RoleMenu{MenuID: reports, RoleID: teacher, RoleIDs: []uuid.UUID{teacher}, CanEdit: true}
+
+	svc := NewService(repo)
+	tests := []struct {
+		name  string
+		query func(context.Context, uuid.UUID) (*rbac.UserMenuResponse, error)
+		want  []rbac.RoleMenu
+	}{
+		{"user menus", svc.GetUserMenus, []rbac.RoleMenu{merged, hidden}},
+		// A menu no role can view is left out
+		{"accessible menus", svc.GetUserAccessibleMenus, []rbac.RoleMenu{merged}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.query(context.Background(), uuid.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := res.Data.([]rbac.RoleMenu)
+			if !ok {
+				t.Fatalf("data is %T, want []rbac.RoleMenu", res.Data)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%d menus, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				// The ID is that of the first role menu of the menu
+				got[i].ID = uuid.Nil
+				if !reflect.DeepEqual(got[i], want) {
+					t.Errorf("menu %d = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
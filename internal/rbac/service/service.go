@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"backend-service-internpro/internal/pkg/routeperm"
 	"backend-service-internpro/internal/rbac"
 
 	"github.com/google/uuid"
@@ -12,44 +14,121 @@ type Service interface {
 	// Role services
 	CreateRole(ctx context.Context, req *rbac.CreateRoleRequest, createdBy uuid.UUID) (*rbac.CreateRoleResponse, error)
 	GetRoleByID(ctx context.Context, id uuid.UUID) (*rbac.RoleResponse, error)
-	GetRoles(ctx context.Context, page, limit int, search string) (*rbac.RoleListResponse, error)
+	// GetRoles, GetPermissions and GetMenus reject a sort field or direction
+	// outside their whitelist; GetRoles and GetPermissions list deleted rows
+	// too with IncludeDeleted
+	GetRoles(ctx context.Context, page, limit int, filter rbac.RoleFilter) (*rbac.RoleListResponse, error)
 	UpdateRole(ctx context.Context, id uuid.UUID, req *rbac.UpdateRoleRequest, updatedBy uuid.UUID) error
-	DeleteRole(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error
+	// DeleteRole refuses roles still assigned to users unless force is set.
+	// The deleted role is detached from its users, permissions and menus.
+	DeleteRole(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID, force bool) error
+	RestoreRole(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) (*rbac.RoleRestoreResponse, error)
 	GetRoleWithPermissions(ctx context.Context, id uuid.UUID) (*rbac.RoleResponse, error)
 	GetRoleWithMenus(ctx context.Context, id uuid.UUID) (*rbac.RoleResponse, error)
+	GetRolePermissions(ctx context.Context, roleID uuid.UUID, page, limit int) (*rbac.PermissionListResponse, error)
+	GetRoleMenus(ctx context.Context, roleID uuid.UUID, page, limit int) (*rbac.MenuListResponse, error)
+	GetRoleUsers(ctx context.Context, roleID uuid.UUID, page, limit int, search string) (*rbac.RoleUserListResponse, error)
+	// AssignPermissionsToRole replaces the role's permissions, while
+	// AddPermissionsToRole and RemovePermissionsFromRole only touch the
+	// listed ones
 	AssignPermissionsToRole(ctx context.Context, roleID uuid.UUID, req *rbac.AssignRolePermissionsRequest, assignedBy uuid.UUID) error
+	AddPermissionsToRole(ctx context.Context, roleID uuid.UUID, req *rbac.AssignRolePermissionsRequest, assignedBy uuid.UUID) error
+	RemovePermissionsFromRole(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID, removedBy uuid.UUID) error
 	AssignMenusToRole(ctx context.Context, roleID uuid.UUID, req *rbac.AssignRoleMenusRequest, assignedBy uuid.UUID) error
+	// RemoveMenusFromRole ignores menus the role does not have
+	RemoveMenusFromRole(ctx context.Context, roleID uuid.UUID, menuIDs []uuid.UUID, removedBy uuid.UUID) error
 
 	// Permission services
 	CreatePermission(ctx context.Context, req *rbac.CreatePermissionRequest, createdBy uuid.UUID) (*rbac.CreatePermissionResponse, error)
+	// GeneratePermissions creates the missing permissions of a resource,
+	// optionally granting them to a role
+	GeneratePermissions(ctx context.Context, req *rbac.GeneratePermissionsRequest, createdBy uuid.UUID) (*rbac.GeneratePermissionsResponse, error)
+	// CreatePermissions creates all permissions or, returning a
+	// *rbac.BulkError for the invalid ones, none
+	CreatePermissions(ctx context.Context, req *rbac.BulkCreatePermissionsRequest, createdBy uuid.UUID) (*rbac.BulkCreatePermissionsResponse, error)
 	GetPermissionByID(ctx context.Context, id uuid.UUID) (*rbac.PermissionResponse, error)
-	GetPermissions(ctx context.Context, page, limit int, search string) (*rbac.PermissionListResponse, error)
+	GetPermissions(ctx context.Context, page, limit int, filter rbac.PermissionFilter) (*rbac.PermissionListResponse, error)
+	GetGroupedPermissions(ctx context.Context, search string) (*rbac.GroupedPermissionsResponse, error)
 	UpdatePermission(ctx context.Context, id uuid.UUID, req *rbac.UpdatePermissionRequest, updatedBy uuid.UUID) error
-	DeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error
+	// DeletePermission refuses permissions still attached to roles unless
+	// force is set; then it detaches them
+	DeletePermission(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID, force bool) error
+	// RestorePermission undeletes a permission within the restore window;
+	// roles it was detached from do not get it back
+	RestorePermission(ctx context.Context, id uuid.UUID, restoredBy uuid.UUID) (*rbac.PermissionResponse, error)
 	GetPermissionsByResource(ctx context.Context, resource string) (*rbac.PermissionListResponse, error)
 
 	// Menu services
 	CreateMenu(ctx context.Context, req *rbac.CreateMenuRequest, createdBy uuid.UUID) (*rbac.CreateMenuResponse, error)
 	GetMenuByID(ctx context.Context, id uuid.UUID) (*rbac.MenuResponse, error)
-	GetMenus(ctx context.Context, page, limit int, search string) (*rbac.MenuListResponse, error)
+	GetMenus(ctx context.Context, page, limit int, filter rbac.MenuFilter) (*rbac.MenuListResponse, error)
 	GetMenuTree(ctx context.Context) (*rbac.MenuTreeResponse, error)
-	UpdateMenu(ctx context.Context, id uuid.UUID, req *rbac.UpdateMenuRequest, updatedBy uuid.UUID) error
-	DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error
+	GetMenuReport(ctx context.Context) (*rbac.MenuReportResponse, error)
+	// UpdateMenu returns non-blocking warnings, e.g. a URL shared with other active menus
+	UpdateMenu(ctx context.Context, id uuid.UUID, req *rbac.UpdateMenuRequest, updatedBy uuid.UUID) ([]string, error)
+	// ReorderMenus moves and reorders the menus in one transaction and
+	// returns the refreshed menu tree
+	ReorderMenus(ctx context.Context, req *rbac.ReorderMenusRequest, updatedBy uuid.UUID) (*rbac.MenuTreeResponse, error)
+	// DeleteMenu refuses menus with children unless force is set; then the
+	// children move up to the deleted menu's parent
+	DeleteMenu(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID, force bool) error
 
 	// User-Role services
+	// AssignRolesToUser replaces the user's roles, while AddRolesToUser
+	// only assigns the missing ones
 	AssignRolesToUser(ctx context.Context, userID uuid.UUID, req *rbac.AssignUserRolesRequest, assignedBy uuid.UUID) (*rbac.UserRoleResponse, error)
+	AddRolesToUser(ctx context.Context, userID uuid.UUID, req *rbac.AssignUserRolesRequest, assignedBy uuid.UUID) (*rbac.UserRoleResponse, error)
 	GetUserRoles(ctx context.Context, userID uuid.UUID) (*rbac.UserRoleListResponse, error)
-	RemoveRolesFromUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID) error
+	// GetExpiringUserRoles lists the assignments ending within the given
+	// time from now
+	GetExpiringUserRoles(ctx context.Context, within time.Duration, page, limit int) (*rbac.ExpiringUserRoleListResponse, error)
+	RemoveRolesFromUser(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID, removedBy uuid.UUID) error
 
 	// Authorization services
 	CheckUserPermission(ctx context.Context, userID uuid.UUID, resource, action string) (bool, error)
+	// ExplainUserPermission lists the roles through which the user holds or
+	// is denied the permission; actors limited to a school only see its users
+	ExplainUserPermission(ctx context.Context, actorID, userID uuid.UUID, resource, action string) (*rbac.PermissionExplanationResponse, error)
+	// CheckUserRole answers from the roles claimed by the request's access
+	// token when it belongs to userID and no role change happened since it
+	// was issued, and from the database otherwise
 	CheckUserRole(ctx context.Context, userID uuid.UUID, roleSlug string) (bool, error)
+	// CountCheck counts a check made through the check endpoints by kind,
+	// permission or role, and result
+	CountCheck(ctx context.Context, kind string, allowed bool) error
+	// GetCheckCounts returns the checks counted by CountCheck, keyed by
+	// "<kind>:<allowed|denied>"
+	GetCheckCounts(ctx context.Context) (map[string]int64, error)
+	// GetUserRoleSlugs lists the slugs of the user's active roles, for the
+	// access token claims
+	GetUserRoleSlugs(ctx context.Context, userID uuid.UUID) ([]string, error)
 	GetUserPermissions(ctx context.Context, userID uuid.UUID) (*rbac.PermissionListResponse, error)
 	GetUserMenus(ctx context.Context, userID uuid.UUID) (*rbac.UserMenuResponse, error)
 	GetUserAccessibleMenus(ctx context.Context, userID uuid.UUID) (*rbac.UserMenuResponse, error)
+	// GetUserLanding returns the menu the user opens first after login
+	GetUserLanding(ctx context.Context, userID uuid.UUID) (*rbac.LandingResponse, error)
 
 	// Validation services
 	ValidateRoleSlug(ctx context.Context, slug string, excludeID *uuid.UUID) error
 	ValidatePermissionSlug(ctx context.Context, slug string, excludeID *uuid.UUID) error
 	ValidateMenuSlug(ctx context.Context, slug string, excludeID *uuid.UUID) error
+
+	// Maintenance services
+	// PruneOrphans removes role_menus, role_permissions and user_roles rows
+	// whose target is gone or was deleted longer ago than the restore window
+	PruneOrphans(ctx context.Context) (*rbac.PruneOrphansData, error)
+	// InvalidateRoleClaims makes role checks read the database for access
+	// tokens issued until now, for everyone, one user or one role
+	InvalidateRoleClaims(ctx context.Context, req *rbac.InvalidateRoleClaimsRequest) (*rbac.InvalidateRoleClaimsResponse, error)
+	// CheckConsistency compares the permissions declared in code, the
+	// permissions table and the permissions routes require
+	CheckConsistency(ctx context.Context, routes []routeperm.Route) (*rbac.ConsistencyReport, error)
+
+	// Transfer services
+	// ExportRBAC returns the roles, permissions, menus and their links,
+	// referring to each other by slug
+	ExportRBAC(ctx context.Context) (*rbac.RBACDocument, error)
+	// ImportRBAC applies an exported document; with dryRun it only reports
+	// what would change
+	ImportRBAC(ctx context.Context, doc *rbac.RBACDocument, dryRun bool, importedBy uuid.UUID) (*rbac.RBACImportResponse, error)
 }
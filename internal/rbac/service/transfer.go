@@ -0,0 +1,681 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/rbac"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) ExportRBAC(ctx context.Context) (*rbac.RBACDocument, error) {
+	snapshot, err := s.repo.GetRBACSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rbac configuration: %w", err)
+	}
+
+	now := s.clock.Now()
+	doc := &rbac.RBACDocument{
+		Version:         rbac.RBACDocumentVersion,
+		ExportedAt:      &now,
+		Permissions:     []rbac.RBACDocumentPermission{},
+		Menus:           []rbac.RBACDocumentMenu{},
+		Roles:           []rbac.RBACDocumentRole{},
+		RolePermissions: []rbac.RBACDocumentRolePermission{},
+		RoleMenus:       []rbac.RBACDocumentRoleMenu{},
+	}
+
+	// The snapshot is ordered by slug; deleted items are left out, along
+	// with the links to them
+	permissionSlugs := make(map[uuid.UUID]string, len(snapshot.Permissions))
+	for _, p := range snapshot.Permissions {
+		if p.DeletedAt != nil {
+			continue
+		}
+		permissionSlugs[p.ID] = p.Slug
+		doc.Permissions = append(doc.Permissions, rbac.RBACDocumentPermission{
+			Slug:        p.Slug,
+			Name:        p.Name,
+			Module:      p.Module,
+			Resource:    p.Resource,
+			Action:      p.Action,
+			Description: p.Description,
+			IsActive:    p.IsActive,
+		})
+	}
+
+	menuSlugs := make(map[uuid.UUID]string, len(snapshot.Menus))
+	for _, m := range snapshot.Menus {
+		if m.DeletedAt == nil {
+			menuSlugs[m.ID] = m.Slug
+		}
+	}
+	for _, m := range snapshot.Menus {
+		if m.DeletedAt != nil {
+			continue
+		}
+		menu := rbac.RBACDocumentMenu{
+			Slug:      m.Slug,
+			Name:      m.Name,
+			URL:       m.URL,
+			Icon:      m.Icon,
+			SortOrder: m.SortOrder,
+			IsActive:  m.IsActive,
+		}
+		if m.ParentID != nil {
+			menu.ParentSlug = menuSlugs[*m.ParentID]
+		}
+		doc.Menus = append(doc.Menus, menu)
+	}
+
+	roleSlugs := make(map[uuid.UUID]string, len(snapshot.Roles))
+	for _, r := range snapshot.Roles {
+		if r.DeletedAt != nil {
+			continue
+		}
+		roleSlugs[r.ID] = r.Slug
+		role := rbac.RBACDocumentRole{
+			Slug:                    r.Slug,
+			Name:                    r.Name,
+			Description:             r.Description,
+			IsActive:                r.IsActive,
+			AssignableBySchoolAdmin: r.AssignableBySchoolAdmin,
+			Priority:                r.Priority,
+		}
+		if r.DefaultMenuID != nil {
+			role.DefaultMenuSlug = menuSlugs[*r.DefaultMenuID]
+		}
+		doc.Roles = append(doc.Roles, role)
+	}
+
+	for _, rp := range snapshot.RolePermissions {
+		roleSlug, permissionSlug := roleSlugs[rp.RoleID], permissionSlugs[rp.PermissionID]
+		if roleSlug == "" || permissionSlug == "" {
+			continue
+		}
+		doc.RolePermissions = append(doc.RolePermissions, rbac.RBACDocumentRolePermission{
+			RoleSlug:       roleSlug,
+			PermissionSlug: permissionSlug,
+			Effect:         rp.Effect,
+		})
+	}
+	sort.Slice(doc.RolePermissions, func(i, j int) bool {
+		a, b := doc.RolePermissions[i], doc.RolePermissions[j]
+		return a.RoleSlug < b.RoleSlug || a.RoleSlug == b.RoleSlug && a.PermissionSlug < b.PermissionSlug
+	})
+
+	for _, rm := range snapshot.RoleMenus {
+		roleSlug, menuSlug := roleSlugs[rm.RoleID], menuSlugs[rm.MenuID]
+		if roleSlug == "" || menuSlug == "" {
+			continue
+		}
+		doc.RoleMenus = append(doc.RoleMenus, rbac.RBACDocumentRoleMenu{
+			RoleSlug:  roleSlug,
+			MenuSlug:  menuSlug,
+			CanView:   rm.CanView,
+			CanCreate: rm.CanCreate,
+			CanEdit:   rm.CanEdit,
+			CanDelete: rm.CanDelete,
+		})
+	}
+	sort.Slice(doc.RoleMenus, func(i, j int) bool {
+		a, b := doc.RoleMenus[i], doc.RoleMenus[j]
+		return a.RoleSlug < b.RoleSlug || a.RoleSlug == b.RoleSlug && a.MenuSlug < b.MenuSlug
+	})
+
+	return doc, nil
+}
+
+// ImportRBAC upserts the roles, permissions and menus of the document by
+// slug and makes the links of the listed roles match it. Items the
+// document leaves out are not touched. Every problem is collected before
+// anything is written, and returned as an *rbac.ImportError.
+func (s *service) ImportRBAC(ctx context.Context, doc *rbac.RBACDocument, dryRun bool, importedBy uuid.UUID) (*rbac.RBACImportResponse, error) {
+	snapshot, err := s.repo.GetRBACSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rbac configuration: %w", err)
+	}
+
+	imp := newRBACImport(snapshot, doc)
+	imp.checkPermissions(s)
+	imp.checkMenus(s)
+	imp.checkRoles(s)
+	imp.checkLinks()
+	if len(imp.issues) > 0 {
+		return nil, &rbac.ImportError{Issues: imp.issues}
+	}
+
+	plan, report := imp.plan(s.clock.Now(), importedBy, s.ids.New)
+	report.DryRun = dryRun
+	if dryRun {
+		return response.Success("Import checked, nothing was written", report), nil
+	}
+
+	if err := s.repo.ImportRBAC(ctx, plan); err != nil {
+		return nil, fmt.Errorf("failed to import rbac configuration: %w", err)
+	}
+	s.roleChanges.everyone(s.clock.Now())
+	s.forgetPermissions(ctx)
+
+	return response.Success("RBAC configuration imported successfully", report), nil
+}
+
+// rbacImport checks a document against the current configuration and
+// plans the writes importing it takes
+type rbacImport struct {
+	doc    *rbac.RBACDocument
+	issues []rbac.ImportIssue
+
+	// Current items by slug, deleted ones included since their slugs stay
+	// taken
+	roles       map[string]*rbac.RoleEntity
+	permissions map[string]*rbac.PermissionEntity
+	menus       map[string]*rbac.MenuEntity
+	// Current names of roles and permissions, which are unique, to the
+	// slug holding them
+	roleNames       map[string]string
+	permissionNames map[string]string
+
+	rolePermissions []rbac.RolePermissionEntity
+	roleMenus       []rbac.RoleMenuEntity
+
+	// Document items by slug
+	docRoles       map[string]int
+	docPermissions map[string]int
+	docMenus       map[string]int
+}
+
+func newRBACImport(snapshot *rbac.RBACSnapshot, doc *rbac.RBACDocument) *rbacImport {
+	imp := &rbacImport{
+		doc:             doc,
+		roles:           make(map[string]*rbac.RoleEntity, len(snapshot.Roles)),
+		permissions:     make(map[string]*rbac.PermissionEntity, len(snapshot.Permissions)),
+		menus:           make(map[string]*rbac.MenuEntity, len(snapshot.Menus)),
+		roleNames:       make(map[string]string, len(snapshot.Roles)),
+		permissionNames: make(map[string]string, len(snapshot.Permissions)),
+		rolePermissions: snapshot.RolePermissions,
+		roleMenus:       snapshot.RoleMenus,
+		docRoles:        make(map[string]int, len(doc.Roles)),
+		docPermissions:  make(map[string]int, len(doc.Permissions)),
+		docMenus:        make(map[string]int, len(doc.Menus)),
+	}
+	for i := range snapshot.Roles {
+		r := &snapshot.Roles[i]
+		imp.roles[r.Slug] = r
+		imp.roleNames[r.Name] = r.Slug
+	}
+	for i := range snapshot.Permissions {
+		p := &snapshot.Permissions[i]
+		imp.permissions[p.Slug] = p
+		imp.permissionNames[p.Name] = p.Slug
+	}
+	for i := range snapshot.Menus {
+		m := &snapshot.Menus[i]
+		imp.menus[m.Slug] = m
+	}
+	return imp
+}
+
+func (imp *rbacImport) invalid(location, value, message string) {
+	imp.issues = append(imp.issues, rbac.ImportIssue{Location: location, Value: value, Message: message})
+}
+
+func (imp *rbacImport) conflict(location, value, message string) {
+	imp.issues = append(imp.issues, rbac.ImportIssue{Location: location, Value: value, Message: message, Conflict: true})
+}
+
+// checkSlug reports a slug breaking the slug pattern or repeating an
+// earlier item of the list, and records it otherwise
+func (imp *rbacImport) checkSlug(s *service, list string, i int, slug string, seen map[string]int) bool {
+	location := fmt.Sprintf("%s[%d].slug", list, i)
+	if first, dup := seen[slug]; dup {
+		imp.invalid(location, slug, fmt.Sprintf("slug repeats %s[%d]", list, first))
+		return false
+	}
+	seen[slug] = i
+	if err := s.checkSlugPattern(slug); err != nil {
+		imp.invalid(location, slug, bulkMessage(err))
+		return false
+	}
+	return true
+}
+
+// checkName reports a name repeating an earlier item of the list or held
+// by an item the document leaves out
+func (imp *rbacImport) checkName(list string, i int, name, slug string, seen map[string]int, current map[string]string, listed map[string]int) {
+	location := fmt.Sprintf("%s[%d].name", list, i)
+	if first, dup := seen[name]; dup {
+		imp.invalid(location, name, fmt.Sprintf("name repeats %s[%d]", list, first))
+		return
+	}
+	seen[name] = i
+	if holder, ok := current[name]; ok && holder != slug {
+		if _, renamed := listed[holder]; !renamed {
+			imp.conflict(location, name, "name is used by "+holder)
+		}
+	}
+}
+
+func (imp *rbacImport) checkPermissions(s *service) {
+	names := make(map[string]int, len(imp.doc.Permissions))
+	for i, p := range imp.doc.Permissions {
+		if !imp.checkSlug(s, "permissions", i, p.Slug, imp.docPermissions) {
+			continue
+		}
+		if current, ok := imp.permissions[p.Slug]; ok {
+			location := fmt.Sprintf("permissions[%d].slug", i)
+			if current.DeletedAt != nil {
+				imp.conflict(location, p.Slug, "slug belongs to a deleted permission")
+			} else if current.Resource != p.Resource || current.Action != p.Action {
+				imp.conflict(location, p.Slug, fmt.Sprintf("slug belongs to %s:%s, not %s:%s", current.Resource, current.Action, p.Resource, p.Action))
+			}
+		}
+	}
+	for i, p := range imp.doc.Permissions {
+		imp.checkName("permissions", i, p.Name, p.Slug, names, imp.permissionNames, imp.docPermissions)
+	}
+}
+
+func (imp *rbacImport) checkMenus(s *service) {
+	for i, m := range imp.doc.Menus {
+		if !imp.checkSlug(s, "menus", i, m.Slug, imp.docMenus) {
+			continue
+		}
+		if current, ok := imp.menus[m.Slug]; ok && current.DeletedAt != nil {
+			imp.conflict(fmt.Sprintf("menus[%d].slug", i), m.Slug, "slug belongs to a deleted menu")
+		}
+	}
+
+	// Parents after the import: the current ones, overridden by the document
+	parents := make(map[string]string, len(imp.menus))
+	slugs := make(map[uuid.UUID]string, len(imp.menus))
+	for slug, m := range imp.menus {
+		slugs[m.ID] = slug
+	}
+	for slug, m := range imp.menus {
+		if m.DeletedAt == nil && m.ParentID != nil {
+			parents[slug] = slugs[*m.ParentID]
+		}
+	}
+	for i, m := range imp.doc.Menus {
+		delete(parents, m.Slug)
+		if m.ParentSlug == "" {
+			continue
+		}
+		if m.ParentSlug == m.Slug || !imp.menuExists(m.ParentSlug) {
+			imp.invalid(fmt.Sprintf("menus[%d].parent_slug", i), m.ParentSlug, "parent menu not found")
+			continue
+		}
+		parents[m.Slug] = m.ParentSlug
+	}
+	for i, m := range imp.doc.Menus {
+		// A loop above the menu is reported for the menus in it
+		for steps, slug := 0, parents[m.Slug]; slug != "" && steps <= len(parents); steps, slug = steps+1, parents[slug] {
+			if slug == m.Slug {
+				imp.invalid(fmt.Sprintf("menus[%d].parent_slug", i), m.ParentSlug, "menu would be its own ancestor")
+				break
+			}
+		}
+	}
+}
+
+// menuExists reports whether the menu is in the document or is current
+func (imp *rbacImport) menuExists(slug string) bool {
+	if _, ok := imp.docMenus[slug]; ok {
+		return true
+	}
+	m, ok := imp.menus[slug]
+	return ok && m.DeletedAt == nil
+}
+
+// landable reports whether the menu will be active with a URL after the
+// import, as a landing menu must be
+func (imp *rbacImport) landable(slug string) bool {
+	if i, ok := imp.docMenus[slug]; ok {
+		m := imp.doc.Menus[i]
+		return m.IsActive && m.URL != ""
+	}
+	m, ok := imp.menus[slug]
+	return ok && m.DeletedAt == nil && m.IsActive && m.URL != ""
+}
+
+func (imp *rbacImport) checkRoles(s *service) {
+	names := make(map[string]int, len(imp.doc.Roles))
+	for i, r := range imp.doc.Roles {
+		if !imp.checkSlug(s, "roles", i, r.Slug, imp.docRoles) {
+			continue
+		}
+		if current, ok := imp.roles[r.Slug]; ok && current.DeletedAt != nil {
+			imp.conflict(fmt.Sprintf("roles[%d].slug", i), r.Slug, "slug belongs to a deleted role, restore it first")
+		}
+		if r.DefaultMenuSlug != "" && !imp.landable(r.DefaultMenuSlug) {
+			imp.invalid(fmt.Sprintf("roles[%d].default_menu_slug", i), r.DefaultMenuSlug, "default menu must be an active menu with a URL")
+		}
+	}
+	for i, r := range imp.doc.Roles {
+		imp.checkName("roles", i, r.Name, r.Slug, names, imp.roleNames, imp.docRoles)
+	}
+}
+
+func (imp *rbacImport) checkLinks() {
+	seen := make(map[string]int, len(imp.doc.RolePermissions))
+	for i, rp := range imp.doc.RolePermissions {
+		location := fmt.Sprintf("role_permissions[%d]", i)
+		if _, ok := imp.docRoles[rp.RoleSlug]; !ok {
+			imp.invalid(location+".role_slug", rp.RoleSlug, "role is not listed in roles")
+			continue
+		}
+		current, ok := imp.permissions[rp.PermissionSlug]
+		if _, listed := imp.docPermissions[rp.PermissionSlug]; !listed && (!ok || current.DeletedAt != nil) {
+			imp.invalid(location+".permission_slug", rp.PermissionSlug, "permission not found")
+			continue
+		}
+		key := rp.RoleSlug + "/" + rp.PermissionSlug
+		if first, dup := seen[key]; dup {
+			imp.invalid(location, key, fmt.Sprintf("link repeats role_permissions[%d]", first))
+			continue
+		}
+		seen[key] = i
+	}
+
+	seen = make(map[string]int, len(imp.doc.RoleMenus))
+	for i, rm := range imp.doc.RoleMenus {
+		location := fmt.Sprintf("role_menus[%d]", i)
+		if _, ok := imp.docRoles[rm.RoleSlug]; !ok {
+			imp.invalid(location+".role_slug", rm.RoleSlug, "role is not listed in roles")
+			continue
+		}
+		if !imp.menuExists(rm.MenuSlug) {
+			imp.invalid(location+".menu_slug", rm.MenuSlug, "menu not found")
+			continue
+		}
+		key := rm.RoleSlug + "/" + rm.MenuSlug
+		if first, dup := seen[key]; dup {
+			imp.invalid(location, key, fmt.Sprintf("link repeats role_menus[%d]", first))
+			continue
+		}
+		seen[key] = i
+	}
+}
+
+// plan works out the writes of a checked document and reports them
+func (imp *rbacImport) plan(now time.Time, by uuid.UUID, newID func() uuid.UUID) (*rbac.RBACImportPlan, rbac.RBACImportReport) {
+	plan := &rbac.RBACImportPlan{}
+	report := rbac.RBACImportReport{
+		Permissions:     newImportChanges(),
+		Menus:           newImportChanges(),
+		Roles:           newImportChanges(),
+		RolePermissions: newImportChanges(),
+		RoleMenus:       newImportChanges(),
+	}
+
+	permissionIDs := make(map[string]uuid.UUID, len(imp.permissions)+len(imp.doc.Permissions))
+	for slug, p := range imp.permissions {
+		permissionIDs[slug] = p.ID
+	}
+	for _, p := range imp.doc.Permissions {
+		module := permissionModule(p.Module, p.Resource)
+		current, ok := imp.permissions[p.Slug]
+		if !ok {
+			entity := rbac.PermissionEntity{
+				ID:          newID(),
+				Name:        p.Name,
+				Slug:        p.Slug,
+				Module:      module,
+				Resource:    p.Resource,
+				Action:      p.Action,
+				Description: p.Description,
+				IsActive:    p.IsActive,
+				CreatedAt:   now,
+				CreatedBy:   &by,
+				UpdatedAt:   now,
+			}
+			permissionIDs[p.Slug] = entity.ID
+			plan.CreatePermissions = append(plan.CreatePermissions, entity)
+			report.Permissions.Created = append(report.Permissions.Created, p.Slug)
+			continue
+		}
+		if current.Name == p.Name && current.Module == module && current.Description == p.Description && current.IsActive == p.IsActive {
+			report.Permissions.Unchanged++
+			continue
+		}
+		entity := *current
+		entity.Name, entity.Module, entity.Description, entity.IsActive = p.Name, module, p.Description, p.IsActive
+		entity.UpdatedAt, entity.UpdatedBy = now, &by
+		plan.UpdatePermissions = append(plan.UpdatePermissions, entity)
+		report.Permissions.Updated = append(report.Permissions.Updated, p.Slug)
+	}
+
+	menuIDs := make(map[string]uuid.UUID, len(imp.menus)+len(imp.doc.Menus))
+	for slug, m := range imp.menus {
+		menuIDs[slug] = m.ID
+	}
+	for _, m := range imp.doc.Menus {
+		if _, ok := imp.menus[m.Slug]; !ok {
+			menuIDs[m.Slug] = newID()
+		}
+	}
+	created := make(map[string]rbac.MenuEntity)
+	for _, m := range imp.doc.Menus {
+		var parentID *uuid.UUID
+		if m.ParentSlug != "" {
+			id := menuIDs[m.ParentSlug]
+			parentID = &id
+		}
+		current, ok := imp.menus[m.Slug]
+		if !ok {
+			created[m.Slug] = rbac.MenuEntity{
+				ID:        menuIDs[m.Slug],
+				Name:      m.Name,
+				Slug:      m.Slug,
+				URL:       m.URL,
+				Icon:      m.Icon,
+				ParentID:  parentID,
+				SortOrder: m.SortOrder,
+				IsActive:  m.IsActive,
+				CreatedAt: now,
+				CreatedBy: &by,
+				UpdatedAt: now,
+			}
+			report.Menus.Created = append(report.Menus.Created, m.Slug)
+			continue
+		}
+		if current.Name == m.Name && current.URL == m.URL && current.Icon == m.Icon && sameID(current.ParentID, parentID) &&
+			current.SortOrder == m.SortOrder && current.IsActive == m.IsActive {
+			report.Menus.Unchanged++
+			continue
+		}
+		entity := *current
+		entity.Name, entity.URL, entity.Icon, entity.ParentID = m.Name, m.URL, m.Icon, parentID
+		entity.SortOrder, entity.IsActive = m.SortOrder, m.IsActive
+		entity.UpdatedAt, entity.UpdatedBy = now, &by
+		plan.UpdateMenus = append(plan.UpdateMenus, entity)
+		report.Menus.Updated = append(report.Menus.Updated, m.Slug)
+	}
+	// Parents before children; the check ruled out loops
+	placed := make(map[string]bool, len(created))
+	var place func(m rbac.MenuEntity)
+	place = func(m rbac.MenuEntity) {
+		if placed[m.Slug] {
+			return
+		}
+		placed[m.Slug] = true
+		if parent := imp.doc.Menus[imp.docMenus[m.Slug]].ParentSlug; parent != "" {
+			if p, ok := created[parent]; ok {
+				place(p)
+			}
+		}
+		plan.CreateMenus = append(plan.CreateMenus, m)
+	}
+	for _, m := range imp.doc.Menus {
+		if c, ok := created[m.Slug]; ok {
+			place(c)
+		}
+	}
+
+	roleIDs := make(map[string]uuid.UUID, len(imp.doc.Roles))
+	for _, r := range imp.doc.Roles {
+		var defaultMenuID *uuid.UUID
+		if r.DefaultMenuSlug != "" {
+			id := menuIDs[r.DefaultMenuSlug]
+			defaultMenuID = &id
+		}
+		current, ok := imp.roles[r.Slug]
+		if !ok {
+			entity := rbac.RoleEntity{
+				ID:                      newID(),
+				Name:                    r.Name,
+				Slug:                    r.Slug,
+				Description:             r.Description,
+				IsActive:                r.IsActive,
+				AssignableBySchoolAdmin: r.AssignableBySchoolAdmin,
+				DefaultMenuID:           defaultMenuID,
+				Priority:                r.Priority,
+				CreatedAt:               now,
+				CreatedBy:               &by,
+				UpdatedAt:               now,
+			}
+			roleIDs[r.Slug] = entity.ID
+			plan.CreateRoles = append(plan.CreateRoles, entity)
+			report.Roles.Created = append(report.Roles.Created, r.Slug)
+			continue
+		}
+		roleIDs[r.Slug] = current.ID
+		if current.Name == r.Name && current.Description == r.Description && current.IsActive == r.IsActive &&
+			current.AssignableBySchoolAdmin == r.AssignableBySchoolAdmin && sameID(current.DefaultMenuID, defaultMenuID) && current.Priority == r.Priority {
+			report.Roles.Unchanged++
+			continue
+		}
+		entity := *current
+		entity.Name, entity.Description, entity.IsActive = r.Name, r.Description, r.IsActive
+		entity.AssignableBySchoolAdmin, entity.DefaultMenuID, entity.Priority = r.AssignableBySchoolAdmin, defaultMenuID, r.Priority
+		entity.UpdatedAt, entity.UpdatedBy = now, &by
+		plan.UpdateRoles = append(plan.UpdateRoles, entity)
+		report.Roles.Updated = append(report.Roles.Updated, r.Slug)
+	}
+
+	imp.planRolePermissions(plan, &report.RolePermissions, roleIDs, permissionIDs, now, by, newID)
+	imp.planRoleMenus(plan, &report.RoleMenus, roleIDs, menuIDs, now, by, newID)
+	return plan, report
+}
+
+func (imp *rbacImport) planRolePermissions(plan *rbac.RBACImportPlan, changes *rbac.RBACImportChanges, roleIDs, permissionIDs map[string]uuid.UUID, now time.Time, by uuid.UUID, newID func() uuid.UUID) {
+	slugs := make(map[uuid.UUID]string, len(permissionIDs))
+	for slug, id := range permissionIDs {
+		slugs[id] = slug
+	}
+	listed := make(map[uuid.UUID]string, len(roleIDs))
+	for slug, id := range roleIDs {
+		listed[id] = slug
+	}
+	current := make(map[string]rbac.RolePermissionEntity)
+	for _, rp := range imp.rolePermissions {
+		if roleSlug, ok := listed[rp.RoleID]; ok {
+			current[roleSlug+"/"+slugs[rp.PermissionID]] = rp
+		}
+	}
+
+	for _, rp := range imp.doc.RolePermissions {
+		key := rp.RoleSlug + "/" + rp.PermissionSlug
+		existing, ok := current[key]
+		delete(current, key)
+		switch {
+		case !ok:
+			plan.CreateRolePermissions = append(plan.CreateRolePermissions, rbac.RolePermissionEntity{
+				ID:           newID(),
+				RoleID:       roleIDs[rp.RoleSlug],
+				PermissionID: permissionIDs[rp.PermissionSlug],
+				Effect:       rp.Effect,
+				CreatedAt:    now,
+				CreatedBy:    &by,
+			})
+			changes.Created = append(changes.Created, key)
+		case existing.Effect != rp.Effect:
+			existing.Effect = rp.Effect
+			plan.UpdateRolePermissions = append(plan.UpdateRolePermissions, existing)
+			changes.Updated = append(changes.Updated, key)
+		default:
+			changes.Unchanged++
+		}
+	}
+	for _, key := range sortedKeys(current) {
+		plan.DeleteRolePermissions = append(plan.DeleteRolePermissions, current[key].ID)
+		changes.Removed = append(changes.Removed, key)
+	}
+}
+
+func (imp *rbacImport) planRoleMenus(plan *rbac.RBACImportPlan, changes *rbac.RBACImportChanges, roleIDs, menuIDs map[string]uuid.UUID, now time.Time, by uuid.UUID, newID func() uuid.UUID) {
+	slugs := make(map[uuid.UUID]string, len(menuIDs))
+	for slug, id := range menuIDs {
+		slugs[id] = slug
+	}
+	listed := make(map[uuid.UUID]string, len(roleIDs))
+	for slug, id := range roleIDs {
+		listed[id] = slug
+	}
+	current := make(map[string]rbac.RoleMenuEntity)
+	for _, rm := range imp.roleMenus {
+		if roleSlug, ok := listed[rm.RoleID]; ok {
+			current[roleSlug+"/"+slugs[rm.MenuID]] = rm
+		}
+	}
+
+	for _, rm := range imp.doc.RoleMenus {
+		key := rm.RoleSlug + "/" + rm.MenuSlug
+		existing, ok := current[key]
+		delete(current, key)
+		switch {
+		case !ok:
+			plan.CreateRoleMenus = append(plan.CreateRoleMenus, rbac.RoleMenuEntity{
+				ID:        newID(),
+				RoleID:    roleIDs[rm.RoleSlug],
+				MenuID:    menuIDs[rm.MenuSlug],
+				CanView:   rm.CanView,
+				CanCreate: rm.CanCreate,
+				CanEdit:   rm.CanEdit,
+				CanDelete: rm.CanDelete,
+				CreatedAt: now,
+				CreatedBy: &by,
+				UpdatedAt: now,
+				UpdatedBy: &by,
+			})
+			changes.Created = append(changes.Created, key)
+		case existing.CanView != rm.CanView || existing.CanCreate != rm.CanCreate || existing.CanEdit != rm.CanEdit || existing.CanDelete != rm.CanDelete:
+			existing.CanView, existing.CanCreate, existing.CanEdit, existing.CanDelete = rm.CanView, rm.CanCreate, rm.CanEdit, rm.CanDelete
+			existing.UpdatedAt, existing.UpdatedBy = now, &by
+			plan.UpdateRoleMenus = append(plan.UpdateRoleMenus, existing)
+			changes.Updated = append(changes.Updated, key)
+		default:
+			changes.Unchanged++
+		}
+	}
+	for _, key := range sortedKeys(current) {
+		plan.DeleteRoleMenus = append(plan.DeleteRoleMenus, current[key].ID)
+		changes.Removed = append(changes.Removed, key)
+	}
+}
+
+func newImportChanges() rbac.RBACImportChanges {
+	return rbac.RBACImportChanges{Created: []string{}, Updated: []string{}, Removed: []string{}}
+}
+
+// sameID reports whether two optional IDs are equal
+func sameID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
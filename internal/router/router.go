@@ -0,0 +1,225 @@
+package router
+
+import (
+	"net/http"
+
+	apikeyhttp "backend-service-internpro/internal/apikey/delivery/http"
+	audithttp "backend-service-internpro/internal/audit/delivery/http"
+	authhttp "backend-service-internpro/internal/auth/delivery/http"
+	"backend-service-internpro/internal/container"
+	integrityhttp "backend-service-internpro/internal/integrity/delivery/http"
+	loglevelhttp "backend-service-internpro/internal/loglevel/delivery/http"
+	notificationhttp "backend-service-internpro/internal/notification/delivery/http"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/routeperm"
+	privacyhttp "backend-service-internpro/internal/privacy/delivery/http"
+	rbachttp "backend-service-internpro/internal/rbac/delivery/http"
+	schoolhttp "backend-service-internpro/internal/school/delivery/http"
+	searchhttp "backend-service-internpro/internal/search/delivery/http"
+	statshttp "backend-service-internpro/internal/stats/delivery/http"
+	usagehttp "backend-service-internpro/internal/usage/delivery/http"
+	userhttp "backend-service-internpro/internal/user/delivery/http"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// HumaConfig returns the Huma configuration with the detailed OpenAPI
+// documentation; port is used for the local development server entry
+func HumaConfig(port string) huma.Config {
+	config := huma.DefaultConfig("Gapura SchoolTech API", "0.0.1")
+	config.OpenAPI.Info.Description = "Dokumentasi API untuk platform SchoolTech. Ini mencakup endpoint untuk autentikasi, kepentingan internal SchoolTech Indonesia, dan kepentingan produk SchoolTech Indonesia."
+	config.OpenAPI.Info.Contact = &huma.Contact{
+		Name:  "ITDB SchoolTech",
+		Email: "itdb@schooltechindonesia.com",
+		URL:   "https://schooltechindonesia.com",
+	}
+	config.OpenAPI.Info.License = &huma.License{
+		Name: "MIT",
+		URL:  "https://opensource.org/licenses/MIT",
+	}
+	config.OpenAPI.Info.TermsOfService = "https://schooltechindonesia.com/terms"
+	config.OpenAPI.Servers = []*huma.Server{
+		{
+			URL:         "https://api.schooltechindonesia.com",
+			Description: "Production server",
+		},
+		{
+			URL:         "https://staging-api.schooltechindonesia.com",
+			Description: "Staging server",
+		},
+		{
+			URL:         "https://testing-api.schooltechindonesia.com",
+			Description: "Testing server",
+		},
+		{
+			URL:         "http://localhost:" + port,
+			Description: "Local development",
+		},
+	}
+
+	// Initialize components if not exists
+	if config.OpenAPI.Components == nil {
+		config.OpenAPI.Components = &huma.Components{}
+	}
+	if config.OpenAPI.Components.SecuritySchemes == nil {
+		config.OpenAPI.Components.SecuritySchemes = make(map[string]*huma.SecurityScheme)
+	}
+
+	// Add security schemes
+	config.OpenAPI.Components.SecuritySchemes["bearerAuth"] = &huma.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+	// Accepted wherever bearerAuth is, limited to the key's scopes
+	config.OpenAPI.Components.SecuritySchemes["apiKeyAuth"] = &huma.SecurityScheme{
+		Type:        "apiKey",
+		In:          "header",
+		Name:        middleware.APIKeyHeader,
+		Description: "API key of a machine caller, accepted instead of a bearer token on routes requiring a permission within the key's scopes",
+	}
+
+	// Add API tags for better organization
+	config.OpenAPI.Tags = []*huma.Tag{
+		{
+			Name:        "Authentication",
+			Description: "Endpoint untuk autentikasi pengguna, login, logout, dan manajemen token",
+		},
+		{
+			Name:        "User Management",
+			Description: "Endpoint untuk manajemen data pengguna",
+		},
+		{
+			Name:        "RBAC - Roles",
+			Description: "Endpoint untuk manajemen roles (peran) dalam sistem RBAC",
+		},
+		{
+			Name:        "RBAC - Permissions",
+			Description: "Endpoint untuk manajemen permissions (izin) dalam sistem RBAC",
+		},
+		{
+			Name:        "RBAC - Menus",
+			Description: "Endpoint untuk manajemen menus dalam sistem RBAC",
+		},
+		{
+			Name:        "RBAC - User Roles",
+			Description: "Endpoint untuk assignment dan manajemen roles pengguna",
+		},
+		{
+			Name:        "RBAC - Authorization",
+			Description: "Endpoint untuk memeriksa izin dan role pengguna",
+		},
+		{
+			Name:        "School Management",
+			Description: "Endpoint untuk manajemen data sekolah",
+		},
+		{
+			Name:        "Teacher Management",
+			Description: "Endpoint untuk manajemen data guru",
+		},
+		{
+			Name:        "Student Management",
+			Description: "Endpoint untuk manajemen data siswa",
+		},
+		{
+			Name:        "Notifications",
+			Description: "Endpoint untuk notifikasi pengguna yang sedang login",
+		},
+		{
+			Name:        "Search",
+			Description: "Endpoint untuk pencarian global lintas pengguna, sekolah, role, dan menu",
+		},
+		{
+			Name:        "Statistics",
+			Description: "Endpoint untuk statistik dashboard admin",
+		},
+	}
+
+	return config
+}
+
+// Register installs the Huma middlewares and every Huma route and returns
+// the permission registry filled by the routes. The generated OpenAPI spec
+// only depends on what is registered here, so the spec generator can pass a
+// container without initialized services.
+func Register(api huma.API, c *container.Container) *routeperm.Registry {
+	// Every route records the permission it requires while registering
+	routes := routeperm.NewRegistry()
+	routes.OnCollision(collisionHandler(c))
+	routes.Attach(api)
+
+	// Huma middlewares must be registered before the routes; the auth
+	// middleware enforces each operation's declared security and the
+	// permission check needs the user it stores
+	api.UseMiddleware(middleware.HumaAuthMiddleware(api, c.JWTSecrets, c.Revocations, c.APIKeyService))
+	api.UseMiddleware(middleware.NewRBACMiddleware(c.RBACService).HumaRoutePermission(api, routes, c.RoutePolicy))
+	if c.RoutePolicy.ReportOnly {
+		logger.Global().Auth().Warn("route permissions are report-only: callers missing a permission are logged, not rejected")
+	}
+
+	// /v1/users is shared by the user, RBAC and privacy modules. The router
+	// owns the group and each module attaches its routes to it, so there is
+	// one place to add group-wide settings and collisions stay visible.
+	users := huma.NewGroup(api, "/v1/users")
+
+	// Register routes
+	authhttp.New(api, c.AuthService)
+	authhttp.NewJWKS(api, c.JWTSecrets)                      // Public keys verifying access tokens
+	userhttp.New(users, c.UserService)                       // User management routes
+	authhttp.NewUserEvents(users, c.AuthService)             // Authentication history of a user
+	rbachttp.NewHuma(api, c.RBACService)                     // RBAC management routes with Swagger
+	rbachttp.NewUserRoles(users, c.RBACService)              // Roles, permissions and menus of a user
+	rbachttp.NewLanding(api, c.RBACService)                  // Menu to open after login
+	rbachttp.NewChecks(api, c.RBACService, c.PrivacyService) // Permission and role checks
+	rbachttp.NewMaintenance(api, c.RBACService)              // RBAC orphan cleanup
+	rbachttp.NewTransfer(api, c.RBACService)                 // RBAC configuration export and import
+	schoolhttp.New(api, c.SchoolService)                     // School management routes
+	searchhttp.New(api, c.SearchService)                     // Global search route
+	statshttp.New(api, c.StatsService)                       // Dashboard counts
+	usagehttp.New(api, users, c.UsageService)                // Per user API usage
+	notificationhttp.New(api, c.NotificationService)         // Current user's notifications
+	privacyhttp.New(api, users, c.PrivacyService)            // Personal data export and erasure
+	rbachttp.NewRouteMap(api, routes)                        // Permission required by each route
+	rbachttp.NewConsistency(api, c.RBACService, routes)      // Permission drift report
+	integrityhttp.New(api, c.IntegrityService)               // Audit references to missing users
+	audithttp.New(api, c.AuditService)                       // Audit log search and export
+	loglevelhttp.New(api, c.LogLevelService)                 // Runtime log levels
+	apikeyhttp.New(api, c.APIKeyService)                     // Machine caller keys
+
+	nameResponses(api.OpenAPI())
+	return routes
+}
+
+// collisionHandler stops startup when two registrations share a method and
+// path, or only logs them when ROUTE_COLLISIONS=warn. Without a config,
+// as when generating the spec, collisions always fail.
+func collisionHandler(c *container.Container) func(routeperm.Collision) {
+	warn := c.Config != nil && c.Config.Server.WarnRouteCollisions
+	return func(col routeperm.Collision) {
+		if !warn {
+			panic(col.Error())
+		}
+		logger.Warn("route collision", "error", col.Error())
+	}
+}
+
+// Table lists every route served by engine: the routes in the registry plus
+// those added to Gin directly, which have no permission check and are
+// listed as public with the "server" source
+func Table(engine *gin.Engine, routes *routeperm.Registry) []routeperm.Route {
+	table := routes.Routes()
+	for _, info := range engine.Routes() {
+		if info.Method == http.MethodHead || routes.Known(info.Method, info.Path) {
+			continue
+		}
+		table = append(table, routeperm.Route{
+			Method: info.Method,
+			Path:   info.Path,
+			Access: routeperm.AccessPublic,
+			Source: "server",
+		})
+	}
+	return table
+}
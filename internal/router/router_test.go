@@ -0,0 +1,105 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"backend-service-internpro/internal/container"
+	"backend-service-internpro/internal/pkg/routeperm"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humagin"
+	"github.com/gin-gonic/gin"
+)
+
+func registerAll(t *testing.T) (huma.API, *routeperm.Registry) {
+	t.Helper()
+	gin.SetMode(gin.ReleaseMode)
+	api := humagin.New(gin.New(), HumaConfig("8080"))
+	// Services are never called while registering routes
+	return api, Register(api, &container.Container{})
+}
+
+func TestRouteMap(t *testing.T) {
+	_, routes := registerAll(t)
+
+	tests := []struct {
+		method string
+		path   string
+		want   routeperm.Permission
+	}{
+		{http.MethodPost, "/v1/auth/login", routeperm.Public},
+		{http.MethodGet, "/v1/auth/me", routeperm.Authenticated},
+		{http.MethodGet, "/v1/schools", routeperm.Require("schools", "view")},
+		{http.MethodPost, "/v1/schools", routeperm.Require("schools", "create")},
+		{http.MethodPut, "/v1/schools/{id}", routeperm.Require("schools", "edit")},
+		{http.MethodDelete, "/v1/schools/{id}", routeperm.Require("schools", "delete")},
+		{http.MethodPost, "/v1/users/{id}/roles", routeperm.Require("users", "edit")},
+		{http.MethodGet, "/v1/rbac/route-map", routeperm.Require("permissions", "view")},
+		{http.MethodPost, "/v1/auth/revoke-token", routeperm.SuperAdmin},
+		{http.MethodPost, "/v1/auth/impersonate/{user_id}", routeperm.SuperAdmin},
+		{http.MethodPost, "/v1/auth/stop-impersonation", routeperm.Authenticated},
+		{http.MethodPost, "/v1/users/{id}/erase/confirmation", routeperm.SuperAdmin},
+		{http.MethodDelete, "/v1/users/{id}/erase", routeperm.SuperAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			route, ok := routes.Lookup(tt.method, tt.path)
+			if !ok {
+				t.Fatal("route is not in the map")
+			}
+			if route.Permission() != tt.want {
+				t.Errorf("permission = %+v, want %+v", route.Permission(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEveryOperationIsMapped(t *testing.T) {
+	api, routes := registerAll(t)
+
+	// An operation registered with huma.Register instead of
+	// routeperm.Register would be denied in production as unknown
+	for path, item := range api.OpenAPI().Paths {
+		for _, op := range []*huma.Operation{item.Get, item.Post, item.Put, item.Patch, item.Delete} {
+			if op == nil {
+				continue
+			}
+			if _, ok := routes.Lookup(op.Method, path); !ok {
+				t.Errorf("%s %s (%s) has no route map entry", op.Method, path, op.OperationID)
+			}
+		}
+	}
+}
+
+func TestRouteCollisions(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	// The Gin RBAC handlers mounted next to their Huma versions
+	mountGinRoute := func(routes *routeperm.Registry) {
+		group := gin.New().Group("/v1/users")
+		routeperm.Handle(routes, group, http.MethodPost, "/:user_id/roles", routeperm.Require("users", "edit"), func(*gin.Context) {})
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		_, routes := registerAll(t)
+		defer func() {
+			msg, _ := recover().(string)
+			want := "route POST /v1/users/:user_id/roles registered by router collides with POST /v1/users/{id}/roles registered by rbac"
+			if msg != want {
+				t.Errorf("panic = %q, want %q", msg, want)
+			}
+		}()
+		mountGinRoute(routes)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		api := humagin.New(gin.New(), HumaConfig("8080"))
+		routes := Register(api, &container.Container{Config: &container.Config{
+			Server: container.ServerConfig{WarnRouteCollisions: true},
+		}})
+		mountGinRoute(routes)
+		if collisions := routes.Collisions(); len(collisions) != 1 || collisions[0].First.Path != "/v1/users/{id}/roles" {
+			t.Errorf("collisions = %+v, want the Huma route first", collisions)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package router
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"backend-service-internpro/internal/pkg/response"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// nameResponses gives every operation answering with the shared ApiResponse
+// envelope its own named schema, <OperationID>Response, so client
+// generators produce one type per operation instead of one shared by all.
+// It runs once every route is registered; the wire format is unchanged.
+//
+// The data property stays untyped: services fill it with any value, and
+// typing it from the documented examples would register types whose names
+// collide across modules, like auth.User and user.User.
+func nameResponses(oapi *huma.OpenAPI) {
+	registry := oapi.Components.Schemas
+	envelopeRef := registry.Schema(reflect.TypeOf(response.ApiResponse{}), true, "").Ref
+	envelope := registry.SchemaFromRef(envelopeRef)
+	prefix := strings.TrimSuffix(envelopeRef, "ApiResponse")
+
+	for _, item := range oapi.Paths {
+		for _, op := range []*huma.Operation{item.Get, item.Post, item.Put, item.Patch, item.Delete} {
+			if op == nil {
+				continue
+			}
+			// Sorted so an operation with several success statuses names
+			// them the same way on every run
+			statuses := make([]string, 0, len(op.Responses))
+			for status := range op.Responses {
+				statuses = append(statuses, status)
+			}
+			sort.Strings(statuses)
+
+			var named int
+			for _, status := range statuses {
+				media := op.Responses[status].Content["application/json"]
+				if !strings.HasPrefix(status, "2") || media == nil || media.Schema == nil || media.Schema.Ref != envelopeRef {
+					continue
+				}
+				name := strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:] + "Response"
+				if named > 0 {
+					name += status
+				}
+				named++
+				if _, taken := registry.Map()[name]; taken {
+					panic(fmt.Errorf("response schema %s of %s %s is already registered", name, op.Method, op.Path))
+				}
+
+				schema := *envelope
+				registry.Map()[name] = &schema
+				media.Schema = &huma.Schema{Ref: prefix + name}
+			}
+		}
+	}
+}
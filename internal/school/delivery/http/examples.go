@@ -0,0 +1,19 @@
+package http
+
+import (
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/school"
+)
+
+// Documented response examples
+var exampleSchoolList = school.SchoolListData{
+	Schools: []school.School{{
+		ID:        response.ExampleID(2),
+		Name:      "SMK Negeri 1 Surabaya",
+		Address:   "Jl. SMEA No. 4, Surabaya",
+		Domain:    "smkn1sby.sch.id",
+		CreatedAt: response.ExampleTime,
+		UpdatedAt: response.ExampleTime,
+	}},
+	Pagination: school.PaginationResult{Page: 1, Limit: 10, Total: 1, TotalPages: 1},
+}
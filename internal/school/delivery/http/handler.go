@@ -1,11 +1,16 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"net/http"
 
-	"backend-service-internpro/internal/pkg/jwt"
-	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/csvimport"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
 	"backend-service-internpro/internal/school"
 	"backend-service-internpro/internal/school/service"
 
@@ -14,51 +19,53 @@ import (
 )
 
 type Handler struct {
-	svc        service.SchoolService
-	jwtSecrets jwt.Secrets
+	svc service.SchoolService
 }
 
 // New registers school management routes into the Huma API.
-func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
+func New(api huma.API, svc service.SchoolService) {
 	h := &Handler{
-		svc:        svc,
-		jwtSecrets: jwtSecrets,
+		svc: svc,
 	}
 
 	// School routes
 	schoolGroup := huma.NewGroup(api, "/v1/schools")
 
 	// GET /schools - List all schools
-	huma.Register(schoolGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "",
-		Summary: "Get list of schools with pagination",
-		Tags:    []string{"School Management"},
+	routeperm.Register(schoolGroup, huma.Operation{
+		OperationID: "listSchools",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of schools with pagination",
+		Tags:        []string{"School Management"},
+		Responses:   response.Example(constants.SchoolListSuccess, exampleSchoolList),
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		Page          int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
-		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
-		Search        string `query:"search" doc:"Search by name, domain, or address"`
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		Page   int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit  int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Search string `query:"search" doc:"Search by name, domain, or address"`
+		Fields string `query:"fields" doc:"Comma separated fields to return for each item, e.g. id,name"`
 	}) (*struct {
 		Body school.PaginatedSchoolsResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
-		}
-
 		params := school.QueryParams{
 			Page:   in.Page,
 			Limit:  in.Limit,
 			Search: in.Search,
 		}
 
+		fields, err := response.ParseFields[school.School](in.Fields)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
 		result, err := h.svc.GetAllSchools(ctx, params)
 		if err != nil {
 			return nil, huma.Error500InternalServerError(err.Error())
 		}
+		result.Data = response.SelectFields(result.Data, fields)
 
 		return &struct {
 			Body school.PaginatedSchoolsResponse
@@ -66,26 +73,25 @@ func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
 	})
 
 	// POST /schools - Create school
-	huma.Register(schoolGroup, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "",
-		Summary: "Create a new school",
-		Tags:    []string{"School Management"},
+	routeperm.Register(schoolGroup, huma.Operation{
+		OperationID: "createSchool",
+		Method:      http.MethodPost,
+		Path:        "",
+		Summary:     "Create a new school",
+		Tags:        []string{"School Management"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string                     `header:"Authorization" required:"true" doc:"Bearer token"`
-		Body          school.CreateSchoolRequest `json:"body"`
+	}, routeperm.Require("schools", "create"), func(ctx context.Context, in *struct {
+		Body school.CreateSchoolRequest `json:"body"`
 	}) (*struct {
 		Body school.School
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
-		}
-
 		result, err := h.svc.CreateSchool(ctx, in.Body)
 		if err != nil {
+			if appErr, ok := apperrors.IsAppError(err); ok {
+				return nil, appErr.ToHumaError()
+			}
 			return nil, huma.Error500InternalServerError(err.Error())
 		}
 
@@ -101,75 +107,67 @@ func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
 	})
 
 	// GET /schools/{id} - Get school by ID
-	huma.Register(schoolGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/{id}",
-		Summary: "Get school by ID",
-		Tags:    []string{"School Management"},
+	routeperm.Register(schoolGroup, huma.Operation{
+		OperationID: "getSchool",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get school by ID",
+		Tags:        []string{"School Management"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            string `path:"id" doc:"School ID"`
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		ID string `path:"id" doc:"School ID"`
 	}) (*struct {
+		ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
 		Body school.School
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
-		}
-
 		id, err := uuid.Parse(in.ID)
 		if err != nil {
 			return nil, huma.Error400BadRequest("Invalid school ID")
 		}
 
-		result, err := h.svc.GetSchoolByID(ctx, id)
+		schoolData, err := h.getSchool(ctx, id)
 		if err != nil {
-			if err.Error() == "school not found" {
-				return nil, huma.Error404NotFound("School not found")
-			}
-			return nil, huma.Error500InternalServerError(err.Error())
-		}
-
-		// Extract the data from the response
-		schoolData, ok := result.Data.(school.School)
-		if !ok {
-			return nil, huma.Error500InternalServerError("Invalid response data type")
+			return nil, err
 		}
 
 		return &struct {
+			ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
 			Body school.School
-		}{Body: schoolData}, nil
+		}{ETag: response.ETag(schoolData.Version), Body: schoolData}, nil
 	})
 
 	// PUT /schools/{id} - Update school
-	huma.Register(schoolGroup, huma.Operation{
-		Method:  http.MethodPut,
-		Path:    "/{id}",
-		Summary: "Update school",
-		Tags:    []string{"School Management"},
+	routeperm.Register(schoolGroup, huma.Operation{
+		OperationID: "updateSchool",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update school",
+		Tags:        []string{"School Management"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string                     `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            string                     `path:"id" doc:"School ID"`
-		Body          school.UpdateSchoolRequest `json:"body"`
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID      string                     `path:"id" doc:"School ID"`
+		IfMatch string                     `header:"If-Match" doc:"ETag from the last read; the update fails with 412 if the school changed since"`
+		Body    school.UpdateSchoolRequest `json:"body"`
 	}) (*struct {
 		Body school.School
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
-		}
-
 		id, err := uuid.Parse(in.ID)
 		if err != nil {
 			return nil, huma.Error400BadRequest("Invalid school ID")
 		}
 
-		result, err := h.svc.UpdateSchool(ctx, id, in.Body)
+		result, err := h.svc.UpdateSchool(ctx, id, in.Body, response.MatchVersions(in.IfMatch))
 		if err != nil {
+			if errors.Is(err, service.ErrSchoolModified) {
+				return nil, preconditionError(in.IfMatch)
+			}
+			if appErr, ok := apperrors.IsAppError(err); ok {
+				return nil, appErr.ToHumaError()
+			}
 			if err.Error() == "school not found" {
 				return nil, huma.Error404NotFound("School not found")
 			}
@@ -188,24 +186,20 @@ func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
 	})
 
 	// DELETE /schools/{id} - Delete school
-	huma.Register(schoolGroup, huma.Operation{
-		Method:  http.MethodDelete,
-		Path:    "/{id}",
-		Summary: "Delete school",
-		Tags:    []string{"School Management"},
+	routeperm.Register(schoolGroup, huma.Operation{
+		OperationID: "deleteSchool",
+		Method:      http.MethodDelete,
+		Path:        "/{id}",
+		Summary:     "Delete school",
+		Tags:        []string{"School Management"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            string `path:"id" doc:"School ID"`
+	}, routeperm.Require("schools", "delete"), func(ctx context.Context, in *struct {
+		ID string `path:"id" doc:"School ID"`
 	}) (*struct {
-		Body map[string]string
+		Body school.DeleteResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
-		}
-
 		id, err := uuid.Parse(in.ID)
 		if err != nil {
 			return nil, huma.Error400BadRequest("Invalid school ID")
@@ -220,35 +214,88 @@ func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
 		}
 
 		return &struct {
-			Body map[string]string
-		}{Body: map[string]string{"message": result.Message}}, nil
+			Body school.DeleteResponse
+		}{Body: school.DeleteResponse{Message: result.Message}}, nil
+	})
+
+	// POST /schools/{id}/classes/rollover - Copy classes into a new academic year
+	routeperm.Register(schoolGroup, huma.Operation{
+		OperationID: "rolloverSchoolClasses",
+		Method:      http.MethodPost,
+		Path:        "/{id}/classes/rollover",
+		Summary:     "Copy classes into a new academic year",
+		Description: "Copies every class of the school from one academic year to another, by default promoting each class one grade (X RPL 1 becomes XI RPL 1) and dropping the final grade. Classes the target year already has are skipped. Set dry_run to preview the report without creating anything.",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID                   `path:"id" doc:"School ID"`
+		Body school.ClassRolloverRequest `json:"body"`
+	}) (*struct {
+		Body school.ClassRolloverResponse
+	}, error) {
+		result, err := h.svc.RolloverClasses(ctx, in.ID, in.Body)
+		if err != nil {
+			return nil, rolloverError(err)
+		}
+
+		return &struct {
+			Body school.ClassRolloverResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /schools/{id}/majorities/import - Create majorities from a CSV file
+	routeperm.Register(schoolGroup, huma.Operation{
+		OperationID: "importSchoolMajorities",
+		Method:      http.MethodPost,
+		Path:        "/{id}/majorities/import",
+		Summary:     "Import majorities from a CSV file",
+		Description: "Creates a majority for each row of a CSV file with a name and an optional description column. The file may be UTF-8, with or without a byte order mark, or Windows-1252 as saved by Excel. Files that are too large, have too many rows, mix encodings or lack the name column are rejected before anything is written. Names the school already has are skipped; the others are created together or not at all.",
+		Tags:        []string{"School Management"},
+		// One byte over the import limit, so csvimport reports the size
+		MaxBodyBytes: csvimport.DefaultLimits.MaxBytes + 1,
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID      uuid.UUID `path:"id" doc:"School ID"`
+		RawBody []byte    `contentType:"text/csv"`
+	}) (*struct {
+		Body school.MajorityImportResponse
+	}, error) {
+		result, err := h.svc.ImportMajorities(ctx, in.ID, bytes.NewReader(in.RawBody))
+		if err != nil {
+			return nil, importError(err)
+		}
+
+		return &struct {
+			Body school.MajorityImportResponse
+		}{Body: *result}, nil
 	})
 
 	// Majority routes
 	majorityGroup := huma.NewGroup(api, "/v1/majorities")
 
 	// GET /majorities - List all majorities
-	huma.Register(majorityGroup, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "",
-		Summary: "Get list of majorities with pagination",
-		Tags:    []string{"School Management"},
+	routeperm.Register(majorityGroup, huma.Operation{
+		OperationID: "listMajorities",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of majorities with pagination",
+		Tags:        []string{"School Management"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		Page          int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
-		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
-		Search        string `query:"search" doc:"Search by name or description"`
-		SchoolID      string `query:"school_id" doc:"Filter by school ID"`
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		Page     int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit    int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Search   string `query:"search" doc:"Search by name or description"`
+		SchoolID string `query:"school_id" doc:"Filter by school ID"`
+		Fields   string `query:"fields" doc:"Comma separated fields to return for each item, e.g. id,name"`
 	}) (*struct {
 		Body school.PaginatedMajoritiesResponse
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
-		}
-
 		params := school.QueryParams{
 			Page:     in.Page,
 			Limit:    in.Limit,
@@ -256,10 +303,16 @@ func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
 			SchoolID: in.SchoolID,
 		}
 
+		fields, err := response.ParseFields[school.Majority](in.Fields)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
 		result, err := h.svc.GetAllMajorities(ctx, params)
 		if err != nil {
 			return nil, huma.Error500InternalServerError(err.Error())
 		}
+		result.Data = response.SelectFields(result.Data, fields)
 
 		return &struct {
 			Body school.PaginatedMajoritiesResponse
@@ -267,24 +320,20 @@ func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
 	})
 
 	// POST /majorities - Create majority
-	huma.Register(majorityGroup, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "",
-		Summary: "Create a new majority",
-		Tags:    []string{"School Management"},
+	routeperm.Register(majorityGroup, huma.Operation{
+		OperationID: "createMajority",
+		Method:      http.MethodPost,
+		Path:        "",
+		Summary:     "Create a new majority",
+		Tags:        []string{"School Management"},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string                       `header:"Authorization" required:"true" doc:"Bearer token"`
-		Body          school.CreateMajorityRequest `json:"body"`
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		Body school.CreateMajorityRequest `json:"body"`
 	}) (*struct {
 		Body school.Majority
 	}, error) {
-		if err := h.validateToken(in.Authorization); err != nil {
-			return nil, err
-		}
-
 		result, err := h.svc.CreateMajority(ctx, in.Body)
 		if err != nil {
 			return nil, huma.Error500InternalServerError(err.Error())
@@ -301,11 +350,619 @@ func New(api huma.API, svc service.SchoolService, jwtSecrets jwt.Secrets) {
 		}{Body: majorityData}, nil
 	})
 
+	// GET /majorities/{id} - Get majority by ID
+	routeperm.Register(majorityGroup, huma.Operation{
+		OperationID: "getMajority",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get majority by ID",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Majority ID"`
+	}) (*struct {
+		ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
+		Body school.Majority
+	}, error) {
+		result, err := h.svc.GetMajorityByID(ctx, in.ID)
+		if err != nil {
+			return nil, detailError(err)
+		}
+
+		data, ok := result.Data.(school.Majority)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+
+		return &struct {
+			ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
+			Body school.Majority
+		}{ETag: response.ETag(data.Version), Body: data}, nil
+	})
+
+	// PUT /majorities/{id} - Update majority
+	routeperm.Register(majorityGroup, huma.Operation{
+		OperationID: "updateMajority",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update majority",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID      uuid.UUID                    `path:"id" doc:"Majority ID"`
+		IfMatch string                       `header:"If-Match" doc:"ETag from the last read; the update fails with 412 if the majority changed since"`
+		Body    school.UpdateMajorityRequest `json:"body"`
+	}) (*struct {
+		Body school.Majority
+	}, error) {
+		result, err := h.svc.UpdateMajority(ctx, in.ID, in.Body, response.MatchVersions(in.IfMatch))
+		if err != nil {
+			if errors.Is(err, service.ErrMajorityModified) {
+				return nil, preconditionError(in.IfMatch)
+			}
+			return nil, detailError(err)
+		}
+
+		data, ok := result.Data.(school.Majority)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+
+		return &struct {
+			Body school.Majority
+		}{Body: data}, nil
+	})
+
+	// Class routes
+	classGroup := huma.NewGroup(api, "/v1/classes")
+
+	// GET /classes/{id} - Get class by ID
+	routeperm.Register(classGroup, huma.Operation{
+		OperationID: "getClass",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get class by ID",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Class ID"`
+	}) (*struct {
+		ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
+		Body school.Class
+	}, error) {
+		result, err := h.svc.GetClassByID(ctx, in.ID)
+		if err != nil {
+			return nil, detailError(err)
+		}
+
+		data, ok := result.Data.(school.Class)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+
+		return &struct {
+			ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
+			Body school.Class
+		}{ETag: response.ETag(data.Version), Body: data}, nil
+	})
+
+	// PUT /classes/{id} - Update class
+	routeperm.Register(classGroup, huma.Operation{
+		OperationID: "updateClass",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update class",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID      uuid.UUID                 `path:"id" doc:"Class ID"`
+		IfMatch string                    `header:"If-Match" doc:"ETag from the last read; the update fails with 412 if the class changed since"`
+		Body    school.UpdateClassRequest `json:"body"`
+	}) (*struct {
+		Body school.Class
+	}, error) {
+		result, err := h.svc.UpdateClass(ctx, in.ID, in.Body, response.MatchVersions(in.IfMatch))
+		if err != nil {
+			if errors.Is(err, service.ErrClassModified) {
+				return nil, preconditionError(in.IfMatch)
+			}
+			return nil, detailError(err)
+		}
+
+		data, ok := result.Data.(school.Class)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+
+		return &struct {
+			Body school.Class
+		}{Body: data}, nil
+	})
+
+	// GET /classes/{id}/schedule - Weekly schedule of a class
+	routeperm.Register(classGroup, huma.Operation{
+		OperationID: "listClassSchedule",
+		Method:      http.MethodGet,
+		Path:        "/{id}/schedule",
+		Summary:     "Get weekly schedule of a class",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Class ID"`
+	}) (*struct {
+		Body school.ClassScheduleListResponse
+	}, error) {
+		result, err := h.svc.GetClassSchedule(ctx, in.ID)
+		if err != nil {
+			return nil, scheduleError(err)
+		}
+
+		return &struct {
+			Body school.ClassScheduleListResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /classes/{id}/schedule - Add a period to a class schedule
+	routeperm.Register(classGroup, huma.Operation{
+		OperationID: "createClassSchedule",
+		Method:      http.MethodPost,
+		Path:        "/{id}/schedule",
+		Summary:     "Add a period to a class schedule",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID                   `path:"id" doc:"Class ID"`
+		Body school.ClassScheduleRequest `json:"body"`
+	}) (*struct {
+		Body school.ClassScheduleResponse
+	}, error) {
+		result, err := h.svc.CreateClassSchedule(ctx, in.ID, in.Body)
+		if err != nil {
+			return nil, scheduleError(err)
+		}
+
+		return &struct {
+			Body school.ClassScheduleResponse
+		}{Body: *result}, nil
+	})
+
+	// PUT /classes/{id}/schedule/{schedule_id} - Replace a period
+	routeperm.Register(classGroup, huma.Operation{
+		OperationID: "updateClassSchedule",
+		Method:      http.MethodPut,
+		Path:        "/{id}/schedule/{schedule_id}",
+		Summary:     "Update a period of a class schedule",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID         uuid.UUID                   `path:"id" doc:"Class ID"`
+		ScheduleID uuid.UUID                   `path:"schedule_id" doc:"Schedule ID"`
+		Body       school.ClassScheduleRequest `json:"body"`
+	}) (*struct {
+		Body school.ClassScheduleResponse
+	}, error) {
+		result, err := h.svc.UpdateClassSchedule(ctx, in.ID, in.ScheduleID, in.Body)
+		if err != nil {
+			return nil, scheduleError(err)
+		}
+
+		return &struct {
+			Body school.ClassScheduleResponse
+		}{Body: *result}, nil
+	})
+
+	// DELETE /classes/{id}/schedule/{schedule_id} - Remove a period
+	routeperm.Register(classGroup, huma.Operation{
+		OperationID: "deleteClassSchedule",
+		Method:      http.MethodDelete,
+		Path:        "/{id}/schedule/{schedule_id}",
+		Summary:     "Delete a period of a class schedule",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID         uuid.UUID `path:"id" doc:"Class ID"`
+		ScheduleID uuid.UUID `path:"schedule_id" doc:"Schedule ID"`
+	}) (*struct {
+		Body school.DeleteResponse
+	}, error) {
+		result, err := h.svc.DeleteClassSchedule(ctx, in.ID, in.ScheduleID)
+		if err != nil {
+			return nil, scheduleError(err)
+		}
+
+		return &struct {
+			Body school.DeleteResponse
+		}{Body: school.DeleteResponse{Message: result.Message}}, nil
+	})
+
+	// GET /teachers/{id}/schedule - Weekly schedule of a teacher across classes
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getTeacherSchedule",
+		Method:      http.MethodGet,
+		Path:        "/v1/teachers/{id}/schedule",
+		Summary:     "Get weekly schedule of a teacher",
+		Tags:        []string{"Teacher Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Teacher user ID"`
+	}) (*struct {
+		Body school.ClassScheduleListResponse
+	}, error) {
+		result, err := h.svc.GetTeacherSchedule(ctx, in.ID)
+		if err != nil {
+			return nil, scheduleError(err)
+		}
+
+		return &struct {
+			Body school.ClassScheduleListResponse
+		}{Body: *result}, nil
+	})
+
+	// Partner routes
+	partnerGroup := huma.NewGroup(api, "/v1/partners")
+
+	// GET /partners/{id} - Get partner by ID
+	routeperm.Register(partnerGroup, huma.Operation{
+		OperationID: "getPartner",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get partner by ID",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Partner ID"`
+	}) (*struct {
+		ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
+		Body school.Partner
+	}, error) {
+		result, err := h.svc.GetPartnerByID(ctx, in.ID)
+		if err != nil {
+			return nil, detailError(err)
+		}
+
+		data, ok := result.Data.(school.Partner)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+
+		return &struct {
+			ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
+			Body school.Partner
+		}{ETag: response.ETag(data.Version), Body: data}, nil
+	})
+
+	// PUT /partners/{id} - Update partner
+	routeperm.Register(partnerGroup, huma.Operation{
+		OperationID: "updatePartner",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update partner",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID      uuid.UUID                   `path:"id" doc:"Partner ID"`
+		IfMatch string                      `header:"If-Match" doc:"ETag from the last read; the update fails with 412 if the partner changed since"`
+		Body    school.UpdatePartnerRequest `json:"body"`
+	}) (*struct {
+		Body school.Partner
+	}, error) {
+		result, err := h.svc.UpdatePartner(ctx, in.ID, in.Body, response.MatchVersions(in.IfMatch))
+		if err != nil {
+			if errors.Is(err, service.ErrPartnerModified) {
+				return nil, preconditionError(in.IfMatch)
+			}
+			return nil, detailError(err)
+		}
+
+		data, ok := result.Data.(school.Partner)
+		if !ok {
+			return nil, huma.Error500InternalServerError("Invalid response data type")
+		}
+
+		return &struct {
+			Body school.Partner
+		}{Body: data}, nil
+	})
+
+	// GET /partners - List partners
+	routeperm.Register(partnerGroup, huma.Operation{
+		OperationID: "listPartners",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of partners with pagination",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		Page     int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit    int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Search   string `query:"search" doc:"Search by name, description, contact name or contact email"`
+		SchoolID string `query:"school_id" doc:"Filter by school ID"`
+		Verified string `query:"verified" enum:"true,false" doc:"Filter by whether the contact email is verified"`
+		Fields   string `query:"fields" doc:"Comma separated fields to return for each item, e.g. id,name"`
+	}) (*struct {
+		Body school.PaginatedPartnersResponse
+	}, error) {
+		params := school.QueryParams{
+			Page:     in.Page,
+			Limit:    in.Limit,
+			Search:   in.Search,
+			SchoolID: in.SchoolID,
+		}
+		if in.Verified != "" {
+			verified := in.Verified == "true"
+			params.Verified = &verified
+		}
+
+		fields, err := response.ParseFields[school.Partner](in.Fields)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		result, err := h.svc.GetAllPartners(ctx, params)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+		result.Data = response.SelectFields(result.Data, fields)
+
+		return &struct {
+			Body school.PaginatedPartnersResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /partners/{id}/verify-contact - Email a verification link to the partner contact
+	routeperm.Register(partnerGroup, huma.Operation{
+		OperationID: "sendPartnerContactVerification",
+		Method:      http.MethodPost,
+		Path:        "/{id}/verify-contact",
+		Summary:     "Send a verification email to the partner contact",
+		Description: "Emails a signed link to the partner's contact email. Opening it marks the address as verified.",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		ID uuid.UUID `path:"id" doc:"Partner ID"`
+	}) (*struct {
+		Body school.BasicResponse
+	}, error) {
+		result, err := h.svc.SendContactVerification(ctx, in.ID)
+		if err != nil {
+			return nil, contactError(err)
+		}
+
+		return &struct {
+			Body school.BasicResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /partners/duplicates - Find partners that are likely the same company
+	routeperm.Register(partnerGroup, huma.Operation{
+		OperationID: "listPartnerDuplicates",
+		Method:      http.MethodGet,
+		Path:        "/duplicates",
+		Summary:     "Find likely duplicate partners",
+		Description: "Groups partners of the same school whose names match after lowercasing, stripping punctuation and legal forms such as PT or CV, and allowing small typos.",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "view"), func(ctx context.Context, in *struct {
+		SchoolID  string  `query:"school_id" doc:"Only check partners of this school"`
+		Threshold float64 `query:"threshold" minimum:"0.5" maximum:"1" default:"0.85" doc:"Minimum name similarity, 1 only matches identical normalized names"`
+	}) (*struct {
+		Body school.PartnerDuplicatesResponse
+	}, error) {
+		var schoolID *uuid.UUID
+		if in.SchoolID != "" {
+			id, err := uuid.Parse(in.SchoolID)
+			if err != nil {
+				return nil, huma.Error400BadRequest("Invalid school ID")
+			}
+			schoolID = &id
+		}
+
+		result, err := h.svc.FindDuplicatePartners(ctx, schoolID, in.Threshold)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body school.PartnerDuplicatesResponse
+		}{Body: *result}, nil
+	})
+
+	// POST /partners/merge - Merge duplicate partners into one
+	routeperm.Register(partnerGroup, huma.Operation{
+		OperationID: "mergePartners",
+		Method:      http.MethodPost,
+		Path:        "/merge",
+		Summary:     "Merge duplicate partners",
+		Description: "Fills the survivor's empty fields from the duplicates, moves their users to the survivor and deletes the duplicates in one transaction. Every merge is recorded with the fields it filled.",
+		Tags:        []string{"School Management"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("schools", "edit"), func(ctx context.Context, in *struct {
+		Body school.MergePartnersRequest
+	}) (*struct {
+		Body school.MergePartnersResponse
+	}, error) {
+		result, err := h.svc.MergePartners(ctx, in.Body)
+		if err != nil {
+			return nil, mergeError(err)
+		}
+
+		return &struct {
+			Body school.MergePartnersResponse
+		}{Body: *result}, nil
+	})
+
+	// GET /verify/contact - Confirm a partner contact email through the emailed link
+	routeperm.Register(api, huma.Operation{
+		OperationID: "verifyPartnerContact",
+		Method:      http.MethodGet,
+		Path:        "/v1/verify/contact",
+		Summary:     "Verify a partner contact email",
+		Description: "Target of the link sent by the verify-contact endpoint; the signed token is the credential.",
+		Tags:        []string{"School Management"},
+	}, routeperm.Public, func(ctx context.Context, in *struct {
+		Token string `query:"token" required:"true" doc:"Token from the verification email"`
+	}) (*struct {
+		Body school.BasicResponse
+	}, error) {
+		result, err := h.svc.VerifyContact(ctx, in.Token)
+		if err != nil {
+			return nil, contactError(err)
+		}
+
+		return &struct {
+			Body school.BasicResponse
+		}{Body: *result}, nil
+	})
+
 	// Continue with other endpoints...
 }
 
-// validateToken validates JWT token from Authorization header
-func (h *Handler) validateToken(authHeader string) error {
-	_, err := middleware.ValidateToken(authHeader, h.jwtSecrets)
-	return err
+// getSchool loads a school, mapping service errors to HTTP errors
+func (h *Handler) getSchool(ctx context.Context, id uuid.UUID) (school.School, error) {
+	result, err := h.svc.GetSchoolByID(ctx, id)
+	if err != nil {
+		if err.Error() == "school not found" {
+			return school.School{}, huma.Error404NotFound("School not found")
+		}
+		return school.School{}, huma.Error500InternalServerError(err.Error())
+	}
+
+	// Extract the data from the response
+	schoolData, ok := result.Data.(school.School)
+	if !ok {
+		return school.School{}, huma.Error500InternalServerError("Invalid response data type")
+	}
+	return schoolData, nil
+}
+
+// preconditionError answers an update made against a replaced version:
+// 412 when the client sent If-Match, 409 when it lost a race without one
+func preconditionError(ifMatch string) error {
+	if ifMatch != "" {
+		return huma.Error412PreconditionFailed(constants.PreconditionFailed)
+	}
+	return huma.Error409Conflict(constants.ConcurrentUpdate)
+}
+
+// detailError maps majority, class and partner service errors to HTTP errors
+func detailError(err error) error {
+	if appErr, ok := apperrors.IsAppError(err); ok {
+		return appErr.ToHumaError()
+	}
+
+	switch err.Error() {
+	case "school not found":
+		return huma.Error404NotFound(constants.SchoolNotFound)
+	case "majority not found":
+		return huma.Error404NotFound(constants.MajorityNotFound)
+	case "class not found":
+		return huma.Error404NotFound(constants.ClassNotFound)
+	case "partner not found":
+		return huma.Error404NotFound(constants.PartnerNotFound)
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// scheduleError maps class schedule service errors to HTTP errors
+func scheduleError(err error) error {
+	if appErr, ok := apperrors.IsAppError(err); ok {
+		return appErr.ToHumaError()
+	}
+
+	switch err.Error() {
+	case "class not found":
+		return huma.Error404NotFound("Class not found")
+	case "schedule not found":
+		return huma.Error404NotFound("Schedule not found")
+	case "schedule overlaps with another period of this class", "teacher is already scheduled at this time":
+		return huma.Error409Conflict(err.Error())
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// rolloverError maps class rollover errors to HTTP errors
+func rolloverError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrSchoolNotFound):
+		return huma.Error404NotFound(constants.SchoolNotFound)
+	case errors.Is(err, service.ErrRolloverSameYear):
+		return huma.Error422UnprocessableEntity(constants.ClassRolloverSameYear)
+	case errors.Is(err, service.ErrRolloverNoClasses):
+		return huma.Error422UnprocessableEntity(constants.ClassRolloverEmptySource)
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// importError maps import errors to HTTP errors; the messages of rejected
+// files say what to fix
+func importError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrSchoolNotFound):
+		return huma.Error404NotFound(constants.SchoolNotFound)
+	case errors.Is(err, csvimport.ErrTooLarge):
+		return huma.NewError(http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, csvimport.ErrTooManyRows), errors.Is(err, csvimport.ErrMixedEncoding),
+		errors.Is(err, csvimport.ErrEncoding), errors.Is(err, csvimport.ErrHeader),
+		errors.Is(err, csvimport.ErrMalformed), errors.Is(err, service.ErrImportRow):
+		return huma.Error422UnprocessableEntity(err.Error())
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// mergeError maps partner merge errors to HTTP errors
+func mergeError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrPartnerNotFound):
+		return huma.Error404NotFound(constants.PartnerNotFound)
+	case errors.Is(err, service.ErrMergeSurvivorListed):
+		return huma.Error422UnprocessableEntity(constants.PartnerMergeSurvivorListed)
+	case errors.Is(err, service.ErrMergeAcrossSchools):
+		return huma.Error422UnprocessableEntity(constants.PartnerMergeSchoolMismatch)
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// contactError maps partner contact verification errors to HTTP errors
+func contactError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrPartnerNotFound):
+		return huma.Error404NotFound(constants.PartnerNotFound)
+	case errors.Is(err, service.ErrNoContactEmail):
+		return huma.Error422UnprocessableEntity(constants.PartnerContactEmailMissing)
+	case errors.Is(err, service.ErrContactLinkInvalid):
+		return huma.Error400BadRequest(constants.PartnerContactLinkInvalid)
+	case errors.Is(err, service.ErrContactMailUnavailable):
+		return huma.Error503ServiceUnavailable(constants.PartnerContactMailUnavailable)
+	}
+	return huma.Error500InternalServerError(err.Error())
 }
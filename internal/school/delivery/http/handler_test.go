@@ -0,0 +1,203 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/school"
+	"backend-service-internpro/internal/school/repository"
+	"backend-service-internpro/internal/school/service"
+
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// uniqueDomains stores schools behind a unique index on domain. Its domain
+// lookups wait until every expected request has looked, so concurrent
+// creates all pass the pre-check and race on the insert; other methods are
+// not used
+type uniqueDomains struct {
+	repository.SchoolRepository
+	looked  sync.WaitGroup
+	mu      sync.Mutex
+	domains map[string]bool
+}
+
+func (r *uniqueDomains) GetByDomain(_ context.Context, domain string) (*school.SchoolEntity, error) {
+	r.looked.Done()
+	r.looked.Wait()
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *uniqueDomains) Create(_ context.Context, entity *school.SchoolEntity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entity.Domain == nil {
+		return nil
+	}
+	if r.domains[*entity.Domain] {
+		return gorm.ErrDuplicatedKey
+	}
+	r.domains[*entity.Domain] = true
+	return nil
+}
+
+func TestConcurrentCreatesClaimDomainOnce(t *testing.T) {
+	const requests = 2
+	repo := &uniqueDomains{domains: map[string]bool{}}
+	repo.looked.Add(requests)
+	_, api := humatest.New(t)
+	New(api, service.NewSchoolService(repo))
+
+	statuses := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := api.Post("/v1/schools", map[string]any{"name": "SMK Negeri 1 Surabaya", "domain": "smkn1sby.sch.id"})
+			statuses <- resp.Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for code := range statuses {
+		counts[code]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != 1 {
+		t.Errorf("statuses = %v, want one %d and one %d", counts, http.StatusOK, http.StatusConflict)
+	}
+}
+
+// versionedSchool stores one school and bumps its version on every update;
+// other methods are not used
+type versionedSchool struct {
+	repository.SchoolRepository
+	school school.SchoolEntity
+}
+
+func (r *versionedSchool) GetByID(_ context.Context, id uuid.UUID) (*school.SchoolEntity, error) {
+	if id != r.school.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := r.school
+	return &copied, nil
+}
+
+func (r *versionedSchool) Update(_ context.Context, entity *school.SchoolEntity) error {
+	if entity.Version != r.school.Version {
+		return repository.ErrStale
+	}
+	entity.Version++
+	r.school = *entity
+	return nil
+}
+
+func TestUpdatePreconditions(t *testing.T) {
+	repo := &versionedSchool{school: school.SchoolEntity{ID: uuid.New(), Name: "SMK Negeri 1 Surabaya", Version: 3, CreatedAt: time.Now()}}
+	_, api := humatest.New(t)
+	New(api, service.NewSchoolService(repo))
+	path := "/v1/schools/" + repo.school.ID.String()
+
+	if resp := api.Get(path); resp.Header().Get("ETag") != response.ETag(3) {
+		t.Fatalf("ETag = %q, want %q", resp.Header().Get("ETag"), response.ETag(3))
+	}
+
+	// Each step runs on the state the previous one left
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+		version int64
+	}{
+		{"fresh tag", response.ETag(3), http.StatusOK, 4},
+		{"stale tag", response.ETag(3), http.StatusPreconditionFailed, 4},
+		{"tag not issued here", `"sxq1c0"`, http.StatusPreconditionFailed, 4},
+		{"no precondition", "", http.StatusOK, 5},
+		{"any version", "*", http.StatusOK, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []any{map[string]any{"name": "SMK Negeri 1 Sidoarjo"}}
+			if tt.ifMatch != "" {
+				args = append([]any{"If-Match: " + tt.ifMatch}, args...)
+			}
+			resp := api.Put(path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body)
+			}
+			if repo.school.Version != tt.version {
+				t.Errorf("version = %d, want %d", repo.school.Version, tt.version)
+			}
+		})
+	}
+}
+
+// versionedPartner stores one partner and bumps its version on every update;
+// other methods are not used
+type versionedPartner struct {
+	repository.SchoolRepository
+	partner school.PartnerEntity
+}
+
+func (r *versionedPartner) GetPartnerByID(_ context.Context, id uuid.UUID) (*school.PartnerEntity, error) {
+	if id != r.partner.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := r.partner
+	return &copied, nil
+}
+
+func (r *versionedPartner) UpdatePartner(_ context.Context, entity *school.PartnerEntity) error {
+	if entity.Version != r.partner.Version {
+		return repository.ErrStale
+	}
+	entity.Version++
+	r.partner = *entity
+	return nil
+}
+
+func TestUpdatePartnerPreconditions(t *testing.T) {
+	repo := &versionedPartner{partner: school.PartnerEntity{ID: uuid.New(), Name: "PT Telkom Indonesia", Version: 2, CreatedAt: time.Now()}}
+	_, api := humatest.New(t)
+	New(api, service.NewSchoolService(repo))
+	path := "/v1/partners/" + repo.partner.ID.String()
+
+	if resp := api.Get(path); resp.Header().Get("ETag") != response.ETag(2) {
+		t.Fatalf("ETag = %q, want %q", resp.Header().Get("ETag"), response.ETag(2))
+	}
+
+	// Each step runs on the state the previous one left
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+		version int64
+	}{
+		{"fresh tag", response.ETag(2), http.StatusOK, 3},
+		{"stale tag", response.ETag(2), http.StatusPreconditionFailed, 3},
+		{"no precondition", "", http.StatusOK, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []any{map[string]any{"name": "PT Telkom Indonesia Tbk"}}
+			if tt.ifMatch != "" {
+				args = append([]any{"If-Match: " + tt.ifMatch}, args...)
+			}
+			resp := api.Put(path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body)
+			}
+			if repo.partner.Version != tt.version {
+				t.Errorf("version = %d, want %d", repo.partner.Version, tt.version)
+			}
+		})
+	}
+}
@@ -9,19 +9,20 @@ import (
 
 // School represents the school data transfer object
 type School struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Address   string    `json:"address,omitempty"`
-	Domain    string    `json:"domain,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID `json:"id" example:"3f1c9a52-8d4e-4b7a-9c2e-1a5b6d7e8f02"`
+	Name      string    `json:"name" example:"SMK Negeri 1 Surabaya"`
+	Address   string    `json:"address,omitempty" example:"Jl. SMEA No. 4, Surabaya"`
+	Domain    string    `json:"domain,omitempty" example:"smkn1sby.sch.id"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-14T08:30:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2025-07-14T08:30:00Z"`
+	Version   int64     `json:"-"` // sent as the ETag
 }
 
 // CreateSchoolRequest represents the request to create a school
 type CreateSchoolRequest struct {
-	Name    string `json:"name" validate:"required,min=1,max=255"`
-	Address string `json:"address,omitempty" validate:"max=255"`
-	Domain  string `json:"domain,omitempty" validate:"max=255"`
+	Name    string `json:"name" validate:"required,min=1,max=255" example:"SMK Negeri 1 Surabaya"`
+	Address string `json:"address,omitempty" validate:"max=255" example:"Jl. SMEA No. 4, Surabaya"`
+	Domain  string `json:"domain,omitempty" validate:"max=255" example:"smkn1sby.sch.id"`
 }
 
 // UpdateSchoolRequest represents the request to update a school
@@ -40,6 +41,7 @@ type Majority struct {
 	School      *School   `json:"school,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int64     `json:"-"` // sent as the ETag
 }
 
 // CreateMajorityRequest represents the request to create a majority
@@ -58,47 +60,181 @@ type UpdateMajorityRequest struct {
 
 // Class represents the class data transfer object
 type Class struct {
-	ID          uuid.UUID `json:"id"`
-	SchoolID    uuid.UUID `json:"school_id"`
-	MajorityID  uuid.UUID `json:"majority_id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description,omitempty"`
-	School      *School   `json:"school,omitempty"`
-	Majority    *Majority `json:"majority,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uuid.UUID  `json:"id"`
+	SchoolID          uuid.UUID  `json:"school_id"`
+	MajorityID        uuid.UUID  `json:"majority_id"`
+	HomeroomTeacherID *uuid.UUID `json:"homeroom_teacher_id,omitempty"`
+	Name              string     `json:"name"`
+	AcademicYear      string     `json:"academic_year,omitempty"`
+	Description       string     `json:"description,omitempty"`
+	School            *School    `json:"school,omitempty"`
+	Majority          *Majority  `json:"majority,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	Version           int64      `json:"-"` // sent as the ETag
 }
 
 // CreateClassRequest represents the request to create a class
 type CreateClassRequest struct {
-	SchoolID    uuid.UUID `json:"school_id" validate:"required"`
-	MajorityID  uuid.UUID `json:"majority_id" validate:"required"`
-	Name        string    `json:"name" validate:"required,min=1,max=255"`
-	Description string    `json:"description,omitempty"`
+	SchoolID          uuid.UUID  `json:"school_id" validate:"required"`
+	MajorityID        uuid.UUID  `json:"majority_id" validate:"required"`
+	HomeroomTeacherID *uuid.UUID `json:"homeroom_teacher_id,omitempty"`
+	Name              string     `json:"name" validate:"required,min=1,max=255"`
+	AcademicYear      string     `json:"academic_year,omitempty" pattern:"^[0-9]{4}/[0-9]{4}$"`
+	Description       string     `json:"description,omitempty"`
 }
 
 // UpdateClassRequest represents the request to update a class
 type UpdateClassRequest struct {
-	SchoolID    uuid.UUID `json:"school_id,omitempty"`
-	MajorityID  uuid.UUID `json:"majority_id,omitempty"`
-	Name        string    `json:"name,omitempty" validate:"min=1,max=255"`
-	Description string    `json:"description,omitempty"`
+	SchoolID          uuid.UUID  `json:"school_id,omitempty"`
+	MajorityID        uuid.UUID  `json:"majority_id,omitempty"`
+	HomeroomTeacherID *uuid.UUID `json:"homeroom_teacher_id,omitempty"`
+	Name              string     `json:"name,omitempty" validate:"min=1,max=255"`
+	AcademicYear      string     `json:"academic_year,omitempty" pattern:"^[0-9]{4}/[0-9]{4}$"`
+	Description       string     `json:"description,omitempty"`
+}
+
+// Class rollover naming modes
+const (
+	// RolloverPromote moves every class one grade up and drops the final grade
+	RolloverPromote = "promote"
+	// RolloverKeep copies the class names as they are
+	RolloverKeep = "keep"
+)
+
+// Class rollover outcomes of a source class
+const (
+	RolloverCreated      = "created"
+	RolloverPlanned      = "planned"
+	RolloverDuplicate    = "duplicate"
+	RolloverGraduated    = "graduated"
+	RolloverUnrecognized = "unrecognized"
+)
+
+// ClassRolloverRequest represents the request to copy the classes of an
+// academic year into the next one
+type ClassRolloverRequest struct {
+	FromYear            string `json:"from_year" pattern:"^[0-9]{4}/[0-9]{4}$" doc:"Academic year to copy from, e.g. 2024/2025"`
+	ToYear              string `json:"to_year" pattern:"^[0-9]{4}/[0-9]{4}$" doc:"Academic year to copy into, e.g. 2025/2026"`
+	Naming              string `json:"naming,omitempty" enum:"promote,keep" default:"promote" doc:"promote moves each class one grade up (X to XI) and drops the final grade; keep copies the names as they are"`
+	FinalGrade          int    `json:"final_grade,omitempty" minimum:"1" maximum:"12" doc:"Grade whose classes graduate and are not copied when promoting; defaults to the highest grade among the source classes"`
+	KeepHomeroomTeacher bool   `json:"keep_homeroom_teacher,omitempty" doc:"Copy the homeroom teacher of each class"`
+	DryRun              bool   `json:"dry_run,omitempty" doc:"Report what would be created without writing anything"`
+}
+
+// ClassRolloverItem is the outcome for one class of the source year
+type ClassRolloverItem struct {
+	SourceID   uuid.UUID  `json:"source_id" doc:"Class in the source year"`
+	SourceName string     `json:"source_name" doc:"Name of the source class"`
+	TargetName string     `json:"target_name,omitempty" doc:"Name of the class in the target year"`
+	TargetID   *uuid.UUID `json:"target_id,omitempty" doc:"Created class, or the existing class for duplicates"`
+	Status     string     `json:"status" enum:"created,planned,duplicate,graduated,unrecognized" doc:"created, or planned on a dry run; duplicate when the target year already has the class; graduated for the final grade; unrecognized when the name has no grade to promote"`
+}
+
+// ClassRolloverResult represents the report of a class rollover
+type ClassRolloverResult struct {
+	FromYear string              `json:"from_year"`
+	ToYear   string              `json:"to_year"`
+	DryRun   bool                `json:"dry_run"`
+	Created  int                 `json:"created" doc:"Classes created, or that would be created on a dry run"`
+	Skipped  int                 `json:"skipped" doc:"Classes not copied"`
+	Classes  []ClassRolloverItem `json:"classes"`
+}
+
+// Majority import outcomes of a row
+const (
+	ImportCreated   = "created"
+	ImportDuplicate = "duplicate"
+)
+
+// MajorityImportItem is the outcome for one row of an import file
+type MajorityImportItem struct {
+	Line   int       `json:"line" doc:"Line of the row in the file, the header being line 1"`
+	Name   string    `json:"name"`
+	ID     uuid.UUID `json:"id" doc:"Created majority, or the existing one for duplicates"`
+	Status string    `json:"status" enum:"created,duplicate" doc:"duplicate when the school, or an earlier row, already has the name"`
+}
+
+// MajorityImportResult represents the report of a majority import
+type MajorityImportResult struct {
+	Created    int                  `json:"created"`
+	Skipped    int                  `json:"skipped" doc:"Rows naming a majority the school already has"`
+	Majorities []MajorityImportItem `json:"majorities"`
+}
+
+// ClassSchedule represents a weekly period of a class
+type ClassSchedule struct {
+	ID        uuid.UUID  `json:"id"`
+	ClassID   uuid.UUID  `json:"class_id"`
+	DayOfWeek int        `json:"day_of_week" doc:"ISO day of week, 1 = Monday ... 7 = Sunday"`
+	StartTime string     `json:"start_time" doc:"Start time (HH:MM)"`
+	EndTime   string     `json:"end_time" doc:"End time (HH:MM)"`
+	Subject   string     `json:"subject"`
+	TeacherID *uuid.UUID `json:"teacher_id,omitempty"`
+	Room      string     `json:"room,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ClassScheduleRequest represents the request to create or replace a class period
+type ClassScheduleRequest struct {
+	DayOfWeek int        `json:"day_of_week" minimum:"1" maximum:"7" doc:"ISO day of week, 1 = Monday ... 7 = Sunday"`
+	StartTime string     `json:"start_time" pattern:"^([01][0-9]|2[0-3]):[0-5][0-9]$" doc:"Start time (HH:MM)"`
+	EndTime   string     `json:"end_time" pattern:"^([01][0-9]|2[0-3]):[0-5][0-9]$" doc:"End time (HH:MM)"`
+	Subject   string     `json:"subject" minLength:"1" maxLength:"255"`
+	TeacherID *uuid.UUID `json:"teacher_id,omitempty" doc:"User ID of the teacher"`
+	Room      string     `json:"room,omitempty" maxLength:"100"`
+}
+
+// ClassScheduleListData represents the data structure for a weekly schedule
+type ClassScheduleListData struct {
+	Schedules []ClassSchedule `json:"schedules"`
 }
 
 // Partner represents the partner data transfer object
 type Partner struct {
-	ID            uuid.UUID `json:"id"`
-	SchoolID      uuid.UUID `json:"school_id"`
-	Name          string    `json:"name"`
-	Website       string    `json:"website,omitempty"`
-	Description   string    `json:"description,omitempty"`
-	Address       string    `json:"address,omitempty"`
-	ContactName   string    `json:"contact_name,omitempty"`
-	ContactPerson string    `json:"contact_person,omitempty"`
-	ContactEmail  string    `json:"contact_email,omitempty"`
-	School        *School   `json:"school,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                     uuid.UUID  `json:"id"`
+	SchoolID               uuid.UUID  `json:"school_id"`
+	Name                   string     `json:"name"`
+	Website                string     `json:"website,omitempty"`
+	Description            string     `json:"description,omitempty"`
+	Address                string     `json:"address,omitempty"`
+	ContactName            string     `json:"contact_name,omitempty"`
+	ContactPerson          string     `json:"contact_person,omitempty"`
+	ContactEmail           string     `json:"contact_email,omitempty"`
+	ContactEmailVerifiedAt *time.Time `json:"contact_email_verified_at,omitempty" doc:"When the contact last confirmed their email"`
+	ContactEmailStale      bool       `json:"contact_email_stale" doc:"Whether the contact has gone unverified for over a year"`
+	School                 *School    `json:"school,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+	Version                int64      `json:"-"` // sent as the ETag
+}
+
+// PartnerDuplicateGroup is a set of partners of a school whose names are
+// likely the same organization
+type PartnerDuplicateGroup struct {
+	SchoolID       uuid.UUID `json:"school_id" doc:"School the partners belong to"`
+	NormalizedName string    `json:"normalized_name" doc:"Name of the first partner without case, punctuation and legal forms"`
+	Partners       []Partner `json:"partners" doc:"Partners in the group, oldest first"`
+}
+
+// PartnerDuplicatesData represents the likely duplicate partners
+type PartnerDuplicatesData struct {
+	Groups []PartnerDuplicateGroup `json:"groups"`
+}
+
+// MergePartnersRequest represents the request to merge duplicate partners
+type MergePartnersRequest struct {
+	SurvivorID   uuid.UUID   `json:"survivor_id" doc:"Partner that is kept"`
+	DuplicateIDs []uuid.UUID `json:"duplicate_ids" minItems:"1" doc:"Partners merged into the survivor and deleted; earlier entries win when they fill the same empty field"`
+}
+
+// MergePartnersResult represents the outcome of a partner merge
+type MergePartnersResult struct {
+	Partner      Partner     `json:"partner" doc:"Survivor after the merge"`
+	Merged       []uuid.UUID `json:"merged" doc:"Partners deleted by the merge"`
+	FilledFields []string    `json:"filled_fields" doc:"Survivor fields that were empty and taken from a duplicate"`
+	UsersMoved   int64       `json:"users_moved" doc:"Users moved from the duplicates to the survivor"`
 }
 
 // CreatePartnerRequest represents the request to create a partner
@@ -170,12 +306,35 @@ type MajorityResponse = response.ApiResponse
 // ClassResponse represents single class response
 type ClassResponse = response.ApiResponse
 
+// PartnerDuplicatesResponse represents the likely duplicate partners response
+type PartnerDuplicatesResponse = response.ApiResponse
+
+// MergePartnersResponse represents the partner merge response
+type MergePartnersResponse = response.ApiResponse
+
+// ClassRolloverResponse represents the class rollover report response
+type ClassRolloverResponse = response.ApiResponse
+
+// MajorityImportResponse represents the majority import report response
+type MajorityImportResponse = response.ApiResponse
+
+// ClassScheduleResponse represents single class period response
+type ClassScheduleResponse = response.ApiResponse
+
+// ClassScheduleListResponse represents a weekly schedule response
+type ClassScheduleListResponse = response.ApiResponse
+
 // PartnerResponse represents single partner response
 type PartnerResponse = response.ApiResponse
 
 // BasicResponse represents basic response with message
 type BasicResponse = response.ApiResponse
 
+// DeleteResponse represents the message-only body returned by delete operations
+type DeleteResponse struct {
+	Message string `json:"message" doc:"Result message"`
+}
+
 // PaginationResult represents pagination metadata
 type PaginationResult struct {
 	Page       int `json:"page"`
@@ -190,4 +349,6 @@ type QueryParams struct {
 	Limit    int    `json:"limit" validate:"min=1,max=100"`
 	Search   string `json:"search"`
 	SchoolID string `json:"school_id"`
+	// Verified filters partners by contact email verification; nil lists all
+	Verified *bool `json:"verified,omitempty"`
 }
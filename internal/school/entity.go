@@ -16,6 +16,7 @@ type SchoolEntity struct {
 	CreatedBy *uuid.UUID `gorm:"type:char(36)"`
 	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
 	UpdatedBy *uuid.UUID `gorm:"type:char(36)"`
+	Version   int64      `gorm:"not null;default:1"` // bumped by every update
 	DeletedAt *time.Time `gorm:"index"`
 	DeletedBy *uuid.UUID `gorm:"type:char(36)"`
 
@@ -37,6 +38,7 @@ func (s *SchoolEntity) ToSchool() School {
 		Name:      s.Name,
 		CreatedAt: s.CreatedAt,
 		UpdatedAt: s.UpdatedAt,
+		Version:   s.Version,
 	}
 
 	if s.Address != nil {
@@ -60,6 +62,7 @@ type MajorityEntity struct {
 	CreatedBy   *uuid.UUID `gorm:"type:char(36)"`
 	UpdatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
 	UpdatedBy   *uuid.UUID `gorm:"type:char(36)"`
+	Version     int64      `gorm:"not null;default:1"` // bumped by every update
 	DeletedAt   *time.Time `gorm:"index"`
 	DeletedBy   *uuid.UUID `gorm:"type:char(36)"`
 
@@ -81,6 +84,7 @@ func (m *MajorityEntity) ToMajority() Majority {
 		Name:      m.Name,
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
+		Version:   m.Version,
 	}
 
 	if m.Description != nil {
@@ -92,17 +96,20 @@ func (m *MajorityEntity) ToMajority() Majority {
 
 // ClassEntity represents the class entity for database operations
 type ClassEntity struct {
-	ID          uuid.UUID  `gorm:"type:char(36);primaryKey"`
-	SchoolID    uuid.UUID  `gorm:"type:char(36);not null;index"`
-	MajorityID  uuid.UUID  `gorm:"type:char(36);not null;index"`
-	Name        string     `gorm:"size:255;not null"`
-	Description *string    `gorm:"type:text"`
-	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
-	CreatedBy   *uuid.UUID `gorm:"type:char(36)"`
-	UpdatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
-	UpdatedBy   *uuid.UUID `gorm:"type:char(36)"`
-	DeletedAt   *time.Time `gorm:"index"`
-	DeletedBy   *uuid.UUID `gorm:"type:char(36)"`
+	ID                uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	SchoolID          uuid.UUID  `gorm:"type:char(36);not null;index"`
+	MajorityID        uuid.UUID  `gorm:"type:char(36);not null;index"`
+	HomeroomTeacherID *uuid.UUID `gorm:"type:char(36)"`
+	Name              string     `gorm:"size:255;not null"`
+	AcademicYear      *string    `gorm:"size:9"`
+	Description       *string    `gorm:"type:text"`
+	CreatedAt         time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	CreatedBy         *uuid.UUID `gorm:"type:char(36)"`
+	UpdatedAt         time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	UpdatedBy         *uuid.UUID `gorm:"type:char(36)"`
+	Version           int64      `gorm:"not null;default:1"` // bumped by every update
+	DeletedAt         *time.Time `gorm:"index"`
+	DeletedBy         *uuid.UUID `gorm:"type:char(36)"`
 
 	// Relationships
 	School   SchoolEntity   `gorm:"foreignKey:SchoolID;references:ID"`
@@ -117,14 +124,19 @@ func (ClassEntity) TableName() string {
 // ToClass converts ClassEntity to Class DTO
 func (c *ClassEntity) ToClass() Class {
 	class := Class{
-		ID:         c.ID,
-		SchoolID:   c.SchoolID,
-		MajorityID: c.MajorityID,
-		Name:       c.Name,
-		CreatedAt:  c.CreatedAt,
-		UpdatedAt:  c.UpdatedAt,
+		ID:                c.ID,
+		SchoolID:          c.SchoolID,
+		MajorityID:        c.MajorityID,
+		HomeroomTeacherID: c.HomeroomTeacherID,
+		Name:              c.Name,
+		CreatedAt:         c.CreatedAt,
+		UpdatedAt:         c.UpdatedAt,
+		Version:           c.Version,
 	}
 
+	if c.AcademicYear != nil {
+		class.AcademicYear = *c.AcademicYear
+	}
 	if c.Description != nil {
 		class.Description = *c.Description
 	}
@@ -132,23 +144,71 @@ func (c *ClassEntity) ToClass() Class {
 	return class
 }
 
+// ClassScheduleEntity represents a weekly period of a class. Times are
+// stored as minutes from midnight so they are independent of time zones.
+type ClassScheduleEntity struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	ClassID     uuid.UUID  `gorm:"type:char(36);not null;index"`
+	DayOfWeek   int        `gorm:"not null;index"`
+	StartMinute int        `gorm:"not null"`
+	EndMinute   int        `gorm:"not null"`
+	Subject     string     `gorm:"size:255;not null"`
+	TeacherID   *uuid.UUID `gorm:"type:char(36);index"`
+	Room        *string    `gorm:"size:100"`
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	CreatedBy   *uuid.UUID `gorm:"type:char(36)"`
+	UpdatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	UpdatedBy   *uuid.UUID `gorm:"type:char(36)"`
+	DeletedAt   *time.Time `gorm:"index"`
+	DeletedBy   *uuid.UUID `gorm:"type:char(36)"`
+}
+
+// TableName returns the table name for the ClassScheduleEntity
+func (ClassScheduleEntity) TableName() string {
+	return "class_schedules"
+}
+
+// ToClassSchedule converts ClassScheduleEntity to ClassSchedule DTO
+func (c *ClassScheduleEntity) ToClassSchedule() ClassSchedule {
+	schedule := ClassSchedule{
+		ID:        c.ID,
+		ClassID:   c.ClassID,
+		DayOfWeek: c.DayOfWeek,
+		StartTime: FormatClockTime(c.StartMinute),
+		EndTime:   FormatClockTime(c.EndMinute),
+		Subject:   c.Subject,
+		TeacherID: c.TeacherID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+
+	if c.Room != nil {
+		schedule.Room = *c.Room
+	}
+
+	return schedule
+}
+
 // PartnerEntity represents the partner entity for database operations
 type PartnerEntity struct {
-	ID            uuid.UUID  `gorm:"type:char(36);primaryKey"`
-	SchoolID      uuid.UUID  `gorm:"type:char(36);not null;index"`
-	Name          string     `gorm:"size:255;not null;index"`
-	Website       *string    `gorm:"size:255"`
-	Description   *string    `gorm:"type:text"`
-	Address       *string    `gorm:"size:255"`
-	ContactName   *string    `gorm:"size:255"`
-	ContactPerson *string    `gorm:"size:255"`
-	ContactEmail  *string    `gorm:"size:255"`
-	CreatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
-	CreatedBy     *uuid.UUID `gorm:"type:char(36)"`
-	UpdatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
-	UpdatedBy     *uuid.UUID `gorm:"type:char(36)"`
-	DeletedAt     *time.Time `gorm:"index"`
-	DeletedBy     *uuid.UUID `gorm:"type:char(36)"`
+	ID                     uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	SchoolID               uuid.UUID  `gorm:"type:char(36);not null;index"`
+	Name                   string     `gorm:"size:255;not null;index"`
+	Website                *string    `gorm:"size:255"`
+	Description            *string    `gorm:"type:text"`
+	Address                *string    `gorm:"size:255"`
+	ContactName            *string    `gorm:"size:255"`
+	ContactPerson          *string    `gorm:"size:255"`
+	ContactEmail           *string    `gorm:"size:255"`
+	ContactEmailVerifiedAt *time.Time `gorm:"index"`
+	ContactEmailFlaggedAt  *time.Time
+	CreatedAt              time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	CreatedBy              *uuid.UUID `gorm:"type:char(36)"`
+	UpdatedAt              time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	UpdatedBy              *uuid.UUID `gorm:"type:char(36)"`
+	Version                int64      `gorm:"not null;default:1"` // bumped by every update
+	DeletedAt              *time.Time `gorm:"index"`
+	DeletedBy              *uuid.UUID `gorm:"type:char(36)"`
 
 	// Relationships
 	School SchoolEntity `gorm:"foreignKey:SchoolID;references:ID"`
@@ -159,6 +219,23 @@ func (PartnerEntity) TableName() string {
 	return "partners"
 }
 
+// PartnerMergeEntity records a duplicate partner merged into a survivor.
+// Fields is a JSON list of the survivor fields filled from the duplicate.
+type PartnerMergeEntity struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	SurvivorID  uuid.UUID  `gorm:"type:char(36);not null;index"`
+	DuplicateID uuid.UUID  `gorm:"type:char(36);not null;index"`
+	MergedBy    *uuid.UUID `gorm:"type:char(36)"`
+	Fields      string     `gorm:"type:json;not null"`
+	UsersMoved  int64      `gorm:"not null;default:0"`
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName returns the table name for the PartnerMergeEntity
+func (PartnerMergeEntity) TableName() string {
+	return "partner_merges"
+}
+
 // ToPartner converts PartnerEntity to Partner DTO
 func (p *PartnerEntity) ToPartner() Partner {
 	partner := Partner{
@@ -167,6 +244,7 @@ func (p *PartnerEntity) ToPartner() Partner {
 		Name:      p.Name,
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
+		Version:   p.Version,
 	}
 
 	if p.Website != nil {
@@ -193,6 +271,9 @@ func (p *PartnerEntity) ToPartner() Partner {
 		partner.ContactEmail = *p.ContactEmail
 	}
 
+	partner.ContactEmailVerifiedAt = p.ContactEmailVerifiedAt
+	partner.ContactEmailStale = p.ContactEmailFlaggedAt != nil
+
 	return partner
 }
 
@@ -204,6 +285,7 @@ type OTPEntity struct {
 	Purpose   string    `gorm:"size:32;not null;index"`
 	ExpiresAt time.Time `gorm:"not null;index"`
 	Used      bool      `gorm:"default:false;index"`
+	Attempts  int       `gorm:"not null;default:0"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
 
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -10,11 +12,21 @@ import (
 	"backend-service-internpro/internal/school"
 )
 
+// ErrStale is returned by the versioned updates when the row was changed
+// by another write since it was read
+var ErrStale = errors.New("row changed since it was read")
+
 // SchoolRepository defines the interface for school repository
 type SchoolRepository interface {
 	Create(ctx context.Context, entity *school.SchoolEntity) error
 	GetByID(ctx context.Context, id uuid.UUID) (*school.SchoolEntity, error)
 	GetAll(ctx context.Context, params school.QueryParams) ([]school.SchoolEntity, int, error)
+	// Update and the other Update methods write the set fields of an active
+	// row, never deleted_at or deleted_by, and return
+	// gorm.ErrRecordNotFound when the row was deleted meanwhile. Update,
+	// UpdateMajority, UpdateClass and UpdatePartner also only write the
+	// version of the row they were given, returning ErrStale for any other,
+	// and bump the version.
 	Update(ctx context.Context, entity *school.SchoolEntity) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetByDomain(ctx context.Context, domain string) (*school.SchoolEntity, error)
@@ -25,6 +37,10 @@ type SchoolRepository interface {
 	GetAllMajorities(ctx context.Context, params school.QueryParams) ([]school.MajorityEntity, int, error)
 	UpdateMajority(ctx context.Context, entity *school.MajorityEntity) error
 	DeleteMajority(ctx context.Context, id uuid.UUID) error
+	// GetMajoritiesBySchool lists the majorities of a school, by name
+	GetMajoritiesBySchool(ctx context.Context, schoolID uuid.UUID) ([]school.MajorityEntity, error)
+	// CreateMajorities creates every majority or none of them
+	CreateMajorities(ctx context.Context, entities []school.MajorityEntity) error
 
 	// Class methods
 	CreateClass(ctx context.Context, entity *school.ClassEntity) error
@@ -32,6 +48,19 @@ type SchoolRepository interface {
 	GetAllClasses(ctx context.Context, params school.QueryParams) ([]school.ClassEntity, int, error)
 	UpdateClass(ctx context.Context, entity *school.ClassEntity) error
 	DeleteClass(ctx context.Context, id uuid.UUID) error
+	// GetClassesByYear lists the classes of a school in an academic year, by name
+	GetClassesByYear(ctx context.Context, schoolID uuid.UUID, academicYear string) ([]school.ClassEntity, error)
+	// CreateClasses creates every class or none of them
+	CreateClasses(ctx context.Context, entities []school.ClassEntity) error
+
+	// Class schedule methods
+	CreateClassSchedule(ctx context.Context, entity *school.ClassScheduleEntity) error
+	GetClassScheduleByID(ctx context.Context, id uuid.UUID) (*school.ClassScheduleEntity, error)
+	GetClassSchedules(ctx context.Context, classID uuid.UUID) ([]school.ClassScheduleEntity, error)
+	GetTeacherSchedules(ctx context.Context, teacherID uuid.UUID) ([]school.ClassScheduleEntity, error)
+	GetSchedulesOnDay(ctx context.Context, dayOfWeek int, classID uuid.UUID, teacherID *uuid.UUID) ([]school.ClassScheduleEntity, error)
+	UpdateClassSchedule(ctx context.Context, entity *school.ClassScheduleEntity) error
+	DeleteClassSchedule(ctx context.Context, id uuid.UUID) error
 
 	// Partner methods
 	CreatePartner(ctx context.Context, entity *school.PartnerEntity) error
@@ -39,6 +68,19 @@ type SchoolRepository interface {
 	GetAllPartners(ctx context.Context, params school.QueryParams) ([]school.PartnerEntity, int, error)
 	UpdatePartner(ctx context.Context, entity *school.PartnerEntity) error
 	DeletePartner(ctx context.Context, id uuid.UUID) error
+	// SetPartnerContactVerified records when the contact email was verified,
+	// nil to reset it, and clears any stale flag
+	SetPartnerContactVerified(ctx context.Context, id uuid.UUID, verifiedAt *time.Time) error
+	// FlagStalePartnerContacts flags contact emails not verified since before
+	// and returns how many were newly flagged
+	FlagStalePartnerContacts(ctx context.Context, before time.Time) (int64, error)
+	// ListPartners returns every partner, of one school when schoolID is set,
+	// oldest first
+	ListPartners(ctx context.Context, schoolID *uuid.UUID) ([]school.PartnerEntity, error)
+	// MergePartners saves survivor, moves the users of the merged partners to
+	// it, deletes the merged partners and records merges in one transaction.
+	// UsersMoved of each merge is set to the number of users moved.
+	MergePartners(ctx context.Context, survivor *school.PartnerEntity, merges []school.PartnerMergeEntity) error
 }
 
 // schoolRepository implements SchoolRepository
@@ -95,7 +137,36 @@ func (r *schoolRepository) GetAll(ctx context.Context, params school.QueryParams
 }
 
 func (r *schoolRepository) Update(ctx context.Context, entity *school.SchoolEntity) error {
-	return r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", entity.ID).Updates(entity).Error
+	return r.updateVersioned(ctx, entity, entity.ID, &entity.Version)
+}
+
+// updateVersioned writes entity, the row id at *version, and bumps
+// *version. It leaves *version as it was when nothing was written.
+func (r *schoolRepository) updateVersioned(ctx context.Context, entity any, id uuid.UUID, version *int64) error {
+	read := *version
+	*version++
+	err := updated(r.db.WithContext(ctx).
+		Where("id = ? AND version = ? AND deleted_at IS NULL", id, read).
+		Omit("deleted_at", "deleted_by").Updates(entity))
+	if err == nil {
+		return nil
+	}
+	*version = read
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// No row matched: either another write bumped the version or the
+	// row is gone
+	var count int64
+	if err := r.db.WithContext(ctx).Model(entity).
+		Where("id = ? AND deleted_at IS NULL", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrStale
+	}
+	return gorm.ErrRecordNotFound
 }
 
 func (r *schoolRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -163,7 +234,7 @@ func (r *schoolRepository) GetAllMajorities(ctx context.Context, params school.Q
 }
 
 func (r *schoolRepository) UpdateMajority(ctx context.Context, entity *school.MajorityEntity) error {
-	return r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", entity.ID).Updates(entity).Error
+	return r.updateVersioned(ctx, entity, entity.ID, &entity.Version)
 }
 
 func (r *schoolRepository) DeleteMajority(ctx context.Context, id uuid.UUID) error {
@@ -224,7 +295,7 @@ func (r *schoolRepository) GetAllClasses(ctx context.Context, params school.Quer
 }
 
 func (r *schoolRepository) UpdateClass(ctx context.Context, entity *school.ClassEntity) error {
-	return r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", entity.ID).Updates(entity).Error
+	return r.updateVersioned(ctx, entity, entity.ID, &entity.Version)
 }
 
 func (r *schoolRepository) DeleteClass(ctx context.Context, id uuid.UUID) error {
@@ -233,6 +304,118 @@ func (r *schoolRepository) DeleteClass(ctx context.Context, id uuid.UUID) error
 		Update("deleted_at", gorm.Expr("NOW()")).Error
 }
 
+func (r *schoolRepository) GetClassesByYear(ctx context.Context, schoolID uuid.UUID, academicYear string) ([]school.ClassEntity, error) {
+	var entities []school.ClassEntity
+	err := r.db.WithContext(ctx).
+		Where("school_id = ? AND academic_year = ? AND deleted_at IS NULL", schoolID, academicYear).
+		Order("name").
+		Find(&entities).Error
+	return entities, err
+}
+
+func (r *schoolRepository) GetMajoritiesBySchool(ctx context.Context, schoolID uuid.UUID) ([]school.MajorityEntity, error) {
+	var entities []school.MajorityEntity
+	err := r.db.WithContext(ctx).
+		Where("school_id = ? AND deleted_at IS NULL", schoolID).
+		Order("name").
+		Find(&entities).Error
+	return entities, err
+}
+
+func (r *schoolRepository) CreateMajorities(ctx context.Context, entities []school.MajorityEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range entities {
+			if err := tx.Create(&entities[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *schoolRepository) CreateClasses(ctx context.Context, entities []school.ClassEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range entities {
+			if err := tx.Create(&entities[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Class schedule methods
+func (r *schoolRepository) CreateClassSchedule(ctx context.Context, entity *school.ClassScheduleEntity) error {
+	return r.db.WithContext(ctx).Create(entity).Error
+}
+
+func (r *schoolRepository) GetClassScheduleByID(ctx context.Context, id uuid.UUID) (*school.ClassScheduleEntity, error) {
+	var entity school.ClassScheduleEntity
+	err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", id).First(&entity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func (r *schoolRepository) GetClassSchedules(ctx context.Context, classID uuid.UUID) ([]school.ClassScheduleEntity, error) {
+	var entities []school.ClassScheduleEntity
+	err := r.db.WithContext(ctx).
+		Where("class_id = ? AND deleted_at IS NULL", classID).
+		Order("day_of_week, start_minute").
+		Find(&entities).Error
+	return entities, err
+}
+
+func (r *schoolRepository) GetTeacherSchedules(ctx context.Context, teacherID uuid.UUID) ([]school.ClassScheduleEntity, error) {
+	var entities []school.ClassScheduleEntity
+	err := r.db.WithContext(ctx).
+		Where("teacher_id = ? AND deleted_at IS NULL", teacherID).
+		Order("day_of_week, start_minute").
+		Find(&entities).Error
+	return entities, err
+}
+
+// GetSchedulesOnDay returns the periods on a day that belong to the class or,
+// when given, to the teacher
+func (r *schoolRepository) GetSchedulesOnDay(ctx context.Context, dayOfWeek int, classID uuid.UUID, teacherID *uuid.UUID) ([]school.ClassScheduleEntity, error) {
+	var entities []school.ClassScheduleEntity
+	query := r.db.WithContext(ctx).Where("day_of_week = ? AND deleted_at IS NULL", dayOfWeek)
+	if teacherID != nil {
+		query = query.Where("class_id = ? OR teacher_id = ?", classID, *teacherID)
+	} else {
+		query = query.Where("class_id = ?", classID)
+	}
+	err := query.Order("start_minute").Find(&entities).Error
+	return entities, err
+}
+
+func (r *schoolRepository) UpdateClassSchedule(ctx context.Context, entity *school.ClassScheduleEntity) error {
+	// Select the columns explicitly so teacher and room can be cleared
+	return updated(r.db.WithContext(ctx).Model(entity).
+		Where("deleted_at IS NULL").
+		Select("day_of_week", "start_minute", "end_minute", "subject", "teacher_id", "room", "updated_at", "updated_by").
+		Updates(entity))
+}
+
+// updated returns the error of an update, or gorm.ErrRecordNotFound when it
+// matched no row
+func updated(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *schoolRepository) DeleteClassSchedule(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&school.ClassScheduleEntity{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Update("deleted_at", gorm.Expr("NOW()")).Error
+}
+
 // Partner methods
 func (r *schoolRepository) CreatePartner(ctx context.Context, entity *school.PartnerEntity) error {
 	return r.db.WithContext(ctx).Create(entity).Error
@@ -268,6 +451,15 @@ func (r *schoolRepository) GetAllPartners(ctx context.Context, params school.Que
 		}
 	}
 
+	// Apply contact verification filter
+	if params.Verified != nil {
+		if *params.Verified {
+			query = query.Where("contact_email_verified_at IS NOT NULL")
+		} else {
+			query = query.Where("contact_email_verified_at IS NULL")
+		}
+	}
+
 	// Count total records
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -283,7 +475,7 @@ func (r *schoolRepository) GetAllPartners(ctx context.Context, params school.Que
 }
 
 func (r *schoolRepository) UpdatePartner(ctx context.Context, entity *school.PartnerEntity) error {
-	return r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", entity.ID).Updates(entity).Error
+	return r.updateVersioned(ctx, entity, entity.ID, &entity.Version)
 }
 
 func (r *schoolRepository) DeletePartner(ctx context.Context, id uuid.UUID) error {
@@ -291,3 +483,70 @@ func (r *schoolRepository) DeletePartner(ctx context.Context, id uuid.UUID) erro
 		Where("id = ? AND deleted_at IS NULL", id).
 		Update("deleted_at", gorm.Expr("NOW()")).Error
 }
+
+func (r *schoolRepository) SetPartnerContactVerified(ctx context.Context, id uuid.UUID, verifiedAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&school.PartnerEntity{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"contact_email_verified_at": verifiedAt,
+			"contact_email_flagged_at":  nil,
+			"version":                   gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *schoolRepository) FlagStalePartnerContacts(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&school.PartnerEntity{}).
+		Where("deleted_at IS NULL AND contact_email_flagged_at IS NULL").
+		Where("contact_email IS NOT NULL AND contact_email <> ''").
+		Where("COALESCE(contact_email_verified_at, created_at) < ?", before).
+		Updates(map[string]interface{}{
+			"contact_email_flagged_at": time.Now(),
+			"version":                  gorm.Expr("version + 1"),
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *schoolRepository) ListPartners(ctx context.Context, schoolID *uuid.UUID) ([]school.PartnerEntity, error) {
+	var entities []school.PartnerEntity
+	query := r.db.WithContext(ctx).Where("deleted_at IS NULL")
+	if schoolID != nil {
+		query = query.Where("school_id = ?", *schoolID)
+	}
+	err := query.Order("created_at, id").Find(&entities).Error
+	return entities, err
+}
+
+func (r *schoolRepository) MergePartners(ctx context.Context, survivor *school.PartnerEntity, merges []school.PartnerMergeEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("*").Omit("School", "CreatedAt", "CreatedBy", "Version", "DeletedAt", "DeletedBy").
+			Where("deleted_at IS NULL").Updates(survivor).Error; err != nil {
+			return err
+		}
+		// The merge is a write like any other, so tags read before it go stale
+		if err := tx.Model(survivor).UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+			return err
+		}
+
+		for i := range merges {
+			merge := &merges[i]
+
+			moved := tx.Table("users").Where("partner_id = ?", merge.DuplicateID).
+				Update("partner_id", merge.SurvivorID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			merge.UsersMoved = moved.RowsAffected
+
+			if err := tx.Model(&school.PartnerEntity{}).
+				Where("id = ? AND deleted_at IS NULL", merge.DuplicateID).
+				Update("deleted_at", gorm.Expr("NOW()")).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Create(merge).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
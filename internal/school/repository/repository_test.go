@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"backend-service-internpro/internal/school"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// recorder returns a repository on a database that renders statements
+// without a server, the statements it ran, and the rows updates affect and
+// counts find
+func recorder(t *testing.T, affected, found int64) (*schoolRepository, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []string
+	err = db.Callback().Update().After("gorm:update").Register("test:affect", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		tx.RowsAffected = affected
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Callback().Query().After("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		if count, ok := tx.Statement.Dest.(*int64); ok {
+			*count, tx.RowsAffected = found, 1
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schoolRepository{db: db}, &statements
+}
+
+func TestUpdateChecksVersion(t *testing.T) {
+	// Each kind updates a row read at version 3 and returns its version after
+	kinds := map[string]func(r *schoolRepository) (int64, error){
+		"school": func(r *schoolRepository) (int64, error) {
+			entity := &school.SchoolEntity{ID: uuid.New(), Name: "SMK Negeri 1 Surabaya", Version: 3}
+			err := r.Update(context.Background(), entity)
+			return entity.Version, err
+		},
+		"majority": func(r *schoolRepository) (int64, error) {
+			entity := &school.MajorityEntity{ID: uuid.New(), Name: "Teknik Komputer dan Jaringan", Version: 3}
+			err := r.UpdateMajority(context.Background(), entity)
+			return entity.Version, err
+		},
+		"class": func(r *schoolRepository) (int64, error) {
+			entity := &school.ClassEntity{ID: uuid.New(), Name: "XI TKJ 1", Version: 3}
+			err := r.UpdateClass(context.Background(), entity)
+			return entity.Version, err
+		},
+		"partner": func(r *schoolRepository) (int64, error) {
+			entity := &school.PartnerEntity{ID: uuid.New(), Name: "PT Telkom Indonesia", Version: 3}
+			err := r.UpdatePartner(context.Background(), entity)
+			return entity.Version, err
+		},
+	}
+	tests := []struct {
+		name            string
+		affected, found int64
+		want            error
+		wantVersion     int64
+	}{
+		{"version matches", 1, 1, nil, 4},
+		{"changed meanwhile", 0, 1, ErrStale, 3},
+		{"deleted meanwhile", 0, 0, gorm.ErrRecordNotFound, 3},
+	}
+	for kind, update := range kinds {
+		for _, tt := range tests {
+			t.Run(kind+" "+tt.name, func(t *testing.T) {
+				r, statements := recorder(t, tt.affected, tt.found)
+
+				version, err := update(r)
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("err = %v, want %v", err, tt.want)
+				}
+				if version != tt.wantVersion {
+					t.Errorf("version = %d, want %d", version, tt.wantVersion)
+				}
+				update := (*statements)[0]
+				if !strings.Contains(update, "`version`=?") || !strings.Contains(update, "version = ?") {
+					t.Errorf("update does not bump and check the version:\n%s", update)
+				}
+			})
+		}
+	}
+}
+
+func TestPartnerVerifiedFilter(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		verified *bool
+		want     string
+	}{
+		{"all partners", nil, ""},
+		{"verified contacts", &yes, "contact_email_verified_at IS NOT NULL"},
+		{"unverified contacts", &no, "contact_email_verified_at IS NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, statements := recorder(t, 0, 0)
+			if _, _, err := r.GetAllPartners(context.Background(), school.QueryParams{Page: 1, Limit: 10, Verified: tt.verified}); err != nil {
+				t.Fatal(err)
+			}
+			if len(*statements) != 2 {
+				t.Fatalf("ran %d statements, want the count and the page", len(*statements))
+			}
+			for _, statement := range *statements {
+				filtered := strings.Contains(statement, "contact_email_verified_at")
+				if filtered != (tt.want != "") || !strings.Contains(statement, tt.want) {
+					t.Errorf("statement does not filter on %q:\n%s", tt.want, statement)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package school
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseClockTime converts an "HH:MM" time of day to minutes from midnight
+func ParseClockTime(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// FormatClockTime converts minutes from midnight to an "HH:MM" time of day
+func FormatClockTime(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// PeriodsOverlap reports whether the half-open periods [aStart, aEnd) and
+// [bStart, bEnd) overlap; back-to-back periods do not overlap
+func PeriodsOverlap(aStart, aEnd, bStart, bEnd int) bool {
+	return aStart < bEnd && bStart < aEnd
+}
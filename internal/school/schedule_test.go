@@ -0,0 +1,33 @@
+package school
+
+import "testing"
+
+func TestPeriodsOverlap(t *testing.T) {
+	// Periods in minutes from midnight, against 08:00-09:30
+	const start, end = 8 * 60, 9*60 + 30
+	tests := []struct {
+		name       string
+		start, end int
+		want       bool
+	}{
+		{"same period", start, end, true},
+		{"starts inside", 9 * 60, 10 * 60, true},
+		{"ends inside", 7 * 60, 8*60 + 1, true},
+		{"contains it", 7 * 60, 10 * 60, true},
+		{"inside it", 8*60 + 30, 9 * 60, true},
+		{"ends when it starts", 7 * 60, start, false},
+		{"starts when it ends", end, 10 * 60, false},
+		{"earlier", 6 * 60, 7 * 60, false},
+		{"later", 10 * 60, 11 * 60, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PeriodsOverlap(start, end, tt.start, tt.end); got != tt.want {
+				t.Errorf("PeriodsOverlap = %v, want %v", got, tt.want)
+			}
+			if got := PeriodsOverlap(tt.start, tt.end, start, end); got != tt.want {
+				t.Errorf("PeriodsOverlap swapped = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/school"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContactVerifyAudience is the audience of contact verification tokens, so
+// they are rejected anywhere else a token signed with the same key is read
+const ContactVerifyAudience = "partner-contact-verification"
+
+// DefaultContactVerifyTTL is how long a contact verification link stays valid
+const DefaultContactVerifyTTL = 7 * 24 * time.Hour
+
+// contactVerifyPath is the public endpoint verification links point to
+const contactVerifyPath = "/v1/verify/contact"
+
+const (
+	contactVerifySubject = "Verifikasi Email Kontak Mitra"
+	contactVerifyBody    = "<p>Halo %s,</p><p>Alamat email ini terdaftar sebagai kontak mitra <b>%s</b>. Klik tautan berikut untuk mengonfirmasi bahwa alamat ini masih aktif:</p><p><a href=\"%s\">%s</a></p><p>Tautan berlaku selama %d hari.</p>"
+)
+
+var (
+	// ErrPartnerNotFound is returned when the partner does not exist
+	ErrPartnerNotFound = errors.New("partner not found")
+	// ErrNoContactEmail is returned when the partner has no contact email to verify
+	ErrNoContactEmail = errors.New("partner has no contact email")
+	// ErrContactLinkInvalid is returned for malformed, expired or outdated verification links
+	ErrContactLinkInvalid = errors.New("invalid or expired contact verification link")
+	// ErrContactMailUnavailable is returned when no email channel is configured
+	ErrContactMailUnavailable = errors.New("contact verification email is not available")
+)
+
+func (s *schoolService) SendContactVerification(ctx context.Context, partnerID uuid.UUID) (*school.BasicResponse, error) {
+	entity, err := s.repo.GetPartnerByID(ctx, partnerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartnerNotFound
+		}
+		return nil, err
+	}
+	if entity.ContactEmail == nil || *entity.ContactEmail == "" {
+		return nil, ErrNoContactEmail
+	}
+	if s.notifier == nil {
+		return nil, ErrContactMailUnavailable
+	}
+
+	// The token carries the address so a link sent before the contact
+	// changed cannot verify the new one
+	token, err := jwt.GenerateScoped(ContactVerifyAudience, entity.ID.String(), *entity.ContactEmail, s.cfg.SigningKey, s.cfg.ContactVerifyTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign verification link: %w", err)
+	}
+	link := strings.TrimSuffix(s.cfg.PublicURL, "/") + contactVerifyPath + "?token=" + url.QueryEscape(token)
+
+	name := *entity.ContactEmail
+	if entity.ContactPerson != nil && *entity.ContactPerson != "" {
+		name = *entity.ContactPerson
+	}
+	msg := notifier.Message{
+		Subject: contactVerifySubject,
+		Body: fmt.Sprintf(contactVerifyBody, html.EscapeString(name), html.EscapeString(entity.Name),
+			html.EscapeString(link), html.EscapeString(link), int(s.cfg.ContactVerifyTTL.Hours()/24)),
+	}
+	if err := s.notifier.Dispatch(ctx, notifier.ChannelEmail, notifier.Recipient{Email: *entity.ContactEmail}, msg); err != nil {
+		if errors.Is(err, notifier.ErrNoChannel) {
+			return nil, ErrContactMailUnavailable
+		}
+		return nil, fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return response.SuccessWithoutData(constants.PartnerContactVerificationSent), nil
+}
+
+func (s *schoolService) VerifyContact(ctx context.Context, token string) (*school.BasicResponse, error) {
+	claims, err := jwt.ParseScoped(token, ContactVerifyAudience, s.cfg.SigningKey, s.cfg.OldSigningKeys...)
+	if err != nil {
+		return nil, ErrContactLinkInvalid
+	}
+	partnerID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, ErrContactLinkInvalid
+	}
+
+	entity, err := s.repo.GetPartnerByID(ctx, partnerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactLinkInvalid
+		}
+		return nil, err
+	}
+	if entity.ContactEmail == nil || *entity.ContactEmail != claims.Value {
+		return nil, ErrContactLinkInvalid
+	}
+
+	now := s.clock.Now()
+	if err := s.repo.SetPartnerContactVerified(ctx, partnerID, &now); err != nil {
+		return nil, err
+	}
+
+	return response.SuccessWithoutData(constants.PartnerContactVerified), nil
+}
+
+func (s *schoolService) FlagStaleContacts(ctx context.Context, maxAge time.Duration) (int64, error) {
+	return s.repo.FlagStalePartnerContacts(ctx, s.clock.Now().Add(-maxAge))
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/notifier"
+	"backend-service-internpro/internal/school"
+	"backend-service-internpro/internal/school/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// partnerRepo knows one partner and records when its contact was
+// verified; other methods are not used
+type partnerRepo struct {
+	repository.SchoolRepository
+	partner *school.PartnerEntity
+}
+
+func (r *partnerRepo) GetPartnerByID(_ context.Context, id uuid.UUID) (*school.PartnerEntity, error) {
+	if r.partner.ID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *r.partner
+	return &copied, nil
+}
+
+func (r *partnerRepo) SetPartnerContactVerified(_ context.Context, _ uuid.UUID, verifiedAt *time.Time) error {
+	r.partner.ContactEmailVerifiedAt = verifiedAt
+	return nil
+}
+
+// sentLinks records the messages sent to it
+type sentLinks []notifier.Message
+
+func (s *sentLinks) Send(_ context.Context, _ notifier.Recipient, msg notifier.Message) error {
+	*s = append(*s, msg)
+	return nil
+}
+
+var linkToken = regexp.MustCompile(`href="[^"]*\?token=([^"]+)"`)
+
+// token returns the verification token in the last message sent
+func (s sentLinks) token(t *testing.T) string {
+	t.Helper()
+	if len(s) == 0 {
+		t.Fatal("no verification email was sent")
+	}
+	m := linkToken.FindStringSubmatch(s[len(s)-1].Body)
+	if m == nil {
+		t.Fatalf("no link in %q", s[len(s)-1].Body)
+	}
+	token, err := url.QueryUnescape(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestContactVerification(t *testing.T) {
+	key := []byte("access-secret")
+	email := "hrd@majujaya.co.id"
+	partner := &school.PartnerEntity{ID: uuid.New(), Name: "PT Maju Jaya", ContactEmail: &email}
+	repo := &partnerRepo{partner: partner}
+	var sent sentLinks
+	s := NewSchoolServiceWithConfig(repo, Config{
+		Notifier:   notifier.NewDispatcher().Register(notifier.ChannelEmail, &sent),
+		SigningKey: key,
+		PublicURL:  "https://api.internpro.id/",
+	})
+	ctx := context.Background()
+
+	if _, err := s.SendContactVerification(ctx, partner.ID); err != nil {
+		t.Fatal(err)
+	}
+	valid := sent.token(t)
+
+	sign := func(audience, subject, value string, ttl time.Duration) string {
+		token, err := jwt.GenerateScoped(audience, subject, value, key, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	access, err := jwt.GenerateAccess(jwt.AccessClaims{UserID: partner.ID.String()}, jwt.Secrets{Access: key}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-token"},
+		{"expired", sign(ContactVerifyAudience, partner.ID.String(), email, -time.Minute)},
+		{"signed with another key", func() string {
+			token, err := jwt.GenerateScoped(ContactVerifyAudience, partner.ID.String(), email, []byte("other-secret"), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}()},
+		{"another audience", sign("password-reset", partner.ID.String(), email, time.Hour)},
+		{"an access token", access},
+		{"an earlier address", sign(ContactVerifyAudience, partner.ID.String(), "old@majujaya.co.id", time.Hour)},
+		{"an unknown partner", sign(ContactVerifyAudience, uuid.NewString(), email, time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.VerifyContact(ctx, tt.token); !errors.Is(err, ErrContactLinkInvalid) {
+				t.Errorf("err = %v, want %v", err, ErrContactLinkInvalid)
+			}
+			if partner.ContactEmailVerifiedAt != nil {
+				t.Error("contact was marked verified")
+			}
+		})
+	}
+
+	if _, err := s.VerifyContact(ctx, valid); err != nil {
+		t.Fatal(err)
+	}
+	if partner.ContactEmailVerifiedAt == nil {
+		t.Error("contact is not marked verified after following the emailed link")
+	}
+}
+
+func TestContactVerificationNeedsAnEmail(t *testing.T) {
+	repo := &partnerRepo{partner: &school.PartnerEntity{ID: uuid.New(), Name: "PT Maju Jaya"}}
+	s := NewSchoolServiceWithConfig(repo, Config{SigningKey: []byte("access-secret")})
+	if _, err := s.SendContactVerification(context.Background(), repo.partner.ID); !errors.Is(err, ErrNoContactEmail) {
+		t.Errorf("err = %v, want %v", err, ErrNoContactEmail)
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Indonesian class names start with the grade, written in Roman numerals
+// (X RPL 1, VII-A) or digits (10 IPA 2), followed by the majority and the
+// parallel class. Grades run from 1 to 12: SD 1-6, SMP 7-9, SMA/SMK 10-12.
+const maxGrade = 12
+
+var romanGrades = []string{"", "I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX", "X", "XI", "XII"}
+
+// classGrade is a class name split into its grade and the rest
+type classGrade struct {
+	grade int
+	roman bool
+	// rest is everything after the grade, including the separator
+	rest string
+}
+
+// parseClassGrade reads the grade at the start of a class name. Digits may
+// be followed directly by the parallel class ("7A"); a Roman grade must be
+// followed by the end of the name, a space, '-' or '.', so a name like
+// "IPA 1" or "Xavier" is not mistaken for a grade.
+func parseClassGrade(name string) (classGrade, bool) {
+	name = strings.TrimSpace(name)
+
+	digits := 0
+	for digits < len(name) && name[digits] >= '0' && name[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 {
+		n, _ := strconv.Atoi(name[:digits])
+		if n < 1 || n > maxGrade {
+			return classGrade{}, false
+		}
+		return classGrade{grade: n, rest: name[digits:]}, true
+	}
+
+	end := strings.IndexAny(name, " -.")
+	if end < 0 {
+		end = len(name)
+	}
+	token, rest := name[:end], name[end:]
+
+	upper := strings.ToUpper(token)
+	for n := 1; n <= maxGrade; n++ {
+		if romanGrades[n] == upper {
+			return classGrade{grade: n, roman: true, rest: rest}, true
+		}
+	}
+	return classGrade{}, false
+}
+
+// withGrade returns the class name for grade n, keeping the numeral style
+func (g classGrade) withGrade(n int) string {
+	if g.roman {
+		return romanGrades[n] + g.rest
+	}
+	return strconv.Itoa(n) + g.rest
+}
+
+// promoteClassName returns the name of the class one grade up, e.g.
+// "X RPL 1" becomes "XI RPL 1". graduated is true when the class is in
+// finalGrade and has no successor. ok is false when the name does not start
+// with a grade.
+func promoteClassName(name string, finalGrade int) (promoted string, graduated, ok bool) {
+	g, ok := parseClassGrade(name)
+	if !ok {
+		return "", false, false
+	}
+	if g.grade >= finalGrade || g.grade >= maxGrade {
+		return "", true, true
+	}
+	return g.withGrade(g.grade + 1), false, true
+}
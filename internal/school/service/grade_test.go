@@ -0,0 +1,46 @@
+package service
+
+import "testing"
+
+func TestPromoteClassName(t *testing.T) {
+	tests := []struct {
+		name       string
+		finalGrade int
+		want       string
+		graduated  bool
+		ok         bool
+	}{
+		{"X RPL 1", 12, "XI RPL 1", false, true},
+		{"XI TKJ 2", 12, "XII TKJ 2", false, true},
+		{"XII IPA 1", 12, "", true, true},
+		{"VII-A", 9, "VIII-A", false, true},
+		{"VIII.B", 9, "IX.B", false, true},
+		{"IX C", 9, "", true, true},
+		{"IV", 6, "V", false, true},
+		// Lowercase Roman grades are read but written in capitals
+		{"x mipa 3", 12, "XI mipa 3", false, true},
+		{"10 IPA 2", 12, "11 IPA 2", false, true},
+		{"7A", 9, "8A", false, true},
+		{"6", 6, "", true, true},
+		{"  9 B ", 12, "10 B", false, true},
+		// Past a final grade set lower than the last grade
+		{"X AKL 1", 10, "", true, true},
+		// Names that do not start with a grade
+		{"IPA 1", 12, "", false, false},
+		{"Xavier", 12, "", false, false},
+		{"XIII A", 12, "", false, false},
+		{"13 A", 12, "", false, false},
+		{"0 A", 12, "", false, false},
+		{"Kelas Unggulan", 12, "", false, false},
+		{"", 12, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, graduated, ok := promoteClassName(tt.name, tt.finalGrade)
+			if got != tt.want || graduated != tt.graduated || ok != tt.ok {
+				t.Errorf("promoteClassName(%q, %d) = %q, %v, %v, want %q, %v, %v",
+					tt.name, tt.finalGrade, got, graduated, ok, tt.want, tt.graduated, tt.ok)
+			}
+		})
+	}
+}
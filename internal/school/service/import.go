@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/csvimport"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/school"
+)
+
+// ErrImportRow is returned, with the line, when a row of an import file
+// cannot be imported; nothing is written then
+var ErrImportRow = errors.New("invalid row")
+
+// majorityColumns are the columns a majority import file needs;
+// description is optional
+var majorityColumns = []string{"name"}
+
+// ImportMajorities creates a majority of schoolID for each row of the CSV
+// file. Names the school already has, or that an earlier row listed, are
+// skipped. The file is checked by csvimport before any row is read, and the
+// majorities are created in one transaction.
+func (s *schoolService) ImportMajorities(ctx context.Context, schoolID uuid.UUID, file io.Reader) (*school.MajorityImportResponse, error) {
+	if _, err := s.repo.GetByID(ctx, schoolID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSchoolNotFound
+		}
+		return nil, err
+	}
+
+	current, err := s.repo.GetMajoritiesBySchool(ctx, schoolID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]uuid.UUID, len(current))
+	for _, m := range current {
+		existing[classKey(m.Name)] = m.ID
+	}
+
+	result := school.MajorityImportResult{Majorities: []school.MajorityImportItem{}}
+	var creates []school.MajorityEntity
+	now := s.clock.Now()
+
+	err = csvimport.Read(file, csvimport.Options{Columns: majorityColumns}, func(row csvimport.Row) error {
+		name := row.Get("name")
+		switch {
+		case name == "":
+			return fmt.Errorf("%w: line %d has no name", ErrImportRow, row.Line)
+		case utf8.RuneCountInString(name) > 255:
+			return fmt.Errorf("%w: the name on line %d is longer than 255 characters", ErrImportRow, row.Line)
+		}
+
+		item := school.MajorityImportItem{Line: row.Line, Name: name}
+		if id, ok := existing[classKey(name)]; ok {
+			item.ID = id
+			item.Status = school.ImportDuplicate
+			result.Skipped++
+		} else {
+			entity := school.MajorityEntity{
+				ID:        s.ids.New(),
+				SchoolID:  schoolID,
+				Name:      name,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if description := row.Get("description"); description != "" {
+				entity.Description = &description
+			}
+			creates = append(creates, entity)
+			existing[classKey(name)] = entity.ID
+
+			item.ID = entity.ID
+			item.Status = school.ImportCreated
+			result.Created++
+		}
+		result.Majorities = append(result.Majorities, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(creates) > 0 {
+		if err := s.repo.CreateMajorities(ctx, creates); err != nil {
+			return nil, err
+		}
+	}
+	return response.Success(constants.MajorityImportSuccess, result), nil
+}
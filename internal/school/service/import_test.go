@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/csvimport"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/school"
+	"backend-service-internpro/internal/school/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeRepo knows one school with its majorities and records the ones
+// created; other methods are not used
+type fakeRepo struct {
+	repository.SchoolRepository
+	schoolID   uuid.UUID
+	majorities []school.MajorityEntity
+	created    []school.MajorityEntity
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id uuid.UUID) (*school.SchoolEntity, error) {
+	if id != r.schoolID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &school.SchoolEntity{ID: id}, nil
+}
+
+func (r *fakeRepo) GetMajoritiesBySchool(context.Context, uuid.UUID) ([]school.MajorityEntity, error) {
+	return r.majorities, nil
+}
+
+func (r *fakeRepo) CreateMajorities(_ context.Context, entities []school.MajorityEntity) error {
+	r.created = append(r.created, entities...)
+	return nil
+}
+
+func TestImportMajorities(t *testing.T) {
+	schoolID, existingID := uuid.New(), uuid.New()
+	repo := &fakeRepo{
+		schoolID:   schoolID,
+		majorities: []school.MajorityEntity{{ID: existingID, SchoolID: schoolID, Name: "Tata Boga"}},
+	}
+	now := time.Date(2025, 7, 14, 8, 30, 0, 0, time.UTC)
+	s := NewSchoolServiceWithConfig(repo, Config{Clock: clock.NewFake(now), IDs: idgen.NewSequence()})
+
+	file := "name,description\n" +
+		"Rekayasa Perangkat Lunak,RPL\n" +
+		"tata boga,\n" + // the school has it
+		"Rekayasa perangkat lunak,\n" // an earlier row has it
+	resp, err := s.ImportMajorities(context.Background(), schoolID, strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := resp.Data.(school.MajorityImportResult)
+	if result.Created != 1 || result.Skipped != 2 {
+		t.Errorf("created %d, skipped %d, want 1 and 2", result.Created, result.Skipped)
+	}
+	if len(repo.created) != 1 {
+		t.Fatalf("created %d majorities, want 1", len(repo.created))
+	}
+	created := repo.created[0]
+	if created.Name != "Rekayasa Perangkat Lunak" || created.Description == nil || *created.Description != "RPL" ||
+		created.SchoolID != schoolID || !created.CreatedAt.Equal(now) {
+		t.Errorf("created %+v", created)
+	}
+	want := []school.MajorityImportItem{
+		{Line: 2, Name: "Rekayasa Perangkat Lunak", ID: created.ID, Status: school.ImportCreated},
+		{Line: 3, Name: "tata boga", ID: existingID, Status: school.ImportDuplicate},
+		{Line: 4, Name: "Rekayasa perangkat lunak", ID: created.ID, Status: school.ImportDuplicate},
+	}
+	for i, item := range result.Majorities {
+		if i >= len(want) || item != want[i] {
+			t.Errorf("rows = %+v, want %+v", result.Majorities, want)
+			break
+		}
+	}
+}
+
+func TestImportMajoritiesWritesNothingOnError(t *testing.T) {
+	schoolID := uuid.New()
+	tests := []struct {
+		name    string
+		file    string
+		wantErr error
+	}{
+		{"row without a name", "name\nTata Boga\n\"\"\n", ErrImportRow},
+		{"header mismatch", "nama\nTata Boga\n", csvimport.ErrHeader},
+		{"bad encoding", "name\nCaf\xc3\xa9 \xe9\n", csvimport.ErrMixedEncoding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{schoolID: schoolID}
+			s := NewSchoolService(repo)
+			_, err := s.ImportMajorities(context.Background(), schoolID, strings.NewReader(tt.file))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.created) > 0 {
+				t.Errorf("created %d majorities from a rejected file", len(repo.created))
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/requestctx"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/school"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultDuplicateThreshold is the name similarity from which two partners
+// of a school are reported as likely duplicates
+const DefaultDuplicateThreshold = 0.85
+
+var (
+	// ErrMergeSurvivorListed is returned when the survivor is also listed as a duplicate
+	ErrMergeSurvivorListed = errors.New("survivor is listed as a duplicate")
+	// ErrMergeAcrossSchools is returned when the partners belong to different schools
+	ErrMergeAcrossSchools = errors.New("partners belong to different schools")
+)
+
+// FindDuplicatePartners groups partners of the same school whose normalized
+// names are at least threshold similar. A partner similar to any member of
+// a group joins it. With schoolID nil every school is checked.
+func (s *schoolService) FindDuplicatePartners(ctx context.Context, schoolID *uuid.UUID, threshold float64) (*school.PartnerDuplicatesResponse, error) {
+	if threshold <= 0 {
+		threshold = DefaultDuplicateThreshold
+	}
+
+	partners, err := s.repo.ListPartners(ctx, schoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(partners))
+	for i := range partners {
+		names[i] = normalizePartnerName(partners[i].Name)
+	}
+
+	// Union-find over the partners, only comparing within a school
+	parent := make([]int, len(partners))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range partners {
+		for j := i + 1; j < len(partners); j++ {
+			if partners[i].SchoolID != partners[j].SchoolID {
+				continue
+			}
+			if nameSimilarity(names[i], names[j]) >= threshold {
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[rj] = ri
+				}
+			}
+		}
+	}
+
+	// Partners are ordered oldest first, so the root of each group is
+	// reached first and groups keep that order
+	members := make(map[int][]int)
+	var roots []int
+	for i := range partners {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	data := school.PartnerDuplicatesData{Groups: []school.PartnerDuplicateGroup{}}
+	for _, root := range roots {
+		if len(members[root]) < 2 {
+			continue
+		}
+		group := school.PartnerDuplicateGroup{
+			SchoolID:       partners[root].SchoolID,
+			NormalizedName: names[root],
+		}
+		for _, i := range members[root] {
+			group.Partners = append(group.Partners, partners[i].ToPartner())
+		}
+		data.Groups = append(data.Groups, group)
+	}
+
+	return response.Success(constants.PartnerDuplicatesSuccess, data), nil
+}
+
+// MergePartners merges the duplicates into the survivor. Survivor fields
+// that are empty are filled from the first duplicate that has them; fields
+// the survivor already has are kept. Users of the duplicates move to the
+// survivor and the duplicates are deleted, all in one transaction, with a
+// partner_merges row per duplicate.
+func (s *schoolService) MergePartners(ctx context.Context, req school.MergePartnersRequest) (*school.MergePartnersResponse, error) {
+	survivor, err := s.getPartner(ctx, req.SurvivorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mergedBy *uuid.UUID
+	if actorID, ok := requestctx.UserID(ctx); ok {
+		mergedBy = &actorID
+	}
+
+	result := school.MergePartnersResult{FilledFields: []string{}}
+	var merges []school.PartnerMergeEntity
+	seen := make(map[uuid.UUID]bool)
+	now := s.clock.Now()
+
+	for _, id := range req.DuplicateIDs {
+		if id == survivor.ID {
+			return nil, ErrMergeSurvivorListed
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		duplicate, err := s.getPartner(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate.SchoolID != survivor.SchoolID {
+			return nil, ErrMergeAcrossSchools
+		}
+
+		filled := fillPartner(survivor, duplicate)
+		fields, err := json.Marshal(filled)
+		if err != nil {
+			return nil, err
+		}
+
+		merges = append(merges, school.PartnerMergeEntity{
+			ID:          s.ids.New(),
+			SurvivorID:  survivor.ID,
+			DuplicateID: duplicate.ID,
+			MergedBy:    mergedBy,
+			Fields:      string(fields),
+			CreatedAt:   now,
+		})
+		result.Merged = append(result.Merged, duplicate.ID)
+		result.FilledFields = append(result.FilledFields, filled...)
+	}
+
+	survivor.UpdatedAt = now
+	if err := s.repo.MergePartners(ctx, survivor, merges); err != nil {
+		return nil, err
+	}
+
+	for _, merge := range merges {
+		result.UsersMoved += merge.UsersMoved
+	}
+	result.Partner = survivor.ToPartner()
+	return response.Success(constants.PartnerMergeSuccess, result), nil
+}
+
+// getPartner loads a partner, mapping a missing one to ErrPartnerNotFound
+func (s *schoolService) getPartner(ctx context.Context, id uuid.UUID) (*school.PartnerEntity, error) {
+	partner, err := s.repo.GetPartnerByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartnerNotFound
+		}
+		return nil, err
+	}
+	return partner, nil
+}
+
+// fillPartner copies the fields survivor lacks from duplicate and returns
+// their JSON names. A contact email brings its verification state along.
+func fillPartner(survivor, duplicate *school.PartnerEntity) []string {
+	filled := []string{}
+	fill := func(name string, dst **string, src *string) {
+		if (*dst == nil || **dst == "") && src != nil && *src != "" {
+			*dst = src
+			filled = append(filled, name)
+		}
+	}
+
+	fill("website", &survivor.Website, duplicate.Website)
+	fill("description", &survivor.Description, duplicate.Description)
+	fill("address", &survivor.Address, duplicate.Address)
+	fill("contact_name", &survivor.ContactName, duplicate.ContactName)
+	fill("contact_person", &survivor.ContactPerson, duplicate.ContactPerson)
+
+	hadEmail := survivor.ContactEmail != nil && *survivor.ContactEmail != ""
+	fill("contact_email", &survivor.ContactEmail, duplicate.ContactEmail)
+	if !hadEmail && survivor.ContactEmail == duplicate.ContactEmail {
+		survivor.ContactEmailVerifiedAt = duplicate.ContactEmailVerifiedAt
+		survivor.ContactEmailFlaggedAt = duplicate.ContactEmailFlaggedAt
+	}
+
+	return filled
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/school"
+	"backend-service-internpro/internal/school/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// partnerTable holds partners in memory and records the last merge; other
+// methods are not used
+type partnerTable struct {
+	repository.SchoolRepository
+	partners map[uuid.UUID]school.PartnerEntity
+	order    []uuid.UUID
+	survivor *school.PartnerEntity
+	merges   []school.PartnerMergeEntity
+}
+
+func (r *partnerTable) add(p school.PartnerEntity) uuid.UUID {
+	if r.partners == nil {
+		r.partners = make(map[uuid.UUID]school.PartnerEntity)
+	}
+	p.ID = uuid.New()
+	r.partners[p.ID] = p
+	r.order = append(r.order, p.ID)
+	return p.ID
+}
+
+func (r *partnerTable) GetPartnerByID(_ context.Context, id uuid.UUID) (*school.PartnerEntity, error) {
+	p, ok := r.partners[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+func (r *partnerTable) ListPartners(_ context.Context, schoolID *uuid.UUID) ([]school.PartnerEntity, error) {
+	var partners []school.PartnerEntity
+	for _, id := range r.order {
+		if p := r.partners[id]; schoolID == nil || p.SchoolID == *schoolID {
+			partners = append(partners, p)
+		}
+	}
+	return partners, nil
+}
+
+func (r *partnerTable) MergePartners(_ context.Context, survivor *school.PartnerEntity, merges []school.PartnerMergeEntity) error {
+	r.survivor, r.merges = survivor, merges
+	return nil
+}
+
+func TestFindDuplicatePartners(t *testing.T) {
+	repo := &partnerTable{}
+	smk, sma := uuid.New(), uuid.New()
+	telkom := repo.add(school.PartnerEntity{SchoolID: smk, Name: "PT Telkom Indonesia"})
+	telkomTbk := repo.add(school.PartnerEntity{SchoolID: smk, Name: "Telkom Tbk"})
+	// One letter off is still a duplicate
+	telkomm := repo.add(school.PartnerEntity{SchoolID: smk, Name: "CV Telkomm"})
+	repo.add(school.PartnerEntity{SchoolID: smk, Name: "PT Astra International"})
+	// The same company at another school is not a duplicate
+	repo.add(school.PartnerEntity{SchoolID: sma, Name: "Telkom"})
+
+	res, err := NewSchoolService(repo).FindDuplicatePartners(context.Background(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := res.Data.(school.PartnerDuplicatesData).Groups
+	if len(groups) != 1 {
+		t.Fatalf("%d groups, want 1: %+v", len(groups), groups)
+	}
+	var ids []uuid.UUID
+	for _, p := range groups[0].Partners {
+		ids = append(ids, p.ID)
+	}
+	if want := []uuid.UUID{telkom, telkomTbk, telkomm}; !slices.Equal(ids, want) || groups[0].NormalizedName != "telkom" {
+		t.Errorf("group %q of %v, want \"telkom\" of %v", groups[0].NormalizedName, ids, want)
+	}
+}
+
+func TestMergePartnersWithConflictingContacts(t *testing.T) {
+	str := func(s string) *string { return &s }
+	verifiedAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	schoolID := uuid.New()
+	tests := []struct {
+		name     string
+		survivor school.PartnerEntity
+		// the survivor's contact email and verification after the merge
+		wantEmail    string
+		wantVerified *time.Time
+		wantFilled   []string
+	}{
+		{
+			name:       "survivor has a contact",
+			survivor:   school.PartnerEntity{Name: "PT Telkom", ContactName: str("Siti"), ContactEmail: str("siti@telkom.co.id")},
+			wantEmail:  "siti@telkom.co.id",
+			wantFilled: []string{"website", "address", "contact_person"},
+		},
+		{
+			name:         "survivor has no contact",
+			survivor:     school.PartnerEntity{Name: "PT Telkom", ContactEmail: str("")},
+			wantEmail:    "budi@telkom.co.id",
+			wantFilled:   []string{"website", "contact_name", "contact_email", "address", "contact_person"},
+			wantVerified: &verifiedAt,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &partnerTable{}
+			tt.survivor.SchoolID = schoolID
+			survivorID := repo.add(tt.survivor)
+			// Both duplicates offer a website; the first listed wins
+			first := repo.add(school.PartnerEntity{SchoolID: schoolID, Name: "Telkom Tbk", Website: str("https://telkom.co.id"),
+				ContactName: str("Budi"), ContactEmail: str("budi@telkom.co.id"), ContactEmailVerifiedAt: &verifiedAt})
+			second := repo.add(school.PartnerEntity{SchoolID: schoolID, Name: "Telkom Indonesia", Website: str("https://www.telkom.co.id"),
+				Address: str("Jl. Japati No. 1, Bandung"), ContactPerson: str("081234567890")})
+			now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+			svc := NewSchoolServiceWithConfig(repo, Config{Clock: clock.NewFake(now)})
+
+			res, err := svc.MergePartners(context.Background(), school.MergePartnersRequest{
+				SurvivorID:   survivorID,
+				DuplicateIDs: []uuid.UUID{first, second, first},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := res.Data.(school.MergePartnersResult)
+			if !slices.Equal(result.FilledFields, tt.wantFilled) {
+				t.Errorf("filled %v, want %v", result.FilledFields, tt.wantFilled)
+			}
+			if !slices.Equal(result.Merged, []uuid.UUID{first, second}) {
+				t.Errorf("merged %v, want %v", result.Merged, []uuid.UUID{first, second})
+			}
+
+			saved := repo.survivor
+			if saved == nil || saved.ID != survivorID {
+				t.Fatalf("saved %+v, want the survivor", saved)
+			}
+			if saved.Name != "PT Telkom" {
+				t.Errorf("survivor renamed to %q", saved.Name)
+			}
+			if got := *saved.Website; got != "https://telkom.co.id" {
+				t.Errorf("website = %q, want the first duplicate's", got)
+			}
+			if *saved.ContactEmail != tt.wantEmail || !reflect.DeepEqual(saved.ContactEmailVerifiedAt, tt.wantVerified) {
+				t.Errorf("contact email %q verified at %v, want %q verified at %v",
+					*saved.ContactEmail, saved.ContactEmailVerifiedAt, tt.wantEmail, tt.wantVerified)
+			}
+			if !saved.UpdatedAt.Equal(now) {
+				t.Errorf("updated at %v, want %v", saved.UpdatedAt, now)
+			}
+
+			// Each merge records the fields its duplicate filled
+			var recorded []string
+			for _, m := range repo.merges {
+				var fields []string
+				if err := json.Unmarshal([]byte(m.Fields), &fields); err != nil {
+					t.Fatal(err)
+				}
+				recorded = append(recorded, fields...)
+			}
+			if !slices.Equal(recorded, tt.wantFilled) {
+				t.Errorf("merges recorded %v, want %v", recorded, tt.wantFilled)
+			}
+		})
+	}
+}
+
+func TestMergePartnersRejects(t *testing.T) {
+	repo := &partnerTable{}
+	schoolID := uuid.New()
+	survivor := repo.add(school.PartnerEntity{SchoolID: schoolID, Name: "PT Telkom"})
+	elsewhere := repo.add(school.PartnerEntity{SchoolID: uuid.New(), Name: "Telkom"})
+	svc := NewSchoolService(repo)
+
+	tests := []struct {
+		name       string
+		duplicates []uuid.UUID
+		want       error
+	}{
+		{"the survivor as a duplicate", []uuid.UUID{survivor}, ErrMergeSurvivorListed},
+		{"a partner of another school", []uuid.UUID{elsewhere}, ErrMergeAcrossSchools},
+		{"a missing partner", []uuid.UUID{uuid.New()}, ErrPartnerNotFound},
+	}
+	for _, tt := range tests {
+		_, err := svc.MergePartners(context.Background(), school.MergePartnersRequest{SurvivorID: survivor, DuplicateIDs: tt.duplicates})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("merging %s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if repo.survivor != nil {
+		t.Error("a refused merge was saved")
+	}
+}
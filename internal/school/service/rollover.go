@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/school"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSchoolNotFound is returned when the school does not exist
+	ErrSchoolNotFound = errors.New("school not found")
+	// ErrSchoolModified is returned when an update was made against a
+	// version of the school that another write has since replaced
+	ErrSchoolModified = errors.New("school changed since it was read")
+	// ErrMajorityModified is ErrSchoolModified for majorities
+	ErrMajorityModified = errors.New("majority changed since it was read")
+	// ErrClassModified is ErrSchoolModified for classes
+	ErrClassModified = errors.New("class changed since it was read")
+	// ErrPartnerModified is ErrSchoolModified for partners
+	ErrPartnerModified = errors.New("partner changed since it was read")
+	// ErrRolloverSameYear is returned when a rollover copies a year onto itself
+	ErrRolloverSameYear = errors.New("source and target academic years are the same")
+	// ErrRolloverNoClasses is returned when the source year has no classes
+	ErrRolloverNoClasses = errors.New("source academic year has no classes")
+)
+
+// RolloverClasses copies the classes of schoolID in req.FromYear into
+// req.ToYear. Classes keep their majority; the homeroom teacher is copied
+// only when asked. Classes the target year already has are skipped, and
+// with promote naming the final grade graduates. Nothing is written on a
+// dry run, otherwise every class is created in one transaction.
+func (s *schoolService) RolloverClasses(ctx context.Context, schoolID uuid.UUID, req school.ClassRolloverRequest) (*school.ClassRolloverResponse, error) {
+	if req.FromYear == req.ToYear {
+		return nil, ErrRolloverSameYear
+	}
+	if req.Naming == "" {
+		req.Naming = school.RolloverPromote
+	}
+
+	if _, err := s.repo.GetByID(ctx, schoolID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSchoolNotFound
+		}
+		return nil, err
+	}
+
+	sources, err := s.repo.GetClassesByYear(ctx, schoolID, req.FromYear)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, ErrRolloverNoClasses
+	}
+
+	targets, err := s.repo.GetClassesByYear(ctx, schoolID, req.ToYear)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]uuid.UUID, len(targets))
+	for _, c := range targets {
+		existing[classKey(c.Name)] = c.ID
+	}
+
+	finalGrade := req.FinalGrade
+	if req.Naming == school.RolloverPromote && finalGrade == 0 {
+		finalGrade = defaultFinalGrade(sources)
+	}
+
+	result := school.ClassRolloverResult{
+		FromYear: req.FromYear,
+		ToYear:   req.ToYear,
+		DryRun:   req.DryRun,
+		Classes:  make([]school.ClassRolloverItem, 0, len(sources)),
+	}
+	var creates []school.ClassEntity
+	now := s.clock.Now()
+
+	for _, src := range sources {
+		item := school.ClassRolloverItem{SourceID: src.ID, SourceName: src.Name}
+
+		item.TargetName = src.Name
+		if req.Naming == school.RolloverPromote {
+			promoted, graduated, ok := promoteClassName(src.Name, finalGrade)
+			switch {
+			case !ok:
+				item.TargetName = ""
+				item.Status = school.RolloverUnrecognized
+			case graduated:
+				item.TargetName = ""
+				item.Status = school.RolloverGraduated
+			default:
+				item.TargetName = promoted
+			}
+		}
+
+		if item.Status == "" {
+			if id, ok := existing[classKey(item.TargetName)]; ok {
+				item.TargetID = &id
+				item.Status = school.RolloverDuplicate
+			}
+		}
+
+		if item.Status == "" {
+			entity := school.ClassEntity{
+				ID:           s.ids.New(),
+				SchoolID:     src.SchoolID,
+				MajorityID:   src.MajorityID,
+				Name:         item.TargetName,
+				AcademicYear: &req.ToYear,
+				Description:  src.Description,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			}
+			if req.KeepHomeroomTeacher {
+				entity.HomeroomTeacherID = src.HomeroomTeacherID
+			}
+			creates = append(creates, entity)
+			// Two source classes promoted to the same name create it once
+			existing[classKey(item.TargetName)] = entity.ID
+
+			item.TargetID = &entity.ID
+			item.Status = school.RolloverCreated
+			if req.DryRun {
+				item.Status = school.RolloverPlanned
+			}
+			result.Created++
+		} else {
+			result.Skipped++
+		}
+
+		result.Classes = append(result.Classes, item)
+	}
+
+	if req.DryRun {
+		return response.Success(constants.ClassRolloverDryRun, result), nil
+	}
+
+	if len(creates) > 0 {
+		if err := s.repo.CreateClasses(ctx, creates); err != nil {
+			return nil, err
+		}
+	}
+
+	return response.Success(constants.ClassRolloverSuccess, result), nil
+}
+
+// classKey compares class names ignoring case and surrounding spaces
+func classKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// defaultFinalGrade is the last grade of the school level of the highest
+// grade among classes: 6 for SD, 9 for SMP and 12 for SMA/SMK. A school
+// that only has grade X classes so far still promotes them to XI.
+func defaultFinalGrade(classes []school.ClassEntity) int {
+	highest := 0
+	for _, c := range classes {
+		if g, ok := parseClassGrade(c.Name); ok && g.grade > highest {
+			highest = g.grade
+		}
+	}
+	switch {
+	case highest <= 6:
+		return 6
+	case highest <= 9:
+		return 9
+	}
+	return maxGrade
+}
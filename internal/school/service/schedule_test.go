@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"backend-service-internpro/internal/school"
+	"backend-service-internpro/internal/school/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// scheduleRepo knows every class and the periods on one day; other
+// methods are not used
+type scheduleRepo struct {
+	repository.SchoolRepository
+	periods []school.ClassScheduleEntity
+}
+
+func (r *scheduleRepo) GetClassByID(_ context.Context, id uuid.UUID) (*school.ClassEntity, error) {
+	return &school.ClassEntity{ID: id}, nil
+}
+
+func (r *scheduleRepo) GetClassScheduleByID(_ context.Context, id uuid.UUID) (*school.ClassScheduleEntity, error) {
+	for _, period := range r.periods {
+		if period.ID == id {
+			return &period, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *scheduleRepo) GetSchedulesOnDay(context.Context, int, uuid.UUID, *uuid.UUID) ([]school.ClassScheduleEntity, error) {
+	return r.periods, nil
+}
+
+func (r *scheduleRepo) CreateClassSchedule(context.Context, *school.ClassScheduleEntity) error {
+	return nil
+}
+
+func (r *scheduleRepo) UpdateClassSchedule(context.Context, *school.ClassScheduleEntity) error {
+	return nil
+}
+
+func TestScheduleConflicts(t *testing.T) {
+	classID, otherClassID := uuid.New(), uuid.New()
+	teacherID, otherTeacherID := uuid.New(), uuid.New()
+	// Monday 08:00-09:30 of the class and 10:00-11:00 of another class with the teacher
+	repo := &scheduleRepo{periods: []school.ClassScheduleEntity{
+		{ID: uuid.New(), ClassID: classID, DayOfWeek: 1, StartMinute: 8 * 60, EndMinute: 9*60 + 30},
+		{ID: uuid.New(), ClassID: otherClassID, DayOfWeek: 1, StartMinute: 10 * 60, EndMinute: 11 * 60, TeacherID: &teacherID},
+	}}
+	s := NewSchoolService(repo)
+
+	tests := []struct {
+		name       string
+		start, end string
+		teacherID  *uuid.UUID
+		want       string
+	}{
+		{"free period", "12:00", "13:00", &teacherID, ""},
+		{"back to back with the class", "09:30", "10:00", nil, ""},
+		{"overlaps the class", "09:00", "10:00", nil, "schedule overlaps with another period of this class"},
+		{"teacher busy in another class", "10:30", "11:30", &teacherID, "teacher is already scheduled at this time"},
+		{"another teacher in the same slot", "10:30", "11:30", &otherTeacherID, ""},
+		{"no teacher in the same slot", "10:30", "11:30", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := school.ClassScheduleRequest{DayOfWeek: 1, StartTime: tt.start, EndTime: tt.end, Subject: "Matematika", TeacherID: tt.teacherID}
+			_, err := s.CreateClassSchedule(context.Background(), classID, req)
+			if got := errString(err); got != tt.want {
+				t.Errorf("err = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Moving a period within its own slot does not conflict with itself
+	own := repo.periods[0]
+	req := school.ClassScheduleRequest{DayOfWeek: 1, StartTime: "08:15", EndTime: "09:15", Subject: "Matematika"}
+	if _, err := s.UpdateClassSchedule(context.Background(), classID, own.ID, req); err != nil {
+		t.Errorf("moving a period within its slot: %v", err)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
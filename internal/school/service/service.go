@@ -3,13 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"backend-service-internpro/internal/pkg/clock"
 	"backend-service-internpro/internal/pkg/constants"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/notifier"
 	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/validator"
 	"backend-service-internpro/internal/school"
 	"backend-service-internpro/internal/school/repository"
 )
@@ -19,50 +27,117 @@ type SchoolService interface {
 	CreateSchool(ctx context.Context, req school.CreateSchoolRequest) (*school.SchoolResponse, error)
 	GetSchoolByID(ctx context.Context, id uuid.UUID) (*school.SchoolResponse, error)
 	GetAllSchools(ctx context.Context, params school.QueryParams) (*school.PaginatedSchoolsResponse, error)
-	UpdateSchool(ctx context.Context, id uuid.UUID, req school.UpdateSchoolRequest) (*school.SchoolResponse, error)
+	// UpdateSchool writes req over the school if its version is one of
+	// versions, or any version when versions is nil, and returns
+	// ErrSchoolModified otherwise
+	UpdateSchool(ctx context.Context, id uuid.UUID, req school.UpdateSchoolRequest, versions []int64) (*school.SchoolResponse, error)
 	DeleteSchool(ctx context.Context, id uuid.UUID) (*school.BasicResponse, error)
 
 	// Majority methods
 	CreateMajority(ctx context.Context, req school.CreateMajorityRequest) (*school.MajorityResponse, error)
 	GetMajorityByID(ctx context.Context, id uuid.UUID) (*school.MajorityResponse, error)
 	GetAllMajorities(ctx context.Context, params school.QueryParams) (*school.PaginatedMajoritiesResponse, error)
-	UpdateMajority(ctx context.Context, id uuid.UUID, req school.UpdateMajorityRequest) (*school.MajorityResponse, error)
+	// UpdateMajority writes req over the majority like UpdateSchool,
+	// returning ErrMajorityModified for a version not in versions
+	UpdateMajority(ctx context.Context, id uuid.UUID, req school.UpdateMajorityRequest, versions []int64) (*school.MajorityResponse, error)
 	DeleteMajority(ctx context.Context, id uuid.UUID) (*school.BasicResponse, error)
 
 	// Class methods
 	CreateClass(ctx context.Context, req school.CreateClassRequest) (*school.ClassResponse, error)
 	GetClassByID(ctx context.Context, id uuid.UUID) (*school.ClassResponse, error)
 	GetAllClasses(ctx context.Context, params school.QueryParams) (*school.PaginatedClassesResponse, error)
-	UpdateClass(ctx context.Context, id uuid.UUID, req school.UpdateClassRequest) (*school.ClassResponse, error)
+	// UpdateClass writes req over the class like UpdateSchool, returning
+	// ErrClassModified for a version not in versions
+	UpdateClass(ctx context.Context, id uuid.UUID, req school.UpdateClassRequest, versions []int64) (*school.ClassResponse, error)
 	DeleteClass(ctx context.Context, id uuid.UUID) (*school.BasicResponse, error)
+	// ImportMajorities creates the majorities listed in a CSV file for a school
+	ImportMajorities(ctx context.Context, schoolID uuid.UUID, file io.Reader) (*school.MajorityImportResponse, error)
+	// RolloverClasses copies the classes of a school from one academic year to another
+	RolloverClasses(ctx context.Context, schoolID uuid.UUID, req school.ClassRolloverRequest) (*school.ClassRolloverResponse, error)
+
+	// Class schedule methods
+	GetClassSchedule(ctx context.Context, classID uuid.UUID) (*school.ClassScheduleListResponse, error)
+	CreateClassSchedule(ctx context.Context, classID uuid.UUID, req school.ClassScheduleRequest) (*school.ClassScheduleResponse, error)
+	UpdateClassSchedule(ctx context.Context, classID, scheduleID uuid.UUID, req school.ClassScheduleRequest) (*school.ClassScheduleResponse, error)
+	DeleteClassSchedule(ctx context.Context, classID, scheduleID uuid.UUID) (*school.BasicResponse, error)
+	GetTeacherSchedule(ctx context.Context, teacherID uuid.UUID) (*school.ClassScheduleListResponse, error)
 
 	// Partner methods
 	CreatePartner(ctx context.Context, req school.CreatePartnerRequest) (*school.PartnerResponse, error)
 	GetPartnerByID(ctx context.Context, id uuid.UUID) (*school.PartnerResponse, error)
 	GetAllPartners(ctx context.Context, params school.QueryParams) (*school.PaginatedPartnersResponse, error)
-	UpdatePartner(ctx context.Context, id uuid.UUID, req school.UpdatePartnerRequest) (*school.PartnerResponse, error)
+	// UpdatePartner writes req over the partner like UpdateSchool,
+	// returning ErrPartnerModified for a version not in versions
+	UpdatePartner(ctx context.Context, id uuid.UUID, req school.UpdatePartnerRequest, versions []int64) (*school.PartnerResponse, error)
 	DeletePartner(ctx context.Context, id uuid.UUID) (*school.BasicResponse, error)
+
+	// Partner contact verification methods
+	SendContactVerification(ctx context.Context, partnerID uuid.UUID) (*school.BasicResponse, error)
+	VerifyContact(ctx context.Context, token string) (*school.BasicResponse, error)
+	// FlagStaleContacts flags partner contacts unverified for longer than maxAge
+	FlagStaleContacts(ctx context.Context, maxAge time.Duration) (int64, error)
+
+	// Partner merge methods
+	FindDuplicatePartners(ctx context.Context, schoolID *uuid.UUID, threshold float64) (*school.PartnerDuplicatesResponse, error)
+	MergePartners(ctx context.Context, req school.MergePartnersRequest) (*school.MergePartnersResponse, error)
+}
+
+// Config holds optional school service settings
+type Config struct {
+	// Notifier delivers contact verification emails; when nil none can be sent
+	Notifier *notifier.Dispatcher
+	// SigningKey signs contact verification links
+	SigningKey []byte
+	// OldSigningKeys still verify links signed before SigningKey was rotated
+	OldSigningKeys [][]byte
+	// PublicURL is the externally reachable base URL of the API, prefixed to
+	// emailed links
+	PublicURL string
+	// ContactVerifyTTL is how long a verification link stays valid
+	ContactVerifyTTL time.Duration
+	// Clock and IDs default to the wall clock and random UUIDs; tests
+	// replace them to get predictable timestamps and IDs
+	Clock clock.Clock
+	IDs   idgen.Generator
 }
 
 // schoolService implements SchoolService
 type schoolService struct {
-	repo repository.SchoolRepository
+	repo      repository.SchoolRepository
+	validator *validator.Validator
+	notifier  *notifier.Dispatcher
+	cfg       Config
+	clock     clock.Clock
+	ids       idgen.Generator
 }
 
 // NewSchoolService creates a new school service
 func NewSchoolService(repo repository.SchoolRepository) SchoolService {
+	return NewSchoolServiceWithConfig(repo, Config{})
+}
+
+// NewSchoolServiceWithConfig creates a new school service with custom settings
+func NewSchoolServiceWithConfig(repo repository.SchoolRepository, cfg Config) SchoolService {
+	if cfg.ContactVerifyTTL <= 0 {
+		cfg.ContactVerifyTTL = DefaultContactVerifyTTL
+	}
 	return &schoolService{
-		repo: repo,
+		repo:      repo,
+		validator: validator.New(),
+		notifier:  cfg.Notifier,
+		cfg:       cfg,
+		clock:     clock.OrReal(cfg.Clock),
+		ids:       idgen.OrRandom(cfg.IDs),
 	}
 }
 
 // School methods
 func (s *schoolService) CreateSchool(ctx context.Context, req school.CreateSchoolRequest) (*school.SchoolResponse, error) {
 	entity := &school.SchoolEntity{
-		ID:        uuid.New(),
+		ID:        s.ids.New(),
 		Name:      req.Name,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
 	}
 
 	if req.Address != "" {
@@ -70,18 +145,27 @@ func (s *schoolService) CreateSchool(ctx context.Context, req school.CreateSchoo
 	}
 
 	if req.Domain != "" {
+		if err := s.checkDomain(req.Domain); err != nil {
+			return nil, err
+		}
+
 		// Check if domain already exists
 		existing, err := s.repo.GetByDomain(ctx, req.Domain)
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, err
 		}
 		if existing != nil {
-			return nil, errors.New("domain already exists")
+			return nil, apperrors.Conflict("domain already exists")
 		}
 		entity.Domain = &req.Domain
 	}
 
+	// The pre-check above is racy; the unique index decides between
+	// concurrent claims of the same domain
 	if err := s.repo.Create(ctx, entity); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, apperrors.Conflict("domain already exists")
+		}
 		return nil, err
 	}
 
@@ -136,7 +220,7 @@ func (s *schoolService) GetAllSchools(ctx context.Context, params school.QueryPa
 	return response.Success(constants.SchoolListSuccess, data), nil
 }
 
-func (s *schoolService) UpdateSchool(ctx context.Context, id uuid.UUID, req school.UpdateSchoolRequest) (*school.SchoolResponse, error) {
+func (s *schoolService) UpdateSchool(ctx context.Context, id uuid.UUID, req school.UpdateSchoolRequest, versions []int64) (*school.SchoolResponse, error) {
 	entity, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -144,6 +228,9 @@ func (s *schoolService) UpdateSchool(ctx context.Context, id uuid.UUID, req scho
 		}
 		return nil, err
 	}
+	if versions != nil && !slices.Contains(versions, entity.Version) {
+		return nil, ErrSchoolModified
+	}
 
 	// Update fields if provided
 	if req.Name != "" {
@@ -153,20 +240,35 @@ func (s *schoolService) UpdateSchool(ctx context.Context, id uuid.UUID, req scho
 		entity.Address = &req.Address
 	}
 	if req.Domain != "" {
+		if err := s.checkDomain(req.Domain); err != nil {
+			return nil, err
+		}
+
 		// Check if domain already exists and belongs to different school
 		existing, err := s.repo.GetByDomain(ctx, req.Domain)
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, err
 		}
 		if existing != nil && existing.ID != id {
-			return nil, errors.New("domain already exists")
+			return nil, apperrors.Conflict("domain already exists")
 		}
 		entity.Domain = &req.Domain
 	}
 
-	entity.UpdatedAt = time.Now()
+	entity.UpdatedAt = s.clock.Now()
 
+	// The update only applies to the version read above, so a write in
+	// between fails instead of being overwritten
 	if err := s.repo.Update(ctx, entity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("school not found")
+		}
+		if errors.Is(err, repository.ErrStale) {
+			return nil, ErrSchoolModified
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, apperrors.Conflict("domain already exists")
+		}
 		return nil, err
 	}
 
@@ -190,6 +292,12 @@ func (s *schoolService) DeleteSchool(ctx context.Context, id uuid.UUID) (*school
 	return response.SuccessWithoutData(constants.SchoolDeleteSuccess), nil
 }
 
+// checkDomain applies the domain normalization rule, report-only until enforced
+func (s *schoolService) checkDomain(domain string) error {
+	ok, msg := s.validator.IsValidDomain(domain)
+	return validator.Check(validator.RuleSchoolDomain, ok, msg)
+}
+
 // Majority methods
 func (s *schoolService) CreateMajority(ctx context.Context, req school.CreateMajorityRequest) (*school.MajorityResponse, error) {
 	// Verify school exists
@@ -202,11 +310,11 @@ func (s *schoolService) CreateMajority(ctx context.Context, req school.CreateMaj
 	}
 
 	entity := &school.MajorityEntity{
-		ID:        uuid.New(),
+		ID:        s.ids.New(),
 		SchoolID:  req.SchoolID,
 		Name:      req.Name,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
 	}
 
 	if req.Description != "" {
@@ -276,7 +384,7 @@ func (s *schoolService) GetAllMajorities(ctx context.Context, params school.Quer
 	return response.Success(constants.MajorityListSuccess, data), nil
 }
 
-func (s *schoolService) UpdateMajority(ctx context.Context, id uuid.UUID, req school.UpdateMajorityRequest) (*school.MajorityResponse, error) {
+func (s *schoolService) UpdateMajority(ctx context.Context, id uuid.UUID, req school.UpdateMajorityRequest, versions []int64) (*school.MajorityResponse, error) {
 	entity, err := s.repo.GetMajorityByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -284,6 +392,9 @@ func (s *schoolService) UpdateMajority(ctx context.Context, id uuid.UUID, req sc
 		}
 		return nil, err
 	}
+	if versions != nil && !slices.Contains(versions, entity.Version) {
+		return nil, ErrMajorityModified
+	}
 
 	// Update fields if provided
 	if req.SchoolID != uuid.Nil {
@@ -304,9 +415,15 @@ func (s *schoolService) UpdateMajority(ctx context.Context, id uuid.UUID, req sc
 		entity.Description = &req.Description
 	}
 
-	entity.UpdatedAt = time.Now()
+	entity.UpdatedAt = s.clock.Now()
 
 	if err := s.repo.UpdateMajority(ctx, entity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("majority not found")
+		}
+		if errors.Is(err, repository.ErrStale) {
+			return nil, ErrMajorityModified
+		}
 		return nil, err
 	}
 
@@ -351,14 +468,18 @@ func (s *schoolService) CreateClass(ctx context.Context, req school.CreateClassR
 	}
 
 	entity := &school.ClassEntity{
-		ID:         uuid.New(),
-		SchoolID:   req.SchoolID,
-		MajorityID: req.MajorityID,
-		Name:       req.Name,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:                s.ids.New(),
+		SchoolID:          req.SchoolID,
+		MajorityID:        req.MajorityID,
+		HomeroomTeacherID: req.HomeroomTeacherID,
+		Name:              req.Name,
+		CreatedAt:         s.clock.Now(),
+		UpdatedAt:         s.clock.Now(),
 	}
 
+	if req.AcademicYear != "" {
+		entity.AcademicYear = &req.AcademicYear
+	}
 	if req.Description != "" {
 		entity.Description = &req.Description
 	}
@@ -434,7 +555,7 @@ func (s *schoolService) GetAllClasses(ctx context.Context, params school.QueryPa
 	return response.Success(constants.ClassGetAllSuccess, data), nil
 }
 
-func (s *schoolService) UpdateClass(ctx context.Context, id uuid.UUID, req school.UpdateClassRequest) (*school.ClassResponse, error) {
+func (s *schoolService) UpdateClass(ctx context.Context, id uuid.UUID, req school.UpdateClassRequest, versions []int64) (*school.ClassResponse, error) {
 	entity, err := s.repo.GetClassByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -442,6 +563,9 @@ func (s *schoolService) UpdateClass(ctx context.Context, id uuid.UUID, req schoo
 		}
 		return nil, err
 	}
+	if versions != nil && !slices.Contains(versions, entity.Version) {
+		return nil, ErrClassModified
+	}
 
 	// Update fields if provided
 	if req.SchoolID != uuid.Nil {
@@ -466,16 +590,28 @@ func (s *schoolService) UpdateClass(ctx context.Context, id uuid.UUID, req schoo
 		}
 		entity.MajorityID = req.MajorityID
 	}
+	if req.HomeroomTeacherID != nil {
+		entity.HomeroomTeacherID = req.HomeroomTeacherID
+	}
 	if req.Name != "" {
 		entity.Name = req.Name
 	}
+	if req.AcademicYear != "" {
+		entity.AcademicYear = &req.AcademicYear
+	}
 	if req.Description != "" {
 		entity.Description = &req.Description
 	}
 
-	entity.UpdatedAt = time.Now()
+	entity.UpdatedAt = s.clock.Now()
 
 	if err := s.repo.UpdateClass(ctx, entity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("class not found")
+		}
+		if errors.Is(err, repository.ErrStale) {
+			return nil, ErrClassModified
+		}
 		return nil, err
 	}
 
@@ -499,6 +635,174 @@ func (s *schoolService) DeleteClass(ctx context.Context, id uuid.UUID) (*school.
 	return response.SuccessWithoutData(constants.ClassDeleteSuccess), nil
 }
 
+// Class schedule methods
+func (s *schoolService) GetClassSchedule(ctx context.Context, classID uuid.UUID) (*school.ClassScheduleListResponse, error) {
+	if _, err := s.repo.GetClassByID(ctx, classID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("class not found")
+		}
+		return nil, err
+	}
+
+	entities, err := s.repo.GetClassSchedules(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Success(constants.ClassScheduleListSuccess, toScheduleList(entities)), nil
+}
+
+func (s *schoolService) CreateClassSchedule(ctx context.Context, classID uuid.UUID, req school.ClassScheduleRequest) (*school.ClassScheduleResponse, error) {
+	if _, err := s.repo.GetClassByID(ctx, classID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("class not found")
+		}
+		return nil, err
+	}
+
+	entity := &school.ClassScheduleEntity{
+		ID:        s.ids.New(),
+		ClassID:   classID,
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+	if err := applyScheduleRequest(entity, req); err != nil {
+		return nil, err
+	}
+	if err := s.checkScheduleConflicts(ctx, entity); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateClassSchedule(ctx, entity); err != nil {
+		return nil, err
+	}
+
+	return response.Success(constants.ClassScheduleCreateSuccess, entity.ToClassSchedule()), nil
+}
+
+func (s *schoolService) UpdateClassSchedule(ctx context.Context, classID, scheduleID uuid.UUID, req school.ClassScheduleRequest) (*school.ClassScheduleResponse, error) {
+	entity, err := s.getClassSchedule(ctx, classID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyScheduleRequest(entity, req); err != nil {
+		return nil, err
+	}
+	if err := s.checkScheduleConflicts(ctx, entity); err != nil {
+		return nil, err
+	}
+	entity.UpdatedAt = s.clock.Now()
+
+	if err := s.repo.UpdateClassSchedule(ctx, entity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("schedule not found")
+		}
+		return nil, err
+	}
+
+	return response.Success(constants.ClassScheduleUpdateSuccess, entity.ToClassSchedule()), nil
+}
+
+func (s *schoolService) DeleteClassSchedule(ctx context.Context, classID, scheduleID uuid.UUID) (*school.BasicResponse, error) {
+	if _, err := s.getClassSchedule(ctx, classID, scheduleID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteClassSchedule(ctx, scheduleID); err != nil {
+		return nil, err
+	}
+
+	return response.SuccessWithoutData(constants.ClassScheduleDeleteSuccess), nil
+}
+
+func (s *schoolService) GetTeacherSchedule(ctx context.Context, teacherID uuid.UUID) (*school.ClassScheduleListResponse, error) {
+	entities, err := s.repo.GetTeacherSchedules(ctx, teacherID)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Success(constants.ClassScheduleListSuccess, toScheduleList(entities)), nil
+}
+
+// getClassSchedule loads a period and makes sure it belongs to the class
+func (s *schoolService) getClassSchedule(ctx context.Context, classID, scheduleID uuid.UUID) (*school.ClassScheduleEntity, error) {
+	entity, err := s.repo.GetClassScheduleByID(ctx, scheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("schedule not found")
+		}
+		return nil, err
+	}
+	if entity.ClassID != classID {
+		return nil, errors.New("schedule not found")
+	}
+	return entity, nil
+}
+
+// checkScheduleConflicts rejects periods that overlap another period of the
+// same class or another period taught by the same teacher
+func (s *schoolService) checkScheduleConflicts(ctx context.Context, entity *school.ClassScheduleEntity) error {
+	sameDay, err := s.repo.GetSchedulesOnDay(ctx, entity.DayOfWeek, entity.ClassID, entity.TeacherID)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range sameDay {
+		if other.ID == entity.ID || !school.PeriodsOverlap(entity.StartMinute, entity.EndMinute, other.StartMinute, other.EndMinute) {
+			continue
+		}
+		if other.ClassID == entity.ClassID {
+			return errors.New("schedule overlaps with another period of this class")
+		}
+		if entity.TeacherID != nil && other.TeacherID != nil && *other.TeacherID == *entity.TeacherID {
+			return errors.New("teacher is already scheduled at this time")
+		}
+	}
+	return nil
+}
+
+// applyScheduleRequest validates the request and copies it onto entity
+func applyScheduleRequest(entity *school.ClassScheduleEntity, req school.ClassScheduleRequest) error {
+	if req.DayOfWeek < 1 || req.DayOfWeek > 7 {
+		return apperrors.ValidationFailed("day of week must be between 1 (Monday) and 7 (Sunday)")
+	}
+	if strings.TrimSpace(req.Subject) == "" {
+		return apperrors.ValidationFailed("subject is required")
+	}
+
+	start, err := school.ParseClockTime(req.StartTime)
+	if err != nil {
+		return apperrors.ValidationFailed(err.Error())
+	}
+	end, err := school.ParseClockTime(req.EndTime)
+	if err != nil {
+		return apperrors.ValidationFailed(err.Error())
+	}
+	if end <= start {
+		return apperrors.ValidationFailed("end time must be after start time")
+	}
+
+	entity.DayOfWeek = req.DayOfWeek
+	entity.StartMinute = start
+	entity.EndMinute = end
+	entity.Subject = strings.TrimSpace(req.Subject)
+	entity.TeacherID = req.TeacherID
+	entity.Room = nil
+	if req.Room != "" {
+		entity.Room = &req.Room
+	}
+	return nil
+}
+
+func toScheduleList(entities []school.ClassScheduleEntity) school.ClassScheduleListData {
+	schedules := make([]school.ClassSchedule, len(entities))
+	for i, entity := range entities {
+		schedules[i] = entity.ToClassSchedule()
+	}
+	return school.ClassScheduleListData{Schedules: schedules}
+}
+
 // Partner methods
 func (s *schoolService) CreatePartner(ctx context.Context, req school.CreatePartnerRequest) (*school.PartnerResponse, error) {
 	// Verify school exists
@@ -511,11 +815,11 @@ func (s *schoolService) CreatePartner(ctx context.Context, req school.CreatePart
 	}
 
 	entity := &school.PartnerEntity{
-		ID:        uuid.New(),
+		ID:        s.ids.New(),
 		SchoolID:  req.SchoolID,
 		Name:      req.Name,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
 	}
 
 	if req.Website != "" {
@@ -600,7 +904,7 @@ func (s *schoolService) GetAllPartners(ctx context.Context, params school.QueryP
 	return response.Success(constants.PartnerGetAllSuccess, data), nil
 }
 
-func (s *schoolService) UpdatePartner(ctx context.Context, id uuid.UUID, req school.UpdatePartnerRequest) (*school.PartnerResponse, error) {
+func (s *schoolService) UpdatePartner(ctx context.Context, id uuid.UUID, req school.UpdatePartnerRequest, versions []int64) (*school.PartnerResponse, error) {
 	entity, err := s.repo.GetPartnerByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -608,6 +912,9 @@ func (s *schoolService) UpdatePartner(ctx context.Context, id uuid.UUID, req sch
 		}
 		return nil, err
 	}
+	if versions != nil && !slices.Contains(versions, entity.Version) {
+		return nil, ErrPartnerModified
+	}
 
 	// Update fields if provided
 	if req.SchoolID != uuid.Nil {
@@ -639,16 +946,33 @@ func (s *schoolService) UpdatePartner(ctx context.Context, id uuid.UUID, req sch
 	if req.ContactPerson != "" {
 		entity.ContactPerson = &req.ContactPerson
 	}
+	contactChanged := false
 	if req.ContactEmail != "" {
+		contactChanged = entity.ContactEmail == nil || *entity.ContactEmail != req.ContactEmail
 		entity.ContactEmail = &req.ContactEmail
 	}
 
-	entity.UpdatedAt = time.Now()
+	entity.UpdatedAt = s.clock.Now()
 
 	if err := s.repo.UpdatePartner(ctx, entity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("partner not found")
+		}
+		if errors.Is(err, repository.ErrStale) {
+			return nil, ErrPartnerModified
+		}
 		return nil, err
 	}
 
+	// A new address has not been verified yet
+	if contactChanged && (entity.ContactEmailVerifiedAt != nil || entity.ContactEmailFlaggedAt != nil) {
+		if err := s.repo.SetPartnerContactVerified(ctx, entity.ID, nil); err != nil {
+			return nil, err
+		}
+		entity.ContactEmailVerifiedAt = nil
+		entity.ContactEmailFlaggedAt = nil
+	}
+
 	result := entity.ToPartner()
 	return response.Success(constants.PartnerUpdateSuccess, result), nil
 }
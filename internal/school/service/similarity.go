@@ -0,0 +1,81 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// legalForms are company forms left out when comparing partner names, so
+// "PT. Telkom" and "Telkom Tbk" compare as "telkom"
+var legalForms = map[string]bool{
+	"pt": true, "cv": true, "ud": true, "pd": true, "fa": true, "firma": true,
+	"tbk": true, "persero": true, "perum": true, "koperasi": true,
+	"ltd": true, "inc": true, "co": true, "corp": true, "llc": true,
+}
+
+// countrySuffix is dropped from the end of a name; many companies register
+// both with and without it
+const countrySuffix = "indonesia"
+
+// normalizePartnerName lowercases name, removes punctuation and legal forms
+// and collapses spaces. Dots and apostrophes are removed rather than
+// replaced so "P.T." reads as "pt".
+func normalizePartnerName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == '.' || r == '\'':
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	words := make([]string, 0, 4)
+	for _, w := range strings.Fields(b.String()) {
+		if !legalForms[w] {
+			words = append(words, w)
+		}
+	}
+	if len(words) > 1 && words[len(words)-1] == countrySuffix {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// nameSimilarity is 1 minus the edit distance of a and b relative to the
+// longer one: 1 for equal names, 0 for nothing in common
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein counts the single rune insertions, deletions and
+// substitutions turning a into b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
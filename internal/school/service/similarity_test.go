@@ -0,0 +1,53 @@
+package service
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizePartnerName(t *testing.T) {
+	tests := map[string]string{
+		"PT. Telkom Indonesia":               "telkom",
+		"P.T. Telkom Indonesia Tbk":          "telkom",
+		"Telkom Tbk":                         "telkom",
+		"CV Maju-Jaya":                       "maju jaya",
+		"  Toko   Sumber  Rejeki  ":          "toko sumber rejeki",
+		"PT Bank Rakyat Indonesia (Persero)": "bank rakyat",
+		"Gojek's Academy":                    "gojeks academy",
+		// A name that is only the country keeps it
+		"Indonesia":          "indonesia",
+		"PT Indonesia Power": "indonesia power",
+	}
+	for in, want := range tests {
+		if got := normalizePartnerName(in); got != want {
+			t.Errorf("normalizePartnerName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"telkom", "telkom", 1},
+		{"", "", 1},
+		{"telkom", "", 0},
+		{"abc", "xyz", 0},
+		// One substitution in ten runes
+		{"maju jayaa", "maju jayab", 0.9},
+		// One insertion against the longer name
+		{"astra", "astra!", 1 - 1.0/6},
+		// Runes, not bytes, are compared
+		{"café", "cafe", 0.75},
+	}
+	for _, tt := range tests {
+		got := nameSimilarity(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if back := nameSimilarity(tt.b, tt.a); back != got {
+			t.Errorf("nameSimilarity(%q, %q) = %v, not symmetric with %v", tt.b, tt.a, back, got)
+		}
+	}
+}
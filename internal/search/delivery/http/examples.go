@@ -0,0 +1,20 @@
+package http
+
+import (
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/search"
+)
+
+// Documented response examples
+var exampleSearch = search.SearchData{
+	Query: "siti",
+	Groups: []search.Group{{
+		Type: search.TypeUsers,
+		Results: []search.Result{{
+			Type: search.TypeUsers,
+			ID:   response.ExampleID(1).String(),
+			Name: "Siti Rahma",
+			Path: "/users/" + response.ExampleID(1).String(),
+		}},
+	}},
+}
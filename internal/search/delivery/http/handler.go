@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/search"
+	"backend-service-internpro/internal/search/service"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// New registers the global search route into the Huma API.
+func New(api huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// GET /v1/search - Search across users, schools, roles and menus
+	routeperm.Register(api, huma.Operation{
+		OperationID: "search",
+		Method:      http.MethodGet,
+		Path:        "/v1/search",
+		Summary:     "Search users, schools, roles and menus",
+		Description: "Results are grouped by type; types the caller cannot view are omitted. Users are limited to the ones the caller may manage: everyone for admins, their school for school admins, none otherwise.",
+		Tags:        []string{"Search"},
+		Responses:   response.Example(constants.SearchSuccess, exampleSearch),
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *struct {
+		Q     string `query:"q" required:"true" minLength:"1" maxLength:"100" doc:"Search query"`
+		Types string `query:"types" default:"users,schools,roles,menus" doc:"Comma separated list of types to search"`
+		Limit int    `query:"limit" minimum:"1" maximum:"20" default:"5" doc:"Maximum results per type"`
+	}) (*struct {
+		Body search.SearchResponse
+	}, error) {
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var types []string
+		for _, t := range strings.Split(in.Types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+
+		result, err := h.svc.Search(ctx, search.Query{Q: in.Q, Types: types, Limit: in.Limit}, actorID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body search.SearchResponse
+		}{Body: *result}, nil
+	})
+}
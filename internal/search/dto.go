@@ -0,0 +1,46 @@
+package search
+
+import (
+	"backend-service-internpro/internal/pkg/response"
+)
+
+// Searchable types
+const (
+	TypeUsers   = "users"
+	TypeSchools = "schools"
+	TypeRoles   = "roles"
+	TypeMenus   = "menus"
+)
+
+// AllTypes lists every searchable type in display order
+var AllTypes = []string{TypeUsers, TypeSchools, TypeRoles, TypeMenus}
+
+// Result represents a single search hit
+type Result struct {
+	Type string `json:"type" doc:"Result type"`
+	ID   string `json:"id" doc:"Resource ID"`
+	Name string `json:"name" doc:"Display name"`
+	Path string `json:"path" doc:"Deep-link path in the admin application"`
+}
+
+// Group holds the results of one type
+type Group struct {
+	Type    string   `json:"type" doc:"Result type"`
+	Results []Result `json:"results" doc:"Matching resources"`
+}
+
+// SearchData represents the data structure for search results
+type SearchData struct {
+	Query  string  `json:"query" doc:"Search query"`
+	Groups []Group `json:"groups" doc:"Results grouped by type"`
+}
+
+// Query represents a global search request
+type Query struct {
+	Q     string
+	Types []string
+	Limit int
+}
+
+// SearchResponse represents the response for global search
+type SearchResponse = response.ApiResponse
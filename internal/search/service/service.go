@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/rbac"
+	rbacRepo "backend-service-internpro/internal/rbac/repository"
+	"backend-service-internpro/internal/school"
+	schoolRepo "backend-service-internpro/internal/school/repository"
+	"backend-service-internpro/internal/search"
+	userRepo "backend-service-internpro/internal/user/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLimit = 5
+	maxLimit     = 20
+	// searchTimeout bounds the fan-out; types that do not answer in time are left out
+	searchTimeout = 2 * time.Second
+)
+
+// PermissionChecker reports whether a user may perform an action on a resource
+type PermissionChecker interface {
+	authz.RoleChecker
+	CheckUserPermission(ctx context.Context, userID uuid.UUID, resource, action string) (bool, error)
+}
+
+type Service interface {
+	Search(ctx context.Context, q search.Query, actorID uuid.UUID) (*search.SearchResponse, error)
+}
+
+type service struct {
+	users   userRepo.Repository
+	schools schoolRepo.SchoolRepository
+	rbac    rbacRepo.Repository
+	perms   PermissionChecker
+}
+
+func New(users userRepo.Repository, schools schoolRepo.SchoolRepository, rbac rbacRepo.Repository, perms PermissionChecker) Service {
+	return &service{
+		users:   users,
+		schools: schools,
+		rbac:    rbac,
+		perms:   perms,
+	}
+}
+
+func (s *service) Search(ctx context.Context, q search.Query, actorID uuid.UUID) (*search.SearchResponse, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+	if q.Limit > maxLimit {
+		q.Limit = maxLimit
+	}
+	if len(q.Types) == 0 {
+		q.Types = search.AllTypes
+	}
+
+	types, err := s.readableTypes(ctx, actorID, q.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users are found within the same scope the user list applies; a
+	// caller limited to themselves gets no user results
+	var scope authz.Scope
+	if slices.Contains(types, search.TypeUsers) {
+		scope, err = s.resolveScope(ctx, actorID)
+		if err != nil {
+			return nil, err
+		}
+		if scope.Restricted && scope.SchoolID == nil {
+			types = slices.DeleteFunc(types, func(t string) bool { return t == search.TypeUsers })
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	groups := make([]search.Group, len(types))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, t := range types {
+		g.Go(func() error {
+			results, err := s.searchType(gctx, t, q.Q, q.Limit, scope)
+			if err != nil {
+				// A slow or failing type must not hide the others
+				logger.Global().Service().ErrorWithErr("search failed", err, "type", t)
+				return nil
+			}
+			groups[i] = search.Group{Type: t, Results: results}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	data := search.SearchData{Query: q.Q, Groups: make([]search.Group, 0, len(groups))}
+	for _, group := range groups {
+		if group.Type != "" {
+			data.Groups = append(data.Groups, group)
+		}
+	}
+
+	return response.Success(constants.SearchSuccess, data), nil
+}
+
+// readableTypes filters the requested types down to those the actor may view
+func (s *service) readableTypes(ctx context.Context, actorID uuid.UUID, requested []string) ([]string, error) {
+	isSuperAdmin, err := s.perms.CheckUserRole(ctx, actorID, authz.RoleSuperAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user role: %w", err)
+	}
+
+	var types []string
+	for _, t := range search.AllTypes {
+		if !slices.Contains(requested, t) {
+			continue
+		}
+
+		if !isSuperAdmin {
+			ok, err := s.perms.CheckUserPermission(ctx, actorID, t, "view")
+			if err != nil {
+				return nil, fmt.Errorf("failed to check user permission: %w", err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// resolveScope determines which users the actor may find
+func (s *service) resolveScope(ctx context.Context, actorID uuid.UUID) (authz.Scope, error) {
+	var actorSchoolID *uuid.UUID
+	actor, err := s.users.GetByID(ctx, actorID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return authz.Scope{}, fmt.Errorf("failed to get actor: %w", err)
+	}
+	if actor != nil {
+		actorSchoolID = actor.SchoolID
+	}
+
+	scope, err := authz.ResolveScope(ctx, s.perms, actorID, actorSchoolID)
+	if err != nil {
+		return authz.Scope{}, fmt.Errorf("failed to resolve actor scope: %w", err)
+	}
+	return scope, nil
+}
+
+func (s *service) searchType(ctx context.Context, t, q string, limit int, scope authz.Scope) ([]search.Result, error) {
+	results := []search.Result{}
+
+	switch t {
+	case search.TypeUsers:
+		users, err := s.users.Search(ctx, q, limit, scope.SchoolID)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			results = append(results, search.Result{
+				Type: t,
+				ID:   u.ID.String(),
+				Name: u.Fullname,
+				Path: "/users/" + u.ID.String(),
+			})
+		}
+
+	case search.TypeSchools:
+		schools, _, err := s.schools.GetAll(ctx, school.QueryParams{Page: 1, Limit: limit, Search: q})
+		if err != nil {
+			return nil, err
+		}
+		for _, sc := range schools {
+			results = append(results, search.Result{
+				Type: t,
+				ID:   sc.ID.String(),
+				Name: sc.Name,
+				Path: "/schools/" + sc.ID.String(),
+			})
+		}
+
+	case search.TypeRoles:
+		roles, _, err := s.rbac.GetRoles(ctx, 1, limit, rbac.RoleFilter{Search: q})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range roles {
+			results = append(results, search.Result{
+				Type: t,
+				ID:   r.ID.String(),
+				Name: r.Name,
+				Path: "/roles/" + r.ID.String(),
+			})
+		}
+
+	case search.TypeMenus:
+		menus, _, err := s.rbac.GetMenus(ctx, 1, limit, rbac.MenuFilter{Search: q})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range menus {
+			path := m.URL
+			if path == "" {
+				path = "/menus/" + m.ID.String()
+			}
+			results = append(results, search.Result{
+				Type: t,
+				ID:   m.ID.String(),
+				Name: m.Name,
+				Path: path,
+			})
+		}
+	}
+
+	return results, nil
+}
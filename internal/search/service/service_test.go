@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/rbac"
+	rbacRepo "backend-service-internpro/internal/rbac/repository"
+	"backend-service-internpro/internal/school"
+	schoolRepo "backend-service-internpro/internal/school/repository"
+	"backend-service-internpro/internal/search"
+	"backend-service-internpro/internal/user"
+	userRepo "backend-service-internpro/internal/user/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakeUsers holds users and filters searches by school like the
+// repository; other methods are not used
+type fakeUsers struct {
+	userRepo.Repository
+	users []user.UserEntity
+}
+
+func (r *fakeUsers) GetByID(_ context.Context, id uuid.UUID) (*user.UserEntity, error) {
+	for i := range r.users {
+		if r.users[i].ID == id {
+			return &r.users[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUsers) Search(_ context.Context, _ string, limit int, schoolID *uuid.UUID) ([]user.UserEntity, error) {
+	var found []user.UserEntity
+	for _, u := range r.users {
+		if schoolID == nil || (u.SchoolID != nil && *u.SchoolID == *schoolID) {
+			found = append(found, u)
+		}
+	}
+	return found[:min(limit, len(found))], nil
+}
+
+type fakeSchools struct {
+	schoolRepo.SchoolRepository
+}
+
+func (fakeSchools) GetAll(context.Context, school.QueryParams) ([]school.SchoolEntity, int, error) {
+	return []school.SchoolEntity{{ID: uuid.New(), Name: "SMK Negeri 1 Surabaya"}}, 1, nil
+}
+
+type fakeRBAC struct {
+	rbacRepo.Repository
+}
+
+func (fakeRBAC) GetRoles(context.Context, int, int, rbac.RoleFilter) ([]rbac.RoleEntity, int64, error) {
+	return []rbac.RoleEntity{{ID: uuid.New(), Name: "School Admin"}}, 1, nil
+}
+
+func (fakeRBAC) GetMenus(context.Context, int, int, rbac.MenuFilter) ([]rbac.MenuEntity, int64, error) {
+	return []rbac.MenuEntity{{ID: uuid.New(), Name: "Schools", URL: "/schools"}}, 1, nil
+}
+
+// grants gives each user roles and lets everyone view everything
+type grants map[uuid.UUID][]string
+
+func (g grants) CheckUserRole(_ context.Context, userID uuid.UUID, slug string) (bool, error) {
+	for _, role := range g[userID] {
+		if role == slug {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (grants) CheckUserPermission(context.Context, uuid.UUID, string, string) (bool, error) {
+	return true, nil
+}
+
+func TestSearchScopesUsers(t *testing.T) {
+	schoolA, schoolB := uuid.New(), uuid.New()
+	admin := user.UserEntity{ID: uuid.New(), Fullname: "Admin"}
+	schoolAdmin := user.UserEntity{ID: uuid.New(), Fullname: "Budi Santoso", SchoolID: &schoolA}
+	student := user.UserEntity{ID: uuid.New(), Fullname: "Siti Rahma", SchoolID: &schoolA}
+	other := user.UserEntity{ID: uuid.New(), Fullname: "Dewi Lestari", SchoolID: &schoolB}
+
+	users := &fakeUsers{users: []user.UserEntity{admin, schoolAdmin, student, other}}
+	s := New(users, fakeSchools{}, fakeRBAC{}, grants{
+		admin.ID:       {authz.RoleAdmin},
+		schoolAdmin.ID: {authz.RoleSchoolAdmin},
+	})
+
+	tests := []struct {
+		name      string
+		actor     uuid.UUID
+		wantUsers int // -1: no users group
+	}{
+		{"admin finds everyone", admin.ID, 4},
+		{"school admin finds their school", schoolAdmin.ID, 2},
+		{"other callers find no users", student.ID, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Search(context.Background(), search.Query{Q: "a", Limit: 20}, tt.actor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			groups := map[string]int{}
+			for _, g := range resp.Data.(search.SearchData).Groups {
+				groups[g.Type] = len(g.Results)
+			}
+
+			got, ok := groups[search.TypeUsers]
+			if !ok {
+				got = -1
+			}
+			if got != tt.wantUsers {
+				t.Errorf("users found = %d, want %d", got, tt.wantUsers)
+			}
+			for _, typ := range []string{search.TypeSchools, search.TypeRoles, search.TypeMenus} {
+				if groups[typ] != 1 {
+					t.Errorf("%s found = %d, want 1", typ, groups[typ])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package seed
+
+// Name pools for demo data. Changing them changes what a given seed produces.
+
+var towns = []string{
+	"Bandung", "Bogor", "Malang", "Surabaya", "Semarang", "Yogyakarta",
+	"Medan", "Makassar", "Denpasar", "Palembang", "Balikpapan", "Padang",
+}
+
+type major struct {
+	name string
+	code string
+}
+
+var majors = []major{
+	{name: "Rekayasa Perangkat Lunak", code: "RPL"},
+	{name: "Teknik Komputer dan Jaringan", code: "TKJ"},
+	{name: "Multimedia", code: "MM"},
+	{name: "Akuntansi", code: "AK"},
+	{name: "Teknik Kendaraan Ringan", code: "TKR"},
+	{name: "Tata Boga", code: "TB"},
+	{name: "Perhotelan", code: "PH"},
+}
+
+var grades = []string{"X", "XI", "XII"}
+
+var companies = []string{
+	"PT Nusantara Digital", "PT Karya Teknologi", "PT Sinar Mandiri",
+	"PT Cipta Solusi", "PT Mitra Informatika", "PT Bumi Kreasi",
+	"PT Lintas Data", "PT Satu Visi",
+}
+
+var firstNames = []string{
+	"Adi", "Budi", "Citra", "Dewi", "Eko", "Fitri", "Gilang", "Hana",
+	"Indra", "Joko", "Kartika", "Lestari", "Made", "Nadia", "Putra", "Rina",
+	"Sari", "Tono", "Wulan", "Yusuf",
+}
+
+var lastNames = []string{
+	"Pratama", "Saputra", "Wijaya", "Hidayat", "Nugroho", "Kusuma",
+	"Santoso", "Putri", "Setiawan", "Lestari", "Gunawan", "Rahmawati",
+}
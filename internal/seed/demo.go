@@ -0,0 +1,415 @@
+package seed
+
+import (
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"backend-service-internpro/internal/container"
+	"backend-service-internpro/internal/pkg/audit"
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/rbac"
+	rbacRepo "backend-service-internpro/internal/rbac/repository"
+	rbacService "backend-service-internpro/internal/rbac/service"
+	"backend-service-internpro/internal/school"
+	schoolRepo "backend-service-internpro/internal/school/repository"
+	schoolService "backend-service-internpro/internal/school/service"
+	"backend-service-internpro/internal/user"
+	userRepo "backend-service-internpro/internal/user/repository"
+	userService "backend-service-internpro/internal/user/service"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DemoAdminUsername is the super admin owning the demo data
+const DemoAdminUsername = "demo.admin"
+
+// ErrNoPassword is returned when no password was given for the demo users
+var ErrNoPassword = errors.New("demo users need a password")
+
+// DemoOptions sets how much demo data is generated. The same options always
+// produce the same names, so a second run only fills what is missing.
+type DemoOptions struct {
+	Schools    int
+	Majorities int // per school
+	Classes    int // per majority
+	Partners   int // per school
+	Users      int
+	Seed       int64
+	Password   string // of every demo user, required
+}
+
+// DefaultDemoOptions returns the profile used by `cli seed demo`
+func DefaultDemoOptions() DemoOptions {
+	return DemoOptions{
+		Schools:    3,
+		Majorities: 3,
+		Classes:    2,
+		Partners:   3,
+		Users:      50,
+		Seed:       1,
+	}
+}
+
+// GeneratePassword returns a random password for the demo users that
+// passes the password rules
+func GeneratePassword() string {
+	t := crand.Text() // 26 characters of A-Z and 2-7
+	return "Demo-" + strings.ToLower(t[:8]) + t[8:16] + "7"
+}
+
+// Counts tallies demo records by kind
+type Counts struct {
+	Schools    int
+	Majorities int
+	Classes    int
+	Partners   int
+	Users      int
+}
+
+// DemoResult reports what a run created and what was already there
+type DemoResult struct {
+	Created  Counts
+	Existing Counts
+}
+
+// Demo writes demo data through the services so validation, audit columns
+// and notifications behave as they do for API requests
+type Demo struct {
+	schools    schoolService.SchoolService
+	schoolRepo schoolRepo.SchoolRepository
+	users      userService.Service
+	userRepo   userRepo.Repository
+	rbac       rbacService.Service
+	rbacRepo   rbacRepo.Repository
+	opts       DemoOptions
+	rng        *rand.Rand
+	result     DemoResult
+}
+
+// demoSchool is a seeded school with the records users are attached to
+type demoSchool struct {
+	id         uuid.UUID
+	domain     string
+	majorities []demoMajority
+	partners   []uuid.UUID
+}
+
+type demoMajority struct {
+	id      uuid.UUID
+	classes []uuid.UUID
+}
+
+// NewDemo creates a demo seeder using the services of c
+func NewDemo(c *container.Container, opts DemoOptions) *Demo {
+	return &Demo{
+		schools:    c.SchoolService,
+		schoolRepo: c.SchoolRepo,
+		users:      c.UserService,
+		userRepo:   c.UserRepo,
+		rbac:       c.RBACService,
+		rbacRepo:   c.RBACRepo,
+		opts:       opts,
+		rng:        rand.New(rand.NewSource(opts.Seed)),
+	}
+}
+
+// Run seeds the demo data. Records are matched by name, domain or username,
+// so running it again is safe.
+func (d *Demo) Run(ctx context.Context) (*DemoResult, error) {
+	if d.opts.Password == "" {
+		return nil, ErrNoPassword
+	}
+	admin, err := d.ensureAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = audit.WithActor(ctx, admin)
+
+	schools := make([]demoSchool, 0, d.opts.Schools)
+	for i := 0; i < d.opts.Schools; i++ {
+		s, err := d.ensureSchool(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		schools = append(schools, s)
+	}
+
+	for i := 0; i < d.opts.Users && len(schools) > 0; i++ {
+		if err := d.ensureUser(ctx, admin, schools, i); err != nil {
+			return nil, err
+		}
+	}
+
+	return &d.result, nil
+}
+
+// ensureAdmin creates the demo super admin. No user exists to create it, so
+// it is created within the seed's system scope.
+func (d *Demo) ensureAdmin(ctx context.Context) (uuid.UUID, error) {
+	systemCtx := authz.WithScope(ctx, authz.SystemScope("demo seed"))
+	id, created, err := d.ensureAccount(systemCtx, uuid.Nil, user.CreateUserRequest{
+		Username: DemoAdminUsername,
+		Email:    "admin@demo.internpro.id",
+		Fullname: "Demo Admin",
+		Password: d.opts.Password,
+		IsAdmin:  true,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	d.count(created, func(c *Counts) { c.Users++ })
+
+	if err := d.ensureRole(ctx, id, id, "super-admin"); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+func (d *Demo) ensureSchool(ctx context.Context, i int) (demoSchool, error) {
+	town := pick(d.rng, towns)
+	name := fmt.Sprintf("SMK Negeri %d %s", i+1, town)
+	domain := fmt.Sprintf("smkn%d-%s.sch.id", i+1, strings.ToLower(strings.ReplaceAll(town, " ", "")))
+	// Draw everything before looking records up so the sequence, and with it
+	// the generated data, does not depend on what already exists
+	address := fmt.Sprintf("Jl. Pendidikan No. %d, %s", 1+d.rng.Intn(200), town)
+
+	s := demoSchool{domain: domain}
+	existing, err := d.schoolRepo.GetByDomain(ctx, domain)
+	switch {
+	case err == nil:
+		s.id = existing.ID
+		d.result.Existing.Schools++
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		res, err := d.schools.CreateSchool(ctx, school.CreateSchoolRequest{
+			Name:    name,
+			Address: address,
+			Domain:  domain,
+		})
+		if err != nil {
+			return s, fmt.Errorf("failed to create school %s: %w", name, err)
+		}
+		s.id = res.Data.(school.School).ID
+		d.result.Created.Schools++
+	default:
+		return s, fmt.Errorf("failed to look up school %s: %w", domain, err)
+	}
+
+	for _, m := range d.rng.Perm(len(majors))[:min(d.opts.Majorities, len(majors))] {
+		majority, err := d.ensureMajority(ctx, s.id, majors[m])
+		if err != nil {
+			return s, err
+		}
+		s.majorities = append(s.majorities, majority)
+	}
+
+	for _, p := range d.rng.Perm(len(companies))[:min(d.opts.Partners, len(companies))] {
+		id, err := d.ensurePartner(ctx, s.id, companies[p])
+		if err != nil {
+			return s, err
+		}
+		s.partners = append(s.partners, id)
+	}
+
+	return s, nil
+}
+
+func (d *Demo) ensureMajority(ctx context.Context, schoolID uuid.UUID, m major) (demoMajority, error) {
+	majority := demoMajority{}
+	existing, _, err := d.schoolRepo.GetAllMajorities(ctx, d.query(schoolID, m.name))
+	if err != nil {
+		return majority, fmt.Errorf("failed to look up majority %s: %w", m.name, err)
+	}
+	for _, e := range existing {
+		if e.Name == m.name {
+			majority.id = e.ID
+		}
+	}
+
+	if majority.id != uuid.Nil {
+		d.result.Existing.Majorities++
+	} else {
+		res, err := d.schools.CreateMajority(ctx, school.CreateMajorityRequest{
+			SchoolID:    schoolID,
+			Name:        m.name,
+			Description: "Program keahlian " + m.name,
+		})
+		if err != nil {
+			return majority, fmt.Errorf("failed to create majority %s: %w", m.name, err)
+		}
+		majority.id = res.Data.(school.Majority).ID
+		d.result.Created.Majorities++
+	}
+
+	for k := 0; k < d.opts.Classes; k++ {
+		name := fmt.Sprintf("%s %s %d", grades[k%len(grades)], m.code, k/len(grades)+1)
+		id, err := d.ensureClass(ctx, schoolID, majority.id, name)
+		if err != nil {
+			return majority, err
+		}
+		majority.classes = append(majority.classes, id)
+	}
+	return majority, nil
+}
+
+func (d *Demo) ensureClass(ctx context.Context, schoolID, majorityID uuid.UUID, name string) (uuid.UUID, error) {
+	existing, _, err := d.schoolRepo.GetAllClasses(ctx, d.query(schoolID, name))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to look up class %s: %w", name, err)
+	}
+	for _, e := range existing {
+		if e.Name == name && e.MajorityID == majorityID {
+			d.result.Existing.Classes++
+			return e.ID, nil
+		}
+	}
+
+	res, err := d.schools.CreateClass(ctx, school.CreateClassRequest{
+		SchoolID:   schoolID,
+		MajorityID: majorityID,
+		Name:       name,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create class %s: %w", name, err)
+	}
+	d.result.Created.Classes++
+	return res.Data.(school.Class).ID, nil
+}
+
+func (d *Demo) ensurePartner(ctx context.Context, schoolID uuid.UUID, name string) (uuid.UUID, error) {
+	contact := pick(d.rng, firstNames) + " " + pick(d.rng, lastNames)
+	existing, _, err := d.schoolRepo.GetAllPartners(ctx, d.query(schoolID, name))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to look up partner %s: %w", name, err)
+	}
+	for _, e := range existing {
+		if e.Name == name {
+			d.result.Existing.Partners++
+			return e.ID, nil
+		}
+	}
+
+	slug := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, "PT "), " ", ""))
+	res, err := d.schools.CreatePartner(ctx, school.CreatePartnerRequest{
+		SchoolID:      schoolID,
+		Name:          name,
+		Website:       "https://" + slug + ".co.id",
+		ContactPerson: contact,
+		ContactEmail:  "hrd@" + slug + ".co.id",
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create partner %s: %w", name, err)
+	}
+	d.result.Created.Partners++
+	return res.Data.(school.Partner).ID, nil
+}
+
+// ensureUser creates the i-th demo user. The first user of every school is
+// its school admin; the rest are mostly students with some teachers and
+// partner staff.
+func (d *Demo) ensureUser(ctx context.Context, admin uuid.UUID, schools []demoSchool, i int) error {
+	s := schools[i%len(schools)]
+	first, last := pick(d.rng, firstNames), pick(d.rng, lastNames)
+	username := fmt.Sprintf("%s.%s%02d", strings.ToLower(first), strings.ToLower(last), i+1)
+
+	req := user.CreateUserRequest{
+		Username: username,
+		Email:    username + "@" + s.domain,
+		Fullname: first + " " + last,
+		Password: d.opts.Password,
+		SchoolID: &s.id,
+		Phone:    fmt.Sprintf("+62812%08d", d.rng.Intn(100000000)),
+	}
+
+	role := "school-admin"
+	if i >= len(schools) {
+		switch n := d.rng.Intn(10); {
+		case n < 2:
+			role = "teacher"
+		case n < 3 && len(s.partners) > 0:
+			role = "partner"
+			req.PartnerID = &s.partners[d.rng.Intn(len(s.partners))]
+		default:
+			role = "student"
+			if len(s.majorities) > 0 {
+				m := s.majorities[d.rng.Intn(len(s.majorities))]
+				req.MajorityID = &m.id
+				if len(m.classes) > 0 {
+					req.ClassID = &m.classes[d.rng.Intn(len(m.classes))]
+				}
+			}
+		}
+	}
+
+	id, created, err := d.ensureAccount(ctx, admin, req)
+	if err != nil {
+		return err
+	}
+	d.count(created, func(c *Counts) { c.Users++ })
+
+	return d.ensureRole(ctx, id, admin, role)
+}
+
+// ensureAccount returns the user with req.Username, creating it when missing
+func (d *Demo) ensureAccount(ctx context.Context, actorID uuid.UUID, req user.CreateUserRequest) (uuid.UUID, bool, error) {
+	existing, err := d.userRepo.GetByUsername(ctx, req.Username)
+	if err == nil {
+		return existing.ID, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, false, fmt.Errorf("failed to look up user %s: %w", req.Username, err)
+	}
+
+	res, err := d.users.CreateUser(ctx, req, actorID)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to create user %s: %w", req.Username, err)
+	}
+	return res.Data.(user.CreateUserData).ID, true, nil
+}
+
+// ensureRole assigns the role with slug unless the user already holds it
+func (d *Demo) ensureRole(ctx context.Context, userID, assignedBy uuid.UUID, slug string) error {
+	has, err := d.rbac.CheckUserRole(ctx, userID, slug)
+	if err != nil {
+		return fmt.Errorf("failed to check role %s: %w", slug, err)
+	}
+	if has {
+		return nil
+	}
+
+	role, err := d.rbacRepo.GetRoleBySlug(ctx, slug)
+	if err != nil {
+		return fmt.Errorf("failed to get role %s: %w", slug, err)
+	}
+	if role == nil {
+		return fmt.Errorf("role %s not found", slug)
+	}
+
+	// The repository is used directly: the service refuses the bootstrap
+	// admin granting itself super-admin
+	_, err = d.rbacRepo.AddRolesToUser(ctx, userID, []uuid.UUID{role.ID}, rbac.RoleValidity{}, assignedBy)
+	if err != nil {
+		return fmt.Errorf("failed to assign role %s: %w", slug, err)
+	}
+	return nil
+}
+
+func (d *Demo) query(schoolID uuid.UUID, search string) school.QueryParams {
+	return school.QueryParams{Page: 1, Limit: 100, Search: search, SchoolID: schoolID.String()}
+}
+
+func (d *Demo) count(created bool, inc func(*Counts)) {
+	if created {
+		inc(&d.result.Created)
+	} else {
+		inc(&d.result.Existing)
+	}
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
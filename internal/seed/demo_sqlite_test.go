@@ -0,0 +1,117 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/container"
+	"backend-service-internpro/internal/pkg/migration"
+	"backend-service-internpro/internal/rbac"
+	rbacRepo "backend-service-internpro/internal/rbac/repository"
+	rbacService "backend-service-internpro/internal/rbac/service"
+	schoolRepo "backend-service-internpro/internal/school/repository"
+	schoolService "backend-service-internpro/internal/school/service"
+	userRepo "backend-service-internpro/internal/user/repository"
+	userService "backend-service-internpro/internal/user/service"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteDriver is SQLite with the MySQL functions the repositories call
+const sqliteDriver = "sqlite3_mysql"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		return conn.RegisterFunc("NOW", func() string {
+			return time.Now().UTC().Format(sqlite3.SQLiteTimestampFormats[0])
+		}, false)
+	}})
+}
+
+// newSQLiteContainer migrates an in-memory SQLite database, adds the roles
+// the seeder assigns and wires the services it writes through
+func newSQLiteContainer(t *testing.T) *container.Container {
+	t.Helper()
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: sqliteDriver, DSN: "file::memory:"}), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	// SQLite has no ON UPDATE clause; the services set updated_at themselves
+	err = db.Callback().Raw().Before("gorm:raw").Register("test:sqlite_ddl", func(tx *gorm.DB) {
+		ddl := strings.ReplaceAll(tx.Statement.SQL.String(), " ON UPDATE CURRENT_TIMESTAMP", "")
+		tx.Statement.SQL.Reset()
+		tx.Statement.SQL.WriteString(ddl)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migration.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, slug := range []string{"super-admin", "school-admin", "teacher", "partner", "student"} {
+		if err := db.Create(&rbac.RoleEntity{ID: uuid.New(), Name: slug, Slug: slug, IsActive: true}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rbacRepository := rbacRepo.NewRepository(db, nil)
+	rbacSvc := rbacService.NewService(rbacRepository)
+	userRepository := userRepo.New(db)
+	schoolRepository := schoolRepo.NewSchoolRepository(db)
+	return &container.Container{
+		RBACRepo:      rbacRepository,
+		RBACService:   rbacSvc,
+		UserRepo:      userRepository,
+		UserService:   userService.New(userRepository, rbacSvc),
+		SchoolRepo:    schoolRepository,
+		SchoolService: schoolService.NewSchoolService(schoolRepository),
+	}
+}
+
+func TestDemoAgainstSQLite(t *testing.T) {
+	c := newSQLiteContainer(t)
+	opts := DefaultDemoOptions()
+	opts.Password = GeneratePassword()
+	ctx := context.Background()
+
+	res, err := NewDemo(c, opts).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Counts{
+		Schools:    opts.Schools,
+		Majorities: opts.Schools * opts.Majorities,
+		Classes:    opts.Schools * opts.Majorities * opts.Classes,
+		Partners:   opts.Schools * opts.Partners,
+		Users:      opts.Users + 1, // the admin
+	}
+	if res.Created != want || res.Existing != (Counts{}) {
+		t.Errorf("first run = %+v, want created %+v", *res, want)
+	}
+
+	// Running again finds everything and creates nothing
+	res, err = NewDemo(c, opts).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != (Counts{}) || res.Existing != want {
+		t.Errorf("second run = %+v, want existing %+v", *res, want)
+	}
+}
@@ -0,0 +1,27 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend-service-internpro/internal/pkg/validator"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	v := validator.New()
+	first := GeneratePassword()
+	if ok, msg := v.IsValidPassword(first); !ok {
+		t.Errorf("generated password %q is refused: %s", first, msg)
+	}
+	if second := GeneratePassword(); second == first {
+		t.Errorf("two generated passwords are both %q", first)
+	}
+}
+
+func TestRunNeedsPassword(t *testing.T) {
+	d := &Demo{opts: DefaultDemoOptions()}
+	if _, err := d.Run(context.Background()); !errors.Is(err, ErrNoPassword) {
+		t.Fatalf("err = %v, want %v", err, ErrNoPassword)
+	}
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/stats"
+	"backend-service-internpro/internal/stats/service"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// New registers the dashboard statistics routes into the Huma API.
+func New(api huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// GET /v1/stats/overview - User counts for the admin dashboard
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getStatsOverview",
+		Method:      http.MethodGet,
+		Path:        "/v1/stats/overview",
+		Summary:     "Get user counts for the dashboard",
+		Description: "Counts users by state, per school and per role. The counts are cached briefly and recomputed after users are created, updated or deleted or roles are assigned; generated_at tells when they were computed.",
+		Tags:        []string{"Statistics"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct{}) (*struct {
+		Body stats.OverviewResponse
+	}, error) {
+		result, err := h.svc.Overview(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return &struct {
+			Body stats.OverviewResponse
+		}{Body: *result}, nil
+	})
+}
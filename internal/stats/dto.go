@@ -0,0 +1,43 @@
+package stats
+
+import (
+	"time"
+
+	"backend-service-internpro/internal/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// UserCounts splits users by state. The tree has no suspension, so a user
+// is either active or soft-deleted.
+type UserCounts struct {
+	Total   int64 `json:"total" doc:"Users including deleted ones"`
+	Active  int64 `json:"active" doc:"Users that are not deleted"`
+	Deleted int64 `json:"deleted" doc:"Soft-deleted users"`
+}
+
+// SchoolCount is the number of active users of a school
+type SchoolCount struct {
+	SchoolID   *uuid.UUID `json:"school_id" doc:"School ID, null for users without a school"`
+	SchoolName string     `json:"school_name,omitempty" doc:"School name"`
+	Users      int64      `json:"users" doc:"Active users of the school"`
+}
+
+// RoleCount is the number of active users holding a role
+type RoleCount struct {
+	RoleID uuid.UUID `json:"role_id" doc:"Role ID"`
+	Slug   string    `json:"slug" doc:"Role slug"`
+	Name   string    `json:"name" doc:"Role name"`
+	Users  int64     `json:"users" doc:"Active users holding the role"`
+}
+
+// Overview holds the dashboard counts
+type Overview struct {
+	Users       UserCounts    `json:"users"`
+	BySchool    []SchoolCount `json:"by_school" doc:"Active users per school, largest first"`
+	ByRole      []RoleCount   `json:"by_role" doc:"Active users per role, largest first"`
+	GeneratedAt time.Time     `json:"generated_at" doc:"When the counts were computed; they may be cached briefly"`
+}
+
+// OverviewResponse represents the dashboard counts response
+type OverviewResponse = response.ApiResponse
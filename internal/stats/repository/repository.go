@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"backend-service-internpro/internal/stats"
+
+	"gorm.io/gorm"
+)
+
+// Repository computes the dashboard aggregates, each in one GROUP BY query
+type Repository interface {
+	CountUsers(ctx context.Context) (stats.UserCounts, error)
+	CountUsersBySchool(ctx context.Context) ([]stats.SchoolCount, error)
+	CountUsersByRole(ctx context.Context) ([]stats.RoleCount, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CountUsers(ctx context.Context) (stats.UserCounts, error) {
+	var counts stats.UserCounts
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Select("COUNT(*) AS total, " +
+			"COALESCE(SUM(CASE WHEN deleted_at IS NULL THEN 1 ELSE 0 END), 0) AS active, " +
+			"COALESCE(SUM(CASE WHEN deleted_at IS NOT NULL THEN 1 ELSE 0 END), 0) AS deleted").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// CountUsersBySchool groups users without a school under a nil school ID
+func (r *repository) CountUsersBySchool(ctx context.Context) ([]stats.SchoolCount, error) {
+	var counts []stats.SchoolCount
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Select("users.school_id AS school_id, COALESCE(schools.name, '') AS school_name, COUNT(*) AS users").
+		Joins("LEFT JOIN schools ON schools.id = users.school_id").
+		Where("users.deleted_at IS NULL").
+		Group("users.school_id, schools.name").
+		Order("users DESC, school_name ASC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// CountUsersByRole only lists roles held by at least one active user
+func (r *repository) CountUsersByRole(ctx context.Context) ([]stats.RoleCount, error) {
+	var counts []stats.RoleCount
+	err := r.db.WithContext(ctx).
+		Table("user_roles").
+		Select("roles.id AS role_id, roles.slug AS slug, roles.name AS name, COUNT(DISTINCT user_roles.user_id) AS users").
+		Joins("INNER JOIN roles ON roles.id = user_roles.role_id AND roles.deleted_at IS NULL").
+		Joins("INNER JOIN users ON users.id = user_roles.user_id AND users.deleted_at IS NULL").
+		Group("roles.id, roles.slug, roles.name").
+		Order("users DESC, roles.name ASC").
+		Scan(&counts).Error
+	return counts, err
+}
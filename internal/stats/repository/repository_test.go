@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/stats"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fixture holds the IDs of the seeded schools and roles
+type fixture struct {
+	smkn1, smkn2                        uuid.UUID
+	student, teacher, partner, archived uuid.UUID
+}
+
+// seed creates the columns the counts read in an in-memory SQLite database
+// and fills them:
+//
+//	SMK Negeri 1: 3 active users and 1 deleted one
+//	SMK Negeri 2: 1 active user
+//	no school:    1 active user and 1 deleted one
+func seed(t *testing.T) (*repository, fixture) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	exec := func(sql string, values ...interface{}) {
+		t.Helper()
+		if err := db.Exec(sql, values...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec("CREATE TABLE users (id TEXT PRIMARY KEY, school_id TEXT, deleted_at DATETIME)")
+	exec("CREATE TABLE schools (id TEXT PRIMARY KEY, name TEXT NOT NULL)")
+	exec("CREATE TABLE roles (id TEXT PRIMARY KEY, slug TEXT NOT NULL, name TEXT NOT NULL, deleted_at DATETIME)")
+	exec("CREATE TABLE user_roles (user_id TEXT NOT NULL, role_id TEXT NOT NULL)")
+
+	f := fixture{
+		smkn1: uuid.New(), smkn2: uuid.New(),
+		student: uuid.New(), teacher: uuid.New(), partner: uuid.New(), archived: uuid.New(),
+	}
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	exec("INSERT INTO schools (id, name) VALUES (?, ?), (?, ?)",
+		f.smkn1, "SMK Negeri 1 Surabaya", f.smkn2, "SMK Negeri 2 Malang")
+	exec("INSERT INTO roles (id, slug, name, deleted_at) VALUES (?, ?, ?, NULL), (?, ?, ?, NULL), (?, ?, ?, NULL), (?, ?, ?, ?)",
+		f.student, "student", "Siswa",
+		f.teacher, "teacher", "Guru",
+		f.partner, "partner", "Mitra",
+		f.archived, "archived", "Arsip", deletedAt)
+
+	users := make(map[string]uuid.UUID)
+	for _, u := range []struct {
+		name     string
+		schoolID *uuid.UUID
+		deleted  bool
+	}{
+		{"siti", &f.smkn1, false},
+		{"budi", &f.smkn1, false},
+		{"andi", &f.smkn1, false},
+		{"rina", &f.smkn1, true},
+		{"dewi", &f.smkn2, false},
+		{"admin", nil, false},
+		{"tono", nil, true},
+	} {
+		users[u.name] = uuid.New()
+		var deleted *time.Time
+		if u.deleted {
+			deleted = &deletedAt
+		}
+		exec("INSERT INTO users (id, school_id, deleted_at) VALUES (?, ?, ?)", users[u.name], u.schoolID, deleted)
+	}
+	for _, ur := range []struct {
+		user string
+		role uuid.UUID
+	}{
+		{"siti", f.student},
+		{"budi", f.student},
+		{"andi", f.student},
+		{"andi", f.teacher},
+		{"dewi", f.teacher},
+		// A deleted role is not listed
+		{"admin", f.archived},
+		// Deleted users do not count towards their roles
+		{"rina", f.student},
+		{"tono", f.partner},
+	} {
+		exec("INSERT INTO user_roles (user_id, role_id) VALUES (?, ?)", users[ur.user], ur.role)
+	}
+	return &repository{db: db}, f
+}
+
+func TestCountUsers(t *testing.T) {
+	r, _ := seed(t)
+	got, err := r.CountUsers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (stats.UserCounts{Total: 7, Active: 5, Deleted: 2}); got != want {
+		t.Errorf("CountUsers() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCountUsersBySchool(t *testing.T) {
+	r, f := seed(t)
+	got, err := r.CountUsersBySchool(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Largest first; ties are ordered by name, so users without a school
+	// come before SMK Negeri 2
+	want := []stats.SchoolCount{
+		{SchoolID: &f.smkn1, SchoolName: "SMK Negeri 1 Surabaya", Users: 3},
+		{SchoolID: nil, SchoolName: "", Users: 1},
+		{SchoolID: &f.smkn2, SchoolName: "SMK Negeri 2 Malang", Users: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CountUsersBySchool() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCountUsersByRole(t *testing.T) {
+	r, f := seed(t)
+	got, err := r.CountUsersByRole(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Mitra is held only by a deleted user and Arsip is deleted itself
+	want := []stats.RoleCount{
+		{RoleID: f.student, Slug: "student", Name: "Siswa", Users: 3},
+		{RoleID: f.teacher, Slug: "teacher", Name: "Guru", Users: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CountUsersByRole() =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/rbac"
+	"backend-service-internpro/internal/stats"
+	"backend-service-internpro/internal/stats/repository"
+	"backend-service-internpro/internal/user"
+)
+
+// DefaultCacheTTL is how long the overview is served from memory by default
+const DefaultCacheTTL = 30 * time.Second
+
+type Service interface {
+	// Overview returns the dashboard counts, cached for the cache TTL
+	Overview(ctx context.Context) (*stats.OverviewResponse, error)
+	// Invalidate drops the cached overview so the next call recomputes it
+	Invalidate()
+	// PublishUserChanged and PublishRolesChanged invalidate the cache when
+	// users are created, updated or deleted or their roles change
+	PublishUserChanged(ctx context.Context, event user.UserChangedEvent)
+	PublishRolesChanged(ctx context.Context, event rbac.RolesChangedEvent)
+}
+
+type service struct {
+	repo repository.Repository
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	cached    *stats.Overview
+	expiresAt time.Time
+}
+
+// New creates a stats service; ttl <= 0 uses DefaultCacheTTL
+func New(repo repository.Repository, ttl time.Duration) Service {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &service{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+func (s *service) Overview(ctx context.Context) (*stats.OverviewResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached == nil || !time.Now().Before(s.expiresAt) {
+		overview, err := s.compute(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.cached = overview
+		s.expiresAt = overview.GeneratedAt.Add(s.ttl)
+	}
+
+	return response.Success(constants.StatsOverviewSuccess, *s.cached), nil
+}
+
+func (s *service) compute(ctx context.Context) (*stats.Overview, error) {
+	users, err := s.repo.CountUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	bySchool, err := s.repo.CountUsersBySchool(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users per school: %w", err)
+	}
+	byRole, err := s.repo.CountUsersByRole(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users per role: %w", err)
+	}
+
+	if bySchool == nil {
+		bySchool = []stats.SchoolCount{}
+	}
+	if byRole == nil {
+		byRole = []stats.RoleCount{}
+	}
+	return &stats.Overview{
+		Users:       users,
+		BySchool:    bySchool,
+		ByRole:      byRole,
+		GeneratedAt: time.Now(),
+	}, nil
+}
+
+func (s *service) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = nil
+}
+
+func (s *service) PublishUserChanged(_ context.Context, _ user.UserChangedEvent) {
+	s.Invalidate()
+}
+
+func (s *service) PublishRolesChanged(_ context.Context, _ rbac.RolesChangedEvent) {
+	s.Invalidate()
+}
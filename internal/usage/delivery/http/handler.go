@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/pkg/routeperm"
+	"backend-service-internpro/internal/usage"
+	"backend-service-internpro/internal/usage/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	svc service.Service
+}
+
+// usageRange is the date range of a usage report
+type usageRange struct {
+	From string `query:"from" doc:"First day (YYYY-MM-DD), defaults to the first day of the current month"`
+	To   string `query:"to" doc:"Last day (YYYY-MM-DD), defaults to today"`
+}
+
+const usageDescription = "Counts the authenticated requests per day and how many ended in a 4xx, a 5xx or 429 Too Many Requests. Days older than the daily retention are summed per month. Counts are written every few seconds, so the latest requests may be missing."
+
+// New registers the API usage report routes. The per user report goes on
+// users, the /v1/users group owned by the router.
+func New(api, users huma.API, svc service.Service) {
+	h := &Handler{
+		svc: svc,
+	}
+
+	// GET /me/usage - The caller's own usage
+	routeperm.Register(api, huma.Operation{
+		OperationID: "getMyUsage",
+		Method:      http.MethodGet,
+		Path:        "/v1/me/usage",
+		Summary:     "Get my API usage",
+		Description: usageDescription,
+		Tags:        []string{"Statistics"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Authenticated, func(ctx context.Context, in *usageRange) (*struct {
+		Body usage.UsageResponse
+	}, error) {
+		userID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return h.usage(ctx, userID, in)
+	})
+
+	// GET /users/{id}/usage - A user's usage, for administrators
+	routeperm.Register(users, huma.Operation{
+		OperationID: "getUserUsage",
+		Method:      http.MethodGet,
+		Path:        "/{id}/usage",
+		Summary:     "Get a user's API usage",
+		Description: usageDescription,
+		Tags:        []string{"Statistics"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct {
+		ID   uuid.UUID `path:"id" doc:"User ID"`
+		From string    `query:"from" doc:"First day (YYYY-MM-DD), defaults to the first day of the current month"`
+		To   string    `query:"to" doc:"Last day (YYYY-MM-DD), defaults to today"`
+	}) (*struct {
+		Body usage.UsageResponse
+	}, error) {
+		return h.usage(ctx, in.ID, &usageRange{From: in.From, To: in.To})
+	})
+}
+
+func (h *Handler) usage(ctx context.Context, userID uuid.UUID, in *usageRange) (*struct {
+	Body usage.UsageResponse
+}, error) {
+	from, to, err := parseRange(in.From, in.To, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.svc.Usage(ctx, userID, from, to)
+	if err != nil {
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+
+	return &struct {
+		Body usage.UsageResponse
+	}{Body: *result}, nil
+}
+
+// parseRange parses the from and to query parameters, defaulting to the
+// current month up to today
+func parseRange(fromParam, toParam string, now time.Time) (time.Time, time.Time, error) {
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var err error
+	if fromParam != "" {
+		if from, err = time.Parse(usage.DateLayout, fromParam); err != nil {
+			return from, to, huma.Error400BadRequest(constants.UsageInvalidDate)
+		}
+	}
+	if toParam != "" {
+		if to, err = time.Parse(usage.DateLayout, toParam); err != nil {
+			return from, to, huma.Error400BadRequest(constants.UsageInvalidDate)
+		}
+	}
+	if to.Before(from) || to.Sub(from) >= service.MaxRangeDays*24*time.Hour {
+		return from, to, huma.Error400BadRequest(fmt.Sprintf(constants.UsageInvalidRange, service.MaxRangeDays))
+	}
+	return from, to, nil
+}
@@ -0,0 +1,46 @@
+package usage
+
+import (
+	"backend-service-internpro/internal/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// DateLayout is the format of the dates in usage queries and responses
+const DateLayout = "2006-01-02"
+
+// Counts are the requests of a user and how they were answered
+type Counts struct {
+	Requests     int64 `json:"requests" doc:"Authenticated requests made"`
+	ClientErrors int64 `json:"client_errors" doc:"Requests answered with a 4xx status, throttled ones included"`
+	ServerErrors int64 `json:"server_errors" doc:"Requests answered with a 5xx status"`
+	Throttled    int64 `json:"throttled" doc:"Requests answered with 429 Too Many Requests"`
+}
+
+// Add adds other to c
+func (c *Counts) Add(other Counts) {
+	c.Requests += other.Requests
+	c.ClientErrors += other.ClientErrors
+	c.ServerErrors += other.ServerErrors
+	c.Throttled += other.Throttled
+}
+
+// Period is the usage of one day, or of a whole month for months older
+// than the daily retention
+type Period struct {
+	Granularity string `json:"granularity" enum:"day,month" doc:"Whether the counts cover a day or a month"`
+	Start       string `json:"start" doc:"First day of the period (YYYY-MM-DD)"`
+	Counts      Counts `json:"counts"`
+}
+
+// Usage is the usage of a user over a date range
+type Usage struct {
+	UserID  uuid.UUID `json:"user_id" doc:"User ID"`
+	From    string    `json:"from" doc:"First day of the range (YYYY-MM-DD)"`
+	To      string    `json:"to" doc:"Last day of the range (YYYY-MM-DD)"`
+	Totals  Counts    `json:"totals" doc:"Counts over every listed period"`
+	Periods []Period  `json:"periods" doc:"Periods with requests, oldest first"`
+}
+
+// UsageResponse represents the usage report response
+type UsageResponse = response.ApiResponse
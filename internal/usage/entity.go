@@ -0,0 +1,45 @@
+package usage
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Granularities of a usage counter row
+const (
+	GranularityDay   = "day"
+	GranularityMonth = "month"
+)
+
+// CounterEntity holds the requests of one user during a day, or during a
+// month once the cleanup job rolled the daily rows up
+type CounterEntity struct {
+	UserID       uuid.UUID `gorm:"type:char(36);primaryKey"`
+	Granularity  string    `gorm:"size:8;primaryKey"`
+	PeriodStart  time.Time `gorm:"type:date;primaryKey"`
+	Requests     int64     `gorm:"not null;default:0"`
+	ClientErrors int64     `gorm:"not null;default:0"`
+	ServerErrors int64     `gorm:"not null;default:0"`
+	Throttled    int64     `gorm:"not null;default:0"`
+	UpdatedAt    time.Time
+}
+
+// TableName returns the table name for the CounterEntity
+func (CounterEntity) TableName() string {
+	return "usage_counters"
+}
+
+// ToPeriod converts CounterEntity to Period DTO
+func (c *CounterEntity) ToPeriod() Period {
+	return Period{
+		Granularity: c.Granularity,
+		Start:       c.PeriodStart.Format(DateLayout),
+		Counts: Counts{
+			Requests:     c.Requests,
+			ClientErrors: c.ClientErrors,
+			ServerErrors: c.ServerErrors,
+			Throttled:    c.Throttled,
+		},
+	}
+}
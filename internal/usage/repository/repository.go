@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend-service-internpro/internal/usage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Add adds the counts of each row to the stored row with the same key,
+	// creating it when missing
+	Add(ctx context.Context, rows []usage.CounterEntity) error
+	// List returns the daily rows of the user from from to to and the
+	// monthly rows of the months they touch, oldest first
+	List(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]usage.CounterEntity, error)
+	// RollUp adds the daily rows before the first day of a month to the
+	// monthly rows and deletes them; it returns how many were rolled up
+	RollUp(ctx context.Context, before time.Time) (int64, error)
+	// DeleteMonthsBefore deletes the monthly rows of months starting before
+	// before
+	DeleteMonthsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// accumulate makes an insert add its counts to an existing row
+var accumulate = clause.OnConflict{
+	DoUpdates: clause.Assignments(map[string]interface{}{
+		"requests":      gorm.Expr("requests + VALUES(requests)"),
+		"client_errors": gorm.Expr("client_errors + VALUES(client_errors)"),
+		"server_errors": gorm.Expr("server_errors + VALUES(server_errors)"),
+		"throttled":     gorm.Expr("throttled + VALUES(throttled)"),
+		"updated_at":    gorm.Expr("VALUES(updated_at)"),
+	}),
+}
+
+func (r *repository) Add(ctx context.Context, rows []usage.CounterEntity) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(accumulate).Create(&rows).Error
+}
+
+func (r *repository) List(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]usage.CounterEntity, error) {
+	firstMonth := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var rows []usage.CounterEntity
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("(granularity = ? AND period_start BETWEEN ? AND ?) OR (granularity = ? AND period_start BETWEEN ? AND ?)",
+			usage.GranularityDay, from, to, usage.GranularityMonth, firstMonth, to).
+		Order("period_start ASC, granularity ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+func (r *repository) RollUp(ctx context.Context, before time.Time) (int64, error) {
+	var rolled int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO usage_counters
+			(user_id, granularity, period_start, requests, client_errors, server_errors, throttled, updated_at)
+			SELECT user_id, ?, DATE_FORMAT(period_start, '%Y-%m-01'),
+				SUM(requests), SUM(client_errors), SUM(server_errors), SUM(throttled), NOW()
+			FROM usage_counters
+			WHERE granularity = ? AND period_start < ?
+			GROUP BY user_id, DATE_FORMAT(period_start, '%Y-%m-01')
+			ON DUPLICATE KEY UPDATE
+				requests = requests + VALUES(requests),
+				client_errors = client_errors + VALUES(client_errors),
+				server_errors = server_errors + VALUES(server_errors),
+				throttled = throttled + VALUES(throttled),
+				updated_at = VALUES(updated_at)`,
+			usage.GranularityMonth, usage.GranularityDay, before).Error; err != nil {
+			return err
+		}
+		res := tx.Where("granularity = ? AND period_start < ?", usage.GranularityDay, before).
+			Delete(&usage.CounterEntity{})
+		rolled = res.RowsAffected
+		return res.Error
+	})
+	return rolled, err
+}
+
+func (r *repository) DeleteMonthsBefore(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("granularity = ? AND period_start < ?", usage.GranularityMonth, before).
+		Delete(&usage.CounterEntity{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/usage"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestListDateRange(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&usage.CounterEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	userID, otherID := uuid.New(), uuid.New()
+	date := func(s string) time.Time {
+		d, err := time.Parse(usage.DateLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	// January and February were rolled up into monthly rows
+	rows := []usage.CounterEntity{
+		{UserID: userID, Granularity: usage.GranularityMonth, PeriodStart: date("2025-01-01"), Requests: 1},
+		{UserID: userID, Granularity: usage.GranularityMonth, PeriodStart: date("2025-02-01"), Requests: 2},
+		{UserID: userID, Granularity: usage.GranularityDay, PeriodStart: date("2025-03-01"), Requests: 3},
+		{UserID: userID, Granularity: usage.GranularityDay, PeriodStart: date("2025-03-05"), Requests: 4},
+		{UserID: userID, Granularity: usage.GranularityDay, PeriodStart: date("2025-03-06"), Requests: 5},
+		{UserID: otherID, Granularity: usage.GranularityDay, PeriodStart: date("2025-03-01"), Requests: 6},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		// The monthly row of the month the range starts in is listed
+		{"across a rolled up month", "2025-02-20", "2025-03-05", []string{"month 2025-02-01", "day 2025-03-01", "day 2025-03-05"}},
+		{"one day", "2025-03-06", "2025-03-06", []string{"day 2025-03-06"}},
+		{"before any usage", "2024-11-01", "2024-12-31", nil},
+	}
+	r := New(db)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.List(context.Background(), userID, date(tt.from), date(tt.to))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var periods []string
+			for _, row := range got {
+				periods = append(periods, row.Granularity+" "+row.PeriodStart.Format(usage.DateLayout))
+			}
+			if !slices.Equal(periods, tt.want) {
+				t.Errorf("List(%s, %s) = %v, want %v", tt.from, tt.to, periods, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"backend-service-internpro/internal/pkg/constants"
+	"backend-service-internpro/internal/pkg/logger"
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/usage"
+	"backend-service-internpro/internal/usage/repository"
+
+	"github.com/google/uuid"
+)
+
+// Defaults used for zero Config values
+const (
+	DefaultFlushInterval    = 30 * time.Second
+	DefaultDailyRetention   = 90 * 24 * time.Hour
+	DefaultMonthlyRetention = 2 * 365 * 24 * time.Hour
+)
+
+// MaxRangeDays bounds the date range of a usage report
+const MaxRangeDays = 366
+
+type Service interface {
+	// Record counts a finished request of the user. It only touches memory;
+	// the counts are written by Flush.
+	Record(userID uuid.UUID, status int)
+	// Flush writes the counts recorded since the last flush
+	Flush(ctx context.Context) error
+	// Start flushes every flush interval for the lifetime of the process
+	Start()
+	// Usage returns the usage of the user from from to to, both inclusive
+	Usage(ctx context.Context, userID uuid.UUID, from, to time.Time) (*usage.UsageResponse, error)
+	// RollUp folds daily counters older than the daily retention into
+	// monthly ones and deletes monthly counters older than the monthly
+	// retention; it runs from the cleanup job, not a request
+	RollUp(ctx context.Context) (RollUpResult, error)
+}
+
+// Config holds the usage service settings
+type Config struct {
+	FlushInterval    time.Duration
+	DailyRetention   time.Duration
+	MonthlyRetention time.Duration
+}
+
+// RollUpResult counts the rows changed by RollUp
+type RollUpResult struct {
+	RolledUp      int64
+	DeletedMonths int64
+}
+
+// key identifies a daily counter
+type key struct {
+	userID uuid.UUID
+	day    time.Time
+}
+
+type service struct {
+	repo repository.Repository
+	cfg  Config
+
+	mu      sync.Mutex
+	pending map[key]*usage.Counts
+}
+
+// New creates a usage service; zero Config values use the defaults
+func New(repo repository.Repository, cfg Config) Service {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.DailyRetention <= 0 {
+		cfg.DailyRetention = DefaultDailyRetention
+	}
+	if cfg.MonthlyRetention <= 0 {
+		cfg.MonthlyRetention = DefaultMonthlyRetention
+	}
+	return &service{
+		repo:    repo,
+		cfg:     cfg,
+		pending: make(map[key]*usage.Counts),
+	}
+}
+
+func (s *service) Record(userID uuid.UUID, status int) {
+	k := key{userID: userID, day: day(time.Now())}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, ok := s.pending[k]
+	if !ok {
+		counts = &usage.Counts{}
+		s.pending[k] = counts
+	}
+	counts.Requests++
+	switch {
+	case status >= 500:
+		counts.ServerErrors++
+	case status >= 400:
+		counts.ClientErrors++
+		if status == http.StatusTooManyRequests {
+			counts.Throttled++
+		}
+	}
+}
+
+func (s *service) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[key]*usage.Counts)
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rows := make([]usage.CounterEntity, 0, len(pending))
+	now := time.Now()
+	for k, counts := range pending {
+		rows = append(rows, usage.CounterEntity{
+			UserID:       k.userID,
+			Granularity:  usage.GranularityDay,
+			PeriodStart:  k.day,
+			Requests:     counts.Requests,
+			ClientErrors: counts.ClientErrors,
+			ServerErrors: counts.ServerErrors,
+			Throttled:    counts.Throttled,
+			UpdatedAt:    now,
+		})
+	}
+	if err := s.repo.Add(ctx, rows); err != nil {
+		// Keep the counts for the next flush rather than losing them
+		s.mu.Lock()
+		for k, counts := range pending {
+			if current, ok := s.pending[k]; ok {
+				counts.Add(*current)
+			}
+			s.pending[k] = counts
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("failed to write usage counters: %w", err)
+	}
+	return nil
+}
+
+func (s *service) Start() {
+	go func() {
+		ticker := time.NewTicker(s.cfg.FlushInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.FlushInterval)
+			if err := s.Flush(ctx); err != nil {
+				logger.Global().Service().ErrorWithErr("failed to flush usage counters", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+func (s *service) Usage(ctx context.Context, userID uuid.UUID, from, to time.Time) (*usage.UsageResponse, error) {
+	from, to = day(from), day(to)
+	rows, err := s.repo.List(ctx, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage counters: %w", err)
+	}
+
+	result := usage.Usage{
+		UserID:  userID,
+		From:    from.Format(usage.DateLayout),
+		To:      to.Format(usage.DateLayout),
+		Periods: make([]usage.Period, 0, len(rows)),
+	}
+	for i := range rows {
+		period := rows[i].ToPeriod()
+		result.Totals.Add(period.Counts)
+		result.Periods = append(result.Periods, period)
+	}
+
+	return response.Success(constants.UsageSuccess, result), nil
+}
+
+func (s *service) RollUp(ctx context.Context) (RollUpResult, error) {
+	var result RollUpResult
+	now := time.Now()
+
+	// Only whole months are rolled up so a month is never split between a
+	// monthly row and daily rows
+	rolled, err := s.repo.RollUp(ctx, month(now.Add(-s.cfg.DailyRetention)))
+	if err != nil {
+		return result, fmt.Errorf("failed to roll up daily usage counters: %w", err)
+	}
+	result.RolledUp = rolled
+
+	deleted, err := s.repo.DeleteMonthsBefore(ctx, month(now.Add(-s.cfg.MonthlyRetention)))
+	if err != nil {
+		return result, fmt.Errorf("failed to delete expired usage counters: %w", err)
+	}
+	result.DeletedMonths = deleted
+
+	return result, nil
+}
+
+// day returns the UTC date of t, the key of the daily counters
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// month returns the first UTC day of the month of t
+func month(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
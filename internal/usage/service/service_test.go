@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/jwt"
+	"backend-service-internpro/internal/pkg/middleware"
+	"backend-service-internpro/internal/usage"
+	"backend-service-internpro/internal/usage/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// counterRepo adds flushed rows to the stored counts of each user like
+// the database does, or fails with err; other methods are not used
+type counterRepo struct {
+	repository.Repository
+	counts map[uuid.UUID]usage.Counts
+	err    error
+}
+
+func (r *counterRepo) Add(_ context.Context, rows []usage.CounterEntity) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, row := range rows {
+		counts := r.counts[row.UserID]
+		counts.Add(usage.Counts{
+			Requests:     row.Requests,
+			ClientErrors: row.ClientErrors,
+			ServerErrors: row.ServerErrors,
+			Throttled:    row.Throttled,
+		})
+		r.counts[row.UserID] = counts
+	}
+	return nil
+}
+
+func TestCountsAccumulateAcrossRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secrets := jwt.Secrets{Access: []byte("access-secret")}
+	repo := &counterRepo{counts: make(map[uuid.UUID]usage.Counts)}
+	s := New(repo, Config{})
+
+	engine := gin.New()
+	engine.Use(middleware.UsageMiddleware(s, secrets))
+	// Stands in for the per-IP rate limit, which runs before authentication
+	engine.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Throttle") != "" {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		}
+	})
+	engine.GET("/public", func(c *gin.Context) { c.Status(http.StatusOK) })
+	authed := engine.Group("", middleware.AuthMiddleware(secrets, nil))
+	authed.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	authed.GET("/missing", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	authed.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	siti, budi := uuid.New(), uuid.New()
+	token := func(userID uuid.UUID) string {
+		token, err := jwt.GenerateAccess(jwt.AccessClaims{UserID: userID.String()}, secrets, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	send := func(path, token string, throttled bool) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if throttled {
+			req.Header.Set("X-Throttle", "1")
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	flush := func() {
+		t.Helper()
+		if err := s.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("/ok", token(siti), false)
+	send("/ok", token(siti), false)
+	send("/missing", token(siti), false)
+	send("/fail", token(siti), false)
+	send("/ok", token(siti), true)
+	send("/ok", token(budi), false)
+	// Neither is attributed to anyone
+	send("/public", "", false)
+	send("/ok", "not-a-token", true)
+	flush()
+
+	// Later flushes add to the stored counts
+	send("/missing", token(siti), false)
+	send("/ok", token(budi), false)
+	flush()
+
+	// A failed flush keeps its counts for the next one
+	repo.err = errors.New("database is down")
+	send("/ok", token(budi), false)
+	if err := s.Flush(context.Background()); err == nil {
+		t.Fatal("flush succeeded while the database is down")
+	}
+	repo.err = nil
+	send("/ok", token(budi), true)
+	flush()
+
+	want := map[uuid.UUID]usage.Counts{
+		siti: {Requests: 6, ClientErrors: 3, ServerErrors: 1, Throttled: 1},
+		budi: {Requests: 4, ClientErrors: 1, Throttled: 1},
+	}
+	if len(repo.counts) != len(want) {
+		t.Errorf("counted %d users, want %d", len(repo.counts), len(want))
+	}
+	for userID, counts := range want {
+		if got := repo.counts[userID]; got != counts {
+			t.Errorf("counts of %s = %+v, want %+v", userID, got, counts)
+		}
+	}
+}
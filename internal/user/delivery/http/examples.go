@@ -0,0 +1,24 @@
+package http
+
+import (
+	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/user"
+)
+
+// Documented response examples
+var exampleSchoolID = response.ExampleID(2)
+
+var exampleUserList = user.UserListData{
+	Users: []user.User{{
+		ID:                  response.ExampleID(1),
+		Username:            "siti_rahma",
+		Email:               "siti.rahma@smkn1sby.sch.id",
+		Fullname:            "Siti Rahma",
+		SchoolID:            &exampleSchoolID,
+		PreferredOTPChannel: "email",
+		EmailNotifications:  true,
+		CreatedAt:           response.ExampleTime,
+		UpdatedAt:           response.ExampleTime,
+	}},
+	Meta: user.Metadata{Page: 1, Limit: 10, TotalPages: 1, TotalItems: 1},
+}
@@ -2,12 +2,15 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
+	"backend-service-internpro/internal/pkg/authz"
 	"backend-service-internpro/internal/pkg/constants"
-	"backend-service-internpro/internal/pkg/jwt"
+	apperrors "backend-service-internpro/internal/pkg/errors"
 	"backend-service-internpro/internal/pkg/middleware"
 	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/routeperm"
 	"backend-service-internpro/internal/user"
 	"backend-service-internpro/internal/user/service"
 
@@ -15,53 +18,51 @@ import (
 )
 
 type Handler struct {
-	svc        service.Service
-	jwtSecrets jwt.Secrets
+	svc service.Service
 }
 
-// New registers user management routes into the Huma API.
-func New(api huma.API, svc service.Service, jwtSecrets jwt.Secrets) {
+// New registers user management routes on users, the /v1/users group
+// owned by the router.
+func New(users huma.API, svc service.Service) {
 	h := &Handler{
-		svc:        svc,
-		jwtSecrets: jwtSecrets,
+		svc: svc,
 	}
-
-	// Group /v1/users
-	g := huma.NewGroup(api, "/v1/users")
+	g := users
 
 	// GET /users - List all users
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "",
-		Summary: "Get list of users with pagination",
-		Tags:    []string{"User Management"},
+	routeperm.Register(g, huma.Operation{
+		OperationID: "listUsers",
+		Method:      http.MethodGet,
+		Path:        "",
+		Summary:     "Get list of users with pagination",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		Responses:   response.Example(constants.UserListSuccess, exampleUserList),
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		Page          int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
-		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct {
+		Page   int    `query:"page" minimum:"1" default:"1" doc:"Page number"`
+		Limit  int    `query:"limit" minimum:"1" maximum:"100" default:"10" doc:"Items per page"`
+		Fields string `query:"fields" doc:"Comma separated fields to return for each item, e.g. id,username,fullname"`
 	}) (*struct {
 		Body user.UserListResponse
 	}, error) {
-		// Validate token
-		if err := h.validateToken(in.Authorization); err != nil {
-			return &struct {
-				Body user.UserListResponse
-			}{
-				Body: *response.Error(constants.TokenInvalid),
-			}, nil
+		fields, err := response.ParseFields[user.User](in.Fields)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
 		}
 
-		resp, err := h.svc.ListUsers(ctx, in.Page, in.Limit)
+		resp, err := h.svc.ListUsers(ctx, in.Page, in.Limit, actorID)
 		if err != nil {
-			return &struct {
-				Body user.UserListResponse
-			}{
-				Body: *response.Error(constants.InternalServerError),
-			}, nil
+			return nil, toHumaError(err, constants.InternalServerError)
 		}
+		resp.Data = response.SelectFields(resp.Data, fields)
 
 		return &struct {
 			Body user.UserListResponse
@@ -71,76 +72,70 @@ func New(api huma.API, svc service.Service, jwtSecrets jwt.Secrets) {
 	})
 
 	// GET /users/{id} - Get user by ID
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodGet,
-		Path:    "/{id}",
-		Summary: "Get user details by ID",
-		Tags:    []string{"User Management"},
+	routeperm.Register(g, huma.Operation{
+		OperationID: "getUser",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		Summary:     "Get user details by ID",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            string `path:"id" format:"uuid" doc:"User ID"`
+	}, routeperm.Require("users", "view"), func(ctx context.Context, in *struct {
+		ID string `path:"id" format:"uuid" doc:"User ID"`
 	}) (*struct {
+		ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
 		Body user.UserResponse
 	}, error) {
-		// Validate token
-		if err := h.validateToken(in.Authorization); err != nil {
-			return &struct {
-				Body user.UserResponse
-			}{
-				Body: *response.Error(constants.TokenInvalid),
-			}, nil
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
 		}
 
-		resp, err := h.svc.GetUserByID(ctx, in.ID)
+		resp, err := h.svc.GetUserByID(ctx, in.ID, actorID)
 		if err != nil {
-			return &struct {
-				Body user.UserResponse
-			}{
-				Body: *response.Error(constants.UserNotFound),
-			}, nil
+			return nil, toHumaError(err, constants.InternalServerError)
+		}
+
+		var etag string
+		if u, ok := resp.Data.(user.User); ok {
+			etag = response.ETag(u.Version)
 		}
 
 		return &struct {
+			ETag string `header:"ETag" doc:"Send back as If-Match when updating to detect concurrent edits"`
 			Body user.UserResponse
 		}{
+			ETag: etag,
 			Body: *resp,
 		}, nil
 	})
 
 	// POST /users - Create new user
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPost,
-		Path:    "",
-		Summary: "Create a new user",
-		Tags:    []string{"User Management"},
+	routeperm.Register(g, huma.Operation{
+		OperationID: "createUser",
+		Method:      http.MethodPost,
+		Path:        "",
+		Summary:     "Create a new user",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		Body          user.CreateUserRequest
+	}, routeperm.Require("users", "create"), func(ctx context.Context, in *struct {
+		Body user.CreateUserRequest
 	}) (*struct {
 		Body user.CreateUserResponse
 	}, error) {
-		// Validate token
-		if err := h.validateToken(in.Authorization); err != nil {
-			return &struct {
-				Body user.CreateUserResponse
-			}{
-				Body: *response.Error(constants.TokenInvalid),
-			}, nil
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
 		}
 
-		resp, err := h.svc.CreateUser(ctx, in.Body)
+		resp, err := h.svc.CreateUser(ctx, in.Body, actorID)
 		if err != nil {
-			return &struct {
-				Body user.CreateUserResponse
-			}{
-				Body: *response.Error(constants.UserCreateFailed),
-			}, nil
+			return nil, toHumaError(err, constants.UserCreateFailed)
 		}
 
 		return &struct {
@@ -151,37 +146,34 @@ func New(api huma.API, svc service.Service, jwtSecrets jwt.Secrets) {
 	})
 
 	// PUT /users/{id} - Update user
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodPut,
-		Path:    "/{id}",
-		Summary: "Update user information",
-		Tags:    []string{"User Management"},
+	routeperm.Register(g, huma.Operation{
+		OperationID: "updateUser",
+		Method:      http.MethodPut,
+		Path:        "/{id}",
+		Summary:     "Update user information",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            string `path:"id" format:"uuid" doc:"User ID"`
-		Body          user.UpdateUserRequest
+	}, routeperm.Require("users", "edit"), func(ctx context.Context, in *struct {
+		ID      string `path:"id" format:"uuid" doc:"User ID"`
+		IfMatch string `header:"If-Match" doc:"ETag from the last read; the update fails with 412 if the user changed since"`
+		Body    user.UpdateUserRequest
 	}) (*struct {
 		Body user.UserBasicResponse
 	}, error) {
-		// Validate token
-		if err := h.validateToken(in.Authorization); err != nil {
-			return &struct {
-				Body user.UserBasicResponse
-			}{
-				Body: *response.Error(constants.TokenInvalid),
-			}, nil
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
 		}
 
-		resp, err := h.svc.UpdateUser(ctx, in.ID, in.Body)
+		resp, err := h.svc.UpdateUser(ctx, in.ID, in.Body, response.MatchVersions(in.IfMatch), actorID)
 		if err != nil {
-			return &struct {
-				Body user.UserBasicResponse
-			}{
-				Body: *response.Error(constants.UserUpdateFailed),
-			}, nil
+			if errors.Is(err, service.ErrUserModified) && in.IfMatch != "" {
+				return nil, huma.Error412PreconditionFailed(constants.PreconditionFailed)
+			}
+			return nil, toHumaError(err, constants.UserUpdateFailed)
 		}
 
 		return &struct {
@@ -192,36 +184,131 @@ func New(api huma.API, svc service.Service, jwtSecrets jwt.Secrets) {
 	})
 
 	// DELETE /users/{id} - Delete user
-	huma.Register(g, huma.Operation{
-		Method:  http.MethodDelete,
-		Path:    "/{id}",
-		Summary: "Delete user by ID",
-		Tags:    []string{"User Management"},
+	routeperm.Register(g, huma.Operation{
+		OperationID: "deleteUser",
+		Method:      http.MethodDelete,
+		Path:        "/{id}",
+		Summary:     "Delete user by ID",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "delete"), func(ctx context.Context, in *struct {
+		ID string `path:"id" format:"uuid" doc:"User ID"`
+	}) (*struct {
+		Body user.UserBasicResponse
+	}, error) {
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		resp, err := h.svc.DeleteUser(ctx, in.ID, actorID)
+		if err != nil {
+			return nil, toHumaError(err, constants.UserDeleteFailed)
+		}
+
+		return &struct {
+			Body user.UserBasicResponse
+		}{
+			Body: *resp,
+		}, nil
+	})
+
+	// POST /users/{id}/release-identifiers - Free the username and email of a deleted user
+	routeperm.Register(g, huma.Operation{
+		OperationID: "releaseUserIdentifiers",
+		Method:      http.MethodPost,
+		Path:        "/{id}/release-identifiers",
+		Summary:     "Release the username and email of a deleted user",
+		Description: "A deleted user keeps their username and email until the retention period ends. Releasing rewrites both so they can be registered again right away.",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "delete"), func(ctx context.Context, in *struct {
+		ID string `path:"id" format:"uuid" doc:"ID of the deleted user"`
+	}) (*struct {
+		Body user.UserBasicResponse
+	}, error) {
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		resp, err := h.svc.ReleaseIdentifiers(ctx, in.ID, actorID)
+		if err != nil {
+			return nil, toHumaError(err, constants.UserIdentifiersReleaseFailed)
+		}
+
+		return &struct {
+			Body user.UserBasicResponse
+		}{
+			Body: *resp,
+		}, nil
+	})
+
+	// POST /users/{id}/deactivate - Block a user from signing in
+	routeperm.Register(g, huma.Operation{
+		OperationID: "deactivateUser",
+		Method:      http.MethodPost,
+		Path:        "/{id}/deactivate",
+		Summary:     "Deactivate a user",
+		Description: "A deactivated user cannot log in or refresh tokens, and all of their sessions and access tokens are revoked. The account and its data are kept; activate it to allow signing in again.",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
-	}, func(ctx context.Context, in *struct {
-		Authorization string `header:"Authorization" required:"true" doc:"Bearer token"`
-		ID            string `path:"id" format:"uuid" doc:"User ID"`
+	}, routeperm.Require("users", "edit"), func(ctx context.Context, in *struct {
+		ID string `path:"id" format:"uuid" doc:"User ID"`
 	}) (*struct {
 		Body user.UserBasicResponse
 	}, error) {
-		// Validate token
-		if err := h.validateToken(in.Authorization); err != nil {
-			return &struct {
-				Body user.UserBasicResponse
-			}{
-				Body: *response.Error(constants.TokenInvalid),
-			}, nil
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
 		}
 
-		resp, err := h.svc.DeleteUser(ctx, in.ID)
+		resp, err := h.svc.DeactivateUser(ctx, in.ID, actorID)
 		if err != nil {
-			return &struct {
-				Body user.UserBasicResponse
-			}{
-				Body: *response.Error(constants.UserDeleteFailed),
-			}, nil
+			return nil, toHumaError(err, constants.UserDeactivateFailed)
+		}
+
+		return &struct {
+			Body user.UserBasicResponse
+		}{
+			Body: *resp,
+		}, nil
+	})
+
+	// POST /users/{id}/activate - Allow a deactivated user to sign in again
+	routeperm.Register(g, huma.Operation{
+		OperationID: "activateUser",
+		Method:      http.MethodPost,
+		Path:        "/{id}/activate",
+		Summary:     "Activate a deactivated user",
+		Description: "Allows a deactivated user to log in again. Sessions revoked on deactivation stay revoked.",
+		Tags:        []string{"User Management"},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, routeperm.Require("users", "edit"), func(ctx context.Context, in *struct {
+		ID string `path:"id" format:"uuid" doc:"User ID"`
+	}) (*struct {
+		Body user.UserBasicResponse
+	}, error) {
+		actorID, err := middleware.UserIDFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized(constants.TokenInvalid)
+		}
+
+		resp, err := h.svc.ActivateUser(ctx, in.ID, actorID)
+		if err != nil {
+			return nil, toHumaError(err, constants.UserActivateFailed)
 		}
 
 		return &struct {
@@ -232,8 +319,28 @@ func New(api huma.API, svc service.Service, jwtSecrets jwt.Secrets) {
 	})
 }
 
-// validateToken validates the Authorization header and returns error if invalid
-func (h *Handler) validateToken(authHeader string) error {
-	_, err := middleware.ValidateToken(authHeader, h.jwtSecrets)
-	return err
+// toHumaError maps school scope violations to 403, identifier conflicts to
+// 409 naming the identifier, missing users to 404 and validation errors to
+// 400. Other errors answer 500 with fallback.
+func toHumaError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, authz.ErrOutOfScope):
+		return huma.Error403Forbidden(constants.UnauthorizedAccess)
+	case errors.Is(err, service.ErrIdentifierRetained):
+		return huma.Error409Conflict(constants.UserIdentifierRetained)
+	case errors.Is(err, service.ErrEmailExists):
+		return huma.Error409Conflict(constants.EmailAlreadyExists)
+	case errors.Is(err, service.ErrUsernameExists):
+		return huma.Error409Conflict(constants.UsernameExists)
+	case errors.Is(err, service.ErrUserNotFound):
+		return huma.Error404NotFound(constants.UserNotFound)
+	case errors.Is(err, service.ErrDeactivateSelf):
+		return huma.Error400BadRequest(constants.UserDeactivateSelf)
+	case errors.Is(err, service.ErrUserModified):
+		return huma.Error409Conflict(constants.ConcurrentUpdate)
+	}
+	if appErr, ok := apperrors.IsAppError(err); ok && appErr.Code == apperrors.CodeValidationFailed {
+		return appErr.ToHumaError()
+	}
+	return huma.Error500InternalServerError(fallback)
 }
@@ -9,17 +9,23 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID         uuid.UUID  `json:"id" doc:"User ID"`
-	Username   string     `json:"username" doc:"User username"`
-	Email      string     `json:"email" doc:"User email address"`
-	Fullname   string     `json:"fullname" doc:"User full name"`
-	IsAdmin    bool       `json:"is_admin" doc:"Whether user is admin"`
-	SchoolID   *uuid.UUID `json:"school_id,omitempty" doc:"User school ID"`
-	MajorityID *uuid.UUID `json:"majority_id,omitempty" doc:"User majority ID"`
-	ClassID    *uuid.UUID `json:"class_id,omitempty" doc:"User class ID"`
-	PartnerID  *uuid.UUID `json:"partner_id,omitempty" doc:"User partner ID"`
-	CreatedAt  time.Time  `json:"created_at" doc:"User creation date"`
-	UpdatedAt  time.Time  `json:"updated_at" doc:"User last update date"`
+	ID                  uuid.UUID  `json:"id" doc:"User ID"`
+	Username            string     `json:"username" doc:"User username"`
+	Email               string     `json:"email" doc:"User email address"`
+	Fullname            string     `json:"fullname" doc:"User full name"`
+	IsAdmin             bool       `json:"is_admin" doc:"Whether user is admin"`
+	IsActive            bool       `json:"is_active" doc:"Whether the user may sign in; false once deactivated"`
+	SchoolID            *uuid.UUID `json:"school_id,omitempty" doc:"User school ID"`
+	MajorityID          *uuid.UUID `json:"majority_id,omitempty" doc:"User majority ID"`
+	ClassID             *uuid.UUID `json:"class_id,omitempty" doc:"User class ID"`
+	PartnerID           *uuid.UUID `json:"partner_id,omitempty" doc:"User partner ID"`
+	Phone               *string    `json:"phone,omitempty" doc:"User phone number"`
+	PreferredOTPChannel string     `json:"preferred_otp_channel" doc:"Channel used to deliver OTP codes"`
+	EmailNotifications  bool       `json:"email_notifications" doc:"Whether notifications are also sent by email"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty" doc:"When the user verified their email; absent until verified"`
+	CreatedAt           time.Time  `json:"created_at" doc:"User creation date"`
+	UpdatedAt           time.Time  `json:"updated_at" doc:"User last update date"`
+	Version             int64      `json:"-"` // sent as the ETag
 }
 
 // Metadata represents pagination metadata
@@ -44,15 +50,18 @@ type UserResponse = response.ApiResponse
 
 // CreateUserRequest represents request to create a new user
 type CreateUserRequest struct {
-	Username   string     `json:"username" form:"username" minLength:"1" maxLength:"60" doc:"User username"`
-	Email      string     `json:"email" form:"email" format:"email" maxLength:"120" doc:"User email address"`
-	Fullname   string     `json:"fullname" form:"fullname" minLength:"1" maxLength:"120" doc:"User full name"`
-	Password   string     `json:"password" form:"password" minLength:"8" doc:"User password"`
-	IsAdmin    bool       `json:"is_admin,omitempty" doc:"Whether user is admin"`
-	SchoolID   *uuid.UUID `json:"school_id,omitempty" doc:"User school ID"`
-	MajorityID *uuid.UUID `json:"majority_id,omitempty" doc:"User majority ID"`
-	ClassID    *uuid.UUID `json:"class_id,omitempty" doc:"User class ID"`
-	PartnerID  *uuid.UUID `json:"partner_id,omitempty" doc:"User partner ID"`
+	Username            string     `json:"username" form:"username" minLength:"1" maxLength:"60" doc:"User username"`
+	Email               string     `json:"email" form:"email" format:"email" maxLength:"120" doc:"User email address"`
+	Fullname            string     `json:"fullname" form:"fullname" minLength:"1" maxLength:"120" doc:"User full name"`
+	Password            string     `json:"password" form:"password" minLength:"8" doc:"User password"`
+	IsAdmin             bool       `json:"is_admin,omitempty" doc:"Whether user is admin"`
+	SchoolID            *uuid.UUID `json:"school_id,omitempty" doc:"User school ID"`
+	MajorityID          *uuid.UUID `json:"majority_id,omitempty" doc:"User majority ID"`
+	ClassID             *uuid.UUID `json:"class_id,omitempty" doc:"User class ID"`
+	PartnerID           *uuid.UUID `json:"partner_id,omitempty" doc:"User partner ID"`
+	Phone               string     `json:"phone,omitempty" maxLength:"32" doc:"User phone number in international format"`
+	PreferredOTPChannel string     `json:"preferred_otp_channel,omitempty" enum:"email,whatsapp,sms" doc:"Channel used to deliver OTP codes"`
+	EmailNotifications  bool       `json:"email_notifications,omitempty" doc:"Whether notifications are also sent by email"`
 }
 
 // CreateUserResponse represents response after creating a user
@@ -60,8 +69,11 @@ type CreateUserResponse = response.ApiResponse
 
 // UpdateUserRequest represents request to update user
 type UpdateUserRequest struct {
-	Username string `json:"username" form:"username" minLength:"1" maxLength:"60" doc:"User username"`
-	Fullname string `json:"fullname" form:"fullname" minLength:"1" maxLength:"120" doc:"User full name"`
+	Username            string `json:"username" form:"username" minLength:"1" maxLength:"60" doc:"User username"`
+	Fullname            string `json:"fullname" form:"fullname" minLength:"1" maxLength:"120" doc:"User full name"`
+	Phone               string `json:"phone,omitempty" form:"phone" maxLength:"32" doc:"User phone number in international format"`
+	PreferredOTPChannel string `json:"preferred_otp_channel,omitempty" form:"preferred_otp_channel" enum:"email,whatsapp,sms" doc:"Channel used to deliver OTP codes"`
+	EmailNotifications  *bool  `json:"email_notifications,omitempty" form:"email_notifications" doc:"Whether notifications are also sent by email"`
 }
 
 // UserBasicResponse represents a basic response with message for user operations
@@ -71,3 +83,22 @@ type UserBasicResponse = response.ApiResponse
 type CreateUserData struct {
 	ID uuid.UUID `json:"id" doc:"Created user ID"`
 }
+
+// Kinds of user changes published by the user service
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+	// ChangeDeactivated and ChangeActivated follow the user being blocked
+	// from signing in or allowed again
+	ChangeDeactivated = "deactivated"
+	ChangeActivated   = "activated"
+)
+
+// UserChangedEvent describes a user created, updated, deleted, deactivated
+// or activated
+type UserChangedEvent struct {
+	UserID  uuid.UUID
+	Change  string
+	ActorID uuid.UUID
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,18 +9,28 @@ import (
 
 // UserEntity represents the user entity for database operations
 type UserEntity struct {
-	ID           uuid.UUID  `gorm:"type:char(36);primaryKey"`
-	Username     string     `gorm:"uniqueIndex;size:60;not null"`
-	Email        string     `gorm:"uniqueIndex;size:120;not null"`
-	Fullname     string     `gorm:"size:120;not null"`
-	PasswordHash string     `gorm:"size:255;not null"`
-	IsAdmin      bool       `gorm:"default:false"`
-	SchoolID     *uuid.UUID `gorm:"type:char(36);index"`
-	MajorityID   *uuid.UUID `gorm:"type:char(36);index"`
-	ClassID      *uuid.UUID `gorm:"type:char(36);index"`
-	PartnerID    *uuid.UUID `gorm:"type:char(36);index"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID                  uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	Username            string     `gorm:"uniqueIndex;size:60;not null"`
+	Email               string     `gorm:"uniqueIndex;size:120;not null"`
+	Fullname            string     `gorm:"size:120;not null"`
+	PasswordHash        string     `gorm:"size:255;not null"`
+	IsAdmin             bool       `gorm:"default:false"`
+	SchoolID            *uuid.UUID `gorm:"type:char(36);index"`
+	MajorityID          *uuid.UUID `gorm:"type:char(36);index"`
+	ClassID             *uuid.UUID `gorm:"type:char(36);index"`
+	PartnerID           *uuid.UUID `gorm:"type:char(36);index"`
+	Phone               *string    `gorm:"size:32"`
+	PreferredOTPChannel string     `gorm:"size:16;not null;default:email"`
+	EmailNotifications  bool       `gorm:"not null;default:false"`
+	EmailVerifiedAt     *time.Time
+	FailedLoginAttempts int `gorm:"not null;default:0"`
+	LockedUntil         *time.Time
+	IsActive            bool `gorm:"not null;default:true"` // deactivated users cannot sign in
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Version             int64      `gorm:"not null;default:1"` // bumped by every update
+	DeletedAt           *time.Time `gorm:"index"`
+	DeletedBy           *uuid.UUID `gorm:"type:char(36)"`
 }
 
 // TableName returns the table name for the UserEntity
@@ -27,16 +38,31 @@ func (UserEntity) TableName() string {
 	return "users"
 }
 
+// ReleasedIdentifierDomain is the email domain of a deleted user whose
+// username and email were rewritten so both can be registered again
+const ReleasedIdentifierDomain = "deleted.invalid"
+
+// NormalizeEmail returns the form emails are stored and compared in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // ToUser converts UserEntity to User DTO
 func (u *UserEntity) ToUser() User {
 	user := User{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		Fullname:  u.Fullname,
-		IsAdmin:   u.IsAdmin,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:                  u.ID,
+		Username:            u.Username,
+		Email:               u.Email,
+		Fullname:            u.Fullname,
+		IsAdmin:             u.IsAdmin,
+		IsActive:            u.IsActive,
+		Phone:               u.Phone,
+		CreatedAt:           u.CreatedAt,
+		UpdatedAt:           u.UpdatedAt,
+		Version:             u.Version,
+		PreferredOTPChannel: u.PreferredOTPChannel,
+		EmailNotifications:  u.EmailNotifications,
+		EmailVerifiedAt:     u.EmailVerifiedAt,
 	}
 
 	if u.SchoolID != nil {
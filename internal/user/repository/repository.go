@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	"backend-service-internpro/internal/user"
 
@@ -9,14 +12,38 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrStale is returned by Update when the user was changed by another write
+// since they were read
+var ErrStale = errors.New("user changed since it was read")
+
 type Repository interface {
 	Create(ctx context.Context, user *user.UserEntity) error
 	GetByID(ctx context.Context, id uuid.UUID) (*user.UserEntity, error)
+	// GetByEmail and GetByUsername include soft-deleted users, whose
+	// identifiers stay taken until they are released
 	GetByEmail(ctx context.Context, email string) (*user.UserEntity, error)
 	GetByUsername(ctx context.Context, username string) (*user.UserEntity, error)
+	// GetDeletedByID returns a soft-deleted user
+	GetDeletedByID(ctx context.Context, id uuid.UUID) (*user.UserEntity, error)
+	// Update writes the profile columns of an active user, never deleted_at
+	// or deleted_by, and returns gorm.ErrRecordNotFound when the user was
+	// deleted meanwhile. It only writes the version of the user it was
+	// given, returning ErrStale for any other, and bumps the version.
 	Update(ctx context.Context, user *user.UserEntity) error
+	// Delete soft-deletes the user and revokes their sessions
 	Delete(ctx context.Context, id uuid.UUID) error
-	List(ctx context.Context, offset, limit int) ([]user.UserEntity, int64, error)
+	// SetActive allows or blocks the user signing in; deactivating also
+	// revokes their sessions
+	SetActive(ctx context.Context, id uuid.UUID, active bool) error
+	// ReleaseIdentifiers rewrites the username and email of a soft-deleted user
+	ReleaseIdentifiers(ctx context.Context, id uuid.UUID) error
+	// ReleaseExpiredIdentifiers releases the identifiers of every user
+	// deleted before deletedBefore and returns how many were released
+	ReleaseExpiredIdentifiers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	List(ctx context.Context, offset, limit int, schoolID *uuid.UUID) ([]user.UserEntity, int64, error)
+	// Search matches active users by username, email or full name, limited
+	// to schoolID when it is set
+	Search(ctx context.Context, query string, limit int, schoolID *uuid.UUID) ([]user.UserEntity, error)
 }
 
 type repository struct {
@@ -35,7 +62,7 @@ func (r *repository) Create(ctx context.Context, user *user.UserEntity) error {
 
 func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*user.UserEntity, error) {
 	var user user.UserEntity
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", id).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -60,27 +87,145 @@ func (r *repository) GetByUsername(ctx context.Context, username string) (*user.
 	return &user, nil
 }
 
+func (r *repository) GetDeletedByID(ctx context.Context, id uuid.UUID) (*user.UserEntity, error) {
+	var user user.UserEntity
+	err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *repository) Update(ctx context.Context, user *user.UserEntity) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	version := user.Version
+	user.Version++
+	result := r.db.WithContext(ctx).Model(user).
+		Where("version = ? AND deleted_at IS NULL", version).
+		Select("username", "fullname", "phone", "preferred_otp_channel", "email_notifications", "updated_at", "version").
+		Updates(user)
+	if result.Error == nil && result.RowsAffected > 0 {
+		return nil
+	}
+	user.Version = version
+	if result.Error != nil {
+		return result.Error
+	}
+
+	// No row matched: either another write bumped the version or the user
+	// is gone
+	var count int64
+	if err := r.db.WithContext(ctx).Table("users").
+		Where("id = ? AND deleted_at IS NULL", user.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrStale
+	}
+	return gorm.ErrRecordNotFound
 }
 
 func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&user.UserEntity{}, "id = ?", id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user.UserEntity{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Update("deleted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Table("refresh_tokens").
+			Where("user_id = ? AND revoked = 0", id).
+			Updates(map[string]interface{}{"revoked": true, "trusted_until": nil}).Error
+	})
+}
+
+func (r *repository) SetActive(ctx context.Context, id uuid.UUID, active bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user.UserEntity{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{"is_active": active, "updated_at": time.Now(), "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if active {
+			return nil
+		}
+
+		return tx.Table("refresh_tokens").
+			Where("user_id = ? AND revoked = 0", id).
+			Updates(map[string]interface{}{"revoked": true, "trusted_until": nil}).Error
+	})
 }
 
-func (r *repository) List(ctx context.Context, offset, limit int) ([]user.UserEntity, int64, error) {
+func (r *repository) ReleaseIdentifiers(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&user.UserEntity{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(releasedIdentifiers())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *repository) ReleaseExpiredIdentifiers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&user.UserEntity{}).
+		Where("deleted_at < ? AND email NOT LIKE ?", deletedBefore, "%@"+user.ReleasedIdentifierDomain).
+		Updates(releasedIdentifiers())
+	return result.RowsAffected, result.Error
+}
+
+// releasedIdentifiers derives a username and email from the user ID so the
+// originals can be registered again while the row stays unique
+func releasedIdentifiers() map[string]interface{} {
+	return map[string]interface{}{
+		"username": gorm.Expr("CONCAT('deleted-', id)"),
+		"email":    gorm.Expr("CONCAT('deleted-', id, '@" + user.ReleasedIdentifierDomain + "')"),
+	}
+}
+
+func (r *repository) List(ctx context.Context, offset, limit int, schoolID *uuid.UUID) ([]user.UserEntity, int64, error) {
 	var users []user.UserEntity
 	var total int64
 
+	query := r.db.WithContext(ctx).Model(&user.UserEntity{}).Where("deleted_at IS NULL")
+	if schoolID != nil {
+		query = query.Where("school_id = ?", *schoolID)
+	}
+
 	// Count total records
-	if err := r.db.WithContext(ctx).Model(&user.UserEntity{}).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated records
-	if err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+	if err := query.Offset(offset).Limit(limit).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
 	return users, total, nil
 }
+
+func (r *repository) Search(ctx context.Context, query string, limit int, schoolID *uuid.UUID) ([]user.UserEntity, error) {
+	var users []user.UserEntity
+	searchPattern := "%" + strings.ToLower(query) + "%"
+	db := r.db.WithContext(ctx).
+		Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ? OR LOWER(fullname) LIKE ?",
+			searchPattern, searchPattern, searchPattern).
+		Where("deleted_at IS NULL")
+	if schoolID != nil {
+		db = db.Where("school_id = ?", *schoolID)
+	}
+	err := db.Order("fullname ASC").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
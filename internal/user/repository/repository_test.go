@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recorder returns a repository on a database that renders statements
+// without a server, and the updates it ran
+func recorder(t *testing.T) (*repository, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []string
+	err = db.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &repository{db: db}, &statements
+}
+
+func TestReleaseExpiredIdentifiers(t *testing.T) {
+	r, statements := recorder(t)
+	deletedBefore := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := r.ReleaseExpiredIdentifiers(context.Background(), deletedBefore); err != nil {
+		t.Fatal(err)
+	}
+	if len(*statements) != 1 {
+		t.Fatalf("ran %d updates, want 1", len(*statements))
+	}
+
+	update := (*statements)[0]
+	for _, want := range []string{
+		// Only users deleted long enough ago, and not those already released
+		"deleted_at < '2025-02-01 00:00:00'",
+		"email NOT LIKE '%@" + user.ReleasedIdentifierDomain + "'",
+		// Tombstones derived from the ID stay unique
+		"`username`=CONCAT('deleted-', id)",
+		"`email`=CONCAT('deleted-', id, '@" + user.ReleasedIdentifierDomain + "')",
+	} {
+		if !strings.Contains(update, want) {
+			t.Errorf("update does not contain %q:\n%s", want, update)
+		}
+	}
+}
+
+func TestUpdateRacingDeleteKeepsUserDeleted(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&user.UserEntity{}); err != nil {
+		t.Fatal(err)
+	}
+	r := &repository{db: db}
+
+	loaded := &user.UserEntity{ID: uuid.New(), Username: "siti", Email: "siti@example.com", Fullname: "Siti", Version: 1}
+	if err := db.Create(loaded).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Another admin deletes the user while the edit is open
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.Model(&user.UserEntity{}).Where("id = ?", loaded.ID).Update("deleted_at", deletedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	loaded.Fullname = "Siti Aminah"
+	if err := r.Update(context.Background(), loaded); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("err = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	var stored user.UserEntity
+	if err := db.Take(&stored, "id = ?", loaded.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.DeletedAt == nil || !stored.DeletedAt.Equal(deletedAt) || stored.Fullname != "Siti" {
+		t.Errorf("stored user = %q deleted at %v, want Siti deleted at %s", stored.Fullname, stored.DeletedAt, deletedAt)
+	}
+}
@@ -3,63 +3,182 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"slices"
 	"time"
 
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/clock"
 	"backend-service-internpro/internal/pkg/constants"
+	apperrors "backend-service-internpro/internal/pkg/errors"
+	"backend-service-internpro/internal/pkg/hasher"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/pkg/notifier"
 	"backend-service-internpro/internal/pkg/response"
+	"backend-service-internpro/internal/pkg/validator"
 	"backend-service-internpro/internal/user"
 	"backend-service-internpro/internal/user/repository"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// Every method takes the ID of the calling user so callers holding
+// school-admin can be limited to users of their own school.
 type Service interface {
-	CreateUser(ctx context.Context, req user.CreateUserRequest) (*user.CreateUserResponse, error)
-	GetUserByID(ctx context.Context, id string) (*user.UserResponse, error)
-	UpdateUser(ctx context.Context, id string, req user.UpdateUserRequest) (*user.UserBasicResponse, error)
-	DeleteUser(ctx context.Context, id string) (*user.UserBasicResponse, error)
-	ListUsers(ctx context.Context, page, limit int) (*user.UserListResponse, error)
+	CreateUser(ctx context.Context, req user.CreateUserRequest, actorID uuid.UUID) (*user.CreateUserResponse, error)
+	GetUserByID(ctx context.Context, id string, actorID uuid.UUID) (*user.UserResponse, error)
+	// UpdateUser writes req over the user if their version is one of
+	// versions, or any version when versions is nil, and returns
+	// ErrUserModified otherwise
+	UpdateUser(ctx context.Context, id string, req user.UpdateUserRequest, versions []int64, actorID uuid.UUID) (*user.UserBasicResponse, error)
+	DeleteUser(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error)
+	// DeactivateUser blocks the user from signing in and signs them out
+	// everywhere; ActivateUser allows them again
+	DeactivateUser(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error)
+	ActivateUser(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error)
+	ListUsers(ctx context.Context, page, limit int, actorID uuid.UUID) (*user.UserListResponse, error)
+	// ReleaseIdentifiers frees the username and email of a deleted user
+	// before the retention period ends
+	ReleaseIdentifiers(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error)
+	// ReleaseExpiredIdentifiers frees the identifiers of users deleted more
+	// than retention ago; it runs from the cleanup job, not a request
+	ReleaseExpiredIdentifiers(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+var (
+	ErrEmailExists    = errors.New("user with this email already exists")
+	ErrUsernameExists = errors.New("user with this username already exists")
+	// ErrIdentifierRetained means the email or username belongs to a
+	// deleted user whose identifiers have not been released yet
+	ErrIdentifierRetained = errors.New("identifier belongs to a deleted user")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrDeactivateSelf     = errors.New("cannot deactivate yourself")
+	// ErrUserModified means an update was made against a version of the
+	// user that another write has since replaced
+	ErrUserModified = errors.New("user changed since it was read")
+)
+
+// EventPublisher receives user change events, e.g. to refresh cached counts
+type EventPublisher interface {
+	PublishUserChanged(ctx context.Context, event user.UserChangedEvent)
+}
+
+// Publishers forwards events to each of its publishers in order
+type Publishers []EventPublisher
+
+func (p Publishers) PublishUserChanged(ctx context.Context, event user.UserChangedEvent) {
+	for _, publisher := range p {
+		publisher.PublishUserChanged(ctx, event)
+	}
+}
+
+// Config holds optional user service settings
+type Config struct {
+	// Events receives user change events; may be nil
+	Events EventPublisher
+	// Passwords hashes the passwords of created users; nil uses bcrypt at
+	// its default cost
+	Passwords hasher.Hasher
+	// Clock and IDs default to the wall clock and random UUIDs; tests
+	// replace them to get predictable timestamps and IDs
+	Clock clock.Clock
+	IDs   idgen.Generator
 }
 
 type service struct {
-	repo repository.Repository
+	repo      repository.Repository
+	roles     authz.RoleChecker
+	validator *validator.Validator
+	events    EventPublisher
+	passwords hasher.Hasher
+	clock     clock.Clock
+	ids       idgen.Generator
+}
+
+func New(repo repository.Repository, roles authz.RoleChecker) Service {
+	return NewWithConfig(repo, roles, Config{})
 }
 
-func New(repo repository.Repository) Service {
+func NewWithConfig(repo repository.Repository, roles authz.RoleChecker, cfg Config) Service {
 	return &service{
-		repo: repo,
+		repo:      repo,
+		roles:     roles,
+		validator: validator.New(),
+		events:    cfg.Events,
+		passwords: hasher.OrDefault(cfg.Passwords),
+		clock:     clock.OrReal(cfg.Clock),
+		ids:       idgen.OrRandom(cfg.IDs),
 	}
 }
 
-func (s *service) CreateUser(ctx context.Context, req user.CreateUserRequest) (*user.CreateUserResponse, error) {
+func (s *service) CreateUser(ctx context.Context, req user.CreateUserRequest, actorID uuid.UUID) (*user.CreateUserResponse, error) {
+	scope, err := s.resolveScope(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Restricted {
+		if req.SchoolID == nil {
+			req.SchoolID = scope.SchoolID
+		}
+		if !scope.AllowsSchool(req.SchoolID) {
+			return nil, authz.ErrOutOfScope
+		}
+		if req.IsAdmin {
+			return nil, fmt.Errorf("%w: cannot create admin users", authz.ErrOutOfScope)
+		}
+	}
+
+	req.Email = user.NormalizeEmail(req.Email)
+
 	// Check if user with email already exists
-	if _, err := s.repo.GetByEmail(ctx, req.Email); err == nil {
-		return nil, errors.New("user with this email already exists")
+	if existing, err := s.repo.GetByEmail(ctx, req.Email); err == nil {
+		return nil, identifierTaken(existing, ErrEmailExists)
 	}
 
 	// Check if user with username already exists
-	if _, err := s.repo.GetByUsername(ctx, req.Username); err == nil {
-		return nil, errors.New("user with this username already exists")
+	if existing, err := s.repo.GetByUsername(ctx, req.Username); err == nil {
+		return nil, identifierTaken(existing, ErrUsernameExists)
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
 
 	// Create user entity
 	userEntity := &user.UserEntity{
-		ID:           uuid.New(),
-		Username:     req.Username,
-		Email:        req.Email,
-		Fullname:     req.Fullname,
-		PasswordHash: string(hashedPassword),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:                  s.ids.New(),
+		Username:            req.Username,
+		Email:               req.Email,
+		Fullname:            req.Fullname,
+		PasswordHash:        hashedPassword,
+		IsAdmin:             req.IsAdmin,
+		IsActive:            true,
+		SchoolID:            req.SchoolID,
+		MajorityID:          req.MajorityID,
+		ClassID:             req.ClassID,
+		PartnerID:           req.PartnerID,
+		CreatedAt:           s.clock.Now(),
+		UpdatedAt:           s.clock.Now(),
+		PreferredOTPChannel: string(notifier.ChannelEmail),
+		EmailNotifications:  req.EmailNotifications,
+	}
+	if req.Phone != "" {
+		userEntity.Phone = &req.Phone
+	}
+	// Users created by a system caller, like the demo seed, are trusted;
+	// everyone else verifies their email with the code sent to it
+	if scope.IsSystem() {
+		userEntity.EmailVerifiedAt = &userEntity.CreatedAt
+	}
+	if req.PreferredOTPChannel != "" {
+		userEntity.PreferredOTPChannel = req.PreferredOTPChannel
+	}
+	if err := s.validateContact(userEntity); err != nil {
+		return nil, err
 	}
 
 	// Save to database
@@ -71,10 +190,11 @@ func (s *service) CreateUser(ctx context.Context, req user.CreateUserRequest) (*
 		ID: userEntity.ID,
 	}
 
+	s.publish(ctx, userEntity.ID, user.ChangeCreated, actorID)
 	return response.Success(constants.UserCreateSuccess, createData), nil
 }
 
-func (s *service) GetUserByID(ctx context.Context, id string) (*user.UserResponse, error) {
+func (s *service) GetUserByID(ctx context.Context, id string, actorID uuid.UUID) (*user.UserResponse, error) {
 	userID, err := uuid.Parse(id)
 	if err != nil {
 		return nil, errors.New("invalid user ID format")
@@ -83,15 +203,19 @@ func (s *service) GetUserByID(ctx context.Context, id string) (*user.UserRespons
 	userEntity, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, errors.New("failed to get user")
 	}
 
+	if err := s.checkTarget(ctx, actorID, userEntity); err != nil {
+		return nil, err
+	}
+
 	return response.Success(constants.UserDetailSuccess, userEntity.ToUser()), nil
 }
 
-func (s *service) UpdateUser(ctx context.Context, id string, req user.UpdateUserRequest) (*user.UserBasicResponse, error) {
+func (s *service) UpdateUser(ctx context.Context, id string, req user.UpdateUserRequest, versions []int64, actorID uuid.UUID) (*user.UserBasicResponse, error) {
 	userID, err := uuid.Parse(id)
 	if err != nil {
 		return nil, errors.New("invalid user ID format")
@@ -101,15 +225,22 @@ func (s *service) UpdateUser(ctx context.Context, id string, req user.UpdateUser
 	userEntity, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, errors.New("failed to get user")
 	}
 
+	if err := s.checkTarget(ctx, actorID, userEntity); err != nil {
+		return nil, err
+	}
+	if versions != nil && !slices.Contains(versions, userEntity.Version) {
+		return nil, ErrUserModified
+	}
+
 	// Check if username is being changed and if it's already taken
 	if req.Username != "" && req.Username != userEntity.Username {
-		if _, err := s.repo.GetByUsername(ctx, req.Username); err == nil {
-			return nil, errors.New("username already taken")
+		if existing, err := s.repo.GetByUsername(ctx, req.Username); err == nil {
+			return nil, identifierTaken(existing, ErrUsernameExists)
 		}
 		userEntity.Username = req.Username
 	}
@@ -118,43 +249,163 @@ func (s *service) UpdateUser(ctx context.Context, id string, req user.UpdateUser
 	if req.Fullname != "" {
 		userEntity.Fullname = req.Fullname
 	}
-	userEntity.UpdatedAt = time.Now()
+	if req.Phone != "" {
+		userEntity.Phone = &req.Phone
+	}
+	if req.PreferredOTPChannel != "" {
+		userEntity.PreferredOTPChannel = req.PreferredOTPChannel
+	}
+	if req.EmailNotifications != nil {
+		userEntity.EmailNotifications = *req.EmailNotifications
+	}
+	if err := s.validateContact(userEntity); err != nil {
+		return nil, err
+	}
+	userEntity.UpdatedAt = s.clock.Now()
 
-	// Save changes
+	// Save changes; only the version read above is written, so a write in
+	// between fails instead of being overwritten
 	if err := s.repo.Update(ctx, userEntity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		if errors.Is(err, repository.ErrStale) {
+			return nil, ErrUserModified
+		}
 		return nil, errors.New("failed to update user")
 	}
+	s.publish(ctx, userID, user.ChangeUpdated, actorID)
 
 	return response.SuccessWithoutData(constants.UserUpdateSuccess), nil
 }
 
-func (s *service) DeleteUser(ctx context.Context, id string) (*user.UserBasicResponse, error) {
+func (s *service) DeleteUser(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error) {
 	userID, err := uuid.Parse(id)
 	if err != nil {
 		return nil, errors.New("invalid user ID format")
 	}
 
 	// Check if user exists
-	_, err = s.repo.GetByID(ctx, userID)
+	userEntity, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, errors.New("failed to get user")
 	}
 
+	if err := s.checkTarget(ctx, actorID, userEntity); err != nil {
+		return nil, err
+	}
+
 	// Delete user
 	if err := s.repo.Delete(ctx, userID); err != nil {
 		return nil, errors.New("failed to delete user")
 	}
+	s.publish(ctx, userID, user.ChangeDeleted, actorID)
 
 	return response.SuccessWithoutData(constants.UserDeleteSuccess), nil
 }
 
-func (s *service) ListUsers(ctx context.Context, page, limit int) (*user.UserListResponse, error) {
+func (s *service) DeactivateUser(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error) {
+	return s.setActive(ctx, id, false, actorID)
+}
+
+func (s *service) ActivateUser(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error) {
+	return s.setActive(ctx, id, true, actorID)
+}
+
+// setActive allows or blocks the user signing in. Setting the current
+// state again succeeds without publishing a change.
+func (s *service) setActive(ctx context.Context, id string, active bool, actorID uuid.UUID) (*user.UserBasicResponse, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+	if !active && userID == actorID {
+		return nil, ErrDeactivateSelf
+	}
+
+	userEntity, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, errors.New("failed to get user")
+	}
+
+	if err := s.checkTarget(ctx, actorID, userEntity); err != nil {
+		return nil, err
+	}
+
+	msg, change := constants.UserActivateSuccess, user.ChangeActivated
+	if !active {
+		msg, change = constants.UserDeactivateSuccess, user.ChangeDeactivated
+	}
+	if userEntity.IsActive == active {
+		return response.SuccessWithoutData(msg), nil
+	}
+
+	if err := s.repo.SetActive(ctx, userID, active); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, errors.New("failed to update user status")
+	}
+	s.publish(ctx, userID, change, actorID)
+
+	return response.SuccessWithoutData(msg), nil
+}
+
+func (s *service) ReleaseIdentifiers(ctx context.Context, id string, actorID uuid.UUID) (*user.UserBasicResponse, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	userEntity, err := s.repo.GetDeletedByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, errors.New("failed to get user")
+	}
+
+	if err := s.checkTarget(ctx, actorID, userEntity); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReleaseIdentifiers(ctx, userID); err != nil {
+		return nil, errors.New("failed to release user identifiers")
+	}
+
+	return response.SuccessWithoutData(constants.UserIdentifiersReleased), nil
+}
+
+func (s *service) ReleaseExpiredIdentifiers(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.ReleaseExpiredIdentifiers(ctx, s.clock.Now().Add(-retention))
+}
+
+// identifierTaken explains why an identifier held by existing cannot be reused
+func identifierTaken(existing *user.UserEntity, taken error) error {
+	if existing.DeletedAt != nil {
+		return fmt.Errorf("%w: %w", ErrIdentifierRetained, taken)
+	}
+	return taken
+}
+
+func (s *service) ListUsers(ctx context.Context, page, limit int, actorID uuid.UUID) (*user.UserListResponse, error) {
 	offset := (page - 1) * limit
 
-	userEntities, total, err := s.repo.List(ctx, offset, limit)
+	scope, err := s.resolveScope(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Restricted && scope.SchoolID == nil {
+		return nil, authz.ErrOutOfScope
+	}
+
+	userEntities, total, err := s.repo.List(ctx, offset, limit, scope.SchoolID)
 	if err != nil {
 		return nil, errors.New("failed to get users")
 	}
@@ -179,3 +430,70 @@ func (s *service) ListUsers(ctx context.Context, page, limit int) (*user.UserLis
 
 	return response.Success(constants.UserListSuccess, listData), nil
 }
+
+// validateContact checks the phone format and that phone based OTP
+// channels have a phone number to deliver to
+func (s *service) validateContact(u *user.UserEntity) error {
+	if u.Phone != nil && *u.Phone != "" {
+		ok, msg := s.validator.IsValidPhone(*u.Phone)
+		if err := validator.Check(validator.RulePhoneFormat, ok, msg); err != nil {
+			return err
+		}
+	}
+
+	ch := notifier.Channel(u.PreferredOTPChannel)
+	if !ch.IsValid() {
+		return apperrors.ValidationFailed("invalid preferred OTP channel")
+	}
+	if ch != notifier.ChannelEmail && (u.Phone == nil || *u.Phone == "") {
+		return apperrors.ValidationFailed("phone number is required for the selected OTP channel")
+	}
+	return nil
+}
+
+// resolveScope determines which users the actor may manage. A scope set
+// with authz.WithScope, like the demo seed's system scope, takes precedence;
+// without one an actor is required.
+func (s *service) resolveScope(ctx context.Context, actorID uuid.UUID) (authz.Scope, error) {
+	if scope, ok := authz.ScopeFrom(ctx); ok {
+		return scope, nil
+	}
+	if actorID == uuid.Nil {
+		return authz.Scope{}, authz.ErrNoActor
+	}
+
+	var actorSchoolID *uuid.UUID
+	actor, err := s.repo.GetByID(ctx, actorID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return authz.Scope{}, errors.New("failed to get actor")
+	}
+	if actor != nil {
+		actorSchoolID = actor.SchoolID
+	}
+
+	scope, err := authz.ResolveScope(ctx, s.roles, actorID, actorSchoolID)
+	if err != nil {
+		return authz.Scope{}, errors.New("failed to resolve actor scope")
+	}
+	return scope, nil
+}
+
+// checkTarget rejects operations on users outside of the actor's scope
+func (s *service) checkTarget(ctx context.Context, actorID uuid.UUID, target *user.UserEntity) error {
+	scope, err := s.resolveScope(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	if !scope.AllowsUser(target.ID, target.SchoolID) {
+		return authz.ErrOutOfScope
+	}
+	return nil
+}
+
+// publish reports a user change to the event publisher, if any
+func (s *service) publish(ctx context.Context, userID uuid.UUID, change string, actorID uuid.UUID) {
+	if s.events == nil {
+		return
+	}
+	s.events.PublishUserChanged(ctx, user.UserChangedEvent{UserID: userID, Change: change, ActorID: actorID})
+}
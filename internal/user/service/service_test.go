@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend-service-internpro/internal/pkg/authz"
+	"backend-service-internpro/internal/pkg/clock"
+	"backend-service-internpro/internal/pkg/idgen"
+	"backend-service-internpro/internal/user"
+	"backend-service-internpro/internal/user/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeRepo knows the users in it, deleted or not, and keeps the ones
+// created; other methods are not used
+type fakeRepo struct {
+	repository.Repository
+	users    []*user.UserEntity
+	created  []*user.UserEntity
+	released time.Time
+}
+
+func (r *fakeRepo) find(match func(*user.UserEntity) bool) (*user.UserEntity, error) {
+	for _, u := range r.users {
+		if match(u) {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id uuid.UUID) (*user.UserEntity, error) {
+	return r.find(func(u *user.UserEntity) bool { return u.ID == id && u.DeletedAt == nil })
+}
+
+func (r *fakeRepo) GetByEmail(_ context.Context, email string) (*user.UserEntity, error) {
+	return r.find(func(u *user.UserEntity) bool { return u.Email == email })
+}
+
+func (r *fakeRepo) GetByUsername(_ context.Context, username string) (*user.UserEntity, error) {
+	return r.find(func(u *user.UserEntity) bool { return u.Username == username })
+}
+
+func (r *fakeRepo) GetDeletedByID(_ context.Context, id uuid.UUID) (*user.UserEntity, error) {
+	return r.find(func(u *user.UserEntity) bool { return u.ID == id && u.DeletedAt != nil })
+}
+
+func (r *fakeRepo) ReleaseIdentifiers(_ context.Context, id uuid.UUID) error {
+	u, err := r.GetDeletedByID(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	u.Username = "deleted-" + id.String()
+	u.Email = "deleted-" + id.String() + "@" + user.ReleasedIdentifierDomain
+	return nil
+}
+
+func (r *fakeRepo) Create(_ context.Context, u *user.UserEntity) error {
+	r.created = append(r.created, u)
+	r.users = append(r.users, u)
+	return nil
+}
+
+func (r *fakeRepo) ReleaseExpiredIdentifiers(_ context.Context, before time.Time) (int64, error) {
+	r.released = before
+	return 0, nil
+}
+
+// superAdmins is a RoleChecker granting super-admin to the users in it
+type superAdmins map[uuid.UUID]bool
+
+func (s superAdmins) CheckUserRole(_ context.Context, userID uuid.UUID, slug string) (bool, error) {
+	return slug == authz.RoleSuperAdmin && s[userID], nil
+}
+
+func TestIdentifiersReusableAfterRelease(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	ids := idgen.NewSequence()
+	actorID := ids.New()
+	deletedAt := now.AddDate(0, 0, -3)
+	deleted := &user.UserEntity{ID: ids.New(), Username: "siti_rahma", Email: "siti@example.com", DeletedAt: &deletedAt}
+	repo := &fakeRepo{users: []*user.UserEntity{deleted}}
+	s := NewWithConfig(repo, superAdmins{actorID: true}, Config{Passwords: plainHasher{}, Clock: clock.NewFake(now), IDs: ids})
+	ctx := context.Background()
+	req := user.CreateUserRequest{
+		Username: "siti_rahma",
+		Email:    "SITI@example.com",
+		Fullname: "Siti Rahma",
+		Password: "Rahasia#2025",
+	}
+
+	// The deleted user still holds the email, whatever its case
+	_, err := s.CreateUser(ctx, req, actorID)
+	if !errors.Is(err, ErrIdentifierRetained) || !errors.Is(err, ErrEmailExists) {
+		t.Fatalf("create before the release: err = %v, want %v and %v", err, ErrIdentifierRetained, ErrEmailExists)
+	}
+
+	if _, err := s.ReleaseIdentifiers(ctx, deleted.ID.String(), actorID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateUser(ctx, req, actorID); err != nil {
+		t.Fatalf("create after the release: %v", err)
+	}
+	if len(repo.created) != 1 || repo.created[0].Email != "siti@example.com" || repo.created[0].Username != "siti_rahma" {
+		t.Errorf("created %+v, want siti_rahma with siti@example.com", repo.created)
+	}
+
+	// Only deleted users can be released
+	if _, err := s.ReleaseIdentifiers(ctx, repo.created[0].ID.String(), actorID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("release of an active user: err = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+// plainHasher stores passwords as they are, which is enough for tests
+type plainHasher struct{}
+
+func (plainHasher) Hash(password string) (string, error) { return password, nil }
+
+func (plainHasher) Verify(password, hash string) (bool, bool) { return password == hash, false }
+
+func TestCreateUserUsesClockAndIDs(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	ids := idgen.NewSequence()
+	actorID := ids.New()
+	repo := &fakeRepo{}
+	s := NewWithConfig(repo, superAdmins{actorID: true}, Config{
+		Passwords: plainHasher{},
+		Clock:     clock.NewFake(now),
+		IDs:       ids,
+	})
+
+	resp, err := s.CreateUser(context.Background(), user.CreateUserRequest{
+		Username: "siti_rahma",
+		Email:    "Siti@Example.com",
+		Fullname: "Siti Rahma",
+		Password: "Rahasia#2025",
+	}, actorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := uuid.MustParse("00000000-0000-4000-8000-000000000002")
+	if got := resp.Data.(user.CreateUserData).ID; got != want {
+		t.Errorf("user ID = %s, want %s", got, want)
+	}
+	if len(repo.created) != 1 {
+		t.Fatalf("created %d users, want 1", len(repo.created))
+	}
+	created := repo.created[0]
+	if !created.CreatedAt.Equal(now) || !created.UpdatedAt.Equal(now) {
+		t.Errorf("created at %s, updated at %s, want both %s", created.CreatedAt, created.UpdatedAt, now)
+	}
+	if created.Email != "siti@example.com" {
+		t.Errorf("email = %q, want it normalized", created.Email)
+	}
+	// An admin creating the user does not vouch for the email
+	if created.EmailVerifiedAt != nil {
+		t.Errorf("email verified at %s, want unverified", created.EmailVerifiedAt)
+	}
+}
+
+func TestCreateUserWithoutActor(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	existing := &user.UserEntity{ID: uuid.New(), Username: "siti_rahma", Email: "siti@example.com"}
+	repo := &fakeRepo{users: []*user.UserEntity{existing}}
+	s := NewWithConfig(repo, superAdmins{}, Config{Passwords: plainHasher{}, Clock: clock.NewFake(now)})
+	req := user.CreateUserRequest{
+		Username: "demo.admin",
+		Email:    "admin@demo.internpro.id",
+		Fullname: "Demo Admin",
+		Password: "Rahasia#2025",
+		IsAdmin:  true,
+	}
+
+	// The nil ID grants nothing by itself
+	if _, err := s.CreateUser(context.Background(), req, uuid.Nil); !errors.Is(err, authz.ErrNoActor) {
+		t.Fatalf("create without an actor: err = %v, want %v", err, authz.ErrNoActor)
+	}
+	if _, err := s.GetUserByID(context.Background(), existing.ID.String(), uuid.Nil); !errors.Is(err, authz.ErrNoActor) {
+		t.Errorf("get without an actor: err = %v, want %v", err, authz.ErrNoActor)
+	}
+	if len(repo.created) != 0 {
+		t.Fatalf("created %d users without an actor", len(repo.created))
+	}
+
+	// The seed creates its admin within a system scope, which vouches for the email
+	ctx := authz.WithScope(context.Background(), authz.SystemScope("demo seed"))
+	if _, err := s.CreateUser(ctx, req, uuid.Nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.created) != 1 || repo.created[0].EmailVerifiedAt == nil || !repo.created[0].EmailVerifiedAt.Equal(now) {
+		t.Errorf("created %+v, want one user verified at %s", repo.created, now)
+	}
+}
+
+func TestReleaseExpiredIdentifiersUsesClock(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{}
+	s := NewWithConfig(repo, superAdmins{}, Config{Clock: clock.NewFake(now)})
+
+	if _, err := s.ReleaseExpiredIdentifiers(context.Background(), 30*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if want := now.AddDate(0, 0, -30); !repo.released.Equal(want) {
+		t.Errorf("released the identifiers of users deleted before %s, want %s", repo.released, want)
+	}
+}